@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -27,6 +28,7 @@ import (
 	"xiangshoufu/internal/middleware"
 	"xiangshoufu/internal/repository"
 	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/sms"
 )
 
 // Config 应用配置
@@ -35,7 +37,10 @@ type Config struct {
 	ServerPort      string
 	HxtPublicKey    string
 	AlertWebhookURL string
-	SwaggerEnabled  bool // 是否启用Swagger UI
+	SwaggerEnabled  bool   // 是否启用Swagger UI
+	LabelVerifyURL  string // 终端标签二维码校验地址
+	LabelSecret     string // 终端标签签名密钥
+	SMSProvider     string // 短信服务商（stub仅供开发），为空时不发送短信
 }
 
 // @title           8通道回调服务 API
@@ -86,7 +91,8 @@ func main() {
 	profitRepo := repository.NewGormProfitRecordRepository(db)
 	walletRepo := repository.NewGormWalletRepository(db)
 	walletLogRepo := repository.NewGormWalletLogRepository(db)
-	agentRepo := repository.NewGormAgentRepository(db)
+	hierarchyRepo := repository.NewGormHierarchyRepository(db) // 代理商层级（闭包表）
+	agentRepo := repository.NewGormAgentRepository(db, hierarchyRepo)
 	agentPolicyRepo := repository.NewGormAgentPolicyRepository(db)
 	merchantRepo := repository.NewGormMerchantRepository(db)
 	rateStagePolicyRepo := repository.NewGormRateStagePolicyRepository(db)
@@ -152,26 +158,28 @@ func main() {
 		walletRepo,
 		walletLogRepo,
 		agentRepo,
+		hierarchyRepo,
 	)
+	deductionPlanChangeRepo := repository.NewGormDeductionPlanChangeRepository(db)
+	deductionService.SetPlanChangeRepo(deductionPlanChangeRepo) // 提前还款与重组
 
-	// 8.1 初始化货款代扣相关Repository和Service
+	// 8.1 初始化货款代扣相关Repository和Service（货款代扣已并入统一代扣计划）
 	goodsDeductionRepo := repository.NewGormGoodsDeductionRepository(db)
-	goodsDeductionDetailRepo := repository.NewGormGoodsDeductionDetailRepository(db)
 	goodsDeductionTerminalRepo := repository.NewGormGoodsDeductionTerminalRepository(db)
 	goodsDeductionNotificationRepo := repository.NewGormGoodsDeductionNotificationRepository(db)
+	goodsDeductionMigrationRepo := repository.NewGormGoodsDeductionMigrationRepository(db)
 
 	goodsDeductionService := service.NewGoodsDeductionService(
 		goodsDeductionRepo,
-		goodsDeductionDetailRepo,
 		goodsDeductionTerminalRepo,
 		goodsDeductionNotificationRepo,
-		walletRepo,
-		walletLogRepo,
+		goodsDeductionMigrationRepo,
 		agentRepo,
+		deductionService,
 	)
 
-	// 8.2 将货款代扣服务注入到分润服务（延迟注入，避免循环依赖）
-	profitService.SetGoodsDeductionService(goodsDeductionService)
+	// 8.2 货款代扣迁移服务（历史货款代扣转换为代扣计划）
+	goodsDeductionMigrationService := service.NewGoodsDeductionMigrationService(goodsDeductionMigrationRepo, walletRepo, deductionService)
 
 	// 9. 初始化终端下发相关Repository和Service
 	terminalDistributeRepo := repository.NewGormTerminalDistributeRepository(db)
@@ -183,6 +191,7 @@ func main() {
 		terminalRepo,
 		terminalDistributeRepo,
 		agentRepo,
+		hierarchyRepo,
 		deductionService,
 	)
 
@@ -194,6 +203,7 @@ func main() {
 		terminalRecallRepo,
 		terminalImportRecordRepo,
 		agentRepo,
+		hierarchyRepo,
 	)
 
 	// 9.2 初始化终端类型服务（延迟到channelRepo初始化后）
@@ -233,7 +243,7 @@ func main() {
 	authService := service.NewAuthService(authConfig, userRepo, refreshTokenRepo, loginLogRepo, agentRepo)
 
 	// 14. 初始化PC端新增Service
-	agentService := service.NewAgentService(agentRepo, agentPolicyRepo, walletRepo, transactionRepo, profitRepo)
+	agentService := service.NewAgentService(agentRepo, hierarchyRepo, agentPolicyRepo, walletRepo, transactionRepo, profitRepo)
 	walletService := service.NewWalletService(walletRepo, walletLogRepo, agentRepo)
 
 	// 15. 初始化PC端新增Handler
@@ -396,6 +406,7 @@ func main() {
 		agentRepo,
 	)
 	walletAdjustmentHandler := handler.NewWalletAdjustmentHandler(walletAdjustmentService)
+	walletAdjustmentHandler.SetAuditService(auditService) // 注入审计服务（三级等保）
 
 	// 注入拆分配置仓库到钱包服务（用于展示逻辑）
 	walletService.SetSplitConfigRepo(walletSplitConfigRepo)
@@ -411,6 +422,7 @@ func main() {
 	taxChannelRepo := repository.NewGormTaxChannelRepository(db)
 	taxChannelService := service.NewTaxChannelService(taxChannelRepo)
 	taxChannelHandler := handler.NewTaxChannelHandler(taxChannelService)
+	withdrawService := service.NewWithdrawService(withdrawRepo, walletRepo, walletLogRepo, agentRepo, taxChannelRepo)
 
 	// 20.4.1 初始化通道服务（费率类型动态化）
 	channelService := service.NewChannelService(channelRepo, channelConfigRepo)
@@ -518,6 +530,178 @@ func main() {
 	// 添加depositCashbackService到定时任务
 	_ = depositCashbackService // 将在后续定时任务中使用
 
+	// 21.5 初始化钱包划转服务（上下级代理商间划转）
+	walletTransferRepo := repository.NewGormWalletTransferRepository(db)
+	walletTransferService := service.NewWalletTransferService(
+		walletTransferRepo,
+		walletRepo,
+		walletLogRepo,
+		agentRepo,
+		hierarchyRepo,
+	)
+	walletTransferService.SetMessageService(messageService)
+	walletTransferHandler := handler.NewWalletTransferHandler(walletTransferService)
+	walletTransferHandler.SetAuditService(auditService) // 注入审计服务（三级等保）
+
+	// 21.6 初始化钱包风控冻结服务（冻结期间禁止提现、划转及使用沉淀款）
+	walletRiskHoldRepo := repository.NewGormWalletRiskHoldRepository(db)
+	walletRiskHoldService := service.NewWalletRiskHoldService(walletRiskHoldRepo, walletRepo, agentRepo)
+	walletRiskHoldService.SetMessageService(messageService)
+	walletService.SetRiskHoldService(walletRiskHoldService)
+	withdrawService.SetRiskHoldService(walletRiskHoldService)
+	settlementWalletService.SetRiskHoldService(walletRiskHoldService)
+	walletTransferService.SetRiskHoldService(walletRiskHoldService)
+	deductionService.SetRiskHoldService(walletRiskHoldService)
+	walletRiskHoldHandler := handler.NewWalletRiskHoldHandler(walletRiskHoldService)
+	walletRiskHoldHandler.SetAuditService(auditService) // 注入审计服务（三级等保）
+
+	// 21.7 初始化钱包余额一致性检查服务（回放流水定位分歧，生成修正调账建议）
+	walletBalanceCheckRepo := repository.NewGormWalletBalanceCheckRepository(db)
+	walletConsistencyService := service.NewWalletConsistencyService(walletBalanceCheckRepo, walletAdjustmentService, agentRepo)
+	walletBalanceCheckHandler := handler.NewWalletBalanceCheckHandler(walletConsistencyService)
+	walletBalanceCheckHandler.SetAuditService(auditService) // 注入审计服务（三级等保）
+
+	// 21.8 初始化代理商月度对账单服务
+	agentStatementRepo := repository.NewGormAgentStatementRepository(db)
+	agentStatementService := service.NewAgentStatementService(agentStatementRepo, agentRepo, channelRepo)
+	agentStatementService.SetMessageService(messageService)
+	agentStatementHandler := handler.NewAgentStatementHandler(agentStatementService)
+
+	// 21.9 初始化代扣逾期服务（补扣、滞纳金、通知、上报上级、冻结出款）
+	deductionOverdueRepo := repository.NewGormDeductionOverdueRepository(db)
+	deductionOverdueService := service.NewDeductionOverdueService(deductionOverdueRepo, deductionPlanRepo, agentRepo, deductionService)
+	deductionOverdueService.SetMessageService(messageService)
+	deductionOverdueService.SetRiskHoldService(walletRiskHoldService)
+	deductionOverdueHandler := handler.NewDeductionOverdueHandler(deductionOverdueService)
+
+	// 21.10 货款代扣迁移（试运行、迁移、对账）
+	goodsDeductionMigrationHandler := handler.NewGoodsDeductionMigrationHandler(goodsDeductionMigrationService)
+
+	// 21.11 终端生命周期状态机（入库、下发、绑定、激活、解绑、回拨统一流转并记录历史）
+	terminalStatusHistoryRepo := repository.NewGormTerminalStatusHistoryRepository(db)
+	terminalLifecycleService := service.NewTerminalLifecycleService(terminalStatusHistoryRepo)
+	callbackProcessor.SetLifecycleService(terminalLifecycleService)
+	terminalDistributeService.SetLifecycleService(terminalLifecycleService)
+	terminalService.SetLifecycleService(terminalLifecycleService)
+
+	// 21.12 终端批量上传（XLSX/CSV入库、下发、回拨，大文件转后台任务）
+	terminalBatchJobRepo := repository.NewGormTerminalBatchJobRepository(db)
+	terminalBatchService := service.NewTerminalBatchService(
+		terminalBatchJobRepo,
+		terminalRepo,
+		terminalTypeRepo,
+		agentRepo,
+		terminalService,
+		terminalDistributeService,
+	)
+	terminalBatchHandler := handler.NewTerminalBatchHandler(terminalBatchService)
+
+	// 21.13 终端库存报表（库存账龄、下级动销、呆滞库存预警）
+	inventoryReportRepo := repository.NewGormInventoryReportRepository(db)
+	inventoryReportService := service.NewInventoryReportService(inventoryReportRepo, agentRepo, hierarchyRepo)
+	inventoryReportService.SetMessageService(messageService)
+	inventoryReportService.SetAlertService(alertService)
+	inventoryReportHandler := handler.NewInventoryReportHandler(inventoryReportService)
+
+	// 21.14 终端回拨结算（冲减/退还货款、追回激活奖励与押金返现）
+	terminalRecallSettlementRepo := repository.NewGormTerminalRecallSettlementRepository(db)
+	terminalRecallSettlementService := service.NewTerminalRecallSettlementService(terminalRecallSettlementRepo, deductionService)
+	terminalRecallSettlementService.SetRiskHoldService(walletRiskHoldService)
+	terminalService.SetRecallSettlementService(terminalRecallSettlementService)
+	terminalRecallSettlementHandler := handler.NewTerminalRecallSettlementHandler(terminalRecallSettlementService)
+
+	// 21.15 终端标签打印（条码/二维码标签、扫码反查）
+	terminalLabelConfig := service.DefaultTerminalLabelConfig()
+	if config.LabelVerifyURL != "" {
+		terminalLabelConfig.VerifyBaseURL = config.LabelVerifyURL
+	}
+	if config.LabelSecret != "" {
+		terminalLabelConfig.Secret = config.LabelSecret
+	}
+	terminalLabelService := service.NewTerminalLabelService(terminalRepo, terminalImportRecordRepo, terminalStatusHistoryRepo, agentRepo, hierarchyRepo, terminalLabelConfig)
+	terminalLabelHandler := handler.NewTerminalLabelHandler(terminalLabelService)
+
+	// 21.16 终端奖励进度自动化（绑定/激活按代理商、通道匹配模版开启进度，解绑/回拨终止，存量补建）
+	rewardProgressRuleRepo := repository.NewGormRewardProgressRuleRepository(db)
+	terminalRewardLifecycleService := service.NewTerminalRewardLifecycleService(rewardProgressRuleRepo, terminalRepo, agentRepo, hierarchyRepo, rewardService)
+	callbackProcessor.SetRewardLifecycleService(terminalRewardLifecycleService)
+	terminalDistributeService.SetRewardLifecycleService(terminalRewardLifecycleService)
+	terminalService.SetRewardLifecycleService(terminalRewardLifecycleService)
+	terminalRewardLifecycleHandler := handler.NewTerminalRewardLifecycleHandler(terminalRewardLifecycleService)
+
+	// 21.17 流量卡管理（ICCID库存、装卡/换卡记录、续费预测与提醒）
+	simCardRepo := repository.NewGormSimCardRepository(db)
+	simCardService := service.NewSimCardService(simCardRepo, terminalRepo, agentRepo, hierarchyRepo)
+	simCardService.SetMessageService(messageService)
+	simCashbackService.SetSimCardService(simCardService)
+	simCardHandler := handler.NewSimCardHandler(simCardService)
+
+	// 21.18 商户分类规则（按通道/一级代理商配置分类规则集，分类变更历史及预览）
+	merchantClassRepo := repository.NewGormMerchantClassRepository(db)
+	merchantClassService := service.NewMerchantClassService(merchantClassRepo, agentRepo)
+	merchantService.SetClassService(merchantClassService)
+	merchantClassHandler := handler.NewMerchantClassHandler(merchantClassService)
+
+	// 21.19 商户流失预警（交易下滑识别、代理商跟进任务、挽回率报表）
+	churnWatchRepo := repository.NewGormChurnWatchRepository(db)
+	churnWatchService := service.NewChurnWatchService(churnWatchRepo, agentRepo, hierarchyRepo)
+	churnWatchService.SetMessageService(messageService)
+	churnWatchHandler := handler.NewChurnWatchHandler(churnWatchService)
+
+	// 21.20 交易风控（套现识别、风控事件审核、高分事件冻结分润）
+	txRiskRepo := repository.NewGormTxRiskRepository(db)
+	txRiskService := service.NewTxRiskService(txRiskRepo, transactionRepo, profitRepo, walletRepo, walletRiskHoldService)
+	profitService.SetTxRiskService(txRiskService)
+	txRiskHandler := handler.NewTxRiskHandler(txRiskService)
+
+	// 21.21 商户费率修改申请（政策/通道限制校验、上级审批、通道同步失败自动重试、费率不一致报表）
+	merchantRateChangeRepo := repository.NewGormMerchantRateChangeRepository(db)
+	rateChangeService := service.NewMerchantRateChangeService(merchantRateChangeRepo, merchantRepo, agentRepo, hierarchyRepo, channelRepo, channelConfigRepo, rateSyncService)
+	rateChangeService.SetMessageService(messageService)
+	merchantService.SetRateChangeService(rateChangeService)
+	rateChangeHandler := handler.NewMerchantRateChangeHandler(rateChangeService)
+
+	// 21.22 商户费率计划（限时优惠到期自动恢复、阶梯调整、调整前提醒）
+	ratePlanRepo := repository.NewGormMerchantRatePlanRepository(db)
+	ratePlanService := service.NewMerchantRatePlanService(ratePlanRepo, merchantRateChangeRepo, merchantRepo, agentRepo, hierarchyRepo, rateChangeService)
+	ratePlanService.SetMessageService(messageService)
+	merchantService.SetRatePlanService(ratePlanService)
+	ratePlanHandler := handler.NewMerchantRatePlanHandler(ratePlanService)
+
+	// 21.23 商户证件到期（法人身份证有效期提醒、到期看板）
+	kycExpiryRepo := repository.NewGormKycExpiryRepository(db)
+	kycExpiryService := service.NewKycExpiryService(kycExpiryRepo, agentRepo)
+	kycExpiryService.SetMessageService(messageService)
+	kycExpiryHandler := handler.NewKycExpiryHandler(kycExpiryService)
+
+	// 21.24 商户自助服务（短信验证码登录、查询本商户交易/日结/费率/终端、结算卡变更申请）
+	smsProvider, err := sms.NewProvider(config.SMSProvider)
+	if errors.Is(err, sms.ErrProviderNotConfigured) {
+		log.Printf("Warning: SMS_PROVIDER not set, merchant portal login codes will not be sent")
+		smsProvider = sms.DisabledProvider{}
+	} else if err != nil {
+		log.Fatalf("Failed to init sms provider: %v", err)
+	}
+	merchantPortalRepo := repository.NewGormMerchantPortalRepository(db)
+	merchantPortalService := service.NewMerchantPortalService(service.DefaultMerchantPortalConfig(authConfig), merchantPortalRepo, merchantRepo, transactionRepo, agentRepo, hierarchyRepo, smsProvider)
+	merchantPortalService.SetMessageService(messageService)
+	ratePlanService.SetMerchantNotifier(merchantPortalService)
+	merchantPortalHandler := handler.NewMerchantPortalHandler(merchantPortalService)
+
+	// 21.25 商户进件（通过支持进件的通道适配器提交，审核结果由状态同步任务和merc_income回调对账）
+	merchantApplicationRepo := repository.NewGormMerchantApplicationRepository(db)
+	onboardingService := service.NewMerchantOnboardingService(merchantApplicationRepo, merchantRepo, agentRepo, hierarchyRepo, channelRepo, terminalRepo, uploadedFileRepo, factory)
+	onboardingService.SetRateChangeService(rateChangeService)
+	onboardingService.SetMessageService(messageService)
+	callbackProcessor.SetOnboardingService(onboardingService)
+	merchantApplicationHandler := handler.NewMerchantApplicationHandler(onboardingService)
+
+	// 21.26 代理商迁移（连同下级树挂到新上级，校验结算价/政策并记录迁移）
+	agentMoveRepo := repository.NewGormAgentMoveRepository(db)
+	agentMoveService := service.NewAgentMoveService(agentRepo, hierarchyRepo, agentMoveRepo, settlementPriceRepo, agentPolicyRepo)
+	agentMoveHandler := handler.NewAgentMoveHandler(agentMoveService)
+	agentMoveHandler.SetAuditService(auditService)
+
 	// 22. 初始化监控服务
 	metricsService := service.NewMetricsService(messageService, memQueue)
 
@@ -537,6 +721,32 @@ func main() {
 		deviceFeeRepo,
 		// 新增参数：奖励模块
 		rewardService,
+		// 新增参数：钱包划转
+		walletTransferService,
+		// 新增参数：钱包风控冻结
+		walletRiskHoldService,
+		// 新增参数：钱包余额一致性检查
+		walletConsistencyService, alertService,
+		// 新增参数：月度对账单
+		agentStatementService,
+		// 新增参数：代扣逾期
+		deductionOverdueService,
+		// 新增参数：终端批量任务
+		terminalBatchService,
+		// 新增参数：呆滞库存预警
+		inventoryReportService,
+		// 新增参数：流量卡续费提醒
+		simCardService,
+		// 新增参数：商户流失预警
+		churnWatchService,
+		// 新增参数：费率同步重试
+		rateChangeService,
+		// 新增参数：商户费率计划
+		ratePlanService,
+		// 新增参数：商户证件到期
+		kycExpiryService,
+		// 新增参数：商户进件
+		onboardingService,
 	)
 	scheduler.Start()
 
@@ -562,6 +772,27 @@ func main() {
 		terminalTypeHandler, // 新增：终端类型Handler
 		depositTierHandler, // 新增：押金档位Handler
 		channelConfigHandler, // 新增：通道配置Handler
+		walletTransferHandler, // 新增：钱包划转Handler
+		walletRiskHoldHandler, // 新增：钱包风控冻结Handler
+		walletBalanceCheckHandler, // 新增：钱包余额检查Handler
+		agentStatementHandler, // 新增：对账单Handler
+		deductionOverdueHandler, // 新增：代扣逾期Handler
+		goodsDeductionMigrationHandler, // 新增：货款代扣迁移Handler
+		terminalBatchHandler, // 新增：终端批量上传Handler
+		inventoryReportHandler, // 新增：终端库存报表Handler
+		terminalRecallSettlementHandler, // 新增：终端回拨结算Handler
+		terminalLabelHandler, // 新增：终端标签Handler
+		terminalRewardLifecycleHandler, // 新增：终端奖励进度自动化Handler
+		simCardHandler, // 新增：流量卡管理Handler
+		merchantClassHandler, // 新增：商户分类规则Handler
+		churnWatchHandler, // 新增：商户流失预警Handler
+		txRiskHandler, // 新增：交易风控Handler
+		rateChangeHandler, // 新增：商户费率修改申请Handler
+		ratePlanHandler, // 新增：商户费率计划Handler
+		kycExpiryHandler, // 新增：商户证件到期Handler
+		merchantPortalHandler, merchantPortalService, // 新增：商户自助服务
+		merchantApplicationHandler, // 新增：商户进件Handler
+		agentMoveHandler, // 新增：代理商迁移Handler
 		authService, metricsService, config.SwaggerEnabled,
 	)
 
@@ -614,6 +845,9 @@ func loadConfig() *Config {
 		HxtPublicKey:    os.Getenv("HENGXINTONG_PUBLIC_KEY"),
 		AlertWebhookURL: os.Getenv("ALERT_WEBHOOK_URL"),
 		SwaggerEnabled:  os.Getenv("SWAGGER_ENABLED") != "false", // 默认启用，生产环境设为false关闭
+		LabelVerifyURL:  os.Getenv("TERMINAL_LABEL_VERIFY_URL"),
+		LabelSecret:     os.Getenv("TERMINAL_LABEL_SECRET"),
+		SMSProvider:     os.Getenv("SMS_PROVIDER"),
 	}
 
 	// 默认值
@@ -722,6 +956,33 @@ func setupScheduler(
 	deviceFeeRepo *repository.GormDeviceFeeRepository,
 	// 新增参数：奖励模块
 	rewardService *service.RewardService,
+	// 新增参数：钱包划转
+	walletTransferService *service.WalletTransferService,
+	// 新增参数：钱包风控冻结
+	walletRiskHoldService *service.WalletRiskHoldService,
+	// 新增参数：钱包余额一致性检查
+	walletConsistencyService *service.WalletConsistencyService,
+	alertService *service.AlertService,
+	// 新增参数：月度对账单
+	agentStatementService *service.AgentStatementService,
+	// 新增参数：代扣逾期
+	deductionOverdueService *service.DeductionOverdueService,
+	// 新增参数：终端批量任务
+	terminalBatchService *service.TerminalBatchService,
+	// 新增参数：呆滞库存预警
+	inventoryReportService *service.InventoryReportService,
+	// 新增参数：流量卡续费提醒
+	simCardService *service.SimCardService,
+	// 新增参数：商户流失预警
+	churnWatchService *service.ChurnWatchService,
+	// 新增参数：费率同步重试
+	rateChangeService *service.MerchantRateChangeService,
+	// 新增参数：商户费率计划
+	ratePlanService *service.MerchantRatePlanService,
+	// 新增参数：商户证件到期
+	kycExpiryService *service.KycExpiryService,
+	// 新增参数：商户进件
+	onboardingService *service.MerchantOnboardingService,
 ) *jobs.Scheduler {
 	scheduler := jobs.NewScheduler()
 
@@ -784,6 +1045,58 @@ func setupScheduler(
 	statsDailyJob := jobs.NewAgentStatsDailyJob(callbackRepo.GetDB())
 	scheduler.AddJob("agent_stats_daily", 24*time.Hour, statsDailyJob.Run)
 
+	// 待确认划转过期处理（每10分钟）
+	transferExpireJob := jobs.NewWalletTransferExpireJob(walletTransferService)
+	scheduler.AddJob("wallet_transfer_expire", 10*time.Minute, transferExpireJob.Run)
+
+	// 风控冻结到期解除（每10分钟）
+	riskHoldExpireJob := jobs.NewWalletRiskHoldExpireJob(walletRiskHoldService)
+	scheduler.AddJob("wallet_risk_hold_expire", 10*time.Minute, riskHoldExpireJob.Run)
+
+	// 钱包余额一致性检查（每天执行一次）
+	walletBalanceCheckJob := jobs.NewWalletBalanceCheckJob(walletConsistencyService, alertService)
+	scheduler.AddJob("wallet_balance_check", 24*time.Hour, walletBalanceCheckJob.Run)
+
+	// 月度对账单生成（每天检查上月对账单是否已生成）
+	agentStatementJob := jobs.NewAgentStatementJob(agentStatementService)
+	scheduler.AddJob("agent_statement", 24*time.Hour, agentStatementJob.Run)
+
+	// 代扣逾期处理（每天执行一次）
+	deductionOverdueJob := jobs.NewDeductionOverdueJob(deductionOverdueService)
+	scheduler.AddJob("deduction_overdue", 24*time.Hour, deductionOverdueJob.Run)
+
+	// 终端批量任务补处理（每分钟执行）
+	terminalBatchJob := jobs.NewTerminalBatchJob(terminalBatchService)
+	scheduler.AddJob("terminal_batch", 1*time.Minute, terminalBatchJob.Run)
+
+	// 呆滞库存预警（每天执行一次）
+	inventoryAlertJob := jobs.NewInventoryAlertJob(inventoryReportService)
+	scheduler.AddJob("inventory_alert", 24*time.Hour, inventoryAlertJob.Run)
+
+	// 流量卡续费提醒（每天执行一次）
+	simRenewalJob := jobs.NewSimRenewalJob(simCardService)
+	scheduler.AddJob("sim_renewal_reminder", 24*time.Hour, simRenewalJob.Run)
+
+	// 商户流失预警（每天执行一次）
+	churnWatchJob := jobs.NewChurnWatchJob(churnWatchService)
+	scheduler.AddJob("churn_watch", 24*time.Hour, churnWatchJob.Run)
+
+	// 费率同步重试（每分钟）
+	rateSyncRetryJob := jobs.NewRateSyncRetryJob(rateChangeService)
+	scheduler.AddJob("rate_sync_retry", 1*time.Minute, rateSyncRetryJob.Run)
+
+	// 商户费率计划（每10分钟）
+	ratePlanJob := jobs.NewRatePlanJob(ratePlanService)
+	scheduler.AddJob("merchant_rate_plan", 10*time.Minute, ratePlanJob.Run)
+
+	// 商户证件到期提醒（每天执行一次）
+	kycExpiryJob := jobs.NewKycExpiryJob(kycExpiryService)
+	scheduler.AddJob("kyc_expiry_reminder", 24*time.Hour, kycExpiryJob.Run)
+
+	// 商户进件审核状态同步（每10分钟）
+	merchantApplicationSyncJob := jobs.NewMerchantApplicationSyncJob(onboardingService)
+	scheduler.AddJob("merchant_application_sync", 10*time.Minute, merchantApplicationSyncJob.Run)
+
 	return scheduler
 }
 
@@ -829,6 +1142,28 @@ func setupRouter(
 	terminalTypeHandler *handler.TerminalTypeHandler, // 新增：终端类型Handler
 	depositTierHandler *handler.DepositTierHandler, // 新增：押金档位Handler
 	channelConfigHandler *handler.ChannelConfigHandler, // 新增：通道配置Handler
+	walletTransferHandler *handler.WalletTransferHandler, // 新增：钱包划转Handler
+	walletRiskHoldHandler *handler.WalletRiskHoldHandler, // 新增：钱包风控冻结Handler
+	walletBalanceCheckHandler *handler.WalletBalanceCheckHandler, // 新增：钱包余额检查Handler
+	agentStatementHandler *handler.AgentStatementHandler, // 新增：对账单Handler
+	deductionOverdueHandler *handler.DeductionOverdueHandler, // 新增：代扣逾期Handler
+	goodsDeductionMigrationHandler *handler.GoodsDeductionMigrationHandler, // 新增：货款代扣迁移Handler
+	terminalBatchHandler *handler.TerminalBatchHandler, // 新增：终端批量上传Handler
+	inventoryReportHandler *handler.InventoryReportHandler, // 新增：终端库存报表Handler
+	terminalRecallSettlementHandler *handler.TerminalRecallSettlementHandler, // 新增：终端回拨结算Handler
+	terminalLabelHandler *handler.TerminalLabelHandler, // 新增：终端标签Handler
+	terminalRewardLifecycleHandler *handler.TerminalRewardLifecycleHandler, // 新增：终端奖励进度自动化Handler
+	simCardHandler *handler.SimCardHandler, // 新增：流量卡管理Handler
+	merchantClassHandler *handler.MerchantClassHandler, // 新增：商户分类规则Handler
+	churnWatchHandler *handler.ChurnWatchHandler, // 新增：商户流失预警Handler
+	txRiskHandler *handler.TxRiskHandler, // 新增：交易风控Handler
+	rateChangeHandler *handler.MerchantRateChangeHandler, // 新增：商户费率修改申请Handler
+	ratePlanHandler *handler.MerchantRatePlanHandler, // 新增：商户费率计划Handler
+	kycExpiryHandler *handler.KycExpiryHandler, // 新增：商户证件到期Handler
+	merchantPortalHandler *handler.MerchantPortalHandler, // 新增：商户自助服务Handler
+	merchantPortalService *service.MerchantPortalService, // 新增：商户令牌校验
+	merchantApplicationHandler *handler.MerchantApplicationHandler, // 新增：商户进件Handler
+	agentMoveHandler *handler.AgentMoveHandler, // 新增：代理商迁移Handler
 	authService *service.AuthService,
 	metricsService *service.MetricsService,
 	swaggerEnabled bool,
@@ -925,6 +1260,27 @@ func setupRouter(
 		handler.RegisterTaxChannelRoutes(apiV1, taxChannelHandler, authService)
 		handler.RegisterWalletSplitRoutes(apiV1, walletSplitHandler, authService) // 新增：钱包拆分配置路由
 		handler.RegisterWalletAdjustmentRoutes(apiV1, walletAdjustmentHandler, authService) // 新增：钱包调账路由
+		handler.RegisterWalletTransferRoutes(apiV1, walletTransferHandler, authService)     // 新增：钱包划转路由
+		handler.RegisterWalletRiskHoldRoutes(apiV1, walletRiskHoldHandler, authService)     // 新增：钱包风控冻结路由
+		handler.RegisterWalletBalanceCheckRoutes(apiV1, walletBalanceCheckHandler, authService) // 新增：钱包余额检查路由
+		handler.RegisterAgentStatementRoutes(apiV1, agentStatementHandler, authService)         // 新增：对账单路由
+		handler.RegisterDeductionOverdueRoutes(apiV1, deductionOverdueHandler, authService)     // 新增：代扣逾期路由
+		handler.RegisterGoodsDeductionMigrationRoutes(apiV1, goodsDeductionMigrationHandler, authService) // 新增：货款代扣迁移路由
+		handler.RegisterTerminalBatchRoutes(apiV1, terminalBatchHandler, authService) // 新增：终端批量上传路由
+		handler.RegisterInventoryReportRoutes(apiV1, inventoryReportHandler, authService) // 新增：终端库存报表路由
+		handler.RegisterTerminalRecallSettlementRoutes(apiV1, terminalRecallSettlementHandler, authService) // 新增：终端回拨结算路由
+		handler.RegisterTerminalLabelRoutes(apiV1, terminalLabelHandler, authService) // 新增：终端标签路由
+		handler.RegisterTerminalRewardLifecycleRoutes(apiV1, terminalRewardLifecycleHandler, authService) // 新增：终端奖励进度自动化路由
+		handler.RegisterSimCardRoutes(apiV1, simCardHandler, authService) // 新增：流量卡管理路由
+		handler.RegisterMerchantClassRoutes(apiV1, merchantClassHandler, authService) // 新增：商户分类规则路由
+		handler.RegisterChurnWatchRoutes(apiV1, churnWatchHandler, authService) // 新增：商户流失预警路由
+		handler.RegisterTxRiskRoutes(apiV1, txRiskHandler, authService) // 新增：交易风控路由
+		handler.RegisterMerchantRateChangeRoutes(apiV1, rateChangeHandler, authService) // 新增：商户费率修改申请路由
+		handler.RegisterMerchantRatePlanRoutes(apiV1, ratePlanHandler, authService) // 新增：商户费率计划路由
+		handler.RegisterKycExpiryRoutes(apiV1, kycExpiryHandler, authService) // 新增：商户证件到期路由
+		handler.RegisterMerchantPortalRoutes(apiV1, merchantPortalHandler, merchantPortalService, authService) // 新增：商户自助服务路由
+		handler.RegisterMerchantApplicationRoutes(apiV1, merchantApplicationHandler, authService) // 新增：商户进件路由
+		handler.RegisterAgentMoveRoutes(apiV1, agentMoveHandler, authService) // 新增：代理商迁移路由
 
 		// 注册全局提现门槛路由
 		thresholdGroup := apiV1.Group("/withdraw-thresholds")
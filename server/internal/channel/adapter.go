@@ -155,6 +155,7 @@ type UnifiedDeviceFee struct {
 	FeeType      int       `json:"fee_type"`      // 1-服务费 2-流量费/通讯费
 	FeeAmount    int64     `json:"fee_amount"`    // 扣费金额（分）
 	ChargingTime time.Time `json:"charging_time"` // 扣款时间
+	ICCID        string    `json:"iccid"`         // 流量卡ICCID（通道回传时）
 
 	// 扩展字段
 	ExtData map[string]interface{} `json:"ext_data"`
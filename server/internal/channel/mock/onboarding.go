@@ -0,0 +1,161 @@
+// Package mock 通道适配器的测试替身
+package mock
+
+import (
+	"errors"
+	"sync"
+
+	"xiangshoufu/internal/channel"
+)
+
+// ChannelCodeMock 模拟通道编码
+const ChannelCodeMock = "MOCK"
+
+// errNotSupported 模拟通道不处理回调
+var errNotSupported = errors.New("mock adapter does not parse callbacks")
+
+// OnboardingAdapter 支持进件的模拟通道
+// 提交默认受理并进入审核中，通过SetResult模拟通道审核结果；SubmitErr不为空时提交返回该错误
+type OnboardingAdapter struct {
+	ChannelCode string
+	SubmitErr   error
+
+	mu        sync.Mutex
+	submitted []*channel.MerchantApplicationRequest
+	results   map[string]*channel.MerchantApplicationResponse // channelApplyNo -> 审核结果
+	reject    map[string]string                               // applyNo -> 受理时拒绝原因
+}
+
+// NewOnboardingAdapter 创建模拟通道
+func NewOnboardingAdapter() *OnboardingAdapter {
+	return &OnboardingAdapter{
+		ChannelCode: ChannelCodeMock,
+		results:     make(map[string]*channel.MerchantApplicationResponse),
+		reject:      make(map[string]string),
+	}
+}
+
+// GetChannelCode 获取通道编码
+func (a *OnboardingAdapter) GetChannelCode() string { return a.ChannelCode }
+
+// GetChannelName 获取通道名称
+func (a *OnboardingAdapter) GetChannelName() string { return "模拟通道" }
+
+// VerifySign 验证签名
+func (a *OnboardingAdapter) VerifySign(rawBody []byte) (bool, error) { return true, nil }
+
+// ParseActionType 解析回调类型
+func (a *OnboardingAdapter) ParseActionType(rawBody []byte) (channel.ActionType, error) {
+	return "", errNotSupported
+}
+
+// ParseIdempotentKey 生成幂等键
+func (a *OnboardingAdapter) ParseIdempotentKey(rawBody []byte) (string, error) {
+	return "", errNotSupported
+}
+
+// ParseMerchantIncome 解析商户入网回调
+func (a *OnboardingAdapter) ParseMerchantIncome(rawBody []byte) (*channel.UnifiedMerchantIncome, error) {
+	return nil, errNotSupported
+}
+
+// ParseTerminalBind 解析终端绑定/解绑回调
+func (a *OnboardingAdapter) ParseTerminalBind(rawBody []byte) (*channel.UnifiedTerminalBind, error) {
+	return nil, errNotSupported
+}
+
+// ParseDeviceFee 解析流量费/服务费回调
+func (a *OnboardingAdapter) ParseDeviceFee(rawBody []byte) (*channel.UnifiedDeviceFee, error) {
+	return nil, errNotSupported
+}
+
+// ParseTransaction 解析交易回调
+func (a *OnboardingAdapter) ParseTransaction(rawBody []byte) (*channel.UnifiedTransaction, error) {
+	return nil, errNotSupported
+}
+
+// ParseRateChange 解析费率变更回调
+func (a *OnboardingAdapter) ParseRateChange(rawBody []byte) (*channel.UnifiedRateChange, error) {
+	return nil, errNotSupported
+}
+
+// UpdateMerchantRate 更新商户费率
+func (a *OnboardingAdapter) UpdateMerchantRate(req *channel.RateUpdateRequest) (*channel.RateUpdateResponse, error) {
+	return &channel.RateUpdateResponse{Success: true, ChannelCode: a.ChannelCode}, nil
+}
+
+// SupportsRateUpdate 是否支持费率实时更新
+func (a *OnboardingAdapter) SupportsRateUpdate() bool { return true }
+
+// RejectOnSubmit 提交applyNo时不受理（模拟资料校验不通过）
+func (a *OnboardingAdapter) RejectOnSubmit(applyNo, message string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reject[applyNo] = message
+}
+
+// SubmitMerchantApplication 提交进件申请，受理号为"MOCK-"+申请单号
+func (a *OnboardingAdapter) SubmitMerchantApplication(req *channel.MerchantApplicationRequest) (*channel.MerchantApplicationResponse, error) {
+	if a.SubmitErr != nil {
+		return nil, a.SubmitErr
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.submitted = append(a.submitted, req)
+	if message, ok := a.reject[req.ApplyNo]; ok {
+		return &channel.MerchantApplicationResponse{
+			Accepted:    false,
+			ChannelCode: a.ChannelCode,
+			Status:      channel.ApplicationRejected,
+			Message:     message,
+		}, nil
+	}
+	return &channel.MerchantApplicationResponse{
+		Accepted:       true,
+		ChannelCode:    a.ChannelCode,
+		ChannelApplyNo: "MOCK-" + req.ApplyNo,
+		Status:         channel.ApplicationReviewing,
+		Message:        "受理成功",
+	}, nil
+}
+
+// QueryMerchantApplication 查询进件审核状态，未设置结果时为审核中
+func (a *OnboardingAdapter) QueryMerchantApplication(channelApplyNo string) (*channel.MerchantApplicationResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if result, ok := a.results[channelApplyNo]; ok {
+		copied := *result
+		return &copied, nil
+	}
+	return &channel.MerchantApplicationResponse{
+		Accepted:       true,
+		ChannelCode:    a.ChannelCode,
+		ChannelApplyNo: channelApplyNo,
+		Status:         channel.ApplicationReviewing,
+	}, nil
+}
+
+// SetResult 设置审核结果
+func (a *OnboardingAdapter) SetResult(channelApplyNo string, status channel.ApplicationStatus, merchantNo, message string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.results[channelApplyNo] = &channel.MerchantApplicationResponse{
+		Accepted:       true,
+		ChannelCode:    a.ChannelCode,
+		ChannelApplyNo: channelApplyNo,
+		MerchantNo:     merchantNo,
+		Status:         status,
+		Message:        message,
+	}
+}
+
+// Submitted 已提交的进件申请
+func (a *OnboardingAdapter) Submitted() []*channel.MerchantApplicationRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]*channel.MerchantApplicationRequest(nil), a.submitted...)
+}
+
+// 确保实现了接口
+var _ channel.MerchantOnboardingAdapter = (*OnboardingAdapter)(nil)
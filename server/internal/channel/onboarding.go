@@ -0,0 +1,86 @@
+package channel
+
+// MerchantOnboardingAdapter 商户进件适配器接口（可选实现）
+// 支持线上进件的通道实现此接口，未实现的通道只能在通道自有APP进件，由merc_income回调同步
+type MerchantOnboardingAdapter interface {
+	ChannelAdapter
+
+	// SubmitMerchantApplication 提交进件申请
+	// 返回: 通道受理结果（通道受理号、审核状态）, 错误信息（网络异常等，可重试）
+	SubmitMerchantApplication(req *MerchantApplicationRequest) (*MerchantApplicationResponse, error)
+
+	// QueryMerchantApplication 查询进件审核状态
+	// channelApplyNo: 提交时通道返回的受理号
+	QueryMerchantApplication(channelApplyNo string) (*MerchantApplicationResponse, error)
+}
+
+// ApplicationStatus 通道进件审核状态
+type ApplicationStatus string
+
+const (
+	ApplicationReviewing ApplicationStatus = "reviewing" // 审核中
+	ApplicationApproved  ApplicationStatus = "approved"  // 审核通过
+	ApplicationRejected  ApplicationStatus = "rejected"  // 审核失败
+)
+
+// 进件资料图片类型
+const (
+	DocIDCardFront     = "id_card_front"    // 法人身份证人像面
+	DocIDCardBack      = "id_card_back"     // 法人身份证国徽面
+	DocSettleCard      = "settle_card"      // 结算卡正面
+	DocHandHeldIDCard  = "hand_held_id"     // 手持身份证
+	DocBusinessLicense = "business_license" // 营业执照
+	DocStoreFront      = "store_front"      // 门头照
+	DocStoreInside     = "store_inside"     // 经营场所内景
+)
+
+// MerchantDocument 进件资料图片
+type MerchantDocument struct {
+	Type     string `json:"type"`      // 资料类型，见Doc*常量
+	FileURL  string `json:"file_url"`  // 图片访问地址（由UploadService上传）
+	FileName string `json:"file_name"` // 原始文件名
+	MimeType string `json:"mime_type"` // MIME类型
+}
+
+// MerchantApplicationRequest 进件申请请求
+type MerchantApplicationRequest struct {
+	ApplyNo    string `json:"apply_no"`    // 本系统申请单号（幂等键）
+	TerminalSN string `json:"terminal_sn"` // 机具SN号
+	BrandCode  string `json:"brand_code"`  // 品牌编号
+
+	// 法人信息
+	LegalName       string `json:"legal_name"`         // 法人姓名
+	LegalIDCard     string `json:"legal_id_card"`      // 法人身份证号
+	LegalPhone      string `json:"legal_phone"`        // 法人手机号
+	IDCardStartDate string `json:"id_card_start_date"` // 身份证有效期开始（yyyy-mm-dd）
+	IDCardEndDate   string `json:"id_card_end_date"`   // 身份证有效期结束（yyyy-mm-dd或"长期"）
+
+	// 结算信息
+	SettleAccountName string `json:"settle_account_name"` // 开户名
+	SettleCardNo      string `json:"settle_card_no"`      // 结算卡号
+	SettleBankName    string `json:"settle_bank_name"`    // 开户行
+	SettleBranchName  string `json:"settle_branch_name"`  // 开户支行
+
+	// 经营信息
+	MerchantName string `json:"merchant_name"` // 商户名称
+	MCC          string `json:"mcc"`           // MCC码
+	DistrictCode string `json:"district_code"` // 经营地区
+	Address      string `json:"address"`       // 详细地址
+
+	// 费率（小数形式，如0.006表示0.6%）
+	CreditRate float64 `json:"credit_rate"` // 贷记卡费率
+	DebitRate  float64 `json:"debit_rate"`  // 借记卡费率
+
+	// 资料图片
+	Documents []MerchantDocument `json:"documents"`
+}
+
+// MerchantApplicationResponse 进件申请结果
+type MerchantApplicationResponse struct {
+	Accepted       bool              `json:"accepted"`         // 通道是否受理（资料校验不通过时为false）
+	ChannelCode    string            `json:"channel_code"`     // 通道编码
+	ChannelApplyNo string            `json:"channel_apply_no"` // 通道受理号
+	MerchantNo     string            `json:"merchant_no"`      // 通道商户号（审核通过后返回，部分通道受理时即返回）
+	Status         ApplicationStatus `json:"status"`           // 审核状态
+	Message        string            `json:"message"`          // 返回消息/驳回原因
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentMoveHandler 代理商迁移处理器
+type AgentMoveHandler struct {
+	moveService  *service.AgentMoveService
+	auditService *service.AuditService
+}
+
+// NewAgentMoveHandler 创建代理商迁移处理器
+func NewAgentMoveHandler(moveService *service.AgentMoveService) *AgentMoveHandler {
+	return &AgentMoveHandler{
+		moveService: moveService,
+	}
+}
+
+// SetAuditService 设置审计服务
+func (h *AgentMoveHandler) SetAuditService(auditService *service.AuditService) {
+	h.auditService = auditService
+}
+
+// MoveAgent 迁移代理商
+// @Summary 迁移代理商及其下级到新上级
+// @Description 仅平台可操作。dry_run=true时仅预览影响范围和冲突；存在须先处理的冲突（结算价/政策优于新上级、跨越边界的待确认下发）时不执行并返回冲突列表；跨越边界的代扣计划/代扣链按原计划继续执行，仅作提示
+// @Tags 代理商迁移
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "代理商ID"
+// @Param request body service.MoveAgentRequest true "迁移信息"
+// @Success 200 {object} service.AgentMoveResult
+// @Router /api/v1/agents/{id}/move [post]
+func (h *AgentMoveHandler) MoveAgent(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		response.Forbidden(c, "仅平台可迁移代理商")
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的代理商ID")
+		return
+	}
+
+	var req service.MoveAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.AgentID = id
+
+	operator := &service.Operator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: true,
+	}
+	result, err := h.moveService.MoveAgent(&req, operator)
+	if errors.Is(err, service.ErrAgentMoveConflict) {
+		response.ErrorWithData(c, http.StatusBadRequest, err.Error(), result)
+		return
+	}
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.DryRun {
+		response.Success(c, result)
+		return
+	}
+
+	// 记录迁移审计日志
+	if h.auditService != nil {
+		auditCtx := service.NewAuditContextFromGin(c)
+		h.auditService.LogGeneric(auditCtx, models.AuditLogTypeAgentMove, models.AuditLogLevelWarning,
+			"agent", result.AgentID, result.AgentName, "move_agent",
+			"迁移代理商到"+result.ToParentName+"名下: "+result.AgentName,
+			map[string]interface{}{"parent_id": result.FromParentID, "path": result.OldPath, "level": result.OldLevel},
+			map[string]interface{}{"parent_id": result.ToParentID, "path": result.NewPath, "level": result.NewLevel, "subtree_size": result.SubtreeSize},
+			true, "")
+	}
+
+	response.SuccessWithMessage(c, result, "迁移成功")
+}
+
+// ListMoves 迁移记录
+// @Summary 代理商迁移记录
+// @Tags 代理商迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} models.AgentMove
+// @Router /api/v1/agent-moves [get]
+func (h *AgentMoveHandler) ListMoves(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		response.Forbidden(c, "仅平台可查看迁移记录")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	list, total, err := h.moveService.ListMoves(&repository.AgentMoveFilter{AgentID: agentID}, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// RegisterAgentMoveRoutes 注册代理商迁移路由
+func RegisterAgentMoveRoutes(r *gin.RouterGroup, h *AgentMoveHandler, authService *service.AuthService) {
+	agents := r.Group("/agents")
+	agents.Use(middleware.AuthMiddleware(authService))
+	{
+		agents.POST("/:id/move", h.MoveAgent)
+	}
+
+	moves := r.Group("/agent-moves")
+	moves.Use(middleware.AuthMiddleware(authService))
+	{
+		moves.GET("", h.ListMoves)
+	}
+}
@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AgentStatementHandler 代理商对账单处理器
+type AgentStatementHandler struct {
+	statementService *service.AgentStatementService
+}
+
+// NewAgentStatementHandler 创建对账单处理器
+func NewAgentStatementHandler(statementService *service.AgentStatementService) *AgentStatementHandler {
+	return &AgentStatementHandler{
+		statementService: statementService,
+	}
+}
+
+// GenerateStatementRequest 生成对账单请求
+type GenerateStatementRequest struct {
+	Period    string `json:"period" binding:"required"` // 账期 YYYY-MM
+	AgentID   int64  `json:"agent_id"`                  // 为0时生成全部代理商
+	ChannelID int64  `json:"channel_id"`
+}
+
+// DisputeStatementRequest 对账单异议请求
+type DisputeStatementRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReplyStatementRequest 异议答复请求
+type ReplyStatementRequest struct {
+	Reply string `json:"reply" binding:"required"`
+}
+
+// scopeAgentID 管理员可查看全部对账单，代理商仅可查看自己的
+func (h *AgentStatementHandler) scopeAgentID(c *gin.Context) int64 {
+	if middleware.IsAdmin(c) {
+		return 0
+	}
+	return middleware.GetCurrentAgentID(c)
+}
+
+// parseListParams 解析列表查询参数
+func (h *AgentStatementHandler) parseListParams(c *gin.Context) *service.StatementListParams {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	params := &service.StatementListParams{
+		Period:   c.Query("period"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if channelIDStr := c.Query("channel_id"); channelIDStr != "" {
+		channelID, _ := strconv.ParseInt(channelIDStr, 10, 64)
+		params.ChannelID = &channelID
+	}
+	if statusStr := c.Query("status"); statusStr != "" {
+		st, _ := strconv.Atoi(statusStr)
+		st16 := int16(st)
+		params.Status = &st16
+	}
+	return params
+}
+
+// GetMyStatements 获取我的对账单
+// @Summary 获取我的对账单
+// @Tags 对账单
+// @Produce json
+// @Security ApiKeyAuth
+// @Param channel_id query int false "通道ID"
+// @Param period query string false "账期 YYYY-MM"
+// @Param status query int false "状态 1待确认 2已确认 3有异议"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements/mine [get]
+func (h *AgentStatementHandler) GetMyStatements(c *gin.Context) {
+	params := h.parseListParams(c)
+	params.AgentID = middleware.GetCurrentAgentID(c)
+
+	list, total, err := h.statementService.GetStatementList(params)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, params.Page, params.PageSize)
+}
+
+// GetStatementList 获取对账单列表（管理员）
+// @Summary 获取对账单列表
+// @Tags 对账单
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID"
+// @Param channel_id query int false "通道ID"
+// @Param period query string false "账期 YYYY-MM"
+// @Param status query int false "状态 1待确认 2已确认 3有异议"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements [get]
+func (h *AgentStatementHandler) GetStatementList(c *gin.Context) {
+	params := h.parseListParams(c)
+	if agentIDStr := c.Query("agent_id"); agentIDStr != "" {
+		params.AgentID, _ = strconv.ParseInt(agentIDStr, 10, 64)
+	}
+
+	list, total, err := h.statementService.GetStatementList(params)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, params.Page, params.PageSize)
+}
+
+// GetStatementDetail 获取对账单详情
+// @Summary 获取对账单详情
+// @Tags 对账单
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "对账单ID"
+// @Success 200 {object} service.StatementInfo
+// @Router /api/v1/statements/{id} [get]
+func (h *AgentStatementHandler) GetStatementDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	info, err := h.statementService.GetStatementDetail(id, h.scopeAgentID(c))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.Success(c, info)
+}
+
+// ExportStatement 导出对账单
+// @Summary 导出对账单
+// @Tags 对账单
+// @Produce application/pdf,text/csv
+// @Security ApiKeyAuth
+// @Param id path int true "对账单ID"
+// @Param format query string false "导出格式 pdf/csv，默认pdf"
+// @Success 200 {file} binary
+// @Router /api/v1/statements/{id}/export [get]
+func (h *AgentStatementHandler) ExportStatement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	info, err := h.statementService.GetStatementDetail(id, h.scopeAgentID(c))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("statement_%s_%d_%d", info.Period, info.AgentID, info.ChannelID)
+	switch c.DefaultQuery("format", "pdf") {
+	case "csv":
+		data, err := h.statementService.ExportCSV(info)
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename+".csv")
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", "attachment; filename="+filename+".pdf")
+		c.Data(http.StatusOK, "application/pdf", h.statementService.ExportPDF(info))
+	default:
+		response.BadRequest(c, "不支持的导出格式")
+	}
+}
+
+// ConfirmStatement 确认对账单
+// @Summary 确认对账单
+// @Tags 对账单
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "对账单ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements/{id}/confirm [post]
+func (h *AgentStatementHandler) ConfirmStatement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.statementService.ConfirmStatement(id, middleware.GetCurrentAgentID(c)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已确认")
+}
+
+// DisputeStatement 对对账单提出异议
+// @Summary 对账单异议
+// @Tags 对账单
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "对账单ID"
+// @Param request body DisputeStatementRequest true "异议内容"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements/{id}/dispute [post]
+func (h *AgentStatementHandler) DisputeStatement(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req DisputeStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写异议内容")
+		return
+	}
+
+	if err := h.statementService.DisputeStatement(id, middleware.GetCurrentAgentID(c), req.Reason); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "异议已提交")
+}
+
+// GenerateStatements 生成对账单（管理员）
+// @Summary 生成对账单
+// @Description 指定代理商时生成该代理商该通道的对账单（可重新生成未确认的对账单），否则为全部代理商补生成缺失的对账单
+// @Tags 对账单
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body GenerateStatementRequest true "生成请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements/generate [post]
+func (h *AgentStatementHandler) GenerateStatements(c *gin.Context) {
+	var req GenerateStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	if req.AgentID > 0 {
+		statement, err := h.statementService.GenerateStatement(req.AgentID, req.ChannelID, req.Period)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		info, err := h.statementService.GetStatementDetail(statement.ID, 0)
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+		response.Success(c, info)
+		return
+	}
+
+	generated, err := h.statementService.GenerateMonthly(req.Period)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"generated": generated})
+}
+
+// ReplyDispute 答复对账单异议（管理员）
+// @Summary 答复对账单异议
+// @Tags 对账单
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "对账单ID"
+// @Param request body ReplyStatementRequest true "答复内容"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/statements/{id}/reply [post]
+func (h *AgentStatementHandler) ReplyDispute(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req ReplyStatementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写答复内容")
+		return
+	}
+
+	err = h.statementService.ReplyDispute(id, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c), req.Reply)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已答复")
+}
+
+// RegisterAgentStatementRoutes 注册对账单路由
+func RegisterAgentStatementRoutes(r *gin.RouterGroup, h *AgentStatementHandler, authService *service.AuthService) {
+	statements := r.Group("/statements")
+	statements.Use(middleware.AuthMiddleware(authService))
+	{
+		statements.GET("/mine", h.GetMyStatements)
+		statements.GET("/:id", h.GetStatementDetail)
+		statements.GET("/:id/export", h.ExportStatement)
+		statements.POST("/:id/confirm", h.ConfirmStatement)
+		statements.POST("/:id/dispute", h.DisputeStatement)
+
+		statements.GET("", middleware.AdminMiddleware(), h.GetStatementList)
+		statements.POST("/generate", middleware.AdminMiddleware(), h.GenerateStatements)
+		statements.POST("/:id/reply", middleware.AdminMiddleware(), h.ReplyDispute)
+	}
+}
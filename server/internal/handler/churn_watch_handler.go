@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChurnWatchHandler 商户流失预警处理器
+type ChurnWatchHandler struct {
+	churnWatchService *service.ChurnWatchService
+}
+
+// NewChurnWatchHandler 创建商户流失预警处理器
+func NewChurnWatchHandler(churnWatchService *service.ChurnWatchService) *ChurnWatchHandler {
+	return &ChurnWatchHandler{
+		churnWatchService: churnWatchService,
+	}
+}
+
+// ListTasks 跟进任务列表
+// @Summary 跟进任务列表
+// @Description 代理商查看本人及下级的跟进任务，未结束的按跟进期限排在前面
+// @Tags 商户流失预警
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "跟进代理商ID"
+// @Param merchant_id query int false "商户ID"
+// @Param status query int false "状态：1待跟进 2跟进中 3已挽回 4已流失"
+// @Param trigger_type query string false "触发类型：volume_drop/inactive"
+// @Param open_only query bool false "只看未结束的任务"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.FollowUpTaskItem
+// @Router /api/v1/churn-watch/tasks [get]
+func (h *ChurnWatchHandler) ListTasks(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	merchantID, _ := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	status, _ := strconv.Atoi(c.Query("status"))
+	filter := &repository.FollowUpTaskFilter{
+		AgentID:     agentID,
+		MerchantID:  merchantID,
+		Status:      int16(status),
+		TriggerType: c.Query("trigger_type"),
+		OpenOnly:    c.Query("open_only") == "true",
+	}
+
+	list, total, err := h.churnWatchService.ListTasks(filter, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetTask 跟进任务详情
+// @Summary 跟进任务详情
+// @Description 包含跟进记录
+// @Tags 商户流失预警
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} service.FollowUpTaskDetail
+// @Router /api/v1/churn-watch/tasks/{id} [get]
+func (h *ChurnWatchHandler) GetTask(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	detail, err := h.churnWatchService.GetTask(id, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// LogFollowUp 记录跟进结果
+// @Summary 记录跟进结果
+// @Description outcome：contacted已联系、replaced_terminal已换机（任务进入跟进中），lost已流失（任务结束）；商户交易恢复后任务自动标记为已挽回
+// @Tags 商户流失预警
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Param request body service.LogFollowUpRequest true "跟进结果"
+// @Success 200 {object} models.MerchantFollowUpTask
+// @Router /api/v1/churn-watch/tasks/{id}/logs [post]
+func (h *ChurnWatchHandler) LogFollowUp(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的任务ID")
+		return
+	}
+	var req service.LogFollowUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	operator := &service.FollowUpOperator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+	task, err := h.churnWatchService.LogFollowUp(id, &req, operator)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, task, "记录成功")
+}
+
+// GetReport 挽回率报表
+// @Summary 挽回率报表
+// @Description 按代理商统计区间内生成的跟进任务数、已挽回、已流失及挽回率，代理商只能查看本人及下级
+// @Tags 商户流失预警
+// @Produce json
+// @Security ApiKeyAuth
+// @Param start_date query string false "开始日期 yyyy-MM-dd，默认近30天"
+// @Param end_date query string false "结束日期 yyyy-MM-dd，默认今天"
+// @Success 200 {object} service.FollowUpReport
+// @Router /api/v1/churn-watch/report [get]
+func (h *ChurnWatchHandler) GetReport(c *gin.Context) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	end, start := today, today.AddDate(0, 0, -29)
+	if v := c.Query("end_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			response.BadRequest(c, "结束日期格式错误")
+			return
+		}
+		end = t
+	}
+	if v := c.Query("start_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			response.BadRequest(c, "开始日期格式错误")
+			return
+		}
+		start = t
+	}
+
+	report, err := h.churnWatchService.GetReport(start, end, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), now)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// GetConfig 获取流失预警配置
+// @Summary 获取流失预警配置
+// @Tags 商户流失预警
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.ChurnWatchConfig
+// @Router /api/v1/churn-watch/config [get]
+func (h *ChurnWatchHandler) GetConfig(c *gin.Context) {
+	config, err := h.churnWatchService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新流失预警配置
+// @Summary 更新流失预警配置
+// @Description 近7天交易额较前7天下降达到drop_percent（前7天不低于min_base_amount分），或距最近交易达到inactive_days天时生成跟进任务
+// @Tags 商户流失预警
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateChurnWatchConfigRequest true "配置"
+// @Success 200 {object} models.ChurnWatchConfig
+// @Router /api/v1/churn-watch/config [put]
+func (h *ChurnWatchHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateChurnWatchConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.churnWatchService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// Scan 立即执行流失检测
+// @Summary 立即执行流失检测
+// @Tags 商户流失预警
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.ChurnWatchResult
+// @Router /api/v1/churn-watch/scan [post]
+func (h *ChurnWatchHandler) Scan(c *gin.Context) {
+	result, err := h.churnWatchService.Scan(time.Now())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RegisterChurnWatchRoutes 注册商户流失预警路由
+func RegisterChurnWatchRoutes(r *gin.RouterGroup, h *ChurnWatchHandler, authService *service.AuthService) {
+	churn := r.Group("/churn-watch")
+	churn.Use(middleware.AuthMiddleware(authService))
+	{
+		churn.GET("/tasks", h.ListTasks)
+		churn.GET("/tasks/:id", h.GetTask)
+		churn.POST("/tasks/:id/logs", h.LogFollowUp)
+		churn.GET("/report", h.GetReport)
+	}
+
+	admin := r.Group("/churn-watch")
+	admin.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		admin.GET("/config", h.GetConfig)
+		admin.PUT("/config", h.UpdateConfig)
+		admin.POST("/scan", h.Scan)
+	}
+}
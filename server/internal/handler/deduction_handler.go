@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 
@@ -600,6 +601,295 @@ func (h *DeductionHandler) GetDeductionStats(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// PrepayDeductionPlanRequest 提前还款请求
+type PrepayDeductionPlanRequest struct {
+	WalletIDs []int64 `json:"wallet_ids" binding:"required,min=1"` // 扣款钱包ID（按顺序扣款）
+	Reason    string  `json:"reason"`                              // 备注
+}
+
+// PrepayDeductionPlan 提前还款
+// @Summary 提前还款
+// @Description 被扣款方从所选钱包一次性结清剩余待扣金额，释放冻结金额并关闭代扣计划
+// @Tags 代扣管理
+// @Accept json
+// @Produce json
+// @Param id path int true "计划ID"
+// @Param request body PrepayDeductionPlanRequest true "提前还款请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/plans/{id}/prepay [post]
+func (h *DeductionHandler) PrepayDeductionPlan(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录或无权操作")
+		return
+	}
+
+	var req PrepayDeductionPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请选择扣款钱包")
+		return
+	}
+
+	change, err := h.deductionService.PrepayDeductionPlan(id, agentID, req.WalletIDs, req.Reason)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "提前还款成功")
+}
+
+// ProposeRestructure 发起代扣计划重组
+// @Summary 发起代扣计划重组
+// @Description 扣款方或被扣款方提议新的剩余期数或每期金额（二选一），对方接收后生效
+// @Tags 代扣管理
+// @Accept json
+// @Produce json
+// @Param id path int true "计划ID"
+// @Param request body service.ProposeRestructureRequest true "重组提议"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/plans/{id}/restructure [post]
+func (h *DeductionHandler) ProposeRestructure(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录或无权操作")
+		return
+	}
+
+	var req service.ProposeRestructureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	change, err := h.deductionService.ProposeRestructure(id, agentID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "重组提议已提交，等待对方确认")
+}
+
+// GetDeductionPlanChanges 获取代扣计划变更记录
+// @Summary 获取代扣计划变更记录
+// @Description 获取提前还款和重组记录，包含变更前后的扣款计划快照
+// @Tags 代扣管理
+// @Produce json
+// @Param id path int true "计划ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/plans/{id}/changes [get]
+func (h *DeductionHandler) GetDeductionPlanChanges(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	changes, err := h.deductionService.GetPlanChanges(id)
+	if err != nil {
+		response.InternalError(c, "查询失败: "+err.Error())
+		return
+	}
+
+	list := make([]gin.H, 0, len(changes))
+	for _, change := range changes {
+		list = append(list, deductionPlanChangeToH(change))
+	}
+
+	response.Success(c, list)
+}
+
+// GetPendingRestructures 获取待我确认的重组提议
+// @Summary 获取待我确认的重组提议
+// @Tags 代扣管理
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/restructures/pending [get]
+func (h *DeductionHandler) GetPendingRestructures(c *gin.Context) {
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	changes, total, err := h.deductionService.GetPendingRestructures(agentID, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "查询失败: "+err.Error())
+		return
+	}
+
+	list := make([]gin.H, 0, len(changes))
+	for _, change := range changes {
+		list = append(list, deductionPlanChangeToH(change))
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// AcceptRestructure 接收重组提议
+// @Summary 接收重组提议
+// @Description 接收后原待扣记录作废，按新计划重新排期
+// @Tags 代扣管理
+// @Produce json
+// @Param id path int true "重组提议ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/restructures/{id}/accept [post]
+func (h *DeductionHandler) AcceptRestructure(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录或无权操作")
+		return
+	}
+
+	if err := h.deductionService.AcceptRestructure(id, agentID); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "重组已生效")
+}
+
+// RejectRestructureRequest 拒绝重组请求
+type RejectRestructureRequest struct {
+	Reason string `json:"reason"` // 拒绝原因
+}
+
+// RejectRestructure 拒绝重组提议
+// @Summary 拒绝重组提议
+// @Tags 代扣管理
+// @Accept json
+// @Produce json
+// @Param id path int true "重组提议ID"
+// @Param request body RejectRestructureRequest false "拒绝原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/restructures/{id}/reject [post]
+func (h *DeductionHandler) RejectRestructure(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录或无权操作")
+		return
+	}
+
+	var req RejectRestructureRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.deductionService.RejectRestructure(id, agentID, req.Reason); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已拒绝")
+}
+
+// WithdrawRestructure 撤回重组提议
+// @Summary 撤回重组提议
+// @Tags 代扣管理
+// @Produce json
+// @Param id path int true "重组提议ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/restructures/{id}/withdraw [post]
+func (h *DeductionHandler) WithdrawRestructure(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := getCurrentAgentID(c)
+	if agentID == 0 {
+		response.Unauthorized(c, "未登录或无权操作")
+		return
+	}
+
+	if err := h.deductionService.WithdrawRestructure(id, agentID); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已撤回")
+}
+
+// deductionPlanChangeToH 转换变更记录为前端友好格式
+func deductionPlanChangeToH(change *models.DeductionPlanChange) gin.H {
+	return gin.H{
+		"id":                   change.ID,
+		"change_no":            change.ChangeNo,
+		"plan_id":              change.PlanID,
+		"plan_no":              change.PlanNo,
+		"change_type":          change.ChangeType,
+		"change_type_name":     models.GetDeductionPlanChangeTypeName(change.ChangeType),
+		"status":               change.Status,
+		"status_name":          models.GetDeductionPlanChangeStatusName(change.Status),
+		"proposer_id":          change.ProposerID,
+		"counterparty_id":      change.CounterpartyID,
+		"old_total_periods":    change.OldTotalPeriods,
+		"old_period_amount":    change.OldPeriodAmount,
+		"old_remaining_amount": change.OldRemainingAmount,
+		"old_schedule":         json.RawMessage(jsonOrEmptyArray(change.OldSchedule)),
+		"new_periods":          change.NewPeriods,
+		"new_period_amount":    change.NewPeriodAmount,
+		"new_schedule":         json.RawMessage(jsonOrEmptyArray(change.NewSchedule)),
+		"prepay_amount":        change.PrepayAmount,
+		"prepay_yuan":          float64(change.PrepayAmount) / 100,
+		"released_frozen":      change.ReleasedFrozen,
+		"wallet_details":       json.RawMessage(jsonOrEmptyArray(change.WalletDetails)),
+		"reason":               change.Reason,
+		"reject_reason":        change.RejectReason,
+		"decided_at":           change.DecidedAt,
+		"created_at":           change.CreatedAt,
+	}
+}
+
+// jsonOrEmptyArray 空JSON字段返回空数组
+func jsonOrEmptyArray(s string) string {
+	if s == "" {
+		return "[]"
+	}
+	return s
+}
+
 // RegisterDeductionRoutes 注册代扣管理路由
 func RegisterDeductionRoutes(r *gin.RouterGroup, h *DeductionHandler) {
 	deduction := r.Group("/deduction")
@@ -616,6 +906,15 @@ func RegisterDeductionRoutes(r *gin.RouterGroup, h *DeductionHandler) {
 		deduction.POST("/plans/:id/accept", h.AcceptDeductionPlan) // 接收确认
 		deduction.POST("/plans/:id/reject", h.RejectDeductionPlan) // 拒绝
 
+		// 提前还款与重组
+		deduction.POST("/plans/:id/prepay", h.PrepayDeductionPlan)          // 提前还款
+		deduction.POST("/plans/:id/restructure", h.ProposeRestructure)      // 发起重组
+		deduction.GET("/plans/:id/changes", h.GetDeductionPlanChanges)      // 变更记录
+		deduction.GET("/restructures/pending", h.GetPendingRestructures)    // 待我确认的重组
+		deduction.POST("/restructures/:id/accept", h.AcceptRestructure)     // 接收重组
+		deduction.POST("/restructures/:id/reject", h.RejectRestructure)     // 拒绝重组
+		deduction.POST("/restructures/:id/withdraw", h.WithdrawRestructure) // 撤回重组
+
 		// 我的代扣（代理商端）
 		deduction.GET("/received", h.GetReceivedDeductions) // 我接收的代扣
 		deduction.GET("/sent", h.GetSentDeductions)         // 我发起的代扣
@@ -674,6 +973,10 @@ func DeductionRecordStatusDesc(status int16) string {
 		return "扣款失败"
 	case models.DeductionRecordStatusPartialSuccess:
 		return "部分成功"
+	case models.DeductionRecordStatusSettled:
+		return "已结清"
+	case models.DeductionRecordStatusRestructured:
+		return "已重组"
 	default:
 		return "未知状态"
 	}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeductionOverdueHandler 代扣逾期处理器
+type DeductionOverdueHandler struct {
+	overdueService *service.DeductionOverdueService
+}
+
+// NewDeductionOverdueHandler 创建代扣逾期处理器
+func NewDeductionOverdueHandler(overdueService *service.DeductionOverdueService) *DeductionOverdueHandler {
+	return &DeductionOverdueHandler{
+		overdueService: overdueService,
+	}
+}
+
+// parsePage 解析分页参数
+func (h *DeductionOverdueHandler) parsePage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// parseFilter 解析通用筛选参数
+func (h *DeductionOverdueHandler) parseFilter(c *gin.Context) *repository.DeductionOverdueFilter {
+	filter := &repository.DeductionOverdueFilter{}
+	if s := c.Query("status"); s != "" {
+		st, _ := strconv.Atoi(s)
+		filter.Status = int16(st)
+	}
+	if s := c.Query("min_days"); s != "" {
+		filter.MinDays, _ = strconv.Atoi(s)
+	}
+	return filter
+}
+
+// GetCreditorDashboard 债权人逾期看板
+// @Summary 获取逾期看板
+// @Description 扣款方查看名下代扣计划的逾期汇总及逾期最久的记录
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.DeductionOverdueDashboard
+// @Router /api/v1/deduction/overdues/dashboard [get]
+func (h *DeductionOverdueHandler) GetCreditorDashboard(c *gin.Context) {
+	dashboard, err := h.overdueService.GetCreditorDashboard(middleware.GetCurrentAgentID(c))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, dashboard)
+}
+
+// GetCreditorOverdues 我发起的计划的逾期列表
+// @Summary 获取我的应收逾期
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态 1逾期中 2已结清 3已关闭"
+// @Param min_days query int false "最少逾期天数"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/overdues/receivable [get]
+func (h *DeductionOverdueHandler) GetCreditorOverdues(c *gin.Context) {
+	page, pageSize := h.parsePage(c)
+	filter := h.parseFilter(c)
+	filter.DeductorID = middleware.GetCurrentAgentID(c)
+
+	list, total, err := h.overdueService.ListOverdues(filter, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetDebtorOverdues 我的逾期列表（被扣款方）
+// @Summary 获取我的逾期
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态 1逾期中 2已结清 3已关闭"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/overdues/mine [get]
+func (h *DeductionOverdueHandler) GetDebtorOverdues(c *gin.Context) {
+	page, pageSize := h.parsePage(c)
+	filter := h.parseFilter(c)
+	filter.DeducteeID = middleware.GetCurrentAgentID(c)
+
+	list, total, err := h.overdueService.ListOverdues(filter, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetOverdueDetail 逾期详情
+// @Summary 获取逾期详情
+// @Description 扣款方、被扣款方、被上报的上级及管理员可查看
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "逾期ID"
+// @Success 200 {object} service.DeductionOverdueInfo
+// @Router /api/v1/deduction/overdues/{id} [get]
+func (h *DeductionOverdueHandler) GetOverdueDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := middleware.GetCurrentAgentID(c)
+	if middleware.IsAdmin(c) {
+		agentID = 0
+	}
+	info, err := h.overdueService.GetOverdue(id, agentID)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.Success(c, info)
+}
+
+// GetOverdueList 逾期列表（管理端）
+// @Summary 获取逾期列表
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param deductor_id query int false "扣款方ID"
+// @Param deductee_id query int false "被扣款方ID"
+// @Param status query int false "状态 1逾期中 2已结清 3已关闭"
+// @Param min_days query int false "最少逾期天数"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/deduction/overdues [get]
+func (h *DeductionOverdueHandler) GetOverdueList(c *gin.Context) {
+	page, pageSize := h.parsePage(c)
+	filter := h.parseFilter(c)
+	filter.DeductorID, _ = strconv.ParseInt(c.Query("deductor_id"), 10, 64)
+	filter.DeducteeID, _ = strconv.ParseInt(c.Query("deductee_id"), 10, 64)
+
+	list, total, err := h.overdueService.ListOverdues(filter, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetConfig 获取逾期处理配置
+// @Summary 获取逾期处理配置
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.DeductionOverdueConfig
+// @Router /api/v1/deduction/overdues/config [get]
+func (h *DeductionOverdueHandler) GetConfig(c *gin.Context) {
+	config, err := h.overdueService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新逾期处理配置
+// @Summary 更新逾期处理配置
+// @Description 宽限天数、滞纳金费率与上限、通知节点、上报天数、上级代偿、冻结出款天数
+// @Tags 代扣逾期
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateDeductionOverdueConfigRequest true "配置"
+// @Success 200 {object} models.DeductionOverdueConfig
+// @Router /api/v1/deduction/overdues/config [put]
+func (h *DeductionOverdueHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateDeductionOverdueConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.overdueService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, config, "保存成功")
+}
+
+// RunOverdues 手动执行逾期处理
+// @Summary 手动执行逾期处理
+// @Tags 代扣逾期
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.DeductionOverdueRunResult
+// @Router /api/v1/deduction/overdues/run [post]
+func (h *DeductionOverdueHandler) RunOverdues(c *gin.Context) {
+	result, err := h.overdueService.ProcessOverdues(time.Now())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RegisterDeductionOverdueRoutes 注册代扣逾期路由
+func RegisterDeductionOverdueRoutes(r *gin.RouterGroup, h *DeductionOverdueHandler, authService *service.AuthService) {
+	overdues := r.Group("/deduction/overdues")
+	overdues.Use(middleware.AuthMiddleware(authService))
+	{
+		overdues.GET("/dashboard", h.GetCreditorDashboard)
+		overdues.GET("/receivable", h.GetCreditorOverdues)
+		overdues.GET("/mine", h.GetDebtorOverdues)
+
+		overdues.GET("", middleware.AdminMiddleware(), h.GetOverdueList)
+		overdues.GET("/config", middleware.AdminMiddleware(), h.GetConfig)
+		overdues.PUT("/config", middleware.AdminMiddleware(), h.UpdateConfig)
+		overdues.POST("/run", middleware.AdminMiddleware(), h.RunOverdues)
+
+		overdues.GET("/:id", h.GetOverdueDetail)
+	}
+}
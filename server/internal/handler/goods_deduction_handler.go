@@ -43,7 +43,7 @@ type CreateGoodsDeductionTerminalRequest struct {
 
 // CreateGoodsDeduction 创建货款代扣
 // @Summary 创建货款代扣
-// @Description 终端划拨时创建货款代扣，需要下级接收确认后生效
+// @Description 终端划拨时创建货款代扣（一期待接收的统一代扣计划），需要下级接收确认后生效
 // @Tags 货款代扣
 // @Accept json
 // @Produce json
@@ -224,7 +224,7 @@ func (h *GoodsDeductionHandler) GetGoodsDeduction(c *gin.Context) {
 // @Tags 货款代扣
 // @Produce json
 // @Security ApiKeyAuth
-// @Param status query int false "状态筛选: 1=待接收 2=进行中 3=已完成 4=已拒绝"
+// @Param status query int false "状态筛选: 1=待接收 2=进行中 3=已完成 4=已拒绝 5=已取消"
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页数量" default(20)
 // @Success 200 {object} map[string]interface{}
@@ -269,7 +269,7 @@ func (h *GoodsDeductionHandler) GetSentList(c *gin.Context) {
 // @Tags 货款代扣
 // @Produce json
 // @Security ApiKeyAuth
-// @Param status query int false "状态筛选: 1=待接收 2=进行中 3=已完成 4=已拒绝"
+// @Param status query int false "状态筛选: 1=待接收 2=进行中 3=已完成 4=已拒绝 5=已取消"
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页数量" default(20)
 // @Success 200 {object} map[string]interface{}
@@ -310,7 +310,7 @@ func (h *GoodsDeductionHandler) GetReceivedList(c *gin.Context) {
 
 // GetDeductionDetails 获取扣款明细列表
 // @Summary 获取扣款明细列表
-// @Description 获取货款代扣的扣款明细记录
+// @Description 获取货款代扣的扣款明细记录（代扣计划的实扣记录）
 // @Tags 货款代扣
 // @Produce json
 // @Security ApiKeyAuth
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GoodsDeductionMigrationHandler 货款代扣迁移处理器
+type GoodsDeductionMigrationHandler struct {
+	migrationService *service.GoodsDeductionMigrationService
+}
+
+// NewGoodsDeductionMigrationHandler 创建货款代扣迁移处理器
+func NewGoodsDeductionMigrationHandler(migrationService *service.GoodsDeductionMigrationService) *GoodsDeductionMigrationHandler {
+	return &GoodsDeductionMigrationHandler{
+		migrationService: migrationService,
+	}
+}
+
+// DryRun 试运行迁移
+// @Summary 货款代扣迁移试运行
+// @Description 核对全部未迁移的货款代扣并生成报告，不写入代扣计划
+// @Tags 货款代扣迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GoodsDeductionMigrationRun
+// @Router /api/v1/goods-deduction-migrations/dry-run [post]
+func (h *GoodsDeductionMigrationHandler) DryRun(c *gin.Context) {
+	run, err := h.migrationService.DryRun(middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, run)
+}
+
+// Run 正式迁移
+// @Summary 执行货款代扣迁移
+// @Description 将未迁移的货款代扣转换为代扣计划，不一致或冲突的记录跳过并列入报告
+// @Tags 货款代扣迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GoodsDeductionMigrationRun
+// @Router /api/v1/goods-deduction-migrations/run [post]
+func (h *GoodsDeductionMigrationHandler) Run(c *gin.Context) {
+	run, err := h.migrationService.Run(middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, run, "迁移完成")
+}
+
+// Reconcile 迁移对账
+// @Summary 货款代扣迁移对账
+// @Description 逐笔核对已迁移的货款代扣与代扣计划的金额和历史记录
+// @Tags 货款代扣迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.GoodsDeductionReconcileReport
+// @Router /api/v1/goods-deduction-migrations/reconcile [get]
+func (h *GoodsDeductionMigrationHandler) Reconcile(c *gin.Context) {
+	report, err := h.migrationService.Reconcile()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, report)
+}
+
+// GetRunList 迁移批次列表
+// @Summary 获取货款代扣迁移批次列表
+// @Tags 货款代扣迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/goods-deduction-migrations [get]
+func (h *GoodsDeductionMigrationHandler) GetRunList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := h.migrationService.ListRuns(page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetRunDetail 迁移批次详情
+// @Summary 获取货款代扣迁移批次详情（含逐笔报告）
+// @Tags 货款代扣迁移
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "批次ID"
+// @Success 200 {object} models.GoodsDeductionMigrationRun
+// @Router /api/v1/goods-deduction-migrations/{id} [get]
+func (h *GoodsDeductionMigrationHandler) GetRunDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	run, err := h.migrationService.GetRun(id)
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.Success(c, run)
+}
+
+// RegisterGoodsDeductionMigrationRoutes 注册货款代扣迁移路由
+func RegisterGoodsDeductionMigrationRoutes(r *gin.RouterGroup, h *GoodsDeductionMigrationHandler, authService *service.AuthService) {
+	migrations := r.Group("/goods-deduction-migrations")
+	migrations.Use(middleware.AuthMiddleware(authService))
+	migrations.Use(middleware.AdminMiddleware())
+	{
+		migrations.GET("", h.GetRunList)
+		migrations.POST("/dry-run", h.DryRun)
+		migrations.POST("/run", h.Run)
+		migrations.GET("/reconcile", h.Reconcile)
+		migrations.GET("/:id", h.GetRunDetail)
+	}
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InventoryReportHandler 终端库存报表处理器
+type InventoryReportHandler struct {
+	reportService *service.InventoryReportService
+}
+
+// NewInventoryReportHandler 创建终端库存报表处理器
+func NewInventoryReportHandler(reportService *service.InventoryReportService) *InventoryReportHandler {
+	return &InventoryReportHandler{
+		reportService: reportService,
+	}
+}
+
+// parseInventoryQuery 解析库存报表公共查询参数
+func parseInventoryQuery(c *gin.Context) *service.InventoryQuery {
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	channelID, _ := strconv.ParseInt(c.Query("channel_id"), 10, 64)
+	return &service.InventoryQuery{
+		OperatorAgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin:         middleware.IsAdmin(c),
+		AgentID:         agentID,
+		Scope:           c.Query("scope"),
+		ChannelID:       channelID,
+		BrandCode:       c.Query("brand_code"),
+		ModelCode:       c.Query("model_code"),
+	}
+}
+
+// parseInventoryPage 解析分页参数
+func parseInventoryPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// GetStockReport 库存汇总
+// @Summary 按代理商/通道/品牌/型号汇总终端库存及账龄
+// @Description 未绑定库存按到达当前持有者的时间（最近确认下发/回拨，无则入库时间）分为0-30/30-60/60-90/90+天；代理商只能查看本人及下级
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID（默认本人，管理员默认全部）"
+// @Param scope query string false "direct:仅本人持有 team:含全部下级（默认）"
+// @Param channel_id query int false "通道ID"
+// @Param brand_code query string false "品牌编码"
+// @Param model_code query string false "型号编码"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} service.InventoryStockReport
+// @Router /api/v1/inventory-reports/stock [get]
+func (h *InventoryReportHandler) GetStockReport(c *gin.Context) {
+	page, pageSize := parseInventoryPage(c)
+	report, err := h.reportService.GetStockReport(parseInventoryQuery(c), page, pageSize, time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"totals":    report.Totals,
+		"list":      report.List,
+		"total":     report.Total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetSubordinateReport 下级动销
+// @Summary 直属下级（含其团队）的库存、呆滞库存、动销率与激活率
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "上级代理商ID（默认本人，管理员默认统计顶级代理商）"
+// @Param channel_id query int false "通道ID"
+// @Success 200 {array} service.InventorySubordinateItem
+// @Router /api/v1/inventory-reports/subordinates [get]
+func (h *InventoryReportHandler) GetSubordinateReport(c *gin.Context) {
+	items, err := h.reportService.GetSubordinateReport(parseInventoryQuery(c), time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, items)
+}
+
+// GetStaleTerminals 呆滞终端明细
+// @Summary 呆滞终端明细（库龄超期未绑定 / 绑定后未激活）
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Param kind query string false "stock:未绑定库存（默认） bound_inactive:已绑定未激活"
+// @Param min_days query int false "最少天数（默认取预警配置的呆滞天数）"
+// @Param agent_id query int false "代理商ID"
+// @Param scope query string false "direct/team"
+// @Param channel_id query int false "通道ID"
+// @Param brand_code query string false "品牌编码"
+// @Param model_code query string false "型号编码"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/inventory-reports/stale [get]
+func (h *InventoryReportHandler) GetStaleTerminals(c *gin.Context) {
+	page, pageSize := parseInventoryPage(c)
+	minDays, _ := strconv.Atoi(c.Query("min_days"))
+
+	list, total, err := h.reportService.ListStaleTerminals(parseInventoryQuery(c), c.Query("kind"), minDays, page, pageSize, time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// Export 导出库存报表
+// @Summary 导出库存报表（CSV）
+// @Tags 库存报表
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param report query string false "stock:库存汇总（默认） subordinates:下级动销 stale:呆滞终端"
+// @Param kind query string false "呆滞类型（report=stale时有效）"
+// @Param min_days query int false "最少天数（report=stale时有效）"
+// @Success 200 {file} file
+// @Router /api/v1/inventory-reports/export [get]
+func (h *InventoryReportHandler) Export(c *gin.Context) {
+	q := parseInventoryQuery(c)
+	now := time.Now()
+
+	var data []byte
+	var err error
+	report := c.DefaultQuery("report", "stock")
+	switch report {
+	case "stock":
+		data, err = h.reportService.ExportStockReport(q, now)
+	case "subordinates":
+		data, err = h.reportService.ExportSubordinateReport(q, now)
+	case "stale":
+		minDays, _ := strconv.Atoi(c.Query("min_days"))
+		data, err = h.reportService.ExportStaleTerminals(q, c.Query("kind"), minDays, now)
+	default:
+		response.BadRequest(c, "无效的报表类型")
+		return
+	}
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=inventory_%s_%s.csv", report, now.Format("20060102")))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// GetAlertList 呆滞库存预警记录
+// @Summary 呆滞库存预警记录（代理商查看本人及下级）
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/inventory-reports/alerts [get]
+func (h *InventoryReportHandler) GetAlertList(c *gin.Context) {
+	page, pageSize := parseInventoryPage(c)
+	list, total, err := h.reportService.ListAlerts(middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetAlertConfig 获取呆滞库存预警配置
+// @Summary 获取呆滞库存预警配置
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.InventoryAlertConfig
+// @Router /api/v1/inventory-reports/alert-config [get]
+func (h *InventoryReportHandler) GetAlertConfig(c *gin.Context) {
+	config, err := h.reportService.GetAlertConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateAlertConfig 更新呆滞库存预警配置
+// @Summary 更新呆滞库存预警配置
+// @Description 呆滞天数、预警台数阈值、重复提醒间隔、是否通知上级及推送总部告警
+// @Tags 库存报表
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateInventoryAlertConfigRequest true "配置"
+// @Success 200 {object} models.InventoryAlertConfig
+// @Router /api/v1/inventory-reports/alert-config [put]
+func (h *InventoryReportHandler) UpdateAlertConfig(c *gin.Context) {
+	var req service.UpdateInventoryAlertConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.reportService.UpdateAlertConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// RunAlertCheck 手动执行呆滞库存扫描
+// @Summary 手动执行呆滞库存扫描
+// @Tags 库存报表
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.InventoryAlertRunResult
+// @Router /api/v1/inventory-reports/alerts/run [post]
+func (h *InventoryReportHandler) RunAlertCheck(c *gin.Context) {
+	result, err := h.reportService.CheckStaleStock(time.Now())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RegisterInventoryReportRoutes 注册终端库存报表路由
+func RegisterInventoryReportRoutes(r *gin.RouterGroup, h *InventoryReportHandler, authService *service.AuthService) {
+	reports := r.Group("/inventory-reports")
+	reports.Use(middleware.AuthMiddleware(authService))
+	{
+		reports.GET("/stock", h.GetStockReport)
+		reports.GET("/subordinates", h.GetSubordinateReport)
+		reports.GET("/stale", h.GetStaleTerminals)
+		reports.GET("/export", h.Export)
+		reports.GET("/alerts", h.GetAlertList)
+
+		reports.GET("/alert-config", middleware.AdminMiddleware(), h.GetAlertConfig)
+		reports.PUT("/alert-config", middleware.AdminMiddleware(), h.UpdateAlertConfig)
+		reports.POST("/alerts/run", middleware.AdminMiddleware(), h.RunAlertCheck)
+	}
+}
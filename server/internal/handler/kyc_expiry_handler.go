@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KycExpiryHandler 商户证件到期处理器
+type KycExpiryHandler struct {
+	kycExpiryService *service.KycExpiryService
+}
+
+// NewKycExpiryHandler 创建商户证件到期处理器
+func NewKycExpiryHandler(kycExpiryService *service.KycExpiryService) *KycExpiryHandler {
+	return &KycExpiryHandler{
+		kycExpiryService: kycExpiryService,
+	}
+}
+
+// GetSummary 证件到期看板统计
+// @Summary 证件到期看板统计
+// @Description 按法人身份证剩余有效期分档统计正常商户数：已过期、7天内、30天内、60天内、60天以上、长期有效、未获取，代理商只统计本人及下级商户
+// @Tags 商户证件到期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID"
+// @Success 200 {object} repository.KycExpirySummary
+// @Router /api/v1/kyc-expiry/summary [get]
+func (h *KycExpiryHandler) GetSummary(c *gin.Context) {
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	filter := &repository.KycExpiryFilter{AgentID: agentID}
+
+	summary, err := h.kycExpiryService.GetSummary(filter, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, summary)
+}
+
+// ListMerchants 证件到期商户列表
+// @Summary 证件到期商户列表
+// @Description 按到期日升序，代理商只能查看本人及下级商户
+// @Tags 商户证件到期
+// @Produce json
+// @Security ApiKeyAuth
+// @Param bucket query string false "分档：expired/within_7/within_30/within_60/valid/long_term/missing"
+// @Param agent_id query int false "代理商ID"
+// @Param keyword query string false "商户号/商户名称"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.KycExpiryMerchantItem
+// @Router /api/v1/kyc-expiry/merchants [get]
+func (h *KycExpiryHandler) ListMerchants(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	filter := &repository.KycExpiryFilter{
+		Bucket:  c.Query("bucket"),
+		AgentID: agentID,
+		Keyword: c.Query("keyword"),
+	}
+
+	list, total, err := h.kycExpiryService.ListMerchants(filter, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), time.Now(), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetConfig 获取证件到期提醒配置
+// @Summary 获取证件到期提醒配置
+// @Tags 商户证件到期
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.KycExpiryConfig
+// @Router /api/v1/kyc-expiry/config [get]
+func (h *KycExpiryHandler) GetConfig(c *gin.Context) {
+	config, err := h.kycExpiryService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新证件到期提醒配置
+// @Summary 更新证件到期提醒配置
+// @Description window_days为到期前提醒窗口（天），每个窗口对同一到期日只提醒一次；notify_expired开启时证件过期后再提醒一次
+// @Tags 商户证件到期
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateKycExpiryConfigRequest true "配置"
+// @Success 200 {object} models.KycExpiryConfig
+// @Router /api/v1/kyc-expiry/config [put]
+func (h *KycExpiryHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateKycExpiryConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.kycExpiryService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// RegisterKycExpiryRoutes 注册商户证件到期路由
+func RegisterKycExpiryRoutes(r *gin.RouterGroup, h *KycExpiryHandler, authService *service.AuthService) {
+	kyc := r.Group("/kyc-expiry")
+	kyc.Use(middleware.AuthMiddleware(authService))
+	{
+		kyc.GET("/summary", h.GetSummary)
+		kyc.GET("/merchants", h.ListMerchants)
+	}
+
+	admin := r.Group("/kyc-expiry")
+	admin.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		admin.GET("/config", h.GetConfig)
+		admin.PUT("/config", h.UpdateConfig)
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantApplicationHandler 商户进件申请处理器
+type MerchantApplicationHandler struct {
+	onboardingService *service.MerchantOnboardingService
+}
+
+// NewMerchantApplicationHandler 创建商户进件申请处理器
+func NewMerchantApplicationHandler(onboardingService *service.MerchantOnboardingService) *MerchantApplicationHandler {
+	return &MerchantApplicationHandler{
+		onboardingService: onboardingService,
+	}
+}
+
+// operator 当前操作人
+func (h *MerchantApplicationHandler) operator(c *gin.Context) *service.Operator {
+	return &service.Operator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+}
+
+// ListApplications 进件申请列表
+// @Summary 进件申请列表
+// @Description 代理商可查看本人及下级的进件申请
+// @Tags 商户进件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态：1待提交 2审核中 3已通过 4已驳回 5已撤销"
+// @Param channel_code query string false "通道编码"
+// @Param keyword query string false "申请单号/商户名称/终端SN/商户号"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications [get]
+func (h *MerchantApplicationHandler) ListApplications(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status, _ := strconv.Atoi(c.Query("status"))
+	filter := &repository.MerchantApplicationFilter{
+		Status:      int16(status),
+		ChannelCode: c.Query("channel_code"),
+		Keyword:     c.Query("keyword"),
+	}
+
+	list, total, err := h.onboardingService.ListApplications(filter, h.operator(c), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// CreateApplication 录入进件申请
+// @Summary 录入进件申请
+// @Description 资料图片先通过上传接口（module=merchant_application）上传，documents引用上传返回的文件ID；费率为小数形式；submit为true时保存后立即提交通道
+// @Tags 商户进件
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MerchantApplicationInput true "进件资料"
+// @Success 200 {object} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications [post]
+func (h *MerchantApplicationHandler) CreateApplication(c *gin.Context) {
+	var req service.MerchantApplicationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	item, err := h.onboardingService.CreateApplication(&req, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, "保存成功")
+}
+
+// GetApplication 进件申请详情
+// @Summary 进件申请详情
+// @Tags 商户进件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications/{id} [get]
+func (h *MerchantApplicationHandler) GetApplication(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.onboardingService.GetApplication(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, item)
+}
+
+// UpdateApplication 修改进件申请
+// @Summary 修改进件申请
+// @Description 仅待提交或已驳回的申请可修改
+// @Tags 商户进件
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body service.MerchantApplicationInput true "进件资料"
+// @Success 200 {object} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications/{id} [put]
+func (h *MerchantApplicationHandler) UpdateApplication(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	var req service.MerchantApplicationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	item, err := h.onboardingService.UpdateApplication(id, &req, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, "保存成功")
+}
+
+// Submit 提交通道
+// @Summary 提交进件申请到通道
+// @Description 待提交或已驳回的申请可提交；通道未受理时保持待提交并返回原因
+// @Tags 商户进件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications/{id}/submit [post]
+func (h *MerchantApplicationHandler) Submit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.onboardingService.Submit(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, "已提交")
+}
+
+// Cancel 撤销进件申请
+// @Summary 撤销进件申请
+// @Description 仅待提交或已驳回的申请可撤销
+// @Tags 商户进件
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.MerchantApplicationItem
+// @Router /api/v1/merchant-applications/{id}/cancel [post]
+func (h *MerchantApplicationHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.onboardingService.Cancel(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, "已撤销")
+}
+
+// RegisterMerchantApplicationRoutes 注册商户进件申请路由
+func RegisterMerchantApplicationRoutes(r *gin.RouterGroup, h *MerchantApplicationHandler, authService *service.AuthService) {
+	apps := r.Group("/merchant-applications")
+	apps.Use(middleware.AuthMiddleware(authService))
+	{
+		apps.GET("", h.ListApplications)
+		apps.POST("", h.CreateApplication)
+		apps.GET("/:id", h.GetApplication)
+		apps.PUT("/:id", h.UpdateApplication)
+		apps.POST("/:id/submit", h.Submit)
+		apps.POST("/:id/cancel", h.Cancel)
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantClassHandler 商户分类规则处理器
+type MerchantClassHandler struct {
+	classService *service.MerchantClassService
+}
+
+// NewMerchantClassHandler 创建商户分类规则处理器
+func NewMerchantClassHandler(classService *service.MerchantClassService) *MerchantClassHandler {
+	return &MerchantClassHandler{
+		classService: classService,
+	}
+}
+
+// GetMetrics 获取可用分类指标及内置默认规则
+// @Summary 获取可用分类指标及内置默认规则
+// @Tags 商户分类规则
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merchant-class-rules/metrics [get]
+func (h *MerchantClassHandler) GetMetrics(c *gin.Context) {
+	response.Success(c, gin.H{
+		"metrics":       h.classService.GetMetricDefs(),
+		"default_rules": h.classService.GetDefaultRules(),
+		"operators":     []string{"gte", "gt", "lte", "lt", "eq"},
+	})
+}
+
+// List 规则集列表
+// @Summary 规则集列表
+// @Tags 商户分类规则
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "一级代理商ID（0为全部代理商）"
+// @Param channel_id query int false "通道ID（0为全部通道）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} models.MerchantClassRuleSet
+// @Router /api/v1/merchant-class-rules [get]
+func (h *MerchantClassHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := h.classService.ListRuleSets(
+		parseOptionalInt64Query(c, "agent_id"),
+		parseOptionalInt64Query(c, "channel_id"),
+		page, pageSize,
+	)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// Get 规则集详情
+// @Summary 规则集详情
+// @Tags 商户分类规则
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "规则集ID"
+// @Success 200 {object} models.MerchantClassRuleSet
+// @Router /api/v1/merchant-class-rules/{id} [get]
+func (h *MerchantClassHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的规则集ID")
+		return
+	}
+
+	set, err := h.classService.GetRuleSet(id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, set)
+}
+
+// Create 新增规则集
+// @Summary 新增规则集
+// @Description 规则按顺序匹配，首条所有条件都满足的规则生效，最后一条须为无条件的兜底规则；金额单位为分
+// @Tags 商户分类规则
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MerchantClassRuleSetRequest true "规则集"
+// @Success 200 {object} models.MerchantClassRuleSet
+// @Router /api/v1/merchant-class-rules [post]
+func (h *MerchantClassHandler) Create(c *gin.Context) {
+	var req service.MerchantClassRuleSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	set, err := h.classService.CreateRuleSet(&req, middleware.GetCurrentUserID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, set)
+}
+
+// Update 更新规则集
+// @Summary 更新规则集
+// @Description 下次商户类型计算任务生效，修改前可先预览
+// @Tags 商户分类规则
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "规则集ID"
+// @Param request body service.MerchantClassRuleSetRequest true "规则集"
+// @Success 200 {object} models.MerchantClassRuleSet
+// @Router /api/v1/merchant-class-rules/{id} [put]
+func (h *MerchantClassHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的规则集ID")
+		return
+	}
+	var req service.MerchantClassRuleSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	set, err := h.classService.UpdateRuleSet(id, &req, middleware.GetCurrentUserID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, set)
+}
+
+// Delete 删除规则集
+// @Summary 删除规则集
+// @Tags 商户分类规则
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "规则集ID"
+// @Success 200 {object} response.Response
+// @Router /api/v1/merchant-class-rules/{id} [delete]
+func (h *MerchantClassHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的规则集ID")
+		return
+	}
+
+	if err := h.classService.DeleteRuleSet(id); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "删除成功")
+}
+
+// Preview 预览规则集
+// @Summary 预览规则集
+// @Description 按当前交易指标统计规则集生效后各分类商户数及分类会变化的商户数，不修改商户；填写rule_set_id时预览已保存的规则集
+// @Tags 商户分类规则
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.MerchantClassPreviewRequest true "规则集"
+// @Success 200 {object} service.MerchantClassPreviewResult
+// @Router /api/v1/merchant-class-rules/preview [post]
+func (h *MerchantClassHandler) Preview(c *gin.Context) {
+	var req service.MerchantClassPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.classService.Preview(&req, time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// ListHistory 商户分类变更历史
+// @Summary 商户分类变更历史
+// @Tags 商户分类规则
+// @Produce json
+// @Security ApiKeyAuth
+// @Param merchant_id query int true "商户ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} models.MerchantClassHistory
+// @Router /api/v1/merchant-class-rules/history [get]
+func (h *MerchantClassHandler) ListHistory(c *gin.Context) {
+	merchantID, err := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	if err != nil || merchantID <= 0 {
+		response.BadRequest(c, "请指定商户ID")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := h.classService.ListHistory(merchantID, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// RegisterMerchantClassRoutes 注册商户分类规则路由
+func RegisterMerchantClassRoutes(r *gin.RouterGroup, h *MerchantClassHandler, authService *service.AuthService) {
+	rules := r.Group("/merchant-class-rules")
+	rules.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		rules.GET("/metrics", h.GetMetrics)
+		rules.GET("/history", h.ListHistory)
+		rules.POST("/preview", h.Preview)
+
+		rules.GET("", h.List)
+		rules.POST("", h.Create)
+		rules.GET("/:id", h.Get)
+		rules.PUT("/:id", h.Update)
+		rules.DELETE("/:id", h.Delete)
+	}
+}
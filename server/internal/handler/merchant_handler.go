@@ -753,11 +753,15 @@ func (h *MerchantHandler) ExportMerchants(c *gin.Context) {
 		if !m.IsDirect {
 			ownerType = "团队"
 		}
+		typeName := m.MerchantTypeLabel // 自定义分类规则的标签
+		if typeName == "" {
+			typeName = getMerchantTypeName(m.MerchantType)
+		}
 		csvData += m.MerchantNo + "," +
 			m.MerchantName + "," +
 			m.TerminalSN + "," +
 			getMerchantStatusName(m.Status) + "," +
-			getMerchantTypeName(m.MerchantType) + "," +
+			typeName + "," +
 			ownerType + "," +
 			m.CreditRate + "," +
 			m.DebitRate + "," +
@@ -0,0 +1,506 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantPortalHandler 商户自助服务处理器
+type MerchantPortalHandler struct {
+	portalService *service.MerchantPortalService
+}
+
+// NewMerchantPortalHandler 创建商户自助服务处理器
+func NewMerchantPortalHandler(portalService *service.MerchantPortalService) *MerchantPortalHandler {
+	return &MerchantPortalHandler{
+		portalService: portalService,
+	}
+}
+
+// pagination 解析分页参数
+func (h *MerchantPortalHandler) pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// parseDate 解析日期参数（yyyy-mm-dd），为空或格式错误时返回nil
+func (h *MerchantPortalHandler) parseDate(c *gin.Context, key string) *time.Time {
+	value := c.Query(key)
+	if value == "" {
+		return nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// ============================================================
+// 商户登录
+// ============================================================
+
+// SendLoginCode 发送登录验证码
+// @Summary 发送商户登录验证码
+// @Description 验证码发送到商户登记手机号，5分钟内有效，60秒内不能重复发送
+// @Tags 商户自助服务
+// @Accept json
+// @Produce json
+// @Param request body service.MerchantSmsCodeRequest true "商户号和手机号"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merchant-portal/auth/sms-code [post]
+func (h *MerchantPortalHandler) SendLoginCode(c *gin.Context) {
+	var req service.MerchantSmsCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.IP = c.ClientIP()
+
+	if err := h.portalService.SendLoginCode(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "验证码已发送")
+}
+
+// Login 商户验证码登录
+// @Summary 商户登录
+// @Description 商户号+登记手机号+短信验证码登录，返回的令牌只能访问商户自助服务接口
+// @Tags 商户自助服务
+// @Accept json
+// @Produce json
+// @Param request body service.MerchantLoginRequest true "登录信息"
+// @Success 200 {object} service.MerchantLoginResponse
+// @Router /api/v1/merchant-portal/auth/login [post]
+func (h *MerchantPortalHandler) Login(c *gin.Context) {
+	var req service.MerchantLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.portalService.Login(&req)
+	if err != nil {
+		response.Unauthorized(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Refresh 刷新商户访问令牌
+// @Summary 刷新商户访问令牌
+// @Tags 商户自助服务
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "刷新请求"
+// @Success 200 {object} service.MerchantLoginResponse
+// @Router /api/v1/merchant-portal/auth/refresh [post]
+func (h *MerchantPortalHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	resp, err := h.portalService.RefreshAccessToken(req.RefreshToken)
+	if err != nil {
+		response.Unauthorized(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Logout 商户登出
+// @Summary 商户登出
+// @Tags 商户自助服务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body LogoutRequest false "登出请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merchant-portal/auth/logout [post]
+func (h *MerchantPortalHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	c.ShouldBindJSON(&req)
+
+	if req.RefreshToken != "" {
+		h.portalService.Logout(req.RefreshToken)
+	}
+
+	response.SuccessMessage(c, "登出成功")
+}
+
+// ============================================================
+// 商户查询
+// ============================================================
+
+// GetProfile 商户资料与当前费率
+// @Summary 商户资料与当前费率
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.MerchantPortalProfile
+// @Router /api/v1/merchant-portal/profile [get]
+func (h *MerchantPortalHandler) GetProfile(c *gin.Context) {
+	profile, err := h.portalService.GetProfile(middleware.GetCurrentMerchantID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, profile)
+}
+
+// ListTransactions 本商户交易流水
+// @Summary 商户交易流水
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param start_date query string false "开始日期（yyyy-mm-dd）"
+// @Param end_date query string false "结束日期（yyyy-mm-dd，含当天）"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merchant-portal/transactions [get]
+func (h *MerchantPortalHandler) ListTransactions(c *gin.Context) {
+	page, pageSize := h.pagination(c)
+	startTime := h.parseDate(c, "start_date")
+	endTime := h.parseDate(c, "end_date")
+	if endTime != nil {
+		endOfDay := endTime.AddDate(0, 0, 1)
+		endTime = &endOfDay
+	}
+
+	transactions, total, err := h.portalService.ListTransactions(middleware.GetCurrentMerchantID(c), startTime, endTime, page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	list := make([]gin.H, 0, len(transactions))
+	for _, tx := range transactions {
+		list = append(list, gin.H{
+			"order_no":        tx.OrderNo,
+			"trade_no":        tx.TradeNo,
+			"terminal_sn":     tx.TerminalSN,
+			"trade_type":      tx.TradeType,
+			"trade_type_name": getTradeTypeName(tx.TradeType),
+			"pay_type":        tx.PayType,
+			"pay_type_name":   getPayTypeName(tx.PayType),
+			"card_type":       tx.CardType,
+			"card_no":         tx.CardNo,
+			"amount":          tx.Amount,
+			"amount_yuan":     float64(tx.Amount) / 100,
+			"fee":             tx.Fee,
+			"fee_yuan":        float64(tx.Fee) / 100,
+			"d0_fee":          tx.D0Fee,
+			"rate":            tx.Rate,
+			"refund_status":   tx.RefundStatus,
+			"trade_time":      tx.TradeTime,
+		})
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// ListDailySettlements 本商户日结汇总
+// @Summary 商户日结汇总
+// @Description 按交易日汇总消费交易（不含已退款），结算金额=交易金额-手续费-D0手续费，以通道实际结算为准；默认近30天，最多93天
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param start_date query string false "开始日期（yyyy-mm-dd）"
+// @Param end_date query string false "结束日期（yyyy-mm-dd，含当天）"
+// @Success 200 {array} repository.MerchantDailySettlement
+// @Router /api/v1/merchant-portal/settlements/daily [get]
+func (h *MerchantPortalHandler) ListDailySettlements(c *gin.Context) {
+	list, err := h.portalService.ListDailySettlements(middleware.GetCurrentMerchantID(c),
+		h.parseDate(c, "start_date"), h.parseDate(c, "end_date"), time.Now())
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, list)
+}
+
+// ListTerminals 本商户终端
+// @Summary 商户终端
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merchant-portal/terminals [get]
+func (h *MerchantPortalHandler) ListTerminals(c *gin.Context) {
+	terminals, err := h.portalService.ListTerminals(middleware.GetCurrentMerchantID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	list := make([]gin.H, 0, len(terminals))
+	for _, t := range terminals {
+		list = append(list, gin.H{
+			"terminal_sn":  t.TerminalSN,
+			"channel_code": t.ChannelCode,
+			"brand_code":   t.BrandCode,
+			"model_code":   t.ModelCode,
+			"status":       t.Status,
+			"status_name":  getTerminalStatusName(t.Status),
+			"bound_at":     t.BoundAt,
+			"activated_at": t.ActivatedAt,
+		})
+	}
+
+	response.Success(c, list)
+}
+
+// ============================================================
+// 结算卡变更申请（商户）
+// ============================================================
+
+// CreateSettleCardRequest 提交结算卡变更申请
+// @Summary 提交结算卡变更申请
+// @Description 平台审核通过后在通道侧变更，同一商户同时只能有一个待审核申请
+// @Tags 商户自助服务
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateSettleCardRequest true "新结算卡信息"
+// @Success 200 {object} models.MerchantSettleCardRequest
+// @Router /api/v1/merchant-portal/settle-card-requests [post]
+func (h *MerchantPortalHandler) CreateSettleCardRequest(c *gin.Context) {
+	var req service.CreateSettleCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	request, err := h.portalService.CreateSettleCardRequest(middleware.GetCurrentMerchantID(c), &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, request, "已提交，请等待审核")
+}
+
+// ListMySettleCardRequests 本商户的结算卡变更申请
+// @Summary 商户结算卡变更申请列表
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.SettleCardRequestItem
+// @Router /api/v1/merchant-portal/settle-card-requests [get]
+func (h *MerchantPortalHandler) ListMySettleCardRequests(c *gin.Context) {
+	page, pageSize := h.pagination(c)
+
+	list, total, err := h.portalService.ListMerchantSettleCardRequests(middleware.GetCurrentMerchantID(c), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// CancelMySettleCardRequest 撤销结算卡变更申请
+// @Summary 撤销结算卡变更申请
+// @Tags 商户自助服务
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.SettleCardRequestItem
+// @Router /api/v1/merchant-portal/settle-card-requests/{id}/cancel [post]
+func (h *MerchantPortalHandler) CancelMySettleCardRequest(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.portalService.CancelSettleCardRequest(middleware.GetCurrentMerchantID(c), id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, "已撤销")
+}
+
+// ============================================================
+// 结算卡变更申请（平台/代理商）
+// ============================================================
+
+// operator 当前操作人
+func (h *MerchantPortalHandler) operator(c *gin.Context) *service.Operator {
+	return &service.Operator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+}
+
+// ListSettleCardRequests 结算卡变更申请列表
+// @Summary 结算卡变更申请列表
+// @Description 代理商仅查看本人及下级商户的申请
+// @Tags 结算卡变更
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态：1待审核 2已通过 3已驳回 4已撤销"
+// @Param merchant_id query int false "商户ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.SettleCardRequestItem
+// @Router /api/v1/settle-card-requests [get]
+func (h *MerchantPortalHandler) ListSettleCardRequests(c *gin.Context) {
+	page, pageSize := h.pagination(c)
+	status, _ := strconv.Atoi(c.Query("status"))
+	merchantID, _ := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	filter := &repository.SettleCardRequestFilter{
+		Status:     int16(status),
+		MerchantID: merchantID,
+	}
+
+	list, total, err := h.portalService.ListSettleCardRequests(filter, h.operator(c), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetSettleCardRequest 结算卡变更申请详情
+// @Summary 结算卡变更申请详情
+// @Description 平台可查看完整卡号
+// @Tags 结算卡变更
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.SettleCardRequestItem
+// @Router /api/v1/settle-card-requests/{id} [get]
+func (h *MerchantPortalHandler) GetSettleCardRequest(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.portalService.GetSettleCardRequest(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, item)
+}
+
+// ReviewSettleCardRequestRequest 审核结算卡变更申请请求
+type ReviewSettleCardRequestRequest struct {
+	Remark string `json:"remark"`
+}
+
+// ApproveSettleCardRequest 通过结算卡变更申请
+// @Summary 通过结算卡变更申请
+// @Description 仅平台可审核，通过后须在通道侧完成结算卡变更
+// @Tags 结算卡变更
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body ReviewSettleCardRequestRequest false "审核备注"
+// @Success 200 {object} service.SettleCardRequestItem
+// @Router /api/v1/settle-card-requests/{id}/approve [post]
+func (h *MerchantPortalHandler) ApproveSettleCardRequest(c *gin.Context) {
+	h.review(c, true)
+}
+
+// RejectSettleCardRequest 驳回结算卡变更申请
+// @Summary 驳回结算卡变更申请
+// @Tags 结算卡变更
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body ReviewSettleCardRequestRequest true "驳回原因"
+// @Success 200 {object} service.SettleCardRequestItem
+// @Router /api/v1/settle-card-requests/{id}/reject [post]
+func (h *MerchantPortalHandler) RejectSettleCardRequest(c *gin.Context) {
+	h.review(c, false)
+}
+
+// review 审核结算卡变更申请
+func (h *MerchantPortalHandler) review(c *gin.Context, approve bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+	var req ReviewSettleCardRequestRequest
+	c.ShouldBindJSON(&req)
+
+	item, err := h.portalService.ReviewSettleCardRequest(id, approve, req.Remark, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, item, item.StatusName)
+}
+
+// RegisterMerchantPortalRoutes 注册商户自助服务路由
+// /merchant-portal 下的接口使用商户令牌；/settle-card-requests 供平台和代理商处理结算卡变更申请
+func RegisterMerchantPortalRoutes(r *gin.RouterGroup, h *MerchantPortalHandler, portalService *service.MerchantPortalService, authService *service.AuthService) {
+	portal := r.Group("/merchant-portal")
+	{
+		// 公开接口，发送验证码按IP限流
+		codeLimiter := middleware.NewIPRateLimiter(0.2, 5)
+		portal.POST("/auth/sms-code", middleware.IPRateLimitMiddleware(codeLimiter), h.SendLoginCode)
+		portal.POST("/auth/login", h.Login)
+		portal.POST("/auth/refresh", h.Refresh)
+
+		merchantRequired := portal.Group("")
+		merchantRequired.Use(middleware.MerchantAuthMiddleware(portalService))
+		{
+			merchantRequired.POST("/auth/logout", h.Logout)
+			merchantRequired.GET("/profile", h.GetProfile)
+			merchantRequired.GET("/transactions", h.ListTransactions)
+			merchantRequired.GET("/settlements/daily", h.ListDailySettlements)
+			merchantRequired.GET("/terminals", h.ListTerminals)
+			merchantRequired.GET("/settle-card-requests", h.ListMySettleCardRequests)
+			merchantRequired.POST("/settle-card-requests", h.CreateSettleCardRequest)
+			merchantRequired.POST("/settle-card-requests/:id/cancel", h.CancelMySettleCardRequest)
+		}
+	}
+
+	requests := r.Group("/settle-card-requests")
+	requests.Use(middleware.AuthMiddleware(authService))
+	{
+		requests.GET("", h.ListSettleCardRequests)
+		requests.GET("/:id", h.GetSettleCardRequest)
+		requests.POST("/:id/approve", middleware.AdminMiddleware(), h.ApproveSettleCardRequest)
+		requests.POST("/:id/reject", middleware.AdminMiddleware(), h.RejectSettleCardRequest)
+	}
+}
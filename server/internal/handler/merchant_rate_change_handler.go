@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantRateChangeHandler 商户费率修改申请处理器
+type MerchantRateChangeHandler struct {
+	rateChangeService *service.MerchantRateChangeService
+}
+
+// NewMerchantRateChangeHandler 创建商户费率修改申请处理器
+func NewMerchantRateChangeHandler(rateChangeService *service.MerchantRateChangeService) *MerchantRateChangeHandler {
+	return &MerchantRateChangeHandler{
+		rateChangeService: rateChangeService,
+	}
+}
+
+// operator 当前操作人
+func (h *MerchantRateChangeHandler) operator(c *gin.Context) *service.RateChangeOperator {
+	return &service.RateChangeOperator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+}
+
+// ListRequests 费率修改申请列表
+// @Summary 费率修改申请列表
+// @Description 代理商可查看本人及下级商户的申请和待本人审批的申请
+// @Tags 商户费率修改
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态：1待审批 2已驳回 3同步中 4已生效 5同步失败 6已取消"
+// @Param merchant_id query int false "商户ID"
+// @Param to_me query bool false "仅看待本人审批的申请"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.RateChangeItem
+// @Router /api/v1/rate-change-requests [get]
+func (h *MerchantRateChangeHandler) ListRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status, _ := strconv.Atoi(c.Query("status"))
+	merchantID, _ := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	operator := h.operator(c)
+	filter := &repository.RateChangeFilter{
+		Status:     int16(status),
+		MerchantID: merchantID,
+	}
+	if toMe := c.Query("to_me"); toMe == "true" || toMe == "1" {
+		filter.OnlyToMe = true
+		filter.ApproverID = operator.AgentID
+		if operator.IsAdmin {
+			// 平台审批一级代理商的申请
+			filter.ApproverID = 0
+		}
+	}
+
+	list, total, err := h.rateChangeService.ListRequests(filter, operator, page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// CreateRequest 提交费率修改申请
+// @Summary 提交费率修改申请
+// @Description 费率为小数形式（0.006表示0.6%）；超出通道底价/上限直接拒绝，低于所属代理商政策费率须上级审批，否则直接同步到通道，通道成功后本地生效
+// @Tags 商户费率修改
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateRateChangeRequest true "申请信息"
+// @Success 200 {object} models.MerchantRateChangeRequest
+// @Router /api/v1/rate-change-requests [post]
+func (h *MerchantRateChangeHandler) CreateRequest(c *gin.Context) {
+	var req service.CreateRateChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	change, err := h.rateChangeService.CreateRequest(&req, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "提交成功")
+}
+
+// GetRequest 费率修改申请详情
+// @Summary 费率修改申请详情
+// @Tags 商户费率修改
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} service.RateChangeItem
+// @Router /api/v1/rate-change-requests/{id} [get]
+func (h *MerchantRateChangeHandler) GetRequest(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	item, err := h.rateChangeService.GetRequest(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, item)
+}
+
+// Approve 审批通过
+// @Summary 审批通过费率修改申请
+// @Description 由商户所属代理商的上级审批，一级代理商的申请由平台审批；通过后立即同步到通道
+// @Tags 商户费率修改
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body service.ReviewRateChangeRequest false "审批意见"
+// @Success 200 {object} models.MerchantRateChangeRequest
+// @Router /api/v1/rate-change-requests/{id}/approve [post]
+func (h *MerchantRateChangeHandler) Approve(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+	var req service.ReviewRateChangeRequest
+	_ = c.ShouldBindJSON(&req)
+
+	change, err := h.rateChangeService.Approve(id, req.Remark, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "审批成功")
+}
+
+// Reject 驳回申请
+// @Summary 驳回费率修改申请
+// @Tags 商户费率修改
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Param request body service.ReviewRateChangeRequest false "驳回原因"
+// @Success 200 {object} models.MerchantRateChangeRequest
+// @Router /api/v1/rate-change-requests/{id}/reject [post]
+func (h *MerchantRateChangeHandler) Reject(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+	var req service.ReviewRateChangeRequest
+	_ = c.ShouldBindJSON(&req)
+
+	change, err := h.rateChangeService.Reject(id, req.Remark, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "已驳回")
+}
+
+// Cancel 取消申请
+// @Summary 取消费率修改申请
+// @Description 仅待审批或同步失败的申请可取消
+// @Tags 商户费率修改
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} models.MerchantRateChangeRequest
+// @Router /api/v1/rate-change-requests/{id}/cancel [post]
+func (h *MerchantRateChangeHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	change, err := h.rateChangeService.Cancel(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, change, "已取消")
+}
+
+// Resync 重新同步
+// @Summary 重新同步费率到通道
+// @Description 自动重试用完后标记为同步失败的申请，可手动重新同步
+// @Tags 商户费率修改
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "申请ID"
+// @Success 200 {object} models.MerchantRateChangeRequest
+// @Router /api/v1/rate-change-requests/{id}/resync [post]
+func (h *MerchantRateChangeHandler) Resync(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的申请ID")
+		return
+	}
+
+	change, err := h.rateChangeService.Resync(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, change)
+}
+
+// ListMismatches 费率不一致商户报表
+// @Summary 费率不一致商户报表
+// @Description 列出最近同步失败或通道回调费率与本地费率不一致的商户，代理商仅查看本人及下级商户
+// @Tags 商户费率修改
+// @Produce json
+// @Security ApiKeyAuth
+// @Param channel_id query int false "通道ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} repository.RateMismatch
+// @Router /api/v1/rate-change-requests/mismatches [get]
+func (h *MerchantRateChangeHandler) ListMismatches(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	channelID, _ := strconv.ParseInt(c.Query("channel_id"), 10, 64)
+	filter := &repository.RateMismatchFilter{ChannelID: channelID}
+
+	list, total, err := h.rateChangeService.ListMismatches(filter, h.operator(c), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// RegisterMerchantRateChangeRoutes 注册商户费率修改申请路由
+func RegisterMerchantRateChangeRoutes(r *gin.RouterGroup, h *MerchantRateChangeHandler, authService *service.AuthService) {
+	changes := r.Group("/rate-change-requests")
+	changes.Use(middleware.AuthMiddleware(authService))
+	{
+		changes.GET("", h.ListRequests)
+		changes.POST("", h.CreateRequest)
+		changes.GET("/mismatches", h.ListMismatches)
+		changes.GET("/:id", h.GetRequest)
+		changes.POST("/:id/approve", h.Approve)
+		changes.POST("/:id/reject", h.Reject)
+		changes.POST("/:id/cancel", h.Cancel)
+		changes.POST("/:id/resync", h.Resync)
+	}
+}
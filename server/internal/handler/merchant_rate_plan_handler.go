@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantRatePlanHandler 商户费率计划处理器
+type MerchantRatePlanHandler struct {
+	ratePlanService *service.MerchantRatePlanService
+}
+
+// NewMerchantRatePlanHandler 创建商户费率计划处理器
+func NewMerchantRatePlanHandler(ratePlanService *service.MerchantRatePlanService) *MerchantRatePlanHandler {
+	return &MerchantRatePlanHandler{
+		ratePlanService: ratePlanService,
+	}
+}
+
+// operator 当前操作人
+func (h *MerchantRatePlanHandler) operator(c *gin.Context) *service.RateChangeOperator {
+	return &service.RateChangeOperator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+}
+
+// ListPlans 费率计划列表
+// @Summary 费率计划列表
+// @Description 代理商仅查看本人及下级商户的计划
+// @Tags 商户费率计划
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态：1执行中 2已完成 3已取消"
+// @Param merchant_id query int false "商户ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.RatePlanDetail
+// @Router /api/v1/merchant-rate-plans [get]
+func (h *MerchantRatePlanHandler) ListPlans(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status, _ := strconv.Atoi(c.Query("status"))
+	merchantID, _ := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	filter := &repository.RatePlanFilter{
+		Status:     int16(status),
+		MerchantID: merchantID,
+	}
+
+	list, total, err := h.ratePlanService.ListPlans(filter, h.operator(c), page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// CreatePlan 创建费率计划
+// @Summary 创建费率计划
+// @Description promo限时优惠：优惠费率在start_at生效（为空立即生效），end_at到期恢复为revert费率（为0时恢复为当前费率）；steps阶梯调整：按effective_at依次调整。费率为小数形式，须在通道底价/上限内，低于政策费率的计划只能由平台创建
+// @Tags 商户费率计划
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateRatePlanRequest true "计划信息"
+// @Success 200 {object} service.RatePlanDetail
+// @Router /api/v1/merchant-rate-plans [post]
+func (h *MerchantRatePlanHandler) CreatePlan(c *gin.Context) {
+	var req service.CreateRatePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	plan, err := h.ratePlanService.CreatePlan(&req, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, plan, "创建成功")
+}
+
+// GetPlan 费率计划详情
+// @Summary 费率计划详情
+// @Tags 商户费率计划
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "计划ID"
+// @Success 200 {object} service.RatePlanDetail
+// @Router /api/v1/merchant-rate-plans/{id} [get]
+func (h *MerchantRatePlanHandler) GetPlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的计划ID")
+		return
+	}
+
+	plan, err := h.ratePlanService.GetPlan(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, plan)
+}
+
+// CancelPlan 取消费率计划
+// @Summary 取消费率计划
+// @Description 未执行的步骤不再执行，已生效的费率不回退
+// @Tags 商户费率计划
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "计划ID"
+// @Success 200 {object} service.RatePlanDetail
+// @Router /api/v1/merchant-rate-plans/{id}/cancel [post]
+func (h *MerchantRatePlanHandler) CancelPlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的计划ID")
+		return
+	}
+
+	plan, err := h.ratePlanService.CancelPlan(id, h.operator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, plan, "已取消")
+}
+
+// RegisterMerchantRatePlanRoutes 注册商户费率计划路由
+func RegisterMerchantRatePlanRoutes(r *gin.RouterGroup, h *MerchantRatePlanHandler, authService *service.AuthService) {
+	plans := r.Group("/merchant-rate-plans")
+	plans.Use(middleware.AuthMiddleware(authService))
+	{
+		plans.GET("", h.ListPlans)
+		plans.POST("", h.CreatePlan)
+		plans.GET("/:id", h.GetPlan)
+		plans.POST("/:id/cancel", h.CancelPlan)
+	}
+}
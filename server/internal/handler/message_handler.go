@@ -260,6 +260,18 @@ func (h *MessageHandler) GetMessageTypes(c *gin.Context) {
 		{"value": models.MessageTypeAnnouncement, "label": "系统公告", "category": "system"},
 		{"value": models.MessageTypeNewAgent, "label": "新代理注册", "category": "register"},
 		{"value": models.MessageTypeTransaction, "label": "交易通知", "category": "consumption"},
+		{"value": models.MessageTypeWalletTransfer, "label": "钱包划转", "category": "system"},
+		{"value": models.MessageTypeRiskHold, "label": "风控冻结", "category": "system"},
+		{"value": models.MessageTypeStatement, "label": "对账单", "category": "system"},
+		{"value": models.MessageTypeDeductionOverdue, "label": "代扣逾期", "category": "system"},
+		{"value": models.MessageTypeInventoryAlert, "label": "库存预警", "category": "system"},
+		{"value": models.MessageTypeSimRenewal, "label": "流量卡续费提醒", "category": "system"},
+		{"value": models.MessageTypeChurnWarning, "label": "商户流失预警", "category": "system"},
+		{"value": models.MessageTypeRateChange, "label": "费率修改审批", "category": "system"},
+		{"value": models.MessageTypeRatePlan, "label": "费率计划", "category": "system"},
+		{"value": models.MessageTypeKycExpiry, "label": "证件到期提醒", "category": "system"},
+		{"value": models.MessageTypeSettleCard, "label": "结算卡变更", "category": "system"},
+		{"value": models.MessageTypeOnboarding, "label": "商户进件", "category": "system"},
 	}
 
 	categories := []gin.H{
@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SimCardHandler 流量卡管理处理器
+type SimCardHandler struct {
+	simCardService *service.SimCardService
+}
+
+// NewSimCardHandler 创建流量卡管理处理器
+func NewSimCardHandler(simCardService *service.SimCardService) *SimCardHandler {
+	return &SimCardHandler{
+		simCardService: simCardService,
+	}
+}
+
+// simCardOperator 当前操作人
+func simCardOperator(c *gin.Context) *service.SimCardOperator {
+	return &service.SimCardOperator{
+		UserID:  middleware.GetCurrentUserID(c),
+		Name:    middleware.GetCurrentUsername(c),
+		AgentID: middleware.GetCurrentAgentID(c),
+		IsAdmin: middleware.IsAdmin(c),
+	}
+}
+
+// simCardPage 解析分页参数
+func simCardPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// List 流量卡列表
+// @Summary 流量卡列表
+// @Description 代理商只能查看本人及下级持有或装在其终端上的流量卡
+// @Tags 流量卡管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param iccid query string false "ICCID（前缀匹配）"
+// @Param terminal_sn query string false "终端SN"
+// @Param carrier query string false "运营商：cmcc/cucc/ctcc/other"
+// @Param status query int false "状态：1库存 2已装机 3已停用"
+// @Param channel_id query int false "通道ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.SimCardListItem
+// @Router /api/v1/sim-cards [get]
+func (h *SimCardHandler) List(c *gin.Context) {
+	page, pageSize := simCardPage(c)
+	status, _ := strconv.Atoi(c.Query("status"))
+	channelID, _ := strconv.ParseInt(c.Query("channel_id"), 10, 64)
+	filter := &repository.SimCardFilter{
+		ICCID:      c.Query("iccid"),
+		TerminalSN: c.Query("terminal_sn"),
+		Carrier:    c.Query("carrier"),
+		Status:     int16(status),
+		ChannelID:  channelID,
+	}
+
+	list, total, err := h.simCardService.ListCards(filter, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// ListRenewals 即将续费的流量卡
+// @Summary 即将续费的流量卡
+// @Description 按预计续费时间升序，包含已到期未缴费的流量卡
+// @Tags 流量卡管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param days query int false "未来天数，默认30"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.SimCardListItem
+// @Router /api/v1/sim-cards/renewals [get]
+func (h *SimCardHandler) ListRenewals(c *gin.Context) {
+	page, pageSize := simCardPage(c)
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	list, total, err := h.simCardService.ListRenewals(days, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c), page, pageSize, time.Now())
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// Get 流量卡详情
+// @Summary 流量卡详情
+// @Description 包含装卡/换卡记录
+// @Tags 流量卡管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "流量卡ID"
+// @Success 200 {object} service.SimCardDetail
+// @Router /api/v1/sim-cards/{id} [get]
+func (h *SimCardHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的流量卡ID")
+		return
+	}
+
+	detail, err := h.simCardService.GetCard(id, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// GetTerminalCards 终端流量卡
+// @Summary 终端流量卡
+// @Description 终端当前所装流量卡及换卡记录
+// @Tags 流量卡管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param terminal_sn path string true "终端SN"
+// @Success 200 {object} service.TerminalSimCards
+// @Router /api/v1/sim-cards/terminals/{terminal_sn} [get]
+func (h *SimCardHandler) GetTerminalCards(c *gin.Context) {
+	result, err := h.simCardService.GetTerminalCards(c.Param("terminal_sn"), middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Bind 装卡/换卡
+// @Summary 装卡/换卡
+// @Description 终端已装其他流量卡时视为换卡，原卡拆下退回终端所属代理商库存
+// @Tags 流量卡管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.BindSimCardRequest true "装卡信息"
+// @Success 200 {object} models.SimCard
+// @Router /api/v1/sim-cards/bind [post]
+func (h *SimCardHandler) Bind(c *gin.Context) {
+	var req service.BindSimCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	sim, err := h.simCardService.BindCard(&req, simCardOperator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, sim, "装卡成功")
+}
+
+// UnbindSimCardRequest 拆卡请求
+type UnbindSimCardRequest struct {
+	Remark string `json:"remark"`
+}
+
+// Unbind 拆卡
+// @Summary 拆卡
+// @Tags 流量卡管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "流量卡ID"
+// @Param request body UnbindSimCardRequest false "备注"
+// @Success 200 {object} models.SimCard
+// @Router /api/v1/sim-cards/{id}/unbind [post]
+func (h *SimCardHandler) Unbind(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的流量卡ID")
+		return
+	}
+	var req UnbindSimCardRequest
+	_ = c.ShouldBindJSON(&req)
+
+	sim, err := h.simCardService.UnbindCard(id, req.Remark, simCardOperator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, sim, "拆卡成功")
+}
+
+// Create 流量卡批量入库
+// @Summary 流量卡批量入库
+// @Description 单次最多1000张，ICCID重复或校验失败的跳过；填写terminal_sn时入库同时装卡
+// @Tags 流量卡管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateSimCardsRequest true "流量卡列表"
+// @Success 200 {object} service.SimCardBatchResult
+// @Router /api/v1/sim-cards [post]
+func (h *SimCardHandler) Create(c *gin.Context) {
+	var req service.CreateSimCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.simCardService.CreateCards(&req, simCardOperator(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Update 更新流量卡
+// @Summary 更新流量卡
+// @Description 更新后重新预测续费时间；已装机的卡须先拆卡才能停用
+// @Tags 流量卡管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "流量卡ID"
+// @Param request body service.UpdateSimCardRequest true "流量卡信息"
+// @Success 200 {object} models.SimCard
+// @Router /api/v1/sim-cards/{id} [put]
+func (h *SimCardHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的流量卡ID")
+		return
+	}
+	var req service.UpdateSimCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	sim, err := h.simCardService.UpdateCard(id, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, sim)
+}
+
+// GetConfig 获取续费预测及提醒配置
+// @Summary 获取续费预测及提醒配置
+// @Tags 流量卡管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.SimCardConfig
+// @Router /api/v1/sim-cards/config [get]
+func (h *SimCardHandler) GetConfig(c *gin.Context) {
+	config, err := h.simCardService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新续费预测及提醒配置
+// @Summary 更新续费预测及提醒配置
+// @Description 未缴过费的卡按开卡（无则终端激活）时间+首次扣费天数预测，已缴费的按最近缴费时间+续费周期预测
+// @Tags 流量卡管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateSimCardConfigRequest true "配置"
+// @Success 200 {object} models.SimCardConfig
+// @Router /api/v1/sim-cards/config [put]
+func (h *SimCardHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateSimCardConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.simCardService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// RegisterSimCardRoutes 注册流量卡管理路由
+func RegisterSimCardRoutes(r *gin.RouterGroup, h *SimCardHandler, authService *service.AuthService) {
+	sims := r.Group("/sim-cards")
+	sims.Use(middleware.AuthMiddleware(authService))
+	{
+		sims.GET("", h.List)
+		sims.GET("/renewals", h.ListRenewals)
+		sims.GET("/terminals/:terminal_sn", h.GetTerminalCards)
+		sims.POST("/bind", h.Bind)
+		sims.GET("/:id", h.Get)
+		sims.POST("/:id/unbind", h.Unbind)
+	}
+
+	admin := r.Group("/sim-cards")
+	admin.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		admin.POST("", h.Create)
+		admin.GET("/config", h.GetConfig)
+		admin.PUT("/config", h.UpdateConfig)
+		admin.PUT("/:id", h.Update)
+	}
+}
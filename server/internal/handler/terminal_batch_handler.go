@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// terminalBatchMaxFileSize 上传文件大小上限
+const terminalBatchMaxFileSize = 10 << 20
+
+// TerminalBatchHandler 终端批量上传处理器
+type TerminalBatchHandler struct {
+	batchService *service.TerminalBatchService
+}
+
+// NewTerminalBatchHandler 创建终端批量上传处理器
+func NewTerminalBatchHandler(batchService *service.TerminalBatchService) *TerminalBatchHandler {
+	return &TerminalBatchHandler{
+		batchService: batchService,
+	}
+}
+
+// UploadImport 上传文件批量入库
+// @Summary 上传XLSX/CSV批量入库终端
+// @Description 表头可含 SN/结束SN/前缀/通道编码/品牌编码/型号编码，无表头时第1列为SN、第2列为号段结束SN；超过200台转为后台任务
+// @Tags 终端批量
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "XLSX或CSV文件"
+// @Param channel_id formData int true "通道ID"
+// @Param channel_code formData string false "通道编码"
+// @Param brand_code formData string true "品牌编码"
+// @Param model_code formData string true "型号编码"
+// @Success 200 {object} models.TerminalBatchJob
+// @Router /api/v1/terminal-batch-jobs/import [post]
+func (h *TerminalBatchHandler) UploadImport(c *gin.Context) {
+	h.upload(c, models.TerminalBatchJobTypeImport)
+}
+
+// UploadDistribute 上传文件批量下发
+// @Summary 上传XLSX/CSV批量下发终端
+// @Description 逐行校验SN重复、通道/品牌/型号、归属和状态后逐台下发；超过200台转为后台任务
+// @Tags 终端批量
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "XLSX或CSV文件"
+// @Param to_agent_id formData int true "接收方代理商ID"
+// @Param channel_id formData int false "限定通道ID"
+// @Param brand_code formData string false "限定品牌编码"
+// @Param model_code formData string false "限定型号编码"
+// @Param goods_price formData int false "货款金额（分）"
+// @Param deduction_type formData int true "1:一次性付款 2:分期代扣 3:货款代扣"
+// @Param deduction_periods formData int false "分期期数"
+// @Param deduction_source formData int false "货款代扣来源"
+// @Param remark formData string false "备注"
+// @Success 200 {object} models.TerminalBatchJob
+// @Router /api/v1/terminal-batch-jobs/distribute [post]
+func (h *TerminalBatchHandler) UploadDistribute(c *gin.Context) {
+	h.upload(c, models.TerminalBatchJobTypeDistribute)
+}
+
+// UploadRecall 上传文件批量回拨
+// @Summary 上传XLSX/CSV批量回拨终端
+// @Description 逐行校验SN重复、通道/品牌/型号、归属和状态后逐台回拨；超过200台转为后台任务
+// @Tags 终端批量
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param file formData file true "XLSX或CSV文件"
+// @Param to_agent_id formData int true "接收方代理商ID"
+// @Param channel_id formData int false "限定通道ID"
+// @Param brand_code formData string false "限定品牌编码"
+// @Param model_code formData string false "限定型号编码"
+// @Param remark formData string false "备注"
+// @Success 200 {object} models.TerminalBatchJob
+// @Router /api/v1/terminal-batch-jobs/recall [post]
+func (h *TerminalBatchHandler) UploadRecall(c *gin.Context) {
+	h.upload(c, models.TerminalBatchJobTypeRecall)
+}
+
+func (h *TerminalBatchHandler) upload(c *gin.Context, jobType string) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "请上传文件")
+		return
+	}
+	if file.Size > terminalBatchMaxFileSize {
+		response.BadRequest(c, "文件大小不能超过10MB")
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		response.BadRequest(c, "读取文件失败")
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		response.BadRequest(c, "读取文件失败")
+		return
+	}
+
+	channelID, _ := strconv.ParseInt(c.PostForm("channel_id"), 10, 64)
+	toAgentID, _ := strconv.ParseInt(c.PostForm("to_agent_id"), 10, 64)
+	goodsPrice, _ := strconv.ParseInt(c.PostForm("goods_price"), 10, 64)
+	deductionType, _ := strconv.ParseInt(c.PostForm("deduction_type"), 10, 16)
+	deductionPeriods, _ := strconv.Atoi(c.PostForm("deduction_periods"))
+	deductionSource, _ := strconv.ParseInt(c.DefaultPostForm("deduction_source", "3"), 10, 16)
+
+	job, err := h.batchService.CreateJob(&service.TerminalBatchUploadRequest{
+		JobType:          jobType,
+		FileName:         file.Filename,
+		Data:             data,
+		AgentID:          middleware.GetCurrentAgentID(c),
+		CreatedBy:        middleware.GetCurrentUserID(c),
+		Source:           getRequestSource(c),
+		ChannelID:        channelID,
+		ChannelCode:      c.PostForm("channel_code"),
+		BrandCode:        c.PostForm("brand_code"),
+		ModelCode:        c.PostForm("model_code"),
+		ToAgentID:        toAgentID,
+		GoodsPrice:       goodsPrice,
+		DeductionType:    int16(deductionType),
+		DeductionPeriods: deductionPeriods,
+		DeductionSource:  int16(deductionSource),
+		Remark:           c.PostForm("remark"),
+	})
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if job.Status == models.TerminalBatchJobStatusPending {
+		response.SuccessWithMessage(c, job, "文件较大，已转为后台任务处理")
+		return
+	}
+	response.Success(c, job)
+}
+
+// GetJobList 批量任务列表
+// @Summary 获取终端批量任务列表
+// @Tags 终端批量
+// @Produce json
+// @Security ApiKeyAuth
+// @Param job_type query string false "任务类型: import/distribute/recall"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/terminal-batch-jobs [get]
+func (h *TerminalBatchHandler) GetJobList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	agentID := middleware.GetCurrentAgentID(c)
+	if middleware.IsAdmin(c) {
+		agentID = 0
+	}
+
+	list, total, err := h.batchService.ListJobs(agentID, c.Query("job_type"), page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetJobDetail 批量任务详情
+// @Summary 获取终端批量任务详情（含进度与前200条错误行）
+// @Tags 终端批量
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} models.TerminalBatchJob
+// @Router /api/v1/terminal-batch-jobs/{id} [get]
+func (h *TerminalBatchHandler) GetJobDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	job, err := h.batchService.GetJob(id, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.Success(c, job)
+}
+
+// DownloadErrorReport 下载错误报告
+// @Summary 下载终端批量任务的逐行错误报告（CSV）
+// @Tags 终端批量
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {file} file
+// @Router /api/v1/terminal-batch-jobs/{id}/error-report [get]
+func (h *TerminalBatchHandler) DownloadErrorReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	job, data, err := h.batchService.ExportErrorReport(id, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_errors.csv", job.JobNo))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+}
+
+// RegisterTerminalBatchRoutes 注册终端批量上传路由
+func RegisterTerminalBatchRoutes(r *gin.RouterGroup, h *TerminalBatchHandler, authService *service.AuthService) {
+	jobs := r.Group("/terminal-batch-jobs")
+	jobs.Use(middleware.AuthMiddleware(authService))
+	{
+		jobs.GET("", h.GetJobList)
+		jobs.POST("/import", h.UploadImport)
+		jobs.POST("/distribute", h.UploadDistribute)
+		jobs.POST("/recall", h.UploadRecall)
+		jobs.GET("/:id", h.GetJobDetail)
+		jobs.GET("/:id/error-report", h.DownloadErrorReport)
+	}
+}
@@ -137,7 +137,7 @@ func getTerminalStatusGroup(t *models.Terminal) string {
 	switch t.Status {
 	case models.TerminalStatusPending:
 		return "unstock"
-	case models.TerminalStatusAllocated:
+	case models.TerminalStatusAllocated, models.TerminalStatusUnbound, models.TerminalStatusRecycled:
 		if t.MerchantID == nil {
 			return "unbound"
 		}
@@ -533,6 +533,7 @@ func RegisterTerminalRoutes(r *gin.RouterGroup, h *TerminalHandler, authService
 		// 5. 终端详情相关 - 参数路径必须放在最后
 		terminals.GET("/:sn", h.GetTerminalDetail)
 		terminals.GET("/:sn/flow-logs", h.GetTerminalFlowLogs) // 流动记录API
+		terminals.GET("/:sn/timeline", h.GetTerminalTimeline)  // 状态时间线API
 		terminals.GET("/:sn/policy", h.GetTerminalPolicy)
 	}
 }
@@ -827,3 +828,59 @@ func (h *TerminalHandler) GetTerminalFlowLogs(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// GetTerminalTimeline 获取终端状态时间线
+// @Summary 获取终端状态时间线
+// @Description 按SN获取终端生命周期状态流转记录（入库、下发确认、绑定、激活、解绑、回拨确认），含操作人与来源单据
+// @Tags 终端管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param sn path string true "终端SN"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/terminals/{sn}/timeline [get]
+func (h *TerminalHandler) GetTerminalTimeline(c *gin.Context) {
+	agentID := middleware.GetCurrentAgentID(c)
+	sn := c.Param("sn")
+
+	// 验证终端权限
+	terminal, err := h.terminalRepo.FindBySN(sn)
+	if err != nil || terminal == nil {
+		response.NotFound(c, "终端不存在")
+		return
+	}
+
+	if terminal.OwnerAgentID != agentID && !middleware.IsAdmin(c) {
+		response.Forbidden(c, "无权访问该终端")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := h.terminalService.GetTerminalTimeline(sn, page, pageSize)
+	if err != nil {
+		response.InternalError(c, "获取终端时间线失败: "+err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"terminal": gin.H{
+			"terminal_sn":    terminal.TerminalSN,
+			"status":         terminal.Status,
+			"status_name":    getTerminalStatusName(terminal.Status),
+			"owner_agent_id": terminal.OwnerAgentID,
+		},
+		"list":      list,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
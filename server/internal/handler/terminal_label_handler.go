@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TerminalLabelHandler 终端标签处理器
+type TerminalLabelHandler struct {
+	labelService *service.TerminalLabelService
+}
+
+// NewTerminalLabelHandler 创建终端标签处理器
+func NewTerminalLabelHandler(labelService *service.TerminalLabelService) *TerminalLabelHandler {
+	return &TerminalLabelHandler{
+		labelService: labelService,
+	}
+}
+
+// Export 生成终端标签
+// @Summary 生成终端标签
+// @Description 为入库批次或指定SN生成标签：每张标签含SN条码（Code128）及二维码（SN、通道编码、校验地址）。
+// @Description pdf为A4标签页（排版可配置标签尺寸及行列），zip为单张标签PNG压缩包。代理商只能打印本人及下级的终端
+// @Tags 终端标签
+// @Accept json
+// @Produce application/pdf,application/zip
+// @Security ApiKeyAuth
+// @Param format query string false "输出格式 pdf/zip，默认pdf"
+// @Param request body service.TerminalLabelRequest true "标签请求"
+// @Success 200 {file} file
+// @Router /api/v1/terminal-labels/export [post]
+func (h *TerminalLabelHandler) Export(c *gin.Context) {
+	var req service.TerminalLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	agentID := middleware.GetCurrentAgentID(c)
+	isAdmin := middleware.IsAdmin(c)
+
+	var file *service.TerminalLabelFile
+	var err error
+	var contentType string
+	switch c.DefaultQuery("format", "pdf") {
+	case "pdf":
+		file, err = h.labelService.GeneratePDF(&req, agentID, isAdmin)
+		contentType = "application/pdf"
+	case "zip":
+		file, err = h.labelService.GenerateZIP(&req, agentID, isAdmin)
+		contentType = "application/zip"
+	default:
+		response.BadRequest(c, "不支持的输出格式")
+		return
+	}
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+file.Filename)
+	c.Header("X-Label-Count", strconv.Itoa(file.Count))
+	c.Data(http.StatusOK, contentType, file.Data)
+}
+
+// TerminalLabelScanRequest 扫码请求
+type TerminalLabelScanRequest struct {
+	Content string `json:"content" binding:"required"` // 扫码内容：二维码为校验地址，条码为终端SN
+}
+
+// Scan 扫码反查终端
+// @Summary 扫码反查终端
+// @Description 扫描标签二维码（校验签名）或条码，返回终端信息及当前归属
+// @Tags 终端标签
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body TerminalLabelScanRequest true "扫码内容"
+// @Success 200 {object} service.TerminalLabelScanResult
+// @Router /api/v1/terminal-labels/scan [post]
+func (h *TerminalLabelHandler) Scan(c *gin.Context) {
+	var req TerminalLabelScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.labelService.Scan(req.Content, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Verify 标签真伪校验（公开）
+// @Summary 标签真伪校验
+// @Description 标签二维码指向的校验地址使用，无需登录，仅返回标签是否有效及终端品牌型号
+// @Tags 终端标签
+// @Produce json
+// @Param sn query string true "终端SN"
+// @Param ch query string false "通道编码"
+// @Param sig query string true "标签签名"
+// @Success 200 {object} service.TerminalLabelVerifyResult
+// @Router /api/v1/terminal-labels/verify [get]
+func (h *TerminalLabelHandler) Verify(c *gin.Context) {
+	response.Success(c, h.labelService.Verify(c.Query("sn"), c.Query("ch"), c.Query("sig")))
+}
+
+// RegisterTerminalLabelRoutes 注册终端标签路由
+func RegisterTerminalLabelRoutes(r *gin.RouterGroup, h *TerminalLabelHandler, authService *service.AuthService) {
+	labels := r.Group("/terminal-labels")
+	labels.GET("/verify", h.Verify)
+
+	authed := labels.Group("")
+	authed.Use(middleware.AuthMiddleware(authService))
+	{
+		authed.POST("/export", h.Export)
+		authed.POST("/scan", h.Scan)
+	}
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TerminalRecallSettlementHandler 终端回拨结算处理器
+type TerminalRecallSettlementHandler struct {
+	settlementService *service.TerminalRecallSettlementService
+}
+
+// NewTerminalRecallSettlementHandler 创建终端回拨结算处理器
+func NewTerminalRecallSettlementHandler(settlementService *service.TerminalRecallSettlementService) *TerminalRecallSettlementHandler {
+	return &TerminalRecallSettlementHandler{
+		settlementService: settlementService,
+	}
+}
+
+// GetSettlement 获取回拨结算详情
+// @Summary 获取回拨结算详情
+// @Description 返回回拨记录上的结算汇总及逐项明细（冲减未扣货款/退还已扣货款/追回奖励），回拨双方或管理员可查看
+// @Tags 终端回拨结算
+// @Produce json
+// @Security ApiKeyAuth
+// @Param recall_id path int true "回拨记录ID"
+// @Success 200 {object} service.TerminalRecallSettlementDetail
+// @Router /api/v1/terminal-recall-settlements/{recall_id} [get]
+func (h *TerminalRecallSettlementHandler) GetSettlement(c *gin.Context) {
+	recallID, err := strconv.ParseInt(c.Param("recall_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的回拨记录ID")
+		return
+	}
+
+	detail, err := h.settlementService.GetSettlement(recallID, middleware.GetCurrentAgentID(c), middleware.IsAdmin(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// SettleRecall 手动结算回拨
+// @Summary 手动结算回拨
+// @Description 回拨确认时自动结算失败的，可由管理员重试；已结算的回拨不会重复结算
+// @Tags 终端回拨结算
+// @Produce json
+// @Security ApiKeyAuth
+// @Param recall_id path int true "回拨记录ID"
+// @Success 200 {object} models.TerminalRecall
+// @Router /api/v1/terminal-recall-settlements/{recall_id}/settle [post]
+func (h *TerminalRecallSettlementHandler) SettleRecall(c *gin.Context) {
+	recallID, err := strconv.ParseInt(c.Param("recall_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的回拨记录ID")
+		return
+	}
+
+	recall, err := h.settlementService.SettleRecall(recallID, middleware.GetCurrentUserID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, recall, "结算完成")
+}
+
+// RetryRewardClawback 继续追回奖励
+// @Summary 继续追回奖励
+// @Description 回拨结算时钱包余额不足、部分追回的激活奖励/押金返现，由管理员在钱包有余额后继续追回
+// @Tags 终端回拨结算
+// @Produce json
+// @Security ApiKeyAuth
+// @Param recall_id path int true "回拨记录ID"
+// @Success 200 {object} models.TerminalRecall
+// @Router /api/v1/terminal-recall-settlements/{recall_id}/retry-clawback [post]
+func (h *TerminalRecallSettlementHandler) RetryRewardClawback(c *gin.Context) {
+	recallID, err := strconv.ParseInt(c.Param("recall_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的回拨记录ID")
+		return
+	}
+
+	recall, err := h.settlementService.RetryRewardClawback(recallID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, recall, "追回完成")
+}
+
+// GetConfig 获取回拨结算规则
+// @Summary 获取回拨结算规则
+// @Tags 终端回拨结算
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TerminalRecallSettlementConfig
+// @Router /api/v1/terminal-recall-settlements/config [get]
+func (h *TerminalRecallSettlementHandler) GetConfig(c *gin.Context) {
+	config, err := h.settlementService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新回拨结算规则
+// @Summary 更新回拨结算规则
+// @Description 是否冲减未扣货款、是否及按何比例退还已扣货款、是否追回激活奖励/押金返现
+// @Tags 终端回拨结算
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateTerminalRecallSettlementConfigRequest true "规则"
+// @Success 200 {object} models.TerminalRecallSettlementConfig
+// @Router /api/v1/terminal-recall-settlements/config [put]
+func (h *TerminalRecallSettlementHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateTerminalRecallSettlementConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.settlementService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// RegisterTerminalRecallSettlementRoutes 注册终端回拨结算路由
+func RegisterTerminalRecallSettlementRoutes(r *gin.RouterGroup, h *TerminalRecallSettlementHandler, authService *service.AuthService) {
+	settlements := r.Group("/terminal-recall-settlements")
+	settlements.Use(middleware.AuthMiddleware(authService))
+	{
+		settlements.GET("/config", middleware.AdminMiddleware(), h.GetConfig)
+		settlements.PUT("/config", middleware.AdminMiddleware(), h.UpdateConfig)
+
+		settlements.GET("/:recall_id", h.GetSettlement)
+		settlements.POST("/:recall_id/settle", middleware.AdminMiddleware(), h.SettleRecall)
+		settlements.POST("/:recall_id/retry-clawback", middleware.AdminMiddleware(), h.RetryRewardClawback)
+	}
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"strconv"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TerminalRewardLifecycleHandler 终端奖励进度自动化处理器
+type TerminalRewardLifecycleHandler struct {
+	lifecycleService *service.TerminalRewardLifecycleService
+}
+
+// NewTerminalRewardLifecycleHandler 创建终端奖励进度自动化处理器
+func NewTerminalRewardLifecycleHandler(lifecycleService *service.TerminalRewardLifecycleService) *TerminalRewardLifecycleHandler {
+	return &TerminalRewardLifecycleHandler{
+		lifecycleService: lifecycleService,
+	}
+}
+
+// GetConfig 获取奖励进度自动化规则
+// @Summary 获取奖励进度自动化规则
+// @Tags 终端奖励进度
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.RewardProgressConfig
+// @Router /api/v1/terminal-reward-rules/config [get]
+func (h *TerminalRewardLifecycleHandler) GetConfig(c *gin.Context) {
+	config, err := h.lifecycleService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新奖励进度自动化规则
+// @Summary 更新奖励进度自动化规则
+// @Description start_event：bind绑定时开启/activate激活时开启；rebind_mode：restart解绑终止、重新绑定重新开始，continue解绑不终止、换绑沿用原进度，once解绑终止、终端仅享受一次
+// @Tags 终端奖励进度
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateRewardProgressConfigRequest true "规则"
+// @Success 200 {object} models.RewardProgressConfig
+// @Router /api/v1/terminal-reward-rules/config [put]
+func (h *TerminalRewardLifecycleHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateRewardProgressConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.lifecycleService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// parseOptionalInt64Query 解析可选的整数查询参数
+func parseOptionalInt64Query(c *gin.Context, key string) *int64 {
+	v, err := strconv.ParseInt(c.Query(key), 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// ListAssignments 模版适用范围列表
+// @Summary 模版适用范围列表
+// @Tags 终端奖励进度
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID（0为平台默认）"
+// @Param channel_id query int false "通道ID（0为全部通道）"
+// @Param template_id query int false "奖励模版ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} models.RewardTemplateAssignment
+// @Router /api/v1/terminal-reward-rules/assignments [get]
+func (h *TerminalRewardLifecycleHandler) ListAssignments(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	list, total, err := h.lifecycleService.ListAssignments(
+		parseOptionalInt64Query(c, "agent_id"),
+		parseOptionalInt64Query(c, "channel_id"),
+		parseOptionalInt64Query(c, "template_id"),
+		page, pageSize,
+	)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// CreateAssignment 新增模版适用范围
+// @Summary 新增模版适用范围
+// @Description 终端按所属代理商逐级向上匹配，agent_id=0为平台默认；同一代理商下指定通道优先于全部通道（channel_id=0），再按优先级
+// @Tags 终端奖励进度
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.RewardTemplateAssignmentRequest true "适用范围"
+// @Success 200 {object} models.RewardTemplateAssignment
+// @Router /api/v1/terminal-reward-rules/assignments [post]
+func (h *TerminalRewardLifecycleHandler) CreateAssignment(c *gin.Context) {
+	var req service.RewardTemplateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	assignment, err := h.lifecycleService.CreateAssignment(&req, middleware.GetCurrentUserID(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, assignment)
+}
+
+// UpdateAssignment 更新模版适用范围
+// @Summary 更新模版适用范围
+// @Tags 终端奖励进度
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "适用范围ID"
+// @Param request body service.RewardTemplateAssignmentRequest true "适用范围"
+// @Success 200 {object} models.RewardTemplateAssignment
+// @Router /api/v1/terminal-reward-rules/assignments/{id} [put]
+func (h *TerminalRewardLifecycleHandler) UpdateAssignment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.RewardTemplateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	assignment, err := h.lifecycleService.UpdateAssignment(id, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, assignment)
+}
+
+// DeleteAssignment 删除模版适用范围
+// @Summary 删除模版适用范围
+// @Description 已开启的奖励进度使用模版快照，不受影响
+// @Tags 终端奖励进度
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "适用范围ID"
+// @Success 200 {object} response.Response
+// @Router /api/v1/terminal-reward-rules/assignments/{id} [delete]
+func (h *TerminalRewardLifecycleHandler) DeleteAssignment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.lifecycleService.DeleteAssignment(id); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "删除成功")
+}
+
+// ResolveTerminal 预览终端匹配的奖励模版
+// @Summary 预览终端匹配的奖励模版
+// @Description 按终端当前所属代理商、通道返回生效的适用范围及奖励进度数
+// @Tags 终端奖励进度
+// @Produce json
+// @Security ApiKeyAuth
+// @Param terminal_sn path string true "终端SN"
+// @Success 200 {object} service.TerminalRewardResolveResult
+// @Router /api/v1/terminal-reward-rules/terminals/{terminal_sn} [get]
+func (h *TerminalRewardLifecycleHandler) ResolveTerminal(c *gin.Context) {
+	result, err := h.lifecycleService.ResolveTerminal(c.Param("terminal_sn"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Backfill 存量终端补建奖励进度
+// @Summary 存量终端补建奖励进度
+// @Description 为已绑定/已激活但没有进行中奖励进度的终端按绑定（激活）时间补建；结果含last_id，has_more为true时以after_id继续
+// @Tags 终端奖励进度
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.RewardProgressBackfillRequest true "补建参数"
+// @Success 200 {object} service.RewardProgressBackfillResult
+// @Router /api/v1/terminal-reward-rules/backfill [post]
+func (h *TerminalRewardLifecycleHandler) Backfill(c *gin.Context) {
+	var req service.RewardProgressBackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	result, err := h.lifecycleService.Backfill(&req)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// RegisterTerminalRewardLifecycleRoutes 注册终端奖励进度自动化路由
+func RegisterTerminalRewardLifecycleRoutes(r *gin.RouterGroup, h *TerminalRewardLifecycleHandler, authService *service.AuthService) {
+	rules := r.Group("/terminal-reward-rules")
+	rules.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		rules.GET("/config", h.GetConfig)
+		rules.PUT("/config", h.UpdateConfig)
+
+		rules.GET("/assignments", h.ListAssignments)
+		rules.POST("/assignments", h.CreateAssignment)
+		rules.PUT("/assignments/:id", h.UpdateAssignment)
+		rules.DELETE("/assignments/:id", h.DeleteAssignment)
+
+		rules.GET("/terminals/:terminal_sn", h.ResolveTerminal)
+		rules.POST("/backfill", h.Backfill)
+	}
+}
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TxRiskHandler 交易风控处理器
+type TxRiskHandler struct {
+	txRiskService *service.TxRiskService
+}
+
+// NewTxRiskHandler 创建交易风控处理器
+func NewTxRiskHandler(txRiskService *service.TxRiskService) *TxRiskHandler {
+	return &TxRiskHandler{
+		txRiskService: txRiskService,
+	}
+}
+
+// ListEvents 风控事件列表
+// @Summary 风控事件列表
+// @Tags 交易风控
+// @Produce json
+// @Security ApiKeyAuth
+// @Param status query int false "状态：1待审核 2确认风险 3已排除"
+// @Param merchant_id query int false "商户ID"
+// @Param agent_id query int false "代理商ID"
+// @Param rule query string false "命中规则：same_card/near_limit/night/spike/shared_id"
+// @Param min_score query int false "最低分数"
+// @Param held query bool false "是否已冻结分润"
+// @Param start_date query string false "开始日期 YYYY-MM-DD"
+// @Param end_date query string false "结束日期 YYYY-MM-DD"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {array} service.TxRiskEventItem
+// @Router /api/v1/tx-risk/events [get]
+func (h *TxRiskHandler) ListEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	status, _ := strconv.Atoi(c.Query("status"))
+	minScore, _ := strconv.Atoi(c.Query("min_score"))
+	merchantID, _ := strconv.ParseInt(c.Query("merchant_id"), 10, 64)
+	agentID, _ := strconv.ParseInt(c.Query("agent_id"), 10, 64)
+	filter := &repository.TxRiskEventFilter{
+		Status:     int16(status),
+		MerchantID: merchantID,
+		AgentID:    agentID,
+		Rule:       c.Query("rule"),
+		MinScore:   minScore,
+	}
+	if heldStr := c.Query("held"); heldStr != "" {
+		held := heldStr == "true" || heldStr == "1"
+		filter.Held = &held
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+		if err != nil {
+			response.BadRequest(c, "开始日期格式错误")
+			return
+		}
+		filter.StartTime = &t
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+		if err != nil {
+			response.BadRequest(c, "结束日期格式错误")
+			return
+		}
+		t = t.AddDate(0, 0, 1)
+		filter.EndTime = &t
+	}
+
+	list, total, err := h.txRiskService.ListEvents(filter, page, pageSize)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetEvent 风控事件详情
+// @Summary 风控事件详情
+// @Description 包含该笔交易产生的分润及关联的未解除冻结
+// @Tags 交易风控
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "事件ID"
+// @Success 200 {object} service.TxRiskEventDetail
+// @Router /api/v1/tx-risk/events/{id} [get]
+func (h *TxRiskHandler) GetEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的事件ID")
+		return
+	}
+
+	detail, err := h.txRiskService.GetEvent(id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, detail)
+}
+
+// ReviewEvent 审核风控事件
+// @Summary 审核风控事件
+// @Description confirm确认风险（hold=true时补充冻结分润），dismiss排除风险并解除关联冻结
+// @Tags 交易风控
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "事件ID"
+// @Param request body service.ReviewTxRiskEventRequest true "审核结果"
+// @Success 200 {object} models.TxRiskEvent
+// @Router /api/v1/tx-risk/events/{id}/review [post]
+func (h *TxRiskHandler) ReviewEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的事件ID")
+		return
+	}
+	var req service.ReviewTxRiskEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	event, err := h.txRiskService.ReviewEvent(id, &req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessWithMessage(c, event, "审核成功")
+}
+
+// PreviewTransaction 交易风控试算
+// @Summary 交易风控试算
+// @Description 按当前规则配置检测指定交易，不生成事件、不冻结分润
+// @Tags 交易风控
+// @Produce json
+// @Security ApiKeyAuth
+// @Param transaction_id path int true "交易ID"
+// @Success 200 {object} service.TxRiskPreview
+// @Router /api/v1/tx-risk/preview/{transaction_id} [get]
+func (h *TxRiskHandler) PreviewTransaction(c *gin.Context) {
+	txID, err := strconv.ParseInt(c.Param("transaction_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的交易ID")
+		return
+	}
+
+	preview, err := h.txRiskService.PreviewTransaction(txID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, preview)
+}
+
+// GetConfig 获取风控规则配置
+// @Summary 获取风控规则配置
+// @Tags 交易风控
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TxRiskConfig
+// @Router /api/v1/tx-risk/config [get]
+func (h *TxRiskHandler) GetConfig(c *gin.Context) {
+	config, err := h.txRiskService.GetConfig()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// UpdateConfig 更新风控规则配置
+// @Summary 更新风控规则配置
+// @Description 各规则命中分数相加为事件总分（最高100），达到事件阈值生成风控事件，开启冻结且达到冻结阈值时冻结该笔交易的分润；金额单位为分
+// @Tags 交易风控
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateTxRiskConfigRequest true "配置"
+// @Success 200 {object} models.TxRiskConfig
+// @Router /api/v1/tx-risk/config [put]
+func (h *TxRiskHandler) UpdateConfig(c *gin.Context) {
+	var req service.UpdateTxRiskConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+
+	config, err := h.txRiskService.UpdateConfig(&req, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, config)
+}
+
+// RegisterTxRiskRoutes 注册交易风控路由
+func RegisterTxRiskRoutes(r *gin.RouterGroup, h *TxRiskHandler, authService *service.AuthService) {
+	risk := r.Group("/tx-risk")
+	risk.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	{
+		risk.GET("/events", h.ListEvents)
+		risk.GET("/events/:id", h.GetEvent)
+		risk.POST("/events/:id/review", h.ReviewEvent)
+		risk.GET("/preview/:transaction_id", h.PreviewTransaction)
+		risk.GET("/config", h.GetConfig)
+		risk.PUT("/config", h.UpdateConfig)
+	}
+}
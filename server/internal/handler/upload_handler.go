@@ -25,7 +25,7 @@ func NewUploadHandler(uploadService service.UploadService) *UploadHandler {
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "图片文件"
-// @Param module formData string false "模块 banner/poster"
+// @Param module formData string false "模块 banner/poster/merchant_application"
 // @Success 200 {object} response.Response
 // @Router /api/v1/upload/image [post]
 func (h *UploadHandler) UploadImage(c *gin.Context) {
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 
 	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
 	"xiangshoufu/internal/service"
 	"xiangshoufu/pkg/response"
 
@@ -14,6 +16,7 @@ import (
 // WalletAdjustmentHandler 钱包调账处理器
 type WalletAdjustmentHandler struct {
 	adjustmentService *service.WalletAdjustmentService
+	auditService      *service.AuditService
 }
 
 // NewWalletAdjustmentHandler 创建钱包调账处理器
@@ -23,6 +26,11 @@ func NewWalletAdjustmentHandler(adjustmentService *service.WalletAdjustmentServi
 	}
 }
 
+// SetAuditService 设置审计服务
+func (h *WalletAdjustmentHandler) SetAuditService(auditService *service.AuditService) {
+	h.auditService = auditService
+}
+
 // CreateAdjustmentRequest 创建调账请求
 type CreateAdjustmentRequest struct {
 	AgentID    int64  `json:"agent_id" binding:"required"`
@@ -34,7 +42,7 @@ type CreateAdjustmentRequest struct {
 
 // CreateAdjustment 创建调账
 // @Summary 创建调账
-// @Description 管理员手动调账（充入或扣减指定钱包余额）
+// @Description 管理员手动调账（充入或扣减指定钱包余额），金额达到审批金额时需其他管理员审批后生效
 // @Tags 钱包调账
 // @Accept json
 // @Produce json
@@ -69,10 +77,17 @@ func (h *WalletAdjustmentHandler) CreateAdjustment(c *gin.Context) {
 
 	adjustment, err := h.adjustmentService.CreateAdjustment(serviceReq)
 	if err != nil {
+		h.logAudit(c, 0, "create_adjustment", fmt.Sprintf("代理商%d调账", req.AgentID), req.Amount, err)
 		response.BadRequest(c, err.Error())
 		return
 	}
+	h.logAudit(c, adjustment.ID, "create_adjustment",
+		fmt.Sprintf("创建调账%s（%s）", adjustment.AdjustmentNo, adjustment.StatusName), adjustment.Amount, nil)
 
+	if adjustment.Status == models.AdjustmentStatusPending {
+		response.SuccessWithMessage(c, adjustment, "调账金额超过审批金额，已提交审批")
+		return
+	}
 	response.SuccessWithMessage(c, adjustment, "调账成功")
 }
 
@@ -85,6 +100,7 @@ func (h *WalletAdjustmentHandler) CreateAdjustment(c *gin.Context) {
 // @Param agent_id query int false "代理商ID"
 // @Param wallet_type query int false "钱包类型"
 // @Param channel_id query int false "通道ID"
+// @Param status query int false "状态 1已生效 2待审批 3已驳回 4已冲正"
 // @Param start_date query string false "开始日期"
 // @Param end_date query string false "结束日期"
 // @Param page query int false "页码"
@@ -116,6 +132,12 @@ func (h *WalletAdjustmentHandler) GetAdjustmentList(c *gin.Context) {
 		params.ChannelID = &chID
 	}
 
+	if statusStr := c.Query("status"); statusStr != "" {
+		st, _ := strconv.Atoi(statusStr)
+		st16 := int16(st)
+		params.Status = &st16
+	}
+
 	if startDate := c.Query("start_date"); startDate != "" {
 		if t, err := time.Parse("2006-01-02", startDate); err == nil {
 			params.StartTime = &t
@@ -163,6 +185,180 @@ func (h *WalletAdjustmentHandler) GetAdjustmentDetail(c *gin.Context) {
 	response.Success(c, adjustment)
 }
 
+// ApproveAdjustment 审批通过调账
+// @Summary 审批通过调账
+// @Description 由非发起人的管理员审批待审批调账，通过后变更钱包余额
+// @Tags 钱包调账
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "调账记录ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-adjustments/{id}/approve [post]
+func (h *WalletAdjustmentHandler) ApproveAdjustment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	adjustment, err := h.adjustmentService.ApproveAdjustment(id, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c))
+	if err != nil {
+		h.logAudit(c, id, "approve_adjustment", "审批通过调账", 0, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, id, "approve_adjustment", "审批通过调账"+adjustment.AdjustmentNo, adjustment.Amount, nil)
+
+	response.SuccessWithMessage(c, adjustment, "审批通过，调账已生效")
+}
+
+// AdjustmentReasonRequest 驳回/冲正原因请求
+type AdjustmentReasonRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// RejectAdjustment 驳回调账
+// @Summary 驳回调账
+// @Tags 钱包调账
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "调账记录ID"
+// @Param request body AdjustmentReasonRequest true "驳回原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-adjustments/{id}/reject [post]
+func (h *WalletAdjustmentHandler) RejectAdjustment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req AdjustmentReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写驳回原因")
+		return
+	}
+
+	err = h.adjustmentService.RejectAdjustment(id, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c), req.Reason)
+	h.logAudit(c, id, "reject_adjustment", "驳回调账: "+req.Reason, 0, err)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已驳回")
+}
+
+// ReverseAdjustment 冲正调账
+// @Summary 冲正调账
+// @Description 对已生效的调账生成反向调账，金额达到审批金额时同样需要审批
+// @Tags 钱包调账
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "调账记录ID"
+// @Param request body AdjustmentReasonRequest true "冲正原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-adjustments/{id}/reverse [post]
+func (h *WalletAdjustmentHandler) ReverseAdjustment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req AdjustmentReasonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写冲正原因")
+		return
+	}
+
+	reversal, err := h.adjustmentService.ReverseAdjustment(&service.ReverseAdjustmentRequest{
+		AdjustmentID: id,
+		Reason:       req.Reason,
+		OperatorID:   middleware.GetCurrentUserID(c),
+		OperatorName: middleware.GetCurrentUsername(c),
+	})
+	if err != nil {
+		h.logAudit(c, id, "reverse_adjustment", "冲正调账: "+req.Reason, 0, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, id, "reverse_adjustment",
+		fmt.Sprintf("冲正调账，冲正单%s（%s）", reversal.AdjustmentNo, reversal.StatusName), reversal.Amount, nil)
+
+	if reversal.Status == models.AdjustmentStatusPending {
+		response.SuccessWithMessage(c, reversal, "冲正金额超过审批金额，已提交审批")
+		return
+	}
+	response.SuccessWithMessage(c, reversal, "冲正成功")
+}
+
+// GetApprovalThresholds 获取调账审批金额配置
+// @Summary 获取调账审批金额配置
+// @Tags 钱包调账
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-adjustments/approval-thresholds [get]
+func (h *WalletAdjustmentHandler) GetApprovalThresholds(c *gin.Context) {
+	thresholds, err := h.adjustmentService.GetApprovalThresholds()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, thresholds)
+}
+
+// UpdateApprovalThreshold 更新调账审批金额配置
+// @Summary 更新调账审批金额配置
+// @Description wallet_type=0为通用配置；调账金额绝对值达到该金额需其他管理员审批
+// @Tags 钱包调账
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateApprovalThresholdRequest true "审批金额配置"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-adjustments/approval-thresholds [put]
+func (h *WalletAdjustmentHandler) UpdateApprovalThreshold(c *gin.Context) {
+	var req service.UpdateApprovalThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.UpdatedBy = middleware.GetCurrentUserID(c)
+
+	threshold, err := h.adjustmentService.UpdateApprovalThreshold(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if h.auditService != nil {
+		auditCtx := service.NewAuditContextFromGin(c)
+		h.auditService.LogGeneric(auditCtx, models.AuditLogTypeConfigChange, models.AuditLogLevelWarning,
+			"wallet_adjustment_threshold", threshold.ID, models.GetWalletTypeName(threshold.WalletType),
+			"update_adjustment_threshold", "更新调账审批金额", nil, threshold, true, "")
+	}
+
+	response.SuccessWithMessage(c, threshold, "保存成功")
+}
+
+// logAudit 记录调账审计日志
+func (h *WalletAdjustmentHandler) logAudit(c *gin.Context, adjustmentID int64, action, description string, amount int64, err error) {
+	if h.auditService == nil {
+		return
+	}
+	auditCtx := service.NewAuditContextFromGin(c)
+	if err != nil {
+		h.auditService.LogWalletAdjustment(auditCtx, adjustmentID, action, description, amount, false, err.Error())
+		return
+	}
+	h.auditService.LogWalletAdjustment(auditCtx, adjustmentID, action, description, amount, true, "")
+}
+
 // RegisterWalletAdjustmentRoutes 注册钱包调账路由
 func RegisterWalletAdjustmentRoutes(r *gin.RouterGroup, h *WalletAdjustmentHandler, authService *service.AuthService) {
 	adjustments := r.Group("/wallet-adjustments")
@@ -170,6 +366,11 @@ func RegisterWalletAdjustmentRoutes(r *gin.RouterGroup, h *WalletAdjustmentHandl
 	{
 		adjustments.POST("", h.CreateAdjustment)
 		adjustments.GET("", h.GetAdjustmentList)
+		adjustments.GET("/approval-thresholds", h.GetApprovalThresholds)
+		adjustments.PUT("/approval-thresholds", middleware.AdminMiddleware(), h.UpdateApprovalThreshold)
 		adjustments.GET("/:id", h.GetAdjustmentDetail)
+		adjustments.POST("/:id/approve", middleware.AdminMiddleware(), h.ApproveAdjustment)
+		adjustments.POST("/:id/reject", middleware.AdminMiddleware(), h.RejectAdjustment)
+		adjustments.POST("/:id/reverse", middleware.AdminMiddleware(), h.ReverseAdjustment)
 	}
 }
@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletBalanceCheckHandler 钱包余额一致性检查处理器
+type WalletBalanceCheckHandler struct {
+	consistencyService *service.WalletConsistencyService
+	auditService       *service.AuditService
+}
+
+// NewWalletBalanceCheckHandler 创建钱包余额一致性检查处理器
+func NewWalletBalanceCheckHandler(consistencyService *service.WalletConsistencyService) *WalletBalanceCheckHandler {
+	return &WalletBalanceCheckHandler{
+		consistencyService: consistencyService,
+	}
+}
+
+// SetAuditService 设置审计服务
+func (h *WalletBalanceCheckHandler) SetAuditService(auditService *service.AuditService) {
+	h.auditService = auditService
+}
+
+// GetCheckList 获取余额检查记录
+// @Summary 获取余额检查记录
+// @Description 按钱包过滤即为该钱包的检查历史
+// @Tags 钱包余额检查
+// @Produce json
+// @Security ApiKeyAuth
+// @Param wallet_id query int false "钱包ID"
+// @Param agent_id query int false "代理商ID"
+// @Param status query int false "状态 1一致 2待处理 3已采纳 4已忽略 5已失效"
+// @Param start_date query string false "开始日期"
+// @Param end_date query string false "结束日期"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-balance-checks [get]
+func (h *WalletBalanceCheckHandler) GetCheckList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	params := &service.BalanceCheckListParams{
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if walletIDStr := c.Query("wallet_id"); walletIDStr != "" {
+		params.WalletID, _ = strconv.ParseInt(walletIDStr, 10, 64)
+	}
+	if agentIDStr := c.Query("agent_id"); agentIDStr != "" {
+		params.AgentID, _ = strconv.ParseInt(agentIDStr, 10, 64)
+	}
+	if statusStr := c.Query("status"); statusStr != "" {
+		st, _ := strconv.Atoi(statusStr)
+		st16 := int16(st)
+		params.Status = &st16
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			params.StartTime = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			t = t.AddDate(0, 0, 1)
+			params.EndTime = &t
+		}
+	}
+
+	list, total, err := h.consistencyService.GetCheckList(params)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetCheckDetail 获取余额检查详情
+// @Summary 获取余额检查详情
+// @Tags 钱包余额检查
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "检查ID"
+// @Success 200 {object} service.BalanceCheckInfo
+// @Router /api/v1/wallet-balance-checks/{id} [get]
+func (h *WalletBalanceCheckHandler) GetCheckDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	check, err := h.consistencyService.GetCheckDetail(id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, check)
+}
+
+// CheckWallet 手动检查钱包
+// @Summary 手动检查钱包余额
+// @Description 立即回放该钱包并生成检查记录，该钱包仍待处理的旧检查将失效
+// @Tags 钱包余额检查
+// @Produce json
+// @Security ApiKeyAuth
+// @Param wallet_id path int true "钱包ID"
+// @Success 200 {object} service.BalanceCheckInfo
+// @Router /api/v1/wallet-balance-checks/wallets/{wallet_id}/check [post]
+func (h *WalletBalanceCheckHandler) CheckWallet(c *gin.Context) {
+	walletID, err := strconv.ParseInt(c.Param("wallet_id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的钱包ID")
+		return
+	}
+
+	check, err := h.consistencyService.CheckWallet(walletID, models.BalanceCheckTriggerManual)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	info, err := h.consistencyService.GetCheckDetail(check.ID)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, info)
+}
+
+// AcceptProposal 采纳修正建议
+// @Summary 采纳修正建议
+// @Description 按建议金额创建调账，达到审批金额时进入调账审批
+// @Tags 钱包余额检查
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "检查ID"
+// @Param request body service.HandleBalanceCheckRequest false "处理说明"
+// @Success 200 {object} service.BalanceCheckInfo
+// @Router /api/v1/wallet-balance-checks/{id}/accept [post]
+func (h *WalletBalanceCheckHandler) AcceptProposal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.HandleBalanceCheckRequest
+	_ = c.ShouldBindJSON(&req)
+	req.ID = id
+	req.OperatorID = middleware.GetCurrentUserID(c)
+	req.OperatorName = middleware.GetCurrentUsername(c)
+
+	info, err := h.consistencyService.AcceptProposal(&req)
+	if err != nil {
+		h.logAudit(c, id, "accept_balance_check", "采纳余额修正建议", nil, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, id, "accept_balance_check",
+		fmt.Sprintf("采纳余额修正建议%s，调账%.2f元", info.CheckNo, info.ProposedAmountYuan), info, nil)
+
+	response.SuccessWithMessage(c, info, "已采纳，修正调账已提交")
+}
+
+// DismissProposal 忽略检查结果
+// @Summary 忽略检查结果
+// @Description 不做调账，以当前钱包余额作为后续检查起点
+// @Tags 钱包余额检查
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "检查ID"
+// @Param request body service.HandleBalanceCheckRequest true "忽略原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-balance-checks/{id}/dismiss [post]
+func (h *WalletBalanceCheckHandler) DismissProposal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req service.HandleBalanceCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写忽略原因")
+		return
+	}
+	req.ID = id
+	req.OperatorID = middleware.GetCurrentUserID(c)
+	req.OperatorName = middleware.GetCurrentUsername(c)
+
+	err = h.consistencyService.DismissProposal(&req)
+	h.logAudit(c, id, "dismiss_balance_check", "忽略余额检查结果: "+req.Remark, nil, err)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已忽略")
+}
+
+// logAudit 记录余额检查审计日志
+func (h *WalletBalanceCheckHandler) logAudit(c *gin.Context, checkID int64, action, description string, newValue interface{}, err error) {
+	if h.auditService == nil {
+		return
+	}
+	auditCtx := service.NewAuditContextFromGin(c)
+	if err != nil {
+		h.auditService.LogGeneric(auditCtx, models.AuditLogTypeWalletAdjust, models.AuditLogLevelCritical,
+			"wallet_balance_check", checkID, "", action, description, nil, newValue, false, err.Error())
+		return
+	}
+	h.auditService.LogGeneric(auditCtx, models.AuditLogTypeWalletAdjust, models.AuditLogLevelCritical,
+		"wallet_balance_check", checkID, "", action, description, nil, newValue, true, "")
+}
+
+// RegisterWalletBalanceCheckRoutes 注册钱包余额检查路由
+func RegisterWalletBalanceCheckRoutes(r *gin.RouterGroup, h *WalletBalanceCheckHandler, authService *service.AuthService) {
+	checks := r.Group("/wallet-balance-checks")
+	checks.Use(middleware.AuthMiddleware(authService))
+	checks.Use(middleware.AdminMiddleware())
+	{
+		checks.GET("", h.GetCheckList)
+		checks.GET("/:id", h.GetCheckDetail)
+		checks.POST("/wallets/:wallet_id/check", h.CheckWallet)
+		checks.POST("/:id/accept", h.AcceptProposal)
+		checks.POST("/:id/dismiss", h.DismissProposal)
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletRiskHoldHandler 钱包风控冻结处理器
+type WalletRiskHoldHandler struct {
+	riskHoldService *service.WalletRiskHoldService
+	auditService    *service.AuditService
+}
+
+// NewWalletRiskHoldHandler 创建钱包风控冻结处理器
+func NewWalletRiskHoldHandler(riskHoldService *service.WalletRiskHoldService) *WalletRiskHoldHandler {
+	return &WalletRiskHoldHandler{
+		riskHoldService: riskHoldService,
+	}
+}
+
+// SetAuditService 设置审计服务
+func (h *WalletRiskHoldHandler) SetAuditService(auditService *service.AuditService) {
+	h.auditService = auditService
+}
+
+// CreateHold 创建风控冻结
+// @Summary 创建风控冻结
+// @Description 管理员冻结代理商指定金额、整个钱包或全部钱包，冻结期间禁止提现、划转及使用沉淀款
+// @Tags 钱包风控冻结
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateRiskHoldRequest true "冻结请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-risk-holds [post]
+func (h *WalletRiskHoldHandler) CreateHold(c *gin.Context) {
+	var req service.CreateRiskHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.CreatedBy = middleware.GetCurrentUserID(c)
+	req.CreatedByName = middleware.GetCurrentUsername(c)
+
+	hold, err := h.riskHoldService.CreateHold(&req)
+	if err != nil {
+		h.logAudit(c, 0, req.AgentID, "create_risk_hold", fmt.Sprintf("风控冻结代理商%d", req.AgentID), nil, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, hold.ID, hold.AgentID, "create_risk_hold",
+		fmt.Sprintf("风控冻结%s（%s）", hold.HoldNo, hold.ScopeName), hold, nil)
+
+	response.SuccessWithMessage(c, hold, "冻结成功")
+}
+
+// GetHoldList 获取风控冻结列表
+// @Summary 获取风控冻结列表
+// @Tags 钱包风控冻结
+// @Produce json
+// @Security ApiKeyAuth
+// @Param agent_id query int false "代理商ID"
+// @Param status query int false "状态 1冻结中 2已解除 3已到期"
+// @Param scope query int false "范围 1指定金额 2整个钱包 3全部钱包"
+// @Param case_ref query string false "关联案件"
+// @Param start_date query string false "开始日期"
+// @Param end_date query string false "结束日期"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-risk-holds [get]
+func (h *WalletRiskHoldHandler) GetHoldList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	params := &service.RiskHoldListParams{
+		CaseRef:  c.Query("case_ref"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if agentIDStr := c.Query("agent_id"); agentIDStr != "" {
+		params.AgentID, _ = strconv.ParseInt(agentIDStr, 10, 64)
+	}
+	if statusStr := c.Query("status"); statusStr != "" {
+		st, _ := strconv.Atoi(statusStr)
+		st16 := int16(st)
+		params.Status = &st16
+	}
+	if scopeStr := c.Query("scope"); scopeStr != "" {
+		sc, _ := strconv.Atoi(scopeStr)
+		sc16 := int16(sc)
+		params.Scope = &sc16
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			params.StartTime = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			t = t.AddDate(0, 0, 1)
+			params.EndTime = &t
+		}
+	}
+
+	list, total, err := h.riskHoldService.GetHoldList(params)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetHoldDetail 获取风控冻结详情
+// @Summary 获取风控冻结详情
+// @Tags 钱包风控冻结
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "冻结ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-risk-holds/{id} [get]
+func (h *WalletRiskHoldHandler) GetHoldDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	hold, err := h.riskHoldService.GetHoldDetail(id)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, hold)
+}
+
+// ReleaseHoldRequest 解除冻结请求
+type ReleaseHoldRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReleaseHold 解除风控冻结
+// @Summary 解除风控冻结
+// @Tags 钱包风控冻结
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "冻结ID"
+// @Param request body ReleaseHoldRequest true "解除原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-risk-holds/{id}/release [post]
+func (h *WalletRiskHoldHandler) ReleaseHold(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req ReleaseHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "请填写解除原因")
+		return
+	}
+
+	err = h.riskHoldService.ReleaseHold(id, middleware.GetCurrentUserID(c), middleware.GetCurrentUsername(c), req.Reason)
+	h.logAudit(c, id, 0, "release_risk_hold", "解除风控冻结: "+req.Reason, nil, err)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已解除冻结")
+}
+
+// GetMyHolds 获取当前代理商的风控冻结
+// @Summary 获取我的风控冻结
+// @Description 代理商查看当前生效的风控冻结及客服提示
+// @Tags 钱包风控冻结
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} service.AgentRiskHoldView
+// @Router /api/v1/wallet-risk-holds/mine [get]
+func (h *WalletRiskHoldHandler) GetMyHolds(c *gin.Context) {
+	view, err := h.riskHoldService.GetAgentHoldView(middleware.GetCurrentAgentID(c))
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, view)
+}
+
+// logAudit 记录风控冻结审计日志
+func (h *WalletRiskHoldHandler) logAudit(c *gin.Context, holdID, agentID int64, action, description string, newValue interface{}, err error) {
+	if h.auditService == nil {
+		return
+	}
+	auditCtx := service.NewAuditContextFromGin(c)
+	targetName := ""
+	if agentID > 0 {
+		targetName = fmt.Sprintf("代理商%d", agentID)
+	}
+	if err != nil {
+		h.auditService.LogGeneric(auditCtx, models.AuditLogTypeRiskHold, models.AuditLogLevelCritical,
+			"wallet_risk_hold", holdID, targetName, action, description, nil, newValue, false, err.Error())
+		return
+	}
+	h.auditService.LogGeneric(auditCtx, models.AuditLogTypeRiskHold, models.AuditLogLevelCritical,
+		"wallet_risk_hold", holdID, targetName, action, description, nil, newValue, true, "")
+}
+
+// RegisterWalletRiskHoldRoutes 注册钱包风控冻结路由
+func RegisterWalletRiskHoldRoutes(r *gin.RouterGroup, h *WalletRiskHoldHandler, authService *service.AuthService) {
+	holds := r.Group("/wallet-risk-holds")
+	holds.Use(middleware.AuthMiddleware(authService))
+	{
+		holds.GET("/mine", h.GetMyHolds)
+		holds.POST("", middleware.AdminMiddleware(), h.CreateHold)
+		holds.GET("", middleware.AdminMiddleware(), h.GetHoldList)
+		holds.GET("/:id", middleware.AdminMiddleware(), h.GetHoldDetail)
+		holds.POST("/:id/release", middleware.AdminMiddleware(), h.ReleaseHold)
+	}
+}
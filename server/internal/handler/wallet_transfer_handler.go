@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/middleware"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/service"
+	"xiangshoufu/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletTransferHandler 钱包划转处理器
+type WalletTransferHandler struct {
+	transferService *service.WalletTransferService
+	auditService    *service.AuditService
+}
+
+// NewWalletTransferHandler 创建钱包划转处理器
+func NewWalletTransferHandler(transferService *service.WalletTransferService) *WalletTransferHandler {
+	return &WalletTransferHandler{
+		transferService: transferService,
+	}
+}
+
+// SetAuditService 设置审计服务
+func (h *WalletTransferHandler) SetAuditService(auditService *service.AuditService) {
+	h.auditService = auditService
+}
+
+// CreateTransfer 发起划转
+// @Summary 发起钱包划转
+// @Description 向同一链路的上级或下级代理商划转钱包余额
+// @Tags 钱包划转
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.CreateTransferRequest true "划转请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers [post]
+func (h *WalletTransferHandler) CreateTransfer(c *gin.Context) {
+	var req service.CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.FromAgentID = middleware.GetCurrentAgentID(c)
+	req.CreatedBy = middleware.GetCurrentUserID(c)
+
+	transfer, err := h.transferService.CreateTransfer(&req)
+	if err != nil {
+		h.logAudit(c, 0, "create_transfer", fmt.Sprintf("发起划转给代理商%d", req.ToAgentID), req.Amount, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, transfer.ID, "create_transfer", fmt.Sprintf("发起划转%s给%s", transfer.TransferNo, transfer.ToAgentName), transfer.Amount, nil)
+
+	message := "划转成功"
+	if transfer.NeedConfirm {
+		message = "划转已提交，等待对方确认"
+	}
+	response.SuccessWithMessage(c, transfer, message)
+}
+
+// GetTransferList 获取划转列表
+// @Summary 获取划转列表
+// @Description 查询当前代理商的转出/转入记录
+// @Tags 钱包划转
+// @Produce json
+// @Security ApiKeyAuth
+// @Param direction query string false "out=转出 in=转入"
+// @Param status query int false "状态"
+// @Param start_date query string false "开始日期"
+// @Param end_date query string false "结束日期"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers [get]
+func (h *WalletTransferHandler) GetTransferList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	params := &service.TransferListParams{
+		AgentID:   middleware.GetCurrentAgentID(c),
+		Direction: c.Query("direction"),
+		Page:      page,
+		PageSize:  pageSize,
+	}
+
+	// 管理员可按代理商查询全部记录
+	if middleware.IsAdmin(c) {
+		params.AgentID = 0
+		if agentIDStr := c.Query("agent_id"); agentIDStr != "" {
+			params.AgentID, _ = strconv.ParseInt(agentIDStr, 10, 64)
+		}
+	}
+
+	if statusStr := c.Query("status"); statusStr != "" {
+		st, _ := strconv.Atoi(statusStr)
+		st16 := int16(st)
+		params.Status = &st16
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			params.StartTime = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			t = t.AddDate(0, 0, 1)
+			params.EndTime = &t
+		}
+	}
+
+	list, total, err := h.transferService.GetTransferList(params)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessPage(c, list, total, page, pageSize)
+}
+
+// GetTransferDetail 获取划转详情
+// @Summary 获取划转详情
+// @Tags 钱包划转
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "划转ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/{id} [get]
+func (h *WalletTransferHandler) GetTransferDetail(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	agentID := middleware.GetCurrentAgentID(c)
+	if middleware.IsAdmin(c) {
+		agentID = 0
+	}
+
+	transfer, err := h.transferService.GetTransferDetail(id, agentID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, transfer)
+}
+
+// ConfirmTransfer 确认划转
+// @Summary 确认划转
+// @Description 接收方确认待确认的划转，确认后双边入账
+// @Tags 钱包划转
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "划转ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/{id}/confirm [post]
+func (h *WalletTransferHandler) ConfirmTransfer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	transfer, err := h.transferService.ConfirmTransfer(id, middleware.GetCurrentAgentID(c))
+	if err != nil {
+		h.logAudit(c, id, "confirm_transfer", "确认划转", 0, err)
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logAudit(c, id, "confirm_transfer", "确认划转"+transfer.TransferNo, transfer.Amount, nil)
+
+	response.SuccessWithMessage(c, transfer, "已确认收款")
+}
+
+// TransferReasonRequest 拒绝/取消划转请求
+type TransferReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RejectTransfer 拒绝划转
+// @Summary 拒绝划转
+// @Tags 钱包划转
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "划转ID"
+// @Param request body TransferReasonRequest false "拒绝原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/{id}/reject [post]
+func (h *WalletTransferHandler) RejectTransfer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req TransferReasonRequest
+	_ = c.ShouldBindJSON(&req)
+
+	err = h.transferService.RejectTransfer(id, middleware.GetCurrentAgentID(c), req.Reason)
+	h.logAudit(c, id, "reject_transfer", "拒绝划转", 0, err)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已拒绝")
+}
+
+// CancelTransfer 取消划转
+// @Summary 取消划转
+// @Description 发起方取消尚未确认的划转，冻结金额退回
+// @Tags 钱包划转
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "划转ID"
+// @Param request body TransferReasonRequest false "取消原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/{id}/cancel [post]
+func (h *WalletTransferHandler) CancelTransfer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req TransferReasonRequest
+	_ = c.ShouldBindJSON(&req)
+
+	err = h.transferService.CancelTransfer(id, middleware.GetCurrentAgentID(c), req.Reason)
+	h.logAudit(c, id, "cancel_transfer", "取消划转", 0, err)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.SuccessMessage(c, "已取消")
+}
+
+// GetMyLimit 获取当前代理商的划转限额
+// @Summary 获取划转限额
+// @Tags 钱包划转
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/limit [get]
+func (h *WalletTransferHandler) GetMyLimit(c *gin.Context) {
+	agentID := middleware.GetCurrentAgentID(c)
+	if middleware.IsAdmin(c) {
+		if agentIDStr := c.Query("agent_id"); agentIDStr != "" {
+			agentID, _ = strconv.ParseInt(agentIDStr, 10, 64)
+		}
+	}
+
+	response.Success(c, h.transferService.GetEffectiveLimit(agentID))
+}
+
+// UpdateLimit 更新划转限额（管理员）
+// @Summary 更新划转限额
+// @Description 管理员设置全局或指定代理商的划转限额，agent_id=0为全局默认
+// @Tags 钱包划转
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body service.UpdateTransferLimitRequest true "限额配置"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/wallet-transfers/limit [put]
+func (h *WalletTransferHandler) UpdateLimit(c *gin.Context) {
+	var req service.UpdateTransferLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.UpdatedBy = middleware.GetCurrentUserID(c)
+
+	limit, err := h.transferService.UpdateTransferLimit(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if h.auditService != nil {
+		auditCtx := service.NewAuditContextFromGin(c)
+		h.auditService.LogGeneric(auditCtx, models.AuditLogTypeConfigChange, models.AuditLogLevelWarning,
+			"wallet_transfer_limit", limit.AgentID, "", "update_transfer_limit", "更新钱包划转限额",
+			nil, limit, true, "")
+	}
+
+	response.SuccessWithMessage(c, limit, "保存成功")
+}
+
+// logAudit 记录划转审计日志
+func (h *WalletTransferHandler) logAudit(c *gin.Context, transferID int64, action, description string, amount int64, err error) {
+	if h.auditService == nil {
+		return
+	}
+	auditCtx := service.NewAuditContextFromGin(c)
+	if err != nil {
+		h.auditService.LogTransfer(auditCtx, transferID, action, description, amount, false, err.Error())
+		return
+	}
+	h.auditService.LogTransfer(auditCtx, transferID, action, description, amount, true, "")
+}
+
+// RegisterWalletTransferRoutes 注册钱包划转路由
+func RegisterWalletTransferRoutes(r *gin.RouterGroup, h *WalletTransferHandler, authService *service.AuthService) {
+	transfers := r.Group("/wallet-transfers")
+	transfers.Use(middleware.AuthMiddleware(authService))
+	{
+		transfers.POST("", h.CreateTransfer)
+		transfers.GET("", h.GetTransferList)
+		transfers.GET("/limit", h.GetMyLimit)
+		transfers.PUT("/limit", middleware.AdminMiddleware(), h.UpdateLimit)
+		transfers.GET("/:id", h.GetTransferDetail)
+		transfers.POST("/:id/confirm", h.ConfirmTransfer)
+		transfers.POST("/:id/reject", h.RejectTransfer)
+		transfers.POST("/:id/cancel", h.CancelTransfer)
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// AgentStatementJob 月度对账单生成任务
+// 每天检查一次，为上一个自然月补生成缺失的对账单，已生成的不重复生成
+type AgentStatementJob struct {
+	statementService *service.AgentStatementService
+	running          bool
+	mu               sync.Mutex
+}
+
+// NewAgentStatementJob 创建月度对账单生成任务
+func NewAgentStatementJob(statementService *service.AgentStatementService) *AgentStatementJob {
+	return &AgentStatementJob{
+		statementService: statementService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *AgentStatementJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	period := service.PreviousPeriod(startTime)
+	generated, err := j.statementService.GenerateMonthly(period)
+	if err != nil {
+		log.Printf("[AgentStatementJob] Failed: period=%s, err=%v", period, err)
+		return
+	}
+	if generated > 0 {
+		log.Printf("[AgentStatementJob] Generated %d statements for %s, took=%v", generated, period, time.Since(startTime))
+	}
+}
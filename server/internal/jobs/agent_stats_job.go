@@ -35,10 +35,9 @@ func (j *AgentStatsRefreshJob) Run() {
 
 	// 获取所有代理商
 	var agents []struct {
-		ID        int64
-		AgentPath string
+		ID int64
 	}
-	if err := j.db.Table("agents").Select("id, agent_path").Find(&agents).Error; err != nil {
+	if err := j.db.Table("agents").Select("id").Find(&agents).Error; err != nil {
 		log.Printf("[AgentStatsRefreshJob] 获取代理商列表失败: %v", err)
 		return
 	}
@@ -46,14 +45,14 @@ func (j *AgentStatsRefreshJob) Run() {
 	successCount := 0
 	for _, agent := range agents {
 		// 刷新直营数据
-		if err := j.refreshAgentDailyStats(agent.ID, agent.AgentPath, today, models.StatScopeDirect); err != nil {
+		if err := j.refreshAgentDailyStats(agent.ID, today, models.StatScopeDirect); err != nil {
 			log.Printf("[AgentStatsRefreshJob] 刷新代理商 %d 直营数据失败: %v", agent.ID, err)
 		} else {
 			successCount++
 		}
 
 		// 刷新团队数据
-		if err := j.refreshAgentDailyStats(agent.ID, agent.AgentPath, today, models.StatScopeTeam); err != nil {
+		if err := j.refreshAgentDailyStats(agent.ID, today, models.StatScopeTeam); err != nil {
 			log.Printf("[AgentStatsRefreshJob] 刷新代理商 %d 团队数据失败: %v", agent.ID, err)
 		}
 	}
@@ -63,7 +62,7 @@ func (j *AgentStatsRefreshJob) Run() {
 }
 
 // refreshAgentDailyStats 刷新单个代理商的每日统计
-func (j *AgentStatsRefreshJob) refreshAgentDailyStats(agentID int64, agentPath string, date string, scope string) error {
+func (j *AgentStatsRefreshJob) refreshAgentDailyStats(agentID int64, date string, scope string) error {
 	stats := &models.AgentDailyStats{
 		AgentID:   agentID,
 		Scope:     scope,
@@ -85,13 +84,11 @@ func (j *AgentStatsRefreshJob) refreshAgentDailyStats(agentID int64, agentPath s
 		merchantCondition = fmt.Sprintf("agent_id = %d", agentID)
 		terminalCondition = fmt.Sprintf("owner_agent_id = %d", agentID)
 	} else {
-		// 团队范围：使用物化路径查询
-		if agentPath == "" {
-			agentPath = fmt.Sprintf("/%d/", agentID)
-		}
-		agentCondition = fmt.Sprintf("agent_id IN (SELECT id FROM agents WHERE agent_path LIKE '%s%%')", agentPath)
-		merchantCondition = fmt.Sprintf("agent_id IN (SELECT id FROM agents WHERE agent_path LIKE '%s%%')", agentPath)
-		terminalCondition = fmt.Sprintf("owner_agent_id IN (SELECT id FROM agents WHERE agent_path LIKE '%s%%')", agentPath)
+		// 团队范围：使用层级闭包表查询（含本人）
+		subtree := fmt.Sprintf("IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = %d)", agentID)
+		agentCondition = "agent_id " + subtree
+		merchantCondition = "agent_id " + subtree
+		terminalCondition = "owner_agent_id " + subtree
 	}
 
 	// 1. 交易统计
@@ -221,17 +218,16 @@ func (j *AgentStatsDailyJob) Run() {
 // refreshDayStats 刷新指定日期的统计
 func (j *AgentStatsDailyJob) refreshDayStats(refreshJob *AgentStatsRefreshJob, date string) {
 	var agents []struct {
-		ID        int64
-		AgentPath string
+		ID int64
 	}
-	if err := j.db.Table("agents").Select("id, agent_path").Find(&agents).Error; err != nil {
+	if err := j.db.Table("agents").Select("id").Find(&agents).Error; err != nil {
 		log.Printf("[AgentStatsDailyJob] 获取代理商列表失败: %v", err)
 		return
 	}
 
 	for _, agent := range agents {
-		refreshJob.refreshAgentDailyStats(agent.ID, agent.AgentPath, date, models.StatScopeDirect)
-		refreshJob.refreshAgentDailyStats(agent.ID, agent.AgentPath, date, models.StatScopeTeam)
+		refreshJob.refreshAgentDailyStats(agent.ID, date, models.StatScopeDirect)
+		refreshJob.refreshAgentDailyStats(agent.ID, date, models.StatScopeTeam)
 	}
 }
 
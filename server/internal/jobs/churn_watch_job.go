@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// ChurnWatchJob 商户流失预警任务
+// 每天执行一次：交易恢复的跟进任务自动标记为已挽回，为新出现流失趋势的商户生成跟进任务并通知代理商
+type ChurnWatchJob struct {
+	churnWatchService *service.ChurnWatchService
+	running           bool
+	mu                sync.Mutex
+}
+
+// NewChurnWatchJob 创建商户流失预警任务
+func NewChurnWatchJob(churnWatchService *service.ChurnWatchService) *ChurnWatchJob {
+	return &ChurnWatchJob{
+		churnWatchService: churnWatchService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *ChurnWatchJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.churnWatchService.Scan(startTime)
+	if err != nil {
+		log.Printf("[ChurnWatchJob] Failed: %v", err)
+		return
+	}
+	log.Printf("[ChurnWatchJob] Scanned %d merchants, created=%d, recovered=%d, agents=%d, failed=%d, took=%v",
+		result.Scanned, result.Created, result.Recovered, result.Agents, result.Failed, time.Since(startTime))
+}
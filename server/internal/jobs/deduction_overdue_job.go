@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// DeductionOverdueJob 代扣逾期处理任务
+// 每天执行一次：补扣逾期款项、计收滞纳金、按节点通知、上报上级及冻结出款
+type DeductionOverdueJob struct {
+	overdueService *service.DeductionOverdueService
+	running        bool
+	mu             sync.Mutex
+}
+
+// NewDeductionOverdueJob 创建代扣逾期处理任务
+func NewDeductionOverdueJob(overdueService *service.DeductionOverdueService) *DeductionOverdueJob {
+	return &DeductionOverdueJob{
+		overdueService: overdueService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *DeductionOverdueJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.overdueService.ProcessOverdues(startTime)
+	if err != nil {
+		log.Printf("[DeductionOverdueJob] Failed: %v", err)
+		return
+	}
+	if result.Processed > 0 {
+		log.Printf("[DeductionOverdueJob] Processed %d plans, took=%v", result.Processed, time.Since(startTime))
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// InventoryAlertJob 呆滞库存预警任务
+// 每天执行一次：统计各代理商库龄超期的未绑定终端，超过阈值时通知代理商/上级并推送总部告警
+type InventoryAlertJob struct {
+	reportService *service.InventoryReportService
+	running       bool
+	mu            sync.Mutex
+}
+
+// NewInventoryAlertJob 创建呆滞库存预警任务
+func NewInventoryAlertJob(reportService *service.InventoryReportService) *InventoryAlertJob {
+	return &InventoryAlertJob{
+		reportService: reportService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *InventoryAlertJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.reportService.CheckStaleStock(startTime)
+	if err != nil {
+		log.Printf("[InventoryAlertJob] Failed: %v", err)
+		return
+	}
+	if result.OverThreshold > 0 {
+		log.Printf("[InventoryAlertJob] %d agents over threshold, alerted=%d, skipped=%d, failed=%d, took=%v",
+			result.OverThreshold, result.Alerted, result.Skipped, result.Failed, time.Since(startTime))
+	}
+}
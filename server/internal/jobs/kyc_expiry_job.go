@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// KycExpiryJob 商户证件到期提醒任务
+// 每天执行一次：按提醒窗口查找法人身份证即将到期或已过期的商户，汇总通知所属代理商
+type KycExpiryJob struct {
+	kycExpiryService *service.KycExpiryService
+	running          bool
+	mu               sync.Mutex
+}
+
+// NewKycExpiryJob 创建商户证件到期提醒任务
+func NewKycExpiryJob(kycExpiryService *service.KycExpiryService) *KycExpiryJob {
+	return &KycExpiryJob{
+		kycExpiryService: kycExpiryService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *KycExpiryJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.kycExpiryService.Scan(startTime)
+	if err != nil {
+		log.Printf("[KycExpiryJob] Failed: %v", err)
+		return
+	}
+	log.Printf("[KycExpiryJob] Scanned %d merchants, reminded=%d, agents=%d, took=%v",
+		result.Scanned, result.Reminded, result.Agents, time.Since(startTime))
+}
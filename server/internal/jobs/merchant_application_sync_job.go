@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// merchantApplicationSyncBatchSize 每次最多查询的进件申请数
+const merchantApplicationSyncBatchSize = 100
+
+// MerchantApplicationSyncJob 进件审核状态同步任务
+// 每10分钟执行一次：向通道查询审核中的进件申请，审核通过后创建本地商户（merc_income回调丢失时兜底）
+type MerchantApplicationSyncJob struct {
+	onboardingService *service.MerchantOnboardingService
+	running           bool
+	mu                sync.Mutex
+}
+
+// NewMerchantApplicationSyncJob 创建进件审核状态同步任务
+func NewMerchantApplicationSyncJob(onboardingService *service.MerchantOnboardingService) *MerchantApplicationSyncJob {
+	return &MerchantApplicationSyncJob{
+		onboardingService: onboardingService,
+	}
+}
+
+// Run 执行任务（每10分钟执行一次）
+func (j *MerchantApplicationSyncJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.onboardingService.SyncStatuses(startTime, merchantApplicationSyncBatchSize)
+	if err != nil {
+		log.Printf("[MerchantApplicationSyncJob] Failed: %v", err)
+		return
+	}
+	if result.Queried > 0 {
+		log.Printf("[MerchantApplicationSyncJob] Queried %d applications, updated=%d, failed=%d, took=%v",
+			result.Queried, result.Updated, result.Failed, time.Since(startTime))
+	}
+}
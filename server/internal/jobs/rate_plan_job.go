@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// ratePlanBatchSize 每次最多处理的计划/提醒数
+const ratePlanBatchSize = 200
+
+// RatePlanJob 商户费率计划任务
+// 每10分钟执行一次：执行到期的费率计划步骤，跟踪同步结果，并在调整前提醒代理商和商户
+type RatePlanJob struct {
+	ratePlanService *service.MerchantRatePlanService
+	running         bool
+	mu              sync.Mutex
+}
+
+// NewRatePlanJob 创建商户费率计划任务
+func NewRatePlanJob(ratePlanService *service.MerchantRatePlanService) *RatePlanJob {
+	return &RatePlanJob{
+		ratePlanService: ratePlanService,
+	}
+}
+
+// Run 执行任务（每10分钟执行一次）
+func (j *RatePlanJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.ratePlanService.Run(startTime, ratePlanBatchSize)
+	if err != nil {
+		log.Printf("[RatePlanJob] Failed: %v", err)
+		return
+	}
+	if result.Plans > 0 || result.Reminded > 0 {
+		log.Printf("[RatePlanJob] Processed %d plans, applied=%d, failed=%d, completed=%d, reminded=%d, took=%v",
+			result.Plans, result.Applied, result.Failed, result.Completed, result.Reminded, time.Since(startTime))
+	}
+}
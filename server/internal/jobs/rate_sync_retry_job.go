@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// rateSyncRetryBatchSize 每次最多重试的同步日志数
+const rateSyncRetryBatchSize = 100
+
+// RateSyncRetryJob 费率同步重试任务
+// 每分钟执行一次：按退避时间重试费率修改申请的失败同步，成功后本地费率生效，重试用完后标记申请同步失败
+type RateSyncRetryJob struct {
+	rateChangeService *service.MerchantRateChangeService
+	running           bool
+	mu                sync.Mutex
+}
+
+// NewRateSyncRetryJob 创建费率同步重试任务
+func NewRateSyncRetryJob(rateChangeService *service.MerchantRateChangeService) *RateSyncRetryJob {
+	return &RateSyncRetryJob{
+		rateChangeService: rateChangeService,
+	}
+}
+
+// Run 执行任务（每1分钟执行一次）
+func (j *RateSyncRetryJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.rateChangeService.RetrySyncs(startTime, rateSyncRetryBatchSize)
+	if err != nil {
+		log.Printf("[RateSyncRetryJob] Failed: %v", err)
+		return
+	}
+	if result.Retried > 0 {
+		log.Printf("[RateSyncRetryJob] Retried %d syncs, succeeded=%d, gave_up=%d, took=%v",
+			result.Retried, result.Succeeded, result.GaveUp, time.Since(startTime))
+	}
+}
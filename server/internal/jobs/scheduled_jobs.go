@@ -407,7 +407,7 @@ func (j *PartitionManagerJob) createMonthlyPartition(month time.Time) error {
 }
 
 // MerchantTypeCalculatorJob 商户类型计算定时任务
-// 每天凌晨2点执行，根据最近30天交易额计算商户类型
+// 每天凌晨2点执行，按商户适用的分类规则集（30/60/90天交易指标）计算商户类型并记录变更历史
 type MerchantTypeCalculatorJob struct {
 	merchantRepo    *repository.GormMerchantRepository
 	merchantService *service.MerchantService
@@ -476,16 +476,10 @@ func (j *MerchantTypeCalculatorJob) Run() {
 			break
 		}
 
-		// 逐个计算商户类型
-		for _, merchantID := range merchantIDs {
-			_, err := j.merchantService.CalculateMerchantType(merchantID)
-			if err != nil {
-				log.Printf("[MerchantTypeCalculatorJob] Calculate type failed for merchant %d: %v", merchantID, err)
-				failCount++
-			} else {
-				successCount++
-			}
-		}
+		// 按分类规则批量计算商户类型
+		success, fail := j.merchantService.CalculateMerchantTypes(merchantIDs)
+		successCount += success
+		failCount += fail
 
 		offset += j.batchSize
 
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// SimRenewalJob 流量卡续费提醒任务
+// 每天执行一次：补充流量卡续费预测，提醒终端所属代理商即将扣取流量费的流量卡
+type SimRenewalJob struct {
+	simCardService *service.SimCardService
+	running        bool
+	mu             sync.Mutex
+}
+
+// NewSimRenewalJob 创建流量卡续费提醒任务
+func NewSimRenewalJob(simCardService *service.SimCardService) *SimRenewalJob {
+	return &SimRenewalJob{
+		simCardService: simCardService,
+	}
+}
+
+// Run 执行任务（每24小时执行一次）
+func (j *SimRenewalJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	result, err := j.simCardService.RemindRenewals(startTime)
+	if err != nil {
+		log.Printf("[SimRenewalJob] Failed: %v", err)
+		return
+	}
+	if result.Predicted > 0 || result.Due > 0 {
+		log.Printf("[SimRenewalJob] Predicted=%d, reminded %d sim cards to %d agents, took=%v",
+			result.Predicted, result.Due, result.Agents, time.Since(startTime))
+	}
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// TerminalBatchJob 终端批量任务补处理
+// 每分钟执行：处理服务重启前未开始的批量上传任务，并将中断的任务标记为失败
+type TerminalBatchJob struct {
+	batchService *service.TerminalBatchService
+	running      bool
+	mu           sync.Mutex
+}
+
+// NewTerminalBatchJob 创建终端批量任务补处理
+func NewTerminalBatchJob(batchService *service.TerminalBatchService) *TerminalBatchJob {
+	return &TerminalBatchJob{
+		batchService: batchService,
+	}
+}
+
+// Run 执行任务（每分钟执行一次）
+func (j *TerminalBatchJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	processed, err := j.batchService.ProcessPending(startTime)
+	if err != nil {
+		log.Printf("[TerminalBatchJob] Failed: %v", err)
+		return
+	}
+	if processed > 0 {
+		log.Printf("[TerminalBatchJob] Processed %d pending jobs, took=%v", processed, time.Since(startTime))
+	}
+}
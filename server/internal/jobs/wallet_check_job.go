@@ -8,27 +8,28 @@ import (
 
 	"xiangshoufu/internal/models"
 	"xiangshoufu/internal/repository"
+	"xiangshoufu/internal/service"
 )
 
 // WalletBalanceCheckJob 钱包余额一致性检查定时任务
-// 业务规则：定时核对钱包余额与流水记录是否一致
+// 业务规则：定时回放钱包流水、分润、代扣和调账记录核对余额，差异生成修正建议并告警
 type WalletBalanceCheckJob struct {
-	walletRepo   repository.WalletRepository
-	alertService Alerter
-	batchSize    int
-	running      bool
-	mu           sync.Mutex
+	consistencyService *service.WalletConsistencyService
+	alertService       Alerter
+	batchSize          int
+	running            bool
+	mu                 sync.Mutex
 }
 
 // NewWalletBalanceCheckJob 创建钱包余额一致性检查任务
 func NewWalletBalanceCheckJob(
-	walletRepo repository.WalletRepository,
+	consistencyService *service.WalletConsistencyService,
 	alertService Alerter,
 ) *WalletBalanceCheckJob {
 	return &WalletBalanceCheckJob{
-		walletRepo:   walletRepo,
-		alertService: alertService,
-		batchSize:    500,
+		consistencyService: consistencyService,
+		alertService:       alertService,
+		batchSize:          500,
 	}
 }
 
@@ -41,6 +42,8 @@ type WalletBalanceDiscrepancy struct {
 	CurrentBalance int64  `json:"current_balance"`      // 当前余额(分)
 	CalculatedBalance int64  `json:"calculated_balance"`  // 流水计算余额(分)
 	Difference     int64  `json:"difference"`           // 差异(分)
+	CheckNo        string `json:"check_no"`             // 检查单号
+	Detail         string `json:"detail"`               // 首个分歧说明
 }
 
 // Run 执行任务（每天凌晨4点执行）
@@ -122,32 +125,31 @@ func (j *WalletBalanceCheckJob) RunWithResult() (*models.JobExecutionResult, err
 }
 
 // checkBalanceConsistency 检查余额一致性
+// 逐个钱包回放并保存检查历史，已有待处理检查的钱包跳过，避免重复告警
 func (j *WalletBalanceCheckJob) checkBalanceConsistency() ([]WalletBalanceDiscrepancy, error) {
 	var discrepancies []WalletBalanceDiscrepancy
 
-	// 查询所有钱包并核对
-	// 注意：这里需要WalletRepository支持相关查询方法
-	// 实际实现中需要对比wallet表余额与wallet_logs表流水汇总
-
-	// 由于需要复杂查询，这里提供SQL参考：
-	// SELECT w.id, w.agent_id, w.wallet_type, w.balance,
-	//        COALESCE(SUM(wl.amount), 0) as calculated_balance
-	// FROM wallets w
-	// LEFT JOIN wallet_logs wl ON w.id = wl.wallet_id
-	// GROUP BY w.id
-	// HAVING w.balance != COALESCE(SUM(wl.amount), 0)
-
-	// 这里使用占位实现，实际需要根据repository接口扩展
 	log.Printf("[WalletBalanceCheckJob] Checking wallet balance consistency...")
 
-	// TODO: 实现具体的余额检查逻辑
-	// wallets, err := j.walletRepo.FindAllWithBalance()
-	// for _, wallet := range wallets {
-	//     calculatedBalance, err := j.walletLogRepo.SumByWalletID(wallet.ID)
-	//     if wallet.Balance != calculatedBalance {
-	//         discrepancies = append(discrepancies, WalletBalanceDiscrepancy{...})
-	//     }
-	// }
+	checked, checks, err := j.consistencyService.CheckAll(j.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[WalletBalanceCheckJob] Checked %d wallets", checked)
+
+	for _, c := range checks {
+		discrepancies = append(discrepancies, WalletBalanceDiscrepancy{
+			WalletID:          c.WalletID,
+			AgentID:           c.AgentID,
+			WalletType:        c.WalletType,
+			WalletTypeName:    models.GetWalletTypeName(c.WalletType),
+			CurrentBalance:    c.CurrentBalance,
+			CalculatedBalance: c.ExpectedBalance,
+			Difference:        c.Difference,
+			CheckNo:           c.CheckNo,
+			Detail:            c.DivergenceDetail,
+		})
+	}
 
 	return discrepancies, nil
 }
@@ -167,11 +169,12 @@ func (j *WalletBalanceCheckJob) sendDiscrepancyAlert(discrepancies []WalletBalan
 			message += fmt.Sprintf("\n... 还有 %d 条差异未显示", len(discrepancies)-10)
 			break
 		}
-		message += fmt.Sprintf("- 钱包ID: %d, 代理商: %d, 类型: %s, 当前余额: %.2f, 计算余额: %.2f, 差异: %.2f\n",
-			d.WalletID, d.AgentID, d.WalletTypeName,
+		message += fmt.Sprintf("- 检查单: %s, 钱包ID: %d, 代理商: %d, 类型: %s, 当前余额: %.2f, 计算余额: %.2f, 差异: %.2f, 分歧: %s\n",
+			d.CheckNo, d.WalletID, d.AgentID, d.WalletTypeName,
 			float64(d.CurrentBalance)/100,
 			float64(d.CalculatedBalance)/100,
-			float64(d.Difference)/100)
+			float64(d.Difference)/100,
+			d.Detail)
 	}
 
 	req := &models.AlertRequest{
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// WalletRiskHoldExpireJob 风控冻结到期解除任务
+// 出款校验已忽略到期冻结，本任务负责回写状态并通知代理商
+type WalletRiskHoldExpireJob struct {
+	riskHoldService *service.WalletRiskHoldService
+	batchSize       int
+	running         bool
+	mu              sync.Mutex
+}
+
+// NewWalletRiskHoldExpireJob 创建风控冻结到期解除任务
+func NewWalletRiskHoldExpireJob(riskHoldService *service.WalletRiskHoldService) *WalletRiskHoldExpireJob {
+	return &WalletRiskHoldExpireJob{
+		riskHoldService: riskHoldService,
+		batchSize:       200,
+	}
+}
+
+// Run 执行任务（每10分钟执行一次）
+func (j *WalletRiskHoldExpireJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	released, err := j.riskHoldService.ExpireHolds(j.batchSize)
+	if err != nil {
+		log.Printf("[WalletRiskHoldExpireJob] Failed: %v", err)
+		return
+	}
+	if released > 0 {
+		log.Printf("[WalletRiskHoldExpireJob] Released %d expired holds, took=%v", released, time.Since(startTime))
+	}
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/service"
+)
+
+// WalletTransferExpireJob 待确认划转过期处理任务
+// 接收方超时未确认的划转自动关闭，并解冻转出方金额
+type WalletTransferExpireJob struct {
+	transferService *service.WalletTransferService
+	batchSize       int
+	running         bool
+	mu              sync.Mutex
+}
+
+// NewWalletTransferExpireJob 创建划转过期处理任务
+func NewWalletTransferExpireJob(transferService *service.WalletTransferService) *WalletTransferExpireJob {
+	return &WalletTransferExpireJob{
+		transferService: transferService,
+		batchSize:       200,
+	}
+}
+
+// Run 执行任务（每10分钟执行一次）
+func (j *WalletTransferExpireJob) Run() {
+	j.mu.Lock()
+	if j.running {
+		j.mu.Unlock()
+		return
+	}
+	j.running = true
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running = false
+		j.mu.Unlock()
+	}()
+
+	startTime := time.Now()
+	expired, err := j.transferService.ExpirePendingTransfers(j.batchSize)
+	if err != nil {
+		log.Printf("[WalletTransferExpireJob] Failed: %v", err)
+		return
+	}
+	if expired > 0 {
+		log.Printf("[WalletTransferExpireJob] Expired %d transfers, took=%v", expired, time.Since(startTime))
+	}
+}
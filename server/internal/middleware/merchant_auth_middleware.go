@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"xiangshoufu/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MerchantAuthMiddleware 商户认证中间件，只接受商户令牌（代理商/管理员令牌无法通过）
+// 商户身份存入merchant_id，不设置user_id/agent_id，代理商接口的中间件也不会接受商户令牌
+func MerchantAuthMiddleware(portalService *service.MerchantPortalService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "请先登录",
+			})
+			c.Abort()
+			return
+		}
+
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "无效的认证格式",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := portalService.ValidateToken(parts[1])
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "认证已过期，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("merchant_id", claims.MerchantID)
+		c.Set("merchant_no", claims.MerchantNo)
+
+		c.Next()
+	}
+}
+
+// GetCurrentMerchantID 从上下文获取当前商户ID
+func GetCurrentMerchantID(c *gin.Context) int64 {
+	merchantID, exists := c.Get("merchant_id")
+	if !exists {
+		return 0
+	}
+	if id, ok := merchantID.(int64); ok {
+		return id
+	}
+	return 0
+}
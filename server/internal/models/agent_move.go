@@ -0,0 +1,102 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 迁移冲突类型
+const (
+	AgentMoveConflictChannelMissing    = "channel_missing"    // 新上级未配置该通道结算价
+	AgentMoveConflictSettlementRate    = "settlement_rate"    // 结算费率低于新上级
+	AgentMoveConflictDepositCashback   = "deposit_cashback"   // 押金返现高于新上级
+	AgentMoveConflictSimCashback       = "sim_cashback"       // 流量费返现高于新上级
+	AgentMoveConflictPolicyRate        = "policy_rate"        // 政策费率低于新上级
+	AgentMoveConflictPendingDistribute = "pending_distribute" // 跨越迁移边界的待确认终端下发
+	AgentMoveConflictDeductionPlan     = "deduction_plan"     // 跨越迁移边界的进行中代扣计划
+	AgentMoveConflictDeductionChain    = "deduction_chain"    // 跨越迁移边界的进行中代扣链
+)
+
+// AgentMoveConflict 迁移冲突项
+// Blocking为true时须先处理（调整结算价/政策、确认或拒绝下发）才能迁移，否则仅作提示
+type AgentMoveConflict struct {
+	Type        string `json:"type"`
+	Blocking    bool   `json:"blocking"`
+	ChannelID   int64  `json:"channel_id,omitempty"`
+	BrandCode   string `json:"brand_code,omitempty"`
+	Item        string `json:"item,omitempty"`         // 费率类型/押金档位/返现档位
+	AgentValue  string `json:"agent_value,omitempty"`  // 迁移代理商的配置
+	ParentValue string `json:"parent_value,omitempty"` // 新上级的配置
+	RelatedID   int64  `json:"related_id,omitempty"`   // 下发记录/代扣计划/代扣链ID
+	Message     string `json:"message"`
+}
+
+// AgentMoveConflicts 迁移冲突列表（JSONB）
+type AgentMoveConflicts []AgentMoveConflict
+
+// Scan 实现sql.Scanner接口
+func (c *AgentMoveConflicts) Scan(value interface{}) error {
+	if value == nil {
+		*c = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into AgentMoveConflicts", value)
+	}
+	if len(bytes) == 0 {
+		*c = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+// Value 实现driver.Valuer接口
+func (c AgentMoveConflicts) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	return json.Marshal(c)
+}
+
+// HasBlocking 是否存在须先处理的冲突
+func (c AgentMoveConflicts) HasBlocking() bool {
+	for _, item := range c {
+		if item.Blocking {
+			return true
+		}
+	}
+	return false
+}
+
+// AgentMove 代理商迁移记录
+type AgentMove struct {
+	ID           int64              `json:"id" gorm:"primaryKey"`
+	AgentID      int64              `json:"agent_id" gorm:"not null;index"`
+	AgentName    string             `json:"agent_name" gorm:"size:100"`
+	FromParentID int64              `json:"from_parent_id"`
+	ToParentID   int64              `json:"to_parent_id"`
+	OldPath      string             `json:"old_path" gorm:"size:500"`
+	NewPath      string             `json:"new_path" gorm:"size:500"`
+	OldLevel     int                `json:"old_level"`
+	NewLevel     int                `json:"new_level"`
+	SubtreeSize  int                `json:"subtree_size"`
+	Warnings     AgentMoveConflicts `json:"warnings" gorm:"type:jsonb"`
+	Reason       string             `json:"reason" gorm:"size:255"`
+	OperatorID   int64              `json:"operator_id"`
+	OperatorName string             `json:"operator_name" gorm:"size:50"`
+	CreatedAt    time.Time          `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (AgentMove) TableName() string {
+	return "agent_moves"
+}
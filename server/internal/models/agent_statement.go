@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+)
+
+// 对账单状态
+const (
+	StatementStatusPending   int16 = 1 // 待确认
+	StatementStatusConfirmed int16 = 2 // 已确认
+	StatementStatusDisputed  int16 = 3 // 有异议
+)
+
+// AgentStatement 代理商月度对账单（按代理商+通道）
+// 金额单位均为分，支出类项目为负数；期末余额 = 期初余额 + 各项变动（税费含在提现中，不重复计算）
+type AgentStatement struct {
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	StatementNo string    `json:"statement_no" gorm:"size:50;uniqueIndex"` // 对账单号
+	AgentID     int64     `json:"agent_id" gorm:"index"`
+	ChannelID   int64     `json:"channel_id" gorm:"default:0"`
+	Period      string    `json:"period" gorm:"size:7"` // 账期 YYYY-MM
+	PeriodStart time.Time `json:"period_start"`         // 账期开始（含）
+	PeriodEnd   time.Time `json:"period_end"`           // 账期结束（不含）
+
+	OpeningBalance   int64 `json:"opening_balance"`   // 期初余额
+	TradeProfit      int64 `json:"trade_profit"`      // 交易分润
+	HighRateProfit   int64 `json:"high_rate_profit"`  // 高调分润
+	D0Profit         int64 `json:"d0_profit"`         // P+0分润
+	ProfitRevoked    int64 `json:"profit_revoked"`    // 退款撤销分润
+	DepositCashback  int64 `json:"deposit_cashback"`  // 押金返现
+	SimCashback      int64 `json:"sim_cashback"`      // 流量费返现
+	ActivationReward int64 `json:"activation_reward"` // 激活奖励
+	DeductionOut     int64 `json:"deduction_out"`     // 代扣扣款
+	DeductionIn      int64 `json:"deduction_in"`      // 代扣收款
+	Adjustment       int64 `json:"adjustment"`        // 调账
+	Withdrawal       int64 `json:"withdrawal"`        // 提现
+	TaxFee           int64 `json:"tax_fee"`           // 提现税费（含在提现中）
+	OtherAmount      int64 `json:"other_amount"`      // 其他变动
+	ClosingBalance   int64 `json:"closing_balance"`   // 期末余额
+
+	// 确认与异议
+	Status        int16      `json:"status" gorm:"default:1"` // 1待确认 2已确认 3有异议
+	ConfirmedAt   *time.Time `json:"confirmed_at"`
+	DisputeReason string     `json:"dispute_reason" gorm:"size:500"`
+	DisputedAt    *time.Time `json:"disputed_at"`
+	DisputeReply  string     `json:"dispute_reply" gorm:"size:500"`
+	RepliedBy     *int64     `json:"replied_by"`
+	RepliedByName string     `json:"replied_by_name" gorm:"size:50"`
+	RepliedAt     *time.Time `json:"replied_at"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+	CreatedAt   time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (AgentStatement) TableName() string {
+	return "agent_statements"
+}
+
+// GetStatementStatusName 获取对账单状态名称
+func GetStatementStatusName(status int16) string {
+	switch status {
+	case StatementStatusPending:
+		return "待确认"
+	case StatementStatusConfirmed:
+		return "已确认"
+	case StatementStatusDisputed:
+		return "有异议"
+	default:
+		return "未知"
+	}
+}
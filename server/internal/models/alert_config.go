@@ -16,6 +16,7 @@ const (
 	AlertTypeJobFailed       int16 = 1 // 任务失败
 	AlertTypeConsecutiveFail int16 = 2 // 连续失败
 	AlertTypeJobTimeout      int16 = 3 // 任务超时
+	AlertTypeInventoryStale  int16 = 4 // 呆滞库存
 )
 
 // 告警发送状态
@@ -48,6 +49,8 @@ func GetAlertTypeName(alertType int16) string {
 		return "连续失败"
 	case AlertTypeJobTimeout:
 		return "任务超时"
+	case AlertTypeInventoryStale:
+		return "呆滞库存"
 	default:
 		return "未知"
 	}
@@ -99,7 +102,7 @@ func (c *AlertConfig) GetChannelTypeName() string {
 type AlertLog struct {
 	ID           int64      `json:"id" gorm:"primaryKey"`
 	JobName      string     `json:"job_name" gorm:"size:100;index"`       // 任务名称
-	AlertType    int16      `json:"alert_type"`                           // 1任务失败 2连续失败 3任务超时
+	AlertType    int16      `json:"alert_type"`                           // 1任务失败 2连续失败 3任务超时 4呆滞库存
 	ChannelType  int16      `json:"channel_type"`                         // 1钉钉 2企微 3邮件
 	ConfigID     *int64     `json:"config_id"`                            // 关联的告警配置ID
 	Title        string     `json:"title" gorm:"size:200"`                // 告警标题
@@ -23,6 +23,9 @@ const (
 	AuditLogTypeTerminalOp   AuditLogType = 13 // 终端操作
 	AuditLogTypeDeduction    AuditLogType = 14 // 代扣操作
 	AuditLogTypeReward       AuditLogType = 15 // 奖励发放
+	AuditLogTypeWalletAdjust AuditLogType = 16 // 钱包调账
+	AuditLogTypeRiskHold     AuditLogType = 17 // 风控冻结
+	AuditLogTypeAgentMove    AuditLogType = 18 // 代理商迁移
 )
 
 // AuditLogLevel 审计日志级别
@@ -82,6 +85,9 @@ func GetLogTypeName(logType AuditLogType) string {
 		AuditLogTypeTerminalOp:   "终端操作",
 		AuditLogTypeDeduction:    "代扣操作",
 		AuditLogTypeReward:       "奖励发放",
+		AuditLogTypeWalletAdjust: "钱包调账",
+		AuditLogTypeRiskHold:     "风控冻结",
+		AuditLogTypeAgentMove:    "代理商迁移",
 	}
 	if name, ok := names[logType]; ok {
 		return name
@@ -48,6 +48,9 @@ type DeviceFee struct {
 	ChargingTime   time.Time `json:"charging_time" gorm:"not null"`
 	ReceivedAt     time.Time `json:"received_at" gorm:"default:now()"`
 	BrandCode      string    `json:"brand_code" gorm:"size:32"`
+	ICCID          string    `json:"iccid" gorm:"column:iccid;size:22"`  // 流量卡ICCID（通道回传或按装卡记录归属）
+	SimCardID      *int64    `json:"sim_card_id"`                        // 归属的流量卡
+	SimFeeCount    int       `json:"sim_fee_count" gorm:"default:0"`     // 该卡第几次缴费
 	ExtData        string    `json:"ext_data" gorm:"type:jsonb"`
 	CreatedAt      time.Time `json:"created_at" gorm:"default:now()"`
 	UpdatedAt      time.Time `json:"updated_at" gorm:"default:now()"`
@@ -107,14 +110,26 @@ func (Message) TableName() string {
 
 // MessageType 消息类型常量
 const (
-	MessageTypeProfit       = 1 // 交易分润
-	MessageTypeActivation   = 2 // 激活奖励
-	MessageTypeDeposit      = 3 // 押金返现
-	MessageTypeSimCashback  = 4 // 流量返现
-	MessageTypeRefund       = 5 // 退款撤销
-	MessageTypeAnnouncement = 6 // 系统公告
-	MessageTypeNewAgent     = 7 // 新代理注册
-	MessageTypeTransaction  = 8 // 交易通知
+	MessageTypeProfit           = 1  // 交易分润
+	MessageTypeActivation       = 2  // 激活奖励
+	MessageTypeDeposit          = 3  // 押金返现
+	MessageTypeSimCashback      = 4  // 流量返现
+	MessageTypeRefund           = 5  // 退款撤销
+	MessageTypeAnnouncement     = 6  // 系统公告
+	MessageTypeNewAgent         = 7  // 新代理注册
+	MessageTypeTransaction      = 8  // 交易通知
+	MessageTypeWalletTransfer   = 9  // 钱包划转
+	MessageTypeRiskHold         = 10 // 风控冻结
+	MessageTypeStatement        = 11 // 对账单
+	MessageTypeDeductionOverdue = 12 // 代扣逾期
+	MessageTypeInventoryAlert   = 13 // 库存预警
+	MessageTypeSimRenewal       = 14 // 流量卡续费提醒
+	MessageTypeChurnWarning     = 15 // 商户流失预警
+	MessageTypeRateChange       = 16 // 费率修改审批
+	MessageTypeRatePlan         = 17 // 费率计划
+	MessageTypeKycExpiry        = 18 // 证件到期提醒
+	MessageTypeSettleCard       = 19 // 结算卡变更
+	MessageTypeOnboarding       = 20 // 商户进件
 )
 
 // MessageCategory APP端消息分类
@@ -123,7 +138,7 @@ const (
 	MessageCategoryProfit      = "profit"      // 分润（类型1,2,3,4）
 	MessageCategoryRegister    = "register"    // 注册（类型7）
 	MessageCategoryConsumption = "consumption" // 消费（类型8）
-	MessageCategorySystem      = "system"      // 系统（类型5,6）
+	MessageCategorySystem      = "system"      // 系统（类型5,6,9,10,11,12,13,14）
 )
 
 // GetMessageTypesByCategory 根据分类获取消息类型列表
@@ -136,7 +151,7 @@ func GetMessageTypesByCategory(category string) []int16 {
 	case MessageCategoryConsumption:
 		return []int16{MessageTypeTransaction}
 	case MessageCategorySystem:
-		return []int16{MessageTypeRefund, MessageTypeAnnouncement}
+		return []int16{MessageTypeRefund, MessageTypeAnnouncement, MessageTypeWalletTransfer, MessageTypeRiskHold, MessageTypeStatement, MessageTypeDeductionOverdue, MessageTypeInventoryAlert, MessageTypeSimRenewal, MessageTypeChurnWarning, MessageTypeRateChange, MessageTypeRatePlan, MessageTypeKycExpiry, MessageTypeSettleCard, MessageTypeOnboarding}
 	default:
 		return nil // 全部类型
 	}
@@ -161,6 +176,30 @@ func GetMessageTypeName(messageType int16) string {
 		return "新代理注册"
 	case MessageTypeTransaction:
 		return "交易通知"
+	case MessageTypeWalletTransfer:
+		return "钱包划转"
+	case MessageTypeRiskHold:
+		return "风控冻结"
+	case MessageTypeStatement:
+		return "对账单"
+	case MessageTypeDeductionOverdue:
+		return "代扣逾期"
+	case MessageTypeInventoryAlert:
+		return "库存预警"
+	case MessageTypeSimRenewal:
+		return "流量卡续费提醒"
+	case MessageTypeChurnWarning:
+		return "商户流失预警"
+	case MessageTypeRateChange:
+		return "费率修改审批"
+	case MessageTypeRatePlan:
+		return "费率计划"
+	case MessageTypeKycExpiry:
+		return "证件到期提醒"
+	case MessageTypeSettleCard:
+		return "结算卡变更"
+	case MessageTypeOnboarding:
+		return "商户进件"
 	default:
 		return "未知类型"
 	}
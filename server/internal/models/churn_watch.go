@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+// 流失预警触发类型
+const (
+	ChurnTriggerVolumeDrop = "volume_drop" // 近7天交易额较前7天下降
+	ChurnTriggerInactive   = "inactive"    // 长时间无交易
+)
+
+// 跟进任务状态
+const (
+	FollowUpStatusPending   int16 = 1 // 待跟进
+	FollowUpStatusFollowing int16 = 2 // 跟进中
+	FollowUpStatusRecovered int16 = 3 // 已挽回
+	FollowUpStatusLost      int16 = 4 // 已流失
+)
+
+// 跟进结果
+const (
+	FollowUpOutcomeContacted        = "contacted"         // 已联系
+	FollowUpOutcomeReplacedTerminal = "replaced_terminal" // 已换机
+	FollowUpOutcomeLost             = "lost"              // 已流失
+	FollowUpOutcomeRecovered        = "recovered"         // 交易恢复（系统）
+)
+
+// ChurnWatchConfig 商户流失预警配置（全局单行）
+type ChurnWatchConfig struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	Enabled        bool      `json:"enabled" gorm:"default:true"`
+	DropPercent    int       `json:"drop_percent" gorm:"default:50"`        // 近7天交易额较前7天下降百分比阈值
+	MinBaseAmount  int64     `json:"min_base_amount" gorm:"default:100000"` // 前7天交易额低于该值（分）不做环比预警
+	InactiveDays   int       `json:"inactive_days" gorm:"default:15"`       // 距最近交易天数阈值
+	RecoverPercent int       `json:"recover_percent" gorm:"default:80"`     // 近7天交易额恢复到基准的百分比视为已挽回
+	CooldownDays   int       `json:"cooldown_days" gorm:"default:30"`       // 任务结束后不再生成任务的天数
+	FollowUpDays   int       `json:"follow_up_days" gorm:"default:7"`       // 跟进期限（天）
+	UpdatedBy      int64     `json:"updated_by"`
+	UpdatedByName  string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (ChurnWatchConfig) TableName() string {
+	return "churn_watch_configs"
+}
+
+// MerchantFollowUpTask 商户流失跟进任务
+type MerchantFollowUpTask struct {
+	ID             int64      `json:"id" gorm:"primaryKey"`
+	MerchantID     int64      `json:"merchant_id" gorm:"not null;index"`
+	MerchantNo     string     `json:"merchant_no" gorm:"size:64"`
+	MerchantName   string     `json:"merchant_name" gorm:"size:100"`
+	AgentID        int64      `json:"agent_id" gorm:"not null;index"`   // 跟进代理商
+	TriggerType    string     `json:"trigger_type" gorm:"size:20"`      // volume_drop/inactive
+	LastWeekAmount int64      `json:"last_week_amount"`                 // 预警时近7天交易额（分）
+	PrevWeekAmount int64      `json:"prev_week_amount"`                 // 预警时前7天交易额（分）
+	BaseAmount     int64      `json:"base_amount"`                      // 挽回判断基准（分）
+	LastTradeAt    *time.Time `json:"last_trade_at"`                    // 预警时最近交易时间
+	InactiveDays   int        `json:"inactive_days"`                    // 预警时距最近交易天数
+	Status         int16      `json:"status" gorm:"default:1"`          // 1待跟进 2跟进中 3已挽回 4已流失
+	LastOutcome    string     `json:"last_outcome" gorm:"size:20"`      // 最近跟进结果
+	FollowUpCount  int        `json:"follow_up_count" gorm:"default:0"` // 跟进次数
+	DueAt          time.Time  `json:"due_at"`                           // 跟进期限
+	ResolvedAt     *time.Time `json:"resolved_at"`                      // 挽回/流失时间
+	CreatedAt      time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantFollowUpTask) TableName() string {
+	return "merchant_follow_up_tasks"
+}
+
+// IsOpen 任务是否未结束
+func (t *MerchantFollowUpTask) IsOpen() bool {
+	return t.Status == FollowUpStatusPending || t.Status == FollowUpStatusFollowing
+}
+
+// MerchantFollowUpLog 商户流失跟进记录
+type MerchantFollowUpLog struct {
+	ID           int64     `json:"id" gorm:"primaryKey"`
+	TaskID       int64     `json:"task_id" gorm:"not null;index"`
+	Outcome      string    `json:"outcome" gorm:"size:20;not null"`
+	Remark       string    `json:"remark" gorm:"size:500"`
+	OperatorID   int64     `json:"operator_id"` // 0为系统
+	OperatorName string    `json:"operator_name" gorm:"size:50"`
+	CreatedAt    time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantFollowUpLog) TableName() string {
+	return "merchant_follow_up_logs"
+}
+
+// GetFollowUpStatusName 获取跟进任务状态名称
+func GetFollowUpStatusName(status int16) string {
+	switch status {
+	case FollowUpStatusPending:
+		return "待跟进"
+	case FollowUpStatusFollowing:
+		return "跟进中"
+	case FollowUpStatusRecovered:
+		return "已挽回"
+	case FollowUpStatusLost:
+		return "已流失"
+	default:
+		return "未知"
+	}
+}
+
+// GetChurnTriggerName 获取预警触发类型名称
+func GetChurnTriggerName(trigger string) string {
+	switch trigger {
+	case ChurnTriggerVolumeDrop:
+		return "交易额下降"
+	case ChurnTriggerInactive:
+		return "长时间无交易"
+	default:
+		return "未知"
+	}
+}
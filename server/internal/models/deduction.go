@@ -127,6 +127,9 @@ type DeductionRecord struct {
 	ScheduledAt   time.Time  `json:"scheduled_at" gorm:"not null;index"` // 计划扣款时间
 	DeductedAt    *time.Time `json:"deducted_at"`                        // 实际扣款时间
 	CreatedAt     time.Time  `json:"created_at" gorm:"default:now()"`
+
+	// 由货款代扣明细迁移而来时的原明细ID（钱包流水为 goods_deduction_detail）
+	LegacyDetailID *int64 `json:"legacy_detail_id"`
 }
 
 func (DeductionRecord) TableName() string {
@@ -139,6 +142,8 @@ const (
 	DeductionRecordStatusSuccess        = 1 // 成功
 	DeductionRecordStatusPartialSuccess = 2 // 部分成功
 	DeductionRecordStatusFailed         = 3 // 失败
+	DeductionRecordStatusSettled        = 4 // 已结清（提前还款后不再扣款）
+	DeductionRecordStatusRestructured   = 5 // 已重组（被新扣款计划替代）
 )
 
 // WalletDeductDetail 钱包扣款明细
@@ -207,8 +212,12 @@ func (DeductionFreezeLog) TableName() string {
 
 // DeductionFreezeTriggerType 冻结触发类型
 const (
-	DeductionFreezeTriggerTypeAccept = "accept" // 接收确认时冻结
-	DeductionFreezeTriggerTypeIncome = "income" // 入账时冻结
+	DeductionFreezeTriggerTypeAccept  = "accept"  // 接收确认时冻结
+	DeductionFreezeTriggerTypeIncome  = "income"  // 入账时冻结
+	DeductionFreezeTriggerTypeDeduct  = "deduct"  // 到期扣款时扣减冻结（金额为负数）
+	DeductionFreezeTriggerTypePrepay  = "prepay"  // 提前还款时释放冻结（金额为负数）
+	DeductionFreezeTriggerTypeOverdue = "overdue" // 逾期补扣时扣减冻结（金额为负数）
+	DeductionFreezeTriggerTypeRecall  = "recall"  // 终端回拨冲减货款时释放冻结（金额为负数）
 )
 
 // DeductionPlanListResponse 代扣计划列表响应
@@ -306,3 +315,93 @@ type DeductionSummary struct {
 	RemainingAmount   int64 `json:"remaining_amount"`    // 剩余待扣总金额（分）
 	TotalFrozenAmount int64 `json:"total_frozen_amount"` // 总冻结金额（分）
 }
+
+// DeductionPlanChange 代扣计划变更（提前还款/重组）
+// 保留变更前后的扣款计划快照，用于审计
+type DeductionPlanChange struct {
+	ID             int64  `json:"id" gorm:"primaryKey"`
+	ChangeNo       string `json:"change_no" gorm:"size:64;uniqueIndex"` // 变更编号
+	PlanID         int64  `json:"plan_id" gorm:"not null;index"`        // 代扣计划ID
+	PlanNo         string `json:"plan_no" gorm:"size:64"`               // 计划编号
+	ChangeType     int16  `json:"change_type" gorm:"not null"`          // 1:提前还款 2:重组
+	Status         int16  `json:"status" gorm:"default:0"`              // 0:待确认 1:已生效 2:已拒绝 3:已撤回 4:已失效
+	ProposerID     int64  `json:"proposer_id" gorm:"not null"`          // 发起方代理商ID
+	CounterpartyID int64  `json:"counterparty_id" gorm:"index"`         // 确认方代理商ID（提前还款为扣款方，仅通知）
+
+	// 变更前
+	OldTotalPeriods    int    `json:"old_total_periods"`              // 原总期数
+	OldPeriodAmount    int64  `json:"old_period_amount"`              // 原每期金额（分）
+	OldRemainingAmount int64  `json:"old_remaining_amount"`           // 变更时剩余金额（分）
+	OldSchedule        string `json:"old_schedule" gorm:"type:jsonb"` // 原未执行扣款计划快照
+
+	// 变更后
+	NewPeriods      int    `json:"new_periods"`                      // 新剩余期数（重组）
+	NewPeriodAmount int64  `json:"new_period_amount"`                // 新每期金额（分，重组）
+	NewSchedule     string `json:"new_schedule" gorm:"type:jsonb"`   // 新扣款计划快照
+	PrepayAmount    int64  `json:"prepay_amount"`                    // 提前还款金额（分）
+	ReleasedFrozen  int64  `json:"released_frozen"`                  // 提前还款释放的冻结金额（分）
+	WalletDetails   string `json:"wallet_details" gorm:"type:jsonb"` // 提前还款钱包扣款明细
+	RecordID        *int64 `json:"record_id"`                        // 提前还款生成的代扣记录ID
+
+	Reason       string     `json:"reason" gorm:"size:255"`        // 变更原因
+	RejectReason string     `json:"reject_reason" gorm:"size:255"` // 拒绝原因
+	DecidedAt    *time.Time `json:"decided_at"`                    // 确认/拒绝时间
+	CreatedAt    time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+func (DeductionPlanChange) TableName() string {
+	return "deduction_plan_changes"
+}
+
+// DeductionPlanChangeType 代扣计划变更类型
+const (
+	DeductionPlanChangeTypePrepay      int16 = 1 // 提前还款
+	DeductionPlanChangeTypeRestructure int16 = 2 // 重组
+)
+
+// DeductionPlanChangeStatus 代扣计划变更状态
+const (
+	DeductionPlanChangeStatusPending   int16 = 0 // 待确认
+	DeductionPlanChangeStatusApplied   int16 = 1 // 已生效
+	DeductionPlanChangeStatusRejected  int16 = 2 // 已拒绝
+	DeductionPlanChangeStatusWithdrawn int16 = 3 // 已撤回
+	DeductionPlanChangeStatusExpired   int16 = 4 // 已失效（计划已结束）
+)
+
+// GetDeductionPlanChangeTypeName 获取变更类型名称
+func GetDeductionPlanChangeTypeName(changeType int16) string {
+	switch changeType {
+	case DeductionPlanChangeTypePrepay:
+		return "提前还款"
+	case DeductionPlanChangeTypeRestructure:
+		return "重组"
+	default:
+		return "未知"
+	}
+}
+
+// GetDeductionPlanChangeStatusName 获取变更状态名称
+func GetDeductionPlanChangeStatusName(status int16) string {
+	switch status {
+	case DeductionPlanChangeStatusPending:
+		return "待确认"
+	case DeductionPlanChangeStatusApplied:
+		return "已生效"
+	case DeductionPlanChangeStatusRejected:
+		return "已拒绝"
+	case DeductionPlanChangeStatusWithdrawn:
+		return "已撤回"
+	case DeductionPlanChangeStatusExpired:
+		return "已失效"
+	default:
+		return "未知"
+	}
+}
+
+// DeductionScheduleItem 扣款计划快照项
+type DeductionScheduleItem struct {
+	PeriodNum   int       `json:"period_num"`
+	Amount      int64     `json:"amount"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
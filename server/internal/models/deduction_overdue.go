@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+)
+
+// DeductionOverdueConfig 代扣逾期处理配置（全局单行）
+type DeductionOverdueConfig struct {
+	ID                   int64     `json:"id" gorm:"primaryKey"`
+	GraceDays            int       `json:"grace_days" gorm:"default:0"`             // 宽限天数：逾期超过宽限期后才计收滞纳金
+	LateFeeDailyRate     int       `json:"late_fee_daily_rate" gorm:"default:0"`    // 滞纳金日费率（万分之），0表示不收取
+	LateFeeCapRate       int       `json:"late_fee_cap_rate" gorm:"default:0"`      // 滞纳金上限（占逾期本金的万分比），0表示不设上限
+	NotifyDays           string    `json:"notify_days" gorm:"size:100"`             // 通知节点（逾期天数，逗号分隔），如 1,3,7,15,30
+	EscalateDays         int       `json:"escalate_days" gorm:"default:0"`          // 逾期N天后上报被扣款方上级，0表示不上报
+	AutoDrawParent       bool      `json:"auto_draw_parent" gorm:"default:false"`   // 上报时是否自动从上级钱包代偿
+	ParentRecoverPeriods int       `json:"parent_recover_periods" gorm:"default:1"` // 上级代偿后通过代扣链向下级追偿的期数
+	BlockWithdrawDays    int       `json:"block_withdraw_days" gorm:"default:0"`    // 逾期N天后冻结被扣款方出款，0表示不冻结
+	UpdatedBy            int64     `json:"updated_by"`
+	UpdatedByName        string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt            time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (DeductionOverdueConfig) TableName() string {
+	return "deduction_overdue_configs"
+}
+
+// 代扣逾期状态
+const (
+	DeductionOverdueStatusOpen    int16 = 1 // 逾期中
+	DeductionOverdueStatusCleared int16 = 2 // 已结清
+	DeductionOverdueStatusClosed  int16 = 3 // 已关闭（计划取消）
+)
+
+// DeductionOverdue 代扣逾期记录（每个计划同时只有一条逾期中的记录）
+// 逾期本金 = 已到期代扣记录的应扣金额 - 实扣金额
+type DeductionOverdue struct {
+	ID         int64  `json:"id" gorm:"primaryKey"`
+	OverdueNo  string `json:"overdue_no" gorm:"size:64;uniqueIndex"` // 逾期编号
+	PlanID     int64  `json:"plan_id" gorm:"not null;index"`         // 代扣计划ID
+	PlanNo     string `json:"plan_no" gorm:"size:64"`                // 计划编号
+	DeductorID int64  `json:"deductor_id" gorm:"not null;index"`     // 扣款方（债权人）
+	DeducteeID int64  `json:"deductee_id" gorm:"not null;index"`     // 被扣款方（债务人）
+	Status     int16  `json:"status" gorm:"default:1"`               // 1逾期中 2已结清 3已关闭
+
+	OverdueAmount    int64      `json:"overdue_amount"`      // 当前逾期本金（分）
+	FirstDueAt       time.Time  `json:"first_due_at"`        // 最早未清偿的应扣时间
+	DaysPastDue      int        `json:"days_past_due"`       // 逾期天数
+	CollectedAmount  int64      `json:"collected_amount"`    // 逾期后补扣本金（分）
+	LateFee          int64      `json:"late_fee"`            // 累计滞纳金（分）
+	LateFeePaid      int64      `json:"late_fee_paid"`       // 已收滞纳金（分）
+	LateFeeAccruedTo *time.Time `json:"late_fee_accrued_to"` // 滞纳金已计至日期
+	LastNotifiedDay  int        `json:"last_notified_day"`   // 最近一次通知的逾期天数节点
+
+	// 上报与代偿
+	EscalatedTo  int64      `json:"escalated_to"`   // 上报的上级代理商ID
+	EscalatedAt  *time.Time `json:"escalated_at"`   // 上报时间
+	DrawnAmount  int64      `json:"drawn_amount"`   // 上级代偿金额（分）
+	DrawnChainID *int64     `json:"drawn_chain_id"` // 上级追偿代扣链ID
+
+	RiskHoldID *int64     `json:"risk_hold_id"` // 出款冻结ID
+	ClearedAt  *time.Time `json:"cleared_at"`   // 结清/关闭时间
+	CreatedAt  time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (DeductionOverdue) TableName() string {
+	return "deduction_overdues"
+}
+
+// UnpaidLateFee 未收滞纳金
+func (o *DeductionOverdue) UnpaidLateFee() int64 {
+	return o.LateFee - o.LateFeePaid
+}
+
+// GetDeductionOverdueStatusName 获取逾期状态名称
+func GetDeductionOverdueStatusName(status int16) string {
+	switch status {
+	case DeductionOverdueStatusOpen:
+		return "逾期中"
+	case DeductionOverdueStatusCleared:
+		return "已结清"
+	case DeductionOverdueStatusClosed:
+		return "已关闭"
+	default:
+		return "未知"
+	}
+}
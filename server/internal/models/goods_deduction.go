@@ -5,14 +5,9 @@ import (
 )
 
 // GoodsDeduction 货款代扣
-// 终端划拨时设置的货款代扣，与代扣管理模块独立
-// 业务规则:
-//   1. 扣款规则: 分润优先 - 先扣分润钱包，扣完再扣服务费钱包
-//   2. 扣款时机: 实时扣款 - 钱包入账时立即触发扣款
-//   3. 部分扣款: 有多少扣多少 - 余额不足时部分扣除，剩余下次继续扣
-//   4. 扣款上限: 无上限 - 每次入账时全部扣除，直到扣完为止
-//   5. 扣款优先级: 货款代扣 > 上级代扣 > 伙伴代扣
-//   6. 与提现关系: 待扣金额占用钱包余额，影响可提现金额
+// 终端划拨时设置的货款代扣，现已并入统一代扣计划（DeductionPlan）：
+// 本表仅保留终端、单价、协议等货款信息，扣款进度以 MigratedPlanID 指向的计划为准，
+// 扣款规则（冻结、每日扣款、FIFO）与代扣管理模块一致
 type GoodsDeduction struct {
 	ID              int64      `json:"id" gorm:"primaryKey"`
 	DeductionNo     string     `json:"deduction_no" gorm:"size:64;uniqueIndex"`  // 代扣编号
@@ -35,6 +30,10 @@ type GoodsDeduction struct {
 	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
 	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:now()"`
 
+	// 并入统一代扣计划后的关联
+	MigratedPlanID *int64     `json:"migrated_plan_id" gorm:"index"` // 代扣计划ID
+	MigratedAt     *time.Time `json:"migrated_at"`                   // 迁移时间
+
 	// 关联数据（非数据库字段）
 	FromAgentName string                     `json:"from_agent_name" gorm:"-"` // 发起方名称
 	ToAgentName   string                     `json:"to_agent_name" gorm:"-"`   // 接收方名称
@@ -52,6 +51,7 @@ const (
 	GoodsDeductionStatusInProgress    int16 = 2 // 进行中
 	GoodsDeductionStatusCompleted     int16 = 3 // 已完成
 	GoodsDeductionStatusRejected      int16 = 4 // 已拒绝
+	GoodsDeductionStatusCancelled     int16 = 5 // 已取消（统一代扣计划取消）
 )
 
 // GetGoodsDeductionStatusName 获取货款代扣状态名称
@@ -65,6 +65,8 @@ func GetGoodsDeductionStatusName(status int16) string {
 		return "已完成"
 	case GoodsDeductionStatusRejected:
 		return "已拒绝"
+	case GoodsDeductionStatusCancelled:
+		return "已取消"
 	default:
 		return "未知"
 	}
@@ -122,8 +124,12 @@ func (GoodsDeductionDetail) TableName() string {
 const (
 	GoodsDeductionTriggerTypeProfit     = "profit_income"      // 分润入账
 	GoodsDeductionTriggerTypeServiceFee = "service_fee_income" // 服务费入账
+	GoodsDeductionTriggerTypePlan       = "deduction_plan"     // 统一代扣计划扣款
 )
 
+// 货款代扣在统一代扣计划中的关联类型
+const GoodsDeductionRelatedType = "goods_deduction"
+
 // GoodsDeductionTerminal 货款代扣终端关联
 type GoodsDeductionTerminal struct {
 	ID          int64     `json:"id" gorm:"primaryKey"`
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// GoodsDeductionMigrationRun 货款代扣迁移批次
+// 试运行只生成核对报告不写入；正式迁移逐笔在事务内转换，失败或不一致的记录跳过并写入报告
+type GoodsDeductionMigrationRun struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	RunNo           string     `json:"run_no" gorm:"size:64;uniqueIndex"` // 批次编号
+	DryRun          bool       `json:"dry_run"`                           // 是否试运行
+	TotalCount      int        `json:"total_count"`                       // 待迁移货款代扣数
+	InFlightCount   int        `json:"in_flight_count"`                   // 其中待接收/进行中数
+	MigratedCount   int        `json:"migrated_count"`                    // 新建计划数
+	AdoptedCount    int        `json:"adopted_count"`                     // 接管031脚本计划数
+	MismatchCount   int        `json:"mismatch_count"`                    // 数据不一致跳过数
+	ConflictCount   int        `json:"conflict_count"`                    // 编号冲突跳过数
+	FailedCount     int        `json:"failed_count"`                      // 执行失败数
+	RemainingAmount int64      `json:"remaining_amount"`                  // 迁移的剩余待扣总额（分）
+	FrozenAmount    int64      `json:"frozen_amount"`                     // 迁移后冻结的现有余额（分）
+	Report          string     `json:"-" gorm:"type:jsonb"`               // 逐笔核对结果JSON
+	OperatorID      int64      `json:"operator_id"`
+	OperatorName    string     `json:"operator_name" gorm:"size:64"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
+	FinishedAt      *time.Time `json:"finished_at"`
+
+	// 关联数据（非数据库字段）
+	Items []*GoodsDeductionMigrationItem `json:"items,omitempty" gorm:"-"` // 逐笔核对结果
+}
+
+func (GoodsDeductionMigrationRun) TableName() string {
+	return "goods_deduction_migration_runs"
+}
+
+// GoodsDeductionMigrationItem 单笔货款代扣的迁移核对结果
+type GoodsDeductionMigrationItem struct {
+	DeductionID     int64    `json:"deduction_id"`
+	DeductionNo     string   `json:"deduction_no"`
+	LegacyStatus    int16    `json:"legacy_status"`
+	PlanID          int64    `json:"plan_id,omitempty"`
+	PlanStatus      int16    `json:"plan_status"`
+	Result          string   `json:"result"`
+	TotalAmount     int64    `json:"total_amount"`
+	DeductedAmount  int64    `json:"deducted_amount"`
+	RemainingAmount int64    `json:"remaining_amount"`
+	DetailCount     int      `json:"detail_count"`
+	DetailAmount    int64    `json:"detail_amount"`
+	FrozenAmount    int64    `json:"frozen_amount,omitempty"`
+	Issues          []string `json:"issues,omitempty"`
+}
+
+// 迁移结果
+const (
+	GoodsDeductionMigrationResultMigrated = "migrated" // 新建计划
+	GoodsDeductionMigrationResultAdopted  = "adopted"  // 接管031脚本计划
+	GoodsDeductionMigrationResultMismatch = "mismatch" // 原数据不一致，跳过
+	GoodsDeductionMigrationResultConflict = "conflict" // 同编号计划已有扣款，跳过
+	GoodsDeductionMigrationResultFailed   = "failed"   // 执行失败
+)
+
+// GoodsDeductionReconcileReport 迁移后对账报告
+type GoodsDeductionReconcileReport struct {
+	CheckedCount    int                            `json:"checked_count"`    // 已迁移核对数
+	MatchedCount    int                            `json:"matched_count"`    // 一致数
+	MismatchCount   int                            `json:"mismatch_count"`   // 不一致数
+	UnmigratedCount int64                          `json:"unmigrated_count"` // 尚未迁移数
+	Items           []*GoodsDeductionMigrationItem `json:"items"`            // 不一致明细
+	CheckedAt       time.Time                      `json:"checked_at"`
+}
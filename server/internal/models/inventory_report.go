@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// InventoryAlertConfig 呆滞库存预警配置（全局单行）
+type InventoryAlertConfig struct {
+	ID                  int64     `json:"id" gorm:"primaryKey"`
+	Enabled             bool      `json:"enabled" gorm:"default:true"`                   // 是否开启呆滞库存预警
+	StaleDays           int       `json:"stale_days" gorm:"default:90"`                  // 库龄超过N天视为呆滞
+	StaleCountThreshold int       `json:"stale_count_threshold" gorm:"default:20"`       // 呆滞台数达到N台时预警
+	RemindIntervalDays  int       `json:"remind_interval_days" gorm:"default:7"`         // 同一代理商重复预警间隔天数
+	NotifyParent        bool      `json:"notify_parent" gorm:"default:true"`             // 是否同时通知直属上级
+	AlertHQ             bool      `json:"alert_hq" gorm:"column:alert_hq;default:false"` // 是否推送总部告警通道
+	UpdatedBy           int64     `json:"updated_by"`
+	UpdatedByName       string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt           time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (InventoryAlertConfig) TableName() string {
+	return "inventory_alert_configs"
+}
+
+// InventoryAlert 呆滞库存预警记录
+type InventoryAlert struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	AgentID    int64     `json:"agent_id" gorm:"not null;index"`
+	AlertDate  time.Time `json:"alert_date" gorm:"type:date;not null"`
+	StaleDays  int       `json:"stale_days"`  // 预警时的呆滞天数
+	StaleCount int       `json:"stale_count"` // 呆滞台数
+	StockCount int       `json:"stock_count"` // 库存总台数
+	Threshold  int       `json:"threshold"`   // 预警阈值
+	CreatedAt  time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (InventoryAlert) TableName() string {
+	return "inventory_alerts"
+}
+
+// 库存账龄分段（天）：0-30 / 30-60 / 60-90 / 90+
+var InventoryAgingBounds = []int{30, 60, 90}
+
+// 呆滞终端类型
+const (
+	InventoryStaleKindStock         = "stock"          // 库龄超期的未绑定库存
+	InventoryStaleKindBoundInactive = "bound_inactive" // 已绑定未激活
+)
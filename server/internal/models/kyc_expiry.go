@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// KycExpiryConfig 证件到期提醒配置（全局一行）
+type KycExpiryConfig struct {
+	ID            int64     `json:"id" gorm:"primaryKey"`
+	Enabled       bool      `json:"enabled" gorm:"default:true"`
+	WindowDays    Int64List `json:"window_days" gorm:"type:jsonb"`      // 到期前提醒窗口（天），如[60,30,7]
+	NotifyExpired bool      `json:"notify_expired" gorm:"default:true"` // 已过期时再提醒一次
+	UpdatedBy     int64     `json:"updated_by"`
+	UpdatedByName string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (KycExpiryConfig) TableName() string {
+	return "kyc_expiry_configs"
+}
+
+// KycExpiryReminder 证件到期提醒记录
+type KycExpiryReminder struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	MerchantID int64     `json:"merchant_id" gorm:"not null"`
+	AgentID    int64     `json:"agent_id" gorm:"not null;index"`
+	EndDate    time.Time `json:"end_date" gorm:"type:date"`
+	WindowDays int       `json:"window_days"` // 命中的提醒窗口，0表示已过期
+	DaysLeft   int       `json:"days_left"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (KycExpiryReminder) TableName() string {
+	return "kyc_expiry_reminders"
+}
+
+// 证件到期分档
+const (
+	KycBucketExpired  = "expired"   // 已过期
+	KycBucketWithin7  = "within_7"  // 7天内到期
+	KycBucketWithin30 = "within_30" // 30天内到期
+	KycBucketWithin60 = "within_60" // 60天内到期
+	KycBucketValid    = "valid"     // 有效期超过60天
+	KycBucketLongTerm = "long_term" // 长期有效
+	KycBucketMissing  = "missing"   // 未获取有效期
+)
@@ -18,13 +18,18 @@ type Merchant struct {
 	CreditRate    string     `json:"credit_rate" gorm:"type:decimal(10,4)"`
 	DebitRate     string     `json:"debit_rate" gorm:"type:decimal(10,4)"`
 	// 新增字段
-	MerchantType    string     `json:"merchant_type" gorm:"size:20;default:'normal';index"` // loyal/quality/potential/normal/low_active/inactive
-	IsDirect        bool       `json:"is_direct" gorm:"default:true;index"`                 // true=直营 false=团队
-	ActivatedAt     *time.Time `json:"activated_at"`                                        // 激活时间(首次交易时间)
-	RegisteredPhone string     `json:"registered_phone" gorm:"size:100"`                    // 登记手机号(加密存储)
-	RegisterRemark  string     `json:"register_remark" gorm:"size:500"`                     // 登记备注
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	MerchantType      string     `json:"merchant_type" gorm:"size:20;default:'normal';index"` // loyal/quality/potential/normal/low_active/inactive
+	MerchantTypeLabel string     `json:"merchant_type_label" gorm:"size:50"`                  // 分类名称（自定义规则的标签）
+	IsDirect          bool       `json:"is_direct" gorm:"default:true;index"`                 // true=直营 false=团队
+	ActivatedAt       *time.Time `json:"activated_at"`                                        // 激活时间(首次交易时间)
+	RegisteredPhone   string     `json:"registered_phone" gorm:"size:100"`                    // 登记手机号(加密存储)
+	RegisterRemark    string     `json:"register_remark" gorm:"size:500"`                     // 登记备注
+	IDCardStartDate   *time.Time `json:"id_card_start_date" gorm:"type:date"`                 // 法人身份证有效期开始
+	IDCardEndDate     *time.Time `json:"id_card_end_date" gorm:"type:date;index"`             // 法人身份证有效期结束
+	IDCardLongTerm    bool       `json:"id_card_long_term" gorm:"default:false"`              // 法人身份证长期有效
+	KycUpdatedAt      *time.Time `json:"kyc_updated_at"`                                      // 证件有效期最近更新时间
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // 商户类型常量（5档分类）
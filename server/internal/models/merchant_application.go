@@ -0,0 +1,140 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 进件申请状态
+const (
+	ApplicationStatusPending   int16 = 1 // 待提交（新建或提交失败，可修改后重新提交）
+	ApplicationStatusReviewing int16 = 2 // 审核中
+	ApplicationStatusApproved  int16 = 3 // 已通过
+	ApplicationStatusRejected  int16 = 4 // 已驳回（可修改后重新提交）
+	ApplicationStatusCancelled int16 = 5 // 已撤销
+)
+
+// 进件状态来源
+const (
+	ApplicationSourceSubmit   = "submit"   // 提交时通道返回
+	ApplicationSourceQuery    = "query"    // 状态同步任务查询
+	ApplicationSourceCallback = "callback" // merc_income回调
+)
+
+// ApplicationDocument 进件资料图片（引用上传文件）
+type ApplicationDocument struct {
+	Type   string `json:"type"`    // 资料类型，见channel.Doc*常量
+	FileID int64  `json:"file_id"` // uploaded_files.id
+}
+
+// ApplicationDocuments 进件资料图片列表（JSONB）
+type ApplicationDocuments []ApplicationDocument
+
+// Scan 实现sql.Scanner接口
+func (d *ApplicationDocuments) Scan(value interface{}) error {
+	if value == nil {
+		*d = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into ApplicationDocuments", value)
+	}
+	if len(bytes) == 0 {
+		*d = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// Value 实现driver.Valuer接口
+func (d ApplicationDocuments) Value() (driver.Value, error) {
+	if d == nil {
+		return "[]", nil
+	}
+	return json.Marshal(d)
+}
+
+// MerchantApplication 商户进件申请
+// 身份证号、手机号、结算卡号加密存储；费率为小数形式，与商户表一致
+type MerchantApplication struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	ApplyNo     string `json:"apply_no" gorm:"size:50;uniqueIndex"`
+	AgentID     int64  `json:"agent_id" gorm:"not null;index"`
+	ChannelID   int64  `json:"channel_id"`
+	ChannelCode string `json:"channel_code" gorm:"size:32"`
+	TerminalSN  string `json:"terminal_sn" gorm:"size:50"`
+	BrandCode   string `json:"brand_code" gorm:"size:32"`
+
+	LegalName       string `json:"legal_name" gorm:"size:50"`
+	LegalIDCard     string `json:"-" gorm:"size:200"`
+	LegalPhone      string `json:"-" gorm:"size:100"`
+	IDCardStartDate string `json:"id_card_start_date" gorm:"size:20"`
+	IDCardEndDate   string `json:"id_card_end_date" gorm:"size:20"`
+
+	SettleAccountName   string `json:"settle_account_name" gorm:"size:50"`
+	SettleBankName      string `json:"settle_bank_name" gorm:"size:100"`
+	SettleBranchName    string `json:"settle_branch_name" gorm:"size:100"`
+	SettleCardEncrypted string `json:"-" gorm:"size:200"`
+	SettleCardMasked    string `json:"settle_card_masked" gorm:"size:30"`
+
+	MerchantName string               `json:"merchant_name" gorm:"size:100"`
+	MCC          string               `json:"mcc" gorm:"size:10"`
+	DistrictCode string               `json:"district_code" gorm:"size:20"`
+	Address      string               `json:"address" gorm:"size:255"`
+	CreditRate   string               `json:"credit_rate" gorm:"type:decimal(10,4)"`
+	DebitRate    string               `json:"debit_rate" gorm:"type:decimal(10,4)"`
+	Documents    ApplicationDocuments `json:"documents" gorm:"type:jsonb"`
+
+	Status         int16      `json:"status" gorm:"default:1"`
+	ChannelApplyNo string     `json:"channel_apply_no" gorm:"size:64"`
+	MerchantNo     string     `json:"merchant_no" gorm:"size:64"`
+	MerchantID     *int64     `json:"merchant_id"`
+	StatusMessage  string     `json:"status_message" gorm:"size:500"`
+	StatusSource   string     `json:"status_source" gorm:"size:20"`
+	SubmitAttempts int        `json:"submit_attempts" gorm:"default:0"`
+	SubmittedAt    *time.Time `json:"submitted_at"`
+	LastQueriedAt  *time.Time `json:"last_queried_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+
+	CreatedBy     int64     `json:"created_by"`
+	CreatedByName string    `json:"created_by_name" gorm:"size:50"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantApplication) TableName() string {
+	return "merchant_applications"
+}
+
+// IsEditable 是否可修改/重新提交
+func (a *MerchantApplication) IsEditable() bool {
+	return a.Status == ApplicationStatusPending || a.Status == ApplicationStatusRejected
+}
+
+// GetApplicationStatusName 获取进件申请状态名称
+func GetApplicationStatusName(status int16) string {
+	switch status {
+	case ApplicationStatusPending:
+		return "待提交"
+	case ApplicationStatusReviewing:
+		return "审核中"
+	case ApplicationStatusApproved:
+		return "已通过"
+	case ApplicationStatusRejected:
+		return "已驳回"
+	case ApplicationStatusCancelled:
+		return "已撤销"
+	default:
+		return "未知"
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 商户分类指标
+const (
+	MerchantMetricAmount30d     = "amount_30d"      // 30天交易额（分）
+	MerchantMetricAmount60d     = "amount_60d"      // 60天交易额（分）
+	MerchantMetricAmount90d     = "amount_90d"      // 90天交易额（分）
+	MerchantMetricCount30d      = "count_30d"       // 30天交易笔数
+	MerchantMetricCount60d      = "count_60d"       // 60天交易笔数
+	MerchantMetricCount90d      = "count_90d"       // 90天交易笔数
+	MerchantMetricActiveDays30d = "active_days_30d" // 30天有交易天数
+	MerchantMetricActiveDays60d = "active_days_60d" // 60天有交易天数
+	MerchantMetricActiveDays90d = "active_days_90d" // 90天有交易天数
+	MerchantMetricAvgTicket30d  = "avg_ticket_30d"  // 30天笔均（分）
+	MerchantMetricAvgTicket90d  = "avg_ticket_90d"  // 90天笔均（分）
+)
+
+// 商户分类条件运算符
+const (
+	MerchantClassOpGTE = "gte" // 大于等于
+	MerchantClassOpGT  = "gt"  // 大于
+	MerchantClassOpLTE = "lte" // 小于等于
+	MerchantClassOpLT  = "lt"  // 小于
+	MerchantClassOpEQ  = "eq"  // 等于
+)
+
+// MerchantClassCondition 分类条件
+type MerchantClassCondition struct {
+	Metric string `json:"metric"` // 指标
+	Op     string `json:"op"`     // 运算符
+	Value  int64  `json:"value"`  // 阈值（金额单位为分）
+}
+
+// MerchantClassRule 分类规则，所有条件同时满足时商户归为该类
+type MerchantClassRule struct {
+	Code       string                   `json:"code"`       // 分类编码，写入merchants.merchant_type
+	Label      string                   `json:"label"`      // 分类名称
+	Conditions []MerchantClassCondition `json:"conditions"` // 条件（按顺序判断），为空表示兜底
+}
+
+// MerchantClassRules 有序分类规则，首条满足的规则生效
+type MerchantClassRules []MerchantClassRule
+
+// Scan 实现sql.Scanner接口
+func (r *MerchantClassRules) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into MerchantClassRules", value)
+	}
+	if len(bytes) == 0 {
+		*r = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// Value 实现driver.Valuer接口
+func (r MerchantClassRules) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	return json.Marshal(r)
+}
+
+// MerchantClassRuleSet 商户分类规则集
+type MerchantClassRuleSet struct {
+	ID          int64              `json:"id" gorm:"primaryKey"`
+	Name        string             `json:"name" gorm:"size:100;not null"`
+	ChannelID   int64              `json:"channel_id" gorm:"default:0"` // 适用通道，0为全部通道
+	AgentID     int64              `json:"agent_id" gorm:"default:0"`   // 适用一级代理商，0为全部代理商
+	Rules       MerchantClassRules `json:"rules" gorm:"type:jsonb"`
+	Enabled     bool               `json:"enabled" gorm:"default:true"`
+	Description string             `json:"description" gorm:"size:255"`
+	CreatedBy   int64              `json:"created_by"`
+	UpdatedBy   int64              `json:"updated_by"`
+	CreatedAt   time.Time          `json:"created_at" gorm:"default:now()"`
+	UpdatedAt   time.Time          `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantClassRuleSet) TableName() string {
+	return "merchant_class_rule_sets"
+}
+
+// MerchantClassHistory 商户分类变更历史
+type MerchantClassHistory struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	MerchantID int64     `json:"merchant_id" gorm:"not null;index"`
+	RuleSetID  int64     `json:"rule_set_id"` // 0为内置默认规则
+	OldType    string    `json:"old_type" gorm:"size:20"`
+	NewType    string    `json:"new_type" gorm:"size:20;not null"`
+	NewLabel   string    `json:"new_label" gorm:"size:50"`
+	Metrics    string    `json:"metrics" gorm:"type:jsonb"` // 分类时的交易指标快照
+	CreatedAt  time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantClassHistory) TableName() string {
+	return "merchant_class_histories"
+}
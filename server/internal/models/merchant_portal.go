@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// 商户短信验证码场景
+const (
+	MerchantSmsSceneLogin = "login" // 登录
+)
+
+// MerchantSmsCode 商户短信验证码（只存哈希）
+type MerchantSmsCode struct {
+	ID         int64      `json:"id" gorm:"primaryKey"`
+	MerchantID int64      `json:"merchant_id" gorm:"not null;index"`
+	Scene      string     `json:"scene" gorm:"size:20;default:'login'"`
+	CodeHash   string     `json:"-" gorm:"size:64"`
+	Attempts   int        `json:"attempts" gorm:"default:0"` // 校验失败次数
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at"`
+	RequestIP  string     `json:"request_ip" gorm:"size:50"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantSmsCode) TableName() string {
+	return "merchant_sms_codes"
+}
+
+// MerchantRefreshToken 商户刷新令牌（只存哈希）
+type MerchantRefreshToken struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	MerchantID int64     `json:"merchant_id" gorm:"index"`
+	TokenHash  string    `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantRefreshToken) TableName() string {
+	return "merchant_refresh_tokens"
+}
+
+// 结算卡变更申请状态
+const (
+	SettleCardRequestPending   int16 = 1 // 待审核
+	SettleCardRequestApproved  int16 = 2 // 已通过
+	SettleCardRequestRejected  int16 = 3 // 已驳回
+	SettleCardRequestCancelled int16 = 4 // 已撤销
+)
+
+// MerchantSettleCardRequest 商户结算卡变更申请，审核通过后由平台在通道侧变更
+type MerchantSettleCardRequest struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	RequestNo       string     `json:"request_no" gorm:"size:50;uniqueIndex"`
+	MerchantID      int64      `json:"merchant_id" gorm:"not null;index"`
+	MerchantNo      string     `json:"merchant_no" gorm:"size:64"`
+	MerchantName    string     `json:"merchant_name" gorm:"size:100"`
+	AgentID         int64      `json:"agent_id" gorm:"not null;index"`
+	AccountName     string     `json:"account_name" gorm:"size:50"`
+	BankName        string     `json:"bank_name" gorm:"size:100"`
+	BranchName      string     `json:"branch_name" gorm:"size:100"`
+	CardNoEncrypted string     `json:"-" gorm:"size:200"`
+	CardNoMasked    string     `json:"card_no_masked" gorm:"size:30"`
+	Reason          string     `json:"reason" gorm:"size:500"`
+	Status          int16      `json:"status" gorm:"default:1"`
+	ReviewedBy      *int64     `json:"reviewed_by"`
+	ReviewedByName  string     `json:"reviewed_by_name" gorm:"size:50"`
+	ReviewRemark    string     `json:"review_remark" gorm:"size:500"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantSettleCardRequest) TableName() string {
+	return "merchant_settle_card_requests"
+}
+
+// GetSettleCardRequestStatusName 获取结算卡变更申请状态名称
+func GetSettleCardRequestStatusName(status int16) string {
+	switch status {
+	case SettleCardRequestPending:
+		return "待审核"
+	case SettleCardRequestApproved:
+		return "已通过"
+	case SettleCardRequestRejected:
+		return "已驳回"
+	case SettleCardRequestCancelled:
+		return "已撤销"
+	default:
+		return "未知"
+	}
+}
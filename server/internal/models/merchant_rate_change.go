@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// 费率修改申请状态
+const (
+	RateChangeStatusPending    int16 = 1 // 待审批
+	RateChangeStatusRejected   int16 = 2 // 已驳回
+	RateChangeStatusSyncing    int16 = 3 // 同步中（已批准，等待通道同步成功）
+	RateChangeStatusApplied    int16 = 4 // 已生效
+	RateChangeStatusSyncFailed int16 = 5 // 同步失败（自动重试已用完）
+	RateChangeStatusCancelled  int16 = 6 // 已取消
+)
+
+// MerchantRateChangeRequest 商户费率修改申请
+// 费率为小数形式（0.0060表示0.6%），与商户表一致；通道费率配置和代理商政策为百分比形式
+type MerchantRateChangeRequest struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	RequestNo       string     `json:"request_no" gorm:"size:50;uniqueIndex"`
+	MerchantID      int64      `json:"merchant_id" gorm:"not null;index"`
+	MerchantNo      string     `json:"merchant_no" gorm:"size:64"`
+	MerchantName    string     `json:"merchant_name" gorm:"size:100"`
+	ChannelID       int64      `json:"channel_id"`
+	AgentID         int64      `json:"agent_id" gorm:"not null;index"` // 商户所属代理商
+	OldCreditRate   string     `json:"old_credit_rate" gorm:"type:decimal(10,4)"`
+	OldDebitRate    string     `json:"old_debit_rate" gorm:"type:decimal(10,4)"`
+	NewCreditRate   string     `json:"new_credit_rate" gorm:"type:decimal(10,4)"`
+	NewDebitRate    string     `json:"new_debit_rate" gorm:"type:decimal(10,4)"`
+	Reason          string     `json:"reason" gorm:"size:500"`
+	Status          int16      `json:"status" gorm:"default:1"`
+	ApprovalReason  string     `json:"approval_reason" gorm:"size:255"` // 需要审批的原因
+	ApproverAgentID int64      `json:"approver_agent_id"`               // 审批代理商，0表示平台审批
+	RequestedBy     int64      `json:"requested_by"`
+	RequestedByName string     `json:"requested_by_name" gorm:"size:50"`
+	ReviewedBy      *int64     `json:"reviewed_by"`
+	ReviewedByName  string     `json:"reviewed_by_name" gorm:"size:50"`
+	ReviewRemark    string     `json:"review_remark" gorm:"size:500"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	AppliedAt       *time.Time `json:"applied_at"`
+	SyncLogID       int64      `json:"sync_log_id"` // 最近一次通道同步日志
+	SyncMessage     string     `json:"sync_message" gorm:"size:500"`
+	SyncedAt        *time.Time `json:"synced_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantRateChangeRequest) TableName() string {
+	return "merchant_rate_change_requests"
+}
+
+// IsOpen 申请是否未结束（未结束时同一商户不能再提交申请）
+func (r *MerchantRateChangeRequest) IsOpen() bool {
+	return r.Status == RateChangeStatusPending || r.Status == RateChangeStatusSyncing || r.Status == RateChangeStatusSyncFailed
+}
+
+// GetRateChangeStatusName 获取费率修改申请状态名称
+func GetRateChangeStatusName(status int16) string {
+	switch status {
+	case RateChangeStatusPending:
+		return "待审批"
+	case RateChangeStatusRejected:
+		return "已驳回"
+	case RateChangeStatusSyncing:
+		return "同步中"
+	case RateChangeStatusApplied:
+		return "已生效"
+	case RateChangeStatusSyncFailed:
+		return "同步失败"
+	case RateChangeStatusCancelled:
+		return "已取消"
+	default:
+		return "未知"
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// 费率计划类型
+const (
+	RatePlanTypePromo = "promo" // 限时优惠：优惠费率到期自动恢复
+	RatePlanTypeSteps = "steps" // 阶梯调整：按多个时间点依次调整
+)
+
+// 费率计划状态
+const (
+	RatePlanStatusActive    int16 = 1 // 执行中
+	RatePlanStatusCompleted int16 = 2 // 已完成
+	RatePlanStatusCancelled int16 = 3 // 已取消
+)
+
+// 费率计划步骤状态
+const (
+	RatePlanStepPending   int16 = 1 // 待执行
+	RatePlanStepExecuting int16 = 2 // 执行中（已提交费率修改申请，等待通道同步）
+	RatePlanStepApplied   int16 = 3 // 已生效
+	RatePlanStepFailed    int16 = 4 // 执行失败
+	RatePlanStepCancelled int16 = 5 // 已取消
+)
+
+// MerchantRatePlan 商户费率计划
+// 与通道级的RateStagePolicy不同，只作用于单个商户
+type MerchantRatePlan struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	PlanNo          string     `json:"plan_no" gorm:"size:50;uniqueIndex"`
+	PlanType        string     `json:"plan_type" gorm:"size:20"`
+	Name            string     `json:"name" gorm:"size:100"`
+	MerchantID      int64      `json:"merchant_id" gorm:"not null;index"`
+	MerchantNo      string     `json:"merchant_no" gorm:"size:64"`
+	MerchantName    string     `json:"merchant_name" gorm:"size:100"`
+	ChannelID       int64      `json:"channel_id"`
+	AgentID         int64      `json:"agent_id" gorm:"not null;index"`
+	RemindDays      int        `json:"remind_days" gorm:"default:3"`
+	Status          int16      `json:"status" gorm:"default:1"`
+	Remark          string     `json:"remark" gorm:"size:500"`
+	CreatedBy       int64      `json:"created_by"`
+	CreatedByName   string     `json:"created_by_name" gorm:"size:50"`
+	CancelledBy     *int64     `json:"cancelled_by"`
+	CancelledByName string     `json:"cancelled_by_name" gorm:"size:50"`
+	CancelledAt     *time.Time `json:"cancelled_at"`
+	CompletedAt     *time.Time `json:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantRatePlan) TableName() string {
+	return "merchant_rate_plans"
+}
+
+// MerchantRatePlanStep 费率计划步骤，费率为小数形式
+type MerchantRatePlanStep struct {
+	ID              int64      `json:"id" gorm:"primaryKey"`
+	PlanID          int64      `json:"plan_id" gorm:"not null;index"`
+	Seq             int        `json:"seq"`
+	EffectiveAt     time.Time  `json:"effective_at"`
+	CreditRate      string     `json:"credit_rate" gorm:"type:decimal(10,4)"`
+	DebitRate       string     `json:"debit_rate" gorm:"type:decimal(10,4)"`
+	IsRevert        bool       `json:"is_revert"`
+	Status          int16      `json:"status" gorm:"default:1"`
+	ChangeRequestID int64      `json:"change_request_id"`
+	Message         string     `json:"message" gorm:"size:500"`
+	RemindedAt      *time.Time `json:"reminded_at"`
+	ExecutedAt      *time.Time `json:"executed_at"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (MerchantRatePlanStep) TableName() string {
+	return "merchant_rate_plan_steps"
+}
+
+// IsFinished 步骤是否已结束
+func (s *MerchantRatePlanStep) IsFinished() bool {
+	return s.Status == RatePlanStepApplied || s.Status == RatePlanStepFailed || s.Status == RatePlanStepCancelled
+}
+
+// GetRatePlanTypeName 获取费率计划类型名称
+func GetRatePlanTypeName(planType string) string {
+	switch planType {
+	case RatePlanTypePromo:
+		return "限时优惠"
+	case RatePlanTypeSteps:
+		return "阶梯调整"
+	default:
+		return "未知"
+	}
+}
+
+// GetRatePlanStatusName 获取费率计划状态名称
+func GetRatePlanStatusName(status int16) string {
+	switch status {
+	case RatePlanStatusActive:
+		return "执行中"
+	case RatePlanStatusCompleted:
+		return "已完成"
+	case RatePlanStatusCancelled:
+		return "已取消"
+	default:
+		return "未知"
+	}
+}
+
+// GetRatePlanStepStatusName 获取费率计划步骤状态名称
+func GetRatePlanStepStatusName(status int16) string {
+	switch status {
+	case RatePlanStepPending:
+		return "待执行"
+	case RatePlanStepExecuting:
+		return "执行中"
+	case RatePlanStepApplied:
+		return "已生效"
+	case RatePlanStepFailed:
+		return "执行失败"
+	case RatePlanStepCancelled:
+		return "已取消"
+	default:
+		return "未知"
+	}
+}
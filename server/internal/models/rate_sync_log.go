@@ -22,6 +22,7 @@ type RateSyncLog struct {
 	TerminalSN string `gorm:"size:64"`
 	ChannelCode string `gorm:"size:32;not null"`
 	AgentID    int64  `gorm:"not null"`
+	RequestID  int64  `gorm:"not null;default:0"` // 关联费率修改申请ID
 
 	// 原费率
 	OldCreditRate   *float64 `gorm:"type:decimal(10,4)"`
@@ -85,7 +86,7 @@ func (r *RateSyncLog) MarkFailed(errMsg string) {
 	r.RetryCount++
 	r.UpdatedAt = time.Now()
 
-	// 计算下次重试时间（指数退避：1分钟、5分钟、15分钟）
+	// 计算下次重试时间（指数退避：1分钟、5分钟、15分钟、30分钟，之后每小时）
 	if r.RetryCount < r.MaxRetries {
 		var delay time.Duration
 		switch r.RetryCount {
@@ -93,8 +94,12 @@ func (r *RateSyncLog) MarkFailed(errMsg string) {
 			delay = 1 * time.Minute
 		case 2:
 			delay = 5 * time.Minute
-		default:
+		case 3:
 			delay = 15 * time.Minute
+		case 4:
+			delay = 30 * time.Minute
+		default:
+			delay = 60 * time.Minute
 		}
 		nextRetry := time.Now().Add(delay)
 		r.NextRetryAt = &nextRetry
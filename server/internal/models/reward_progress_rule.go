@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// 奖励进度开启时机
+const (
+	RewardProgressStartBind     = TerminalEventBind     // 绑定时开启
+	RewardProgressStartActivate = TerminalEventActivate // 激活时开启
+)
+
+// 换绑规则
+const (
+	RewardRebindRestart  = "restart"  // 解绑终止，重新绑定后按新绑定时间重新开始
+	RewardRebindContinue = "continue" // 解绑不终止，换绑新商户沿用原进度
+	RewardRebindOnce     = "once"     // 解绑终止，终端仅享受一次奖励进度
+)
+
+// RewardProgressConfig 终端奖励进度自动化规则（全局单行）
+type RewardProgressConfig struct {
+	ID            int64     `json:"id" gorm:"primaryKey"`
+	Enabled       bool      `json:"enabled" gorm:"default:true"`                  // 是否自动开启/终止奖励进度
+	StartEvent    string    `json:"start_event" gorm:"size:20;default:'bind'"`    // 开启时机：bind/activate
+	RebindMode    string    `json:"rebind_mode" gorm:"size:20;default:'restart'"` // 换绑规则：restart/continue/once
+	UpdatedBy     int64     `json:"updated_by"`
+	UpdatedByName string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (RewardProgressConfig) TableName() string {
+	return "reward_progress_configs"
+}
+
+// RewardTemplateAssignment 奖励政策模版适用范围
+// 终端按所属代理商逐级向上匹配，agent_id=0为平台默认，channel_id=0为全部通道
+type RewardTemplateAssignment struct {
+	ID         int64     `json:"id" gorm:"primaryKey"`
+	AgentID    int64     `json:"agent_id" gorm:"not null;default:0;index"` // 代理商（含其下级），0为平台默认
+	ChannelID  int64     `json:"channel_id" gorm:"not null;default:0"`     // 通道，0为全部通道
+	TemplateID int64     `json:"template_id" gorm:"not null;index"`        // 奖励政策模版
+	Priority   int       `json:"priority" gorm:"default:0"`                // 优先级，大者优先
+	Enabled    bool      `json:"enabled" gorm:"default:true"`
+	Remark     string    `json:"remark" gorm:"size:255"`
+	CreatedBy  int64     `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (RewardTemplateAssignment) TableName() string {
+	return "reward_template_assignments"
+}
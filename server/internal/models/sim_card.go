@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// 流量卡状态
+const (
+	SimCardStatusStock     int16 = 1 // 库存
+	SimCardStatusInstalled int16 = 2 // 已装机
+	SimCardStatusDisabled  int16 = 3 // 已停用
+)
+
+// 运营商
+const (
+	SimCarrierCMCC  = "cmcc"  // 中国移动
+	SimCarrierCUCC  = "cucc"  // 中国联通
+	SimCarrierCTCC  = "ctcc"  // 中国电信
+	SimCarrierOther = "other" // 其他
+)
+
+// 装卡来源
+const (
+	SimBindSourceManual   = "manual"   // 手动装卡
+	SimBindSourceSwap     = "swap"     // 换卡
+	SimBindSourceCallback = "callback" // 流量费回调登记
+)
+
+// SimCard 流量卡
+type SimCard struct {
+	ID                int64      `json:"id" gorm:"primaryKey"`
+	ICCID             string     `json:"iccid" gorm:"column:iccid;size:22;uniqueIndex"` // 流量卡ICCID
+	MSISDN            string     `json:"msisdn" gorm:"column:msisdn;size:20"`           // 卡号
+	Carrier           string     `json:"carrier" gorm:"size:10;default:'other'"`        // 运营商
+	ChannelID         int64      `json:"channel_id" gorm:"default:0"`                   // 所属通道
+	OwnerAgentID      int64      `json:"owner_agent_id" gorm:"default:0;index"`         // 库存持有代理商，0为平台
+	TerminalID        *int64     `json:"terminal_id"`                                   // 当前装卡终端
+	TerminalSN        string     `json:"terminal_sn" gorm:"size:50;index"`
+	Status            int16      `json:"status" gorm:"default:1"`             // 1库存 2已装机 3已停用
+	ActivatedAt       *time.Time `json:"activated_at"`                        // 开卡/激活日期
+	ExpireAt          *time.Time `json:"expire_at"`                           // 卡有效期
+	RenewalCycleDays  int        `json:"renewal_cycle_days" gorm:"default:0"` // 续费周期（天），0使用全局配置
+	FeeCount          int        `json:"fee_count" gorm:"default:0"`          // 已缴流量费次数
+	LastFeeAt         *time.Time `json:"last_fee_at"`                         // 最近缴费时间
+	NextRenewalAt     *time.Time `json:"next_renewal_at"`                     // 预计下次续费时间
+	RemindedRenewalAt *time.Time `json:"reminded_renewal_at"`                 // 已提醒的续费时间
+	Remark            string     `json:"remark" gorm:"size:255"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (SimCard) TableName() string {
+	return "sim_cards"
+}
+
+// SimCardBinding 流量卡装卡/换卡记录
+type SimCardBinding struct {
+	ID           int64      `json:"id" gorm:"primaryKey"`
+	SimCardID    int64      `json:"sim_card_id" gorm:"not null;index"`
+	ICCID        string     `json:"iccid" gorm:"column:iccid;size:22;not null"`
+	TerminalID   int64      `json:"terminal_id" gorm:"not null"`
+	TerminalSN   string     `json:"terminal_sn" gorm:"size:50;not null;index"`
+	BoundAt      time.Time  `json:"bound_at" gorm:"not null"`       // 装卡时间
+	UnboundAt    *time.Time `json:"unbound_at"`                     // 拆卡时间，为空表示当前装卡
+	Source       string     `json:"source" gorm:"size:20;not null"` // manual/swap/callback
+	OperatorID   int64      `json:"operator_id"`
+	OperatorName string     `json:"operator_name" gorm:"size:50"`
+	Remark       string     `json:"remark" gorm:"size:255"`
+}
+
+// TableName 表名
+func (SimCardBinding) TableName() string {
+	return "sim_card_bindings"
+}
+
+// SimCardConfig 流量卡续费预测及提醒配置（全局单行）
+type SimCardConfig struct {
+	ID               int64     `json:"id" gorm:"primaryKey"`
+	FirstFeeDays     int       `json:"first_fee_days" gorm:"default:180"`     // 首次扣费距激活天数
+	RenewalCycleDays int       `json:"renewal_cycle_days" gorm:"default:365"` // 续费周期（天）
+	RemindEnabled    bool      `json:"remind_enabled" gorm:"default:true"`    // 是否提醒代理商
+	RemindDaysBefore int       `json:"remind_days_before" gorm:"default:15"`  // 提前提醒天数
+	UpdatedBy        int64     `json:"updated_by"`
+	UpdatedByName    string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (SimCardConfig) TableName() string {
+	return "sim_card_configs"
+}
+
+// GetSimCardStatusName 获取流量卡状态名称
+func GetSimCardStatusName(status int16) string {
+	switch status {
+	case SimCardStatusStock:
+		return "库存"
+	case SimCardStatusInstalled:
+		return "已装机"
+	case SimCardStatusDisabled:
+		return "已停用"
+	default:
+		return "未知"
+	}
+}
+
+// GetSimCarrierName 获取运营商名称
+func GetSimCarrierName(carrier string) string {
+	switch carrier {
+	case SimCarrierCMCC:
+		return "中国移动"
+	case SimCarrierCUCC:
+		return "中国联通"
+	case SimCarrierCTCC:
+		return "中国电信"
+	default:
+		return "其他"
+	}
+}
@@ -69,6 +69,8 @@ type SimCashbackRecord struct {
 	UpperCashback  int64      `json:"upper_cashback" gorm:"not null"`            // 上级应返金额（分）
 	ActualCashback int64      `json:"actual_cashback" gorm:"not null"`           // 实际返现金额（级差）（分）
 	SourceAgentID  int64      `json:"source_agent_id"`                           // 下级代理商ID（级差来源）
+	SimCardID      *int64     `json:"sim_card_id"`                               // 归属的流量卡
+	ICCID          string     `json:"iccid" gorm:"column:iccid;size:22"`         // 流量卡ICCID
 	WalletType     int16      `json:"wallet_type" gorm:"default:1"`              // 钱包类型
 	WalletStatus   int16      `json:"wallet_status" gorm:"default:0"`            // 0:待入账 1:已入账
 	CreatedAt      time.Time  `json:"created_at" gorm:"default:now()"`
@@ -52,7 +52,7 @@ type TerminalDistribute struct {
 	DeductionType    int16      `json:"deduction_type" gorm:"not null"`            // 1:一次性付款 2:分期代扣
 	DeductionSource  int16      `json:"deduction_source" gorm:"default:3"`         // 扣款来源：1-分润 2-服务费 3-两者
 	DeductionPlanID  *int64     `json:"deduction_plan_id"`                         // 关联代扣计划ID
-	GoodsDeductionID *int64     `json:"goods_deduction_id"`                        // 关联货款代扣ID
+	GoodsDeductionID *int64     `json:"goods_deduction_id"`                        // 关联货款代扣ID（并入代扣计划前的旧数据）
 	ChainID          *int64     `json:"chain_id"`                                  // 关联代扣链ID（跨级时）
 	Status           int16      `json:"status" gorm:"default:1"`                   // 1:待确认 2:已确认 3:已拒绝 4:已取消
 	Source           int16      `json:"source" gorm:"not null"`                    // 1:APP 2:PC
@@ -104,6 +104,14 @@ type TerminalRecall struct {
 	ConfirmedBy    *int64     `json:"confirmed_by"`
 	CreatedAt      time.Time  `json:"created_at" gorm:"default:now()"`
 	ConfirmedAt    *time.Time `json:"confirmed_at"`
+
+	// 回拨结算汇总
+	SettlementStatus     int16      `json:"settlement_status" gorm:"default:0"`         // 0:未结算 1:已结算 2:部分完成 3:无需结算
+	PlanReducedAmount    int64      `json:"plan_reduced_amount" gorm:"default:0"`       // 冲减未扣货款（分）
+	GoodsRefundAmount    int64      `json:"goods_refund_amount" gorm:"default:0"`       // 退还已扣货款（分）
+	RewardClawbackAmount int64      `json:"reward_clawback_amount" gorm:"default:0"`    // 追回奖励/返现（分）
+	SettlementSummary    string     `json:"settlement_summary" gorm:"size:500"`         // 结算摘要
+	SettledAt            *time.Time `json:"settled_at"`
 }
 
 func (TerminalRecall) TableName() string {
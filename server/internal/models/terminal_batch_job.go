@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// 终端批量任务类型
+const (
+	TerminalBatchJobTypeImport     = "import"     // 批量入库
+	TerminalBatchJobTypeDistribute = "distribute" // 批量下发
+	TerminalBatchJobTypeRecall     = "recall"     // 批量回拨
+)
+
+// 终端批量任务状态
+const (
+	TerminalBatchJobStatusPending    int16 = 1 // 待处理
+	TerminalBatchJobStatusProcessing int16 = 2 // 处理中
+	TerminalBatchJobStatusCompleted  int16 = 3 // 已完成
+	TerminalBatchJobStatusFailed     int16 = 4 // 失败
+)
+
+// TerminalBatchJob 终端批量入库/下发/回拨任务
+// 上传的XLSX/CSV在创建任务时解析并展开号段，逐行校验结果写入 Errors，可导出为错误报告
+type TerminalBatchJob struct {
+	ID               int64      `json:"id" gorm:"primaryKey"`
+	JobNo            string     `json:"job_no" gorm:"size:64;uniqueIndex"` // 任务编号
+	JobType          string     `json:"job_type" gorm:"size:20"`           // import/distribute/recall
+	Status           int16      `json:"status" gorm:"default:1"`           // 1待处理 2处理中 3已完成 4失败
+	FileName         string     `json:"file_name" gorm:"size:255"`         // 上传文件名
+	AgentID          int64      `json:"agent_id" gorm:"index"`             // 操作代理商
+	CreatedBy        int64      `json:"created_by"`                        // 操作人
+	Source           int16      `json:"source"`                            // 1:APP 2:PC
+	ChannelID        int64      `json:"channel_id"`                        // 通道
+	ChannelCode      string     `json:"channel_code" gorm:"size:32"`
+	BrandCode        string     `json:"brand_code" gorm:"size:32"`
+	ModelCode        string     `json:"model_code" gorm:"size:32"`
+	ToAgentID        int64      `json:"to_agent_id"`                   // 下发/回拨接收方
+	GoodsPrice       int64      `json:"goods_price"`                   // 下发货款（分）
+	DeductionType    int16      `json:"deduction_type"`                // 下发代扣类型
+	DeductionPeriods int        `json:"deduction_periods"`             // 分期期数
+	DeductionSource  int16      `json:"deduction_source"`              // 货款代扣来源
+	Remark           string     `json:"remark" gorm:"size:255"`        // 备注
+	Rows             string     `json:"-" gorm:"type:jsonb"`           // 展开后的待处理行JSON
+	TotalCount       int        `json:"total_count"`                   // 总行数（按SN计）
+	SuccessCount     int        `json:"success_count"`                 // 成功数
+	FailedCount      int        `json:"failed_count"`                  // 失败数
+	Errors           string     `json:"-" gorm:"type:jsonb"`           // 逐行错误JSON
+	ResultNos        string     `json:"result_nos" gorm:"type:text"`   // 生成的入库批次号（逗号分隔）
+	ErrorMessage     string     `json:"error_message" gorm:"size:500"` // 任务级错误
+	StartedAt        *time.Time `json:"started_at"`
+	FinishedAt       *time.Time `json:"finished_at"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"default:now()"`
+
+	// 关联数据（非数据库字段）
+	RowErrors []*TerminalBatchRowError `json:"row_errors,omitempty" gorm:"-"` // 逐行错误
+}
+
+func (TerminalBatchJob) TableName() string {
+	return "terminal_batch_jobs"
+}
+
+// TerminalBatchRow 展开后的单个SN行
+type TerminalBatchRow struct {
+	Row         int    `json:"row"`                    // 表格行号
+	SN          string `json:"sn"`                     // 终端SN
+	ChannelCode string `json:"channel_code,omitempty"` // 行内通道编码（可选）
+	BrandCode   string `json:"brand_code,omitempty"`   // 行内品牌编码（可选）
+	ModelCode   string `json:"model_code,omitempty"`   // 行内型号编码（可选）
+}
+
+// TerminalBatchRowError 行级错误
+type TerminalBatchRowError struct {
+	Row    int    `json:"row"`    // 表格行号
+	SN     string `json:"sn"`     // 终端SN
+	Reason string `json:"reason"` // 错误原因
+}
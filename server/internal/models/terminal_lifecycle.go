@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// 终端生命周期事件
+const (
+	TerminalEventImport            = "import"             // 入库
+	TerminalEventDistributeConfirm = "distribute_confirm" // 下发确认
+	TerminalEventBind              = "bind"               // 绑定回调
+	TerminalEventActivate          = "activate"           // 激活
+	TerminalEventUnbind            = "unbind"             // 解绑回调
+	TerminalEventRecallConfirm     = "recall_confirm"     // 回拨确认
+)
+
+// 终端状态流转操作方类型
+const (
+	TerminalActorUser    = "user"    // 后台/APP用户
+	TerminalActorChannel = "channel" // 支付通道回调
+	TerminalActorSystem  = "system"  // 系统任务
+)
+
+// 终端状态流转来源单据类型
+const (
+	TerminalSourceImport     = "terminal_import"     // 入库批次
+	TerminalSourceDistribute = "terminal_distribute" // 下发记录
+	TerminalSourceRecall     = "terminal_recall"     // 回拨记录
+	TerminalSourceCallback   = "callback"            // 通道回调
+)
+
+// TerminalStockStatuses 代理商库存中的终端状态（未绑定商户）
+var TerminalStockStatuses = []int16{TerminalStatusAllocated, TerminalStatusUnbound, TerminalStatusRecycled}
+
+// TerminalStatusHistory 终端状态流转记录
+type TerminalStatusHistory struct {
+	ID          int64     `json:"id" gorm:"primaryKey"`
+	TerminalID  int64     `json:"terminal_id" gorm:"not null;index"`
+	TerminalSN  string    `json:"terminal_sn" gorm:"size:50;not null;index"` // 终端SN
+	Event       string    `json:"event" gorm:"size:32;not null"`             // 事件
+	FromStatus  int16     `json:"from_status"`                               // 流转前状态（入库为0）
+	ToStatus    int16     `json:"to_status"`                                 // 流转后状态
+	FromOwnerID int64     `json:"from_owner_id"`                             // 流转前所属代理商
+	ToOwnerID   int64     `json:"to_owner_id"`                               // 流转后所属代理商
+	MerchantNo  string    `json:"merchant_no" gorm:"size:64"`                // 绑定/解绑的商户号
+	ActorType   string    `json:"actor_type" gorm:"size:20;not null"`        // 操作方类型
+	ActorID     int64     `json:"actor_id"`                                  // 操作人ID
+	ActorName   string    `json:"actor_name" gorm:"size:50"`                 // 操作人名称/通道编码
+	SourceType  string    `json:"source_type" gorm:"size:32"`                // 来源单据类型
+	SourceID    int64     `json:"source_id"`                                 // 来源单据ID
+	SourceNo    string    `json:"source_no" gorm:"size:64"`                  // 来源单据编号
+	Remark      string    `json:"remark" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (TerminalStatusHistory) TableName() string {
+	return "terminal_status_history"
+}
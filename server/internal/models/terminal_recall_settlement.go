@@ -0,0 +1,129 @@
+package models
+
+import "time"
+
+// TerminalRecallSettlementConfig 终端回拨结算规则（全局单行）
+type TerminalRecallSettlementConfig struct {
+	ID                 int64     `json:"id" gorm:"primaryKey"`
+	Enabled            bool      `json:"enabled" gorm:"default:true"`              // 是否开启回拨结算
+	ReduceOutstanding  bool      `json:"reduce_outstanding" gorm:"default:true"`   // 冲减未扣货款（代扣计划剩余金额）
+	RefundPaid         bool      `json:"refund_paid" gorm:"default:true"`          // 退还已扣货款给回拨方
+	RefundRate         int       `json:"refund_rate" gorm:"default:100"`           // 已扣货款退还比例（%）
+	ClawbackActivation bool      `json:"clawback_activation" gorm:"default:false"` // 追回激活奖励
+	ClawbackDeposit    bool      `json:"clawback_deposit" gorm:"default:false"`    // 追回押金返现
+	UpdatedBy          int64     `json:"updated_by"`
+	UpdatedByName      string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (TerminalRecallSettlementConfig) TableName() string {
+	return "terminal_recall_settlement_configs"
+}
+
+// 回拨结算状态
+const (
+	TerminalRecallSettlementPending     int16 = 0 // 未结算
+	TerminalRecallSettlementDone        int16 = 1 // 已结算
+	TerminalRecallSettlementPartial     int16 = 2 // 部分完成（存在跳过/失败/待追回的明细）
+	TerminalRecallSettlementNotRequired int16 = 3 // 无需结算
+)
+
+// GetTerminalRecallSettlementStatusName 获取回拨结算状态名称
+func GetTerminalRecallSettlementStatusName(status int16) string {
+	switch status {
+	case TerminalRecallSettlementPending:
+		return "未结算"
+	case TerminalRecallSettlementDone:
+		return "已结算"
+	case TerminalRecallSettlementPartial:
+		return "部分完成"
+	case TerminalRecallSettlementNotRequired:
+		return "无需结算"
+	default:
+		return "未知"
+	}
+}
+
+// TerminalRecallSettlementItem 终端回拨结算明细
+type TerminalRecallSettlementItem struct {
+	ID             int64     `json:"id" gorm:"primaryKey"`
+	RecallID       int64     `json:"recall_id" gorm:"not null;index"`
+	RecallNo       string    `json:"recall_no" gorm:"size:64"`
+	TerminalSN     string    `json:"terminal_sn" gorm:"size:50"`
+	ItemType       string    `json:"item_type" gorm:"size:32"` // plan_reduce/goods_refund/activation_clawback/deposit_clawback
+	RefType        string    `json:"ref_type" gorm:"size:32"`  // deduction_plan/activation_reward/deposit_cashback
+	RefID          int64     `json:"ref_id"`                   // 关联对象ID
+	AgentID        int64     `json:"agent_id"`                 // 出资方（退款付款方/被追回方/被扣款方）
+	CounterpartyID int64     `json:"counterparty_id"`          // 收款方（退款收款方，追回为0）
+	Amount         int64     `json:"amount"`                   // 应处理金额（分）
+	SettledAmount  int64     `json:"settled_amount"`           // 实际处理金额（分）
+	Status         int16     `json:"status" gorm:"default:1"`  // 1:已完成 2:部分完成 3:已跳过 4:失败
+	RefundPlanID   *int64    `json:"refund_plan_id"`           // 余额不足时生成的退款代扣计划
+	Remark         string    `json:"remark" gorm:"size:255"`
+	CreatedAt      time.Time `json:"created_at" gorm:"default:now()"`
+
+	ItemTypeName string `json:"item_type_name" gorm:"-"`
+	StatusName   string `json:"status_name" gorm:"-"`
+}
+
+// TableName 表名
+func (TerminalRecallSettlementItem) TableName() string {
+	return "terminal_recall_settlement_items"
+}
+
+// 回拨结算明细类型
+const (
+	RecallSettlementItemPlanReduce         = "plan_reduce"         // 冲减未扣货款
+	RecallSettlementItemGoodsRefund        = "goods_refund"        // 退还已扣货款
+	RecallSettlementItemActivationClawback = "activation_clawback" // 追回激活奖励
+	RecallSettlementItemDepositClawback    = "deposit_clawback"    // 追回押金返现
+)
+
+// GetRecallSettlementItemTypeName 获取结算明细类型名称
+func GetRecallSettlementItemTypeName(itemType string) string {
+	switch itemType {
+	case RecallSettlementItemPlanReduce:
+		return "冲减未扣货款"
+	case RecallSettlementItemGoodsRefund:
+		return "退还已扣货款"
+	case RecallSettlementItemActivationClawback:
+		return "追回激活奖励"
+	case RecallSettlementItemDepositClawback:
+		return "追回押金返现"
+	default:
+		return "未知"
+	}
+}
+
+// 回拨结算明细状态
+const (
+	RecallSettlementItemStatusDone    int16 = 1 // 已完成
+	RecallSettlementItemStatusPartial int16 = 2 // 部分完成
+	RecallSettlementItemStatusSkipped int16 = 3 // 已跳过
+	RecallSettlementItemStatusFailed  int16 = 4 // 失败
+)
+
+// GetRecallSettlementItemStatusName 获取结算明细状态名称
+func GetRecallSettlementItemStatusName(status int16) string {
+	switch status {
+	case RecallSettlementItemStatusDone:
+		return "已完成"
+	case RecallSettlementItemStatusPartial:
+		return "部分完成"
+	case RecallSettlementItemStatusSkipped:
+		return "已跳过"
+	case RecallSettlementItemStatusFailed:
+		return "失败"
+	default:
+		return "未知"
+	}
+}
+
+// 激活奖励/押金返现记录入账状态
+const (
+	RewardWalletStatusPending         int16 = 0 // 待入账
+	RewardWalletStatusCredited        int16 = 1 // 已入账
+	RewardWalletStatusClawedBack      int16 = 2 // 已追回（终端回拨）
+	RewardWalletStatusClawbackPartial int16 = 3 // 部分追回，钱包余额不足待继续追回（终端回拨）
+)
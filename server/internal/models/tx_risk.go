@@ -0,0 +1,211 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 交易风控规则
+const (
+	TxRiskRuleSameCard  = "same_card"  // 同卡频繁交易
+	TxRiskRuleNearLimit = "near_limit" // 临界整数金额
+	TxRiskRuleNight     = "night"      // 夜间集中交易
+	TxRiskRuleSpike     = "spike"      // 金额突增
+	TxRiskRuleSharedID  = "shared_id"  // 法人身份证共用
+)
+
+// 风控事件状态
+const (
+	TxRiskStatusPending   int16 = 1 // 待审核
+	TxRiskStatusConfirmed int16 = 2 // 确认风险
+	TxRiskStatusDismissed int16 = 3 // 已排除
+)
+
+// Int64List 整数列表（JSONB）
+type Int64List []int64
+
+// Scan 实现sql.Scanner接口
+func (l *Int64List) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into Int64List", value)
+	}
+	if len(bytes) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+// Value 实现driver.Valuer接口
+func (l Int64List) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	return json.Marshal(l)
+}
+
+// TxRiskConfig 交易风控配置（全局单行），金额单位均为分
+type TxRiskConfig struct {
+	ID          int64 `json:"id" gorm:"primaryKey"`
+	Enabled     bool  `json:"enabled" gorm:"default:true"`
+	EventScore  int   `json:"event_score" gorm:"default:20"`     // 命中规则总分达到该值时生成风控事件
+	HoldEnabled bool  `json:"hold_enabled" gorm:"default:false"` // 是否冻结高分事件关联的分润
+	HoldScore   int   `json:"hold_score" gorm:"default:60"`      // 事件总分达到该值时冻结分润
+
+	SameCardEnabled bool `json:"same_card_enabled" gorm:"default:true"`
+	SameCardHours   int  `json:"same_card_hours" gorm:"default:24"` // 统计窗口（小时）
+	SameCardCount   int  `json:"same_card_count" gorm:"default:5"`  // 窗口内同卡交易笔数阈值
+	SameCardScore   int  `json:"same_card_score" gorm:"default:30"`
+
+	NearLimitEnabled   bool      `json:"near_limit_enabled" gorm:"default:true"`
+	NearLimitAmounts   Int64List `json:"near_limit_amounts" gorm:"type:jsonb"`       // 限额列表
+	NearLimitMargin    int64     `json:"near_limit_margin" gorm:"default:50000"`     // 低于限额不超过该值
+	NearLimitRoundUnit int64     `json:"near_limit_round_unit" gorm:"default:10000"` // 金额须为该值整数倍，0不要求
+	NearLimitScore     int       `json:"near_limit_score" gorm:"default:20"`
+
+	NightEnabled   bool `json:"night_enabled" gorm:"default:true"`
+	NightStartHour int  `json:"night_start_hour" gorm:"default:0"` // 夜间开始小时（含）
+	NightEndHour   int  `json:"night_end_hour" gorm:"default:5"`   // 夜间结束小时（不含），小于开始小时表示跨零点
+	NightCount     int  `json:"night_count" gorm:"default:3"`      // 同一夜间时段交易笔数阈值
+	NightScore     int  `json:"night_score" gorm:"default:20"`
+
+	SpikeEnabled     bool  `json:"spike_enabled" gorm:"default:true"`
+	SpikeHistoryDays int   `json:"spike_history_days" gorm:"default:30"`   // 历史笔均统计天数
+	SpikeMinHistory  int   `json:"spike_min_history" gorm:"default:10"`    // 历史交易笔数不足时不判断
+	SpikeMultiple    int   `json:"spike_multiple" gorm:"default:5"`        // 超过历史笔均的倍数
+	SpikeMinAmount   int64 `json:"spike_min_amount" gorm:"default:500000"` // 单笔低于该值不判断
+	SpikeScore       int   `json:"spike_score" gorm:"default:25"`
+
+	SharedIDEnabled bool `json:"shared_id_enabled" gorm:"default:true"`
+	SharedIDCount   int  `json:"shared_id_count" gorm:"default:3"` // 同一法人身份证名下正常商户数阈值
+	SharedIDScore   int  `json:"shared_id_score" gorm:"default:30"`
+
+	UpdatedBy     int64     `json:"updated_by"`
+	UpdatedByName string    `json:"updated_by_name" gorm:"size:50"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (TxRiskConfig) TableName() string {
+	return "tx_risk_configs"
+}
+
+// TxRiskHit 命中规则
+type TxRiskHit struct {
+	Rule   string `json:"rule"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// TxRiskHits 命中规则列表（JSONB）
+type TxRiskHits []TxRiskHit
+
+// Scan 实现sql.Scanner接口
+func (h *TxRiskHits) Scan(value interface{}) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan type %T into TxRiskHits", value)
+	}
+	if len(bytes) == 0 {
+		*h = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, h)
+}
+
+// Value 实现driver.Valuer接口
+func (h TxRiskHits) Value() (driver.Value, error) {
+	if h == nil {
+		return "[]", nil
+	}
+	return json.Marshal(h)
+}
+
+// TxRiskEvent 交易风控事件
+type TxRiskEvent struct {
+	ID             int64      `json:"id" gorm:"primaryKey"`
+	EventNo        string     `json:"event_no" gorm:"size:50;uniqueIndex"`
+	TransactionID  int64      `json:"transaction_id" gorm:"uniqueIndex"`
+	OrderNo        string     `json:"order_no" gorm:"size:64"`
+	ChannelID      int64      `json:"channel_id"`
+	MerchantID     int64      `json:"merchant_id" gorm:"index"`
+	MerchantNo     string     `json:"merchant_no" gorm:"size:64"`
+	MerchantName   string     `json:"merchant_name" gorm:"size:100"`
+	AgentID        int64      `json:"agent_id" gorm:"index"`
+	TerminalSN     string     `json:"terminal_sn" gorm:"size:50"`
+	CardNo         string     `json:"card_no" gorm:"size:50"`
+	Amount         int64      `json:"amount"` // 交易金额（分）
+	TradeTime      time.Time  `json:"trade_time"`
+	Score          int        `json:"score"` // 命中规则总分
+	Hits           TxRiskHits `json:"hits" gorm:"type:jsonb"`
+	Status         int16      `json:"status" gorm:"default:1"`      // 1待审核 2确认风险 3已排除
+	Held           bool       `json:"held" gorm:"default:false"`    // 是否已冻结关联分润
+	HeldAmount     int64      `json:"held_amount" gorm:"default:0"` // 冻结分润金额（分）
+	ReviewRemark   string     `json:"review_remark" gorm:"size:500"`
+	ReviewedBy     *int64     `json:"reviewed_by"`
+	ReviewedByName string     `json:"reviewed_by_name" gorm:"size:50"`
+	ReviewedAt     *time.Time `json:"reviewed_at"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (TxRiskEvent) TableName() string {
+	return "tx_risk_events"
+}
+
+// GetTxRiskRuleName 获取风控规则名称
+func GetTxRiskRuleName(rule string) string {
+	switch rule {
+	case TxRiskRuleSameCard:
+		return "同卡频繁交易"
+	case TxRiskRuleNearLimit:
+		return "临界整数金额"
+	case TxRiskRuleNight:
+		return "夜间集中交易"
+	case TxRiskRuleSpike:
+		return "金额突增"
+	case TxRiskRuleSharedID:
+		return "法人身份证共用"
+	default:
+		return "未知"
+	}
+}
+
+// GetTxRiskStatusName 获取风控事件状态名称
+func GetTxRiskStatusName(status int16) string {
+	switch status {
+	case TxRiskStatusPending:
+		return "待审核"
+	case TxRiskStatusConfirmed:
+		return "确认风险"
+	case TxRiskStatusDismissed:
+		return "已排除"
+	default:
+		return "未知"
+	}
+}
@@ -7,8 +7,9 @@ import (
 // 调账状态
 const (
 	AdjustmentStatusEffective int16 = 1 // 已生效
-	AdjustmentStatusPending   int16 = 2 // 待审批（预留）
-	AdjustmentStatusRejected  int16 = 3 // 已驳回（预留）
+	AdjustmentStatusPending   int16 = 2 // 待审批
+	AdjustmentStatusRejected  int16 = 3 // 已驳回
+	AdjustmentStatusReversed  int16 = 4 // 已冲正
 )
 
 // WalletAdjustment 钱包调账记录
@@ -27,11 +28,17 @@ type WalletAdjustment struct {
 	OperatorName  string `json:"operator_name" gorm:"size:50"`             // 操作人名称
 
 	// 状态
-	Status       int16      `json:"status" gorm:"default:1"`      // 1已生效 2待审批 3已驳回
-	ApprovedBy   *int64     `json:"approved_by"`                  // 审批人ID
-	ApprovedAt   *time.Time `json:"approved_at"`                  // 审批时间
+	Status       int16      `json:"status" gorm:"default:1"`       // 1已生效 2待审批 3已驳回 4已冲正
+	ApprovedBy   *int64     `json:"approved_by"`                   // 审批人ID
+	ApproverName string     `json:"approver_name" gorm:"size:50"`  // 审批人名称
+	ApprovedAt   *time.Time `json:"approved_at"`                   // 审批时间
 	RejectReason string     `json:"reject_reason" gorm:"size:500"` // 驳回原因
 
+	// 冲正
+	ReversalOfID *int64     `json:"reversal_of_id"` // 冲正单对应的原调账ID
+	ReversedByID *int64     `json:"reversed_by_id"` // 冲正该调账的冲正单ID
+	ReversedAt   *time.Time `json:"reversed_at"`    // 冲正时间
+
 	// 关联
 	WalletLogID *int64 `json:"wallet_log_id"` // 关联的钱包流水ID
 
@@ -53,11 +60,29 @@ func GetAdjustmentStatusName(status int16) string {
 		return "待审批"
 	case AdjustmentStatusRejected:
 		return "已驳回"
+	case AdjustmentStatusReversed:
+		return "已冲正"
 	default:
 		return "未知"
 	}
 }
 
+// WalletAdjustmentApprovalThreshold 调账审批金额配置
+// WalletType=0 为通用配置，特定钱包类型配置优先
+type WalletAdjustmentApprovalThreshold struct {
+	ID              int64     `json:"id" gorm:"primaryKey"`
+	WalletType      int16     `json:"wallet_type" gorm:"uniqueIndex;default:0"`
+	ThresholdAmount int64     `json:"threshold_amount"` // 调账金额绝对值达到该值需审批(分)
+	UpdatedBy       *int64    `json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (WalletAdjustmentApprovalThreshold) TableName() string {
+	return "wallet_adjustment_approval_thresholds"
+}
+
 // GetAdjustmentTypeName 获取调账类型名称（充入/扣减）
 func GetAdjustmentTypeName(amount int64) string {
 	if amount >= 0 {
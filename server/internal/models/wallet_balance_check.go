@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+)
+
+// 余额检查触发方式
+const (
+	BalanceCheckTriggerScheduled int16 = 1 // 定时任务
+	BalanceCheckTriggerManual    int16 = 2 // 手动触发
+)
+
+// 余额检查状态
+const (
+	BalanceCheckStatusConsistent int16 = 1 // 一致
+	BalanceCheckStatusPending    int16 = 2 // 待处理
+	BalanceCheckStatusAccepted   int16 = 3 // 已采纳
+	BalanceCheckStatusDismissed  int16 = 4 // 已忽略
+	BalanceCheckStatusSuperseded int16 = 5 // 已失效（被新检查取代）
+)
+
+// 分歧类型
+const (
+	DivergenceMissingLog     = "missing_log"     // 业务记录缺少钱包流水
+	DivergenceBalanceChain   = "balance_chain"   // 流水变动前余额与回放余额不一致
+	DivergenceAmountMismatch = "amount_mismatch" // 流水前后余额差与金额不符
+	DivergenceFinalBalance   = "final_balance"   // 钱包余额与回放结果不一致
+)
+
+// WalletBalanceCheck 钱包余额一致性检查记录
+// 回放从上一条已关闭检查（一致/已采纳/已忽略）开始，期初余额取该次检查时的钱包余额
+type WalletBalanceCheck struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	CheckNo     string `json:"check_no" gorm:"size:50;uniqueIndex"` // 检查单号
+	WalletID    int64  `json:"wallet_id" gorm:"index"`
+	AgentID     int64  `json:"agent_id" gorm:"index"`
+	WalletType  int16  `json:"wallet_type"`
+	ChannelID   int64  `json:"channel_id" gorm:"default:0"`
+	TriggerType int16  `json:"trigger_type" gorm:"default:1"` // 1定时任务 2手动
+
+	// 回放结果
+	BaselineAt      *time.Time `json:"baseline_at"`      // 回放起点，空表示从头回放
+	OpeningBalance  int64      `json:"opening_balance"`  // 回放期初余额(分)
+	CurrentBalance  int64      `json:"current_balance"`  // 检查时钱包余额(分)
+	ExpectedBalance int64      `json:"expected_balance"` // 回放余额(分)
+	Difference      int64      `json:"difference"`       // 回放余额 - 钱包余额(分)
+	EntryCount      int        `json:"entry_count"`      // 回放条目数
+
+	// 首个分歧条目
+	DivergenceKind   string     `json:"divergence_kind" gorm:"size:30"`
+	DivergentSource  string     `json:"divergent_source" gorm:"size:30"`
+	DivergentRefID   *int64     `json:"divergent_ref_id"`
+	DivergentLogID   *int64     `json:"divergent_log_id"`
+	DivergentAt      *time.Time `json:"divergent_at"`
+	DivergenceDetail string     `json:"divergence_detail" gorm:"size:500"`
+
+	// 修正建议
+	ProposedAmount int64      `json:"proposed_amount"`                // 建议调账金额(分)，0表示无需调账
+	Status         int16      `json:"status" gorm:"default:1"`        // 1一致 2待处理 3已采纳 4已忽略 5已失效
+	AdjustmentID   *int64     `json:"adjustment_id"`                  // 采纳后生成的调账ID
+	HandledBy      *int64     `json:"handled_by"`                     // 处理人ID
+	HandledByName  string     `json:"handled_by_name" gorm:"size:50"` // 处理人名称
+	HandledAt      *time.Time `json:"handled_at"`
+	HandleRemark   string     `json:"handle_remark" gorm:"size:500"`
+
+	CheckedAt time.Time `json:"checked_at"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (WalletBalanceCheck) TableName() string {
+	return "wallet_balance_checks"
+}
+
+// GetBalanceCheckStatusName 获取检查状态名称
+func GetBalanceCheckStatusName(status int16) string {
+	switch status {
+	case BalanceCheckStatusConsistent:
+		return "一致"
+	case BalanceCheckStatusPending:
+		return "待处理"
+	case BalanceCheckStatusAccepted:
+		return "已采纳"
+	case BalanceCheckStatusDismissed:
+		return "已忽略"
+	case BalanceCheckStatusSuperseded:
+		return "已失效"
+	default:
+		return "未知"
+	}
+}
+
+// GetDivergenceKindName 获取分歧类型名称
+func GetDivergenceKindName(kind string) string {
+	switch kind {
+	case DivergenceMissingLog:
+		return "缺少钱包流水"
+	case DivergenceBalanceChain:
+		return "流水余额断链"
+	case DivergenceAmountMismatch:
+		return "流水金额不符"
+	case DivergenceFinalBalance:
+		return "钱包余额不符"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+)
+
+// 划转方向
+const (
+	TransferDirectionDown int16 = 1 // 上级转下级
+	TransferDirectionUp   int16 = 2 // 下级转上级
+)
+
+// 划转状态
+const (
+	TransferStatusPending   int16 = 1 // 待确认
+	TransferStatusCompleted int16 = 2 // 已完成
+	TransferStatusRejected  int16 = 3 // 已拒绝
+	TransferStatusCancelled int16 = 4 // 已取消
+	TransferStatusExpired   int16 = 5 // 已过期
+)
+
+// WalletTransfer 代理商钱包划转记录
+// 待确认期间转出金额冻结在转出钱包中，确认后双边同时记账
+type WalletTransfer struct {
+	ID          int64  `json:"id" gorm:"primaryKey"`
+	TransferNo  string `json:"transfer_no" gorm:"size:50;uniqueIndex"` // 划转单号
+	FromAgentID int64  `json:"from_agent_id" gorm:"index"`             // 转出代理商ID
+	ToAgentID   int64  `json:"to_agent_id" gorm:"index"`               // 转入代理商ID
+	Direction   int16  `json:"direction"`                              // 1上级转下级 2下级转上级
+
+	// 钱包
+	FromWalletID   int64 `json:"from_wallet_id"`
+	FromWalletType int16 `json:"from_wallet_type"`
+	FromChannelID  int64 `json:"from_channel_id" gorm:"default:0"`
+	ToWalletID     int64 `json:"to_wallet_id"`
+	ToWalletType   int16 `json:"to_wallet_type"`
+	ToChannelID    int64 `json:"to_channel_id" gorm:"default:0"`
+
+	Amount      int64 `json:"amount"`                            // 划转金额(分)
+	NeedConfirm bool  `json:"need_confirm" gorm:"default:false"` // 是否需要接收方确认
+
+	// 状态
+	Status       int16      `json:"status" gorm:"default:1"`
+	Remark       string     `json:"remark" gorm:"size:500"`
+	RejectReason string     `json:"reject_reason" gorm:"size:500"`
+	ExpireAt     *time.Time `json:"expire_at"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	CompletedAt  *time.Time `json:"completed_at"`
+
+	// 关联流水
+	FromWalletLogID *int64 `json:"from_wallet_log_id"`
+	ToWalletLogID   *int64 `json:"to_wallet_log_id"`
+
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (WalletTransfer) TableName() string {
+	return "wallet_transfers"
+}
+
+// WalletTransferLimit 钱包划转限额配置
+// AgentID=0 为全局默认配置，代理商单独配置优先
+type WalletTransferLimit struct {
+	ID              int64     `json:"id" gorm:"primaryKey"`
+	AgentID         int64     `json:"agent_id" gorm:"uniqueIndex;default:0"`
+	SingleMaxAmount int64     `json:"single_max_amount"`                    // 单笔上限(分)
+	DailyMaxAmount  int64     `json:"daily_max_amount"`                     // 每日累计上限(分)
+	DailyMaxCount   int       `json:"daily_max_count"`                      // 每日笔数上限
+	RequireConfirm  bool      `json:"require_confirm" gorm:"default:false"` // 是否强制接收方确认
+	UpdatedBy       *int64    `json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at" gorm:"default:now()"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"default:now()"`
+}
+
+// TableName 表名
+func (WalletTransferLimit) TableName() string {
+	return "wallet_transfer_limits"
+}
+
+// GetTransferStatusName 获取划转状态名称
+func GetTransferStatusName(status int16) string {
+	switch status {
+	case TransferStatusPending:
+		return "待确认"
+	case TransferStatusCompleted:
+		return "已完成"
+	case TransferStatusRejected:
+		return "已拒绝"
+	case TransferStatusCancelled:
+		return "已取消"
+	case TransferStatusExpired:
+		return "已过期"
+	default:
+		return "未知"
+	}
+}
+
+// GetTransferDirectionName 获取划转方向名称
+func GetTransferDirectionName(direction int16) string {
+	switch direction {
+	case TransferDirectionDown:
+		return "上级转下级"
+	case TransferDirectionUp:
+		return "下级转上级"
+	default:
+		return "未知"
+	}
+}
+
+// IsTransferableWalletType 判断钱包类型是否允许参与划转
+// 充值钱包和沉淀钱包有独立的资金规则，不参与代理商间划转
+func IsTransferableWalletType(walletType int16) bool {
+	switch walletType {
+	case WalletTypeProfit, WalletTypeService, WalletTypeReward:
+		return true
+	default:
+		return false
+	}
+}
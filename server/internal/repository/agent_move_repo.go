@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAgentMoveStale 迁移期间代理商层级已被修改
+var ErrAgentMoveStale = errors.New("代理商层级已变化，请重新预览后再迁移")
+
+// GormAgentMoveRepository 代理商迁移仓库
+type GormAgentMoveRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAgentMoveRepository 创建代理商迁移仓库
+func NewGormAgentMoveRepository(db *gorm.DB) *GormAgentMoveRepository {
+	return &GormAgentMoveRepository{db: db}
+}
+
+// AgentSubtreeSummary 下级树统计
+type AgentSubtreeSummary struct {
+	Size          int `gorm:"column:size"`           // 代理商数（含根）
+	MaxLevel      int `gorm:"column:max_level"`      // 最深层级
+	MerchantCount int `gorm:"column:merchant_count"` // 直营商户数合计
+}
+
+// GetSubtreeSummary 统计代理商的下级树（含根）
+func (r *GormAgentMoveRepository) GetSubtreeSummary(agentID int64) (*AgentSubtreeSummary, error) {
+	var summary AgentSubtreeSummary
+	err := r.db.Table("agents").
+		Select("COUNT(*) AS size, COALESCE(MAX(level), 0) AS max_level, COALESCE(SUM(direct_merchant_count), 0) AS merchant_count").
+		Where(AgentSubtreeCondition("id"), agentID).
+		Scan(&summary).Error
+	return &summary, err
+}
+
+// crossingCond 两个代理商列一个在下级树内、一个在树外的条件，参数为两次树根代理商ID
+func crossingCond(columnA, columnB string) string {
+	return "(" + AgentSubtreeCondition(columnA) + ") <> (" + AgentSubtreeCondition(columnB) + ")"
+}
+
+// FindCrossingPendingDistributes 查询一方在下级树内、另一方在树外的待确认终端下发
+func (r *GormAgentMoveRepository) FindCrossingPendingDistributes(agentID int64) ([]*models.TerminalDistribute, error) {
+	var list []*models.TerminalDistribute
+	err := r.db.Where("status = ?", models.TerminalDistributeStatusPending).
+		Where(crossingCond("from_agent_id", "to_agent_id"), agentID, agentID).
+		Order("id").
+		Find(&list).Error
+	return list, err
+}
+
+// FindCrossingOpenDeductionPlans 查询一方在下级树内、另一方在树外的未结束代扣计划（待接收/进行中/已暂停）
+func (r *GormAgentMoveRepository) FindCrossingOpenDeductionPlans(agentID int64) ([]*models.DeductionPlan, error) {
+	var list []*models.DeductionPlan
+	err := r.db.Where("status IN ?", []int16{
+		models.DeductionPlanStatusPendingAccept,
+		models.DeductionPlanStatusActive,
+		models.DeductionPlanStatusPaused,
+	}).
+		Where(crossingCond("deductor_id", "deductee_id"), agentID, agentID).
+		Order("id").
+		Find(&list).Error
+	return list, err
+}
+
+// FindCrossingActiveChains 查询节点跨越下级树边界的进行中代扣链
+func (r *GormAgentMoveRepository) FindCrossingActiveChains(agentID int64) ([]*models.DeductionChain, error) {
+	var list []*models.DeductionChain
+	err := r.db.Where("status = ?", 1).
+		Where("id IN (SELECT chain_id FROM deduction_chain_items WHERE "+crossingCond("from_agent_id", "to_agent_id")+")", agentID, agentID).
+		Order("id").
+		Find(&list).Error
+	return list, err
+}
+
+// ExecuteMove 事务内迁移代理商及其下级树：重写path/level和层级闭包表、更新上级、调整各级团队计数并写入迁移记录
+// 锁定代理商行后校验路径未变化，否则返回ErrAgentMoveStale
+func (r *GormAgentMoveRepository) ExecuteMove(move *models.AgentMove, summary *AgentSubtreeSummary) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current Agent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, move.AgentID).Error; err != nil {
+			return err
+		}
+		if current.Path != move.OldPath || current.ParentID != move.FromParentID {
+			return ErrAgentMoveStale
+		}
+		var target Agent
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&target, move.ToParentID).Error; err != nil {
+			return err
+		}
+		if target.Path+strconv.FormatInt(move.AgentID, 10)+"/" != move.NewPath {
+			return ErrAgentMoveStale
+		}
+
+		// 1. 重写下级树的物化路径和层级
+		levelDelta := move.NewLevel - move.OldLevel
+		result := tx.Exec("UPDATE agents SET path = ? || SUBSTRING(path FROM ?), level = level + ? WHERE path LIKE ?",
+			move.NewPath, len(move.OldPath)+1, levelDelta, move.OldPath+"%")
+		if result.Error != nil {
+			return fmt.Errorf("重写下级路径失败: %w", result.Error)
+		}
+		if int(result.RowsAffected) != summary.Size {
+			return ErrAgentMoveStale
+		}
+		if err := tx.Table("agents").Where("id = ?", move.AgentID).Update("parent_id", move.ToParentID).Error; err != nil {
+			return err
+		}
+		if err := moveSubtreeLinks(tx, move.AgentID, move.ToParentID); err != nil {
+			return fmt.Errorf("更新代理商层级失败: %w", err)
+		}
+
+		// 2. 直属下级计数
+		if move.FromParentID > 0 {
+			if err := tx.Exec("UPDATE agents SET direct_agent_count = GREATEST(direct_agent_count - 1, 0) WHERE id = ?", move.FromParentID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("UPDATE agents SET direct_agent_count = direct_agent_count + 1 WHERE id = ?", move.ToParentID).Error; err != nil {
+			return err
+		}
+
+		// 3. 团队计数：原上级链减去、新上级链加上（共同祖先一减一加不变）
+		oldAncestors := pathAncestorIDs(move.OldPath, move.AgentID)
+		newAncestors := pathAncestorIDs(move.NewPath, move.AgentID)
+		if len(oldAncestors) > 0 {
+			if err := tx.Exec("UPDATE agents SET team_agent_count = GREATEST(team_agent_count - ?, 0), team_merchant_count = GREATEST(team_merchant_count - ?, 0) WHERE id IN ?",
+				summary.Size, summary.MerchantCount, oldAncestors).Error; err != nil {
+				return err
+			}
+		}
+		if len(newAncestors) > 0 {
+			if err := tx.Exec("UPDATE agents SET team_agent_count = team_agent_count + ?, team_merchant_count = team_merchant_count + ? WHERE id IN ?",
+				summary.Size, summary.MerchantCount, newAncestors).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(move).Error
+	})
+}
+
+// pathAncestorIDs 从物化路径解析上级ID（不含自身）
+func pathAncestorIDs(path string, selfID int64) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id == selfID {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// AgentMoveFilter 迁移记录查询条件
+type AgentMoveFilter struct {
+	AgentID int64
+}
+
+// List 分页查询迁移记录
+func (r *GormAgentMoveRepository) List(filter *AgentMoveFilter, limit, offset int) ([]*models.AgentMove, int64, error) {
+	query := r.db.Model(&models.AgentMove{})
+	if filter.AgentID > 0 {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.AgentMove
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
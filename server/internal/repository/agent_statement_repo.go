@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormAgentStatementRepository GORM实现的代理商对账单仓库
+type GormAgentStatementRepository struct {
+	db *gorm.DB
+}
+
+// NewGormAgentStatementRepository 创建仓库
+func NewGormAgentStatementRepository(db *gorm.DB) *GormAgentStatementRepository {
+	return &GormAgentStatementRepository{db: db}
+}
+
+// Create 创建对账单
+func (r *GormAgentStatementRepository) Create(statement *models.AgentStatement) error {
+	return r.db.Create(statement).Error
+}
+
+// Save 保存对账单（重新生成时覆盖金额）
+func (r *GormAgentStatementRepository) Save(statement *models.AgentStatement) error {
+	return r.db.Save(statement).Error
+}
+
+// GetByID 根据ID获取对账单
+func (r *GormAgentStatementRepository) GetByID(id int64) (*models.AgentStatement, error) {
+	var statement models.AgentStatement
+	err := r.db.First(&statement, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &statement, err
+}
+
+// FindByPeriod 获取代理商某通道某账期的对账单
+func (r *GormAgentStatementRepository) FindByPeriod(agentID, channelID int64, period string) (*models.AgentStatement, error) {
+	var statement models.AgentStatement
+	err := r.db.Where("agent_id = ? AND channel_id = ? AND period = ?", agentID, channelID, period).
+		First(&statement).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &statement, err
+}
+
+// FindPrevious 获取指定账期之前最近一期对账单
+func (r *GormAgentStatementRepository) FindPrevious(agentID, channelID int64, period string) (*models.AgentStatement, error) {
+	var statement models.AgentStatement
+	err := r.db.Where("agent_id = ? AND channel_id = ? AND period < ?", agentID, channelID, period).
+		Order("period DESC").
+		First(&statement).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &statement, err
+}
+
+// UpdateStatus 按当前状态条件更新对账单
+func (r *GormAgentStatementRepository) UpdateStatus(id int64, fromStatus []int16, updates map[string]interface{}) (bool, error) {
+	updates["updated_at"] = time.Now()
+	result := r.db.Model(&models.AgentStatement{}).
+		Where("id = ? AND status IN ?", id, fromStatus).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// AgentStatementQueryParams 对账单查询参数
+type AgentStatementQueryParams struct {
+	AgentID   int64
+	ChannelID *int64
+	Period    string
+	Status    *int16
+	Limit     int
+	Offset    int
+}
+
+// List 查询对账单列表
+func (r *GormAgentStatementRepository) List(params *AgentStatementQueryParams) ([]*models.AgentStatement, int64, error) {
+	query := r.db.Model(&models.AgentStatement{})
+
+	if params.AgentID > 0 {
+		query = query.Where("agent_id = ?", params.AgentID)
+	}
+	if params.ChannelID != nil {
+		query = query.Where("channel_id = ?", *params.ChannelID)
+	}
+	if params.Period != "" {
+		query = query.Where("period = ?", params.Period)
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var statements []*models.AgentStatement
+	err := query.Order("period DESC, agent_id ASC, channel_id ASC").
+		Limit(params.Limit).
+		Offset(params.Offset).
+		Find(&statements).Error
+	return statements, total, err
+}
+
+// AgentChannelPair 代理商+通道
+type AgentChannelPair struct {
+	AgentID   int64
+	ChannelID int64
+}
+
+// FindAgentChannelPairs 获取所有拥有钱包的代理商+通道组合
+func (r *GormAgentStatementRepository) FindAgentChannelPairs() ([]AgentChannelPair, error) {
+	var pairs []AgentChannelPair
+	err := r.db.Model(&Wallet{}).
+		Select("DISTINCT agent_id, channel_id").
+		Order("agent_id ASC, channel_id ASC").
+		Scan(&pairs).Error
+	return pairs, err
+}
+
+// FindWalletIDs 获取代理商某通道下的全部钱包ID
+func (r *GormAgentStatementRepository) FindWalletIDs(agentID, channelID int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.Model(&Wallet{}).
+		Where("agent_id = ? AND channel_id = ?", agentID, channelID).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SumWalletBalance 汇总钱包当前余额
+func (r *GormAgentStatementRepository) SumWalletBalance(walletIDs []int64) (int64, error) {
+	if len(walletIDs) == 0 {
+		return 0, nil
+	}
+	var total int64
+	err := r.db.Model(&Wallet{}).
+		Where("id IN ?", walletIDs).
+		Select("COALESCE(SUM(balance), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// StatementProfitSum 交易分润汇总
+type StatementProfitSum struct {
+	ProfitAmount   int64
+	HighRateProfit int64
+	D0ExtraProfit  int64
+}
+
+// SumTradeProfits 汇总账期内产生的交易分润（含已撤销，撤销单独按撤销时间统计）
+func (r *GormAgentStatementRepository) SumTradeProfits(agentID, channelID int64, start, end time.Time) (*StatementProfitSum, error) {
+	var sum StatementProfitSum
+	err := r.db.Model(&ProfitRecord{}).
+		Select("COALESCE(SUM(profit_amount), 0) AS profit_amount, COALESCE(SUM(high_rate_profit), 0) AS high_rate_profit, COALESCE(SUM(d0_extra_profit), 0) AS d0_extra_profit").
+		Where("agent_id = ? AND channel_id = ? AND profit_type = 1 AND created_at >= ? AND created_at < ?", agentID, channelID, start, end).
+		Scan(&sum).Error
+	return &sum, err
+}
+
+// SumRevokedProfits 汇总账期内撤销的交易分润
+func (r *GormAgentStatementRepository) SumRevokedProfits(agentID, channelID int64, start, end time.Time) (int64, error) {
+	var total int64
+	err := r.db.Model(&ProfitRecord{}).
+		Select("COALESCE(SUM(profit_amount), 0)").
+		Where("agent_id = ? AND channel_id = ? AND profit_type = 1 AND is_revoked = ? AND revoked_at >= ? AND revoked_at < ?",
+			agentID, channelID, true, start, end).
+		Scan(&total).Error
+	return total, err
+}
+
+// WalletLogSum 钱包流水分组汇总
+type WalletLogSum struct {
+	LogType int16
+	RefType string
+	Credit  bool // 是否为入账（金额为正）
+	Amount  int64
+}
+
+// SumWalletLogs 按流水类型、关联类型和收支方向汇总钱包流水
+func (r *GormAgentStatementRepository) SumWalletLogs(walletIDs []int64, start, end time.Time) ([]WalletLogSum, error) {
+	var sums []WalletLogSum
+	if len(walletIDs) == 0 {
+		return sums, nil
+	}
+	err := r.db.Model(&WalletLog{}).
+		Select("log_type, COALESCE(ref_type, '') AS ref_type, amount > 0 AS credit, SUM(amount) AS amount").
+		Where("wallet_id IN ? AND created_at >= ? AND created_at < ?", walletIDs, start, end).
+		Group("log_type, COALESCE(ref_type, ''), amount > 0").
+		Scan(&sums).Error
+	return sums, err
+}
+
+// SumWithdrawTax 汇总账期内已打款提现的税费
+func (r *GormAgentStatementRepository) SumWithdrawTax(walletIDs []int64, start, end time.Time) (int64, error) {
+	if len(walletIDs) == 0 {
+		return 0, nil
+	}
+	var total int64
+	err := r.db.Model(&models.WithdrawRecord{}).
+		Select("COALESCE(SUM(tax_fee), 0)").
+		Where("wallet_id IN ? AND status = ? AND paid_at >= ? AND paid_at < ?", walletIDs, models.WithdrawStatusPaid, start, end).
+		Scan(&total).Error
+	return total, err
+}
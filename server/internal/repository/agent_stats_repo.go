@@ -215,12 +215,9 @@ func (r *GormAgentStatsRepository) GetChannelStats(agentID int64, startDate, end
 		agentCondition = "t.agent_id = ?"
 		args = append(args, agentID)
 	} else {
-		// 团队范围：查询所有下级代理商的交易
-		agentCondition = "t.agent_id IN (SELECT id FROM agents WHERE agent_path LIKE ?)"
-		// 获取当前代理商的agent_path
-		var agent struct{ AgentPath string }
-		r.db.Table("agents").Select("agent_path").Where("id = ?", agentID).Scan(&agent)
-		args = append(args, agent.AgentPath+"%")
+		// 团队范围：查询本人及所有下级代理商的交易
+		agentCondition = AgentSubtreeCondition("t.agent_id")
+		args = append(args, agentID)
 	}
 
 	args = append(args, startDate, endDate)
@@ -274,10 +271,8 @@ func (r *GormAgentStatsRepository) GetMerchantDistribution(agentID int64, scope
 		condition = "agent_id = ?"
 		args = append(args, agentID)
 	} else {
-		condition = "agent_id IN (SELECT id FROM agents WHERE agent_path LIKE ?)"
-		var agent struct{ AgentPath string }
-		r.db.Table("agents").Select("agent_path").Where("id = ?", agentID).Scan(&agent)
-		args = append(args, agent.AgentPath+"%")
+		condition = AgentSubtreeCondition("agent_id")
+		args = append(args, agentID)
 	}
 
 	query := fmt.Sprintf(`
@@ -437,10 +432,8 @@ func (r *GormAgentStatsRepository) GetMerchantRanking(agentID int64, merchantTyp
 		merchantCondition = "m.agent_id = ?"
 		args = append(args, agentID)
 	} else {
-		merchantCondition = "m.agent_id IN (SELECT id FROM agents WHERE agent_path LIKE ?)"
-		var agent struct{ AgentPath string }
-		r.db.Table("agents").Select("agent_path").Where("id = ?", agentID).Scan(&agent)
-		args = append(args, agent.AgentPath+"%")
+		merchantCondition = AgentSubtreeCondition("m.agent_id")
+		args = append(args, agentID)
 	}
 
 	// 添加商户类型筛选
@@ -501,10 +494,8 @@ func (r *GormAgentStatsRepository) GetTerminalStats(agentID int64, scope string)
 		condition = "owner_agent_id = ?"
 		args = append(args, agentID)
 	} else {
-		condition = "owner_agent_id IN (SELECT id FROM agents WHERE agent_path LIKE ?)"
-		var agent struct{ AgentPath string }
-		r.db.Table("agents").Select("agent_path").Where("id = ?", agentID).Scan(&agent)
-		args = append(args, agent.AgentPath+"%")
+		condition = AgentSubtreeCondition("owner_agent_id")
+		args = append(args, agentID)
 	}
 
 	var stats models.TerminalStats
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormChurnWatchRepository 商户流失预警仓库
+type GormChurnWatchRepository struct {
+	db *gorm.DB
+}
+
+// NewGormChurnWatchRepository 创建商户流失预警仓库
+func NewGormChurnWatchRepository(db *gorm.DB) *GormChurnWatchRepository {
+	return &GormChurnWatchRepository{db: db}
+}
+
+// GetConfig 获取预警配置，不存在时返回nil
+func (r *GormChurnWatchRepository) GetConfig() (*models.ChurnWatchConfig, error) {
+	var config models.ChurnWatchConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存预警配置
+func (r *GormChurnWatchRepository) SaveConfig(config *models.ChurnWatchConfig) error {
+	return r.db.Save(config).Error
+}
+
+// ChurnWatchTarget 待检测商户
+type ChurnWatchTarget struct {
+	ID           int64  `json:"id"`
+	MerchantNo   string `json:"merchant_no"`
+	MerchantName string `json:"merchant_name"`
+	AgentID      int64  `json:"agent_id"`
+}
+
+// FindWatchTargets 按ID升序查询有所属代理商的正常商户
+func (r *GormChurnWatchRepository) FindWatchTargets(afterID int64, limit int) ([]*ChurnWatchTarget, error) {
+	var targets []*ChurnWatchTarget
+	err := r.db.Model(&models.Merchant{}).
+		Select("id, merchant_no, merchant_name, agent_id").
+		Where("status = ? AND agent_id > 0 AND id > ?", models.MerchantStatusActive, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Scan(&targets).Error
+	return targets, err
+}
+
+// MerchantTradeTrend 商户交易趋势
+type MerchantTradeTrend struct {
+	MerchantID     int64      `json:"merchant_id"`
+	LastWeekAmount int64      `json:"last_week_amount"` // 近7天交易额（分）
+	PrevWeekAmount int64      `json:"prev_week_amount"` // 前7天交易额（分）
+	LastWeekCount  int64      `json:"last_week_count"`  // 近7天交易笔数
+	LastTradeAt    *time.Time `json:"last_trade_at"`    // 回溯期内最近交易时间
+}
+
+// GetTradeTrends 批量统计商户截至now的近两周交易额及回溯lookbackDays天内的最近交易时间，回溯期内无交易的商户不在结果中
+func (r *GormChurnWatchRepository) GetTradeTrends(merchantIDs []int64, now time.Time, lookbackDays int) (map[int64]*MerchantTradeTrend, error) {
+	weekStart := now.AddDate(0, 0, -7)
+	prevStart := now.AddDate(0, 0, -14)
+
+	var rows []*MerchantTradeTrend
+	err := r.db.Table("transactions").
+		Select(`merchant_id,
+			COALESCE(SUM(amount) FILTER (WHERE trade_time >= ?), 0) AS last_week_amount,
+			COALESCE(SUM(amount) FILTER (WHERE trade_time >= ? AND trade_time < ?), 0) AS prev_week_amount,
+			COUNT(*) FILTER (WHERE trade_time >= ?) AS last_week_count,
+			MAX(trade_time) AS last_trade_at`,
+			weekStart, prevStart, weekStart, weekStart).
+		Where("merchant_id IN ? AND trade_time >= ? AND trade_time < ?", merchantIDs, now.AddDate(0, 0, -lookbackDays), now).
+		Group("merchant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]*MerchantTradeTrend, len(rows))
+	for _, row := range rows {
+		result[row.MerchantID] = row
+	}
+	return result, nil
+}
+
+// FindSuppressedMerchants 返回有未结束任务或在since之后结束过任务的商户（不再生成新任务）
+func (r *GormChurnWatchRepository) FindSuppressedMerchants(merchantIDs []int64, since time.Time) (map[int64]bool, error) {
+	var ids []int64
+	err := r.db.Model(&models.MerchantFollowUpTask{}).
+		Where("merchant_id IN ?", merchantIDs).
+		Where("status IN ? OR resolved_at >= ?", []int16{models.FollowUpStatusPending, models.FollowUpStatusFollowing}, since).
+		Distinct().
+		Pluck("merchant_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// CreateTasks 批量创建跟进任务
+func (r *GormChurnWatchRepository) CreateTasks(tasks []*models.MerchantFollowUpTask) error {
+	return r.db.CreateInBatches(tasks, 100).Error
+}
+
+// FindOpenTasks 按ID升序查询未结束的跟进任务
+func (r *GormChurnWatchRepository) FindOpenTasks(afterID int64, limit int) ([]*models.MerchantFollowUpTask, error) {
+	var tasks []*models.MerchantFollowUpTask
+	err := r.db.Where("status IN ? AND id > ?", []int16{models.FollowUpStatusPending, models.FollowUpStatusFollowing}, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// FindTaskByID 根据ID获取跟进任务，不存在时返回nil
+func (r *GormChurnWatchRepository) FindTaskByID(id int64) (*models.MerchantFollowUpTask, error) {
+	var task models.MerchantFollowUpTask
+	err := r.db.First(&task, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &task, err
+}
+
+// SaveTaskWithLog 保存跟进任务并记录跟进结果
+func (r *GormChurnWatchRepository) SaveTaskWithLog(task *models.MerchantFollowUpTask, log *models.MerchantFollowUpLog) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		task.UpdatedAt = time.Now()
+		if err := tx.Save(task).Error; err != nil {
+			return err
+		}
+		log.TaskID = task.ID
+		return tx.Create(log).Error
+	})
+}
+
+// FindLogs 获取跟进记录（新到旧）
+func (r *GormChurnWatchRepository) FindLogs(taskID int64) ([]*models.MerchantFollowUpLog, error) {
+	var logs []*models.MerchantFollowUpLog
+	err := r.db.Where("task_id = ?", taskID).Order("created_at DESC, id DESC").Find(&logs).Error
+	return logs, err
+}
+
+// FollowUpTaskFilter 跟进任务查询条件
+type FollowUpTaskFilter struct {
+	ScopeAgentID int64 // 代理商及下级的任务
+	AgentID      int64
+	MerchantID   int64
+	Status       int16
+	TriggerType  string
+	OpenOnly     bool
+}
+
+// ListTasks 分页查询跟进任务，未结束的按期限升序在前
+func (r *GormChurnWatchRepository) ListTasks(filter *FollowUpTaskFilter, limit, offset int) ([]*models.MerchantFollowUpTask, int64, error) {
+	query := r.db.Model(&models.MerchantFollowUpTask{})
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("agent_id"), filter.ScopeAgentID)
+	}
+	if filter.AgentID > 0 {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.TriggerType != "" {
+		query = query.Where("trigger_type = ?", filter.TriggerType)
+	}
+	if filter.OpenOnly {
+		query = query.Where("status IN ?", []int16{models.FollowUpStatusPending, models.FollowUpStatusFollowing})
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tasks []*models.MerchantFollowUpTask
+	err := query.Order("CASE WHEN status IN (1, 2) THEN 0 ELSE 1 END, due_at ASC, id DESC").
+		Limit(limit).Offset(offset).Find(&tasks).Error
+	return tasks, total, err
+}
+
+// FollowUpAgentStats 代理商跟进统计
+type FollowUpAgentStats struct {
+	AgentID   int64  `json:"agent_id"`
+	AgentName string `json:"agent_name"`
+	Total     int64  `json:"total"`     // 任务数
+	Pending   int64  `json:"pending"`   // 待跟进
+	Following int64  `json:"following"` // 跟进中
+	Recovered int64  `json:"recovered"` // 已挽回
+	Lost      int64  `json:"lost"`      // 已流失
+	Contacted int64  `json:"contacted"` // 有跟进记录的任务数
+	Overdue   int64  `json:"overdue"`   // 超期未跟进
+}
+
+// GetAgentStats 按代理商统计[start, end)内生成的跟进任务，scopeAgentID非零时只统计该代理商及下级
+func (r *GormChurnWatchRepository) GetAgentStats(scopeAgentID int64, start, end, now time.Time) ([]*FollowUpAgentStats, error) {
+	query := r.db.Table("merchant_follow_up_tasks t").
+		Select(`t.agent_id, COALESCE(a.agent_name, '') AS agent_name,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE t.status = ?) AS pending,
+			COUNT(*) FILTER (WHERE t.status = ?) AS following,
+			COUNT(*) FILTER (WHERE t.status = ?) AS recovered,
+			COUNT(*) FILTER (WHERE t.status = ?) AS lost,
+			COUNT(*) FILTER (WHERE t.follow_up_count > 0) AS contacted,
+			COUNT(*) FILTER (WHERE t.status = ? AND t.due_at < ?) AS overdue`,
+			models.FollowUpStatusPending, models.FollowUpStatusFollowing,
+			models.FollowUpStatusRecovered, models.FollowUpStatusLost,
+			models.FollowUpStatusPending, now).
+		Joins("LEFT JOIN agents a ON a.id = t.agent_id").
+		Where("t.created_at >= ? AND t.created_at < ?", start, end)
+	if scopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("t.agent_id"), scopeAgentID)
+	}
+
+	var rows []*FollowUpAgentStats
+	err := query.Group("t.agent_id, a.agent_name").Order("total DESC, t.agent_id ASC").Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormDeductionOverdueRepository GORM实现的代扣逾期仓库
+type GormDeductionOverdueRepository struct {
+	db *gorm.DB
+}
+
+// NewGormDeductionOverdueRepository 创建仓库
+func NewGormDeductionOverdueRepository(db *gorm.DB) *GormDeductionOverdueRepository {
+	return &GormDeductionOverdueRepository{db: db}
+}
+
+// GetConfig 获取逾期处理配置，不存在时返回nil
+func (r *GormDeductionOverdueRepository) GetConfig() (*models.DeductionOverdueConfig, error) {
+	var config models.DeductionOverdueConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存逾期处理配置
+func (r *GormDeductionOverdueRepository) SaveConfig(config *models.DeductionOverdueConfig) error {
+	return r.db.Save(config).Error
+}
+
+// OverduePlanStat 计划逾期本金统计
+type OverduePlanStat struct {
+	PlanID           int64     `json:"plan_id"`
+	OverduePrincipal int64     `json:"overdue_principal"`
+	EarliestDueAt    time.Time `json:"earliest_due_at"`
+}
+
+// FindOverduePlanStats 统计进行中/已暂停计划在指定时间前到期且未扣足的金额
+func (r *GormDeductionOverdueRepository) FindOverduePlanStats(before time.Time) ([]*OverduePlanStat, error) {
+	var stats []*OverduePlanStat
+	err := r.db.Table("deduction_records AS r").
+		Select("r.plan_id, SUM(r.amount - r.actual_amount) AS overdue_principal, MIN(r.scheduled_at) AS earliest_due_at").
+		Joins("JOIN deduction_plans p ON p.id = r.plan_id").
+		Where("r.status IN ? AND r.scheduled_at < ? AND r.amount > r.actual_amount",
+			[]int16{models.DeductionRecordStatusPartialSuccess, models.DeductionRecordStatusFailed}, before).
+		Where("p.status IN ?", []int16{models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused}).
+		Group("r.plan_id").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// FindOverdueRecords 获取计划已逾期的代扣记录（按应扣时间从早到晚）
+func (r *GormDeductionOverdueRepository) FindOverdueRecords(tx *gorm.DB, planID int64, before time.Time) ([]*models.DeductionRecord, error) {
+	var records []*models.DeductionRecord
+	err := tx.Where("plan_id = ? AND status IN ? AND scheduled_at < ? AND amount > actual_amount",
+		planID, []int16{models.DeductionRecordStatusPartialSuccess, models.DeductionRecordStatusFailed}, before).
+		Order("scheduled_at ASC, period_num ASC").
+		Find(&records).Error
+	return records, err
+}
+
+// FindOpen 获取全部逾期中的记录
+func (r *GormDeductionOverdueRepository) FindOpen() ([]*models.DeductionOverdue, error) {
+	var overdues []*models.DeductionOverdue
+	err := r.db.Where("status = ?", models.DeductionOverdueStatusOpen).Order("id ASC").Find(&overdues).Error
+	return overdues, err
+}
+
+// FindOpenByPlan 获取计划逾期中的记录
+func (r *GormDeductionOverdueRepository) FindOpenByPlan(planID int64) (*models.DeductionOverdue, error) {
+	var overdue models.DeductionOverdue
+	err := r.db.Where("plan_id = ? AND status = ?", planID, models.DeductionOverdueStatusOpen).First(&overdue).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &overdue, err
+}
+
+// Create 创建逾期记录
+func (r *GormDeductionOverdueRepository) Create(overdue *models.DeductionOverdue) error {
+	return r.db.Create(overdue).Error
+}
+
+// Save 保存逾期记录
+func (r *GormDeductionOverdueRepository) Save(overdue *models.DeductionOverdue) error {
+	overdue.UpdatedAt = time.Now()
+	return r.db.Save(overdue).Error
+}
+
+// GetByID 根据ID获取逾期记录
+func (r *GormDeductionOverdueRepository) GetByID(id int64) (*models.DeductionOverdue, error) {
+	var overdue models.DeductionOverdue
+	err := r.db.First(&overdue, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &overdue, err
+}
+
+// DeductionOverdueFilter 逾期记录查询条件
+type DeductionOverdueFilter struct {
+	DeductorID int64
+	DeducteeID int64
+	Status     int16
+	MinDays    int
+}
+
+// List 分页查询逾期记录
+func (r *GormDeductionOverdueRepository) List(filter *DeductionOverdueFilter, limit, offset int) ([]*models.DeductionOverdue, int64, error) {
+	query := r.db.Model(&models.DeductionOverdue{})
+	if filter.DeductorID > 0 {
+		query = query.Where("deductor_id = ?", filter.DeductorID)
+	}
+	if filter.DeducteeID > 0 {
+		query = query.Where("deductee_id = ?", filter.DeducteeID)
+	}
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.MinDays > 0 {
+		query = query.Where("days_past_due >= ?", filter.MinDays)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var overdues []*models.DeductionOverdue
+	err := query.Order("days_past_due DESC, id DESC").Limit(limit).Offset(offset).Find(&overdues).Error
+	return overdues, total, err
+}
+
+// DeductionOverdueSummary 债权人逾期汇总
+type DeductionOverdueSummary struct {
+	OpenCount        int64 `json:"open_count"`          // 逾期中计划数
+	OverdueAmount    int64 `json:"overdue_amount"`      // 逾期本金（分）
+	UnpaidLateFee    int64 `json:"unpaid_late_fee"`     // 未收滞纳金（分）
+	EscalatedCount   int64 `json:"escalated_count"`     // 已上报上级数
+	LongOverdueCount int64 `json:"long_overdue_count"`  // 逾期超30天数
+	CollectedAmount  int64 `json:"collected_amount"`    // 累计补扣本金（分）
+	DrawnAmount      int64 `json:"drawn_amount"`        // 累计上级代偿（分）
+	ClearedCount     int64 `json:"cleared_count"`       // 已结清数
+	LateFeePaidTotal int64 `json:"late_fee_paid_total"` // 累计已收滞纳金（分）
+}
+
+// GetSummaryByDeductor 获取债权人逾期汇总
+func (r *GormDeductionOverdueRepository) GetSummaryByDeductor(deductorID int64) (*DeductionOverdueSummary, error) {
+	summary := &DeductionOverdueSummary{}
+	err := r.db.Model(&models.DeductionOverdue{}).
+		Select(`
+			COUNT(*) FILTER (WHERE status = ?) AS open_count,
+			COALESCE(SUM(overdue_amount) FILTER (WHERE status = ?), 0) AS overdue_amount,
+			COALESCE(SUM(late_fee - late_fee_paid) FILTER (WHERE status = ?), 0) AS unpaid_late_fee,
+			COUNT(*) FILTER (WHERE status = ? AND escalated_at IS NOT NULL) AS escalated_count,
+			COUNT(*) FILTER (WHERE status = ? AND days_past_due > 30) AS long_overdue_count,
+			COALESCE(SUM(collected_amount), 0) AS collected_amount,
+			COALESCE(SUM(drawn_amount), 0) AS drawn_amount,
+			COUNT(*) FILTER (WHERE status = ?) AS cleared_count,
+			COALESCE(SUM(late_fee_paid), 0) AS late_fee_paid_total`,
+			models.DeductionOverdueStatusOpen, models.DeductionOverdueStatusOpen, models.DeductionOverdueStatusOpen,
+			models.DeductionOverdueStatusOpen, models.DeductionOverdueStatusOpen, models.DeductionOverdueStatusCleared).
+		Where("deductor_id = ?", deductorID).
+		Scan(summary).Error
+	return summary, err
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormDeductionOverdueRepository) GetDB() *gorm.DB {
+	return r.db
+}
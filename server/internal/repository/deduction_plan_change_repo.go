@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormDeductionPlanChangeRepository GORM实现的代扣计划变更仓库
+type GormDeductionPlanChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewGormDeductionPlanChangeRepository 创建仓库
+func NewGormDeductionPlanChangeRepository(db *gorm.DB) *GormDeductionPlanChangeRepository {
+	return &GormDeductionPlanChangeRepository{db: db}
+}
+
+// Create 创建变更记录
+func (r *GormDeductionPlanChangeRepository) Create(change *models.DeductionPlanChange) error {
+	return r.db.Create(change).Error
+}
+
+// GetByID 根据ID获取变更记录
+func (r *GormDeductionPlanChangeRepository) GetByID(id int64) (*models.DeductionPlanChange, error) {
+	var change models.DeductionPlanChange
+	err := r.db.First(&change, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &change, err
+}
+
+// FindPendingByPlan 获取计划待确认的重组提议
+func (r *GormDeductionPlanChangeRepository) FindPendingByPlan(planID int64) (*models.DeductionPlanChange, error) {
+	var change models.DeductionPlanChange
+	err := r.db.Where("plan_id = ? AND status = ?", planID, models.DeductionPlanChangeStatusPending).
+		First(&change).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &change, err
+}
+
+// FindByPlanID 获取计划的全部变更记录
+func (r *GormDeductionPlanChangeRepository) FindByPlanID(planID int64) ([]*models.DeductionPlanChange, error) {
+	var changes []*models.DeductionPlanChange
+	err := r.db.Where("plan_id = ?", planID).Order("created_at DESC, id DESC").Find(&changes).Error
+	return changes, err
+}
+
+// FindPendingByCounterparty 获取待我确认的重组提议
+func (r *GormDeductionPlanChangeRepository) FindPendingByCounterparty(agentID int64, limit, offset int) ([]*models.DeductionPlanChange, int64, error) {
+	query := r.db.Model(&models.DeductionPlanChange{}).
+		Where("counterparty_id = ? AND status = ?", agentID, models.DeductionPlanChangeStatusPending)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var changes []*models.DeductionPlanChange
+	err := query.Order("created_at ASC").Limit(limit).Offset(offset).Find(&changes).Error
+	return changes, total, err
+}
+
+// UpdateStatus 按当前状态条件更新变更记录
+func (r *GormDeductionPlanChangeRepository) UpdateStatus(id int64, fromStatus int16, updates map[string]interface{}) (bool, error) {
+	updates["updated_at"] = time.Now()
+	result := r.db.Model(&models.DeductionPlanChange{}).
+		Where("id = ? AND status = ?", id, fromStatus).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormDeductionPlanChangeRepository) GetDB() *gorm.DB {
+	return r.db
+}
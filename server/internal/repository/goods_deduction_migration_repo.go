@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormGoodsDeductionMigrationRepository 货款代扣迁移及兼容查询仓库
+type GormGoodsDeductionMigrationRepository struct {
+	db *gorm.DB
+}
+
+// NewGormGoodsDeductionMigrationRepository 创建仓库
+func NewGormGoodsDeductionMigrationRepository(db *gorm.DB) *GormGoodsDeductionMigrationRepository {
+	return &GormGoodsDeductionMigrationRepository{db: db}
+}
+
+// FindUnmigrated 按ID游标获取尚未迁移的货款代扣
+func (r *GormGoodsDeductionMigrationRepository) FindUnmigrated(afterID int64, limit int) ([]*models.GoodsDeduction, error) {
+	var deductions []*models.GoodsDeduction
+	err := r.db.Where("migrated_plan_id IS NULL AND id > ?", afterID).
+		Order("id ASC").Limit(limit).Find(&deductions).Error
+	return deductions, err
+}
+
+// CountUnmigrated 统计尚未迁移的货款代扣
+func (r *GormGoodsDeductionMigrationRepository) CountUnmigrated() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.GoodsDeduction{}).Where("migrated_plan_id IS NULL").Count(&count).Error
+	return count, err
+}
+
+// FindMigrated 按ID游标获取已迁移的货款代扣
+func (r *GormGoodsDeductionMigrationRepository) FindMigrated(afterID int64, limit int) ([]*models.GoodsDeduction, error) {
+	var deductions []*models.GoodsDeduction
+	err := r.db.Where("migrated_plan_id IS NOT NULL AND id > ?", afterID).
+		Order("id ASC").Limit(limit).Find(&deductions).Error
+	return deductions, err
+}
+
+// FindByPlanIDs 按代扣计划ID批量获取货款信息
+func (r *GormGoodsDeductionMigrationRepository) FindByPlanIDs(planIDs []int64) ([]*models.GoodsDeduction, error) {
+	var deductions []*models.GoodsDeduction
+	if len(planIDs) == 0 {
+		return deductions, nil
+	}
+	err := r.db.Where("migrated_plan_id IN ?", planIDs).Find(&deductions).Error
+	return deductions, err
+}
+
+// FindAllDetails 获取货款代扣的全部扣款明细（按扣款时间从早到晚）
+func (r *GormGoodsDeductionMigrationRepository) FindAllDetails(deductionID int64) ([]*models.GoodsDeductionDetail, error) {
+	var details []*models.GoodsDeductionDetail
+	err := r.db.Where("deduction_id = ?", deductionID).Order("created_at ASC, id ASC").Find(&details).Error
+	return details, err
+}
+
+// FindPlanByNo 按编号获取代扣计划，不存在时返回nil
+func (r *GormGoodsDeductionMigrationRepository) FindPlanByNo(planNo string) (*models.DeductionPlan, error) {
+	var plan models.DeductionPlan
+	err := r.db.Where("plan_no = ?", planNo).First(&plan).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &plan, err
+}
+
+// FindPlanByID 获取代扣计划，不存在时返回nil
+func (r *GormGoodsDeductionMigrationRepository) FindPlanByID(planID int64) (*models.DeductionPlan, error) {
+	var plan models.DeductionPlan
+	err := r.db.First(&plan, planID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &plan, err
+}
+
+// UpdatePlanRelation 更新代扣计划的关联业务
+func (r *GormGoodsDeductionMigrationRepository) UpdatePlanRelation(planID int64, relatedType string, relatedID int64) error {
+	return r.db.Model(&models.DeductionPlan{}).Where("id = ?", planID).Updates(map[string]interface{}{
+		"related_type": relatedType,
+		"related_id":   relatedID,
+	}).Error
+}
+
+// CountPlanRecords 统计计划的代扣记录数
+func (r *GormGoodsDeductionMigrationRepository) CountPlanRecords(planID int64) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.DeductionRecord{}).Where("plan_id = ?", planID).Count(&count).Error
+	return count, err
+}
+
+// FindLegacyRecords 获取计划中由货款代扣明细转换的代扣记录
+func (r *GormGoodsDeductionMigrationRepository) FindLegacyRecords(planID int64) ([]*models.DeductionRecord, error) {
+	var records []*models.DeductionRecord
+	err := r.db.Where("plan_id = ? AND legacy_detail_id IS NOT NULL", planID).
+		Order("period_num ASC").Find(&records).Error
+	return records, err
+}
+
+// FindDeductedRecords 获取计划已实际扣款的记录（按扣款时间从早到晚）
+func (r *GormGoodsDeductionMigrationRepository) FindDeductedRecords(planID int64) ([]*models.DeductionRecord, error) {
+	var records []*models.DeductionRecord
+	err := r.db.Where("plan_id = ? AND actual_amount > 0", planID).
+		Order("COALESCE(deducted_at, scheduled_at) ASC, id ASC").Find(&records).Error
+	return records, err
+}
+
+// FindGoodsPlans 查询由货款代扣并入的代扣计划（deductorID/deducteeID 为0时不限）
+func (r *GormGoodsDeductionMigrationRepository) FindGoodsPlans(deductorID, deducteeID int64, status []int16, limit, offset int) ([]*models.DeductionPlan, int64, error) {
+	query := r.goodsPlanQuery(deductorID, deducteeID)
+	if len(status) > 0 {
+		query = query.Where("status IN ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var plans []*models.DeductionPlan
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&plans).Error
+	return plans, total, err
+}
+
+// GetGoodsPlanSummary 按货款代扣口径汇总由货款代扣并入的代扣计划
+func (r *GormGoodsDeductionMigrationRepository) GetGoodsPlanSummary(deductorID, deducteeID int64) (*models.GoodsDeductionSummary, error) {
+	var summary models.GoodsDeductionSummary
+	err := r.goodsPlanQuery(deductorID, deducteeID).
+		Select(`COUNT(*) AS total_count,
+			COUNT(*) FILTER (WHERE status = ?) AS pending_count,
+			COUNT(*) FILTER (WHERE status IN ?) AS in_progress_count,
+			COUNT(*) FILTER (WHERE status = ?) AS completed_count,
+			COALESCE(SUM(total_amount), 0) AS total_amount,
+			COALESCE(SUM(deducted_amount), 0) AS deducted_amount,
+			COALESCE(SUM(remaining_amount) FILTER (WHERE status IN ?), 0) AS remaining_amount`,
+			models.DeductionPlanStatusPendingAccept,
+			[]int16{models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused},
+			models.DeductionPlanStatusCompleted,
+			[]int16{models.DeductionPlanStatusPendingAccept, models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused}).
+		Scan(&summary).Error
+	return &summary, err
+}
+
+// goodsPlanQuery 有货款代扣记录的货款类代扣计划（/goods-deduction 兼容接口以货款代扣ID寻址）
+func (r *GormGoodsDeductionMigrationRepository) goodsPlanQuery(deductorID, deducteeID int64) *gorm.DB {
+	query := r.db.Model(&models.DeductionPlan{}).
+		Where("plan_type = ? AND id IN (SELECT migrated_plan_id FROM goods_deductions WHERE migrated_plan_id IS NOT NULL)",
+			models.DeductionPlanTypeGoods)
+	if deductorID > 0 {
+		query = query.Where("deductor_id = ?", deductorID)
+	}
+	if deducteeID > 0 {
+		query = query.Where("deductee_id = ?", deducteeID)
+	}
+	return query
+}
+
+// CreateRun 创建迁移批次
+func (r *GormGoodsDeductionMigrationRepository) CreateRun(run *models.GoodsDeductionMigrationRun) error {
+	return r.db.Create(run).Error
+}
+
+// SaveRun 保存迁移批次
+func (r *GormGoodsDeductionMigrationRepository) SaveRun(run *models.GoodsDeductionMigrationRun) error {
+	return r.db.Save(run).Error
+}
+
+// GetRun 获取迁移批次，不存在时返回nil
+func (r *GormGoodsDeductionMigrationRepository) GetRun(id int64) (*models.GoodsDeductionMigrationRun, error) {
+	var run models.GoodsDeductionMigrationRun
+	err := r.db.First(&run, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &run, err
+}
+
+// ListRuns 分页获取迁移批次（不含报告明细）
+func (r *GormGoodsDeductionMigrationRepository) ListRuns(limit, offset int) ([]*models.GoodsDeductionMigrationRun, int64, error) {
+	query := r.db.Model(&models.GoodsDeductionMigrationRun{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []*models.GoodsDeductionMigrationRun
+	err := query.Omit("report").Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&runs).Error
+	return runs, total, err
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormGoodsDeductionMigrationRepository) GetDB() *gorm.DB {
+	return r.db
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+)
+
+// AgentSubtreeCondition 代理商在某代理商下级树内（含自身）的查询条件，参数为树根代理商ID
+// 如 AgentSubtreeCondition("t.agent_id") 生成 "t.agent_id IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = ?)"
+func AgentSubtreeCondition(column string) string {
+	return column + " IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = ?)"
+}
+
+// HierarchyNode 下级树节点
+type HierarchyNode struct {
+	Agent
+	Depth int `json:"depth"` // 与树根相隔层数
+}
+
+// GormHierarchyRepository 代理商层级仓库（闭包表）
+type GormHierarchyRepository struct {
+	db *gorm.DB
+}
+
+// NewGormHierarchyRepository 创建代理商层级仓库
+func NewGormHierarchyRepository(db *gorm.DB) *GormHierarchyRepository {
+	return &GormHierarchyRepository{db: db}
+}
+
+// FindAncestors 查询上级链，按从顶级到直属上级排序，includeSelf为true时末尾包含自身
+func (r *GormHierarchyRepository) FindAncestors(agentID int64, includeSelf bool) ([]*Agent, error) {
+	minDepth := 1
+	if includeSelf {
+		minDepth = 0
+	}
+	var ancestors []*Agent
+	err := r.db.Raw(`
+		SELECT a.* FROM agent_hierarchy h
+		JOIN agents a ON a.id = h.ancestor_id
+		WHERE h.descendant_id = ? AND h.depth >= ?
+		ORDER BY h.depth DESC
+	`, agentID, minDepth).Scan(&ancestors).Error
+	return ancestors, err
+}
+
+// FindDescendants 查询下级树（不含自身），按层数、创建时间倒序排序，maxDepth<=0时不限深度
+func (r *GormHierarchyRepository) FindDescendants(agentID int64, maxDepth int) ([]*HierarchyNode, error) {
+	query := r.db.Table("agent_hierarchy h").
+		Select("a.*, h.depth").
+		Joins("JOIN agents a ON a.id = h.descendant_id").
+		Where("h.ancestor_id = ? AND h.depth > 0", agentID)
+	if maxDepth > 0 {
+		query = query.Where("h.depth <= ?", maxDepth)
+	}
+
+	var nodes []*HierarchyNode
+	err := query.Order("h.depth ASC, a.created_at DESC").Scan(&nodes).Error
+	return nodes, err
+}
+
+// IsDescendant descendantID是否为ancestorID的下级（不含自身）
+func (r *GormHierarchyRepository) IsDescendant(ancestorID, descendantID int64) (bool, error) {
+	var count int64
+	err := r.db.Table("agent_hierarchy").
+		Where("ancestor_id = ? AND descendant_id = ? AND depth > 0", ancestorID, descendantID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetPathBetween 查询上级到下级的链路（含两端，从上级到下级排序），不是上下级关系时返回空
+func (r *GormHierarchyRepository) GetPathBetween(ancestorID, descendantID int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.Raw(`
+		SELECT h.ancestor_id FROM agent_hierarchy h
+		WHERE h.descendant_id = ?
+		  AND h.depth <= (SELECT depth FROM agent_hierarchy WHERE ancestor_id = ? AND descendant_id = ?)
+		ORDER BY h.depth DESC
+	`, descendantID, ancestorID, descendantID).Scan(&ids).Error
+	return ids, err
+}
+
+// InsertNode 新建代理商时写入层级：复制上级的上级链并加上自身记录，parentID为0时仅写自身
+func (r *GormHierarchyRepository) InsertNode(agentID, parentID int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return insertNodeLinks(tx, agentID, parentID)
+	})
+}
+
+// insertNodeLinks 写入新代理商的层级记录（须在事务内调用）
+// SELECT列表中的占位参数须显式转为bigint，否则PostgreSQL会推断为text导致写入失败
+func insertNodeLinks(tx *gorm.DB, agentID, parentID int64) error {
+	if err := tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, depth)
+		VALUES (?, ?, 0)
+		ON CONFLICT (ancestor_id, descendant_id) DO NOTHING
+	`, agentID, agentID).Error; err != nil {
+		return err
+	}
+	if parentID == 0 {
+		return nil
+	}
+	return tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, depth)
+		SELECT ancestor_id, ?::bigint, depth + 1 FROM agent_hierarchy WHERE descendant_id = ?
+		ON CONFLICT (ancestor_id, descendant_id) DO NOTHING
+	`, agentID, parentID).Error
+}
+
+// moveSubtreeLinks 迁移下级树的层级（须在事务内调用）：
+// 删除树外上级到树内节点的记录，再按新上级的上级链与树内节点做笛卡尔积写入
+func moveSubtreeLinks(tx *gorm.DB, agentID, newParentID int64) error {
+	if err := tx.Exec(`
+		DELETE FROM agent_hierarchy
+		WHERE descendant_id IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = ?)
+		  AND ancestor_id NOT IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = ?)
+	`, agentID, agentID).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`
+		INSERT INTO agent_hierarchy (ancestor_id, descendant_id, depth)
+		SELECT p.ancestor_id, s.descendant_id, p.depth + s.depth + 1
+		FROM agent_hierarchy p
+		CROSS JOIN agent_hierarchy s
+		WHERE p.descendant_id = ? AND s.ancestor_id = ?
+	`, newParentID, agentID).Error
+}
+
+// 确保实现了接口
+var _ HierarchyRepository = (*GormHierarchyRepository)(nil)
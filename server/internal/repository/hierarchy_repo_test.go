@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 连接TEST_DATABASE_DSN指定的PostgreSQL，未配置时跳过
+// 返回的连接处于事务中，测试结束后回滚
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN未配置，跳过数据库测试")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+
+	tx := db.Begin()
+	require.NoError(t, tx.Error)
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// TestInsertNodePostgres 在真实PostgreSQL上写入层级闭包表
+func TestInsertNodePostgres(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.Exec(`
+		CREATE TEMP TABLE agent_hierarchy (
+			ancestor_id BIGINT NOT NULL,
+			descendant_id BIGINT NOT NULL,
+			depth INT NOT NULL,
+			PRIMARY KEY (ancestor_id, descendant_id)
+		) ON COMMIT DROP
+	`).Error)
+
+	repo := NewGormHierarchyRepository(db)
+	require.NoError(t, repo.InsertNode(1, 0))
+	require.NoError(t, repo.InsertNode(2, 1))
+	require.NoError(t, repo.InsertNode(3, 2))
+	// 重复写入不报错
+	require.NoError(t, repo.InsertNode(3, 2))
+
+	var count int64
+	require.NoError(t, db.Table("agent_hierarchy").Count(&count).Error)
+	assert.Equal(t, int64(6), count)
+
+	path, err := repo.GetPathBetween(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, path)
+
+	isDescendant, err := repo.IsDescendant(1, 3)
+	require.NoError(t, err)
+	assert.True(t, isDescendant)
+
+	isDescendant, err = repo.IsDescendant(3, 1)
+	require.NoError(t, err)
+	assert.False(t, isDescendant)
+}
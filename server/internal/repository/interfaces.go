@@ -216,6 +216,15 @@ type AgentRepository interface {
 	FindAncestors(agentID int64) ([]*Agent, error) // 查找所有上级代理商
 }
 
+// HierarchyRepository 代理商层级仓库接口（基于闭包表agent_hierarchy）
+type HierarchyRepository interface {
+	FindAncestors(agentID int64, includeSelf bool) ([]*Agent, error)       // 上级链（从顶级到直属上级）
+	FindDescendants(agentID int64, maxDepth int) ([]*HierarchyNode, error) // 下级树（maxDepth<=0不限深度）
+	IsDescendant(ancestorID, descendantID int64) (bool, error)             // descendantID是否为ancestorID的下级（不含自身）
+	GetPathBetween(ancestorID, descendantID int64) ([]int64, error)        // 上级到下级的链路（含两端）
+	InsertNode(agentID, parentID int64) error                              // 新建代理商时写入层级
+}
+
 // Agent 代理商模型
 type Agent struct {
 	ID          int64  `json:"id" gorm:"primaryKey"`
@@ -247,7 +256,9 @@ type AgentPolicy struct {
 type MerchantRepository interface {
 	FindByID(id int64) (*models.Merchant, error)
 	FindByMerchantNo(merchantNo string) (*models.Merchant, error)
+	FindByTerminalSN(terminalSN string) (*models.Merchant, error)
 	UpdateApproveStatus(id int64, status int16) error
+	UpdateIDCardValidity(id int64, startDate, endDate *time.Time, longTerm bool) error
 }
 
 // ChannelDepositTierRepository 通道押金档位仓库接口
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// inventoryReceivedAtExpr 终端到达当前持有代理商的时间：
+// 最近一次确认下发/回拨给当前持有者的时间，均无则取入库时间
+const inventoryReceivedAtExpr = `COALESCE(GREATEST(
+	(SELECT MAX(d.confirmed_at) FROM terminal_distributes d
+		WHERE d.terminal_sn = t.terminal_sn AND d.to_agent_id = t.owner_agent_id AND d.status = 2),
+	(SELECT MAX(rc.confirmed_at) FROM terminal_recalls rc
+		WHERE rc.terminal_sn = t.terminal_sn AND rc.to_agent_id = t.owner_agent_id AND rc.status = 2)
+), t.created_at)`
+
+// inventoryBoundAtExpr 已绑定未激活终端的绑定时间
+const inventoryBoundAtExpr = `COALESCE(t.bound_at, t.updated_at)`
+
+// InventoryFilter 库存报表筛选条件
+type InventoryFilter struct {
+	AgentID   int64  // 代理商ID，0表示全部
+	Scope     string // direct:仅本人持有 team:本人及全部下级
+	ChannelID int64
+	BrandCode string
+	ModelCode string
+}
+
+// InventoryStockRow 按代理商/通道/品牌/型号汇总的库存
+type InventoryStockRow struct {
+	AgentID            int64  `json:"agent_id" gorm:"column:agent_id"`
+	AgentName          string `json:"agent_name" gorm:"column:agent_name"`
+	ChannelID          int64  `json:"channel_id" gorm:"column:channel_id"`
+	ChannelName        string `json:"channel_name" gorm:"column:channel_name"`
+	BrandCode          string `json:"brand_code" gorm:"column:brand_code"`
+	ModelCode          string `json:"model_code" gorm:"column:model_code"`
+	TotalCount         int64  `json:"total_count" gorm:"column:total_count"`                   // 持有终端总数
+	StockCount         int64  `json:"stock_count" gorm:"column:stock_count"`                   // 未绑定库存
+	BoundInactiveCount int64  `json:"bound_inactive_count" gorm:"column:bound_inactive_count"` // 已绑定未激活
+	ActivatedCount     int64  `json:"activated_count" gorm:"column:activated_count"`           // 已激活
+	Age0To30           int64  `json:"age_0_30" gorm:"column:age_0_30"`                         // 库龄0-30天
+	Age30To60          int64  `json:"age_30_60" gorm:"column:age_30_60"`                       // 库龄30-60天
+	Age60To90          int64  `json:"age_60_90" gorm:"column:age_60_90"`                       // 库龄60-90天
+	Age90Plus          int64  `json:"age_90_plus" gorm:"column:age_90_plus"`                   // 库龄90天以上
+}
+
+// InventorySubordinateRow 直属下级（含其团队）的库存与动销
+type InventorySubordinateRow struct {
+	AgentID            int64  `json:"agent_id" gorm:"column:agent_id"`
+	AgentName          string `json:"agent_name" gorm:"column:agent_name"`
+	TotalCount         int64  `json:"total_count" gorm:"column:total_count"`
+	StockCount         int64  `json:"stock_count" gorm:"column:stock_count"`
+	BoundInactiveCount int64  `json:"bound_inactive_count" gorm:"column:bound_inactive_count"`
+	ActivatedCount     int64  `json:"activated_count" gorm:"column:activated_count"`
+	StaleCount         int64  `json:"stale_count" gorm:"column:stale_count"` // 呆滞库存
+}
+
+// InventoryStaleTerminal 呆滞终端明细
+type InventoryStaleTerminal struct {
+	ID          int64     `json:"id" gorm:"column:id"`
+	TerminalSN  string    `json:"terminal_sn" gorm:"column:terminal_sn"`
+	AgentID     int64     `json:"agent_id" gorm:"column:agent_id"`
+	AgentName   string    `json:"agent_name" gorm:"column:agent_name"`
+	ChannelID   int64     `json:"channel_id" gorm:"column:channel_id"`
+	ChannelCode string    `json:"channel_code" gorm:"column:channel_code"`
+	BrandCode   string    `json:"brand_code" gorm:"column:brand_code"`
+	ModelCode   string    `json:"model_code" gorm:"column:model_code"`
+	Status      int16     `json:"status" gorm:"column:status"`
+	MerchantNo  string    `json:"merchant_no" gorm:"column:merchant_no"`
+	SinceAt     time.Time `json:"since_at" gorm:"column:since_at"` // 到达/绑定时间
+}
+
+// InventoryAgentStale 代理商呆滞库存统计
+type InventoryAgentStale struct {
+	AgentID    int64 `gorm:"column:agent_id"`
+	StockCount int64 `gorm:"column:stock_count"`
+	StaleCount int64 `gorm:"column:stale_count"`
+}
+
+// GormInventoryReportRepository 终端库存报表仓库
+type GormInventoryReportRepository struct {
+	db *gorm.DB
+}
+
+// NewGormInventoryReportRepository 创建终端库存报表仓库
+func NewGormInventoryReportRepository(db *gorm.DB) *GormInventoryReportRepository {
+	return &GormInventoryReportRepository{db: db}
+}
+
+// terminalCondition 按筛选条件生成terminals表(t)的查询条件
+func (r *GormInventoryReportRepository) terminalCondition(filter *InventoryFilter) (string, []interface{}) {
+	conditions := []string{"t.owner_agent_id > 0"}
+	var args []interface{}
+
+	if filter.AgentID > 0 {
+		if filter.Scope == models.StatScopeTeam {
+			conditions = append(conditions, AgentSubtreeCondition("t.owner_agent_id"))
+			args = append(args, filter.AgentID)
+		} else {
+			conditions = append(conditions, "t.owner_agent_id = ?")
+			args = append(args, filter.AgentID)
+		}
+	}
+	if filter.ChannelID > 0 {
+		conditions = append(conditions, "t.channel_id = ?")
+		args = append(args, filter.ChannelID)
+	}
+	if filter.BrandCode != "" {
+		conditions = append(conditions, "t.brand_code = ?")
+		args = append(args, filter.BrandCode)
+	}
+	if filter.ModelCode != "" {
+		conditions = append(conditions, "t.model_code = ?")
+		args = append(args, filter.ModelCode)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// GetStockSummary 按代理商/通道/品牌/型号汇总库存及未绑定库存账龄
+// cutoffs 为各账龄分段的起点时间（now-30天、now-60天、now-90天）
+func (r *GormInventoryReportRepository) GetStockSummary(filter *InventoryFilter, cutoffs [3]time.Time) ([]*InventoryStockRow, error) {
+	condition, args := r.terminalCondition(filter)
+	stock := models.TerminalStockStatuses
+
+	query := `
+		SELECT x.owner_agent_id AS agent_id, COALESCE(a.agent_name, '') AS agent_name,
+			x.channel_id, COALESCE(ch.channel_name, '') AS channel_name,
+			COALESCE(x.brand_code, '') AS brand_code, COALESCE(x.model_code, '') AS model_code,
+			COUNT(*) AS total_count,
+			COUNT(*) FILTER (WHERE x.status IN ?) AS stock_count,
+			COUNT(*) FILTER (WHERE x.status = ?) AS bound_inactive_count,
+			COUNT(*) FILTER (WHERE x.status = ?) AS activated_count,
+			COUNT(*) FILTER (WHERE x.status IN ? AND x.received_at > ?) AS age_0_30,
+			COUNT(*) FILTER (WHERE x.status IN ? AND x.received_at <= ? AND x.received_at > ?) AS age_30_60,
+			COUNT(*) FILTER (WHERE x.status IN ? AND x.received_at <= ? AND x.received_at > ?) AS age_60_90,
+			COUNT(*) FILTER (WHERE x.status IN ? AND x.received_at <= ?) AS age_90_plus
+		FROM (
+			SELECT t.owner_agent_id, t.channel_id, t.brand_code, t.model_code, t.status,
+				` + inventoryReceivedAtExpr + ` AS received_at
+			FROM terminals t
+			WHERE ` + condition + `
+		) x
+		LEFT JOIN agents a ON a.id = x.owner_agent_id
+		LEFT JOIN channels ch ON ch.id = x.channel_id
+		GROUP BY x.owner_agent_id, a.agent_name, x.channel_id, ch.channel_name, x.brand_code, x.model_code
+		ORDER BY stock_count DESC, x.owner_agent_id, x.channel_id, x.brand_code, x.model_code
+	`
+	queryArgs := []interface{}{
+		stock, models.TerminalStatusBound, models.TerminalStatusActivated,
+		stock, cutoffs[0],
+		stock, cutoffs[0], cutoffs[1],
+		stock, cutoffs[1], cutoffs[2],
+		stock, cutoffs[2],
+	}
+	queryArgs = append(queryArgs, args...)
+
+	var rows []*InventoryStockRow
+	err := r.db.Raw(query, queryArgs...).Scan(&rows).Error
+	return rows, err
+}
+
+// GetSubordinateStats 统计直属下级（含其全部下级）的库存与动销情况
+// parentID 为0时统计顶级代理商；staleBefore 之前到达的未绑定库存计为呆滞
+func (r *GormInventoryReportRepository) GetSubordinateStats(parentID, channelID int64, staleBefore time.Time) ([]*InventorySubordinateRow, error) {
+	stock := models.TerminalStockStatuses
+	terminalJoin := "t.owner_agent_id = h.descendant_id"
+	var joinArgs []interface{}
+	if channelID > 0 {
+		terminalJoin += " AND t.channel_id = ?"
+		joinArgs = append(joinArgs, channelID)
+	}
+
+	query := `
+		SELECT a.id AS agent_id, a.agent_name,
+			COUNT(t.id) AS total_count,
+			COUNT(t.id) FILTER (WHERE t.status IN ?) AS stock_count,
+			COUNT(t.id) FILTER (WHERE t.status = ?) AS bound_inactive_count,
+			COUNT(t.id) FILTER (WHERE t.status = ?) AS activated_count,
+			COUNT(t.id) FILTER (WHERE t.status IN ? AND ` + inventoryReceivedAtExpr + ` <= ?) AS stale_count
+		FROM agents a
+		LEFT JOIN agent_hierarchy h ON h.ancestor_id = a.id
+		LEFT JOIN terminals t ON ` + terminalJoin + `
+		WHERE COALESCE(a.parent_id, 0) = ?
+		GROUP BY a.id, a.agent_name
+		ORDER BY a.id
+	`
+	args := []interface{}{stock, models.TerminalStatusBound, models.TerminalStatusActivated, stock, staleBefore}
+	args = append(args, joinArgs...)
+	args = append(args, parentID)
+
+	var rows []*InventorySubordinateRow
+	err := r.db.Raw(query, args...).Scan(&rows).Error
+	return rows, err
+}
+
+// staleQuery 生成呆滞终端明细的子查询
+func (r *GormInventoryReportRepository) staleQuery(filter *InventoryFilter, kind string, before time.Time) (string, []interface{}) {
+	condition, args := r.terminalCondition(filter)
+	sinceExpr := inventoryReceivedAtExpr
+	statusCondition := "t.status IN ?"
+	var statusArg interface{} = models.TerminalStockStatuses
+	if kind == models.InventoryStaleKindBoundInactive {
+		sinceExpr = inventoryBoundAtExpr
+		statusCondition = "t.status = ?"
+		statusArg = models.TerminalStatusBound
+	}
+
+	query := `
+		SELECT * FROM (
+			SELECT t.id, t.terminal_sn, t.owner_agent_id AS agent_id, COALESCE(a.agent_name, '') AS agent_name,
+				t.channel_id, COALESCE(t.channel_code, '') AS channel_code,
+				COALESCE(t.brand_code, '') AS brand_code, COALESCE(t.model_code, '') AS model_code,
+				t.status, COALESCE(t.merchant_no, '') AS merchant_no,
+				` + sinceExpr + ` AS since_at
+			FROM terminals t
+			LEFT JOIN agents a ON a.id = t.owner_agent_id
+			WHERE ` + condition + ` AND ` + statusCondition + `
+		) x
+		WHERE x.since_at <= ?`
+	args = append(args, statusArg, before)
+	return query, args
+}
+
+// ListStaleTerminals 分页查询呆滞终端（按到达/绑定时间升序，最久的在前）
+func (r *GormInventoryReportRepository) ListStaleTerminals(filter *InventoryFilter, kind string, before time.Time, limit, offset int) ([]*InventoryStaleTerminal, int64, error) {
+	query, args := r.staleQuery(filter, kind, before)
+
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(*) FROM ("+query+") c", args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*InventoryStaleTerminal
+	if limit > 0 {
+		query += " ORDER BY x.since_at ASC, x.id ASC LIMIT ? OFFSET ?"
+		args = append(args, limit, offset)
+	} else {
+		query += " ORDER BY x.since_at ASC, x.id ASC"
+	}
+	err := r.db.Raw(query, args...).Scan(&list).Error
+	return list, total, err
+}
+
+// FindAgentsOverStaleThreshold 查询呆滞库存达到阈值的代理商（仅统计本人持有的库存）
+func (r *GormInventoryReportRepository) FindAgentsOverStaleThreshold(before time.Time, threshold int) ([]*InventoryAgentStale, error) {
+	query := `
+		SELECT x.owner_agent_id AS agent_id,
+			COUNT(*) AS stock_count,
+			COUNT(*) FILTER (WHERE x.received_at <= ?) AS stale_count
+		FROM (
+			SELECT t.owner_agent_id, ` + inventoryReceivedAtExpr + ` AS received_at
+			FROM terminals t
+			WHERE t.owner_agent_id > 0 AND t.status IN ?
+		) x
+		GROUP BY x.owner_agent_id
+		HAVING COUNT(*) FILTER (WHERE x.received_at <= ?) >= ?
+		ORDER BY stale_count DESC
+	`
+	var rows []*InventoryAgentStale
+	err := r.db.Raw(query, before, models.TerminalStockStatuses, before, threshold).Scan(&rows).Error
+	return rows, err
+}
+
+// GetAlertConfig 获取预警配置，不存在时返回nil
+func (r *GormInventoryReportRepository) GetAlertConfig() (*models.InventoryAlertConfig, error) {
+	var config models.InventoryAlertConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveAlertConfig 保存预警配置
+func (r *GormInventoryReportRepository) SaveAlertConfig(config *models.InventoryAlertConfig) error {
+	return r.db.Save(config).Error
+}
+
+// FindLastAlert 获取代理商最近一次预警，不存在时返回nil
+func (r *GormInventoryReportRepository) FindLastAlert(agentID int64) (*models.InventoryAlert, error) {
+	var alert models.InventoryAlert
+	err := r.db.Where("agent_id = ?", agentID).Order("alert_date DESC").First(&alert).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &alert, err
+}
+
+// CreateAlert 记录预警，同一代理商同一天已预警时返回false
+func (r *GormInventoryReportRepository) CreateAlert(alert *models.InventoryAlert) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListAlerts 分页查询预警记录，scopeAgentID 非零时仅查询该代理商及其下级
+func (r *GormInventoryReportRepository) ListAlerts(scopeAgentID int64, limit, offset int) ([]*models.InventoryAlert, int64, error) {
+	query := r.db.Model(&models.InventoryAlert{})
+	if scopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("agent_id"), scopeAgentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var alerts []*models.InventoryAlert
+	err := query.Order("alert_date DESC, id DESC").Limit(limit).Offset(offset).Find(&alerts).Error
+	return alerts, total, err
+}
@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormKycExpiryRepository 证件到期提醒仓库
+type GormKycExpiryRepository struct {
+	db *gorm.DB
+}
+
+// NewGormKycExpiryRepository 创建证件到期提醒仓库
+func NewGormKycExpiryRepository(db *gorm.DB) *GormKycExpiryRepository {
+	return &GormKycExpiryRepository{db: db}
+}
+
+// GetConfig 获取提醒配置，不存在时返回nil
+func (r *GormKycExpiryRepository) GetConfig() (*models.KycExpiryConfig, error) {
+	var config models.KycExpiryConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存提醒配置
+func (r *GormKycExpiryRepository) SaveConfig(config *models.KycExpiryConfig) error {
+	return r.db.Save(config).Error
+}
+
+// KycExpiringMerchant 证件即将到期或已过期的商户
+type KycExpiringMerchant struct {
+	ID            int64     `json:"id"`
+	MerchantNo    string    `json:"merchant_no"`
+	MerchantName  string    `json:"merchant_name"`
+	AgentID       int64     `json:"agent_id"`
+	IDCardEndDate time.Time `json:"id_card_end_date"`
+}
+
+// FindExpiringMerchants 按ID游标查询证件有效期截止不晚于until的正常商户（不含长期有效）
+func (r *GormKycExpiryRepository) FindExpiringMerchants(until time.Time, afterID int64, limit int) ([]*KycExpiringMerchant, error) {
+	var list []*KycExpiringMerchant
+	err := r.db.Table("merchants").
+		Select("id, merchant_no, merchant_name, agent_id, id_card_end_date").
+		Where("id > ? AND status = ? AND id_card_long_term = FALSE AND id_card_end_date IS NOT NULL AND id_card_end_date <= ?",
+			afterID, models.MerchantStatusActive, until).
+		Order("id ASC").
+		Limit(limit).
+		Scan(&list).Error
+	return list, err
+}
+
+// CreateReminder 记录提醒，同一到期日同一窗口已提醒过时返回false
+func (r *GormKycExpiryRepository) CreateReminder(reminder *models.KycExpiryReminder) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "merchant_id"}, {Name: "end_date"}, {Name: "window_days"}},
+		DoNothing: true,
+	}).Create(reminder)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// KycExpirySummary 证件到期分档统计
+type KycExpirySummary struct {
+	Total    int64 `json:"total"`
+	Expired  int64 `json:"expired"`
+	Within7  int64 `json:"within_7" gorm:"column:within_7"`
+	Within30 int64 `json:"within_30" gorm:"column:within_30"`
+	Within60 int64 `json:"within_60" gorm:"column:within_60"`
+	Valid    int64 `json:"valid"`
+	LongTerm int64 `json:"long_term"`
+	Missing  int64 `json:"missing"`
+}
+
+// KycExpiryFilter 证件到期查询条件
+type KycExpiryFilter struct {
+	Bucket       string // 分档，见models.KycBucket*
+	ScopeAgentID int64  // 代理商及下级的商户
+	AgentID      int64
+	Keyword      string // 商户号/商户名称
+}
+
+// kycBucketCondition 分档条件，today为当天零点
+func kycBucketCondition(bucket string, today time.Time) (string, []interface{}) {
+	day7, day30, day60 := today.AddDate(0, 0, 7), today.AddDate(0, 0, 30), today.AddDate(0, 0, 60)
+	switch bucket {
+	case models.KycBucketExpired:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date < ?", []interface{}{today}
+	case models.KycBucketWithin7:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date >= ? AND m.id_card_end_date <= ?", []interface{}{today, day7}
+	case models.KycBucketWithin30:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date > ? AND m.id_card_end_date <= ?", []interface{}{day7, day30}
+	case models.KycBucketWithin60:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date > ? AND m.id_card_end_date <= ?", []interface{}{day30, day60}
+	case models.KycBucketValid:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date > ?", []interface{}{day60}
+	case models.KycBucketLongTerm:
+		return "m.id_card_long_term = TRUE", nil
+	case models.KycBucketMissing:
+		return "m.id_card_long_term = FALSE AND m.id_card_end_date IS NULL", nil
+	}
+	return "", nil
+}
+
+// applyKycScope 商户范围条件（正常商户、代理商范围、关键字）
+func applyKycScope(query *gorm.DB, filter *KycExpiryFilter) *gorm.DB {
+	query = query.Where("m.status = ?", models.MerchantStatusActive)
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("m.agent_id"), filter.ScopeAgentID)
+	}
+	if filter.AgentID > 0 {
+		query = query.Where("m.agent_id = ?", filter.AgentID)
+	}
+	if filter.Keyword != "" {
+		like := "%" + filter.Keyword + "%"
+		query = query.Where("(m.merchant_no LIKE ? OR m.merchant_name LIKE ?)", like, like)
+	}
+	return query
+}
+
+// GetSummary 证件到期分档统计
+func (r *GormKycExpiryRepository) GetSummary(filter *KycExpiryFilter, today time.Time) (*KycExpirySummary, error) {
+	buckets := []string{
+		models.KycBucketExpired, models.KycBucketWithin7, models.KycBucketWithin30, models.KycBucketWithin60,
+		models.KycBucketValid, models.KycBucketLongTerm, models.KycBucketMissing,
+	}
+	selects := "COUNT(*) AS total"
+	var args []interface{}
+	for _, bucket := range buckets {
+		cond, condArgs := kycBucketCondition(bucket, today)
+		selects += ", COUNT(*) FILTER (WHERE " + cond + ") AS " + bucket
+		args = append(args, condArgs...)
+	}
+
+	var summary KycExpirySummary
+	query := r.db.Table("merchants m").Select(selects, args...)
+	err := applyKycScope(query, filter).Scan(&summary).Error
+	return &summary, err
+}
+
+// KycExpiryMerchant 证件到期商户明细
+type KycExpiryMerchant struct {
+	ID              int64      `json:"id"`
+	MerchantNo      string     `json:"merchant_no"`
+	MerchantName    string     `json:"merchant_name"`
+	AgentID         int64      `json:"agent_id"`
+	AgentName       string     `json:"agent_name"`
+	LegalName       string     `json:"legal_name"`
+	IDCardStartDate *time.Time `json:"id_card_start_date"`
+	IDCardEndDate   *time.Time `json:"id_card_end_date"`
+	IDCardLongTerm  bool       `json:"id_card_long_term"`
+	KycUpdatedAt    *time.Time `json:"kyc_updated_at"`
+}
+
+// ListMerchants 按分档分页查询商户，按到期日升序
+func (r *GormKycExpiryRepository) ListMerchants(filter *KycExpiryFilter, today time.Time, limit, offset int) ([]*KycExpiryMerchant, int64, error) {
+	query := applyKycScope(r.db.Table("merchants m"), filter)
+	if cond, args := kycBucketCondition(filter.Bucket, today); cond != "" {
+		query = query.Where(cond, args...)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*KycExpiryMerchant
+	err := query.
+		Select("m.id, m.merchant_no, m.merchant_name, m.agent_id, a.agent_name, m.legal_name, " +
+			"m.id_card_start_date, m.id_card_end_date, m.id_card_long_term, m.kyc_updated_at").
+		Joins("LEFT JOIN agents a ON a.id = m.agent_id").
+		Order("m.id_card_end_date ASC NULLS LAST, m.id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&list).Error
+	return list, total, err
+}
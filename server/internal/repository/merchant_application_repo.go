@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormMerchantApplicationRepository 商户进件申请仓库
+type GormMerchantApplicationRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMerchantApplicationRepository 创建商户进件申请仓库
+func NewGormMerchantApplicationRepository(db *gorm.DB) *GormMerchantApplicationRepository {
+	return &GormMerchantApplicationRepository{db: db}
+}
+
+// Create 创建申请
+func (r *GormMerchantApplicationRepository) Create(app *models.MerchantApplication) error {
+	return r.db.Create(app).Error
+}
+
+// Save 保存申请
+func (r *GormMerchantApplicationRepository) Save(app *models.MerchantApplication) error {
+	app.UpdatedAt = time.Now()
+	return r.db.Save(app).Error
+}
+
+// FindByID 根据ID查询申请，不存在时返回nil
+func (r *GormMerchantApplicationRepository) FindByID(id int64) (*models.MerchantApplication, error) {
+	var app models.MerchantApplication
+	err := r.db.First(&app, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &app, err
+}
+
+// FindOpenByTerminal 查询终端未结束（待提交/审核中）的申请，不存在时返回nil
+func (r *GormMerchantApplicationRepository) FindOpenByTerminal(channelCode, terminalSN string) (*models.MerchantApplication, error) {
+	var app models.MerchantApplication
+	err := r.db.Where("channel_code = ? AND terminal_sn = ? AND status IN ?", channelCode, terminalSN,
+		[]int16{models.ApplicationStatusPending, models.ApplicationStatusReviewing}).
+		Order("id DESC").
+		First(&app).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &app, err
+}
+
+// FindByMerchantNo 根据通道商户号查询最近的申请，不存在时返回nil
+func (r *GormMerchantApplicationRepository) FindByMerchantNo(channelCode, merchantNo string) (*models.MerchantApplication, error) {
+	var app models.MerchantApplication
+	err := r.db.Where("channel_code = ? AND merchant_no = ?", channelCode, merchantNo).
+		Order("id DESC").
+		First(&app).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &app, err
+}
+
+// FindReviewingToQuery 查询审核中且queriedBefore之后未查询过的申请（按最久未查询优先）
+func (r *GormMerchantApplicationRepository) FindReviewingToQuery(queriedBefore time.Time, limit int) ([]*models.MerchantApplication, error) {
+	var list []*models.MerchantApplication
+	err := r.db.Where("status = ? AND channel_apply_no <> '' AND (last_queried_at IS NULL OR last_queried_at < ?)",
+		models.ApplicationStatusReviewing, queriedBefore).
+		Order("last_queried_at ASC NULLS FIRST, id ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+// MerchantApplicationFilter 进件申请查询条件
+type MerchantApplicationFilter struct {
+	Status       int16
+	ChannelCode  string
+	Keyword      string // 申请单号/商户名称/终端SN/商户号
+	ScopeAgentID int64  // 非零时仅查询该代理商及下级的申请
+}
+
+// List 分页查询申请
+func (r *GormMerchantApplicationRepository) List(filter *MerchantApplicationFilter, limit, offset int) ([]*models.MerchantApplication, int64, error) {
+	query := r.db.Model(&models.MerchantApplication{})
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ChannelCode != "" {
+		query = query.Where("channel_code = ?", filter.ChannelCode)
+	}
+	if filter.Keyword != "" {
+		like := "%" + filter.Keyword + "%"
+		query = query.Where("(apply_no LIKE ? OR merchant_name LIKE ? OR terminal_sn LIKE ? OR merchant_no LIKE ?)",
+			like, like, like, like)
+	}
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("agent_id"), filter.ScopeAgentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.MerchantApplication
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
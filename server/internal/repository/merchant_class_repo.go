@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormMerchantClassRepository 商户分类规则仓库
+type GormMerchantClassRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMerchantClassRepository 创建商户分类规则仓库
+func NewGormMerchantClassRepository(db *gorm.DB) *GormMerchantClassRepository {
+	return &GormMerchantClassRepository{db: db}
+}
+
+// CreateRuleSet 创建规则集
+func (r *GormMerchantClassRepository) CreateRuleSet(set *models.MerchantClassRuleSet) error {
+	return r.db.Create(set).Error
+}
+
+// SaveRuleSet 保存规则集
+func (r *GormMerchantClassRepository) SaveRuleSet(set *models.MerchantClassRuleSet) error {
+	set.UpdatedAt = time.Now()
+	return r.db.Save(set).Error
+}
+
+// DeleteRuleSet 删除规则集
+func (r *GormMerchantClassRepository) DeleteRuleSet(id int64) error {
+	return r.db.Delete(&models.MerchantClassRuleSet{}, id).Error
+}
+
+// FindRuleSetByID 根据ID获取规则集，不存在时返回nil
+func (r *GormMerchantClassRepository) FindRuleSetByID(id int64) (*models.MerchantClassRuleSet, error) {
+	var set models.MerchantClassRuleSet
+	err := r.db.First(&set, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &set, err
+}
+
+// ListRuleSets 查询规则集，agentID/channelID为nil时不过滤
+func (r *GormMerchantClassRepository) ListRuleSets(agentID, channelID *int64, limit, offset int) ([]*models.MerchantClassRuleSet, int64, error) {
+	query := r.db.Model(&models.MerchantClassRuleSet{})
+	if agentID != nil {
+		query = query.Where("agent_id = ?", *agentID)
+	}
+	if channelID != nil {
+		query = query.Where("channel_id = ?", *channelID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sets []*models.MerchantClassRuleSet
+	err := query.Order("agent_id ASC, channel_id ASC, id ASC").Limit(limit).Offset(offset).Find(&sets).Error
+	return sets, total, err
+}
+
+// FindEnabledRuleSets 获取所有启用的规则集
+func (r *GormMerchantClassRepository) FindEnabledRuleSets() ([]*models.MerchantClassRuleSet, error) {
+	var sets []*models.MerchantClassRuleSet
+	err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&sets).Error
+	return sets, err
+}
+
+// ExistsEnabledScope 同一范围是否已有其他启用的规则集
+func (r *GormMerchantClassRepository) ExistsEnabledScope(agentID, channelID, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.MerchantClassRuleSet{}).
+		Where("agent_id = ? AND channel_id = ? AND enabled = ? AND id <> ?", agentID, channelID, true, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// MerchantClassTarget 待分类商户
+type MerchantClassTarget struct {
+	ID                int64  `json:"id"`
+	AgentID           int64  `json:"agent_id"`
+	ChannelID         int64  `json:"channel_id"`
+	MerchantType      string `json:"merchant_type"`
+	MerchantTypeLabel string `json:"merchant_type_label"`
+	AgentPath         string `json:"agent_path"`
+}
+
+// MerchantClassTargetFilter 待分类商户过滤条件
+type MerchantClassTargetFilter struct {
+	IDs          []int64 // 指定商户
+	ScopeAgentID int64   // 代理商及下级的商户
+	ChannelID    int64
+}
+
+// FindClassTargets 按ID升序查询正常状态的待分类商户
+func (r *GormMerchantClassRepository) FindClassTargets(filter *MerchantClassTargetFilter, afterID int64, limit int) ([]*MerchantClassTarget, error) {
+	query := r.db.Table("merchants m").
+		Select("m.id, m.agent_id, m.channel_id, m.merchant_type, m.merchant_type_label, COALESCE(a.path, '') AS agent_path").
+		Joins("LEFT JOIN agents a ON a.id = m.agent_id").
+		Where("m.status = ? AND m.id > ?", models.MerchantStatusActive, afterID)
+	if len(filter.IDs) > 0 {
+		query = query.Where("m.id IN ?", filter.IDs)
+	}
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("m.agent_id"), filter.ScopeAgentID)
+	}
+	if filter.ChannelID > 0 {
+		query = query.Where("m.channel_id = ?", filter.ChannelID)
+	}
+
+	var targets []*MerchantClassTarget
+	err := query.Order("m.id ASC").Limit(limit).Scan(&targets).Error
+	return targets, err
+}
+
+// MerchantClassMetrics 商户交易指标
+type MerchantClassMetrics struct {
+	MerchantID    int64 `json:"-"`
+	Amount30d     int64 `json:"amount_30d" gorm:"column:amount_30d"`
+	Amount60d     int64 `json:"amount_60d" gorm:"column:amount_60d"`
+	Amount90d     int64 `json:"amount_90d" gorm:"column:amount_90d"`
+	Count30d      int64 `json:"count_30d" gorm:"column:count_30d"`
+	Count60d      int64 `json:"count_60d" gorm:"column:count_60d"`
+	Count90d      int64 `json:"count_90d" gorm:"column:count_90d"`
+	ActiveDays30d int64 `json:"active_days_30d" gorm:"column:active_days_30d"`
+	ActiveDays60d int64 `json:"active_days_60d" gorm:"column:active_days_60d"`
+	ActiveDays90d int64 `json:"active_days_90d" gorm:"column:active_days_90d"`
+}
+
+// GetClassMetrics 批量统计商户截至endTime的30/60/90天交易指标，无交易的商户不在结果中
+func (r *GormMerchantClassRepository) GetClassMetrics(merchantIDs []int64, endTime time.Time) (map[int64]*MerchantClassMetrics, error) {
+	start30 := endTime.AddDate(0, 0, -30)
+	start60 := endTime.AddDate(0, 0, -60)
+	start90 := endTime.AddDate(0, 0, -90)
+
+	var rows []*MerchantClassMetrics
+	err := r.db.Table("transactions").
+		Select(`merchant_id,
+			COALESCE(SUM(amount) FILTER (WHERE trade_time >= ?), 0) AS amount_30d,
+			COALESCE(SUM(amount) FILTER (WHERE trade_time >= ?), 0) AS amount_60d,
+			COALESCE(SUM(amount), 0) AS amount_90d,
+			COUNT(*) FILTER (WHERE trade_time >= ?) AS count_30d,
+			COUNT(*) FILTER (WHERE trade_time >= ?) AS count_60d,
+			COUNT(*) AS count_90d,
+			COUNT(DISTINCT DATE(trade_time)) FILTER (WHERE trade_time >= ?) AS active_days_30d,
+			COUNT(DISTINCT DATE(trade_time)) FILTER (WHERE trade_time >= ?) AS active_days_60d,
+			COUNT(DISTINCT DATE(trade_time)) AS active_days_90d`,
+			start30, start60, start30, start60, start30, start60).
+		Where("merchant_id IN ? AND trade_time >= ? AND trade_time < ?", merchantIDs, start90, endTime).
+		Group("merchant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]*MerchantClassMetrics, len(rows))
+	for _, row := range rows {
+		result[row.MerchantID] = row
+	}
+	return result, nil
+}
+
+// ApplyClass 更新商户分类，history不为空时同时记录变更历史
+func (r *GormMerchantClassRepository) ApplyClass(merchantID int64, merchantType, label string, history *models.MerchantClassHistory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Merchant{}).Where("id = ?", merchantID).Updates(map[string]interface{}{
+			"merchant_type":       merchantType,
+			"merchant_type_label": label,
+			"updated_at":          time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if history == nil {
+			return nil
+		}
+		return tx.Create(history).Error
+	})
+}
+
+// ListHistory 查询商户分类变更历史（新到旧）
+func (r *GormMerchantClassRepository) ListHistory(merchantID int64, limit, offset int) ([]*models.MerchantClassHistory, int64, error) {
+	query := r.db.Model(&models.MerchantClassHistory{}).Where("merchant_id = ?", merchantID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.MerchantClassHistory
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
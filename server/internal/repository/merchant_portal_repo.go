@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormMerchantPortalRepository 商户自助服务仓库
+type GormMerchantPortalRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMerchantPortalRepository 创建商户自助服务仓库
+func NewGormMerchantPortalRepository(db *gorm.DB) *GormMerchantPortalRepository {
+	return &GormMerchantPortalRepository{db: db}
+}
+
+// ============================================================
+// 短信验证码
+// ============================================================
+
+// CreateSmsCode 创建验证码
+func (r *GormMerchantPortalRepository) CreateSmsCode(code *models.MerchantSmsCode) error {
+	return r.db.Create(code).Error
+}
+
+// UseSmsCodeAttempt 占用一次验证码尝试次数（条件更新），验证码已使用或次数已满时返回false
+func (r *GormMerchantPortalRepository) UseSmsCodeAttempt(id int64, maxAttempts int) (bool, error) {
+	result := r.db.Model(&models.MerchantSmsCode{}).
+		Where("id = ? AND used_at IS NULL AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkSmsCodeUsed 标记验证码已使用（条件更新），已被使用时返回false
+func (r *GormMerchantPortalRepository) MarkSmsCodeUsed(id int64, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.MerchantSmsCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindLatestSmsCode 查询商户最近一条验证码，不存在时返回nil
+func (r *GormMerchantPortalRepository) FindLatestSmsCode(merchantID int64, scene string) (*models.MerchantSmsCode, error) {
+	var code models.MerchantSmsCode
+	err := r.db.Where("merchant_id = ? AND scene = ?", merchantID, scene).
+		Order("id DESC").
+		First(&code).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &code, err
+}
+
+// CountSmsCodesSince 统计商户since之后发送的验证码数量
+func (r *GormMerchantPortalRepository) CountSmsCodesSince(merchantID int64, scene string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.MerchantSmsCode{}).
+		Where("merchant_id = ? AND scene = ? AND created_at >= ?", merchantID, scene, since).
+		Count(&count).Error
+	return count, err
+}
+
+// ============================================================
+// 刷新令牌
+// ============================================================
+
+// CreateRefreshToken 创建刷新令牌
+func (r *GormMerchantPortalRepository) CreateRefreshToken(token *models.MerchantRefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindRefreshToken 根据令牌哈希查询未过期的刷新令牌，不存在时返回nil
+func (r *GormMerchantPortalRepository) FindRefreshToken(tokenHash string) (*models.MerchantRefreshToken, error) {
+	var token models.MerchantRefreshToken
+	err := r.db.Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).First(&token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// ConsumeRefreshToken 删除未过期的刷新令牌，已被删除（如并发刷新）或已过期时返回false
+func (r *GormMerchantPortalRepository) ConsumeRefreshToken(tokenHash string) (bool, error) {
+	result := r.db.Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).Delete(&models.MerchantRefreshToken{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteRefreshToken 删除刷新令牌
+func (r *GormMerchantPortalRepository) DeleteRefreshToken(tokenHash string) error {
+	return r.db.Where("token_hash = ?", tokenHash).Delete(&models.MerchantRefreshToken{}).Error
+}
+
+// DeleteExpiredRefreshTokens 清理过期的刷新令牌
+func (r *GormMerchantPortalRepository) DeleteExpiredRefreshTokens() (int64, error) {
+	result := r.db.Where("expires_at < ?", time.Now()).Delete(&models.MerchantRefreshToken{})
+	return result.RowsAffected, result.Error
+}
+
+// ============================================================
+// 日结汇总与终端
+// ============================================================
+
+// MerchantDailySettlement 商户按交易日汇总（金额单位：分）
+type MerchantDailySettlement struct {
+	Date         string `json:"date"`
+	TradeCount   int64  `json:"trade_count"`
+	TradeAmount  int64  `json:"trade_amount"`
+	Fee          int64  `json:"fee"`
+	D0Fee        int64  `json:"d0_fee" gorm:"column:d0_fee"`
+	SettleAmount int64  `json:"settle_amount"` // 交易金额-手续费-D0手续费
+}
+
+// GetDailySettlements 按交易日汇总商户消费交易（不含已退款交易），按日期倒序
+func (r *GormMerchantPortalRepository) GetDailySettlements(merchantID int64, startDate, endDate time.Time) ([]*MerchantDailySettlement, error) {
+	var list []*MerchantDailySettlement
+	err := r.db.Table("transactions").
+		Select("TO_CHAR(DATE(trade_time), 'YYYY-MM-DD') AS date, COUNT(*) AS trade_count, "+
+			"COALESCE(SUM(amount), 0) AS trade_amount, COALESCE(SUM(fee), 0) AS fee, COALESCE(SUM(d0_fee), 0) AS d0_fee, "+
+			"COALESCE(SUM(amount - fee - d0_fee), 0) AS settle_amount").
+		Where("merchant_id = ? AND trade_type = 1 AND refund_status = 0 AND trade_time >= ? AND trade_time < ?",
+			merchantID, startDate, endDate).
+		Group("DATE(trade_time)").
+		Order("DATE(trade_time) DESC").
+		Scan(&list).Error
+	return list, err
+}
+
+// FindTerminalsByMerchant 查询商户绑定的终端
+func (r *GormMerchantPortalRepository) FindTerminalsByMerchant(merchantID int64, merchantNo string) ([]*models.Terminal, error) {
+	var list []*models.Terminal
+	err := r.db.Where("merchant_id = ? OR (merchant_no = ? AND merchant_no <> '')", merchantID, merchantNo).
+		Order("bound_at DESC NULLS LAST, id DESC").
+		Find(&list).Error
+	return list, err
+}
+
+// ============================================================
+// 结算卡变更申请
+// ============================================================
+
+// CreateSettleCardRequest 创建结算卡变更申请
+func (r *GormMerchantPortalRepository) CreateSettleCardRequest(req *models.MerchantSettleCardRequest) error {
+	return r.db.Create(req).Error
+}
+
+// SaveSettleCardRequest 保存结算卡变更申请
+func (r *GormMerchantPortalRepository) SaveSettleCardRequest(req *models.MerchantSettleCardRequest) error {
+	req.UpdatedAt = time.Now()
+	return r.db.Save(req).Error
+}
+
+// FindSettleCardRequest 根据ID查询申请，不存在时返回nil
+func (r *GormMerchantPortalRepository) FindSettleCardRequest(id int64) (*models.MerchantSettleCardRequest, error) {
+	var req models.MerchantSettleCardRequest
+	err := r.db.First(&req, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &req, err
+}
+
+// ExistsPendingSettleCardRequest 商户是否有待审核的申请
+func (r *GormMerchantPortalRepository) ExistsPendingSettleCardRequest(merchantID int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.MerchantSettleCardRequest{}).
+		Where("merchant_id = ? AND status = ?", merchantID, models.SettleCardRequestPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SettleCardRequestFilter 结算卡变更申请查询条件
+type SettleCardRequestFilter struct {
+	Status       int16
+	MerchantID   int64
+	ScopeAgentID int64 // 非零时仅查询该代理商及下级商户的申请
+}
+
+// ListSettleCardRequests 分页查询结算卡变更申请
+func (r *GormMerchantPortalRepository) ListSettleCardRequests(filter *SettleCardRequestFilter, limit, offset int) ([]*models.MerchantSettleCardRequest, int64, error) {
+	query := r.db.Model(&models.MerchantSettleCardRequest{})
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("agent_id"), filter.ScopeAgentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.MerchantSettleCardRequest
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
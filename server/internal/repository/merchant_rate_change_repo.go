@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormMerchantRateChangeRepository 商户费率修改申请仓库
+type GormMerchantRateChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMerchantRateChangeRepository 创建商户费率修改申请仓库
+func NewGormMerchantRateChangeRepository(db *gorm.DB) *GormMerchantRateChangeRepository {
+	return &GormMerchantRateChangeRepository{db: db}
+}
+
+// Create 创建申请
+func (r *GormMerchantRateChangeRepository) Create(req *models.MerchantRateChangeRequest) error {
+	return r.db.Create(req).Error
+}
+
+// Save 保存申请
+func (r *GormMerchantRateChangeRepository) Save(req *models.MerchantRateChangeRequest) error {
+	req.UpdatedAt = time.Now()
+	return r.db.Save(req).Error
+}
+
+// FindByID 根据ID查询申请，不存在时返回nil
+func (r *GormMerchantRateChangeRepository) FindByID(id int64) (*models.MerchantRateChangeRequest, error) {
+	var req models.MerchantRateChangeRequest
+	err := r.db.First(&req, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &req, err
+}
+
+// ExistsOpenByMerchant 商户是否有未结束的申请
+func (r *GormMerchantRateChangeRepository) ExistsOpenByMerchant(merchantID int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.MerchantRateChangeRequest{}).
+		Where("merchant_id = ? AND status IN ?", merchantID, []int16{
+			models.RateChangeStatusPending, models.RateChangeStatusSyncing, models.RateChangeStatusSyncFailed,
+		}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RateChangeFilter 申请查询条件
+type RateChangeFilter struct {
+	Status       int16
+	MerchantID   int64
+	ScopeAgentID int64 // 非零时仅查询该代理商及下级商户的申请，或由ApproverID审批的申请
+	ApproverID   int64
+	OnlyToMe     bool // 仅查询由ApproverID审批的申请
+}
+
+// List 分页查询申请
+func (r *GormMerchantRateChangeRepository) List(filter *RateChangeFilter, limit, offset int) ([]*models.MerchantRateChangeRequest, int64, error) {
+	query := r.db.Model(&models.MerchantRateChangeRequest{})
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.OnlyToMe {
+		query = query.Where("approver_agent_id = ?", filter.ApproverID)
+	} else if filter.ScopeAgentID > 0 {
+		query = query.Where("("+AgentSubtreeCondition("agent_id")+" OR approver_agent_id = ?)",
+			filter.ScopeAgentID, filter.ApproverID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.MerchantRateChangeRequest
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// AgentPolicyRate 代理商政策费率（百分比）
+type AgentPolicyRate struct {
+	CreditRate string `json:"credit_rate"`
+	DebitRate  string `json:"debit_rate"`
+}
+
+// FindAgentPolicyRate 查询代理商在通道下的政策费率，未配置时返回nil
+func (r *GormMerchantRateChangeRepository) FindAgentPolicyRate(agentID, channelID int64) (*AgentPolicyRate, error) {
+	var rates []*AgentPolicyRate
+	err := r.db.Table("agent_policies").
+		Select("credit_rate, debit_rate").
+		Where("agent_id = ? AND channel_id = ?", agentID, channelID).
+		Limit(1).
+		Scan(&rates).Error
+	if err != nil || len(rates) == 0 {
+		return nil, err
+	}
+	return rates[0], nil
+}
+
+// FindRetryableSyncLogs 查询到期可重试的费率申请同步日志
+func (r *GormMerchantRateChangeRepository) FindRetryableSyncLogs(now time.Time, limit int) ([]*models.RateSyncLog, error) {
+	var logs []*models.RateSyncLog
+	err := r.db.Where("request_id > 0 AND sync_status = ? AND retry_count < max_retries AND next_retry_at <= ?",
+		models.RateSyncStatusFailed, now).
+		Order("next_retry_at ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// RateMismatchFilter 费率不一致查询条件
+type RateMismatchFilter struct {
+	ScopeAgentID int64 // 非零时仅查询该代理商及下级的商户
+	ChannelID    int64
+}
+
+// RateMismatch 本地与通道费率不一致的商户
+type RateMismatch struct {
+	MerchantID        int64      `json:"merchant_id"`
+	MerchantNo        string     `json:"merchant_no"`
+	MerchantName      string     `json:"merchant_name"`
+	AgentID           int64      `json:"agent_id"`
+	ChannelID         int64      `json:"channel_id"`
+	LocalCreditRate   string     `json:"local_credit_rate"`
+	LocalDebitRate    string     `json:"local_debit_rate"`
+	ChannelCreditRate *string    `json:"channel_credit_rate"` // 通道最近回调的费率（已换算为小数形式）
+	ChannelDebitRate  *string    `json:"channel_debit_rate"`
+	ChannelReportedAt *time.Time `json:"channel_reported_at"`
+	SyncLogID         *int64     `json:"sync_log_id"` // 最近一次同步日志
+	SyncStatus        *int       `json:"sync_status"`
+	SyncError         *string    `json:"sync_error"`
+	SyncAt            *time.Time `json:"sync_at"`
+	Mismatch          string     `json:"mismatch"` // rate_diff通道回调费率不一致 sync_failed最近同步失败
+}
+
+// rateMismatchSQL 商户本地费率与通道最近回调费率、最近同步结果对比
+// 通道回调费率为百分比，换算为小数后比较；最近回调早于最近一次成功同步时以同步结果为准
+const rateMismatchSQL = `
+SELECT * FROM (
+	SELECT m.id AS merchant_id, m.merchant_no, m.merchant_name, m.agent_id, m.channel_id,
+		m.credit_rate::TEXT AS local_credit_rate, m.debit_rate::TEXT AS local_debit_rate,
+		(rc.credit_rate / 100)::DECIMAL(10,4)::TEXT AS channel_credit_rate,
+		(rc.debit_rate / 100)::DECIMAL(10,4)::TEXT AS channel_debit_rate,
+		rc.received_at AS channel_reported_at,
+		sl.id AS sync_log_id, sl.sync_status, sl.error_message AS sync_error, sl.updated_at AS sync_at,
+		CASE
+			WHEN sl.sync_status = 3 THEN 'sync_failed'
+			WHEN rc.id IS NOT NULL AND (sl.synced_at IS NULL OR rc.received_at > sl.synced_at)
+				AND ((rc.credit_rate IS NOT NULL AND (rc.credit_rate / 100)::DECIMAL(10,4) IS DISTINCT FROM m.credit_rate)
+					OR (rc.debit_rate IS NOT NULL AND (rc.debit_rate / 100)::DECIMAL(10,4) IS DISTINCT FROM m.debit_rate))
+				THEN 'rate_diff'
+			ELSE ''
+		END AS mismatch
+	FROM merchants m
+	LEFT JOIN LATERAL (
+		SELECT id, sync_status, error_message, updated_at, synced_at
+		FROM rate_sync_logs WHERE merchant_id = m.id ORDER BY id DESC LIMIT 1
+	) sl ON TRUE
+	LEFT JOIN LATERAL (
+		SELECT id, credit_rate, debit_rate, received_at
+		FROM rate_changes WHERE merchant_no = m.merchant_no ORDER BY received_at DESC LIMIT 1
+	) rc ON TRUE
+	WHERE (sl.id IS NOT NULL OR rc.id IS NOT NULL)
+		AND (? = 0 OR m.channel_id = ?)
+		AND (? = 0 OR m.agent_id IN (SELECT descendant_id FROM agent_hierarchy WHERE ancestor_id = ?))
+) t
+WHERE t.mismatch <> ''`
+
+// ListRateMismatches 分页查询本地与通道费率不一致的商户
+func (r *GormMerchantRateChangeRepository) ListRateMismatches(filter *RateMismatchFilter, limit, offset int) ([]*RateMismatch, int64, error) {
+	args := []interface{}{filter.ChannelID, filter.ChannelID, filter.ScopeAgentID, filter.ScopeAgentID}
+
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(*) FROM ("+rateMismatchSQL+") c", args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*RateMismatch
+	err := r.db.Raw(rateMismatchSQL+" ORDER BY merchant_id LIMIT ? OFFSET ?", append(args, limit, offset)...).
+		Scan(&list).Error
+	return list, total, err
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormMerchantRatePlanRepository 商户费率计划仓库
+type GormMerchantRatePlanRepository struct {
+	db *gorm.DB
+}
+
+// NewGormMerchantRatePlanRepository 创建商户费率计划仓库
+func NewGormMerchantRatePlanRepository(db *gorm.DB) *GormMerchantRatePlanRepository {
+	return &GormMerchantRatePlanRepository{db: db}
+}
+
+// CreateWithSteps 创建计划及步骤
+func (r *GormMerchantRatePlanRepository) CreateWithSteps(plan *models.MerchantRatePlan, steps []*models.MerchantRatePlanStep) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(plan).Error; err != nil {
+			return err
+		}
+		for _, step := range steps {
+			step.PlanID = plan.ID
+		}
+		return tx.Create(&steps).Error
+	})
+}
+
+// SavePlan 保存计划
+func (r *GormMerchantRatePlanRepository) SavePlan(plan *models.MerchantRatePlan) error {
+	plan.UpdatedAt = time.Now()
+	return r.db.Save(plan).Error
+}
+
+// SaveStep 保存步骤
+func (r *GormMerchantRatePlanRepository) SaveStep(step *models.MerchantRatePlanStep) error {
+	step.UpdatedAt = time.Now()
+	return r.db.Save(step).Error
+}
+
+// CancelPlan 取消计划及未执行的步骤
+func (r *GormMerchantRatePlanRepository) CancelPlan(plan *models.MerchantRatePlan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		plan.UpdatedAt = now
+		if err := tx.Save(plan).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.MerchantRatePlanStep{}).
+			Where("plan_id = ? AND status = ?", plan.ID, models.RatePlanStepPending).
+			Updates(map[string]interface{}{"status": models.RatePlanStepCancelled, "updated_at": now}).Error
+	})
+}
+
+// FindByID 根据ID查询计划，不存在时返回nil
+func (r *GormMerchantRatePlanRepository) FindByID(id int64) (*models.MerchantRatePlan, error) {
+	var plan models.MerchantRatePlan
+	err := r.db.First(&plan, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &plan, err
+}
+
+// FindActiveByMerchant 查询商户执行中的计划，不存在时返回nil
+func (r *GormMerchantRatePlanRepository) FindActiveByMerchant(merchantID int64) (*models.MerchantRatePlan, error) {
+	var plan models.MerchantRatePlan
+	err := r.db.Where("merchant_id = ? AND status = ?", merchantID, models.RatePlanStatusActive).
+		Order("id DESC").
+		First(&plan).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &plan, err
+}
+
+// FindSteps 查询计划步骤（按执行顺序）
+func (r *GormMerchantRatePlanRepository) FindSteps(planID int64) ([]*models.MerchantRatePlanStep, error) {
+	var steps []*models.MerchantRatePlanStep
+	err := r.db.Where("plan_id = ?", planID).Order("seq ASC").Find(&steps).Error
+	return steps, err
+}
+
+// FindActivePlanIDsWithOpenSteps 查询有到期待执行步骤或执行中步骤的计划
+func (r *GormMerchantRatePlanRepository) FindActivePlanIDsWithOpenSteps(now time.Time, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.db.Model(&models.MerchantRatePlanStep{}).
+		Joins("JOIN merchant_rate_plans p ON p.id = merchant_rate_plan_steps.plan_id").
+		Where("p.status = ?", models.RatePlanStatusActive).
+		Where("(merchant_rate_plan_steps.status = ? AND merchant_rate_plan_steps.effective_at <= ?) OR merchant_rate_plan_steps.status = ?",
+			models.RatePlanStepPending, now, models.RatePlanStepExecuting).
+		Distinct().
+		Limit(limit).
+		Pluck("merchant_rate_plan_steps.plan_id", &ids).Error
+	return ids, err
+}
+
+// FindStepsToRemind 查询即将执行且未提醒的步骤（首个步骤创建时即生效，不提醒）
+func (r *GormMerchantRatePlanRepository) FindStepsToRemind(now time.Time, limit int) ([]*models.MerchantRatePlanStep, error) {
+	var steps []*models.MerchantRatePlanStep
+	err := r.db.Model(&models.MerchantRatePlanStep{}).
+		Joins("JOIN merchant_rate_plans p ON p.id = merchant_rate_plan_steps.plan_id").
+		Where("p.status = ? AND merchant_rate_plan_steps.status = ? AND merchant_rate_plan_steps.reminded_at IS NULL",
+			models.RatePlanStatusActive, models.RatePlanStepPending).
+		Where("merchant_rate_plan_steps.effective_at > ?", now).
+		Where("merchant_rate_plan_steps.effective_at <= ? + p.remind_days * INTERVAL '1 day'", now).
+		Order("merchant_rate_plan_steps.effective_at ASC").
+		Limit(limit).
+		Find(&steps).Error
+	return steps, err
+}
+
+// RatePlanFilter 费率计划查询条件
+type RatePlanFilter struct {
+	Status       int16
+	MerchantID   int64
+	ScopeAgentID int64 // 非零时仅查询该代理商及下级商户的计划
+}
+
+// List 分页查询计划
+func (r *GormMerchantRatePlanRepository) List(filter *RatePlanFilter, limit, offset int) ([]*models.MerchantRatePlan, int64, error) {
+	query := r.db.Model(&models.MerchantRatePlan{})
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.ScopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition("agent_id"), filter.ScopeAgentID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []*models.MerchantRatePlan
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
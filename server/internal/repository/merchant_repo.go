@@ -198,6 +198,19 @@ func (r *GormMerchantRepository) UpdateApproveStatus(id int64, status int16) err
 		}).Error
 }
 
+// UpdateIDCardValidity 更新法人身份证有效期
+func (r *GormMerchantRepository) UpdateIDCardValidity(id int64, startDate, endDate *time.Time, longTerm bool) error {
+	now := time.Now()
+	return r.db.Model(&models.Merchant{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"id_card_start_date": startDate,
+			"id_card_end_date":   endDate,
+			"id_card_long_term":  longTerm,
+			"kyc_updated_at":     now,
+			"updated_at":         now,
+		}).Error
+}
+
 // UpdateRate 更新商户费率
 func (r *GormMerchantRepository) UpdateRate(id int64, creditRate, debitRate string) error {
 	return r.db.Model(&models.Merchant{}).Where("id = ?", id).
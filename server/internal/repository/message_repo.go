@@ -129,8 +129,8 @@ func (r *GormMessageRepository) GetStatsByAgentID(agentID int64) (*MessageStats,
 		return nil, err
 	}
 
-	// 系统类消息数（类型5,6）
-	systemTypes := []int16{models.MessageTypeRefund, models.MessageTypeAnnouncement}
+	// 系统类消息数（类型5,6及业务通知类）
+	systemTypes := models.GetMessageTypesByCategory(models.MessageCategorySystem)
 	if err := r.db.Model(&models.Message{}).Where("agent_id = ? AND message_type IN ?", agentID, systemTypes).Count(&stats.SystemCount).Error; err != nil {
 		return nil, err
 	}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormRewardProgressRuleRepository 终端奖励进度自动化规则仓库
+type GormRewardProgressRuleRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRewardProgressRuleRepository 创建终端奖励进度自动化规则仓库
+func NewGormRewardProgressRuleRepository(db *gorm.DB) *GormRewardProgressRuleRepository {
+	return &GormRewardProgressRuleRepository{db: db}
+}
+
+// GetConfig 获取自动化规则，不存在时返回nil
+func (r *GormRewardProgressRuleRepository) GetConfig() (*models.RewardProgressConfig, error) {
+	var config models.RewardProgressConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存自动化规则
+func (r *GormRewardProgressRuleRepository) SaveConfig(config *models.RewardProgressConfig) error {
+	return r.db.Save(config).Error
+}
+
+// CreateAssignment 创建模版适用范围
+func (r *GormRewardProgressRuleRepository) CreateAssignment(assignment *models.RewardTemplateAssignment) error {
+	return r.db.Create(assignment).Error
+}
+
+// SaveAssignment 保存模版适用范围
+func (r *GormRewardProgressRuleRepository) SaveAssignment(assignment *models.RewardTemplateAssignment) error {
+	return r.db.Save(assignment).Error
+}
+
+// FindAssignmentByID 根据ID获取模版适用范围
+func (r *GormRewardProgressRuleRepository) FindAssignmentByID(id int64) (*models.RewardTemplateAssignment, error) {
+	var assignment models.RewardTemplateAssignment
+	if err := r.db.First(&assignment, id).Error; err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// DeleteAssignment 删除模版适用范围
+func (r *GormRewardProgressRuleRepository) DeleteAssignment(id int64) error {
+	return r.db.Delete(&models.RewardTemplateAssignment{}, id).Error
+}
+
+// ListAssignments 分页查询模版适用范围，agentID/channelID/templateID为nil时不过滤
+func (r *GormRewardProgressRuleRepository) ListAssignments(agentID, channelID, templateID *int64, limit, offset int) ([]*models.RewardTemplateAssignment, int64, error) {
+	var list []*models.RewardTemplateAssignment
+	var total int64
+
+	query := r.db.Model(&models.RewardTemplateAssignment{})
+	if agentID != nil {
+		query = query.Where("agent_id = ?", *agentID)
+	}
+	if channelID != nil {
+		query = query.Where("channel_id = ?", *channelID)
+	}
+	if templateID != nil {
+		query = query.Where("template_id = ?", *templateID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("agent_id ASC, channel_id ASC, priority DESC, id DESC").
+		Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// FindEnabledAssignments 查找指定代理商链路、通道下可用的适用范围（模版须为启用状态）
+func (r *GormRewardProgressRuleRepository) FindEnabledAssignments(agentIDs []int64, channelID int64) ([]*models.RewardTemplateAssignment, error) {
+	var list []*models.RewardTemplateAssignment
+	err := r.db.Table("reward_template_assignments a").
+		Select("a.*").
+		Joins("JOIN reward_policy_templates t ON t.id = a.template_id AND t.enabled = TRUE").
+		Where("a.enabled = TRUE AND a.agent_id IN ? AND a.channel_id IN ?", agentIDs, []int64{channelID, 0}).
+		Find(&list).Error
+	return list, err
+}
+
+// CountProgress 统计终端的进行中及全部奖励进度数
+func (r *GormRewardProgressRuleRepository) CountProgress(terminalSN string) (int64, int64, error) {
+	var result struct {
+		Active int64
+		Total  int64
+	}
+	err := r.db.Model(&models.TerminalRewardProgress{}).
+		Select("COUNT(*) FILTER (WHERE status = ?) AS active, COUNT(*) AS total", models.RewardProgressStatusActive).
+		Where("terminal_sn = ?", terminalSN).
+		Scan(&result).Error
+	return result.Active, result.Total, err
+}
+
+// FindTerminalsWithoutProgress 按ID顺序查找处于指定状态、且没有进行中奖励进度的终端
+// withoutAny 为true时要求终端从未有过奖励进度
+func (r *GormRewardProgressRuleRepository) FindTerminalsWithoutProgress(statuses []int16, withoutAny bool, afterID int64, limit int) ([]*models.Terminal, error) {
+	progressCond := "p.terminal_sn = terminals.terminal_sn"
+	args := []interface{}{}
+	if !withoutAny {
+		progressCond += " AND p.status = ?"
+		args = append(args, models.RewardProgressStatusActive)
+	}
+
+	var list []*models.Terminal
+	err := r.db.Model(&models.Terminal{}).
+		Where("status IN ? AND id > ?", statuses, afterID).
+		Where("NOT EXISTS (SELECT 1 FROM terminal_reward_progress p WHERE "+progressCond+")", args...).
+		Order("id ASC").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormSimCardRepository 流量卡仓库
+type GormSimCardRepository struct {
+	db *gorm.DB
+}
+
+// NewGormSimCardRepository 创建流量卡仓库
+func NewGormSimCardRepository(db *gorm.DB) *GormSimCardRepository {
+	return &GormSimCardRepository{db: db}
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormSimCardRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+// GetConfig 获取续费预测及提醒配置，不存在时返回nil
+func (r *GormSimCardRepository) GetConfig() (*models.SimCardConfig, error) {
+	var config models.SimCardConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存续费预测及提醒配置
+func (r *GormSimCardRepository) SaveConfig(config *models.SimCardConfig) error {
+	return r.db.Save(config).Error
+}
+
+// BatchCreate 批量创建流量卡
+func (r *GormSimCardRepository) BatchCreate(sims []*models.SimCard) error {
+	return r.db.CreateInBatches(sims, 100).Error
+}
+
+// Save 保存流量卡
+func (r *GormSimCardRepository) Save(sim *models.SimCard) error {
+	sim.UpdatedAt = time.Now()
+	return r.db.Save(sim).Error
+}
+
+// FindByID 根据ID获取流量卡，不存在时返回nil
+func (r *GormSimCardRepository) FindByID(id int64) (*models.SimCard, error) {
+	var sim models.SimCard
+	err := r.db.First(&sim, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &sim, err
+}
+
+// FindByICCID 根据ICCID获取流量卡，不存在时返回nil
+func (r *GormSimCardRepository) FindByICCID(iccid string) (*models.SimCard, error) {
+	var sim models.SimCard
+	err := r.db.Where("iccid = ?", iccid).First(&sim).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &sim, err
+}
+
+// FindExistingICCIDs 返回已存在的ICCID
+func (r *GormSimCardRepository) FindExistingICCIDs(iccids []string) ([]string, error) {
+	var existing []string
+	err := r.db.Model(&models.SimCard{}).Where("iccid IN ?", iccids).Pluck("iccid", &existing).Error
+	return existing, err
+}
+
+// FindByTerminalSN 获取终端当前装的流量卡，不存在时返回nil
+func (r *GormSimCardRepository) FindByTerminalSN(terminalSN string) (*models.SimCard, error) {
+	var sim models.SimCard
+	err := r.db.Where("terminal_sn = ? AND status = ?", terminalSN, models.SimCardStatusInstalled).First(&sim).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &sim, err
+}
+
+// SimCardFilter 流量卡查询条件
+type SimCardFilter struct {
+	ICCID        string
+	TerminalSN   string
+	Carrier      string
+	Status       int16
+	ChannelID    int64
+	RenewalAfter *time.Time // 预计续费时间下限
+	RenewalUntil *time.Time // 预计续费时间上限
+}
+
+// List 分页查询流量卡，scopeAgentID非零时只返回该代理商及下级持有或装在其终端上的流量卡
+func (r *GormSimCardRepository) List(filter *SimCardFilter, scopeAgentID int64, limit, offset int) ([]*models.SimCard, int64, error) {
+	query := r.db.Model(&models.SimCard{})
+	if scopeAgentID > 0 {
+		query = query.Where(AgentSubtreeCondition(
+			"COALESCE((SELECT t.owner_agent_id FROM terminals t WHERE t.id = sim_cards.terminal_id), sim_cards.owner_agent_id)"),
+			scopeAgentID)
+	}
+	if filter.ICCID != "" {
+		query = query.Where("iccid LIKE ?", filter.ICCID+"%")
+	}
+	if filter.TerminalSN != "" {
+		query = query.Where("terminal_sn = ?", filter.TerminalSN)
+	}
+	if filter.Carrier != "" {
+		query = query.Where("carrier = ?", filter.Carrier)
+	}
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ChannelID > 0 {
+		query = query.Where("channel_id = ?", filter.ChannelID)
+	}
+	if filter.RenewalAfter != nil {
+		query = query.Where("next_renewal_at >= ?", *filter.RenewalAfter)
+	}
+	if filter.RenewalUntil != nil {
+		query = query.Where("next_renewal_at <= ?", *filter.RenewalUntil)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sims []*models.SimCard
+	order := "id DESC"
+	if filter.RenewalUntil != nil {
+		order = "next_renewal_at ASC, id ASC"
+	}
+	err := query.Order(order).Limit(limit).Offset(offset).Find(&sims).Error
+	return sims, total, err
+}
+
+// FindBindings 获取流量卡的装卡记录（新到旧）
+func (r *GormSimCardRepository) FindBindings(simCardID int64) ([]*models.SimCardBinding, error) {
+	var bindings []*models.SimCardBinding
+	err := r.db.Where("sim_card_id = ?", simCardID).Order("bound_at DESC, id DESC").Find(&bindings).Error
+	return bindings, err
+}
+
+// FindBindingsByTerminal 获取终端的装卡记录（新到旧）
+func (r *GormSimCardRepository) FindBindingsByTerminal(terminalSN string) ([]*models.SimCardBinding, error) {
+	var bindings []*models.SimCardBinding
+	err := r.db.Where("terminal_sn = ?", terminalSN).Order("bound_at DESC, id DESC").Find(&bindings).Error
+	return bindings, err
+}
+
+// FindBindingAt 获取流量卡在指定时间所在终端的装卡记录，不存在时返回nil
+func (r *GormSimCardRepository) FindBindingAt(simCardID int64, at time.Time) (*models.SimCardBinding, error) {
+	var binding models.SimCardBinding
+	err := r.db.Where("sim_card_id = ? AND bound_at <= ? AND (unbound_at IS NULL OR unbound_at > ?)", simCardID, at, at).
+		Order("bound_at DESC").First(&binding).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &binding, err
+}
+
+// FindTerminalBindingAt 获取终端在指定时间所装流量卡的装卡记录，不存在时返回nil
+func (r *GormSimCardRepository) FindTerminalBindingAt(terminalSN string, at time.Time) (*models.SimCardBinding, error) {
+	var binding models.SimCardBinding
+	err := r.db.Where("terminal_sn = ? AND bound_at <= ? AND (unbound_at IS NULL OR unbound_at > ?)", terminalSN, at, at).
+		Order("bound_at DESC").First(&binding).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &binding, err
+}
+
+// SimRenewalDue 待提醒续费的流量卡
+type SimRenewalDue struct {
+	SimCardID     int64     `json:"sim_card_id"`
+	ICCID         string    `json:"iccid" gorm:"column:iccid"`
+	TerminalSN    string    `json:"terminal_sn"`
+	OwnerAgentID  int64     `json:"owner_agent_id"` // 终端所属代理商
+	NextRenewalAt time.Time `json:"next_renewal_at"`
+}
+
+// FindDueRenewals 查找预计续费时间早于before、本期未提醒的已装机流量卡
+func (r *GormSimCardRepository) FindDueRenewals(before time.Time, limit int) ([]*SimRenewalDue, error) {
+	var rows []*SimRenewalDue
+	err := r.db.Table("sim_cards s").
+		Select("s.id AS sim_card_id, s.iccid, s.terminal_sn, t.owner_agent_id, s.next_renewal_at").
+		Joins("JOIN terminals t ON t.id = s.terminal_id").
+		Where("s.status = ? AND s.next_renewal_at IS NOT NULL AND s.next_renewal_at <= ?", models.SimCardStatusInstalled, before).
+		Where("(s.reminded_renewal_at IS NULL OR s.reminded_renewal_at <> s.next_renewal_at)").
+		Order("s.next_renewal_at ASC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// MarkReminded 标记流量卡本期续费已提醒
+func (r *GormSimCardRepository) MarkReminded(ids []int64) error {
+	return r.db.Model(&models.SimCard{}).Where("id IN ?", ids).
+		Update("reminded_renewal_at", gorm.Expr("next_renewal_at")).Error
+}
+
+// FindUnpredicted 查找尚无续费预测、但已可按激活时间预测的已装机流量卡
+func (r *GormSimCardRepository) FindUnpredicted(limit int) ([]*models.SimCard, error) {
+	var sims []*models.SimCard
+	err := r.db.Model(&models.SimCard{}).
+		Where("status = ? AND next_renewal_at IS NULL", models.SimCardStatusInstalled).
+		Where("(activated_at IS NOT NULL OR expire_at IS NOT NULL OR EXISTS (SELECT 1 FROM terminals t WHERE t.id = sim_cards.terminal_id AND t.activated_at IS NOT NULL))").
+		Order("id ASC").
+		Limit(limit).
+		Find(&sims).Error
+	return sims, err
+}
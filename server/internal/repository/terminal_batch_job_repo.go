@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormTerminalBatchJobRepository 终端批量任务仓库
+type GormTerminalBatchJobRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTerminalBatchJobRepository 创建仓库
+func NewGormTerminalBatchJobRepository(db *gorm.DB) *GormTerminalBatchJobRepository {
+	return &GormTerminalBatchJobRepository{db: db}
+}
+
+// Create 创建任务
+func (r *GormTerminalBatchJobRepository) Create(job *models.TerminalBatchJob) error {
+	return r.db.Create(job).Error
+}
+
+// Save 保存任务
+func (r *GormTerminalBatchJobRepository) Save(job *models.TerminalBatchJob) error {
+	return r.db.Save(job).Error
+}
+
+// FindByID 获取任务，不存在时返回nil
+func (r *GormTerminalBatchJobRepository) FindByID(id int64) (*models.TerminalBatchJob, error) {
+	var job models.TerminalBatchJob
+	err := r.db.First(&job, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &job, err
+}
+
+// Claim 将待处理任务标记为处理中，返回是否抢占成功（防止重复执行）
+func (r *GormTerminalBatchJobRepository) Claim(id int64, now time.Time) (bool, error) {
+	result := r.db.Model(&models.TerminalBatchJob{}).
+		Where("id = ? AND status = ?", id, models.TerminalBatchJobStatusPending).
+		Updates(map[string]interface{}{
+			"status":     models.TerminalBatchJobStatusProcessing,
+			"started_at": now,
+			"updated_at": now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindPendingBefore 获取创建时间早于指定时间仍未处理的任务ID（服务重启后补处理）
+func (r *GormTerminalBatchJobRepository) FindPendingBefore(before time.Time, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.db.Model(&models.TerminalBatchJob{}).
+		Where("status = ? AND created_at < ?", models.TerminalBatchJobStatusPending, before).
+		Order("id ASC").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FailStale 将长时间处于处理中的任务标记为失败（处理进程中断）
+func (r *GormTerminalBatchJobRepository) FailStale(before time.Time, message string) (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&models.TerminalBatchJob{}).
+		Where("status = ? AND started_at < ?", models.TerminalBatchJobStatusProcessing, before).
+		Updates(map[string]interface{}{
+			"status":        models.TerminalBatchJobStatusFailed,
+			"error_message": message,
+			"finished_at":   now,
+			"updated_at":    now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// List 分页获取任务（不含行数据与错误明细）
+func (r *GormTerminalBatchJobRepository) List(agentID int64, jobType string, limit, offset int) ([]*models.TerminalBatchJob, int64, error) {
+	query := r.db.Model(&models.TerminalBatchJob{})
+	if agentID > 0 {
+		query = query.Where("agent_id = ?", agentID)
+	}
+	if jobType != "" {
+		query = query.Where("job_type = ?", jobType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []*models.TerminalBatchJob
+	err := query.Omit("rows", "errors").Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// UpdateProgress 更新处理进度
+func (r *GormTerminalBatchJobRepository) UpdateProgress(id int64, successCount, failedCount int) error {
+	return r.db.Model(&models.TerminalBatchJob{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"success_count": successCount,
+		"failed_count":  failedCount,
+		"updated_at":    time.Now(),
+	}).Error
+}
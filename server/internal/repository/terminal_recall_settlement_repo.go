@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormTerminalRecallSettlementRepository 终端回拨结算仓库
+type GormTerminalRecallSettlementRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTerminalRecallSettlementRepository 创建终端回拨结算仓库
+func NewGormTerminalRecallSettlementRepository(db *gorm.DB) *GormTerminalRecallSettlementRepository {
+	return &GormTerminalRecallSettlementRepository{db: db}
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormTerminalRecallSettlementRepository) GetDB() *gorm.DB {
+	return r.db
+}
+
+// GetConfig 获取回拨结算规则，不存在时返回nil
+func (r *GormTerminalRecallSettlementRepository) GetConfig() (*models.TerminalRecallSettlementConfig, error) {
+	var config models.TerminalRecallSettlementConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存回拨结算规则
+func (r *GormTerminalRecallSettlementRepository) SaveConfig(config *models.TerminalRecallSettlementConfig) error {
+	return r.db.Save(config).Error
+}
+
+// FindItemsByRecallID 获取回拨的结算明细
+func (r *GormTerminalRecallSettlementRepository) FindItemsByRecallID(recallID int64) ([]*models.TerminalRecallSettlementItem, error) {
+	var items []*models.TerminalRecallSettlementItem
+	err := r.db.Where("recall_id = ?", recallID).Order("id ASC").Find(&items).Error
+	return items, err
+}
+
+// UpdateItem 更新结算明细
+func (r *GormTerminalRecallSettlementRepository) UpdateItem(id int64, updates map[string]interface{}) error {
+	return r.db.Model(&models.TerminalRecallSettlementItem{}).Where("id = ?", id).Updates(updates).Error
+}
@@ -15,8 +15,6 @@ type TerminalRepository interface {
 	FindByID(id int64) (*models.Terminal, error)
 	FindBySN(terminalSN string) (*models.Terminal, error)
 	FindByOwner(ownerAgentID int64, status []int16, limit, offset int) ([]*models.Terminal, int64, error)
-	UpdateOwner(id int64, newOwnerID int64) error
-	UpdateStatus(id int64, status int16) error
 	UpdateSimFeeCount(id int64, count int) error
 	FindActivatedAfter(channelID int64, activatedAfter time.Time) ([]*models.Terminal, error)
 	CountByTypeCode(channelID int64, brandCode, modelCode string) (int64, error)
@@ -106,25 +104,6 @@ func (r *GormTerminalRepository) FindByOwner(ownerAgentID int64, status []int16,
 	return terminals, total, err
 }
 
-func (r *GormTerminalRepository) UpdateOwner(id int64, newOwnerID int64) error {
-	return r.db.Model(&models.Terminal{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"owner_agent_id": newOwnerID,
-			"status":         models.TerminalStatusAllocated,
-			"updated_at":     time.Now(),
-		}).Error
-}
-
-func (r *GormTerminalRepository) UpdateStatus(id int64, status int16) error {
-	return r.db.Model(&models.Terminal{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"status":     status,
-			"updated_at": time.Now(),
-		}).Error
-}
-
 func (r *GormTerminalRepository) UpdateSimFeeCount(id int64, count int) error {
 	now := time.Now()
 	return r.db.Model(&models.Terminal{}).
@@ -639,11 +618,11 @@ func (r *GormTerminalRepository) FindByOwnerWithFilter(params TerminalFilterPara
 		// 未出库: Status=1
 		query = query.Where("status = ?", models.TerminalStatusPending)
 	case "stocked":
-		// 已出库: Status=2
-		query = query.Where("status = ?", models.TerminalStatusAllocated)
+		// 已出库: Status=2/5/6（已分配、已解绑、已回收）
+		query = query.Where("status IN ?", models.TerminalStockStatuses)
 	case "unbound":
-		// 未绑定: Status=2 且 MerchantID=null
-		query = query.Where("status = ? AND merchant_id IS NULL", models.TerminalStatusAllocated)
+		// 未绑定: Status=2/5/6 且 MerchantID=null
+		query = query.Where("status IN ? AND merchant_id IS NULL", models.TerminalStockStatuses)
 	case "inactive":
 		// 未激活: Status=3 且 ActivatedAt=null
 		query = query.Where("status = ? AND activated_at IS NULL", models.TerminalStatusBound)
@@ -723,12 +702,12 @@ func (r *GormTerminalRepository) GetStatusGroupCounts(ownerAgentID int64, channe
 
 	// 已出库
 	var stockedCount int64
-	r.db.Model(&models.Terminal{}).Where("owner_agent_id = ? AND status = ?", ownerAgentID, models.TerminalStatusAllocated).Count(&stockedCount)
+	r.db.Model(&models.Terminal{}).Where("owner_agent_id = ? AND status IN ?", ownerAgentID, models.TerminalStockStatuses).Count(&stockedCount)
 	counts = append(counts, StatusGroupCount{Key: "stocked", Label: "已出库", Count: stockedCount})
 
 	// 未绑定
 	var unboundCount int64
-	r.db.Model(&models.Terminal{}).Where("owner_agent_id = ? AND status = ? AND merchant_id IS NULL", ownerAgentID, models.TerminalStatusAllocated).Count(&unboundCount)
+	r.db.Model(&models.Terminal{}).Where("owner_agent_id = ? AND status IN ? AND merchant_id IS NULL", ownerAgentID, models.TerminalStockStatuses).Count(&unboundCount)
 	counts = append(counts, StatusGroupCount{Key: "unbound", Label: "未绑定", Count: unboundCount})
 
 	// 未激活
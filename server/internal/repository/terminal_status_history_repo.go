@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormTerminalStatusHistoryRepository 终端状态流转记录仓库
+type GormTerminalStatusHistoryRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTerminalStatusHistoryRepository 创建仓库
+func NewGormTerminalStatusHistoryRepository(db *gorm.DB) *GormTerminalStatusHistoryRepository {
+	return &GormTerminalStatusHistoryRepository{db: db}
+}
+
+// FindBySN 按SN获取状态流转记录（按发生时间从早到晚）
+func (r *GormTerminalStatusHistoryRepository) FindBySN(terminalSN string, limit, offset int) ([]*models.TerminalStatusHistory, int64, error) {
+	query := r.db.Model(&models.TerminalStatusHistory{}).Where("terminal_sn = ?", terminalSN)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var histories []*models.TerminalStatusHistory
+	err := query.Order("created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&histories).Error
+	return histories, total, err
+}
+
+// FindSNsBySource 按来源单据获取涉及的终端SN（如入库批次），按记录顺序去重
+func (r *GormTerminalStatusHistoryRepository) FindSNsBySource(sourceType, sourceNo string) ([]string, error) {
+	var sns []string
+	err := r.db.Model(&models.TerminalStatusHistory{}).
+		Select("terminal_sn").
+		Where("source_type = ? AND source_no = ?", sourceType, sourceNo).
+		Group("terminal_sn").
+		Order("MIN(id) ASC").
+		Pluck("terminal_sn", &sns).Error
+	return sns, err
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormTerminalStatusHistoryRepository) GetDB() *gorm.DB {
+	return r.db
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormTxRiskRepository 交易风控仓库
+type GormTxRiskRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTxRiskRepository 创建交易风控仓库
+func NewGormTxRiskRepository(db *gorm.DB) *GormTxRiskRepository {
+	return &GormTxRiskRepository{db: db}
+}
+
+// GetConfig 获取风控配置，不存在时返回nil
+func (r *GormTxRiskRepository) GetConfig() (*models.TxRiskConfig, error) {
+	var config models.TxRiskConfig
+	err := r.db.Order("id ASC").First(&config).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &config, err
+}
+
+// SaveConfig 保存风控配置
+func (r *GormTxRiskRepository) SaveConfig(config *models.TxRiskConfig) error {
+	return r.db.Save(config).Error
+}
+
+// CountSameCardTrades 统计商户同一脱敏卡号在[since, until]内的消费笔数
+func (r *GormTxRiskRepository) CountSameCardTrades(merchantID int64, cardNo string, since, until time.Time) (int64, error) {
+	var count int64
+	err := r.db.Table("transactions").
+		Where("merchant_id = ? AND card_no = ? AND trade_type = 1 AND trade_time >= ? AND trade_time <= ?",
+			merchantID, cardNo, since, until).
+		Count(&count).Error
+	return count, err
+}
+
+// CountMerchantTrades 统计商户在[since, until]内的消费笔数
+func (r *GormTxRiskRepository) CountMerchantTrades(merchantID int64, since, until time.Time) (int64, error) {
+	var count int64
+	err := r.db.Table("transactions").
+		Where("merchant_id = ? AND trade_type = 1 AND trade_time >= ? AND trade_time <= ?", merchantID, since, until).
+		Count(&count).Error
+	return count, err
+}
+
+// MerchantTradeHistory 商户历史消费统计
+type MerchantTradeHistory struct {
+	TradeCount int64 `json:"trade_count"`
+	AvgAmount  int64 `json:"avg_amount"` // 笔均金额（分）
+}
+
+// GetMerchantTradeHistory 统计商户在[since, until)内除指定交易外的消费笔数及笔均金额
+func (r *GormTxRiskRepository) GetMerchantTradeHistory(merchantID, excludeTxID int64, since, until time.Time) (*MerchantTradeHistory, error) {
+	var history MerchantTradeHistory
+	err := r.db.Table("transactions").
+		Select("COUNT(*) AS trade_count, COALESCE(AVG(amount), 0)::BIGINT AS avg_amount").
+		Where("merchant_id = ? AND id <> ? AND trade_type = 1 AND trade_time >= ? AND trade_time < ?",
+			merchantID, excludeTxID, since, until).
+		Scan(&history).Error
+	return &history, err
+}
+
+// TxRiskMerchant 风控检测用商户信息
+type TxRiskMerchant struct {
+	ID           int64  `json:"id"`
+	MerchantNo   string `json:"merchant_no"`
+	MerchantName string `json:"merchant_name"`
+	LegalIDCard  string `json:"legal_id_card"`
+}
+
+// FindMerchant 查询商户信息，不存在时返回nil
+func (r *GormTxRiskRepository) FindMerchant(merchantID int64) (*TxRiskMerchant, error) {
+	var merchants []*TxRiskMerchant
+	err := r.db.Model(&models.Merchant{}).
+		Select("id, merchant_no, merchant_name, legal_id_card").
+		Where("id = ?", merchantID).
+		Limit(1).
+		Scan(&merchants).Error
+	if err != nil || len(merchants) == 0 {
+		return nil, err
+	}
+	return merchants[0], nil
+}
+
+// CountMerchantsByLegalID 统计同一法人身份证名下的正常商户数
+func (r *GormTxRiskRepository) CountMerchantsByLegalID(legalIDCard string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Merchant{}).
+		Where("legal_id_card = ? AND status = ?", legalIDCard, models.MerchantStatusActive).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateEvent 创建风控事件，同一交易已有事件时不重复创建，返回是否新建
+func (r *GormTxRiskRepository) CreateEvent(event *models.TxRiskEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "transaction_id"}},
+		DoNothing: true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindEventByID 根据ID查询风控事件，不存在时返回nil
+func (r *GormTxRiskRepository) FindEventByID(id int64) (*models.TxRiskEvent, error) {
+	var event models.TxRiskEvent
+	err := r.db.First(&event, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &event, err
+}
+
+// SaveEvent 保存风控事件
+func (r *GormTxRiskRepository) SaveEvent(event *models.TxRiskEvent) error {
+	return r.db.Save(event).Error
+}
+
+// TxRiskEventFilter 风控事件查询条件
+type TxRiskEventFilter struct {
+	Status     int16
+	MerchantID int64
+	AgentID    int64
+	Rule       string
+	MinScore   int
+	Held       *bool
+	StartTime  *time.Time
+	EndTime    *time.Time
+}
+
+// ListEvents 分页查询风控事件
+func (r *GormTxRiskRepository) ListEvents(filter *TxRiskEventFilter, limit, offset int) ([]*models.TxRiskEvent, int64, error) {
+	query := r.db.Model(&models.TxRiskEvent{})
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", filter.MerchantID)
+	}
+	if filter.AgentID > 0 {
+		query = query.Where("agent_id = ?", filter.AgentID)
+	}
+	if filter.Rule != "" {
+		query = query.Where("hits @> ?::jsonb", `[{"rule":"`+filter.Rule+`"}]`)
+	}
+	if filter.MinScore > 0 {
+		query = query.Where("score >= ?", filter.MinScore)
+	}
+	if filter.Held != nil {
+		query = query.Where("held = ?", *filter.Held)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at < ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.TxRiskEvent
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error
+	return events, total, err
+}
@@ -98,6 +98,43 @@ func (r *GormWalletAdjustmentRepository) UpdateWalletLogID(id int64, walletLogID
 		Update("wallet_log_id", walletLogID).Error
 }
 
+// FindActiveReversal 查找原调账对应的待审批或已生效冲正单
+func (r *GormWalletAdjustmentRepository) FindActiveReversal(originalID int64) (*models.WalletAdjustment, error) {
+	var adjustment models.WalletAdjustment
+	err := r.db.Where("reversal_of_id = ? AND status IN ?", originalID,
+		[]int16{models.AdjustmentStatusPending, models.AdjustmentStatusEffective}).
+		First(&adjustment).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &adjustment, err
+}
+
+// GetApprovalThreshold 获取审批金额配置（walletType=0为通用配置）
+func (r *GormWalletAdjustmentRepository) GetApprovalThreshold(walletType int16) (*models.WalletAdjustmentApprovalThreshold, error) {
+	var threshold models.WalletAdjustmentApprovalThreshold
+	err := r.db.Where("wallet_type = ?", walletType).First(&threshold).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &threshold, err
+}
+
+// ListApprovalThresholds 获取全部审批金额配置
+func (r *GormWalletAdjustmentRepository) ListApprovalThresholds() ([]*models.WalletAdjustmentApprovalThreshold, error) {
+	var thresholds []*models.WalletAdjustmentApprovalThreshold
+	err := r.db.Order("wallet_type ASC").Find(&thresholds).Error
+	return thresholds, err
+}
+
+// SaveApprovalThreshold 保存审批金额配置
+func (r *GormWalletAdjustmentRepository) SaveApprovalThreshold(threshold *models.WalletAdjustmentApprovalThreshold) error {
+	if threshold.ID == 0 {
+		return r.db.Create(threshold).Error
+	}
+	return r.db.Save(threshold).Error
+}
+
 // GetDB 获取数据库连接（用于事务）
 func (r *GormWalletAdjustmentRepository) GetDB() *gorm.DB {
 	return r.db
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormWalletBalanceCheckRepository GORM实现的钱包余额检查仓库
+type GormWalletBalanceCheckRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWalletBalanceCheckRepository 创建仓库
+func NewGormWalletBalanceCheckRepository(db *gorm.DB) *GormWalletBalanceCheckRepository {
+	return &GormWalletBalanceCheckRepository{db: db}
+}
+
+// Create 创建检查记录
+func (r *GormWalletBalanceCheckRepository) Create(check *models.WalletBalanceCheck) error {
+	return r.db.Create(check).Error
+}
+
+// GetByID 根据ID获取检查记录
+func (r *GormWalletBalanceCheckRepository) GetByID(id int64) (*models.WalletBalanceCheck, error) {
+	var check models.WalletBalanceCheck
+	err := r.db.First(&check, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &check, err
+}
+
+// FindLatestClosed 获取钱包最近一次已关闭的检查（一致/已采纳/已忽略）
+func (r *GormWalletBalanceCheckRepository) FindLatestClosed(walletID int64) (*models.WalletBalanceCheck, error) {
+	var check models.WalletBalanceCheck
+	err := r.db.Where("wallet_id = ? AND status IN ?", walletID, []int16{
+		models.BalanceCheckStatusConsistent,
+		models.BalanceCheckStatusAccepted,
+		models.BalanceCheckStatusDismissed,
+	}).Order("checked_at DESC, id DESC").First(&check).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &check, err
+}
+
+// HasPending 钱包是否存在待处理的检查
+func (r *GormWalletBalanceCheckRepository) HasPending(walletID int64) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.WalletBalanceCheck{}).
+		Where("wallet_id = ? AND status = ?", walletID, models.BalanceCheckStatusPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SupersedePending 将钱包待处理的检查置为已失效
+func (r *GormWalletBalanceCheckRepository) SupersedePending(walletID int64) error {
+	return r.db.Model(&models.WalletBalanceCheck{}).
+		Where("wallet_id = ? AND status = ?", walletID, models.BalanceCheckStatusPending).
+		Updates(map[string]interface{}{
+			"status":     models.BalanceCheckStatusSuperseded,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Close 关闭待处理的检查（采纳/忽略），仅当状态仍为待处理时更新
+func (r *GormWalletBalanceCheckRepository) Close(id int64, status int16, adjustmentID *int64, handledBy int64, handledByName, remark string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.WalletBalanceCheck{}).
+		Where("id = ? AND status = ?", id, models.BalanceCheckStatusPending).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"adjustment_id":   adjustmentID,
+			"handled_by":      handledBy,
+			"handled_by_name": handledByName,
+			"handled_at":      now,
+			"handle_remark":   remark,
+			"updated_at":      now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Reopen 将已采纳但调账创建失败的检查恢复为待处理
+func (r *GormWalletBalanceCheckRepository) Reopen(id int64) error {
+	return r.db.Model(&models.WalletBalanceCheck{}).
+		Where("id = ? AND status = ? AND adjustment_id IS NULL", id, models.BalanceCheckStatusAccepted).
+		Updates(map[string]interface{}{
+			"status":          models.BalanceCheckStatusPending,
+			"handled_by":      nil,
+			"handled_by_name": "",
+			"handled_at":      nil,
+			"handle_remark":   "",
+			"updated_at":      time.Now(),
+		}).Error
+}
+
+// SetAdjustmentID 关联修正调账
+func (r *GormWalletBalanceCheckRepository) SetAdjustmentID(id int64, adjustmentID int64) error {
+	return r.db.Model(&models.WalletBalanceCheck{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"adjustment_id": adjustmentID,
+			"updated_at":    time.Now(),
+		}).Error
+}
+
+// WalletBalanceCheckQueryParams 检查记录查询参数
+type WalletBalanceCheckQueryParams struct {
+	WalletID  int64
+	AgentID   int64
+	Status    *int16
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
+
+// List 查询检查记录
+func (r *GormWalletBalanceCheckRepository) List(params *WalletBalanceCheckQueryParams) ([]*models.WalletBalanceCheck, int64, error) {
+	query := r.db.Model(&models.WalletBalanceCheck{})
+
+	if params.WalletID > 0 {
+		query = query.Where("wallet_id = ?", params.WalletID)
+	}
+	if params.AgentID > 0 {
+		query = query.Where("agent_id = ?", params.AgentID)
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+	if params.StartTime != nil {
+		query = query.Where("checked_at >= ?", *params.StartTime)
+	}
+	if params.EndTime != nil {
+		query = query.Where("checked_at < ?", *params.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var checks []*models.WalletBalanceCheck
+	err := query.Order("checked_at DESC, id DESC").
+		Limit(params.Limit).
+		Offset(params.Offset).
+		Find(&checks).Error
+	return checks, total, err
+}
+
+// FindWalletIDsAfter 按ID分页获取钱包ID
+func (r *GormWalletBalanceCheckRepository) FindWalletIDsAfter(lastID int64, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.db.Model(&Wallet{}).
+		Where("id > ?", lastID).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// WalletLedgerSources 钱包回放所需的原始数据
+type WalletLedgerSources struct {
+	Since           *time.Time // 回放起点，空表示全部历史
+	CheckedAt       time.Time  // 快照时间，作为下次检查的回放起点
+	Wallet          *Wallet
+	Logs            []*WalletLog
+	ProfitRecords   []*ProfitRecord
+	Adjustments     []*models.WalletAdjustment
+	DeducteeRecords []*models.DeductionRecord // 从本钱包扣款的代扣记录
+	DeductorRecords []*models.DeductionRecord // 转入本钱包的代扣收款记录
+}
+
+// LoadLedgerSources 在同一只读快照内加载钱包及其回放数据
+// since 为空时加载全部历史；分润记录仅包含交易分润（该路径入账不写钱包流水）
+// 快照时间在事务内取数据库时间，保证本次回放的数据与下次检查的起点首尾相接、不重复
+func (r *GormWalletBalanceCheckRepository) LoadLedgerSources(walletID int64, since *time.Time) (*WalletLedgerSources, error) {
+	sources := &WalletLedgerSources{Since: since}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 第一条语句建立快照，now()为本事务开始时间
+		if err := tx.Raw("SELECT now()").Scan(&sources.CheckedAt).Error; err != nil {
+			return err
+		}
+
+		var wallet Wallet
+		if err := tx.First(&wallet, walletID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("钱包不存在")
+			}
+			return err
+		}
+		sources.Wallet = &wallet
+
+		logQuery := tx.Where("wallet_id = ?", walletID)
+		if since != nil {
+			logQuery = logQuery.Where("created_at > ?", *since)
+		}
+		if err := logQuery.Order("created_at ASC, id ASC").Find(&sources.Logs).Error; err != nil {
+			return err
+		}
+
+		profitQuery := tx.Where("agent_id = ? AND channel_id = ? AND wallet_type = ? AND profit_type = 1",
+			wallet.AgentID, wallet.ChannelID, wallet.WalletType)
+		if since != nil {
+			profitQuery = profitQuery.Where("(created_at > ? OR (is_revoked = ? AND revoked_at > ?))", *since, true, *since)
+		}
+		if err := profitQuery.Order("id ASC").Find(&sources.ProfitRecords).Error; err != nil {
+			return err
+		}
+
+		adjQuery := tx.Where("wallet_id = ? AND status IN ?", walletID,
+			[]int16{models.AdjustmentStatusEffective, models.AdjustmentStatusReversed})
+		if since != nil {
+			adjQuery = adjQuery.Where("COALESCE(approved_at, created_at) > ?", *since)
+		}
+		if err := adjQuery.Order("id ASC").Find(&sources.Adjustments).Error; err != nil {
+			return err
+		}
+
+		// 由货款代扣明细转换的记录对应的流水是 goods_deduction_detail，不参与代扣记录核对
+		deducteeQuery := tx.Where("deductee_id = ? AND actual_amount > 0 AND legacy_detail_id IS NULL AND wallet_details @> ?::jsonb",
+			wallet.AgentID, fmt.Sprintf(`[{"wallet_id": %d}]`, walletID))
+		if since != nil {
+			deducteeQuery = deducteeQuery.Where("deducted_at > ?", *since)
+		}
+		if err := deducteeQuery.Order("id ASC").Find(&sources.DeducteeRecords).Error; err != nil {
+			return err
+		}
+
+		// 代扣收款固定转入扣款方通道1的分润钱包
+		if wallet.ChannelID == 1 && wallet.WalletType == 1 {
+			deductorQuery := tx.Where("deductor_id = ? AND actual_amount > 0 AND legacy_detail_id IS NULL", wallet.AgentID)
+			if since != nil {
+				deductorQuery = deductorQuery.Where("deducted_at > ?", *since)
+			}
+			if err := deductorQuery.Order("id ASC").Find(&sources.DeductorRecords).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
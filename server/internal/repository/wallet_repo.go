@@ -1,8 +1,7 @@
 package repository
 
 import (
-	"fmt"
-	"strings"
+	"strconv"
 	"time"
 
 	"xiangshoufu/internal/models"
@@ -181,12 +180,13 @@ func (r *GormWalletLogRepository) FindByAgentID(agentID int64, walletID int64, l
 
 // GormAgentRepository GORM实现的代理商仓库
 type GormAgentRepository struct {
-	db *gorm.DB
+	db        *gorm.DB
+	hierarchy HierarchyRepository
 }
 
-// NewGormAgentRepository 创建仓库
-func NewGormAgentRepository(db *gorm.DB) *GormAgentRepository {
-	return &GormAgentRepository{db: db}
+// NewGormAgentRepository 创建仓库，上级链和上下级判断走层级仓库
+func NewGormAgentRepository(db *gorm.DB, hierarchy HierarchyRepository) *GormAgentRepository {
+	return &GormAgentRepository{db: db, hierarchy: hierarchy}
 }
 
 // FindByID 根据ID查找代理商
@@ -209,37 +209,10 @@ func (r *GormAgentRepository) FindByAgentNo(agentNo string) (*Agent, error) {
 	return &agent, nil
 }
 
-// FindAncestors 查找所有上级代理商（通过物化路径）
+// FindAncestors 查找上级代理商链（通过层级闭包表，单次查询）
+// 与原物化路径实现保持一致：按层级从顶级到自身排序，结果包含自身
 func (r *GormAgentRepository) FindAncestors(agentID int64) ([]*Agent, error) {
-	// 先获取当前代理商
-	agent, err := r.FindByID(agentID)
-	if err != nil {
-		return nil, err
-	}
-
-	// 解析物化路径获取所有上级ID
-	// 路径格式: /1/5/12/
-	if agent.Path == "" || agent.Path == "/" {
-		return []*Agent{}, nil
-	}
-
-	// 去除首尾斜杠，分割
-	pathStr := strings.Trim(agent.Path, "/")
-	if pathStr == "" {
-		return []*Agent{}, nil
-	}
-
-	// 查询所有上级（不包括自己）
-	var ancestors []*Agent
-	err = r.db.Raw(`
-		SELECT * FROM agents
-		WHERE id IN (
-			SELECT unnest(string_to_array(?, '/'))::bigint
-		)
-		ORDER BY level ASC
-	`, pathStr).Scan(&ancestors).Error
-
-	return ancestors, err
+	return r.hierarchy.FindAncestors(agentID, true)
 }
 
 // GetAllAgentIDs 获取所有活跃代理商的ID（用于消息广播）
@@ -358,11 +331,9 @@ func (r *GormAgentRepository) Update(agent *AgentFull) error {
 	return r.db.Save(agent).Error
 }
 
-// FindAllByParentPath 查找所有下级（通过路径匹配）
+// FindAllByParentPath 查找所有下级（通过层级闭包表，含自身）
 func (r *GormAgentRepository) FindAllByParentPath(parentID int64, limit, offset int) ([]*AgentFull, int64, error) {
-	pathPattern := fmt.Sprintf("%%/%d/%%", parentID)
-
-	query := r.db.Model(&AgentFull{}).Where("path LIKE ?", pathPattern)
+	query := r.db.Model(&AgentFull{}).Where(AgentSubtreeCondition("id"), parentID)
 
 	var total int64
 	query.Count(&total)
@@ -377,6 +348,27 @@ func (r *GormAgentRepository) Create(agent *AgentFull) error {
 	return r.db.Create(agent).Error
 }
 
+// CreateWithHierarchy 事务内创建代理商：写入代理商后按新ID回填物化路径及fill设置的字段，再写入层级闭包表
+// parentPath为上级的物化路径，顶级代理商传空；任一步失败整体回滚，不会留下没有层级记录的代理商
+func (r *GormAgentRepository) CreateWithHierarchy(agent *AgentFull, parentPath string, fill func(agent *AgentFull)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(agent).Error; err != nil {
+			return err
+		}
+		if parentPath == "" {
+			parentPath = "/"
+		}
+		agent.Path = parentPath + strconv.FormatInt(agent.ID, 10) + "/"
+		if fill != nil {
+			fill(agent)
+		}
+		if err := tx.Save(agent).Error; err != nil {
+			return err
+		}
+		return insertNodeLinks(tx, agent.ID, agent.ParentID)
+	})
+}
+
 // FindByPhone 根据手机号查找代理商
 func (r *GormAgentRepository) FindByPhone(phone string) (*AgentFull, error) {
 	var agent AgentFull
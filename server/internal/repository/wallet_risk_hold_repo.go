@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormWalletRiskHoldRepository GORM实现的钱包风控冻结仓库
+type GormWalletRiskHoldRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWalletRiskHoldRepository 创建仓库
+func NewGormWalletRiskHoldRepository(db *gorm.DB) *GormWalletRiskHoldRepository {
+	return &GormWalletRiskHoldRepository{db: db}
+}
+
+// Create 创建冻结记录
+func (r *GormWalletRiskHoldRepository) Create(hold *models.WalletRiskHold) error {
+	return r.db.Create(hold).Error
+}
+
+// GetByID 根据ID获取冻结记录
+func (r *GormWalletRiskHoldRepository) GetByID(id int64) (*models.WalletRiskHold, error) {
+	var hold models.WalletRiskHold
+	err := r.db.First(&hold, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &hold, err
+}
+
+// FindEffectiveByAgent 查询代理商当前生效的冻结（未解除且未到期）
+func (r *GormWalletRiskHoldRepository) FindEffectiveByAgent(agentID int64, now time.Time) ([]*models.WalletRiskHold, error) {
+	var holds []*models.WalletRiskHold
+	err := r.db.Where("agent_id = ? AND status = ? AND (expire_at IS NULL OR expire_at > ?)",
+		agentID, models.RiskHoldStatusActive, now).
+		Order("id ASC").
+		Find(&holds).Error
+	return holds, err
+}
+
+// FindActiveByCaseRef 查询关联指定风控案件且未解除的冻结
+func (r *GormWalletRiskHoldRepository) FindActiveByCaseRef(caseRef string) ([]*models.WalletRiskHold, error) {
+	var holds []*models.WalletRiskHold
+	err := r.db.Where("case_ref = ? AND status = ?", caseRef, models.RiskHoldStatusActive).
+		Order("id ASC").
+		Find(&holds).Error
+	return holds, err
+}
+
+// WalletRiskHoldQueryParams 冻结查询参数
+type WalletRiskHoldQueryParams struct {
+	AgentID   int64
+	Status    *int16
+	Scope     *int16
+	CaseRef   string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
+
+// List 查询冻结列表
+func (r *GormWalletRiskHoldRepository) List(params *WalletRiskHoldQueryParams) ([]*models.WalletRiskHold, int64, error) {
+	query := r.db.Model(&models.WalletRiskHold{})
+
+	if params.AgentID > 0 {
+		query = query.Where("agent_id = ?", params.AgentID)
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+	if params.Scope != nil {
+		query = query.Where("scope = ?", *params.Scope)
+	}
+	if params.CaseRef != "" {
+		query = query.Where("case_ref LIKE ?", "%"+params.CaseRef+"%")
+	}
+	if params.StartTime != nil {
+		query = query.Where("created_at >= ?", *params.StartTime)
+	}
+	if params.EndTime != nil {
+		query = query.Where("created_at < ?", *params.EndTime)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var holds []*models.WalletRiskHold
+	err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Offset).Find(&holds).Error
+	return holds, total, err
+}
+
+// FindExpiredActive 查找已到期但未解除的冻结
+func (r *GormWalletRiskHoldRepository) FindExpiredActive(now time.Time, limit int) ([]*models.WalletRiskHold, error) {
+	var holds []*models.WalletRiskHold
+	err := r.db.Where("status = ? AND expire_at IS NOT NULL AND expire_at <= ?", models.RiskHoldStatusActive, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&holds).Error
+	return holds, err
+}
+
+// Release 解除冻结（仅冻结中的记录可解除）
+func (r *GormWalletRiskHoldRepository) Release(id int64, status int16, releasedBy *int64, releasedByName, reason string, now time.Time) (bool, error) {
+	result := r.db.Model(&models.WalletRiskHold{}).
+		Where("id = ? AND status = ?", id, models.RiskHoldStatusActive).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"released_by":      releasedBy,
+			"released_by_name": releasedByName,
+			"released_at":      now,
+			"release_reason":   reason,
+			"updated_at":       now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
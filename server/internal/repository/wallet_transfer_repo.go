@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"time"
+
+	"xiangshoufu/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// GormWalletTransferRepository GORM实现的钱包划转仓库
+type GormWalletTransferRepository struct {
+	db *gorm.DB
+}
+
+// NewGormWalletTransferRepository 创建仓库
+func NewGormWalletTransferRepository(db *gorm.DB) *GormWalletTransferRepository {
+	return &GormWalletTransferRepository{db: db}
+}
+
+// Create 创建划转记录
+func (r *GormWalletTransferRepository) Create(transfer *models.WalletTransfer) error {
+	return r.db.Create(transfer).Error
+}
+
+// GetByID 根据ID获取划转记录
+func (r *GormWalletTransferRepository) GetByID(id int64) (*models.WalletTransfer, error) {
+	var transfer models.WalletTransfer
+	err := r.db.First(&transfer, id).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &transfer, err
+}
+
+// WalletTransferQueryParams 划转查询参数
+type WalletTransferQueryParams struct {
+	AgentID   int64  // 与该代理商相关（转出或转入）
+	Direction string // out=转出 in=转入 空=全部
+	Status    *int16
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Offset    int
+}
+
+// List 查询划转列表
+func (r *GormWalletTransferRepository) List(params *WalletTransferQueryParams) ([]*models.WalletTransfer, int64, error) {
+	query := r.db.Model(&models.WalletTransfer{})
+
+	if params.AgentID > 0 {
+		switch params.Direction {
+		case "out":
+			query = query.Where("from_agent_id = ?", params.AgentID)
+		case "in":
+			query = query.Where("to_agent_id = ?", params.AgentID)
+		default:
+			query = query.Where("from_agent_id = ? OR to_agent_id = ?", params.AgentID, params.AgentID)
+		}
+	}
+	if params.Status != nil {
+		query = query.Where("status = ?", *params.Status)
+	}
+	if params.StartTime != nil {
+		query = query.Where("created_at >= ?", *params.StartTime)
+	}
+	if params.EndTime != nil {
+		query = query.Where("created_at < ?", *params.EndTime)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var transfers []*models.WalletTransfer
+	err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Offset).Find(&transfers).Error
+	return transfers, total, err
+}
+
+// GetDailyOutStats 获取代理商当日转出统计（待确认+已完成）
+func (r *GormWalletTransferRepository) GetDailyOutStats(agentID int64, day time.Time) (amount int64, count int64, err error) {
+	return r.GetDailyOutStatsTx(r.db, agentID, day)
+}
+
+// GetDailyOutStatsTx 在事务内获取代理商当日转出统计（待确认+已完成）
+func (r *GormWalletTransferRepository) GetDailyOutStatsTx(tx *gorm.DB, agentID int64, day time.Time) (amount int64, count int64, err error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	var result struct {
+		Amount int64
+		Count  int64
+	}
+	err = tx.Model(&models.WalletTransfer{}).
+		Select("COALESCE(SUM(amount), 0) AS amount, COUNT(*) AS count").
+		Where("from_agent_id = ? AND status IN ? AND created_at >= ? AND created_at < ?",
+			agentID, []int16{models.TransferStatusPending, models.TransferStatusCompleted}, start, end).
+		Scan(&result).Error
+	return result.Amount, result.Count, err
+}
+
+// FindExpiredPending 查找已过期的待确认划转
+func (r *GormWalletTransferRepository) FindExpiredPending(now time.Time, limit int) ([]*models.WalletTransfer, error) {
+	var transfers []*models.WalletTransfer
+	err := r.db.Where("status = ? AND expire_at IS NOT NULL AND expire_at < ?", models.TransferStatusPending, now).
+		Order("id ASC").
+		Limit(limit).
+		Find(&transfers).Error
+	return transfers, err
+}
+
+// GetLimit 获取限额配置（agentID=0为全局默认）
+func (r *GormWalletTransferRepository) GetLimit(agentID int64) (*models.WalletTransferLimit, error) {
+	var limit models.WalletTransferLimit
+	err := r.db.Where("agent_id = ?", agentID).First(&limit).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	return &limit, err
+}
+
+// SaveLimit 保存限额配置
+func (r *GormWalletTransferRepository) SaveLimit(limit *models.WalletTransferLimit) error {
+	if limit.ID == 0 {
+		return r.db.Create(limit).Error
+	}
+	return r.db.Save(limit).Error
+}
+
+// GetDB 获取数据库连接（用于事务）
+func (r *GormWalletTransferRepository) GetDB() *gorm.DB {
+	return r.db
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// maxAgentLevel 代理商最大层级（与创建代理商的限制一致）
+const maxAgentLevel = 10
+
+// ErrAgentMoveConflict 存在须先处理的迁移冲突
+var ErrAgentMoveConflict = errors.New("存在须先处理的冲突，请调整后重新迁移")
+
+// AgentMoveService 代理商迁移服务
+// 平台将代理商连同整棵下级树挂到新上级：
+//   - 迁移代理商的结算价、政策不能优于新上级（费率不低于、返现不高于），下级之间的约束不受影响
+//   - 一方在树内、一方在树外的待确认终端下发须先确认或拒绝（确认时按原层级生成代扣链）
+//   - 跨越边界的代扣计划/代扣链属于已产生的债务，迁移后按原计划继续执行，仅作提示
+//
+// 支持预览（dry_run），执行时在事务内重写下级树的path/level并写入迁移记录
+type AgentMoveService struct {
+	agentRepo           *repository.GormAgentRepository
+	hierarchyRepo       repository.HierarchyRepository
+	moveRepo            *repository.GormAgentMoveRepository
+	settlementPriceRepo *repository.GormSettlementPriceRepository
+	agentPolicyRepo     *repository.GormAgentPolicyRepository
+}
+
+// NewAgentMoveService 创建代理商迁移服务
+func NewAgentMoveService(
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	moveRepo *repository.GormAgentMoveRepository,
+	settlementPriceRepo *repository.GormSettlementPriceRepository,
+	agentPolicyRepo *repository.GormAgentPolicyRepository,
+) *AgentMoveService {
+	return &AgentMoveService{
+		agentRepo:           agentRepo,
+		hierarchyRepo:       hierarchyRepo,
+		moveRepo:            moveRepo,
+		settlementPriceRepo: settlementPriceRepo,
+		agentPolicyRepo:     agentPolicyRepo,
+	}
+}
+
+// MoveAgentRequest 迁移代理商请求
+type MoveAgentRequest struct {
+	AgentID     int64  `json:"-"`
+	NewParentID int64  `json:"new_parent_id" binding:"required"`
+	Reason      string `json:"reason" binding:"max=255"`
+	DryRun      bool   `json:"dry_run"` // 仅预览，不执行
+}
+
+// AgentMoveResult 迁移预览/执行结果
+type AgentMoveResult struct {
+	AgentID       int64                     `json:"agent_id"`
+	AgentName     string                    `json:"agent_name"`
+	FromParentID  int64                     `json:"from_parent_id"`
+	ToParentID    int64                     `json:"to_parent_id"`
+	ToParentName  string                    `json:"to_parent_name"`
+	OldPath       string                    `json:"old_path"`
+	NewPath       string                    `json:"new_path"`
+	OldLevel      int                       `json:"old_level"`
+	NewLevel      int                       `json:"new_level"`
+	SubtreeSize   int                       `json:"subtree_size"`    // 受影响的代理商数（含本人）
+	MaxLevelAfter int                       `json:"max_level_after"` // 迁移后下级树最深层级
+	Conflicts     models.AgentMoveConflicts `json:"conflicts"`
+	Blocking      bool                      `json:"blocking"` // 是否存在须先处理的冲突
+	Executed      bool                      `json:"executed"`
+	MoveID        int64                     `json:"move_id,omitempty"`
+}
+
+// MoveAgent 迁移代理商及其下级树（仅平台），dry_run时仅返回预览
+// 存在须先处理的冲突时返回结果和ErrAgentMoveConflict
+func (s *AgentMoveService) MoveAgent(req *MoveAgentRequest, operator *Operator) (*AgentMoveResult, error) {
+	if !operator.IsAdmin {
+		return nil, errors.New("仅平台可迁移代理商")
+	}
+
+	agent, err := s.agentRepo.FindByIDFull(req.AgentID)
+	if err != nil || agent == nil {
+		return nil, errors.New("代理商不存在")
+	}
+	parent, err := s.agentRepo.FindByIDFull(req.NewParentID)
+	if err != nil || parent == nil {
+		return nil, errors.New("新上级代理商不存在")
+	}
+	if err := s.checkMoveTarget(&agent.Agent, &parent.Agent); err != nil {
+		return nil, err
+	}
+
+	summary, err := s.moveRepo.GetSubtreeSummary(agent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("统计下级失败: %w", err)
+	}
+	result := &AgentMoveResult{
+		AgentID:      agent.ID,
+		AgentName:    agent.AgentName,
+		FromParentID: agent.ParentID,
+		ToParentID:   parent.ID,
+		ToParentName: parent.AgentName,
+		OldPath:      agent.Path,
+		NewPath:      parent.Path + strconv.FormatInt(agent.ID, 10) + "/",
+		OldLevel:     agent.Level,
+		NewLevel:     parent.Level + 1,
+		SubtreeSize:  summary.Size,
+	}
+	result.MaxLevelAfter = summary.MaxLevel + result.NewLevel - result.OldLevel
+	if result.MaxLevelAfter > maxAgentLevel {
+		return nil, fmt.Errorf("迁移后下级最深为%d级，代理商层级不能超过%d级", result.MaxLevelAfter, maxAgentLevel)
+	}
+
+	if result.Conflicts, err = s.collectConflicts(agent.ID, parent.ID); err != nil {
+		return nil, err
+	}
+	result.Blocking = result.Conflicts.HasBlocking()
+	if req.DryRun {
+		return result, nil
+	}
+	if result.Blocking {
+		return result, ErrAgentMoveConflict
+	}
+
+	move := &models.AgentMove{
+		AgentID:      agent.ID,
+		AgentName:    agent.AgentName,
+		FromParentID: agent.ParentID,
+		ToParentID:   parent.ID,
+		OldPath:      result.OldPath,
+		NewPath:      result.NewPath,
+		OldLevel:     result.OldLevel,
+		NewLevel:     result.NewLevel,
+		SubtreeSize:  summary.Size,
+		Warnings:     result.Conflicts,
+		Reason:       strings.TrimSpace(req.Reason),
+		OperatorID:   operator.UserID,
+		OperatorName: operator.Name,
+	}
+	if err := s.moveRepo.ExecuteMove(move, summary); err != nil {
+		if errors.Is(err, repository.ErrAgentMoveStale) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("迁移代理商失败: %w", err)
+	}
+
+	result.Executed = true
+	result.MoveID = move.ID
+	log.Printf("[AgentMoveService] Agent %d moved from parent %d to %d (%s -> %s, %d agents) by user %d",
+		agent.ID, move.FromParentID, move.ToParentID, move.OldPath, move.NewPath, move.SubtreeSize, operator.UserID)
+	return result, nil
+}
+
+// checkMoveTarget 校验新上级：不能是自己、当前上级或自己的下级
+func (s *AgentMoveService) checkMoveTarget(agent, parent *repository.Agent) error {
+	if parent.ID == agent.ID {
+		return errors.New("不能将代理商迁移到自己名下")
+	}
+	if parent.ID == agent.ParentID {
+		return errors.New("新上级与当前上级相同")
+	}
+	isDescendant, err := s.hierarchyRepo.IsDescendant(agent.ID, parent.ID)
+	if err != nil {
+		return fmt.Errorf("查询代理商层级失败: %w", err)
+	}
+	if isDescendant {
+		return errors.New("不能将代理商迁移到自己的下级名下")
+	}
+	if parent.Status != 1 {
+		return errors.New("新上级代理商状态异常")
+	}
+	return nil
+}
+
+// collectConflicts 收集迁移冲突：结算价/政策与新上级比较、跨越迁移边界的下发和代扣
+func (s *AgentMoveService) collectConflicts(agentID, parentID int64) (models.AgentMoveConflicts, error) {
+	agentPrices, err := s.settlementPriceRepo.ListByAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询结算价失败: %w", err)
+	}
+	parentPrices, err := s.settlementPriceRepo.ListByAgent(parentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询新上级结算价失败: %w", err)
+	}
+	agentPolicies, err := s.agentPolicyRepo.FindByAgentID(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询政策失败: %w", err)
+	}
+	parentPolicies, err := s.agentPolicyRepo.FindByAgentID(parentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询新上级政策失败: %w", err)
+	}
+	conflicts := compareWithNewParent(agentPrices, parentPrices, agentPolicies, parentPolicies)
+
+	distributes, err := s.moveRepo.FindCrossingPendingDistributes(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询待确认下发失败: %w", err)
+	}
+	for _, d := range distributes {
+		conflicts = append(conflicts, models.AgentMoveConflict{
+			Type:      models.AgentMoveConflictPendingDistribute,
+			Blocking:  true,
+			ChannelID: d.ChannelID,
+			RelatedID: d.ID,
+			Message:   fmt.Sprintf("终端%s的下发单%s待确认，请先确认或拒绝", d.TerminalSN, d.DistributeNo),
+		})
+	}
+
+	plans, err := s.moveRepo.FindCrossingOpenDeductionPlans(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代扣计划失败: %w", err)
+	}
+	for _, p := range plans {
+		conflicts = append(conflicts, models.AgentMoveConflict{
+			Type:      models.AgentMoveConflictDeductionPlan,
+			RelatedID: p.ID,
+			Message:   fmt.Sprintf("代扣计划%s剩余%.2f元，迁移后按原计划继续扣款", p.PlanNo, float64(p.RemainingAmount)/100),
+		})
+	}
+
+	chains, err := s.moveRepo.FindCrossingActiveChains(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代扣链失败: %w", err)
+	}
+	for _, c := range chains {
+		conflicts = append(conflicts, models.AgentMoveConflict{
+			Type:      models.AgentMoveConflictDeductionChain,
+			RelatedID: c.ID,
+			Message:   fmt.Sprintf("终端%s的代扣链%s进行中，迁移后按原链路继续扣款", c.TerminalSN, c.ChainNo),
+		})
+	}
+	return conflicts, nil
+}
+
+// compareWithNewParent 比较迁移代理商与新上级的结算价和政策，下级不能优于上级
+// 结算费率/政策费率不能低于上级，押金返现/流量费返现不能高于上级，新上级未配置的通道不能迁移
+func compareWithNewParent(agentPrices, parentPrices []models.SettlementPrice, agentPolicies, parentPolicies []*repository.AgentPolicy) models.AgentMoveConflicts {
+	conflicts := models.AgentMoveConflicts{}
+
+	type priceKey struct {
+		channelID int64
+		brandCode string
+	}
+	parentByKey := make(map[priceKey]*models.SettlementPrice, len(parentPrices))
+	for i := range parentPrices {
+		parentByKey[priceKey{parentPrices[i].ChannelID, parentPrices[i].BrandCode}] = &parentPrices[i]
+	}
+
+	sorted := append([]models.SettlementPrice(nil), agentPrices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].ChannelID != sorted[j].ChannelID {
+			return sorted[i].ChannelID < sorted[j].ChannelID
+		}
+		return sorted[i].BrandCode < sorted[j].BrandCode
+	})
+	for _, price := range sorted {
+		parent, ok := parentByKey[priceKey{price.ChannelID, price.BrandCode}]
+		if !ok {
+			conflicts = append(conflicts, models.AgentMoveConflict{
+				Type:      models.AgentMoveConflictChannelMissing,
+				Blocking:  true,
+				ChannelID: price.ChannelID,
+				BrandCode: price.BrandCode,
+				Message:   "新上级未配置该通道结算价",
+			})
+			continue
+		}
+
+		rateCodes := make([]string, 0, len(price.RateConfigs))
+		for code := range price.RateConfigs {
+			rateCodes = append(rateCodes, code)
+		}
+		sort.Strings(rateCodes)
+		for _, code := range rateCodes {
+			upper, ok := parent.RateConfigs[code]
+			if !ok {
+				continue
+			}
+			rate := price.RateConfigs[code].Rate
+			if models.ParseRateToFloat(rate) < models.ParseRateToFloat(upper.Rate) {
+				conflicts = append(conflicts, models.AgentMoveConflict{
+					Type:        models.AgentMoveConflictSettlementRate,
+					Blocking:    true,
+					ChannelID:   price.ChannelID,
+					BrandCode:   price.BrandCode,
+					Item:        code,
+					AgentValue:  rate,
+					ParentValue: upper.Rate,
+					Message:     fmt.Sprintf("%s结算费率%s低于新上级%s", getRateCodeName(code), rate, upper.Rate),
+				})
+			}
+		}
+
+		for _, dc := range price.DepositCashbacks {
+			for _, upper := range parent.DepositCashbacks {
+				if dc.DepositAmount == upper.DepositAmount && dc.CashbackAmount > upper.CashbackAmount {
+					conflicts = append(conflicts, models.AgentMoveConflict{
+						Type:        models.AgentMoveConflictDepositCashback,
+						Blocking:    true,
+						ChannelID:   price.ChannelID,
+						BrandCode:   price.BrandCode,
+						Item:        strconv.FormatInt(dc.DepositAmount, 10),
+						AgentValue:  strconv.FormatInt(dc.CashbackAmount, 10),
+						ParentValue: strconv.FormatInt(upper.CashbackAmount, 10),
+						Message: fmt.Sprintf("押金%d元的返现%d元高于新上级%d元",
+							dc.DepositAmount/100, dc.CashbackAmount/100, upper.CashbackAmount/100),
+					})
+				}
+			}
+		}
+
+		for _, tier := range price.SimCashbacks {
+			upperAmount := parent.SimCashbacks.GetCashbackAmount(tier.TierOrder)
+			if upperAmount > 0 && tier.CashbackAmount > upperAmount {
+				conflicts = append(conflicts, models.AgentMoveConflict{
+					Type:        models.AgentMoveConflictSimCashback,
+					Blocking:    true,
+					ChannelID:   price.ChannelID,
+					BrandCode:   price.BrandCode,
+					Item:        strconv.Itoa(tier.TierOrder),
+					AgentValue:  strconv.FormatInt(tier.CashbackAmount, 10),
+					ParentValue: strconv.FormatInt(upperAmount, 10),
+					Message: fmt.Sprintf("流量费第%d次返现%d元高于新上级%d元",
+						tier.TierOrder, tier.CashbackAmount/100, upperAmount/100),
+				})
+			}
+		}
+	}
+
+	parentPolicyByChannel := make(map[int64]*repository.AgentPolicy, len(parentPolicies))
+	for _, p := range parentPolicies {
+		parentPolicyByChannel[p.ChannelID] = p
+	}
+	for _, policy := range agentPolicies {
+		upper, ok := parentPolicyByChannel[policy.ChannelID]
+		if !ok {
+			continue
+		}
+		checks := []struct{ item, name, rate, upperRate string }{
+			{"CREDIT", "贷记卡", policy.CreditRate, upper.CreditRate},
+			{"DEBIT", "借记卡", policy.DebitRate, upper.DebitRate},
+		}
+		for _, c := range checks {
+			if c.rate == "" || c.upperRate == "" {
+				continue
+			}
+			if models.ParseRateToFloat(c.rate) < models.ParseRateToFloat(c.upperRate) {
+				conflicts = append(conflicts, models.AgentMoveConflict{
+					Type:        models.AgentMoveConflictPolicyRate,
+					Blocking:    true,
+					ChannelID:   policy.ChannelID,
+					Item:        c.item,
+					AgentValue:  c.rate,
+					ParentValue: c.upperRate,
+					Message:     fmt.Sprintf("%s政策费率%s低于新上级%s", c.name, c.rate, c.upperRate),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// ListMoves 迁移记录列表
+func (s *AgentMoveService) ListMoves(filter *repository.AgentMoveFilter, page, pageSize int) ([]*models.AgentMove, int64, error) {
+	list, total, err := s.moveRepo.List(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询迁移记录失败: %w", err)
+	}
+	return list, total, nil
+}
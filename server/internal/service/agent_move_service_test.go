@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestCheckMoveTarget(t *testing.T) {
+	agentRepo := NewMockAgentRepository()
+	agentRepo.AddAgent(1, "A1", 0, "/1/", 1)
+	agentRepo.AddAgent(5, "A5", 1, "/1/5/", 2)
+	agentRepo.AddAgent(12, "A12", 5, "/1/5/12/", 3)
+	agentRepo.AddAgent(55, "A55", 1, "/1/55/", 2)
+	s := &AgentMoveService{hierarchyRepo: &MockHierarchyRepository{agentRepo: agentRepo}}
+
+	agent := &repository.Agent{ID: 5, ParentID: 1, Path: "/1/5/", Level: 2, Status: 1}
+	tests := []struct {
+		name    string
+		parent  *repository.Agent
+		wantErr bool
+	}{
+		{"迁移到同级代理商", &repository.Agent{ID: 6, ParentID: 1, Path: "/1/6/", Level: 2, Status: 1}, false},
+		{"迁移到其他顶级", &repository.Agent{ID: 2, Path: "/2/", Level: 1, Status: 1}, false},
+		{"路径前缀相同但不是下级", &repository.Agent{ID: 55, Path: "/1/55/", Level: 2, Status: 1}, false},
+		{"迁移到自己", agent, true},
+		{"当前上级", &repository.Agent{ID: 1, Path: "/1/", Level: 1, Status: 1}, true},
+		{"迁移到自己的下级", &repository.Agent{ID: 12, ParentID: 5, Path: "/1/5/12/", Level: 3, Status: 1}, true},
+		{"新上级已禁用", &repository.Agent{ID: 7, Path: "/7/", Level: 1, Status: 2}, true},
+	}
+	for _, tt := range tests {
+		if err := s.checkMoveTarget(agent, tt.parent); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkMoveTarget() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCompareWithNewParent(t *testing.T) {
+	parentPrices := []models.SettlementPrice{
+		{
+			ChannelID:        1,
+			RateConfigs:      models.RateConfigs{"CREDIT": {Rate: "0.50"}, "DEBIT": {Rate: "0.45"}},
+			DepositCashbacks: models.DepositCashbacks{{DepositAmount: 9900, CashbackAmount: 5000}},
+			SimCashbacks:     models.SimCashbacks{{TierOrder: 1, CashbackAmount: 3000}},
+		},
+	}
+	parentPolicies := []*repository.AgentPolicy{{ChannelID: 1, CreditRate: "0.0055", DebitRate: "0.0050"}}
+
+	typesOf := func(conflicts models.AgentMoveConflicts) []string {
+		var types []string
+		for _, c := range conflicts {
+			types = append(types, c.Type)
+		}
+		return types
+	}
+
+	tests := []struct {
+		name     string
+		prices   []models.SettlementPrice
+		policies []*repository.AgentPolicy
+		want     []string
+	}{
+		{
+			name: "不优于新上级",
+			prices: []models.SettlementPrice{{
+				ChannelID:        1,
+				RateConfigs:      models.RateConfigs{"CREDIT": {Rate: "0.55"}, "DEBIT": {Rate: "0.45"}, "WECHAT": {Rate: "0.30"}},
+				DepositCashbacks: models.DepositCashbacks{{DepositAmount: 9900, CashbackAmount: 4000}, {DepositAmount: 19900, CashbackAmount: 9000}},
+				SimCashbacks:     models.SimCashbacks{{TierOrder: 1, CashbackAmount: 3000}},
+			}},
+			policies: []*repository.AgentPolicy{{ChannelID: 1, CreditRate: "0.0060", DebitRate: "0.0050"}, {ChannelID: 2, CreditRate: "0.0040"}},
+			want:     nil,
+		},
+		{
+			name: "费率低于新上级",
+			prices: []models.SettlementPrice{{
+				ChannelID:   1,
+				RateConfigs: models.RateConfigs{"DEBIT": {Rate: "0.40"}, "CREDIT": {Rate: "0.48"}},
+			}},
+			want: []string{models.AgentMoveConflictSettlementRate, models.AgentMoveConflictSettlementRate},
+		},
+		{
+			name: "返现高于新上级",
+			prices: []models.SettlementPrice{{
+				ChannelID:        1,
+				DepositCashbacks: models.DepositCashbacks{{DepositAmount: 9900, CashbackAmount: 6000}},
+				SimCashbacks:     models.SimCashbacks{{TierOrder: 1, CashbackAmount: 3500}},
+			}},
+			want: []string{models.AgentMoveConflictDepositCashback, models.AgentMoveConflictSimCashback},
+		},
+		{
+			name:   "新上级未配置通道或品牌",
+			prices: []models.SettlementPrice{{ChannelID: 2}, {ChannelID: 1, BrandCode: "B1"}},
+			want:   []string{models.AgentMoveConflictChannelMissing, models.AgentMoveConflictChannelMissing},
+		},
+		{
+			name:     "政策费率低于新上级",
+			policies: []*repository.AgentPolicy{{ChannelID: 1, CreditRate: "0.0050", DebitRate: "0.0050"}},
+			want:     []string{models.AgentMoveConflictPolicyRate},
+		},
+	}
+	for _, tt := range tests {
+		conflicts := compareWithNewParent(tt.prices, parentPrices, tt.policies, parentPolicies)
+		got := typesOf(conflicts)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: conflicts = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: conflicts = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+		if len(tt.want) > 0 && !conflicts.HasBlocking() {
+			t.Errorf("%s: price/policy conflicts should be blocking", tt.name)
+		}
+	}
+}
+
+func TestAgentMoveConflictsHasBlocking(t *testing.T) {
+	warnings := models.AgentMoveConflicts{
+		{Type: models.AgentMoveConflictDeductionPlan},
+		{Type: models.AgentMoveConflictDeductionChain},
+	}
+	if warnings.HasBlocking() {
+		t.Errorf("deduction plans/chains should not block the move")
+	}
+	blocking := append(warnings, models.AgentMoveConflict{Type: models.AgentMoveConflictPendingDistribute, Blocking: true})
+	if !blocking.HasBlocking() {
+		t.Errorf("pending distributes should block the move")
+	}
+}
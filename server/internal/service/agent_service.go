@@ -14,6 +14,7 @@ import (
 // AgentService 代理商服务
 type AgentService struct {
 	agentRepo       *repository.GormAgentRepository
+	hierarchyRepo   repository.HierarchyRepository
 	agentPolicyRepo *repository.GormAgentPolicyRepository
 	walletRepo      *repository.GormWalletRepository
 	transactionRepo *repository.GormTransactionRepository
@@ -24,6 +25,7 @@ type AgentService struct {
 // NewAgentService 创建代理商服务
 func NewAgentService(
 	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
 	agentPolicyRepo *repository.GormAgentPolicyRepository,
 	walletRepo *repository.GormWalletRepository,
 	transactionRepo *repository.GormTransactionRepository,
@@ -31,6 +33,7 @@ func NewAgentService(
 ) *AgentService {
 	return &AgentService{
 		agentRepo:       agentRepo,
+		hierarchyRepo:   hierarchyRepo,
 		agentPolicyRepo: agentPolicyRepo,
 		walletRepo:      walletRepo,
 		transactionRepo: transactionRepo,
@@ -179,7 +182,11 @@ type TeamTreeNode struct {
 	Children     []*TeamTreeNode `json:"children,omitempty"`
 }
 
+// teamTreeMaxChildren 团队树每个节点最多展示的直属下级数
+const teamTreeMaxChildren = 100
+
 // GetTeamTree 获取团队层级树
+// 通过层级闭包表一次查出限定深度内的下级，在内存中组装
 func (s *AgentService) GetTeamTree(agentID int64, maxDepth int) (*TeamTreeNode, error) {
 	agent, err := s.agentRepo.FindByID(agentID)
 	if err != nil || agent == nil {
@@ -194,49 +201,37 @@ func (s *AgentService) GetTeamTree(agentID int64, maxDepth int) (*TeamTreeNode,
 		Status:    agent.Status,
 	}
 
-	if maxDepth > 0 {
-		children, err := s.buildTreeChildren(agentID, 1, maxDepth)
+	if maxDepth > 1 {
+		nodes, err := s.hierarchyRepo.FindDescendants(agentID, maxDepth-1)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("查询下级代理商失败: %w", err)
 		}
-		root.Children = children
-		root.ChildCount = len(children)
+		buildTeamTree(root, nodes, teamTreeMaxChildren)
 	}
 
 	return root, nil
 }
 
-// buildTreeChildren 递归构建子树
-func (s *AgentService) buildTreeChildren(parentID int64, currentDepth, maxDepth int) ([]*TeamTreeNode, error) {
-	if currentDepth >= maxDepth {
-		return nil, nil
-	}
-
-	children, _, err := s.agentRepo.FindSubordinates(parentID, "", nil, 100, 0)
-	if err != nil {
-		return nil, err
-	}
-
-	nodes := make([]*TeamTreeNode, 0, len(children))
-	for _, child := range children {
-		node := &TeamTreeNode{
-			ID:        child.ID,
-			AgentNo:   child.AgentNo,
-			AgentName: child.AgentName,
-			Level:     child.Level,
-			Status:    child.Status,
+// buildTeamTree 将按层数排序的下级节点挂到树上，每个节点最多挂maxChildren个直属下级
+// 上级未挂上（超出数量）的节点一并忽略
+func buildTeamTree(root *TeamTreeNode, nodes []*repository.HierarchyNode, maxChildren int) {
+	treeNodes := map[int64]*TeamTreeNode{root.ID: root}
+	for _, n := range nodes {
+		parent, ok := treeNodes[n.ParentID]
+		if !ok || len(parent.Children) >= maxChildren {
+			continue
 		}
-
-		subChildren, _ := s.buildTreeChildren(child.ID, currentDepth+1, maxDepth)
-		if len(subChildren) > 0 {
-			node.Children = subChildren
-			node.ChildCount = len(subChildren)
+		node := &TeamTreeNode{
+			ID:        n.ID,
+			AgentNo:   n.AgentNo,
+			AgentName: n.AgentName,
+			Level:     n.Level,
+			Status:    n.Status,
 		}
-
-		nodes = append(nodes, node)
+		parent.Children = append(parent.Children, node)
+		parent.ChildCount = len(parent.Children)
+		treeNodes[node.ID] = node
 	}
-
-	return nodes, nil
 }
 
 // AgentStatsResponse 代理商统计响应
@@ -387,10 +382,11 @@ func (s *AgentService) IsSubordinate(parentID, childID int64) (bool, error) {
 	if err != nil || child == nil {
 		return false, errors.New("代理商不存在")
 	}
+	if parentID == childID {
+		return true, nil
+	}
 
-	// 检查path是否包含parentID
-	parentPath := fmt.Sprintf("/%d/", parentID)
-	return strings.Contains(child.Path, parentPath), nil
+	return s.hierarchyRepo.IsDescendant(parentID, childID)
 }
 
 // getAgentStatusName 获取代理商状态名称
@@ -440,7 +436,6 @@ func (s *AgentService) CreateAgent(req *CreateAgentRequest, operatorID int64) (*
 
 	// 获取上级代理商信息（如果有）
 	var parentAgent *repository.AgentFull
-	var path string
 	var level int = 1
 
 	if req.ParentID > 0 {
@@ -480,25 +475,15 @@ func (s *AgentService) CreateAgent(req *CreateAgentRequest, operatorID int64) (*
 		CreatedAt:    now,
 	}
 
-	// 创建代理商
-	if err := s.agentRepo.Create(agent); err != nil {
-		return nil, fmt.Errorf("创建代理商失败: %w", err)
-	}
-
-	// 构建物化路径
+	// 创建代理商：同一事务内写入代理商、回填物化路径和邀请码、写入层级闭包表
+	var parentPath string
 	if parentAgent != nil {
-		path = fmt.Sprintf("%s%d/", parentAgent.Path, agent.ID)
-	} else {
-		path = fmt.Sprintf("/%d/", agent.ID)
+		parentPath = parentAgent.Path
 	}
-	agent.Path = path
-
-	// 生成邀请码
-	agent.InviteCode = generateInviteCode(agent.ID)
-
-	// 更新路径和邀请码
-	if err := s.agentRepo.Update(agent); err != nil {
-		return nil, fmt.Errorf("更新代理商路径失败: %w", err)
+	if err := s.agentRepo.CreateWithHierarchy(agent, parentPath, func(created *repository.AgentFull) {
+		created.InviteCode = generateInviteCode(created.ID)
+	}); err != nil {
+		return nil, fmt.Errorf("创建代理商失败: %w", err)
 	}
 
 	// 更新上级代理商的统计计数
@@ -528,7 +513,6 @@ func (s *AgentService) CreateAgentWithStatus(req *CreateAgentRequest, operatorID
 
 	// 获取上级代理商信息（如果有）
 	var parentAgent *repository.AgentFull
-	var path string
 	var level int = 1
 
 	if req.ParentID > 0 {
@@ -568,25 +552,15 @@ func (s *AgentService) CreateAgentWithStatus(req *CreateAgentRequest, operatorID
 		CreatedAt:    now,
 	}
 
-	// 创建代理商
-	if err := s.agentRepo.Create(agent); err != nil {
-		return nil, fmt.Errorf("创建代理商失败: %w", err)
-	}
-
-	// 构建物化路径
+	// 创建代理商：同一事务内写入代理商、回填物化路径和邀请码、写入层级闭包表
+	var parentPath string
 	if parentAgent != nil {
-		path = fmt.Sprintf("%s%d/", parentAgent.Path, agent.ID)
-	} else {
-		path = fmt.Sprintf("/%d/", agent.ID)
+		parentPath = parentAgent.Path
 	}
-	agent.Path = path
-
-	// 生成邀请码
-	agent.InviteCode = generateInviteCode(agent.ID)
-
-	// 更新路径和邀请码
-	if err := s.agentRepo.Update(agent); err != nil {
-		return nil, fmt.Errorf("更新代理商路径失败: %w", err)
+	if err := s.agentRepo.CreateWithHierarchy(agent, parentPath, func(created *repository.AgentFull) {
+		created.InviteCode = generateInviteCode(created.ID)
+	}); err != nil {
+		return nil, fmt.Errorf("创建代理商失败: %w", err)
 	}
 
 	// 更新上级代理商的统计计数
@@ -294,3 +294,31 @@ func TestAgentStatusName(t *testing.T) {
 		})
 	}
 }
+
+// TestBuildTeamTree 测试按层级闭包表结果组装团队树
+func TestBuildTeamTree(t *testing.T) {
+	node := func(id, parentID int64, depth int) *repository.HierarchyNode {
+		return &repository.HierarchyNode{Agent: repository.Agent{ID: id, ParentID: parentID, Level: depth + 1}, Depth: depth}
+	}
+	root := &TeamTreeNode{ID: 1, Level: 1}
+	nodes := []*repository.HierarchyNode{
+		node(2, 1, 1),
+		node(3, 1, 1),
+		node(4, 1, 1), // 超出每个节点的直属下级上限
+		node(5, 2, 2),
+		node(6, 4, 2), // 上级未挂上，一并忽略
+		node(7, 5, 3),
+	}
+
+	buildTeamTree(root, nodes, 2)
+
+	assert.Equal(t, 2, root.ChildCount)
+	assert.Len(t, root.Children, 2)
+	assert.Equal(t, int64(2), root.Children[0].ID)
+	assert.Equal(t, int64(3), root.Children[1].ID)
+	assert.Equal(t, 1, root.Children[0].ChildCount)
+	assert.Equal(t, int64(5), root.Children[0].Children[0].ID)
+	assert.Equal(t, int64(7), root.Children[0].Children[0].Children[0].ID)
+	assert.Equal(t, 0, root.Children[1].ChildCount)
+	assert.Nil(t, root.Children[1].Children)
+}
@@ -0,0 +1,600 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/pkg/pdf"
+)
+
+// AgentStatementService 代理商月度对账单服务
+// 按代理商+通道汇总分润记录、钱包流水和提现记录生成月度对账单，支持导出PDF/CSV、代理商确认或提出异议
+type AgentStatementService struct {
+	statementRepo  *repository.GormAgentStatementRepository
+	agentRepo      *repository.GormAgentRepository
+	channelRepo    *repository.GormChannelRepository
+	messageService *MessageService
+}
+
+// NewAgentStatementService 创建对账单服务
+func NewAgentStatementService(
+	statementRepo *repository.GormAgentStatementRepository,
+	agentRepo *repository.GormAgentRepository,
+	channelRepo *repository.GormChannelRepository,
+) *AgentStatementService {
+	return &AgentStatementService{
+		statementRepo: statementRepo,
+		agentRepo:     agentRepo,
+		channelRepo:   channelRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（对账单生成后通知代理商）
+func (s *AgentStatementService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// 对账单流水分类
+const (
+	statementCategoryNone       = ""
+	statementCategoryDeposit    = "deposit_cashback"
+	statementCategorySim        = "sim_cashback"
+	statementCategoryActivation = "activation_reward"
+	statementCategoryDeduction  = "deduction"
+	statementCategoryAdjustment = "adjustment"
+	statementCategoryWithdrawal = "withdrawal"
+	statementCategoryOther      = "other"
+)
+
+// classifyStatementLog 按流水类型和关联类型归类
+// 提现冻结/退回流水不改变余额，不计入对账单
+func classifyStatementLog(logType int16, refType string) string {
+	if logType == WalletLogTypeWithdrawFreeze || logType == WalletLogTypeWithdrawReturn {
+		return statementCategoryNone
+	}
+	switch refType {
+	case "deposit_cashback":
+		return statementCategoryDeposit
+	case "sim_cashback":
+		return statementCategorySim
+	case "activation_reward", "stage_reward":
+		return statementCategoryActivation
+	case "deduction_record", "goods_deduction_detail":
+		return statementCategoryDeduction
+	case "wallet_adjustment":
+		return statementCategoryAdjustment
+	}
+	if logType == WalletLogTypeWithdrawSuccess {
+		return statementCategoryWithdrawal
+	}
+	return statementCategoryOther
+}
+
+// statementSources 对账单汇总数据
+type statementSources struct {
+	OpeningBalance int64
+	Profit         *repository.StatementProfitSum
+	RevokedProfit  int64
+	LogSums        []repository.WalletLogSum
+	TaxFee         int64
+}
+
+// fillStatementAmounts 计算对账单各项金额及期末余额
+// 交易分润记录的分润金额已包含高调和P+0部分，拆分展示
+func fillStatementAmounts(st *models.AgentStatement, src *statementSources) {
+	st.OpeningBalance = src.OpeningBalance
+	st.HighRateProfit = src.Profit.HighRateProfit
+	st.D0Profit = src.Profit.D0ExtraProfit
+	st.TradeProfit = src.Profit.ProfitAmount - src.Profit.HighRateProfit - src.Profit.D0ExtraProfit
+	st.ProfitRevoked = -src.RevokedProfit
+	st.TaxFee = src.TaxFee
+
+	st.DepositCashback, st.SimCashback, st.ActivationReward = 0, 0, 0
+	st.DeductionOut, st.DeductionIn, st.Adjustment, st.Withdrawal, st.OtherAmount = 0, 0, 0, 0, 0
+	for _, sum := range src.LogSums {
+		switch classifyStatementLog(sum.LogType, sum.RefType) {
+		case statementCategoryDeposit:
+			st.DepositCashback += sum.Amount
+		case statementCategorySim:
+			st.SimCashback += sum.Amount
+		case statementCategoryActivation:
+			st.ActivationReward += sum.Amount
+		case statementCategoryDeduction:
+			if sum.Credit {
+				st.DeductionIn += sum.Amount
+			} else {
+				st.DeductionOut += sum.Amount
+			}
+		case statementCategoryAdjustment:
+			st.Adjustment += sum.Amount
+		case statementCategoryWithdrawal:
+			st.Withdrawal += sum.Amount
+		case statementCategoryOther:
+			st.OtherAmount += sum.Amount
+		}
+	}
+
+	st.ClosingBalance = st.OpeningBalance + st.TradeProfit + st.HighRateProfit + st.D0Profit + st.ProfitRevoked +
+		st.DepositCashback + st.SimCashback + st.ActivationReward +
+		st.DeductionOut + st.DeductionIn + st.Adjustment + st.Withdrawal + st.OtherAmount
+}
+
+// netBalanceChange 计算流水及交易分润对余额的净影响（用于倒推期初余额）
+func netBalanceChange(profit *repository.StatementProfitSum, revoked int64, sums []repository.WalletLogSum) int64 {
+	net := profit.ProfitAmount - revoked
+	for _, sum := range sums {
+		if classifyStatementLog(sum.LogType, sum.RefType) != statementCategoryNone {
+			net += sum.Amount
+		}
+	}
+	return net
+}
+
+// parseStatementPeriod 解析账期 YYYY-MM，返回账期起止时间
+func parseStatementPeriod(period string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("账期格式错误，应为YYYY-MM")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// PreviousPeriod 获取上一个自然月账期
+func PreviousPeriod(now time.Time) string {
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return firstOfMonth.AddDate(0, -1, 0).Format("2006-01")
+}
+
+// GenerateStatement 生成（或重新生成）代理商某通道某账期的对账单
+// 已确认的对账单不可重新生成；重新生成后状态恢复为待确认，期末余额变化时级联重新生成后续账期
+func (s *AgentStatementService) GenerateStatement(agentID, channelID int64, period string) (*models.AgentStatement, error) {
+	start, end, err := parseStatementPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if end.After(now) {
+		return nil, fmt.Errorf("账期%s尚未结束，无法生成对账单", period)
+	}
+
+	existing, err := s.statementRepo.FindByPeriod(agentID, channelID, period)
+	if err != nil {
+		return nil, fmt.Errorf("查询对账单失败: %w", err)
+	}
+	if existing != nil && existing.Status == models.StatementStatusConfirmed {
+		return nil, fmt.Errorf("对账单已确认，不能重新生成")
+	}
+
+	walletIDs, err := s.statementRepo.FindWalletIDs(agentID, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("查询钱包失败: %w", err)
+	}
+
+	src := &statementSources{}
+	if src.Profit, err = s.statementRepo.SumTradeProfits(agentID, channelID, start, end); err != nil {
+		return nil, fmt.Errorf("汇总分润失败: %w", err)
+	}
+	if src.RevokedProfit, err = s.statementRepo.SumRevokedProfits(agentID, channelID, start, end); err != nil {
+		return nil, fmt.Errorf("汇总撤销分润失败: %w", err)
+	}
+	if src.LogSums, err = s.statementRepo.SumWalletLogs(walletIDs, start, end); err != nil {
+		return nil, fmt.Errorf("汇总钱包流水失败: %w", err)
+	}
+	if src.TaxFee, err = s.statementRepo.SumWithdrawTax(walletIDs, start, end); err != nil {
+		return nil, fmt.Errorf("汇总提现税费失败: %w", err)
+	}
+	if src.OpeningBalance, err = s.openingBalance(agentID, channelID, period, walletIDs, start, now); err != nil {
+		return nil, err
+	}
+
+	var previousClosing int64
+	if existing != nil {
+		previousClosing = existing.ClosingBalance
+	}
+	statement := existing
+	if statement == nil {
+		statement = &models.AgentStatement{
+			StatementNo: fmt.Sprintf("ST%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+			AgentID:     agentID,
+			ChannelID:   channelID,
+			Period:      period,
+			PeriodStart: start,
+			PeriodEnd:   end,
+			CreatedAt:   now,
+		}
+	}
+	fillStatementAmounts(statement, src)
+	statement.Status = models.StatementStatusPending
+	statement.GeneratedAt = now
+	statement.UpdatedAt = now
+
+	if existing == nil {
+		if err := s.statementRepo.Create(statement); err != nil {
+			return nil, fmt.Errorf("保存对账单失败: %w", err)
+		}
+		s.notify(statement, "对账单已生成",
+			fmt.Sprintf("您%s的对账单已生成，期末余额%.2f元，请核对后确认。如有疑问可提出异议。",
+				period, float64(statement.ClosingBalance)/100))
+	} else {
+		if err := s.statementRepo.Save(statement); err != nil {
+			return nil, fmt.Errorf("保存对账单失败: %w", err)
+		}
+		s.notify(statement, "对账单已更新",
+			fmt.Sprintf("您%s的对账单已重新生成，期末余额%.2f元，请重新核对。", period, float64(statement.ClosingBalance)/100))
+		if statement.ClosingBalance != previousClosing {
+			s.regenerateNextStatement(agentID, channelID, end)
+		}
+	}
+
+	return statement, nil
+}
+
+// openingBalance 期初余额：优先取上一自然月对账单的期末余额，上月无对账单时按当前余额倒推
+func (s *AgentStatementService) openingBalance(agentID, channelID int64, period string, walletIDs []int64, start, now time.Time) (int64, error) {
+	previous, err := s.statementRepo.FindPrevious(agentID, channelID, period)
+	if err != nil {
+		return 0, fmt.Errorf("查询上期对账单失败: %w", err)
+	}
+	if balance, ok := openingFromPrevious(previous, start); ok {
+		return balance, nil
+	}
+
+	current, err := s.statementRepo.SumWalletBalance(walletIDs)
+	if err != nil {
+		return 0, fmt.Errorf("查询钱包余额失败: %w", err)
+	}
+	profit, err := s.statementRepo.SumTradeProfits(agentID, channelID, start, now)
+	if err != nil {
+		return 0, fmt.Errorf("汇总分润失败: %w", err)
+	}
+	revoked, err := s.statementRepo.SumRevokedProfits(agentID, channelID, start, now)
+	if err != nil {
+		return 0, fmt.Errorf("汇总撤销分润失败: %w", err)
+	}
+	sums, err := s.statementRepo.SumWalletLogs(walletIDs, start, now)
+	if err != nil {
+		return 0, fmt.Errorf("汇总钱包流水失败: %w", err)
+	}
+	return current - netBalanceChange(profit, revoked, sums), nil
+}
+
+// openingFromPrevious 上期对账单恰为上一自然月时取其期末余额；中间有月份缺失时不可沿用
+func openingFromPrevious(previous *models.AgentStatement, start time.Time) (int64, bool) {
+	if previous == nil || previous.Period != PreviousPeriod(start) {
+		return 0, false
+	}
+	return previous.ClosingBalance, true
+}
+
+// regenerateNextStatement 期末余额变化后重新生成下一账期的对账单，使其期初余额保持一致，逐期向后级联
+// 下一账期已确认时不再改动，只记录日志
+func (s *AgentStatementService) regenerateNextStatement(agentID, channelID int64, nextStart time.Time) {
+	period := nextStart.Format("2006-01")
+	next, err := s.statementRepo.FindByPeriod(agentID, channelID, period)
+	if err != nil || next == nil {
+		return
+	}
+	if next.Status == models.StatementStatusConfirmed {
+		log.Printf("[AgentStatementService] Next statement confirmed, opening balance left unchanged: agent=%d, channel=%d, period=%s",
+			agentID, channelID, period)
+		return
+	}
+	if _, err := s.GenerateStatement(agentID, channelID, period); err != nil {
+		log.Printf("[AgentStatementService] Regenerate next statement failed: agent=%d, channel=%d, period=%s, err=%v",
+			agentID, channelID, period, err)
+	}
+}
+
+// GenerateMonthly 为所有代理商+通道生成指定账期的对账单，已存在的跳过
+func (s *AgentStatementService) GenerateMonthly(period string) (int, error) {
+	pairs, err := s.statementRepo.FindAgentChannelPairs()
+	if err != nil {
+		return 0, fmt.Errorf("查询代理商钱包失败: %w", err)
+	}
+
+	generated := 0
+	for _, pair := range pairs {
+		existing, err := s.statementRepo.FindByPeriod(pair.AgentID, pair.ChannelID, period)
+		if err != nil || existing != nil {
+			continue
+		}
+		if _, err := s.GenerateStatement(pair.AgentID, pair.ChannelID, period); err != nil {
+			log.Printf("[AgentStatementService] Generate statement failed: agent=%d, channel=%d, period=%s, err=%v",
+				pair.AgentID, pair.ChannelID, period, err)
+			continue
+		}
+		generated++
+	}
+	return generated, nil
+}
+
+// ConfirmStatement 代理商确认对账单
+func (s *AgentStatementService) ConfirmStatement(id, agentID int64) error {
+	if agentID <= 0 {
+		return fmt.Errorf("仅代理商本人可确认对账单")
+	}
+	statement, err := s.getAgentStatement(id, agentID)
+	if err != nil {
+		return err
+	}
+	if statement.Status != models.StatementStatusPending {
+		return fmt.Errorf("对账单状态为%s，无法确认", models.GetStatementStatusName(statement.Status))
+	}
+
+	ok, err := s.statementRepo.UpdateStatus(id, []int16{models.StatementStatusPending}, map[string]interface{}{
+		"status":       models.StatementStatusConfirmed,
+		"confirmed_at": time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("确认对账单失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("对账单状态已变更，请刷新后重试")
+	}
+	return nil
+}
+
+// DisputeStatement 代理商对对账单提出异议
+func (s *AgentStatementService) DisputeStatement(id, agentID int64, reason string) error {
+	if agentID <= 0 {
+		return fmt.Errorf("仅代理商本人可提出异议")
+	}
+	if reason == "" {
+		return fmt.Errorf("请填写异议内容")
+	}
+	statement, err := s.getAgentStatement(id, agentID)
+	if err != nil {
+		return err
+	}
+	if statement.Status != models.StatementStatusPending {
+		return fmt.Errorf("对账单状态为%s，无法提出异议", models.GetStatementStatusName(statement.Status))
+	}
+
+	ok, err := s.statementRepo.UpdateStatus(id, []int16{models.StatementStatusPending}, map[string]interface{}{
+		"status":         models.StatementStatusDisputed,
+		"dispute_reason": reason,
+		"disputed_at":    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("提交异议失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("对账单状态已变更，请刷新后重试")
+	}
+	return nil
+}
+
+// ReplyDispute 管理员答复异议，对账单恢复为待确认
+// 如需更正金额，应先调账再重新生成对账单
+func (s *AgentStatementService) ReplyDispute(id, operatorID int64, operatorName, reply string) error {
+	if reply == "" {
+		return fmt.Errorf("请填写答复内容")
+	}
+	statement, err := s.statementRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("查询对账单失败: %w", err)
+	}
+	if statement == nil {
+		return fmt.Errorf("对账单不存在")
+	}
+
+	ok, err := s.statementRepo.UpdateStatus(id, []int16{models.StatementStatusDisputed}, map[string]interface{}{
+		"status":          models.StatementStatusPending,
+		"dispute_reply":   reply,
+		"replied_by":      operatorID,
+		"replied_by_name": operatorName,
+		"replied_at":      time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("答复异议失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("对账单不在异议状态")
+	}
+
+	s.notify(statement, "对账单异议已答复", fmt.Sprintf("您%s对账单的异议已答复：%s", statement.Period, reply))
+	return nil
+}
+
+// getAgentStatement 获取对账单并校验归属，agentID为0表示管理员
+func (s *AgentStatementService) getAgentStatement(id, agentID int64) (*models.AgentStatement, error) {
+	statement, err := s.statementRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询对账单失败: %w", err)
+	}
+	if statement == nil || (agentID > 0 && statement.AgentID != agentID) {
+		return nil, fmt.Errorf("对账单不存在")
+	}
+	return statement, nil
+}
+
+func (s *AgentStatementService) notify(statement *models.AgentStatement, title, content string) {
+	if s.messageService == nil {
+		return
+	}
+	msg := &NotificationMessage{
+		AgentID:     statement.AgentID,
+		MessageType: models.MessageTypeStatement,
+		Title:       title,
+		Content:     content,
+		RelatedID:   statement.ID,
+		RelatedType: "agent_statement",
+	}
+	if err := s.messageService.SendNotification(msg); err != nil {
+		log.Printf("[AgentStatementService] Send notification failed: %v", err)
+	}
+}
+
+// StatementInfo 对账单信息
+type StatementInfo struct {
+	*models.AgentStatement
+	AgentName   string          `json:"agent_name"`
+	ChannelName string          `json:"channel_name"`
+	StatusName  string          `json:"status_name"`
+	Lines       []StatementLine `json:"lines"`
+}
+
+// StatementLine 对账单明细行
+type StatementLine struct {
+	Label      string  `json:"label"`
+	Amount     int64   `json:"amount"`
+	AmountYuan float64 `json:"amount_yuan"`
+	Note       string  `json:"note,omitempty"`
+}
+
+// statementLines 对账单展示明细
+func statementLines(st *models.AgentStatement) []StatementLine {
+	lines := []StatementLine{
+		{Label: "期初余额", Amount: st.OpeningBalance},
+		{Label: "交易分润", Amount: st.TradeProfit},
+		{Label: "高调分润", Amount: st.HighRateProfit},
+		{Label: "P+0分润", Amount: st.D0Profit},
+		{Label: "退款撤销分润", Amount: st.ProfitRevoked},
+		{Label: "押金返现", Amount: st.DepositCashback},
+		{Label: "流量费返现", Amount: st.SimCashback},
+		{Label: "激活奖励", Amount: st.ActivationReward},
+		{Label: "代扣扣款", Amount: st.DeductionOut},
+		{Label: "代扣收款", Amount: st.DeductionIn},
+		{Label: "调账", Amount: st.Adjustment},
+		{Label: "提现", Amount: st.Withdrawal},
+		{Label: "其中：提现税费", Amount: st.TaxFee, Note: "已含在提现金额中"},
+		{Label: "其他变动", Amount: st.OtherAmount, Note: "划转、沉淀款、充值钱包等"},
+		{Label: "期末余额", Amount: st.ClosingBalance},
+	}
+	for i := range lines {
+		lines[i].AmountYuan = float64(lines[i].Amount) / 100
+	}
+	return lines
+}
+
+func (s *AgentStatementService) toStatementInfo(st *models.AgentStatement) *StatementInfo {
+	info := &StatementInfo{
+		AgentStatement: st,
+		StatusName:     models.GetStatementStatusName(st.Status),
+		Lines:          statementLines(st),
+	}
+	if agent, _ := s.agentRepo.FindByIDFull(st.AgentID); agent != nil {
+		info.AgentName = agent.AgentName
+	}
+	if channel, _ := s.channelRepo.FindByID(st.ChannelID); channel != nil {
+		info.ChannelName = channel.ChannelName
+	}
+	return info
+}
+
+// StatementListParams 对账单列表查询参数
+type StatementListParams struct {
+	AgentID   int64
+	ChannelID *int64
+	Period    string
+	Status    *int16
+	Page      int
+	PageSize  int
+}
+
+// GetStatementList 获取对账单列表
+func (s *AgentStatementService) GetStatementList(params *StatementListParams) ([]*StatementInfo, int64, error) {
+	statements, total, err := s.statementRepo.List(&repository.AgentStatementQueryParams{
+		AgentID:   params.AgentID,
+		ChannelID: params.ChannelID,
+		Period:    params.Period,
+		Status:    params.Status,
+		Limit:     params.PageSize,
+		Offset:    (params.Page - 1) * params.PageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询对账单失败: %w", err)
+	}
+
+	list := make([]*StatementInfo, 0, len(statements))
+	for _, st := range statements {
+		list = append(list, s.toStatementInfo(st))
+	}
+	return list, total, nil
+}
+
+// GetStatementDetail 获取对账单详情，agentID为0表示管理员
+func (s *AgentStatementService) GetStatementDetail(id, agentID int64) (*StatementInfo, error) {
+	statement, err := s.getAgentStatement(id, agentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toStatementInfo(statement), nil
+}
+
+// ExportCSV 导出对账单CSV（带UTF-8 BOM，兼容Excel）
+func (s *AgentStatementService) ExportCSV(info *StatementInfo) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(buf)
+
+	records := [][]string{
+		{"对账单号", info.StatementNo},
+		{"代理商", info.AgentName},
+		{"通道", info.ChannelName},
+		{"账期", info.Period},
+		{"状态", info.StatusName},
+		{},
+		{"项目", "金额(元)", "说明"},
+	}
+	for _, line := range info.Lines {
+		records = append(records, []string{line.Label, fmt.Sprintf("%.2f", line.AmountYuan), line.Note})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("生成CSV失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportPDF 导出对账单PDF
+func (s *AgentStatementService) ExportPDF(info *StatementInfo) []byte {
+	doc := pdf.New()
+	doc.AddPage()
+
+	const left, amountRight, noteLeft = 60.0, 400.0, 420.0
+	doc.Text(left, 70, 18, "代理商月度对账单")
+	y := 105.0
+	for _, row := range [][2]string{
+		{"对账单号", info.StatementNo},
+		{"代理商", info.AgentName},
+		{"通道", info.ChannelName},
+		{"账期", fmt.Sprintf("%s（%s 至 %s）", info.Period,
+			info.PeriodStart.Format("2006-01-02"), info.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))},
+		{"状态", info.StatusName},
+	} {
+		doc.Text(left, y, 10, row[0]+"："+row[1])
+		y += 18
+	}
+
+	y += 10
+	doc.Line(left, y, pdf.PageWidth-left, y)
+	y += 18
+	doc.Text(left, y, 11, "项目")
+	doc.Text(amountRight-pdf.TextWidth("金额(元)", 11), y, 11, "金额(元)")
+	doc.Text(noteLeft, y, 11, "说明")
+	y += 8
+	doc.Line(left, y, pdf.PageWidth-left, y)
+	y += 18
+
+	for _, line := range info.Lines {
+		amount := fmt.Sprintf("%.2f", line.AmountYuan)
+		doc.Text(left, y, 10, line.Label)
+		doc.Text(amountRight-pdf.TextWidth(amount, 10), y, 10, amount)
+		if line.Note != "" {
+			doc.Text(noteLeft, y, 8, line.Note)
+		}
+		y += 20
+	}
+	doc.Line(left, y-10, pdf.PageWidth-left, y-10)
+
+	y += 10
+	doc.Text(left, y, 8, "期末余额 = 期初余额 + 各项收入与支出（提现税费已含在提现金额中）")
+	doc.Text(left, y+14, 8, "生成时间："+info.GeneratedAt.Format("2006-01-02 15:04:05"))
+
+	return doc.Bytes()
+}
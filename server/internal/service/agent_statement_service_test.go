@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// TestClassifyStatementLog 测试钱包流水归类
+func TestClassifyStatementLog(t *testing.T) {
+	assert.Equal(t, statementCategoryNone, classifyStatementLog(WalletLogTypeWithdrawFreeze, "withdraw"))
+	assert.Equal(t, statementCategoryNone, classifyStatementLog(WalletLogTypeWithdrawReturn, "withdraw"))
+	assert.Equal(t, statementCategoryWithdrawal, classifyStatementLog(WalletLogTypeWithdrawSuccess, "withdraw_paid"))
+	assert.Equal(t, statementCategoryDeposit, classifyStatementLog(7, "deposit_cashback"))
+	assert.Equal(t, statementCategoryActivation, classifyStatementLog(11, "activation_reward"))
+	assert.Equal(t, statementCategoryAdjustment, classifyStatementLog(WalletLogTypeAdjustmentIn, "wallet_adjustment"))
+	assert.Equal(t, statementCategoryDeduction, classifyStatementLog(6, "deduction_record"))
+	assert.Equal(t, statementCategoryOther, classifyStatementLog(21, "wallet_transfer"))
+}
+
+// TestFillStatementAmounts 测试对账单金额拆分与期末余额
+func TestFillStatementAmounts(t *testing.T) {
+	st := &models.AgentStatement{}
+	src := &statementSources{
+		OpeningBalance: 10000,
+		Profit:         &repository.StatementProfitSum{ProfitAmount: 5000, HighRateProfit: 800, D0ExtraProfit: 200},
+		RevokedProfit:  300,
+		LogSums: []repository.WalletLogSum{
+			{LogType: 7, RefType: "deposit_cashback", Credit: true, Amount: 2000},
+			{LogType: 6, RefType: "deduction_record", Credit: false, Amount: -1500},
+			{LogType: 6, RefType: "deduction_record", Credit: true, Amount: 400},
+			{LogType: WalletLogTypeWithdrawFreeze, RefType: "withdraw", Credit: false, Amount: -6000},
+			{LogType: WalletLogTypeWithdrawSuccess, RefType: "withdraw_paid", Credit: false, Amount: -6000},
+		},
+		TaxFee: 540,
+	}
+	fillStatementAmounts(st, src)
+
+	assert.Equal(t, int64(4000), st.TradeProfit)
+	assert.Equal(t, int64(800), st.HighRateProfit)
+	assert.Equal(t, int64(200), st.D0Profit)
+	assert.Equal(t, int64(-300), st.ProfitRevoked)
+	assert.Equal(t, int64(-1500), st.DeductionOut)
+	assert.Equal(t, int64(400), st.DeductionIn)
+	assert.Equal(t, int64(-6000), st.Withdrawal)
+	assert.Equal(t, int64(540), st.TaxFee)
+	// 10000 + 5000 - 300 + 2000 - 1500 + 400 - 6000，冻结流水和税费不重复计算
+	assert.Equal(t, int64(9600), st.ClosingBalance)
+	assert.Equal(t, st.ClosingBalance-st.OpeningBalance, netBalanceChange(src.Profit, src.RevokedProfit, src.LogSums))
+}
+
+// TestPreviousPeriod 测试上一账期计算（跨年）
+func TestPreviousPeriod(t *testing.T) {
+	assert.Equal(t, "2026-09", PreviousPeriod(time.Date(2026, 10, 31, 23, 0, 0, 0, time.Local)))
+	assert.Equal(t, "2025-12", PreviousPeriod(time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)))
+
+	start, end, err := parseStatementPeriod("2026-02")
+	assert.NoError(t, err)
+	assert.Equal(t, 28, int(end.Sub(start).Hours()/24))
+	_, _, err = parseStatementPeriod("2026/02")
+	assert.Error(t, err)
+}
+
+// TestOpeningFromPrevious 测试期初余额仅沿用上一自然月对账单
+func TestOpeningFromPrevious(t *testing.T) {
+	start, _, err := parseStatementPeriod("2026-03")
+	assert.NoError(t, err)
+
+	balance, ok := openingFromPrevious(&models.AgentStatement{Period: "2026-02", ClosingBalance: 12345}, start)
+	assert.True(t, ok)
+	assert.Equal(t, int64(12345), balance)
+
+	// 2月缺失对账单，1月期末余额不能作为3月期初余额
+	_, ok = openingFromPrevious(&models.AgentStatement{Period: "2026-01", ClosingBalance: 12345}, start)
+	assert.False(t, ok)
+
+	_, ok = openingFromPrevious(nil, start)
+	assert.False(t, ok)
+}
@@ -180,6 +180,62 @@ func (s *AuditService) LogWithdraw(ctx *AuditContext, withdrawID int64, amount i
 	s.saveLog(auditLog)
 }
 
+// LogTransfer 记录钱包划转操作
+func (s *AuditService) LogTransfer(ctx *AuditContext, transferID int64, action string, description string, amount int64, success bool, failMsg string) {
+	auditLog := &models.AuditLog{
+		LogType:       models.AuditLogTypeTransfer,
+		LogLevel:      models.AuditLogLevelCritical,
+		UserID:        ctx.UserID,
+		Username:      ctx.Username,
+		AgentID:       ctx.AgentID,
+		TargetType:    "wallet_transfer",
+		TargetID:      transferID,
+		Action:        action,
+		Description:   description,
+		NewValue:      toJSON(map[string]interface{}{"amount": amount}),
+		IP:            ctx.IP,
+		UserAgent:     ctx.UserAgent,
+		RequestPath:   ctx.RequestPath,
+		RequestMethod: ctx.RequestMethod,
+		Result:        1,
+	}
+
+	if !success {
+		auditLog.Result = 2
+		auditLog.ErrorMsg = failMsg
+	}
+
+	s.saveLog(auditLog)
+}
+
+// LogWalletAdjustment 记录钱包调账操作（发起/审批/驳回/冲正）
+func (s *AuditService) LogWalletAdjustment(ctx *AuditContext, adjustmentID int64, action string, description string, amount int64, success bool, failMsg string) {
+	auditLog := &models.AuditLog{
+		LogType:       models.AuditLogTypeWalletAdjust,
+		LogLevel:      models.AuditLogLevelCritical,
+		UserID:        ctx.UserID,
+		Username:      ctx.Username,
+		AgentID:       ctx.AgentID,
+		TargetType:    "wallet_adjustment",
+		TargetID:      adjustmentID,
+		Action:        action,
+		Description:   description,
+		NewValue:      toJSON(map[string]interface{}{"amount": amount}),
+		IP:            ctx.IP,
+		UserAgent:     ctx.UserAgent,
+		RequestPath:   ctx.RequestPath,
+		RequestMethod: ctx.RequestMethod,
+		Result:        1,
+	}
+
+	if !success {
+		auditLog.Result = 2
+		auditLog.ErrorMsg = failMsg
+	}
+
+	s.saveLog(auditLog)
+}
+
 // LogAgentCreate 记录创建代理商
 func (s *AuditService) LogAgentCreate(ctx *AuditContext, newAgentID int64, newAgentName string) {
 	auditLog := &models.AuditLog{
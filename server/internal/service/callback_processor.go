@@ -24,6 +24,10 @@ type CallbackProcessor struct {
 	terminalRepo    repository.TerminalRepository
 	profitService   *ProfitService
 	queue           async.MessageQueue
+
+	lifecycleService       *TerminalLifecycleService       // 终端生命周期服务
+	rewardLifecycleService *TerminalRewardLifecycleService // 终端奖励进度自动化服务
+	onboardingService      *MerchantOnboardingService      // 商户进件服务
 }
 
 // NewCallbackProcessor 创建回调处理服务
@@ -51,6 +55,21 @@ func NewCallbackProcessor(
 	}
 }
 
+// SetLifecycleService 设置终端生命周期服务
+func (p *CallbackProcessor) SetLifecycleService(lifecycleService *TerminalLifecycleService) {
+	p.lifecycleService = lifecycleService
+}
+
+// SetRewardLifecycleService 设置终端奖励进度自动化服务
+func (p *CallbackProcessor) SetRewardLifecycleService(rewardLifecycleService *TerminalRewardLifecycleService) {
+	p.rewardLifecycleService = rewardLifecycleService
+}
+
+// SetOnboardingService 设置商户进件服务（入网回调对账进件申请）
+func (p *CallbackProcessor) SetOnboardingService(onboardingService *MerchantOnboardingService) {
+	p.onboardingService = onboardingService
+}
+
 // ProcessMessage 处理队列消息
 func (p *CallbackProcessor) ProcessMessage(msgBytes []byte) error {
 	var msg QueueMessage
@@ -88,7 +107,7 @@ func (p *CallbackProcessor) ProcessCallback(logID int64, channelCode, actionType
 	case channel.ActionMerchantIncome:
 		processErr = p.processMerchantIncome(adapter, rawBody)
 	case channel.ActionTerminalBind:
-		processErr = p.processTerminalBind(adapter, rawBody)
+		processErr = p.processTerminalBind(adapter, rawBody, logID)
 	default:
 		processErr = fmt.Errorf("unknown action type: %s", actionType)
 	}
@@ -129,6 +148,7 @@ func (p *CallbackProcessor) processTransaction(adapter channel.ChannelAdapter, r
 		ReceivedAt:  time.Now(),
 		ExtData:     string(extDataBytes),
 	}
+	p.resolveTransactionMerchant(tx, unified.MerchantNo)
 
 	// 3. 检查是否已存在（幂等）
 	existing, _ := p.transactionRepo.FindByOrderNo(tx.OrderNo)
@@ -156,6 +176,27 @@ func (p *CallbackProcessor) processTransaction(adapter channel.ChannelAdapter, r
 	return nil
 }
 
+// resolveTransactionMerchant 按商户号（缺省时按终端SN）关联商户，补齐交易的商户ID和通道ID
+// 商户未入库时通道ID取自终端，商户查询和交易风控均依赖这两个字段
+func (p *CallbackProcessor) resolveTransactionMerchant(tx *repository.Transaction, merchantNo string) {
+	var merchant *models.Merchant
+	if merchantNo != "" {
+		merchant, _ = p.merchantRepo.FindByMerchantNo(merchantNo)
+	} else if tx.TerminalSN != "" {
+		merchant, _ = p.merchantRepo.FindByTerminalSN(tx.TerminalSN)
+	}
+	if merchant != nil {
+		tx.MerchantID = merchant.ID
+		tx.ChannelID = merchant.ChannelID
+	}
+
+	if tx.ChannelID == 0 && tx.TerminalSN != "" {
+		if terminal, err := p.terminalRepo.FindBySN(tx.TerminalSN); err == nil && terminal != nil {
+			tx.ChannelID = terminal.ChannelID
+		}
+	}
+}
+
 // ProfitMessage 分润计算消息
 type ProfitMessage struct {
 	TransactionID int64  `json:"transaction_id"`
@@ -182,6 +223,7 @@ func (p *CallbackProcessor) processDeviceFee(adapter channel.ChannelAdapter, raw
 		ChargingTime: unified.ChargingTime,
 		ReceivedAt:   time.Now(),
 		BrandCode:    unified.BrandCode,
+		ICCID:        unified.ICCID,
 	}
 
 	// 检查是否已存在
@@ -231,25 +273,31 @@ func (p *CallbackProcessor) processMerchantIncome(adapter channel.ChannelAdapter
 		return fmt.Errorf("parse merchant income failed: %w", err)
 	}
 
+	// 对账本系统提交的进件申请（审核通过时创建本地商户，需先于商户审核状态更新）
+	if p.onboardingService != nil {
+		if err := p.onboardingService.ReconcileIncome(unified); err != nil {
+			log.Printf("[CallbackProcessor] Reconcile merchant application %s failed: %v", unified.MerchantNo, err)
+		}
+	}
+
 	// 更新商户表审核状态
 	if p.merchantRepo != nil && unified.MerchantNo != "" {
 		merchant, err := p.merchantRepo.FindByMerchantNo(unified.MerchantNo)
 		if err == nil && merchant != nil {
 			// 根据审核状态更新商户审核状态
-			var newStatus int16
-			switch unified.ApproveStatus {
-			case 1: // 审核通过
-				newStatus = models.MerchantApproveStatusApproved
-			case 2: // 审核拒绝
-				newStatus = models.MerchantApproveStatusRejected
-			default:
-				newStatus = models.MerchantApproveStatusPending
-			}
+			newStatus := merchantApproveStatusFromIncome(unified.ApproveStatus)
 			if err := p.merchantRepo.UpdateApproveStatus(merchant.ID, newStatus); err != nil {
 				log.Printf("[CallbackProcessor] Update merchant approve status failed: %v", err)
 			} else {
 				log.Printf("[CallbackProcessor] Merchant %s approve status updated to %d", unified.MerchantNo, newStatus)
 			}
+
+			// 法人身份证有效期：每次入网回调都以通道最新数据为准
+			if start, end, longTerm, ok := parseIDCardValidity(unified.IDCardStartDate, unified.IDCardEndDate); ok {
+				if err := p.merchantRepo.UpdateIDCardValidity(merchant.ID, start, end, longTerm); err != nil {
+					log.Printf("[CallbackProcessor] Update merchant %s id card validity failed: %v", unified.MerchantNo, err)
+				}
+			}
 		}
 	}
 
@@ -257,32 +305,33 @@ func (p *CallbackProcessor) processMerchantIncome(adapter channel.ChannelAdapter
 	return nil
 }
 
+// merchantApproveStatusFromIncome merc_income回调审核状态（1审核中 2审核通过 3审核失败 4商户停用）转换为商户审核状态
+// 此前按1通过、2拒绝处理，会把审核中误记为已通过、把审核通过误记为已拒绝；商户停用等其他状态视为待审核
+func merchantApproveStatusFromIncome(approveStatus int) int16 {
+	switch approveStatus {
+	case 2:
+		return models.MerchantApproveStatusApproved
+	case 3:
+		return models.MerchantApproveStatusRejected
+	}
+	return models.MerchantApproveStatusPending
+}
+
 // processTerminalBind 处理终端绑定回调
-func (p *CallbackProcessor) processTerminalBind(adapter channel.ChannelAdapter, rawBody []byte) error {
+func (p *CallbackProcessor) processTerminalBind(adapter channel.ChannelAdapter, rawBody []byte, logID int64) error {
 	unified, err := adapter.ParseTerminalBind(rawBody)
 	if err != nil {
 		return fmt.Errorf("parse terminal bind failed: %w", err)
 	}
 
-	// 更新终端表状态
-	if p.terminalRepo != nil && unified.TerminalSN != "" {
-		terminal, err := p.terminalRepo.FindBySN(unified.TerminalSN)
-		if err == nil && terminal != nil {
-			// 根据绑定状态更新终端状态
-			var newStatus int16
-			switch unified.BindStatus {
-			case 1: // 绑定成功
-				newStatus = models.TerminalStatusActivated
-			case 2: // 解绑
-				newStatus = models.TerminalStatusAllocated
-			default:
-				newStatus = terminal.Status // 保持原状态
-			}
-			if err := p.terminalRepo.UpdateStatus(terminal.ID, newStatus); err != nil {
-				log.Printf("[CallbackProcessor] Update terminal status failed: %v", err)
-			} else {
-				log.Printf("[CallbackProcessor] Terminal %s status updated to %d", unified.TerminalSN, newStatus)
-			}
+	// 按绑定状态流转终端生命周期
+	if p.lifecycleService != nil && unified.TerminalSN != "" {
+		switch unified.BindStatus {
+		case 1: // 绑定成功：通道绑定即视为激活
+			p.applyTerminalEvent(unified, logID, models.TerminalEventBind)
+			p.applyTerminalEvent(unified, logID, models.TerminalEventActivate)
+		case 2: // 解绑
+			p.applyTerminalEvent(unified, logID, models.TerminalEventUnbind)
 		}
 	}
 
@@ -290,6 +339,29 @@ func (p *CallbackProcessor) processTerminalBind(adapter channel.ChannelAdapter,
 	return nil
 }
 
+// applyTerminalEvent 将绑定回调转换为终端生命周期事件
+func (p *CallbackProcessor) applyTerminalEvent(unified *channel.UnifiedTerminalBind, logID int64, event string) {
+	history, err := p.lifecycleService.Transition(unified.TerminalSN, &TerminalTransitionRequest{
+		Event:      event,
+		MerchantNo: unified.MerchantNo,
+		ActorType:  models.TerminalActorChannel,
+		ActorName:  unified.ChannelCode,
+		SourceType: models.TerminalSourceCallback,
+		SourceID:   logID,
+	})
+	if err != nil {
+		log.Printf("[CallbackProcessor] Terminal %s event %s failed: %v", unified.TerminalSN, event, err)
+		return
+	}
+
+	// 按规则开启/终止奖励进度，失败不影响终端状态流转
+	if history != nil && p.rewardLifecycleService != nil {
+		if err := p.rewardLifecycleService.OnTerminalEvent(history); err != nil {
+			log.Printf("[CallbackProcessor] Terminal %s reward progress on %s failed: %v", unified.TerminalSN, event, err)
+		}
+	}
+}
+
 // markSuccess 标记处理成功
 func (p *CallbackProcessor) markSuccess(logID int64) error {
 	return p.callbackRepo.UpdateStatus(logID, models.ProcessStatusSuccess, "")
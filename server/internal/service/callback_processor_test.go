@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/channel"
+	"xiangshoufu/internal/channel/hengxintong"
+	"xiangshoufu/internal/models"
+)
+
+func TestMerchantApproveStatusFromIncome(t *testing.T) {
+	tests := []struct {
+		approveStatus int
+		want          int16
+	}{
+		{1, models.MerchantApproveStatusPending},  // 审核中
+		{2, models.MerchantApproveStatusApproved}, // 审核通过
+		{3, models.MerchantApproveStatusRejected}, // 审核失败
+		{4, models.MerchantApproveStatusPending},  // 商户停用
+		{0, models.MerchantApproveStatusPending},
+	}
+
+	for _, tt := range tests {
+		if got := merchantApproveStatusFromIncome(tt.approveStatus); got != tt.want {
+			t.Errorf("merchantApproveStatusFromIncome(%d) = %d, want %d", tt.approveStatus, got, tt.want)
+		}
+	}
+}
+
+// processorMockCallbackRepository 模拟回调日志仓库，只记录处理状态
+type processorMockCallbackRepository struct {
+	statuses map[int64]int16
+	errors   map[int64]string
+}
+
+func (m *processorMockCallbackRepository) Create(log *models.RawCallbackLog) error { return nil }
+func (m *processorMockCallbackRepository) Update(log *models.RawCallbackLog) error { return nil }
+func (m *processorMockCallbackRepository) FindByID(id int64) (*models.RawCallbackLog, error) {
+	return nil, nil
+}
+func (m *processorMockCallbackRepository) FindByIdempotentKey(key string) (*models.RawCallbackLog, error) {
+	return nil, nil
+}
+func (m *processorMockCallbackRepository) FindPendingLogs(limit int) ([]*models.RawCallbackLog, error) {
+	return nil, nil
+}
+func (m *processorMockCallbackRepository) FindFailedLogs(maxRetry int, limit int) ([]*models.RawCallbackLog, error) {
+	return nil, nil
+}
+func (m *processorMockCallbackRepository) UpdateStatus(id int64, status int16, errorMsg string) error {
+	m.statuses[id] = status
+	m.errors[id] = errorMsg
+	return nil
+}
+func (m *processorMockCallbackRepository) IncrementRetryCount(id int64) error { return nil }
+
+// processorMockMerchantRepository 模拟商户仓库
+type processorMockMerchantRepository struct {
+	merchants []*models.Merchant
+}
+
+func (m *processorMockMerchantRepository) FindByID(id int64) (*models.Merchant, error) {
+	for _, merchant := range m.merchants {
+		if merchant.ID == id {
+			return merchant, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *processorMockMerchantRepository) FindByMerchantNo(merchantNo string) (*models.Merchant, error) {
+	for _, merchant := range m.merchants {
+		if merchant.MerchantNo == merchantNo {
+			return merchant, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *processorMockMerchantRepository) FindByTerminalSN(terminalSN string) (*models.Merchant, error) {
+	for _, merchant := range m.merchants {
+		if merchant.TerminalSN == terminalSN {
+			return merchant, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+func (m *processorMockMerchantRepository) UpdateApproveStatus(id int64, status int16) error {
+	return nil
+}
+func (m *processorMockMerchantRepository) UpdateIDCardValidity(id int64, startDate, endDate *time.Time, longTerm bool) error {
+	return nil
+}
+
+// TestProcessTransactionFeedsTxRisk 交易回调入库时关联商户和通道，入库交易可被风控规则命中
+func TestProcessTransactionFeedsTxRisk(t *testing.T) {
+	adapter, _ := hengxintong.NewAdapter(&channel.ChannelConfig{})
+	factory := channel.GetFactory()
+	factory.Register(adapter)
+
+	terminalRepo := NewMockTerminalRepository()
+	terminalRepo.AddTerminal("SN-RISK-001", 10, 3, models.TerminalStatusActivated)
+	terminalRepo.AddTerminal("SN-RISK-002", 10, 4, models.TerminalStatusActivated)
+
+	tests := []struct {
+		name          string
+		orderNo       string
+		terminalSN    string
+		merchantNo    string
+		wantMerchant  int64
+		wantChannelID int64
+	}{
+		{"按商户号关联", "ORDER-RISK-1", "SN-RISK-001", "M-RISK-001", 101, 3},
+		{"商户号缺省时按终端SN关联", "ORDER-RISK-2", "SN-RISK-002", "", 102, 4},
+		{"商户未入库时通道取自终端", "ORDER-RISK-3", "SN-RISK-001", "M-UNKNOWN", 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callbackRepo := &processorMockCallbackRepository{statuses: map[int64]int16{}, errors: map[int64]string{}}
+			transactionRepo := NewProfitMockTransactionRepository()
+			merchantRepo := &processorMockMerchantRepository{merchants: []*models.Merchant{
+				{ID: 101, MerchantNo: "M-RISK-001", ChannelID: 3, TerminalSN: "SN-RISK-001"},
+				{ID: 102, MerchantNo: "M-RISK-002", ChannelID: 4, TerminalSN: "SN-RISK-002"},
+			}}
+			processor := NewCallbackProcessor(factory, callbackRepo, transactionRepo, nil, nil,
+				merchantRepo, terminalRepo, nil, NewProfitMockMessageQueue())
+
+			// 9900元整数金额，略低于1万元限额
+			rawBody := []byte(`{"action":"pos_order","brandCode":"HXT001","tusn":"` + tt.terminalSN +
+				`","transTime":"2024-01-15 10:30:00","orderNo":"` + tt.orderNo +
+				`","transCardType":"02","cardNo":"6228480402564890018","amount":"990000",` +
+				`"transactionFee":"0.60","feeExt":"0","merchantNo":"` + tt.merchantNo + `","agentId":"10"}`)
+			if err := processor.ProcessCallback(1, channel.ChannelCodeHengxintong, string(channel.ActionTransaction), rawBody); err != nil {
+				t.Fatalf("ProcessCallback() error = %v", err)
+			}
+			if callbackRepo.statuses[1] != models.ProcessStatusSuccess {
+				t.Fatalf("callback status = %d, err = %s", callbackRepo.statuses[1], callbackRepo.errors[1])
+			}
+
+			tx, _ := transactionRepo.FindByOrderNo(tt.orderNo)
+			if tx == nil {
+				t.Fatalf("transaction %s not saved", tt.orderNo)
+			}
+			if tx.MerchantID != tt.wantMerchant || tx.ChannelID != tt.wantChannelID {
+				t.Errorf("merchant_id = %d, channel_id = %d, want %d, %d", tx.MerchantID, tx.ChannelID, tt.wantMerchant, tt.wantChannelID)
+			}
+
+			wantEvaluated := tt.wantMerchant != 0
+			if got := isTxRiskCandidate(tx); got != wantEvaluated {
+				t.Fatalf("isTxRiskCandidate() = %v, want %v", got, wantEvaluated)
+			}
+			if !wantEvaluated {
+				return
+			}
+
+			config := &models.TxRiskConfig{
+				NearLimitEnabled:   true,
+				NearLimitAmounts:   models.Int64List{1000000},
+				NearLimitMargin:    50000,
+				NearLimitRoundUnit: 10000,
+				NearLimitScore:     20,
+			}
+			hits := (&TxRiskService{}).collectHits(tx, nil, config)
+			if len(hits) != 1 || hits[0].Rule != models.TxRiskRuleNearLimit {
+				t.Errorf("collectHits() = %+v, want near limit hit", hits)
+			}
+		})
+	}
+}
@@ -0,0 +1,581 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// 流失预警处理参数
+const (
+	churnWatchBatchSize     = 500
+	churnWatchLookbackDays  = 90 // 回溯期内无交易的商户视为早已流失，不再生成任务
+	churnWatchMessageMaxNum = 5
+)
+
+// ChurnWatchService 商户流失预警服务
+// 每天按近两周交易额环比、距最近交易天数识别有流失趋势的商户，给所属代理商生成跟进任务并发送消息；
+// 代理商记录跟进结果，商户交易恢复后任务自动标记为已挽回
+type ChurnWatchService struct {
+	churnRepo      *repository.GormChurnWatchRepository
+	agentRepo      repository.AgentRepository
+	hierarchyRepo  repository.HierarchyRepository
+	messageService *MessageService
+}
+
+// NewChurnWatchService 创建商户流失预警服务
+func NewChurnWatchService(churnRepo *repository.GormChurnWatchRepository, agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository) *ChurnWatchService {
+	return &ChurnWatchService{
+		churnRepo:     churnRepo,
+		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（预警通知）
+func (s *ChurnWatchService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// FollowUpOperator 跟进操作人
+type FollowUpOperator struct {
+	UserID  int64
+	Name    string
+	AgentID int64
+	IsAdmin bool
+}
+
+// ============================================================
+// 配置
+// ============================================================
+
+// defaultChurnWatchConfig 默认预警配置
+func defaultChurnWatchConfig() *models.ChurnWatchConfig {
+	return &models.ChurnWatchConfig{
+		Enabled:        true,
+		DropPercent:    50,
+		MinBaseAmount:  100000,
+		InactiveDays:   15,
+		RecoverPercent: 80,
+		CooldownDays:   30,
+		FollowUpDays:   7,
+	}
+}
+
+// GetConfig 获取预警配置（未配置时返回默认值）
+func (s *ChurnWatchService) GetConfig() (*models.ChurnWatchConfig, error) {
+	config, err := s.churnRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取流失预警配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultChurnWatchConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateChurnWatchConfigRequest 更新预警配置请求
+type UpdateChurnWatchConfigRequest struct {
+	Enabled        bool  `json:"enabled"`
+	DropPercent    int   `json:"drop_percent"`    // 近7天交易额较前7天下降百分比阈值
+	MinBaseAmount  int64 `json:"min_base_amount"` // 前7天交易额低于该值（分）不做环比预警
+	InactiveDays   int   `json:"inactive_days"`   // 距最近交易天数阈值
+	RecoverPercent int   `json:"recover_percent"` // 近7天交易额恢复到基准的百分比视为已挽回
+	CooldownDays   int   `json:"cooldown_days"`   // 任务结束后不再生成任务的天数
+	FollowUpDays   int   `json:"follow_up_days"`  // 跟进期限（天）
+}
+
+// UpdateConfig 更新预警配置
+func (s *ChurnWatchService) UpdateConfig(req *UpdateChurnWatchConfigRequest, operatorID int64, operatorName string) (*models.ChurnWatchConfig, error) {
+	switch {
+	case req.DropPercent < 10 || req.DropPercent > 100:
+		return nil, errors.New("下降百分比必须在10-100之间")
+	case req.MinBaseAmount < 0:
+		return nil, errors.New("环比基准金额不能为负数")
+	case req.InactiveDays < 3 || req.InactiveDays >= churnWatchLookbackDays:
+		return nil, fmt.Errorf("无交易天数必须在3-%d之间", churnWatchLookbackDays-1)
+	case req.RecoverPercent < 10 || req.RecoverPercent > 200:
+		return nil, errors.New("挽回百分比必须在10-200之间")
+	case req.CooldownDays < 0 || req.CooldownDays > 365:
+		return nil, errors.New("冷却天数必须在0-365之间")
+	case req.FollowUpDays < 1 || req.FollowUpDays > 60:
+		return nil, errors.New("跟进期限必须在1-60天之间")
+	}
+
+	config, err := s.churnRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取流失预警配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.ChurnWatchConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.DropPercent = req.DropPercent
+	config.MinBaseAmount = req.MinBaseAmount
+	config.InactiveDays = req.InactiveDays
+	config.RecoverPercent = req.RecoverPercent
+	config.CooldownDays = req.CooldownDays
+	config.FollowUpDays = req.FollowUpDays
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+
+	if err := s.churnRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存流失预警配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// ============================================================
+// 检测
+// ============================================================
+
+// ChurnWatchResult 检测结果
+type ChurnWatchResult struct {
+	Scanned   int `json:"scanned"`   // 检测的商户数
+	Created   int `json:"created"`   // 新生成的跟进任务数
+	Recovered int `json:"recovered"` // 交易恢复自动挽回的任务数
+	Agents    int `json:"agents"`    // 通知的代理商数
+	Failed    int `json:"failed"`
+}
+
+// Scan 检测流失趋势：先将交易恢复的任务标记为已挽回，再为新出现流失趋势的商户生成跟进任务并通知代理商
+func (s *ChurnWatchService) Scan(now time.Time) (*ChurnWatchResult, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ChurnWatchResult{}
+	if err := s.recoverTasks(config, now, result); err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return result, nil
+	}
+
+	created := make(map[int64][]*models.MerchantFollowUpTask)
+	var afterID int64
+	for {
+		targets, err := s.churnRepo.FindWatchTargets(afterID, churnWatchBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("查询商户失败: %w", err)
+		}
+		if len(targets) == 0 {
+			break
+		}
+		afterID = targets[len(targets)-1].ID
+		result.Scanned += len(targets)
+
+		tasks, err := s.detectBatch(targets, config, now)
+		if err != nil {
+			log.Printf("[ChurnWatchService] Detect batch after %d failed: %v", afterID, err)
+			result.Failed += len(targets)
+			continue
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+		if err := s.churnRepo.CreateTasks(tasks); err != nil {
+			log.Printf("[ChurnWatchService] Create follow-up tasks failed: %v", err)
+			result.Failed += len(tasks)
+			continue
+		}
+		result.Created += len(tasks)
+		for _, task := range tasks {
+			created[task.AgentID] = append(created[task.AgentID], task)
+		}
+	}
+
+	agentIDs := make([]int64, 0, len(created))
+	for agentID := range created {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Slice(agentIDs, func(i, j int) bool { return agentIDs[i] < agentIDs[j] })
+	for _, agentID := range agentIDs {
+		title, content := churnWarningMessage(created[agentID])
+		s.sendMessage(agentID, title, content)
+		result.Agents++
+	}
+	return result, nil
+}
+
+// detectBatch 识别一批商户的流失趋势，返回待创建的跟进任务
+func (s *ChurnWatchService) detectBatch(targets []*repository.ChurnWatchTarget, config *models.ChurnWatchConfig, now time.Time) ([]*models.MerchantFollowUpTask, error) {
+	ids := make([]int64, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+	}
+	trends, err := s.churnRepo.GetTradeTrends(ids, now, churnWatchLookbackDays)
+	if err != nil {
+		return nil, fmt.Errorf("统计交易趋势失败: %w", err)
+	}
+	suppressed, err := s.churnRepo.FindSuppressedMerchants(ids, now.AddDate(0, 0, -config.CooldownDays))
+	if err != nil {
+		return nil, fmt.Errorf("查询已有任务失败: %w", err)
+	}
+
+	var tasks []*models.MerchantFollowUpTask
+	for _, target := range targets {
+		if suppressed[target.ID] {
+			continue
+		}
+		trend := trends[target.ID]
+		trigger, inactiveDays := detectChurnTrigger(trend, config, now)
+		if trigger == "" {
+			continue
+		}
+		tasks = append(tasks, &models.MerchantFollowUpTask{
+			MerchantID:     target.ID,
+			MerchantNo:     target.MerchantNo,
+			MerchantName:   target.MerchantName,
+			AgentID:        target.AgentID,
+			TriggerType:    trigger,
+			LastWeekAmount: trend.LastWeekAmount,
+			PrevWeekAmount: trend.PrevWeekAmount,
+			BaseAmount:     trend.PrevWeekAmount,
+			LastTradeAt:    trend.LastTradeAt,
+			InactiveDays:   inactiveDays,
+			Status:         models.FollowUpStatusPending,
+			DueAt:          now.AddDate(0, 0, config.FollowUpDays),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	return tasks, nil
+}
+
+// recoverTasks 将预警后交易恢复的未结束任务标记为已挽回
+func (s *ChurnWatchService) recoverTasks(config *models.ChurnWatchConfig, now time.Time, result *ChurnWatchResult) error {
+	var afterID int64
+	for {
+		tasks, err := s.churnRepo.FindOpenTasks(afterID, churnWatchBatchSize)
+		if err != nil {
+			return fmt.Errorf("查询跟进任务失败: %w", err)
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+		afterID = tasks[len(tasks)-1].ID
+
+		ids := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.MerchantID)
+		}
+		trends, err := s.churnRepo.GetTradeTrends(ids, now, churnWatchLookbackDays)
+		if err != nil {
+			return fmt.Errorf("统计交易趋势失败: %w", err)
+		}
+
+		for _, task := range tasks {
+			trend := trends[task.MerchantID]
+			if !isFollowUpRecovered(task, trend, config) {
+				continue
+			}
+			resolvedAt := now
+			task.Status = models.FollowUpStatusRecovered
+			task.LastOutcome = models.FollowUpOutcomeRecovered
+			task.ResolvedAt = &resolvedAt
+			entry := &models.MerchantFollowUpLog{
+				Outcome:      models.FollowUpOutcomeRecovered,
+				Remark:       fmt.Sprintf("近7天交易%.2f元，交易已恢复", float64(trend.LastWeekAmount)/100),
+				OperatorName: "系统",
+				CreatedAt:    now,
+			}
+			if err := s.churnRepo.SaveTaskWithLog(task, entry); err != nil {
+				log.Printf("[ChurnWatchService] Mark task %d recovered failed: %v", task.ID, err)
+				result.Failed++
+				continue
+			}
+			result.Recovered++
+		}
+	}
+}
+
+// sendMessage 发送流失预警消息
+func (s *ChurnWatchService) sendMessage(agentID int64, title, content string) {
+	if s.messageService == nil {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeChurnWarning,
+		Title:       title,
+		Content:     content,
+		RelatedType: "merchant_follow_up",
+	}); err != nil {
+		log.Printf("[ChurnWatchService] Send churn warning to agent %d failed: %v", agentID, err)
+	}
+}
+
+// ============================================================
+// 跟进任务
+// ============================================================
+
+// FollowUpTaskItem 跟进任务列表项
+type FollowUpTaskItem struct {
+	*models.MerchantFollowUpTask
+	StatusName  string `json:"status_name"`
+	TriggerName string `json:"trigger_name"`
+	Overdue     bool   `json:"overdue"` // 超期未跟进
+}
+
+func newFollowUpTaskItem(task *models.MerchantFollowUpTask, now time.Time) *FollowUpTaskItem {
+	return &FollowUpTaskItem{
+		MerchantFollowUpTask: task,
+		StatusName:           models.GetFollowUpStatusName(task.Status),
+		TriggerName:          models.GetChurnTriggerName(task.TriggerType),
+		Overdue:              task.Status == models.FollowUpStatusPending && task.DueAt.Before(now),
+	}
+}
+
+// ListTasks 查询跟进任务，代理商只能查看本人及下级的任务
+func (s *ChurnWatchService) ListTasks(filter *repository.FollowUpTaskFilter, agentID int64, isAdmin bool, page, pageSize int) ([]*FollowUpTaskItem, int64, error) {
+	if !isAdmin {
+		agent, err := s.agentRepo.FindByID(agentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	tasks, total, err := s.churnRepo.ListTasks(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询跟进任务失败: %w", err)
+	}
+	now := time.Now()
+	items := make([]*FollowUpTaskItem, 0, len(tasks))
+	for _, task := range tasks {
+		items = append(items, newFollowUpTaskItem(task, now))
+	}
+	return items, total, nil
+}
+
+// FollowUpTaskDetail 跟进任务详情
+type FollowUpTaskDetail struct {
+	*FollowUpTaskItem
+	Logs []*models.MerchantFollowUpLog `json:"logs"` // 跟进记录（新到旧）
+}
+
+// GetTask 获取跟进任务详情
+func (s *ChurnWatchService) GetTask(id int64, agentID int64, isAdmin bool) (*FollowUpTaskDetail, error) {
+	task, err := s.findAccessibleTask(id, agentID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := s.churnRepo.FindLogs(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询跟进记录失败: %w", err)
+	}
+	return &FollowUpTaskDetail{FollowUpTaskItem: newFollowUpTaskItem(task, time.Now()), Logs: logs}, nil
+}
+
+// LogFollowUpRequest 记录跟进结果请求
+type LogFollowUpRequest struct {
+	Outcome string `json:"outcome" binding:"required"` // contacted已联系 replaced_terminal已换机 lost已流失
+	Remark  string `json:"remark"`
+}
+
+// LogFollowUp 记录跟进结果：已联系、已换机的任务进入跟进中，已流失的任务结束
+func (s *ChurnWatchService) LogFollowUp(id int64, req *LogFollowUpRequest, operator *FollowUpOperator) (*models.MerchantFollowUpTask, error) {
+	task, err := s.findAccessibleTask(id, operator.AgentID, operator.IsAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !task.IsOpen() {
+		return nil, errors.New("跟进任务已结束")
+	}
+	remark := []rune(strings.TrimSpace(req.Remark))
+	if len(remark) > 500 {
+		return nil, errors.New("备注最多500字")
+	}
+
+	now := time.Now()
+	switch req.Outcome {
+	case models.FollowUpOutcomeContacted, models.FollowUpOutcomeReplacedTerminal:
+		task.Status = models.FollowUpStatusFollowing
+	case models.FollowUpOutcomeLost:
+		task.Status = models.FollowUpStatusLost
+		task.ResolvedAt = &now
+	default:
+		return nil, fmt.Errorf("不支持的跟进结果: %s", req.Outcome)
+	}
+	task.LastOutcome = req.Outcome
+	task.FollowUpCount++
+
+	entry := &models.MerchantFollowUpLog{
+		Outcome:      req.Outcome,
+		Remark:       string(remark),
+		OperatorID:   operator.UserID,
+		OperatorName: operator.Name,
+		CreatedAt:    now,
+	}
+	if err := s.churnRepo.SaveTaskWithLog(task, entry); err != nil {
+		return nil, fmt.Errorf("记录跟进结果失败: %w", err)
+	}
+	return task, nil
+}
+
+// findAccessibleTask 获取当前代理商可访问的跟进任务
+func (s *ChurnWatchService) findAccessibleTask(id int64, agentID int64, isAdmin bool) (*models.MerchantFollowUpTask, error) {
+	task, err := s.churnRepo.FindTaskByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询跟进任务失败: %w", err)
+	}
+	if task == nil {
+		return nil, errors.New("跟进任务不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, agentID, isAdmin).canAccess(task.AgentID) {
+		return nil, errors.New("无权操作该跟进任务")
+	}
+	return task, nil
+}
+
+// ============================================================
+// 挽回率报表
+// ============================================================
+
+// FollowUpReportRow 代理商挽回率
+type FollowUpReportRow struct {
+	*repository.FollowUpAgentStats
+	RecoveryRate float64 `json:"recovery_rate"` // 挽回率（%）= 已挽回 / 任务数
+	ContactRate  float64 `json:"contact_rate"`  // 跟进率（%）= 有跟进记录的任务 / 任务数
+}
+
+// FollowUpReport 挽回率报表
+type FollowUpReport struct {
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Summary   *FollowUpReportRow   `json:"summary"`
+	Agents    []*FollowUpReportRow `json:"agents"`
+}
+
+// GetReport 按代理商统计[start, end]内生成的跟进任务的挽回率，代理商只能查看本人及下级
+func (s *ChurnWatchService) GetReport(start, end time.Time, agentID int64, isAdmin bool, now time.Time) (*FollowUpReport, error) {
+	if end.Before(start) {
+		return nil, errors.New("结束日期不能早于开始日期")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, errors.New("统计区间最长一年")
+	}
+
+	var scopeAgentID int64
+	if !isAdmin {
+		agent, err := s.agentRepo.FindByID(agentID)
+		if err != nil || agent == nil {
+			return nil, errors.New("代理商不存在")
+		}
+		scopeAgentID = agent.ID
+	}
+
+	stats, err := s.churnRepo.GetAgentStats(scopeAgentID, start, end.AddDate(0, 0, 1), now)
+	if err != nil {
+		return nil, fmt.Errorf("统计跟进任务失败: %w", err)
+	}
+
+	report := &FollowUpReport{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Agents:    make([]*FollowUpReportRow, 0, len(stats)),
+	}
+	summary := &repository.FollowUpAgentStats{AgentName: "合计"}
+	for _, row := range stats {
+		report.Agents = append(report.Agents, newFollowUpReportRow(row))
+		summary.Total += row.Total
+		summary.Pending += row.Pending
+		summary.Following += row.Following
+		summary.Recovered += row.Recovered
+		summary.Lost += row.Lost
+		summary.Contacted += row.Contacted
+		summary.Overdue += row.Overdue
+	}
+	report.Summary = newFollowUpReportRow(summary)
+	return report, nil
+}
+
+func newFollowUpReportRow(stats *repository.FollowUpAgentStats) *FollowUpReportRow {
+	return &FollowUpReportRow{
+		FollowUpAgentStats: stats,
+		RecoveryRate:       followUpPercent(stats.Recovered, stats.Total),
+		ContactRate:        followUpPercent(stats.Contacted, stats.Total),
+	}
+}
+
+// ============================================================
+// 工具函数
+// ============================================================
+
+// detectChurnTrigger 判断商户是否出现流失趋势，返回触发类型及距最近交易天数；回溯期内无交易的不预警
+func detectChurnTrigger(trend *repository.MerchantTradeTrend, config *models.ChurnWatchConfig, now time.Time) (string, int) {
+	if trend == nil || trend.LastTradeAt == nil {
+		return "", 0
+	}
+
+	inactiveDays := int(now.Sub(*trend.LastTradeAt).Hours() / 24)
+	if inactiveDays >= config.InactiveDays {
+		return models.ChurnTriggerInactive, inactiveDays
+	}
+	if trend.PrevWeekAmount > 0 && trend.PrevWeekAmount >= config.MinBaseAmount &&
+		(trend.PrevWeekAmount-trend.LastWeekAmount)*100 >= trend.PrevWeekAmount*int64(config.DropPercent) {
+		return models.ChurnTriggerVolumeDrop, inactiveDays
+	}
+	return "", inactiveDays
+}
+
+// isFollowUpRecovered 预警后有新交易，且近7天交易额恢复到基准的指定百分比（无基准时有交易即可）
+func isFollowUpRecovered(task *models.MerchantFollowUpTask, trend *repository.MerchantTradeTrend, config *models.ChurnWatchConfig) bool {
+	if trend == nil || trend.LastTradeAt == nil || !trend.LastTradeAt.After(task.CreatedAt) {
+		return false
+	}
+	if task.BaseAmount <= 0 {
+		return trend.LastWeekCount > 0
+	}
+	return trend.LastWeekAmount*100 >= task.BaseAmount*int64(config.RecoverPercent)
+}
+
+// churnWarningMessage 生成流失预警消息，列出前几个商户
+func churnWarningMessage(tasks []*models.MerchantFollowUpTask) (string, string) {
+	title := fmt.Sprintf("%d个商户有流失风险", len(tasks))
+
+	parts := make([]string, 0, churnWatchMessageMaxNum)
+	for i, task := range tasks {
+		if i >= churnWatchMessageMaxNum {
+			break
+		}
+		var reason string
+		switch task.TriggerType {
+		case models.ChurnTriggerInactive:
+			reason = fmt.Sprintf("%d天无交易", task.InactiveDays)
+		default:
+			reason = fmt.Sprintf("近7天交易%.2f元，前7天%.2f元", float64(task.LastWeekAmount)/100, float64(task.PrevWeekAmount)/100)
+		}
+		name := task.MerchantName
+		if name == "" {
+			name = task.MerchantNo
+		}
+		parts = append(parts, fmt.Sprintf("%s（%s）", name, reason))
+	}
+
+	content := "以下商户交易下滑：" + strings.Join(parts, "、")
+	if len(tasks) > churnWatchMessageMaxNum {
+		content += fmt.Sprintf("等%d个商户", len(tasks))
+	}
+	content += "。已生成跟进任务，请及时联系商户并记录跟进结果。"
+	return title, content
+}
+
+// followUpPercent 百分比，保留两位小数
+func followUpPercent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(n)*10000/float64(total)) / 100
+}
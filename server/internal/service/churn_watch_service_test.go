@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestDetectChurnTrigger(t *testing.T) {
+	config := defaultChurnWatchConfig()
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.Local)
+	daysAgo := func(d int) *time.Time {
+		t := now.AddDate(0, 0, -d)
+		return &t
+	}
+
+	tests := []struct {
+		name     string
+		trend    *repository.MerchantTradeTrend
+		want     string
+		wantDays int
+	}{
+		{"回溯期内无交易", nil, "", 0},
+		{"长时间无交易", &repository.MerchantTradeTrend{LastTradeAt: daysAgo(20)}, models.ChurnTriggerInactive, 20},
+		{"交易额下降一半", &repository.MerchantTradeTrend{LastTradeAt: daysAgo(1), PrevWeekAmount: 1000000, LastWeekAmount: 500000}, models.ChurnTriggerVolumeDrop, 1},
+		{"下降不足", &repository.MerchantTradeTrend{LastTradeAt: daysAgo(1), PrevWeekAmount: 1000000, LastWeekAmount: 500001}, "", 1},
+		{"基准过小不做环比", &repository.MerchantTradeTrend{LastTradeAt: daysAgo(8), PrevWeekAmount: 99999}, "", 8},
+		{"前7天无交易", &repository.MerchantTradeTrend{LastTradeAt: daysAgo(0), LastWeekAmount: 100}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, days := detectChurnTrigger(tt.trend, config, now)
+			if got != tt.want || days != tt.wantDays {
+				t.Errorf("detectChurnTrigger() = %q, %d, want %q, %d", got, days, tt.want, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestIsFollowUpRecovered(t *testing.T) {
+	config := defaultChurnWatchConfig()
+	created := time.Date(2026, 6, 1, 0, 0, 0, 0, time.Local)
+	before := created.Add(-time.Hour)
+	after := created.AddDate(0, 0, 3)
+
+	task := &models.MerchantFollowUpTask{BaseAmount: 1000000, CreatedAt: created}
+	tests := []struct {
+		name  string
+		task  *models.MerchantFollowUpTask
+		trend *repository.MerchantTradeTrend
+		want  bool
+	}{
+		{"无交易", task, nil, false},
+		{"预警后无新交易", task, &repository.MerchantTradeTrend{LastTradeAt: &before, LastWeekAmount: 2000000}, false},
+		{"恢复到80%", task, &repository.MerchantTradeTrend{LastTradeAt: &after, LastWeekAmount: 800000}, true},
+		{"恢复不足", task, &repository.MerchantTradeTrend{LastTradeAt: &after, LastWeekAmount: 799999}, false},
+		{"无基准有交易即可", &models.MerchantFollowUpTask{CreatedAt: created}, &repository.MerchantTradeTrend{LastTradeAt: &after, LastWeekCount: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFollowUpRecovered(tt.task, tt.trend, config); got != tt.want {
+				t.Errorf("isFollowUpRecovered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChurnWarningMessage(t *testing.T) {
+	tasks := make([]*models.MerchantFollowUpTask, 0, 6)
+	tasks = append(tasks, &models.MerchantFollowUpTask{MerchantName: "张记面馆", TriggerType: models.ChurnTriggerInactive, InactiveDays: 16})
+	tasks = append(tasks, &models.MerchantFollowUpTask{MerchantNo: "M002", TriggerType: models.ChurnTriggerVolumeDrop, PrevWeekAmount: 1000000, LastWeekAmount: 300000})
+	for i := 0; i < 4; i++ {
+		tasks = append(tasks, &models.MerchantFollowUpTask{MerchantName: "商户", TriggerType: models.ChurnTriggerInactive, InactiveDays: 20})
+	}
+
+	title, content := churnWarningMessage(tasks)
+	if title != "6个商户有流失风险" {
+		t.Errorf("title = %s", title)
+	}
+	for _, want := range []string{"张记面馆（16天无交易）", "M002（近7天交易3000.00元，前7天10000.00元）", "等6个商户"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content应包含%q, got %s", want, content)
+		}
+	}
+}
+
+func TestFollowUpPercent(t *testing.T) {
+	if got := followUpPercent(1, 3); got != 33.33 {
+		t.Errorf("followUpPercent(1, 3) = %v", got)
+	}
+	if got := followUpPercent(0, 0); got != 0 {
+		t.Errorf("followUpPercent(0, 0) = %v", got)
+	}
+}
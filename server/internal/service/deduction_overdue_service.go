@@ -0,0 +1,1151 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeductionOverdueService 代扣逾期服务
+// 每日扫描部分成功/失败且已到期的代扣记录，按计划维护逾期记录：
+// 补扣（优先使用本计划冻结金额，其次可用余额）→ 计收滞纳金 → 按节点通知 → 上报上级（可自动代偿） → 冻结出款
+type DeductionOverdueService struct {
+	overdueRepo      *repository.GormDeductionOverdueRepository
+	planRepo         repository.DeductionPlanRepository
+	agentRepo        repository.AgentRepository
+	deductionService *DeductionService
+	messageService   *MessageService
+	riskHoldService  *WalletRiskHoldService
+}
+
+// NewDeductionOverdueService 创建代扣逾期服务
+func NewDeductionOverdueService(
+	overdueRepo *repository.GormDeductionOverdueRepository,
+	planRepo repository.DeductionPlanRepository,
+	agentRepo repository.AgentRepository,
+	deductionService *DeductionService,
+) *DeductionOverdueService {
+	return &DeductionOverdueService{
+		overdueRepo:      overdueRepo,
+		planRepo:         planRepo,
+		agentRepo:        agentRepo,
+		deductionService: deductionService,
+	}
+}
+
+// SetMessageService 设置消息服务
+func (s *DeductionOverdueService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// SetRiskHoldService 设置风控冻结服务（用于冻结逾期代理商出款）
+func (s *DeductionOverdueService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
+// defaultDeductionOverdueConfig 默认配置：仅跟踪与通知，不收滞纳金、不上报、不冻结
+func defaultDeductionOverdueConfig() *models.DeductionOverdueConfig {
+	return &models.DeductionOverdueConfig{
+		NotifyDays:           "1,3,7,15,30",
+		ParentRecoverPeriods: 1,
+	}
+}
+
+// GetConfig 获取逾期处理配置
+func (s *DeductionOverdueService) GetConfig() (*models.DeductionOverdueConfig, error) {
+	config, err := s.overdueRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("查询逾期配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultDeductionOverdueConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateDeductionOverdueConfigRequest 更新逾期处理配置请求
+type UpdateDeductionOverdueConfigRequest struct {
+	GraceDays            int    `json:"grace_days"`
+	LateFeeDailyRate     int    `json:"late_fee_daily_rate"`
+	LateFeeCapRate       int    `json:"late_fee_cap_rate"`
+	NotifyDays           string `json:"notify_days"`
+	EscalateDays         int    `json:"escalate_days"`
+	AutoDrawParent       bool   `json:"auto_draw_parent"`
+	ParentRecoverPeriods int    `json:"parent_recover_periods"`
+	BlockWithdrawDays    int    `json:"block_withdraw_days"`
+}
+
+// UpdateConfig 更新逾期处理配置
+func (s *DeductionOverdueService) UpdateConfig(req *UpdateDeductionOverdueConfigRequest, operatorID int64, operatorName string) (*models.DeductionOverdueConfig, error) {
+	if req.GraceDays < 0 || req.EscalateDays < 0 || req.BlockWithdrawDays < 0 {
+		return nil, errors.New("天数不能为负数")
+	}
+	if req.LateFeeDailyRate < 0 || req.LateFeeCapRate < 0 {
+		return nil, errors.New("滞纳金费率不能为负数")
+	}
+	if req.LateFeeDailyRate > 100 {
+		return nil, errors.New("滞纳金日费率不能超过万分之100")
+	}
+	if _, err := parseNotifyDays(req.NotifyDays); err != nil {
+		return nil, err
+	}
+	if req.AutoDrawParent && req.EscalateDays == 0 {
+		return nil, errors.New("开启上级代偿需先设置上报天数")
+	}
+	if req.ParentRecoverPeriods <= 0 {
+		req.ParentRecoverPeriods = 1
+	}
+
+	config, err := s.overdueRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("查询逾期配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.DeductionOverdueConfig{}
+	}
+	config.GraceDays = req.GraceDays
+	config.LateFeeDailyRate = req.LateFeeDailyRate
+	config.LateFeeCapRate = req.LateFeeCapRate
+	config.NotifyDays = strings.TrimSpace(req.NotifyDays)
+	config.EscalateDays = req.EscalateDays
+	config.AutoDrawParent = req.AutoDrawParent
+	config.ParentRecoverPeriods = req.ParentRecoverPeriods
+	config.BlockWithdrawDays = req.BlockWithdrawDays
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+	if err := s.overdueRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存逾期配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// DeductionOverdueRunResult 逾期处理结果
+type DeductionOverdueRunResult struct {
+	Processed int   `json:"processed"` // 处理计划数
+	Opened    int   `json:"opened"`    // 新增逾期数
+	Cleared   int   `json:"cleared"`   // 结清/关闭数
+	Escalated int   `json:"escalated"` // 上报上级数
+	Blocked   int   `json:"blocked"`   // 冻结出款数
+	Collected int64 `json:"collected"` // 补扣金额（分，含滞纳金）
+	Drawn     int64 `json:"drawn"`     // 上级代偿金额（分）
+	Failed    int   `json:"failed"`    // 处理失败数
+}
+
+// ProcessOverdues 执行逾期处理（定时任务每日调用）
+func (s *DeductionOverdueService) ProcessOverdues(now time.Time) (*DeductionOverdueRunResult, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	notifyDays, _ := parseNotifyDays(config.NotifyDays)
+
+	today := startOfDay(now)
+	stats, err := s.overdueRepo.FindOverduePlanStats(today)
+	if err != nil {
+		return nil, fmt.Errorf("统计逾期计划失败: %w", err)
+	}
+	openCases, err := s.overdueRepo.FindOpen()
+	if err != nil {
+		return nil, fmt.Errorf("查询逾期记录失败: %w", err)
+	}
+
+	statByPlan := make(map[int64]*repository.OverduePlanStat, len(stats))
+	planIDs := make([]int64, 0, len(stats)+len(openCases))
+	for _, st := range stats {
+		statByPlan[st.PlanID] = st
+		planIDs = append(planIDs, st.PlanID)
+	}
+	caseByPlan := make(map[int64]*models.DeductionOverdue, len(openCases))
+	for _, c := range openCases {
+		caseByPlan[c.PlanID] = c
+		if _, ok := statByPlan[c.PlanID]; !ok {
+			planIDs = append(planIDs, c.PlanID)
+		}
+	}
+	sort.Slice(planIDs, func(i, j int) bool { return planIDs[i] < planIDs[j] })
+
+	result := &DeductionOverdueRunResult{}
+	for _, planID := range planIDs {
+		result.Processed++
+		if err := s.processPlan(config, notifyDays, planID, statByPlan[planID], caseByPlan[planID], now, result); err != nil {
+			result.Failed++
+			log.Printf("[DeductionOverdueService] Process plan %d failed: %v", planID, err)
+		}
+	}
+
+	log.Printf("[DeductionOverdueService] Overdue run finished: processed=%d, opened=%d, cleared=%d, escalated=%d, blocked=%d, collected=%d, drawn=%d, failed=%d",
+		result.Processed, result.Opened, result.Cleared, result.Escalated, result.Blocked, result.Collected, result.Drawn, result.Failed)
+	return result, nil
+}
+
+// processPlan 处理单个计划的逾期
+func (s *DeductionOverdueService) processPlan(config *models.DeductionOverdueConfig, notifyDays []int, planID int64,
+	stat *repository.OverduePlanStat, overdue *models.DeductionOverdue, now time.Time, result *DeductionOverdueRunResult) error {
+	plan, err := s.planRepo.FindByID(planID)
+	if err != nil || plan == nil {
+		return fmt.Errorf("代扣计划不存在: %d", planID)
+	}
+
+	if overdue == nil {
+		if stat == nil {
+			return nil
+		}
+		overdue = &models.DeductionOverdue{
+			OverdueNo:     newOverdueNo(),
+			PlanID:        plan.ID,
+			PlanNo:        plan.PlanNo,
+			DeductorID:    plan.DeductorID,
+			DeducteeID:    plan.DeducteeID,
+			Status:        models.DeductionOverdueStatusOpen,
+			OverdueAmount: stat.OverduePrincipal,
+			FirstDueAt:    stat.EarliestDueAt,
+			DaysPastDue:   daysPastDue(stat.EarliestDueAt, now),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := s.overdueRepo.Create(overdue); err != nil {
+			return fmt.Errorf("创建逾期记录失败: %w", err)
+		}
+		result.Opened++
+	}
+
+	// 计划已取消/拒绝：关闭逾期记录，不再追收
+	if plan.Status == models.DeductionPlanStatusCancelled || plan.Status == models.DeductionPlanStatusRejected {
+		s.closeOverdue(overdue, models.DeductionOverdueStatusClosed, now)
+		result.Cleared++
+		return s.overdueRepo.Save(overdue)
+	}
+
+	// 1. 补扣（已暂停的计划不补扣，仅更新逾期金额）
+	collected, err := s.collectOverdue(overdue, now)
+	if err != nil {
+		return err
+	}
+	result.Collected += collected
+
+	if s.tryClear(overdue, now) {
+		result.Cleared++
+		return s.overdueRepo.Save(overdue)
+	}
+
+	if overdue.OverdueAmount > 0 {
+		overdue.DaysPastDue = daysPastDue(overdue.FirstDueAt, now)
+	}
+
+	// 已暂停的计划只更新逾期金额与天数，不计滞纳金、不上报、不冻结
+	if plan.Status == models.DeductionPlanStatusActive && overdue.OverdueAmount > 0 {
+		// 2. 计收滞纳金
+		if config.LateFeeDailyRate > 0 {
+			today := startOfDay(now)
+			days := lateFeeDays(overdue.FirstDueAt, overdue.LateFeeAccruedTo, config.GraceDays, today)
+			fee := accrueLateFee(overdue.OverdueAmount, config.LateFeeDailyRate, config.LateFeeCapRate, overdue.LateFee, days)
+			if days > 0 {
+				overdue.LateFee += fee
+				overdue.LateFeeAccruedTo = &today
+			}
+		}
+
+		// 3. 按节点通知
+		if threshold := notifyThreshold(notifyDays, overdue.LastNotifiedDay, overdue.DaysPastDue); threshold > 0 {
+			s.notifyOverdue(overdue)
+			overdue.LastNotifiedDay = threshold
+		}
+
+		// 4. 上报上级（可自动代偿）
+		if config.EscalateDays > 0 && overdue.DaysPastDue >= config.EscalateDays && overdue.EscalatedAt == nil {
+			drawn, err := s.escalate(config, plan, overdue, now)
+			if err != nil {
+				log.Printf("[DeductionOverdueService] Escalate overdue %s failed: %v", overdue.OverdueNo, err)
+			} else if overdue.EscalatedAt != nil {
+				result.Escalated++
+				result.Drawn += drawn
+			}
+			if s.tryClear(overdue, now) {
+				result.Cleared++
+				return s.overdueRepo.Save(overdue)
+			}
+		}
+
+		// 5. 冻结出款
+		if config.BlockWithdrawDays > 0 && overdue.DaysPastDue >= config.BlockWithdrawDays &&
+			overdue.RiskHoldID == nil && s.riskHoldService != nil {
+			hold, err := s.riskHoldService.CreateHold(&CreateRiskHoldRequest{
+				AgentID:       overdue.DeducteeID,
+				Scope:         models.RiskHoldScopeAgent,
+				Reason:        fmt.Sprintf("代扣逾期%d天，冻结出款", overdue.DaysPastDue),
+				AgentMessage:  fmt.Sprintf("您的代扣计划%s已逾期，逾期款项结清后将自动解除出款冻结。", overdue.PlanNo),
+				CaseRef:       overdue.OverdueNo,
+				CreatedByName: "系统",
+			})
+			if err != nil {
+				log.Printf("[DeductionOverdueService] Block withdraw for overdue %s failed: %v", overdue.OverdueNo, err)
+			} else {
+				overdue.RiskHoldID = &hold.ID
+				result.Blocked++
+			}
+		}
+	}
+
+	return s.overdueRepo.Save(overdue)
+}
+
+// tryClear 逾期本金与滞纳金均已结清时关闭逾期记录
+func (s *DeductionOverdueService) tryClear(overdue *models.DeductionOverdue, now time.Time) bool {
+	if overdue.OverdueAmount > 0 || overdue.UnpaidLateFee() > 0 {
+		return false
+	}
+	s.closeOverdue(overdue, models.DeductionOverdueStatusCleared, now)
+	return true
+}
+
+// closeOverdue 结清或关闭逾期记录，解除出款冻结并通知双方
+func (s *DeductionOverdueService) closeOverdue(overdue *models.DeductionOverdue, status int16, now time.Time) {
+	overdue.Status = status
+	overdue.ClearedAt = &now
+	if status == models.DeductionOverdueStatusCleared {
+		overdue.OverdueAmount = 0
+	}
+
+	if overdue.RiskHoldID != nil && s.riskHoldService != nil {
+		if err := s.riskHoldService.ReleaseHold(*overdue.RiskHoldID, 0, "系统", "代扣逾期已"+models.GetDeductionOverdueStatusName(status)); err != nil {
+			log.Printf("[DeductionOverdueService] Release hold %d for overdue %s failed: %v", *overdue.RiskHoldID, overdue.OverdueNo, err)
+		}
+	}
+
+	title := "代扣逾期已结清"
+	content := fmt.Sprintf("代扣计划%s的逾期款项已结清。", overdue.PlanNo)
+	if status == models.DeductionOverdueStatusClosed {
+		title = "代扣逾期已关闭"
+		content = fmt.Sprintf("代扣计划%s已取消，逾期记录已关闭。", overdue.PlanNo)
+	}
+	s.sendMessage(overdue.DeducteeID, title, content, overdue.ID)
+	s.sendMessage(overdue.DeductorID, title, content, overdue.ID)
+
+	log.Printf("[DeductionOverdueService] Overdue %s %s", overdue.OverdueNo, models.GetDeductionOverdueStatusName(status))
+}
+
+// collectOverdue 补扣逾期款项并刷新逾期本金，返回本次补扣金额（含滞纳金）
+// 仅进行中的计划补扣本金；计划已完成但仍有未收滞纳金时继续补扣滞纳金
+func (s *DeductionOverdueService) collectOverdue(overdue *models.DeductionOverdue, now time.Time) (int64, error) {
+	var collected int64
+	err := s.overdueRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var plan models.DeductionPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, overdue.PlanID).Error; err != nil {
+			return fmt.Errorf("代扣计划不存在: %d", overdue.PlanID)
+		}
+
+		var records []*models.DeductionRecord
+		if plan.Status == models.DeductionPlanStatusActive || plan.Status == models.DeductionPlanStatusPaused {
+			var err error
+			records, err = s.overdueRepo.FindOverdueRecords(tx, plan.ID, startOfDay(now))
+			if err != nil {
+				return fmt.Errorf("查询逾期代扣记录失败: %w", err)
+			}
+		}
+		refreshOverdueAmount(overdue, records)
+
+		if plan.Status != models.DeductionPlanStatusActive && plan.Status != models.DeductionPlanStatusCompleted {
+			return nil
+		}
+
+		need := overdue.OverdueAmount + overdue.UnpaidLateFee()
+		if need <= 0 {
+			return nil
+		}
+
+		// 锁定被扣款方钱包（按ID顺序，避免死锁）
+		var allWallets []*repository.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ?", plan.DeducteeID).Order("id ASC").Find(&allWallets).Error; err != nil {
+			return fmt.Errorf("锁定钱包失败: %w", err)
+		}
+		walletByID := make(map[int64]*repository.Wallet, len(allWallets))
+		wallets := make([]*repository.Wallet, 0, len(allWallets))
+		for _, w := range allWallets {
+			walletByID[w.ID] = w
+			if s.deductionService.isWalletTypeAllowedForDeduction(plan.DeductionSource, w.WalletType) {
+				wallets = append(wallets, w)
+			}
+		}
+
+		planFrozen, err := planFrozenByWallet(tx, &plan)
+		if err != nil {
+			return err
+		}
+		fromFrozen, fromAvailable := allocateOverdueCollection(wallets, planFrozen, need)
+
+		sources := make([]int64, len(wallets))
+		var total, frozenTotal int64
+		for i := range wallets {
+			sources[i] = fromFrozen[i] + fromAvailable[i]
+			total += sources[i]
+			frozenTotal += fromFrozen[i]
+		}
+		if total <= 0 {
+			return nil
+		}
+
+		// 先还逾期本金（从早到晚），再还滞纳金
+		targets := make([]int64, 0, len(records)+1)
+		var principalCollected int64
+		for _, amount := range allocateToRecords(records, total) {
+			targets = append(targets, amount)
+			principalCollected += amount
+		}
+		feeCollected := total - principalCollected
+		var feeRecord *models.DeductionRecord
+		if feeCollected > 0 {
+			feeRecord = &models.DeductionRecord{
+				PlanID:       plan.ID,
+				PlanNo:       plan.PlanNo,
+				DeductorID:   plan.DeductorID,
+				DeducteeID:   plan.DeducteeID,
+				PeriodNum:    0,
+				Amount:       feeCollected,
+				ActualAmount: feeCollected,
+				Status:       models.DeductionRecordStatusSuccess,
+				FailReason:   "逾期滞纳金",
+				ScheduledAt:  now,
+				DeductedAt:   &now,
+				CreatedAt:    now,
+			}
+			if err := tx.Create(feeRecord).Error; err != nil {
+				return fmt.Errorf("创建滞纳金代扣记录失败: %w", err)
+			}
+			targets = append(targets, feeCollected)
+		}
+		targetRecord := func(i int) *models.DeductionRecord {
+			if i < len(records) {
+				return records[i]
+			}
+			return feeRecord
+		}
+
+		// 扣减钱包（冻结部分同时扣减冻结金额）
+		for i, wallet := range wallets {
+			if sources[i] <= 0 {
+				continue
+			}
+			result := tx.Model(&repository.Wallet{}).
+				Where("id = ? AND balance - frozen_amount >= ? AND frozen_amount >= ?", wallet.ID, fromAvailable[i], fromFrozen[i]).
+				Updates(map[string]interface{}{
+					"balance":       gorm.Expr("balance - ?", sources[i]),
+					"frozen_amount": gorm.Expr("frozen_amount - ?", fromFrozen[i]),
+					"version":       gorm.Expr("version + 1"),
+				})
+			if result.Error != nil {
+				return fmt.Errorf("扣减钱包失败: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("钱包%d余额不足", wallet.ID)
+			}
+			if fromFrozen[i] > 0 {
+				freezeLog := &models.DeductionFreezeLog{
+					PlanID:       plan.ID,
+					AgentID:      plan.DeducteeID,
+					WalletID:     wallet.ID,
+					WalletType:   wallet.WalletType,
+					ChannelID:    wallet.ChannelID,
+					FreezeAmount: -fromFrozen[i],
+					TotalFrozen:  plan.FrozenAmount - fromFrozen[i],
+					TriggerType:  models.DeductionFreezeTriggerTypeOverdue,
+					TriggerRefID: overdue.ID,
+					CreatedAt:    now,
+				}
+				if err := tx.Create(freezeLog).Error; err != nil {
+					return fmt.Errorf("创建冻结明细失败: %w", err)
+				}
+				plan.FrozenAmount -= fromFrozen[i]
+			}
+		}
+
+		// 按代扣记录拆分钱包流水
+		detailsByTarget := make(map[int][]models.WalletDeductDetail)
+		for _, piece := range splitAcross(sources, targets) {
+			wallet := wallets[piece.Source]
+			record := targetRecord(piece.Target)
+			remark := "逾期补扣"
+			if record == feeRecord {
+				remark = "逾期滞纳金"
+			}
+			walletLog := &repository.WalletLog{
+				WalletID:      wallet.ID,
+				AgentID:       wallet.AgentID,
+				WalletType:    wallet.WalletType,
+				LogType:       6, // 代扣
+				Amount:        -piece.Amount,
+				BalanceBefore: wallet.Balance,
+				BalanceAfter:  wallet.Balance - piece.Amount,
+				RefType:       "deduction_record",
+				RefID:         record.ID,
+				Remark:        remark,
+				CreatedAt:     now,
+			}
+			if err := tx.Create(walletLog).Error; err != nil {
+				return fmt.Errorf("创建钱包流水失败: %w", err)
+			}
+			detailsByTarget[piece.Target] = append(detailsByTarget[piece.Target], models.WalletDeductDetail{
+				WalletID:      wallet.ID,
+				WalletType:    wallet.WalletType,
+				WalletName:    getWalletTypeName(wallet.WalletType),
+				BalanceBefore: wallet.Balance,
+				DeductAmount:  piece.Amount,
+				BalanceAfter:  wallet.Balance - piece.Amount,
+			})
+			wallet.Balance -= piece.Amount
+		}
+		for i, w := range wallets {
+			w.FrozenAmount -= fromFrozen[i]
+		}
+
+		// 更新代扣记录并转入扣款方
+		for i, amount := range targets {
+			if amount <= 0 {
+				continue
+			}
+			record := targetRecord(i)
+			remark := "逾期补扣收款"
+			if record == feeRecord {
+				remark = "逾期滞纳金收款"
+				if err := tx.Model(record).Update("wallet_details", mergeWalletDetails("", detailsByTarget[i])).Error; err != nil {
+					return fmt.Errorf("更新代扣记录失败: %w", err)
+				}
+			} else if err := s.applyRecordPayment(tx, record, amount, detailsByTarget[i], "逾期补扣", now); err != nil {
+				return err
+			}
+			if err := s.deductionService.creditDeductorTx(tx, plan.DeductorID, amount, record.ID, remark); err != nil {
+				return err
+			}
+		}
+
+		if principalCollected > 0 {
+			if err := s.applyPlanPayment(tx, &plan, principalCollected, walletByID, now); err != nil {
+				return err
+			}
+		} else if frozenTotal > 0 {
+			if err := tx.Model(&plan).Updates(map[string]interface{}{
+				"frozen_amount": plan.FrozenAmount,
+				"updated_at":    now,
+			}).Error; err != nil {
+				return fmt.Errorf("更新代扣计划失败: %w", err)
+			}
+		}
+
+		overdue.CollectedAmount += principalCollected
+		overdue.LateFeePaid += feeCollected
+		refreshOverdueAmount(overdue, records)
+		collected = total
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if collected > 0 {
+		log.Printf("[DeductionOverdueService] Collected overdue %s: amount=%d, remaining_overdue=%d",
+			overdue.OverdueNo, collected, overdue.OverdueAmount)
+	}
+	return collected, nil
+}
+
+// applyRecordPayment 逾期代扣记录补足（必须在事务内调用）
+func (s *DeductionOverdueService) applyRecordPayment(tx *gorm.DB, record *models.DeductionRecord, amount int64,
+	details []models.WalletDeductDetail, reason string, now time.Time) error {
+	record.ActualAmount += amount
+	record.WalletDetails = mergeWalletDetails(record.WalletDetails, details)
+	record.DeductedAt = &now
+	if record.ActualAmount >= record.Amount {
+		record.Status = models.DeductionRecordStatusSuccess
+		record.FailReason = reason + "已补足"
+	} else {
+		record.Status = models.DeductionRecordStatusPartialSuccess
+		record.FailReason = fmt.Sprintf("%s，应扣%d分，实扣%d分", reason, record.Amount, record.ActualAmount)
+	}
+	if err := tx.Model(record).Updates(map[string]interface{}{
+		"actual_amount":  record.ActualAmount,
+		"status":         record.Status,
+		"wallet_details": record.WalletDetails,
+		"fail_reason":    record.FailReason,
+		"deducted_at":    now,
+	}).Error; err != nil {
+		return fmt.Errorf("更新代扣记录失败: %w", err)
+	}
+	return nil
+}
+
+// applyPlanPayment 更新计划已扣/剩余金额，扣完时关闭计划并释放剩余冻结（必须在事务内调用）
+func (s *DeductionOverdueService) applyPlanPayment(tx *gorm.DB, plan *models.DeductionPlan, amount int64,
+	debtorWallets map[int64]*repository.Wallet, now time.Time) error {
+	plan.DeductedAmount += amount
+	plan.RemainingAmount -= amount
+	if plan.RemainingAmount <= 0 {
+		plan.RemainingAmount = 0
+		if _, err := s.deductionService.releasePlanFrozen(tx, plan, debtorWallets); err != nil {
+			return err
+		}
+		plan.FrozenAmount = 0
+		plan.Status = models.DeductionPlanStatusCompleted
+		plan.CompletedAt = &now
+	}
+	plan.UpdatedAt = now
+	if err := tx.Save(plan).Error; err != nil {
+		return fmt.Errorf("更新代扣计划失败: %w", err)
+	}
+	return nil
+}
+
+// escalate 上报被扣款方上级，开启代偿时从上级可用余额代偿逾期本金，并通过代扣链向下级追偿
+// 上级仅在首次上报时通知；代偿失败或上级暂无可代偿余额时不记上报时间，次日继续尝试
+func (s *DeductionOverdueService) escalate(config *models.DeductionOverdueConfig, plan *models.DeductionPlan,
+	overdue *models.DeductionOverdue, now time.Time) (int64, error) {
+	debtor, err := s.agentRepo.FindByID(plan.DeducteeID)
+	if err != nil || debtor == nil {
+		return 0, fmt.Errorf("被扣款方不存在: %d", plan.DeducteeID)
+	}
+	if debtor.ParentID == 0 {
+		log.Printf("[DeductionOverdueService] Overdue %s debtor %d has no parent, skip escalation", overdue.OverdueNo, debtor.ID)
+		overdue.EscalatedAt = &now
+		return 0, nil
+	}
+
+	if overdue.EscalatedTo != debtor.ParentID {
+		overdue.EscalatedTo = debtor.ParentID
+		s.sendMessage(debtor.ParentID, "下级代扣逾期",
+			fmt.Sprintf("您的下级%s的代扣计划%s已逾期%d天，逾期本金%.2f元，请协助催收。",
+				debtor.AgentName, overdue.PlanNo, overdue.DaysPastDue, float64(overdue.OverdueAmount)/100), overdue.ID)
+	}
+
+	// 上级即债权人时代偿无意义
+	if !config.AutoDrawParent || debtor.ParentID == plan.DeductorID {
+		overdue.EscalatedAt = &now
+		return 0, nil
+	}
+
+	drawn, err := s.drawFromParent(config, overdue, debtor.ParentID, now)
+	if err != nil {
+		return 0, err
+	}
+	if drawn <= 0 {
+		return 0, nil
+	}
+	overdue.EscalatedAt = &now
+	overdue.DrawnAmount += drawn
+
+	s.sendMessage(debtor.ParentID, "下级逾期代偿",
+		fmt.Sprintf("已从您的钱包代偿下级%s逾期代扣%.2f元，将分%d期向下级追偿。",
+			debtor.AgentName, float64(drawn)/100, config.ParentRecoverPeriods), overdue.ID)
+	s.sendMessage(debtor.ID, "逾期已由上级代偿",
+		fmt.Sprintf("您的代扣计划%s逾期款项%.2f元已由上级代偿，上级将分%d期向您扣回。",
+			overdue.PlanNo, float64(drawn)/100, config.ParentRecoverPeriods), overdue.ID)
+
+	log.Printf("[DeductionOverdueService] Parent %d drew %d for overdue %s", debtor.ParentID, drawn, overdue.OverdueNo)
+	return drawn, nil
+}
+
+// drawFromParent 从上级分润/服务费钱包可用余额代偿逾期本金，并在同一事务内创建向下级追偿的代扣链
+// 上级钱包存在风控冻结或追偿代扣链创建失败时整体回滚，上级钱包不会被扣款
+func (s *DeductionOverdueService) drawFromParent(config *models.DeductionOverdueConfig, overdue *models.DeductionOverdue,
+	parentID int64, now time.Time) (int64, error) {
+	var drawn int64
+	err := s.overdueRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var plan models.DeductionPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, overdue.PlanID).Error; err != nil {
+			return fmt.Errorf("代扣计划不存在: %d", overdue.PlanID)
+		}
+		if plan.Status != models.DeductionPlanStatusActive {
+			return nil
+		}
+		records, err := s.overdueRepo.FindOverdueRecords(tx, plan.ID, startOfDay(now))
+		if err != nil {
+			return fmt.Errorf("查询逾期代扣记录失败: %w", err)
+		}
+		refreshOverdueAmount(overdue, records)
+		if overdue.OverdueAmount <= 0 {
+			return nil
+		}
+
+		// 按ID顺序锁定上级与被扣款方钱包，避免死锁
+		var lockedWallets []*repository.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id IN ?", []int64{parentID, plan.DeducteeID}).Order("id ASC").Find(&lockedWallets).Error; err != nil {
+			return fmt.Errorf("锁定钱包失败: %w", err)
+		}
+		debtorWallets := make(map[int64]*repository.Wallet)
+		parentWallets := make([]*repository.Wallet, 0)
+		for _, w := range lockedWallets {
+			if w.AgentID == plan.DeducteeID {
+				debtorWallets[w.ID] = w
+			} else if w.WalletType == models.WalletTypeProfit || w.WalletType == models.WalletTypeServiceFee {
+				parentWallets = append(parentWallets, w)
+			}
+		}
+
+		_, fromAvailable := allocateOverdueCollection(parentWallets, nil, overdue.OverdueAmount)
+		var total int64
+		for i, wallet := range parentWallets {
+			if fromAvailable[i] <= 0 {
+				continue
+			}
+			if s.riskHoldService != nil {
+				if err := s.riskHoldService.CheckWithdraw(parentID, wallet, fromAvailable[i]); err != nil {
+					return fmt.Errorf("上级钱包%d不可代偿: %w", wallet.ID, err)
+				}
+			}
+			result := tx.Model(&repository.Wallet{}).
+				Where("id = ? AND balance - frozen_amount >= ?", wallet.ID, fromAvailable[i]).
+				Updates(map[string]interface{}{
+					"balance": gorm.Expr("balance - ?", fromAvailable[i]),
+					"version": gorm.Expr("version + 1"),
+				})
+			if result.Error != nil {
+				return fmt.Errorf("扣减上级钱包失败: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("上级钱包%d可用余额不足", wallet.ID)
+			}
+			total += fromAvailable[i]
+		}
+		if total <= 0 {
+			return nil
+		}
+
+		targets := allocateToRecords(records, total)
+		detailsByTarget := make(map[int][]models.WalletDeductDetail)
+		for _, piece := range splitAcross(fromAvailable, targets) {
+			wallet := parentWallets[piece.Source]
+			walletLog := &repository.WalletLog{
+				WalletID:      wallet.ID,
+				AgentID:       wallet.AgentID,
+				WalletType:    wallet.WalletType,
+				LogType:       6, // 代扣
+				Amount:        -piece.Amount,
+				BalanceBefore: wallet.Balance,
+				BalanceAfter:  wallet.Balance - piece.Amount,
+				RefType:       "deduction_record",
+				RefID:         records[piece.Target].ID,
+				Remark:        "上级代偿逾期代扣",
+				CreatedAt:     now,
+			}
+			if err := tx.Create(walletLog).Error; err != nil {
+				return fmt.Errorf("创建钱包流水失败: %w", err)
+			}
+			detailsByTarget[piece.Target] = append(detailsByTarget[piece.Target], models.WalletDeductDetail{
+				WalletID:      wallet.ID,
+				WalletType:    wallet.WalletType,
+				WalletName:    getWalletTypeName(wallet.WalletType),
+				BalanceBefore: wallet.Balance,
+				DeductAmount:  piece.Amount,
+				BalanceAfter:  wallet.Balance - piece.Amount,
+			})
+			wallet.Balance -= piece.Amount
+		}
+
+		for i, amount := range targets {
+			if amount <= 0 {
+				continue
+			}
+			if err := s.applyRecordPayment(tx, records[i], amount, detailsByTarget[i], "上级代偿", now); err != nil {
+				return err
+			}
+			if err := s.deductionService.creditDeductorTx(tx, plan.DeductorID, amount, records[i].ID, "上级代偿逾期收款"); err != nil {
+				return err
+			}
+		}
+		if err := s.applyPlanPayment(tx, &plan, total, debtorWallets, now); err != nil {
+			return err
+		}
+
+		// 代偿金额不足每期1分时减少期数
+		periods := int(min(int64(config.ParentRecoverPeriods), total))
+		chain, err := s.deductionService.createDeductionChainTx(tx, &CreateDeductionChainRequest{
+			AgentPath:    []int64{parentID, plan.DeducteeID},
+			TotalAmount:  total,
+			TotalPeriods: periods,
+			Remark:       fmt.Sprintf("逾期代偿追偿 - 计划%s", overdue.PlanNo),
+		})
+		if err != nil {
+			return fmt.Errorf("创建追偿代扣链失败: %w", err)
+		}
+
+		refreshOverdueAmount(overdue, records)
+		overdue.DrawnChainID = &chain.ID
+		drawn = total
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return drawn, nil
+}
+
+// notifyOverdue 逾期节点通知双方
+func (s *DeductionOverdueService) notifyOverdue(overdue *models.DeductionOverdue) {
+	amount := float64(overdue.OverdueAmount) / 100
+	fee := float64(overdue.UnpaidLateFee()) / 100
+	s.sendMessage(overdue.DeducteeID, "代扣逾期提醒",
+		fmt.Sprintf("您的代扣计划%s已逾期%d天，逾期金额%.2f元，未付滞纳金%.2f元，请尽快保证钱包余额充足。",
+			overdue.PlanNo, overdue.DaysPastDue, amount, fee), overdue.ID)
+	s.sendMessage(overdue.DeductorID, "代扣逾期提醒",
+		fmt.Sprintf("您发起的代扣计划%s已逾期%d天，逾期金额%.2f元。",
+			overdue.PlanNo, overdue.DaysPastDue, amount), overdue.ID)
+}
+
+// sendMessage 发送逾期消息
+func (s *DeductionOverdueService) sendMessage(agentID int64, title, content string, overdueID int64) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeDeductionOverdue,
+		Title:       title,
+		Content:     content,
+		RelatedID:   overdueID,
+		RelatedType: "deduction_overdue",
+	}); err != nil {
+		log.Printf("[DeductionOverdueService] Send message to agent %d failed: %v", agentID, err)
+	}
+}
+
+// DeductionOverdueInfo 逾期记录展示信息
+type DeductionOverdueInfo struct {
+	*models.DeductionOverdue
+	StatusName      string `json:"status_name"`
+	DeductorName    string `json:"deductor_name"`
+	DeducteeName    string `json:"deductee_name"`
+	EscalatedToName string `json:"escalated_to_name"`
+	UnpaidLateFee   int64  `json:"unpaid_late_fee"`
+}
+
+// ListOverdues 分页查询逾期记录
+func (s *DeductionOverdueService) ListOverdues(filter *repository.DeductionOverdueFilter, page, pageSize int) ([]*DeductionOverdueInfo, int64, error) {
+	offset := (page - 1) * pageSize
+	overdues, total, err := s.overdueRepo.List(filter, pageSize, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询逾期记录失败: %w", err)
+	}
+	list := make([]*DeductionOverdueInfo, 0, len(overdues))
+	for _, o := range overdues {
+		list = append(list, s.toOverdueInfo(o))
+	}
+	return list, total, nil
+}
+
+// DeductionOverdueDashboard 债权人逾期看板
+type DeductionOverdueDashboard struct {
+	Summary *repository.DeductionOverdueSummary `json:"summary"`
+	Top     []*DeductionOverdueInfo             `json:"top"` // 逾期天数最长的记录
+}
+
+// GetCreditorDashboard 获取债权人逾期看板
+func (s *DeductionOverdueService) GetCreditorDashboard(deductorID int64) (*DeductionOverdueDashboard, error) {
+	summary, err := s.overdueRepo.GetSummaryByDeductor(deductorID)
+	if err != nil {
+		return nil, fmt.Errorf("查询逾期汇总失败: %w", err)
+	}
+	top, _, err := s.ListOverdues(&repository.DeductionOverdueFilter{
+		DeductorID: deductorID,
+		Status:     models.DeductionOverdueStatusOpen,
+	}, 1, 10)
+	if err != nil {
+		return nil, err
+	}
+	return &DeductionOverdueDashboard{Summary: summary, Top: top}, nil
+}
+
+// GetOverdue 获取逾期记录详情（agentID为0表示管理员）
+func (s *DeductionOverdueService) GetOverdue(id int64, agentID int64) (*DeductionOverdueInfo, error) {
+	overdue, err := s.overdueRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询逾期记录失败: %w", err)
+	}
+	if overdue == nil {
+		return nil, errors.New("逾期记录不存在")
+	}
+	if agentID > 0 && overdue.DeductorID != agentID && overdue.DeducteeID != agentID && overdue.EscalatedTo != agentID {
+		return nil, errors.New("无权查看此逾期记录")
+	}
+	return s.toOverdueInfo(overdue), nil
+}
+
+// toOverdueInfo 转换为展示信息
+func (s *DeductionOverdueService) toOverdueInfo(overdue *models.DeductionOverdue) *DeductionOverdueInfo {
+	info := &DeductionOverdueInfo{
+		DeductionOverdue: overdue,
+		StatusName:       models.GetDeductionOverdueStatusName(overdue.Status),
+		UnpaidLateFee:    overdue.UnpaidLateFee(),
+	}
+	if agent, err := s.agentRepo.FindByID(overdue.DeductorID); err == nil && agent != nil {
+		info.DeductorName = agent.AgentName
+	}
+	if agent, err := s.agentRepo.FindByID(overdue.DeducteeID); err == nil && agent != nil {
+		info.DeducteeName = agent.AgentName
+	}
+	if overdue.EscalatedTo > 0 {
+		if agent, err := s.agentRepo.FindByID(overdue.EscalatedTo); err == nil && agent != nil {
+			info.EscalatedToName = agent.AgentName
+		}
+	}
+	return info
+}
+
+// planFrozenByWallet 查询计划在各钱包的累计冻结金额
+func planFrozenByWallet(tx *gorm.DB, plan *models.DeductionPlan) (map[int64]int64, error) {
+	result := make(map[int64]int64)
+	if plan.FrozenAmount <= 0 {
+		return result, nil
+	}
+	var rows []struct {
+		WalletID int64
+		Amount   int64
+	}
+	if err := tx.Model(&models.DeductionFreezeLog{}).
+		Select("wallet_id, SUM(freeze_amount) AS amount").
+		Where("plan_id = ?", plan.ID).
+		Group("wallet_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询冻结明细失败: %w", err)
+	}
+	remaining := plan.FrozenAmount
+	for _, row := range rows {
+		amount := row.Amount
+		if amount > remaining {
+			amount = remaining
+		}
+		if amount > 0 {
+			result[row.WalletID] = amount
+			remaining -= amount
+		}
+	}
+	return result, nil
+}
+
+// newOverdueNo 生成逾期编号
+func newOverdueNo() string {
+	return fmt.Sprintf("OD%s%06d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000000)
+}
+
+// refreshOverdueAmount 按逾期代扣记录刷新逾期本金与最早应扣时间
+func refreshOverdueAmount(overdue *models.DeductionOverdue, records []*models.DeductionRecord) {
+	var amount int64
+	var firstDue time.Time
+	for _, r := range records {
+		unpaid := r.Amount - r.ActualAmount
+		if unpaid <= 0 {
+			continue
+		}
+		amount += unpaid
+		if firstDue.IsZero() || r.ScheduledAt.Before(firstDue) {
+			firstDue = r.ScheduledAt
+		}
+	}
+	overdue.OverdueAmount = amount
+	if !firstDue.IsZero() {
+		overdue.FirstDueAt = firstDue
+	}
+}
+
+// startOfDay 当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween 两个日期之间相差的自然日数
+func daysBetween(from, to time.Time) int {
+	from = startOfDay(from)
+	to = startOfDay(to.In(from.Location()))
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// daysPastDue 逾期天数：最早未清偿的应扣日期至今的自然日数
+func daysPastDue(firstDueAt, now time.Time) int {
+	days := daysBetween(firstDueAt, now)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// lateFeeDays 本次需计收滞纳金的天数（宽限期后开始计收，已计收的日期不重复计收）
+func lateFeeDays(firstDueAt time.Time, accruedTo *time.Time, graceDays int, today time.Time) int {
+	start := startOfDay(firstDueAt).AddDate(0, 0, graceDays)
+	if accruedTo != nil && accruedTo.After(start) {
+		start = startOfDay(*accruedTo)
+	}
+	days := daysBetween(start, today)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// accrueLateFee 计算新增滞纳金：逾期本金 × 日费率（万分之）× 天数，累计不超过本金 × 上限（万分比）
+func accrueLateFee(principal int64, dailyRate, capRate int, accrued int64, days int) int64 {
+	if principal <= 0 || dailyRate <= 0 || days <= 0 {
+		return 0
+	}
+	fee := principal * int64(dailyRate) * int64(days) / 10000
+	if capRate > 0 {
+		capAmount := principal * int64(capRate) / 10000
+		if accrued+fee > capAmount {
+			fee = capAmount - accrued
+		}
+	}
+	if fee < 0 {
+		return 0
+	}
+	return fee
+}
+
+// parseNotifyDays 解析通知节点配置
+func parseNotifyDays(s string) ([]int, error) {
+	days := make([]int, 0)
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := strconv.Atoi(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的通知节点: %s", part)
+		}
+		if !seen[d] {
+			seen[d] = true
+			days = append(days, d)
+		}
+	}
+	sort.Ints(days)
+	return days, nil
+}
+
+// notifyThreshold 返回本次应通知的节点（已越过且未通知的最大节点），无需通知返回0
+func notifyThreshold(notifyDays []int, lastNotified, daysPastDue int) int {
+	threshold := 0
+	for _, d := range notifyDays {
+		if d > lastNotified && d <= daysPastDue {
+			threshold = d
+		}
+	}
+	return threshold
+}
+
+// allocateOverdueCollection 分配补扣金额：先扣本计划冻结金额，再扣可用余额（均按钱包顺序）
+func allocateOverdueCollection(wallets []*repository.Wallet, planFrozen map[int64]int64, need int64) ([]int64, []int64) {
+	fromFrozen := make([]int64, len(wallets))
+	fromAvailable := make([]int64, len(wallets))
+	remaining := need
+
+	for i, w := range wallets {
+		if remaining <= 0 {
+			break
+		}
+		amount := minInt64(planFrozen[w.ID], w.FrozenAmount)
+		amount = minInt64(amount, w.Balance)
+		amount = minInt64(amount, remaining)
+		if amount > 0 {
+			fromFrozen[i] = amount
+			remaining -= amount
+		}
+	}
+	for i, w := range wallets {
+		if remaining <= 0 {
+			break
+		}
+		amount := minInt64(w.Balance-w.FrozenAmount, remaining)
+		if amount > 0 {
+			fromAvailable[i] = amount
+			remaining -= amount
+		}
+	}
+	return fromFrozen, fromAvailable
+}
+
+// allocateToRecords 将金额按顺序分配到逾期代扣记录的未扣部分
+func allocateToRecords(records []*models.DeductionRecord, amount int64) []int64 {
+	allocations := make([]int64, len(records))
+	for i, r := range records {
+		if amount <= 0 {
+			break
+		}
+		allocations[i] = minInt64(r.Amount-r.ActualAmount, amount)
+		if allocations[i] < 0 {
+			allocations[i] = 0
+		}
+		amount -= allocations[i]
+	}
+	return allocations
+}
+
+// collectionPiece 钱包扣款在代扣记录间的拆分
+type collectionPiece struct {
+	Source int   // 钱包下标
+	Target int   // 代扣记录下标
+	Amount int64 // 金额（分）
+}
+
+// splitAcross 按顺序将各钱包扣款金额拆分到各代扣记录，用于生成逐条流水
+func splitAcross(sources, targets []int64) []collectionPiece {
+	pieces := make([]collectionPiece, 0)
+	si, ti := 0, 0
+	var sLeft, tLeft int64
+	if len(sources) > 0 {
+		sLeft = sources[0]
+	}
+	if len(targets) > 0 {
+		tLeft = targets[0]
+	}
+	for si < len(sources) && ti < len(targets) {
+		if sLeft <= 0 {
+			si++
+			if si < len(sources) {
+				sLeft = sources[si]
+			}
+			continue
+		}
+		if tLeft <= 0 {
+			ti++
+			if ti < len(targets) {
+				tLeft = targets[ti]
+			}
+			continue
+		}
+		amount := minInt64(sLeft, tLeft)
+		pieces = append(pieces, collectionPiece{Source: si, Target: ti, Amount: amount})
+		sLeft -= amount
+		tLeft -= amount
+	}
+	return pieces
+}
+
+// mergeWalletDetails 追加钱包扣款明细
+func mergeWalletDetails(existing string, details []models.WalletDeductDetail) string {
+	merged := make([]models.WalletDeductDetail, 0)
+	if existing != "" {
+		_ = json.Unmarshal([]byte(existing), &merged)
+	}
+	merged = append(merged, details...)
+	data, _ := json.Marshal(merged)
+	return string(data)
+}
+
+// minInt64 取较小值
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestDaysPastDue(t *testing.T) {
+	due := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"当天", time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local), 0},
+		{"次日凌晨", time.Date(2026, 3, 2, 0, 30, 0, 0, time.Local), 1},
+		{"跨月", time.Date(2026, 4, 1, 9, 0, 0, 0, time.Local), 31},
+		{"未到期", time.Date(2026, 2, 28, 9, 0, 0, 0, time.Local), 0},
+	}
+	for _, tt := range tests {
+		if got := daysPastDue(due, tt.now); got != tt.want {
+			t.Errorf("%s: daysPastDue = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLateFeeDays(t *testing.T) {
+	due := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+
+	if got := lateFeeDays(due, nil, 0, today); got != 9 {
+		t.Errorf("无宽限期: got %d, want 9", got)
+	}
+	if got := lateFeeDays(due, nil, 3, today); got != 6 {
+		t.Errorf("宽限3天: got %d, want 6", got)
+	}
+	if got := lateFeeDays(due, nil, 20, today); got != 0 {
+		t.Errorf("宽限期内: got %d, want 0", got)
+	}
+	accrued := time.Date(2026, 3, 8, 0, 0, 0, 0, time.Local)
+	if got := lateFeeDays(due, &accrued, 3, today); got != 2 {
+		t.Errorf("已计至3月8日: got %d, want 2", got)
+	}
+	if got := lateFeeDays(due, &today, 0, today); got != 0 {
+		t.Errorf("当日重复执行: got %d, want 0", got)
+	}
+}
+
+func TestAccrueLateFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal int64
+		dailyRate int
+		capRate   int
+		accrued   int64
+		days      int
+		want      int64
+	}{
+		{"万分之五3天", 100000, 5, 0, 0, 3, 150},
+		{"未配置费率", 100000, 0, 0, 0, 3, 0},
+		{"无逾期天数", 100000, 5, 0, 0, 0, 0},
+		{"达到上限", 100000, 5, 100, 900, 3, 100},
+		{"已超上限", 100000, 5, 100, 1000, 3, 0},
+		{"无上限", 100000, 5, 0, 5000, 10, 500},
+	}
+	for _, tt := range tests {
+		got := accrueLateFee(tt.principal, tt.dailyRate, tt.capRate, tt.accrued, tt.days)
+		if got != tt.want {
+			t.Errorf("%s: accrueLateFee = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseNotifyDays(t *testing.T) {
+	days, err := parseNotifyDays(" 7,1, 3,7 ,,30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(days, []int{1, 3, 7, 30}) {
+		t.Errorf("parseNotifyDays = %v", days)
+	}
+	if days, err := parseNotifyDays(""); err != nil || len(days) != 0 {
+		t.Errorf("空配置: days=%v err=%v", days, err)
+	}
+	if _, err := parseNotifyDays("1,abc"); err == nil {
+		t.Error("非数字节点应报错")
+	}
+	if _, err := parseNotifyDays("0,3"); err == nil {
+		t.Error("非正数节点应报错")
+	}
+}
+
+func TestNotifyThreshold(t *testing.T) {
+	days := []int{1, 3, 7, 15}
+	tests := []struct {
+		lastNotified int
+		daysPastDue  int
+		want         int
+	}{
+		{0, 1, 1},
+		{1, 2, 0},
+		{1, 3, 3},
+		{3, 10, 7},
+		{0, 20, 15}, // 停跑多日只通知最大节点
+		{15, 40, 0},
+	}
+	for _, tt := range tests {
+		if got := notifyThreshold(days, tt.lastNotified, tt.daysPastDue); got != tt.want {
+			t.Errorf("notifyThreshold(last=%d, days=%d) = %d, want %d", tt.lastNotified, tt.daysPastDue, got, tt.want)
+		}
+	}
+}
+
+func TestAllocateOverdueCollection(t *testing.T) {
+	wallets := []*repository.Wallet{
+		{ID: 1, Balance: 5000, FrozenAmount: 3000},
+		{ID: 2, Balance: 2000, FrozenAmount: 0},
+	}
+	planFrozen := map[int64]int64{1: 2000}
+
+	frozen, available := allocateOverdueCollection(wallets, planFrozen, 6000)
+	if !reflect.DeepEqual(frozen, []int64{2000, 0}) {
+		t.Errorf("fromFrozen = %v", frozen)
+	}
+	// 钱包1可用余额2000（冻结3000中有1000属于其他计划），钱包2可用2000
+	if !reflect.DeepEqual(available, []int64{2000, 2000}) {
+		t.Errorf("fromAvailable = %v", available)
+	}
+
+	frozen, available = allocateOverdueCollection(wallets, planFrozen, 1500)
+	if !reflect.DeepEqual(frozen, []int64{1500, 0}) || !reflect.DeepEqual(available, []int64{0, 0}) {
+		t.Errorf("优先冻结金额: frozen=%v available=%v", frozen, available)
+	}
+
+	frozen, available = allocateOverdueCollection(wallets, nil, 3000)
+	if !reflect.DeepEqual(frozen, []int64{0, 0}) || !reflect.DeepEqual(available, []int64{2000, 1000}) {
+		t.Errorf("无计划冻结: frozen=%v available=%v", frozen, available)
+	}
+}
+
+func TestAllocateToRecords(t *testing.T) {
+	records := []*models.DeductionRecord{
+		{Amount: 1000, ActualAmount: 400},
+		{Amount: 1000, ActualAmount: 0},
+		{Amount: 500, ActualAmount: 0},
+	}
+	if got := allocateToRecords(records, 1200); !reflect.DeepEqual(got, []int64{600, 600, 0}) {
+		t.Errorf("部分补扣: %v", got)
+	}
+	if got := allocateToRecords(records, 5000); !reflect.DeepEqual(got, []int64{600, 1000, 500}) {
+		t.Errorf("超额补扣: %v", got)
+	}
+}
+
+func TestSplitAcross(t *testing.T) {
+	pieces := splitAcross([]int64{700, 0, 800}, []int64{600, 600, 300})
+	want := []collectionPiece{
+		{Source: 0, Target: 0, Amount: 600},
+		{Source: 0, Target: 1, Amount: 100},
+		{Source: 2, Target: 1, Amount: 500},
+		{Source: 2, Target: 2, Amount: 300},
+	}
+	if !reflect.DeepEqual(pieces, want) {
+		t.Errorf("splitAcross = %+v", pieces)
+	}
+
+	var total int64
+	for _, p := range splitAcross([]int64{100}, []int64{0, 50, 200}) {
+		total += p.Amount
+	}
+	if total != 100 {
+		t.Errorf("拆分总额 = %d, want 100", total)
+	}
+}
+
+func TestRefreshOverdueAmount(t *testing.T) {
+	overdue := &models.DeductionOverdue{}
+	first := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	records := []*models.DeductionRecord{
+		{Amount: 1000, ActualAmount: 1000, ScheduledAt: first},
+		{Amount: 1000, ActualAmount: 300, ScheduledAt: first.AddDate(0, 0, 1)},
+		{Amount: 1000, ActualAmount: 0, ScheduledAt: first.AddDate(0, 0, 2)},
+	}
+	refreshOverdueAmount(overdue, records)
+	if overdue.OverdueAmount != 1700 {
+		t.Errorf("OverdueAmount = %d, want 1700", overdue.OverdueAmount)
+	}
+	if !overdue.FirstDueAt.Equal(first.AddDate(0, 0, 1)) {
+		t.Errorf("FirstDueAt = %v", overdue.FirstDueAt)
+	}
+}
+
+func TestEscalateWithoutDraw(t *testing.T) {
+	agentRepo := NewMockAgentRepository()
+	agentRepo.AddAgent(1, "A1", 0, "/1/", 1)
+	agentRepo.AddAgent(2, "A2", 1, "/1/2/", 2)
+	agentRepo.AddAgent(3, "A3", 2, "/1/2/3/", 3)
+	s := &DeductionOverdueService{agentRepo: agentRepo}
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name          string
+		config        *models.DeductionOverdueConfig
+		plan          *models.DeductionPlan
+		wantEscalated int64
+	}{
+		{"无上级", &models.DeductionOverdueConfig{AutoDrawParent: true}, &models.DeductionPlan{DeductorID: 9, DeducteeID: 1}, 0},
+		{"未开启代偿", &models.DeductionOverdueConfig{}, &models.DeductionPlan{DeductorID: 9, DeducteeID: 3}, 2},
+		{"上级即债权人", &models.DeductionOverdueConfig{AutoDrawParent: true}, &models.DeductionPlan{DeductorID: 2, DeducteeID: 3}, 2},
+	}
+	for _, tt := range tests {
+		overdue := &models.DeductionOverdue{OverdueAmount: 1000}
+		drawn, err := s.escalate(tt.config, tt.plan, overdue, now)
+		if err != nil || drawn != 0 {
+			t.Errorf("%s: escalate() = %d, %v", tt.name, drawn, err)
+		}
+		if overdue.EscalatedAt == nil || overdue.EscalatedTo != tt.wantEscalated {
+			t.Errorf("%s: EscalatedAt = %v, EscalatedTo = %d, want escalated to %d", tt.name, overdue.EscalatedAt, overdue.EscalatedTo, tt.wantEscalated)
+		}
+	}
+}
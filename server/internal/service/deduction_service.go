@@ -2,14 +2,17 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
-	"strings"
 	"time"
 
 	"xiangshoufu/internal/models"
 	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DeductionService 代扣服务（统一代扣管理）
@@ -29,6 +32,11 @@ type DeductionService struct {
 	walletRepo    repository.WalletRepository
 	walletLogRepo repository.WalletLogRepository
 	agentRepo     repository.AgentRepository
+	hierarchyRepo repository.HierarchyRepository
+
+	// 提前还款/重组
+	planChangeRepo  *repository.GormDeductionPlanChangeRepository
+	riskHoldService *WalletRiskHoldService
 }
 
 // NewDeductionService 创建代扣服务
@@ -41,6 +49,7 @@ func NewDeductionService(
 	walletRepo repository.WalletRepository,
 	walletLogRepo repository.WalletLogRepository,
 	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
 ) *DeductionService {
 	return &DeductionService{
 		planRepo:      planRepo,
@@ -51,9 +60,20 @@ func NewDeductionService(
 		walletRepo:    walletRepo,
 		walletLogRepo: walletLogRepo,
 		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
 	}
 }
 
+// SetPlanChangeRepo 设置代扣计划变更仓库（提前还款/重组）
+func (s *DeductionService) SetPlanChangeRepo(planChangeRepo *repository.GormDeductionPlanChangeRepository) {
+	s.planChangeRepo = planChangeRepo
+}
+
+// SetRiskHoldService 设置风控冻结服务（风控冻结期间禁止从冻结钱包提前还款）
+func (s *DeductionService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
 // ListPlans 分页查询代扣计划列表
 func (s *DeductionService) ListPlans(page, pageSize int, status, planType int16) ([]*models.DeductionPlan, int64, error) {
 	offset := (page - 1) * pageSize
@@ -176,36 +196,49 @@ func (s *DeductionService) CreateDeductionPlan(req *CreateDeductionPlanRequest)
 
 // generateDeductionRecords 生成代扣记录
 func (s *DeductionService) generateDeductionRecords(plan *models.DeductionPlan) error {
-	records := make([]*models.DeductionRecord, 0, plan.TotalPeriods)
+	amounts := splitDeductionAmounts(plan.TotalAmount, plan.TotalPeriods, plan.PeriodAmount)
+	records := buildDeductionRecords(plan, 1, amounts, firstDeductionTime(time.Now()))
+	return s.recordRepo.BatchCreate(records)
+}
 
-	// 从明天开始，每天一期
-	baseTime := time.Now().AddDate(0, 0, 1)
-	baseTime = time.Date(baseTime.Year(), baseTime.Month(), baseTime.Day(), 8, 0, 0, 0, baseTime.Location()) // 每天8点扣款
+// firstDeductionTime 首期扣款时间：从明天开始，每天8点扣款
+func firstDeductionTime(now time.Time) time.Time {
+	baseTime := now.AddDate(0, 0, 1)
+	return time.Date(baseTime.Year(), baseTime.Month(), baseTime.Day(), 8, 0, 0, 0, baseTime.Location())
+}
 
-	remainingAmount := plan.TotalAmount
-	for i := 1; i <= plan.TotalPeriods; i++ {
-		// 最后一期扣除剩余金额
-		amount := plan.PeriodAmount
-		if i == plan.TotalPeriods {
+// splitDeductionAmounts 按期数拆分金额，最后一期扣除剩余金额
+func splitDeductionAmounts(totalAmount int64, periods int, periodAmount int64) []int64 {
+	amounts := make([]int64, 0, periods)
+	remainingAmount := totalAmount
+	for i := 1; i <= periods; i++ {
+		amount := periodAmount
+		if i == periods {
 			amount = remainingAmount
 		}
 		remainingAmount -= amount
+		amounts = append(amounts, amount)
+	}
+	return amounts
+}
 
-		record := &models.DeductionRecord{
+// buildDeductionRecords 按金额列表生成从startPeriod开始、每天一期的代扣记录
+func buildDeductionRecords(plan *models.DeductionPlan, startPeriod int, amounts []int64, baseTime time.Time) []*models.DeductionRecord {
+	records := make([]*models.DeductionRecord, 0, len(amounts))
+	for i, amount := range amounts {
+		records = append(records, &models.DeductionRecord{
 			PlanID:      plan.ID,
 			PlanNo:      plan.PlanNo,
 			DeductorID:  plan.DeductorID,
 			DeducteeID:  plan.DeducteeID,
-			PeriodNum:   i,
+			PeriodNum:   startPeriod + i,
 			Amount:      amount,
 			Status:      models.DeductionRecordStatusPending,
-			ScheduledAt: baseTime.AddDate(0, 0, i-1), // 每天扣一期
+			ScheduledAt: baseTime.AddDate(0, 0, i), // 每天扣一期
 			CreatedAt:   time.Now(),
-		}
-		records = append(records, record)
+		})
 	}
-
-	return s.recordRepo.BatchCreate(records)
+	return records
 }
 
 // WalletInfo 钱包信息（用于多钱包扣款排序）
@@ -532,6 +565,7 @@ type CreateDeductionChainRequest struct {
 	TotalAmount  int64   `json:"total_amount"`  // 总金额
 	TotalPeriods int     `json:"total_periods"` // 总期数
 	CreatedBy    int64   `json:"created_by"`    // 创建人
+	Remark       string  `json:"remark"`        // 计划备注，为空时按跨级下发生成
 }
 
 // CreateDeductionChain 创建代扣链（用于跨级下发）
@@ -579,6 +613,10 @@ func (s *DeductionService) CreateDeductionChain(req *CreateDeductionChainRequest
 	}
 
 	// 为每个节点创建代扣计划
+	remark := req.Remark
+	if remark == "" {
+		remark = fmt.Sprintf("跨级下发货款代扣 - 终端%s", req.TerminalSN)
+	}
 	for _, item := range items {
 		planReq := &CreateDeductionPlanRequest{
 			DeductorID:   item.ToAgentID,
@@ -588,7 +626,7 @@ func (s *DeductionService) CreateDeductionChain(req *CreateDeductionChainRequest
 			TotalPeriods: req.TotalPeriods,
 			RelatedType:  "deduction_chain_item",
 			RelatedID:    item.ID,
-			Remark:       fmt.Sprintf("跨级下发货款代扣 - 终端%s", req.TerminalSN),
+			Remark:       remark,
 			CreatedBy:    req.CreatedBy,
 		}
 
@@ -609,56 +647,108 @@ func (s *DeductionService) CreateDeductionChain(req *CreateDeductionChainRequest
 	return chain, nil
 }
 
-// GetAgentPathBetween 获取两个代理商之间的路径
-func (s *DeductionService) GetAgentPathBetween(fromAgentID, toAgentID int64) ([]int64, error) {
-	// 获取目标代理商（下级）
-	toAgent, err := s.agentRepo.FindByID(toAgentID)
-	if err != nil || toAgent == nil {
-		return nil, fmt.Errorf("目标代理商不存在: %d", toAgentID)
+// createDeductionChainTx 在事务内创建代扣链及各节点的代扣计划和代扣记录（必须在事务内调用）
+// 任一步失败即返回错误，由调用方回滚，不会留下缺少计划的代扣链
+func (s *DeductionService) createDeductionChainTx(tx *gorm.DB, req *CreateDeductionChainRequest) (*models.DeductionChain, error) {
+	if len(req.AgentPath) < 2 {
+		return nil, fmt.Errorf("代扣链至少需要2个代理商")
 	}
-
-	// 解析物化路径
-	// 路径格式: /1/5/12/
-	if toAgent.Path == "" {
-		return nil, fmt.Errorf("代理商路径为空")
+	if req.TotalPeriods <= 0 || req.TotalAmount/int64(req.TotalPeriods) <= 0 {
+		return nil, fmt.Errorf("每期金额必须大于0")
 	}
 
-	pathStr := strings.Trim(toAgent.Path, "/")
-	if pathStr == "" {
-		return nil, fmt.Errorf("代理商路径为空")
+	now := time.Now()
+	chain := &models.DeductionChain{
+		ChainNo:      fmt.Sprintf("DC%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		DistributeID: req.DistributeID,
+		TerminalSN:   req.TerminalSN,
+		TotalLevels:  len(req.AgentPath) - 1,
+		TotalAmount:  req.TotalAmount,
+		Status:       1, // 进行中
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := tx.Create(chain).Error; err != nil {
+		return nil, fmt.Errorf("创建代扣链失败: %w", err)
 	}
 
-	// 分割路径
-	pathParts := strings.Split(pathStr, "/")
-	agentPath := make([]int64, 0)
-
-	// 找到fromAgentID的位置
-	foundFrom := false
-	for _, part := range pathParts {
-		var id int64
-		fmt.Sscanf(part, "%d", &id)
-
-		if id == fromAgentID {
-			foundFrom = true
+	periodAmount := req.TotalAmount / int64(req.TotalPeriods)
+	for i := 0; i < len(req.AgentPath)-1; i++ {
+		item := &models.DeductionChainItem{
+			ChainID:     chain.ID,
+			ChainNo:     chain.ChainNo,
+			Level:       i + 1,
+			FromAgentID: req.AgentPath[i+1], // 下级代理商扣款
+			ToAgentID:   req.AgentPath[i],   // 上级代理商收款
+			Amount:      req.TotalAmount,
+			Status:      0, // 待处理
+		}
+		if err := tx.Create(item).Error; err != nil {
+			return nil, fmt.Errorf("创建代扣链节点失败: %w", err)
 		}
 
-		if foundFrom {
-			agentPath = append(agentPath, id)
+		plan := &models.DeductionPlan{
+			PlanNo:          fmt.Sprintf("DP%s%06d", now.Format("20060102150405"), (now.UnixNano()+int64(i))%1000000),
+			DeductorID:      item.ToAgentID,
+			DeducteeID:      item.FromAgentID,
+			PlanType:        models.DeductionPlanTypeGoods,
+			TotalAmount:     item.Amount,
+			RemainingAmount: item.Amount,
+			TotalPeriods:    req.TotalPeriods,
+			PeriodAmount:    periodAmount,
+			Status:          models.DeductionPlanStatusActive,
+			RelatedType:     "deduction_chain_item",
+			RelatedID:       item.ID,
+			Remark:          req.Remark,
+			CreatedBy:       req.CreatedBy,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if err := tx.Create(plan).Error; err != nil {
+			return nil, fmt.Errorf("创建代扣计划失败: %w", err)
+		}
+		amounts := splitDeductionAmounts(plan.TotalAmount, plan.TotalPeriods, plan.PeriodAmount)
+		if err := tx.Create(buildDeductionRecords(plan, 1, amounts, firstDeductionTime(now))).Error; err != nil {
+			return nil, fmt.Errorf("生成代扣记录失败: %w", err)
+		}
+		if err := tx.Model(item).Updates(map[string]interface{}{"plan_id": plan.ID, "status": 1}).Error; err != nil {
+			return nil, fmt.Errorf("更新代扣链节点失败: %w", err)
 		}
 	}
+	return chain, nil
+}
 
-	// 添加目标代理商
-	agentPath = append(agentPath, toAgentID)
-
-	if !foundFrom {
+// GetAgentPathBetween 获取两个代理商之间的路径（含两端，从下发方到目标代理商）
+func (s *DeductionService) GetAgentPathBetween(fromAgentID, toAgentID int64) ([]int64, error) {
+	agentPath, err := s.hierarchyRepo.GetPathBetween(fromAgentID, toAgentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代理商层级失败: %w", err)
+	}
+	if len(agentPath) < 2 {
 		return nil, fmt.Errorf("下发方不在目标代理商的上级链中")
 	}
-
 	return agentPath, nil
 }
 
 // CreateDeductionPlanWithAccept 创建需要接收确认的代扣计划
 func (s *DeductionService) CreateDeductionPlanWithAccept(req *models.CreateDeductionPlanWithAcceptRequest, createdBy int64) (*models.DeductionPlan, error) {
+	plan, err := s.newPlanWithAccept(req, createdBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Create(plan); err != nil {
+		return nil, fmt.Errorf("创建代扣计划失败: %w", err)
+	}
+
+	log.Printf("[DeductionService] Created deduction plan with accept: %s, deductor: %d, deductee: %d, amount: %d",
+		plan.PlanNo, req.DeductorID, req.DeducteeID, req.TotalAmount)
+
+	return plan, nil
+}
+
+// newPlanWithAccept 校验请求并构造待接收的代扣计划（不落库），供调用方在自己的事务内创建
+func (s *DeductionService) newPlanWithAccept(req *models.CreateDeductionPlanWithAcceptRequest, createdBy int64) (*models.DeductionPlan, error) {
 	// 验证扣款方和被扣款方是否存在
 	deductor, err := s.agentRepo.FindByID(req.DeductorID)
 	if err != nil || deductor == nil {
@@ -703,14 +793,6 @@ func (s *DeductionService) CreateDeductionPlanWithAccept(req *models.CreateDeduc
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
-
-	if err := s.planRepo.Create(plan); err != nil {
-		return nil, fmt.Errorf("创建代扣计划失败: %w", err)
-	}
-
-	log.Printf("[DeductionService] Created deduction plan with accept: %s, deductor: %d, deductee: %d, amount: %d",
-		planNo, req.DeductorID, req.DeducteeID, req.TotalAmount)
-
 	return plan, nil
 }
 
@@ -978,7 +1060,7 @@ func (s *DeductionService) isWalletTypeAllowedForDeduction(deductionSource int16
 }
 
 // ExecuteDeductionWithUnfreeze 执行扣款并解冻（定时任务调用）
-// 从冻结金额中扣款，而非直接从余额扣
+// 从冻结金额中扣款，而非直接从余额扣；冻结扣减明细写入失败时按已扣金额更新记录和计划后返回错误
 func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.DeductionRecord) error {
 	// 获取代扣计划
 	plan, err := s.planRepo.FindByID(record.PlanID)
@@ -1009,7 +1091,15 @@ func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.Deduction
 		return fmt.Errorf("获取钱包失败: %w", err)
 	}
 
+	// 只扣减本计划在各钱包冻结的部分，钱包上其他业务（提现、转账等）的冻结不动
+	freezeLogs, err := s.freezeLogRepo.FindByPlanID(plan.ID)
+	if err != nil {
+		return fmt.Errorf("查询冻结明细失败: %w", err)
+	}
+	planFrozen := sumFreezeLogsByWallet(freezeLogs)
+
 	var totalDeducted int64
+	var freezeLogErr error
 	walletDetails := make([]models.WalletDeductDetail, 0)
 	remainingAmount := deductAmount
 
@@ -1018,8 +1108,8 @@ func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.Deduction
 			break
 		}
 
-		// 从冻结金额中扣款
-		walletFrozen := wallet.FrozenAmount
+		// 从本计划的冻结金额中扣款
+		walletFrozen := min(wallet.FrozenAmount, planFrozen[wallet.ID])
 		if walletFrozen <= 0 {
 			continue
 		}
@@ -1037,6 +1127,23 @@ func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.Deduction
 		if err := s.walletRepo.UpdateFrozenAmount(wallet.ID, -deductFromWallet); err != nil {
 			log.Printf("[DeductionService] Unfreeze wallet %d failed: %v", wallet.ID, err)
 		}
+		planFrozen[wallet.ID] -= deductFromWallet
+
+		// 记录冻结扣减明细，保持计划在各钱包的冻结余额准确；写入失败时本钱包照常入账，不再扣其余钱包
+		if err := s.freezeLogRepo.Create(&models.DeductionFreezeLog{
+			PlanID:       plan.ID,
+			AgentID:      plan.DeducteeID,
+			WalletID:     wallet.ID,
+			WalletType:   wallet.WalletType,
+			ChannelID:    wallet.ChannelID,
+			FreezeAmount: -deductFromWallet,
+			TotalFrozen:  plan.FrozenAmount - totalDeducted - deductFromWallet,
+			TriggerType:  models.DeductionFreezeTriggerTypeDeduct,
+			TriggerRefID: record.ID,
+			CreatedAt:    time.Now(),
+		}); err != nil {
+			freezeLogErr = fmt.Errorf("记录冻结扣减明细失败(钱包%d): %w", wallet.ID, err)
+		}
 
 		// 记录钱包流水
 		walletLog := &repository.WalletLog{
@@ -1067,6 +1174,9 @@ func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.Deduction
 
 		totalDeducted += deductFromWallet
 		remainingAmount -= deductFromWallet
+		if freezeLogErr != nil {
+			break
+		}
 	}
 
 	// 转换为JSON
@@ -1120,7 +1230,7 @@ func (s *DeductionService) ExecuteDeductionWithUnfreeze(record *models.Deduction
 	log.Printf("[DeductionService] Deduction with unfreeze executed: record=%d, amount=%d, deducted=%d, status=%d",
 		record.ID, record.Amount, totalDeducted, status)
 
-	return nil
+	return freezeLogErr
 }
 
 // GetReceivedPlans 获取我接收的代扣列表
@@ -1196,3 +1306,718 @@ func (s *DeductionService) GetDeductionSummary(agentID int64) (*models.Deduction
 
 	return summary, nil
 }
+
+// maxRestructurePeriods 重组后剩余期数上限（每天一期）
+const maxRestructurePeriods = 1000
+
+// restructureSchedule 计算重组后的剩余期数和每期金额
+// 指定新期数时按期数均分（末期补差），指定每期金额时按金额向上取整计算期数，二者只能指定其一
+func restructureSchedule(remainingAmount int64, newPeriods int, newPeriodAmount int64) (int, int64, error) {
+	if remainingAmount <= 0 {
+		return 0, 0, errors.New("代扣计划无剩余待扣金额")
+	}
+	if (newPeriods > 0) == (newPeriodAmount > 0) {
+		return 0, 0, errors.New("请指定新的剩余期数或每期金额（二选一）")
+	}
+
+	if newPeriods > 0 {
+		periodAmount := remainingAmount / int64(newPeriods)
+		if periodAmount <= 0 {
+			return 0, 0, errors.New("每期金额必须大于0")
+		}
+		if newPeriods > maxRestructurePeriods {
+			return 0, 0, fmt.Errorf("剩余期数不能超过%d期", maxRestructurePeriods)
+		}
+		return newPeriods, periodAmount, nil
+	}
+
+	if newPeriodAmount > remainingAmount {
+		newPeriodAmount = remainingAmount
+	}
+	periods := (remainingAmount + newPeriodAmount - 1) / newPeriodAmount
+	if periods > maxRestructurePeriods {
+		return 0, 0, fmt.Errorf("每期金额过小，剩余期数不能超过%d期", maxRestructurePeriods)
+	}
+	return int(periods), newPeriodAmount, nil
+}
+
+// nextDeductionPeriod 计算下一期期数（已执行过的最大期数+1，不含已结清/已重组的记录）
+func nextDeductionPeriod(records []*models.DeductionRecord) int {
+	maxPeriod := 0
+	for _, record := range records {
+		switch record.Status {
+		case models.DeductionRecordStatusSuccess, models.DeductionRecordStatusPartialSuccess, models.DeductionRecordStatusFailed:
+			if record.PeriodNum > maxPeriod {
+				maxPeriod = record.PeriodNum
+			}
+		}
+	}
+	return maxPeriod + 1
+}
+
+// deductionScheduleSnapshot 生成扣款计划快照JSON（仅包含指定状态的记录）
+func deductionScheduleSnapshot(records []*models.DeductionRecord, status int16) string {
+	items := make([]models.DeductionScheduleItem, 0, len(records))
+	for _, record := range records {
+		if record.Status != status {
+			continue
+		}
+		items = append(items, models.DeductionScheduleItem{
+			PeriodNum:   record.PeriodNum,
+			Amount:      record.Amount,
+			ScheduledAt: record.ScheduledAt,
+		})
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+// allocatePrepayment 按所选钱包顺序分配提前还款金额（仅使用可用余额）
+func allocatePrepayment(wallets []*repository.Wallet, amount int64) ([]int64, error) {
+	allocations := make([]int64, len(wallets))
+	remaining := amount
+	for i, wallet := range wallets {
+		if remaining <= 0 {
+			break
+		}
+		available := wallet.Balance - wallet.FrozenAmount
+		if available <= 0 {
+			continue
+		}
+		if available > remaining {
+			available = remaining
+		}
+		allocations[i] = available
+		remaining -= available
+	}
+	if remaining > 0 {
+		return nil, fmt.Errorf("所选钱包可用余额不足，还差%.2f元", float64(remaining)/100)
+	}
+	return allocations, nil
+}
+
+// newPlanChangeNo 生成变更编号
+func newPlanChangeNo() string {
+	return fmt.Sprintf("DC%s%06d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000000)
+}
+
+// isPlanChangeable 计划是否允许提前还款/重组（进行中或已暂停）
+func isPlanChangeable(status int16) bool {
+	return status == models.DeductionPlanStatusActive || status == models.DeductionPlanStatusPaused
+}
+
+// PrepayDeductionPlan 提前还款：被扣款方从所选钱包一次性结清剩余金额并关闭计划
+// 先释放计划已冻结的金额，再按所选钱包顺序从可用余额扣款，转入扣款方钱包
+func (s *DeductionService) PrepayDeductionPlan(planID int64, agentID int64, walletIDs []int64, reason string) (*models.DeductionPlanChange, error) {
+	if s.planChangeRepo == nil {
+		return nil, errors.New("提前还款功能未启用")
+	}
+	if len(walletIDs) == 0 {
+		return nil, errors.New("请选择扣款钱包")
+	}
+
+	var change *models.DeductionPlanChange
+	err := s.planChangeRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var plan models.DeductionPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, planID).Error; err != nil {
+			return fmt.Errorf("代扣计划不存在: %d", planID)
+		}
+		if plan.DeducteeID != agentID {
+			return errors.New("无权操作此代扣计划")
+		}
+		if !isPlanChangeable(plan.Status) {
+			return errors.New("代扣计划状态不允许提前还款")
+		}
+		if plan.RemainingAmount <= 0 {
+			return errors.New("代扣计划无剩余待扣金额")
+		}
+
+		var records []*models.DeductionRecord
+		if err := tx.Where("plan_id = ?", plan.ID).Order("period_num ASC").Find(&records).Error; err != nil {
+			return fmt.Errorf("查询代扣记录失败: %w", err)
+		}
+
+		// 锁定被扣款方全部钱包（按ID顺序，避免死锁）
+		var wallets []*repository.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ?", agentID).Order("id ASC").Find(&wallets).Error; err != nil {
+			return fmt.Errorf("锁定钱包失败: %w", err)
+		}
+		walletByID := make(map[int64]*repository.Wallet, len(wallets))
+		for _, w := range wallets {
+			walletByID[w.ID] = w
+		}
+		selected := make([]*repository.Wallet, 0, len(walletIDs))
+		seen := make(map[int64]bool, len(walletIDs))
+		for _, id := range walletIDs {
+			w, ok := walletByID[id]
+			if !ok {
+				return fmt.Errorf("钱包不存在或不属于当前代理商: %d", id)
+			}
+			if !seen[id] {
+				seen[id] = true
+				selected = append(selected, w)
+			}
+		}
+
+		// 1. 释放本计划冻结金额
+		released, err := s.releasePlanFrozen(tx, &plan, walletByID)
+		if err != nil {
+			return err
+		}
+
+		// 2. 按所选钱包顺序分配扣款金额
+		amount := plan.RemainingAmount
+		allocations, err := allocatePrepayment(selected, amount)
+		if err != nil {
+			return err
+		}
+		if s.riskHoldService != nil {
+			for i, wallet := range selected {
+				if allocations[i] <= 0 {
+					continue
+				}
+				if err := s.riskHoldService.CheckWithdraw(agentID, wallet, allocations[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 3. 生成提前还款代扣记录
+		now := time.Now()
+		periodNum := nextDeductionPeriod(records)
+		record := &models.DeductionRecord{
+			PlanID:       plan.ID,
+			PlanNo:       plan.PlanNo,
+			DeductorID:   plan.DeductorID,
+			DeducteeID:   plan.DeducteeID,
+			PeriodNum:    periodNum,
+			Amount:       amount,
+			ActualAmount: amount,
+			Status:       models.DeductionRecordStatusSuccess,
+			ScheduledAt:  now,
+			DeductedAt:   &now,
+			CreatedAt:    now,
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("创建代扣记录失败: %w", err)
+		}
+
+		// 4. 扣减所选钱包
+		walletDetails := make([]models.WalletDeductDetail, 0, len(selected))
+		for i, wallet := range selected {
+			deductAmount := allocations[i]
+			if deductAmount <= 0 {
+				continue
+			}
+			result := tx.Model(&repository.Wallet{}).
+				Where("id = ? AND balance - frozen_amount >= ?", wallet.ID, deductAmount).
+				Updates(map[string]interface{}{
+					"balance": gorm.Expr("balance - ?", deductAmount),
+					"version": gorm.Expr("version + 1"),
+				})
+			if result.Error != nil {
+				return fmt.Errorf("扣减钱包失败: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("钱包%d可用余额不足", wallet.ID)
+			}
+
+			walletLog := &repository.WalletLog{
+				WalletID:      wallet.ID,
+				AgentID:       wallet.AgentID,
+				WalletType:    wallet.WalletType,
+				LogType:       6, // 代扣
+				Amount:        -deductAmount,
+				BalanceBefore: wallet.Balance,
+				BalanceAfter:  wallet.Balance - deductAmount,
+				RefType:       "deduction_record",
+				RefID:         record.ID,
+				Remark:        "代扣提前还款",
+				CreatedAt:     now,
+			}
+			if err := tx.Create(walletLog).Error; err != nil {
+				return fmt.Errorf("创建钱包流水失败: %w", err)
+			}
+
+			walletDetails = append(walletDetails, models.WalletDeductDetail{
+				WalletID:      wallet.ID,
+				WalletType:    wallet.WalletType,
+				WalletName:    getWalletTypeName(wallet.WalletType),
+				BalanceBefore: wallet.Balance,
+				DeductAmount:  deductAmount,
+				BalanceAfter:  wallet.Balance - deductAmount,
+			})
+			wallet.Balance -= deductAmount
+		}
+		detailsJSON, _ := json.Marshal(walletDetails)
+		if err := tx.Model(record).Update("wallet_details", string(detailsJSON)).Error; err != nil {
+			return fmt.Errorf("更新代扣记录失败: %w", err)
+		}
+
+		// 5. 转入扣款方钱包（默认通道1分润钱包，与定时扣款一致）
+		if err := s.creditDeductorTx(tx, plan.DeductorID, amount, record.ID, "代扣提前还款收款"); err != nil {
+			return err
+		}
+
+		// 6. 剩余待扣记录标记为已结清，待确认的重组提议失效
+		if err := tx.Model(&models.DeductionRecord{}).
+			Where("plan_id = ? AND status = ?", plan.ID, models.DeductionRecordStatusPending).
+			Updates(map[string]interface{}{
+				"status":      models.DeductionRecordStatusSettled,
+				"fail_reason": "提前还款已结清",
+			}).Error; err != nil {
+			return fmt.Errorf("更新待扣记录失败: %w", err)
+		}
+		if err := tx.Model(&models.DeductionPlanChange{}).
+			Where("plan_id = ? AND status = ?", plan.ID, models.DeductionPlanChangeStatusPending).
+			Updates(map[string]interface{}{
+				"status":     models.DeductionPlanChangeStatusExpired,
+				"updated_at": now,
+			}).Error; err != nil {
+			return fmt.Errorf("关闭重组提议失败: %w", err)
+		}
+
+		// 7. 保存变更快照
+		change = &models.DeductionPlanChange{
+			ChangeNo:           newPlanChangeNo(),
+			PlanID:             plan.ID,
+			PlanNo:             plan.PlanNo,
+			ChangeType:         models.DeductionPlanChangeTypePrepay,
+			Status:             models.DeductionPlanChangeStatusApplied,
+			ProposerID:         agentID,
+			CounterpartyID:     plan.DeductorID,
+			OldTotalPeriods:    plan.TotalPeriods,
+			OldPeriodAmount:    plan.PeriodAmount,
+			OldRemainingAmount: amount,
+			OldSchedule:        deductionScheduleSnapshot(records, models.DeductionRecordStatusPending),
+			NewSchedule:        deductionScheduleSnapshot([]*models.DeductionRecord{record}, models.DeductionRecordStatusSuccess),
+			PrepayAmount:       amount,
+			ReleasedFrozen:     released,
+			WalletDetails:      string(detailsJSON),
+			RecordID:           &record.ID,
+			Reason:             reason,
+			DecidedAt:          &now,
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+		if err := tx.Create(change).Error; err != nil {
+			return fmt.Errorf("创建变更记录失败: %w", err)
+		}
+
+		// 8. 关闭计划
+		plan.DeductedAmount += amount
+		plan.RemainingAmount = 0
+		plan.FrozenAmount = 0
+		plan.CurrentPeriod = periodNum
+		plan.Status = models.DeductionPlanStatusCompleted
+		plan.CompletedAt = &now
+		plan.UpdatedAt = now
+		if err := tx.Save(&plan).Error; err != nil {
+			return fmt.Errorf("更新代扣计划失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[DeductionService] Prepaid deduction plan: %s, agent: %d, amount: %d, released_frozen: %d",
+		change.PlanNo, agentID, change.PrepayAmount, change.ReleasedFrozen)
+
+	return change, nil
+}
+
+// releasePlanFrozen 释放计划已冻结的金额（必须在事务内调用）
+// 每个钱包最多释放本计划在该钱包累计冻结的金额
+func (s *DeductionService) releasePlanFrozen(tx *gorm.DB, plan *models.DeductionPlan, wallets map[int64]*repository.Wallet) (int64, error) {
+	return s.releasePlanFrozenAmount(tx, plan, wallets, plan.FrozenAmount, models.DeductionFreezeTriggerTypePrepay)
+}
+
+// sumFreezeLogsByWallet 按钱包汇总冻结明细，得到计划在各钱包的冻结余额
+// 冻结记正数，扣款、释放记负数
+func sumFreezeLogsByWallet(logs []*models.DeductionFreezeLog) map[int64]int64 {
+	result := make(map[int64]int64)
+	for _, l := range logs {
+		result[l.WalletID] += l.FreezeAmount
+	}
+	return result
+}
+
+// releasePlanFrozenAmount 释放计划冻结金额中的指定部分（必须在事务内调用）
+// 按冻结明细汇总的各钱包冻结余额释放，不会动到钱包上其他业务的冻结
+func (s *DeductionService) releasePlanFrozenAmount(tx *gorm.DB, plan *models.DeductionPlan, wallets map[int64]*repository.Wallet,
+	releaseAmount int64, triggerType string) (int64, error) {
+	if plan.FrozenAmount <= 0 || releaseAmount <= 0 {
+		return 0, nil
+	}
+
+	var frozenByWallet []struct {
+		WalletID int64
+		Amount   int64
+	}
+	if err := tx.Model(&models.DeductionFreezeLog{}).
+		Select("wallet_id, SUM(freeze_amount) AS amount").
+		Where("plan_id = ?", plan.ID).
+		Group("wallet_id").
+		Order("wallet_id ASC").
+		Scan(&frozenByWallet).Error; err != nil {
+		return 0, fmt.Errorf("查询冻结明细失败: %w", err)
+	}
+
+	var released int64
+	toRelease := min(releaseAmount, plan.FrozenAmount)
+	for _, item := range frozenByWallet {
+		if toRelease <= 0 {
+			break
+		}
+		wallet, ok := wallets[item.WalletID]
+		if !ok {
+			continue
+		}
+		amount := item.Amount
+		if amount > wallet.FrozenAmount {
+			amount = wallet.FrozenAmount
+		}
+		if amount > toRelease {
+			amount = toRelease
+		}
+		if amount <= 0 {
+			continue
+		}
+
+		if err := tx.Model(&repository.Wallet{}).
+			Where("id = ? AND frozen_amount >= ?", wallet.ID, amount).
+			Updates(map[string]interface{}{
+				"frozen_amount": gorm.Expr("frozen_amount - ?", amount),
+				"version":       gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return 0, fmt.Errorf("释放冻结金额失败: %w", err)
+		}
+		wallet.FrozenAmount -= amount
+		released += amount
+		toRelease -= amount
+
+		freezeLog := &models.DeductionFreezeLog{
+			PlanID:       plan.ID,
+			AgentID:      plan.DeducteeID,
+			WalletID:     wallet.ID,
+			WalletType:   wallet.WalletType,
+			ChannelID:    wallet.ChannelID,
+			FreezeAmount: -amount,
+			TotalFrozen:  plan.FrozenAmount - released,
+			TriggerType:  triggerType,
+			CreatedAt:    time.Now(),
+		}
+		if err := tx.Create(freezeLog).Error; err != nil {
+			return 0, fmt.Errorf("创建冻结明细失败: %w", err)
+		}
+	}
+
+	if toRelease > 0 {
+		log.Printf("[DeductionService] Plan %d frozen amount %d not fully matched to wallets, unmatched: %d",
+			plan.ID, min(releaseAmount, plan.FrozenAmount), toRelease)
+	}
+	return released, nil
+}
+
+// creditDeductorTx 将扣款金额转入扣款方钱包（必须在事务内调用）
+func (s *DeductionService) creditDeductorTx(tx *gorm.DB, deductorID int64, amount int64, recordID int64, remark string) error {
+	var wallet repository.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("agent_id = ? AND channel_id = ? AND wallet_type = ?", deductorID, 1, models.WalletTypeProfit).
+		First(&wallet).Error; err != nil {
+		return fmt.Errorf("扣款方钱包不存在: %w", err)
+	}
+
+	if err := tx.Model(&repository.Wallet{}).
+		Where("id = ?", wallet.ID).
+		Updates(map[string]interface{}{
+			"balance":      gorm.Expr("balance + ?", amount),
+			"total_income": gorm.Expr("total_income + ?", amount),
+			"version":      gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("增加扣款方余额失败: %w", err)
+	}
+
+	walletLog := &repository.WalletLog{
+		WalletID:      wallet.ID,
+		AgentID:       deductorID,
+		WalletType:    wallet.WalletType,
+		LogType:       6, // 代扣
+		Amount:        amount,
+		BalanceBefore: wallet.Balance,
+		BalanceAfter:  wallet.Balance + amount,
+		RefType:       "deduction_record",
+		RefID:         recordID,
+		Remark:        remark,
+		CreatedAt:     time.Now(),
+	}
+	if err := tx.Create(walletLog).Error; err != nil {
+		return fmt.Errorf("创建扣款方流水失败: %w", err)
+	}
+	return nil
+}
+
+// ProposeRestructureRequest 代扣计划重组提议
+type ProposeRestructureRequest struct {
+	NewPeriods      int    `json:"new_periods"`       // 新剩余期数
+	NewPeriodAmount int64  `json:"new_period_amount"` // 新每期金额（分）
+	Reason          string `json:"reason"`            // 重组原因
+}
+
+// ProposeRestructure 发起代扣计划重组提议（扣款方或被扣款方均可发起，由对方接收或拒绝）
+// 仅对剩余待扣金额重新排期，已执行的扣款记录保持不变
+func (s *DeductionService) ProposeRestructure(planID int64, agentID int64, req *ProposeRestructureRequest) (*models.DeductionPlanChange, error) {
+	if s.planChangeRepo == nil {
+		return nil, errors.New("重组功能未启用")
+	}
+
+	plan, err := s.planRepo.FindByID(planID)
+	if err != nil || plan == nil {
+		return nil, fmt.Errorf("代扣计划不存在: %d", planID)
+	}
+
+	var counterpartyID int64
+	switch agentID {
+	case plan.DeductorID:
+		counterpartyID = plan.DeducteeID
+	case plan.DeducteeID:
+		counterpartyID = plan.DeductorID
+	default:
+		return nil, errors.New("无权操作此代扣计划")
+	}
+
+	if !isPlanChangeable(plan.Status) {
+		return nil, errors.New("代扣计划状态不允许重组")
+	}
+
+	periods, periodAmount, err := restructureSchedule(plan.RemainingAmount, req.NewPeriods, req.NewPeriodAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.planChangeRepo.FindPendingByPlan(plan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询重组提议失败: %w", err)
+	}
+	if pending != nil {
+		return nil, errors.New("该计划已有待确认的重组提议")
+	}
+
+	records, err := s.recordRepo.FindByPlanID(plan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代扣记录失败: %w", err)
+	}
+	preview := buildDeductionRecords(plan, nextDeductionPeriod(records),
+		splitDeductionAmounts(plan.RemainingAmount, periods, periodAmount), firstDeductionTime(time.Now()))
+
+	now := time.Now()
+	change := &models.DeductionPlanChange{
+		ChangeNo:           newPlanChangeNo(),
+		PlanID:             plan.ID,
+		PlanNo:             plan.PlanNo,
+		ChangeType:         models.DeductionPlanChangeTypeRestructure,
+		Status:             models.DeductionPlanChangeStatusPending,
+		ProposerID:         agentID,
+		CounterpartyID:     counterpartyID,
+		OldTotalPeriods:    plan.TotalPeriods,
+		OldPeriodAmount:    plan.PeriodAmount,
+		OldRemainingAmount: plan.RemainingAmount,
+		OldSchedule:        deductionScheduleSnapshot(records, models.DeductionRecordStatusPending),
+		NewPeriods:         periods,
+		NewPeriodAmount:    periodAmount,
+		NewSchedule:        deductionScheduleSnapshot(preview, models.DeductionRecordStatusPending),
+		Reason:             req.Reason,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	if err := s.planChangeRepo.Create(change); err != nil {
+		return nil, fmt.Errorf("创建重组提议失败: %w", err)
+	}
+
+	log.Printf("[DeductionService] Proposed restructure: %s, plan: %s, proposer: %d, periods: %d, period_amount: %d",
+		change.ChangeNo, plan.PlanNo, agentID, periods, periodAmount)
+
+	return change, nil
+}
+
+// getPendingRestructure 获取待确认的重组提议
+func (s *DeductionService) getPendingRestructure(changeID int64) (*models.DeductionPlanChange, error) {
+	if s.planChangeRepo == nil {
+		return nil, errors.New("重组功能未启用")
+	}
+	change, err := s.planChangeRepo.GetByID(changeID)
+	if err != nil {
+		return nil, fmt.Errorf("查询重组提议失败: %w", err)
+	}
+	if change == nil || change.ChangeType != models.DeductionPlanChangeTypeRestructure {
+		return nil, errors.New("重组提议不存在")
+	}
+	if change.Status != models.DeductionPlanChangeStatusPending {
+		return nil, fmt.Errorf("重组提议%s，无法操作", models.GetDeductionPlanChangeStatusName(change.Status))
+	}
+	return change, nil
+}
+
+// AcceptRestructure 接收重组提议：作废原待扣记录并按新计划重新排期
+// 提议后剩余金额发生变化（已发生扣款）或计划已结束时，提议失效
+func (s *DeductionService) AcceptRestructure(changeID int64, agentID int64) error {
+	change, err := s.getPendingRestructure(changeID)
+	if err != nil {
+		return err
+	}
+	if change.CounterpartyID != agentID {
+		return errors.New("无权操作此重组提议")
+	}
+
+	var expireReason string
+	err = s.planChangeRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var plan models.DeductionPlan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, change.PlanID).Error; err != nil {
+			return fmt.Errorf("代扣计划不存在: %d", change.PlanID)
+		}
+
+		now := time.Now()
+		if !isPlanChangeable(plan.Status) {
+			expireReason = "代扣计划已结束，重组提议已失效"
+		} else if plan.RemainingAmount != change.OldRemainingAmount {
+			expireReason = "提议后剩余金额已变化，重组提议已失效，请重新发起"
+		}
+		if expireReason != "" {
+			return tx.Model(&models.DeductionPlanChange{}).
+				Where("id = ? AND status = ?", change.ID, models.DeductionPlanChangeStatusPending).
+				Updates(map[string]interface{}{
+					"status":        models.DeductionPlanChangeStatusExpired,
+					"reject_reason": expireReason,
+					"updated_at":    now,
+				}).Error
+		}
+
+		var records []*models.DeductionRecord
+		if err := tx.Where("plan_id = ?", plan.ID).Order("period_num ASC").Find(&records).Error; err != nil {
+			return fmt.Errorf("查询代扣记录失败: %w", err)
+		}
+
+		// 1. 作废原待扣记录
+		if err := tx.Model(&models.DeductionRecord{}).
+			Where("plan_id = ? AND status = ?", plan.ID, models.DeductionRecordStatusPending).
+			Updates(map[string]interface{}{
+				"status":      models.DeductionRecordStatusRestructured,
+				"fail_reason": "已重组: " + change.ChangeNo,
+			}).Error; err != nil {
+			return fmt.Errorf("作废原待扣记录失败: %w", err)
+		}
+
+		// 2. 按新计划生成待扣记录
+		startPeriod := nextDeductionPeriod(records)
+		newRecords := buildDeductionRecords(&plan, startPeriod,
+			splitDeductionAmounts(plan.RemainingAmount, change.NewPeriods, change.NewPeriodAmount), firstDeductionTime(now))
+		if err := tx.CreateInBatches(newRecords, 100).Error; err != nil {
+			return fmt.Errorf("生成新代扣记录失败: %w", err)
+		}
+
+		// 3. 更新计划
+		plan.TotalPeriods = startPeriod - 1 + change.NewPeriods
+		plan.PeriodAmount = change.NewPeriodAmount
+		plan.UpdatedAt = now
+		if err := tx.Save(&plan).Error; err != nil {
+			return fmt.Errorf("更新代扣计划失败: %w", err)
+		}
+
+		// 4. 保存最终快照
+		result := tx.Model(&models.DeductionPlanChange{}).
+			Where("id = ? AND status = ?", change.ID, models.DeductionPlanChangeStatusPending).
+			Updates(map[string]interface{}{
+				"status":       models.DeductionPlanChangeStatusApplied,
+				"old_schedule": deductionScheduleSnapshot(records, models.DeductionRecordStatusPending),
+				"new_schedule": deductionScheduleSnapshot(newRecords, models.DeductionRecordStatusPending),
+				"decided_at":   now,
+				"updated_at":   now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新重组提议失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("重组提议状态已变更，请刷新后重试")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if expireReason != "" {
+		return errors.New(expireReason)
+	}
+
+	log.Printf("[DeductionService] Accepted restructure: %s, plan: %s, agent: %d", change.ChangeNo, change.PlanNo, agentID)
+
+	return nil
+}
+
+// RejectRestructure 拒绝重组提议
+func (s *DeductionService) RejectRestructure(changeID int64, agentID int64, reason string) error {
+	change, err := s.getPendingRestructure(changeID)
+	if err != nil {
+		return err
+	}
+	if change.CounterpartyID != agentID {
+		return errors.New("无权操作此重组提议")
+	}
+
+	now := time.Now()
+	ok, err := s.planChangeRepo.UpdateStatus(change.ID, models.DeductionPlanChangeStatusPending, map[string]interface{}{
+		"status":        models.DeductionPlanChangeStatusRejected,
+		"reject_reason": reason,
+		"decided_at":    now,
+	})
+	if err != nil {
+		return fmt.Errorf("更新重组提议失败: %w", err)
+	}
+	if !ok {
+		return errors.New("重组提议状态已变更，请刷新后重试")
+	}
+
+	log.Printf("[DeductionService] Rejected restructure: %s, agent: %d", change.ChangeNo, agentID)
+
+	return nil
+}
+
+// WithdrawRestructure 发起方撤回重组提议
+func (s *DeductionService) WithdrawRestructure(changeID int64, agentID int64) error {
+	change, err := s.getPendingRestructure(changeID)
+	if err != nil {
+		return err
+	}
+	if change.ProposerID != agentID {
+		return errors.New("无权操作此重组提议")
+	}
+
+	ok, err := s.planChangeRepo.UpdateStatus(change.ID, models.DeductionPlanChangeStatusPending, map[string]interface{}{
+		"status": models.DeductionPlanChangeStatusWithdrawn,
+	})
+	if err != nil {
+		return fmt.Errorf("更新重组提议失败: %w", err)
+	}
+	if !ok {
+		return errors.New("重组提议状态已变更，请刷新后重试")
+	}
+	return nil
+}
+
+// GetPlanChanges 获取代扣计划的变更记录（提前还款/重组）
+func (s *DeductionService) GetPlanChanges(planID int64) ([]*models.DeductionPlanChange, error) {
+	if s.planChangeRepo == nil {
+		return []*models.DeductionPlanChange{}, nil
+	}
+	return s.planChangeRepo.FindByPlanID(planID)
+}
+
+// GetPendingRestructures 获取待我确认的重组提议
+func (s *DeductionService) GetPendingRestructures(agentID int64, page, pageSize int) ([]*models.DeductionPlanChange, int64, error) {
+	if s.planChangeRepo == nil {
+		return []*models.DeductionPlanChange{}, 0, nil
+	}
+	offset := (page - 1) * pageSize
+	return s.planChangeRepo.FindPendingByCounterparty(agentID, pageSize, offset)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -364,8 +365,9 @@ func (m *MockWalletRepository) UpdateFrozenAmount(id int64, amount int64) error
 
 // MockDeductionFreezeLogRepository 模拟代扣冻结日志仓库
 type MockDeductionFreezeLogRepository struct {
-	logs   map[int64]*models.DeductionFreezeLog
-	nextID int64
+	logs      map[int64]*models.DeductionFreezeLog
+	nextID    int64
+	createErr error // 非空时Create返回该错误
 }
 
 func NewMockDeductionFreezeLogRepository() *MockDeductionFreezeLogRepository {
@@ -376,6 +378,9 @@ func NewMockDeductionFreezeLogRepository() *MockDeductionFreezeLogRepository {
 }
 
 func (m *MockDeductionFreezeLogRepository) Create(log *models.DeductionFreezeLog) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
 	log.ID = m.nextID
 	m.nextID++
 	m.logs[log.ID] = log
@@ -489,6 +494,48 @@ func (m *MockAgentRepository) FindAncestors(agentID int64) ([]*repository.Agent,
 	return ancestors, nil
 }
 
+// MockHierarchyRepository 模拟代理商层级仓库（按ParentID逐级上溯）
+type MockHierarchyRepository struct {
+	agentRepo *MockAgentRepository
+}
+
+func (m *MockHierarchyRepository) FindAncestors(agentID int64, includeSelf bool) ([]*repository.Agent, error) {
+	return nil, nil
+}
+
+func (m *MockHierarchyRepository) FindDescendants(agentID int64, maxDepth int) ([]*repository.HierarchyNode, error) {
+	return nil, nil
+}
+
+func (m *MockHierarchyRepository) IsDescendant(ancestorID, descendantID int64) (bool, error) {
+	path, err := m.GetPathBetween(ancestorID, descendantID)
+	return len(path) > 1, err
+}
+
+func (m *MockHierarchyRepository) GetPathBetween(ancestorID, descendantID int64) ([]int64, error) {
+	var reversed []int64
+	for id := descendantID; id != 0; {
+		agent, ok := m.agentRepo.agents[id]
+		if !ok {
+			return nil, nil
+		}
+		reversed = append(reversed, id)
+		if id == ancestorID {
+			path := make([]int64, 0, len(reversed))
+			for i := len(reversed) - 1; i >= 0; i-- {
+				path = append(path, reversed[i])
+			}
+			return path, nil
+		}
+		id = agent.ParentID
+	}
+	return nil, nil
+}
+
+func (m *MockHierarchyRepository) InsertNode(agentID, parentID int64) error {
+	return nil
+}
+
 // =============================================================================
 // 测试用例
 // =============================================================================
@@ -512,6 +559,7 @@ func TestCreateDeductionPlan_Success(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	// 执行 - Q6测试：伙伴代扣（任意代理商之间）
@@ -579,6 +627,7 @@ func TestCreateDeductionPlan_PartnerDeduction_AnyAgent(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	testCases := []struct {
@@ -631,6 +680,7 @@ func TestCreateDeductionPlan_InvalidAgent(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	// 被扣款方不存在
@@ -666,6 +716,7 @@ func TestPauseAndResumeDeductionPlan(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	// 创建计划
@@ -719,6 +770,7 @@ func TestCancelDeductionPlan(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	// 创建计划
@@ -763,6 +815,7 @@ func TestCreateDeductionChain_CrossLevel(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	// 创建跨级代扣链：A 下发给 C，需要生成 C→B→A 的代扣链
@@ -816,6 +869,7 @@ func TestCreateDeductionChain_MinimumAgents(t *testing.T) {
 		planRepo, recordRepo, chainRepo, chainItemRepo,
 		NewMockDeductionFreezeLogRepository(),
 		walletRepo, walletLogRepo, agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 	)
 
 	req := &CreateDeductionChainRequest{
@@ -854,3 +908,250 @@ func TestGetWalletTypeName(t *testing.T) {
 		})
 	}
 }
+
+// TestSplitDeductionAmounts 测试按期拆分金额（末期补差）
+func TestSplitDeductionAmounts(t *testing.T) {
+	amounts := splitDeductionAmounts(10000, 3, 3333)
+	want := []int64{3333, 3333, 3334}
+	if len(amounts) != len(want) {
+		t.Fatalf("len = %d, want %d", len(amounts), len(want))
+	}
+	for i := range want {
+		if amounts[i] != want[i] {
+			t.Errorf("amounts[%d] = %d, want %d", i, amounts[i], want[i])
+		}
+	}
+
+	plan := &models.DeductionPlan{ID: 1, PlanNo: "DP1", DeductorID: 1, DeducteeID: 2}
+	base := time.Date(2026, 10, 20, 8, 0, 0, 0, time.Local)
+	records := buildDeductionRecords(plan, 4, amounts, base)
+	if records[0].PeriodNum != 4 || records[2].PeriodNum != 6 {
+		t.Errorf("period nums = %d..%d, want 4..6", records[0].PeriodNum, records[2].PeriodNum)
+	}
+	if !records[2].ScheduledAt.Equal(base.AddDate(0, 0, 2)) {
+		t.Errorf("last scheduled at %v, want %v", records[2].ScheduledAt, base.AddDate(0, 0, 2))
+	}
+}
+
+// TestRestructureSchedule 测试重组期数与每期金额计算
+func TestRestructureSchedule(t *testing.T) {
+	testCases := []struct {
+		name         string
+		remaining    int64
+		periods      int
+		periodAmount int64
+		wantPeriods  int
+		wantAmount   int64
+		wantErr      bool
+	}{
+		{"按期数均分", 10000, 4, 0, 4, 2500, false},
+		{"按每期金额向上取整", 10000, 0, 3000, 4, 3000, false},
+		{"每期金额超过剩余", 10000, 0, 20000, 1, 10000, false},
+		{"同时指定", 10000, 4, 3000, 0, 0, true},
+		{"均未指定", 10000, 0, 0, 0, 0, true},
+		{"期数过多", 10, 20, 0, 0, 0, true},
+		{"超过期数上限", 1000000, 0, 1, 0, 0, true},
+		{"无剩余金额", 0, 4, 0, 0, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			periods, amount, err := restructureSchedule(tc.remaining, tc.periods, tc.periodAmount)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got periods=%d amount=%d", periods, amount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if periods != tc.wantPeriods || amount != tc.wantAmount {
+				t.Errorf("got (%d, %d), want (%d, %d)", periods, amount, tc.wantPeriods, tc.wantAmount)
+			}
+		})
+	}
+}
+
+// TestNextDeductionPeriod 测试下一期期数（忽略待扣、已结清和已重组记录）
+func TestNextDeductionPeriod(t *testing.T) {
+	records := []*models.DeductionRecord{
+		{PeriodNum: 1, Status: models.DeductionRecordStatusSuccess},
+		{PeriodNum: 2, Status: models.DeductionRecordStatusFailed},
+		{PeriodNum: 3, Status: models.DeductionRecordStatusRestructured},
+		{PeriodNum: 4, Status: models.DeductionRecordStatusPending},
+	}
+	if got := nextDeductionPeriod(records); got != 3 {
+		t.Errorf("nextDeductionPeriod = %d, want 3", got)
+	}
+	if got := nextDeductionPeriod(nil); got != 1 {
+		t.Errorf("nextDeductionPeriod(nil) = %d, want 1", got)
+	}
+}
+
+// TestAllocatePrepayment 测试提前还款按所选钱包顺序分配（仅使用可用余额）
+func TestAllocatePrepayment(t *testing.T) {
+	wallets := []*repository.Wallet{
+		{ID: 1, Balance: 5000, FrozenAmount: 2000},
+		{ID: 2, Balance: 1000, FrozenAmount: 1000},
+		{ID: 3, Balance: 8000},
+	}
+
+	allocations, err := allocatePrepayment(wallets, 6000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int64{3000, 0, 3000}
+	for i := range want {
+		if allocations[i] != want[i] {
+			t.Errorf("allocations[%d] = %d, want %d", i, allocations[i], want[i])
+		}
+	}
+
+	if _, err := allocatePrepayment(wallets, 12000); err == nil {
+		t.Error("expected insufficient balance error")
+	}
+}
+
+// TestGetAgentPathBetween 测试获取下发方到目标代理商的链路
+func TestGetAgentPathBetween(t *testing.T) {
+	agentRepo := NewMockAgentRepository()
+	// A -> B -> C，D为另一顶级
+	agentRepo.AddAgent(1, "A", 0, "/", 1)
+	agentRepo.AddAgent(2, "B", 1, "/1/", 2)
+	agentRepo.AddAgent(3, "C", 2, "/1/2/", 3)
+	agentRepo.AddAgent(4, "D", 0, "/", 1)
+
+	tests := []struct {
+		name     string
+		from, to int64
+		want     []int64
+		wantErr  bool
+	}{
+		{"跨两级", 1, 3, []int64{1, 2, 3}, false},
+		{"直属下级", 2, 3, []int64{2, 3}, false},
+		{"非上级", 4, 3, nil, true},
+		{"自己", 3, 3, nil, true},
+	}
+	service := NewDeductionService(
+		NewMockDeductionPlanRepository(), NewMockDeductionRecordRepository(),
+		NewMockDeductionChainRepository(), NewMockDeductionChainItemRepository(),
+		NewMockDeductionFreezeLogRepository(),
+		NewMockWalletRepository(), NewMockWalletLogRepository(), agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
+	)
+	for _, tt := range tests {
+		got, err := service.GetAgentPathBetween(tt.from, tt.to)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// TestExecuteDeductionWithUnfreeze_OnlyPlanFrozen 测试到期扣款只扣本计划的冻结并写入扣减明细
+func TestExecuteDeductionWithUnfreeze_OnlyPlanFrozen(t *testing.T) {
+	planRepo := NewMockDeductionPlanRepository()
+	recordRepo := NewMockDeductionRecordRepository()
+	walletRepo := NewMockWalletRepository()
+	freezeLogRepo := NewMockDeductionFreezeLogRepository()
+	agentRepo := NewMockAgentRepository()
+	service := NewDeductionService(
+		planRepo, recordRepo, NewMockDeductionChainRepository(), NewMockDeductionChainItemRepository(),
+		freezeLogRepo, walletRepo, NewMockWalletLogRepository(), agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
+	)
+
+	// 分润钱包的冻结来自提现，服务费钱包的冻结属于本计划
+	profitWallet := walletRepo.AddWallet(2, 1, models.WalletTypeProfit, 5000)
+	profitWallet.FrozenAmount = 2000
+	feeWallet := walletRepo.AddWallet(2, 1, models.WalletTypeServiceFee, 5000)
+	feeWallet.FrozenAmount = 3000
+	walletRepo.AddWallet(1, 1, models.WalletTypeProfit, 0) // 扣款方收款钱包
+
+	plan := &models.DeductionPlan{
+		DeductorID:      1,
+		DeducteeID:      2,
+		DeductionSource: models.DeductionSourceBoth,
+		TotalAmount:     6000,
+		RemainingAmount: 6000,
+		FrozenAmount:    3000,
+		Status:          models.DeductionPlanStatusActive,
+	}
+	planRepo.Create(plan)
+	freezeLogRepo.Create(&models.DeductionFreezeLog{PlanID: plan.ID, WalletID: feeWallet.ID, FreezeAmount: 3000})
+
+	record := &models.DeductionRecord{PlanID: plan.ID, DeductorID: 1, DeducteeID: 2, PeriodNum: 1, Amount: 3000}
+	recordRepo.Create(record)
+
+	if err := service.ExecuteDeductionWithUnfreeze(record); err != nil {
+		t.Fatalf("ExecuteDeductionWithUnfreeze failed: %v", err)
+	}
+	if profitWallet.FrozenAmount != 2000 || profitWallet.Balance != 5000 {
+		t.Errorf("profit wallet frozen = %d, balance = %d, want untouched", profitWallet.FrozenAmount, profitWallet.Balance)
+	}
+	if feeWallet.FrozenAmount != 0 || feeWallet.Balance != 2000 {
+		t.Errorf("fee wallet frozen = %d, balance = %d, want 0 and 2000", feeWallet.FrozenAmount, feeWallet.Balance)
+	}
+	logs, _ := freezeLogRepo.FindByPlanID(plan.ID)
+	if remaining := sumFreezeLogsByWallet(logs)[feeWallet.ID]; remaining != 0 {
+		t.Errorf("plan frozen on fee wallet = %d, want 0", remaining)
+	}
+}
+
+// TestExecuteDeductionWithUnfreeze_FreezeLogError 测试冻结扣减明细写入失败时停止扣款并返回错误
+func TestExecuteDeductionWithUnfreeze_FreezeLogError(t *testing.T) {
+	planRepo := NewMockDeductionPlanRepository()
+	recordRepo := NewMockDeductionRecordRepository()
+	walletRepo := NewMockWalletRepository()
+	freezeLogRepo := NewMockDeductionFreezeLogRepository()
+	agentRepo := NewMockAgentRepository()
+	service := NewDeductionService(
+		planRepo, recordRepo, NewMockDeductionChainRepository(), NewMockDeductionChainItemRepository(),
+		freezeLogRepo, walletRepo, NewMockWalletLogRepository(), agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
+	)
+
+	profitWallet := walletRepo.AddWallet(2, 1, models.WalletTypeProfit, 5000)
+	profitWallet.FrozenAmount = 1000
+	feeWallet := walletRepo.AddWallet(2, 1, models.WalletTypeServiceFee, 5000)
+	feeWallet.FrozenAmount = 1000
+	walletRepo.AddWallet(1, 1, models.WalletTypeProfit, 0) // 扣款方收款钱包
+
+	plan := &models.DeductionPlan{
+		DeductorID:      1,
+		DeducteeID:      2,
+		DeductionSource: models.DeductionSourceBoth,
+		TotalAmount:     2000,
+		RemainingAmount: 2000,
+		FrozenAmount:    2000,
+		Status:          models.DeductionPlanStatusActive,
+	}
+	planRepo.Create(plan)
+	freezeLogRepo.Create(&models.DeductionFreezeLog{PlanID: plan.ID, WalletID: profitWallet.ID, FreezeAmount: 1000})
+	freezeLogRepo.Create(&models.DeductionFreezeLog{PlanID: plan.ID, WalletID: feeWallet.ID, FreezeAmount: 1000})
+	freezeLogRepo.createErr = errors.New("db error")
+
+	record := &models.DeductionRecord{PlanID: plan.ID, DeductorID: 1, DeducteeID: 2, PeriodNum: 1, Amount: 2000}
+	recordRepo.Create(record)
+
+	if err := service.ExecuteDeductionWithUnfreeze(record); err == nil {
+		t.Fatal("ExecuteDeductionWithUnfreeze should return the freeze log error")
+	}
+	if deducted := 10000 - profitWallet.Balance - feeWallet.Balance; deducted != 1000 {
+		t.Errorf("deducted = %d, want 1000 (stop after the failed freeze log)", deducted)
+	}
+	if plan.FrozenAmount != 1000 || plan.DeductedAmount != 1000 {
+		t.Errorf("plan frozen = %d, deducted = %d, want 1000 and 1000", plan.FrozenAmount, plan.DeductedAmount)
+	}
+}
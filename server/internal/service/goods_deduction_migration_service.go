@@ -0,0 +1,579 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// goodsDeductionMigrationBatch 每批读取的货款代扣数量
+const goodsDeductionMigrationBatch = 100
+
+// GoodsDeductionMigrationService 货款代扣迁移服务
+// 将 goods_deductions 逐笔转换为统一代扣计划：
+//   - 计划编号沿用代扣编号，关联类型 goods_deduction，关联ID为原货款代扣ID
+//   - 每条扣款明细转换为一条成功的代扣记录（legacy_detail_id 指向原明细），剩余金额生成一期待扣记录
+//   - 进行中的计划迁移后按接收确认规则冻结被扣款方现有余额
+//   - 原数据自身不一致（明细合计与已扣金额不符等）的记录跳过，列入报告人工处理
+type GoodsDeductionMigrationService struct {
+	migrationRepo    *repository.GormGoodsDeductionMigrationRepository
+	walletRepo       repository.WalletRepository
+	deductionService *DeductionService
+}
+
+// NewGoodsDeductionMigrationService 创建货款代扣迁移服务
+func NewGoodsDeductionMigrationService(
+	migrationRepo *repository.GormGoodsDeductionMigrationRepository,
+	walletRepo repository.WalletRepository,
+	deductionService *DeductionService,
+) *GoodsDeductionMigrationService {
+	return &GoodsDeductionMigrationService{
+		migrationRepo:    migrationRepo,
+		walletRepo:       walletRepo,
+		deductionService: deductionService,
+	}
+}
+
+// DryRun 试运行：核对全部未迁移的货款代扣并生成报告，不写入代扣计划
+func (s *GoodsDeductionMigrationService) DryRun(operatorID int64, operatorName string) (*models.GoodsDeductionMigrationRun, error) {
+	return s.execute(true, operatorID, operatorName)
+}
+
+// Run 正式迁移全部未迁移的货款代扣
+func (s *GoodsDeductionMigrationService) Run(operatorID int64, operatorName string) (*models.GoodsDeductionMigrationRun, error) {
+	return s.execute(false, operatorID, operatorName)
+}
+
+func (s *GoodsDeductionMigrationService) execute(dryRun bool, operatorID int64, operatorName string) (*models.GoodsDeductionMigrationRun, error) {
+	now := time.Now()
+	run := &models.GoodsDeductionMigrationRun{
+		RunNo:        fmt.Sprintf("GDM%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		DryRun:       dryRun,
+		OperatorID:   operatorID,
+		OperatorName: operatorName,
+		CreatedAt:    now,
+	}
+	if err := s.migrationRepo.CreateRun(run); err != nil {
+		return nil, fmt.Errorf("创建迁移批次失败: %w", err)
+	}
+
+	items := make([]*models.GoodsDeductionMigrationItem, 0)
+	var lastID int64
+	for {
+		deductions, err := s.migrationRepo.FindUnmigrated(lastID, goodsDeductionMigrationBatch)
+		if err != nil {
+			return nil, fmt.Errorf("查询货款代扣失败: %w", err)
+		}
+		if len(deductions) == 0 {
+			break
+		}
+
+		for _, gd := range deductions {
+			lastID = gd.ID
+			item := s.migrateOne(gd, dryRun, now)
+			items = append(items, item)
+
+			run.TotalCount++
+			if isGoodsDeductionInFlight(gd.Status) {
+				run.InFlightCount++
+			}
+			switch item.Result {
+			case models.GoodsDeductionMigrationResultMigrated:
+				run.MigratedCount++
+				run.RemainingAmount += gd.RemainingAmount
+			case models.GoodsDeductionMigrationResultAdopted:
+				run.AdoptedCount++
+				run.RemainingAmount += gd.RemainingAmount
+			case models.GoodsDeductionMigrationResultMismatch:
+				run.MismatchCount++
+			case models.GoodsDeductionMigrationResultConflict:
+				run.ConflictCount++
+			default:
+				run.FailedCount++
+			}
+			run.FrozenAmount += item.FrozenAmount
+		}
+	}
+
+	report, _ := json.Marshal(items)
+	finishedAt := time.Now()
+	run.Report = string(report)
+	run.FinishedAt = &finishedAt
+	if err := s.migrationRepo.SaveRun(run); err != nil {
+		return nil, fmt.Errorf("保存迁移报告失败: %w", err)
+	}
+	run.Items = items
+
+	log.Printf("[GoodsDeductionMigration] Run %s (dry_run=%v): total=%d, migrated=%d, adopted=%d, mismatch=%d, conflict=%d, failed=%d",
+		run.RunNo, dryRun, run.TotalCount, run.MigratedCount, run.AdoptedCount, run.MismatchCount, run.ConflictCount, run.FailedCount)
+
+	return run, nil
+}
+
+// migrateOne 核对并迁移一笔货款代扣
+func (s *GoodsDeductionMigrationService) migrateOne(gd *models.GoodsDeduction, dryRun bool, now time.Time) *models.GoodsDeductionMigrationItem {
+	item := &models.GoodsDeductionMigrationItem{
+		DeductionID:     gd.ID,
+		DeductionNo:     gd.DeductionNo,
+		LegacyStatus:    gd.Status,
+		TotalAmount:     gd.TotalAmount,
+		DeductedAmount:  gd.DeductedAmount,
+		RemainingAmount: gd.RemainingAmount,
+	}
+	fail := func(result string, issues ...string) *models.GoodsDeductionMigrationItem {
+		item.Result = result
+		item.Issues = append(item.Issues, issues...)
+		return item
+	}
+
+	details, err := s.migrationRepo.FindAllDetails(gd.ID)
+	if err != nil {
+		return fail(models.GoodsDeductionMigrationResultFailed, "查询扣款明细失败: "+err.Error())
+	}
+	item.DetailCount = len(details)
+	for _, d := range details {
+		item.DetailAmount += d.Amount
+	}
+
+	if issues := checkGoodsDeduction(gd, details); len(issues) > 0 {
+		return fail(models.GoodsDeductionMigrationResultMismatch, issues...)
+	}
+
+	// 031脚本已插入的同编号计划：尚无扣款记录时接管，否则人工处理
+	existing, err := s.migrationRepo.FindPlanByNo(gd.DeductionNo)
+	if err != nil {
+		return fail(models.GoodsDeductionMigrationResultFailed, "查询同编号计划失败: "+err.Error())
+	}
+	if existing != nil {
+		recordCount, err := s.migrationRepo.CountPlanRecords(existing.ID)
+		if err != nil {
+			return fail(models.GoodsDeductionMigrationResultFailed, "查询同编号计划记录失败: "+err.Error())
+		}
+		if issues := checkAdoptablePlan(existing, gd, recordCount); len(issues) > 0 {
+			item.PlanID = existing.ID
+			item.PlanStatus = existing.Status
+			return fail(models.GoodsDeductionMigrationResultConflict, issues...)
+		}
+	}
+
+	plan, records := buildMigratedPlan(gd, details, s.resolveWalletIDs(gd.ToAgentID, details), now)
+	item.PlanStatus = plan.Status
+	item.Result = models.GoodsDeductionMigrationResultMigrated
+	if existing != nil {
+		item.PlanID = existing.ID
+		item.Result = models.GoodsDeductionMigrationResultAdopted
+	}
+	if dryRun {
+		return item
+	}
+
+	err = s.migrationRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var locked models.GoodsDeduction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, gd.ID).Error; err != nil {
+			return err
+		}
+		if locked.MigratedPlanID != nil {
+			return fmt.Errorf("已被其他批次迁移至计划%d", *locked.MigratedPlanID)
+		}
+		if locked.DeductedAmount != gd.DeductedAmount || locked.Status != gd.Status {
+			return fmt.Errorf("核对后数据已变化，请重新执行")
+		}
+
+		if existing != nil {
+			plan.ID = existing.ID
+			plan.FrozenAmount = existing.FrozenAmount
+			if err := tx.Save(plan).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Create(plan).Error; err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			record.PlanID = plan.ID
+		}
+		if len(records) > 0 {
+			if err := tx.Create(&records).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&locked).Updates(map[string]interface{}{
+			"migrated_plan_id": plan.ID,
+			"migrated_at":      now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 终端划拨关联到代扣计划
+		return tx.Model(&models.TerminalDistribute{}).
+			Where("goods_deduction_id = ? AND deduction_plan_id IS NULL", gd.ID).
+			Update("deduction_plan_id", plan.ID).Error
+	})
+	if err != nil {
+		return fail(models.GoodsDeductionMigrationResultFailed, "迁移失败: "+err.Error())
+	}
+	item.PlanID = plan.ID
+
+	// 进行中的计划按接收确认规则冻结现有余额
+	if plan.Status == models.DeductionPlanStatusActive && plan.RemainingAmount > plan.FrozenAmount {
+		frozen, err := s.deductionService.freezeExistingBalance(plan)
+		if err != nil {
+			item.Issues = append(item.Issues, "冻结现有余额失败: "+err.Error())
+		}
+		item.FrozenAmount = frozen
+	}
+
+	// 迁移后立即核对
+	if issues := s.reconcileOne(gd, details); len(issues) > 0 {
+		item.Issues = append(item.Issues, issues...)
+	}
+	return item
+}
+
+// resolveWalletIDs 按扣款明细的通道和钱包类型查找被扣款方钱包ID，找不到时为0
+func (s *GoodsDeductionMigrationService) resolveWalletIDs(agentID int64, details []*models.GoodsDeductionDetail) []int64 {
+	walletIDs := make([]int64, len(details))
+	cache := make(map[string]int64)
+	for i, d := range details {
+		if d.ChannelID == nil {
+			continue
+		}
+		key := fmt.Sprintf("%d-%d", *d.ChannelID, d.WalletType)
+		walletID, ok := cache[key]
+		if !ok {
+			if wallet, err := s.walletRepo.FindByAgentAndType(agentID, *d.ChannelID, d.WalletType); err == nil && wallet != nil {
+				walletID = wallet.ID
+			}
+			cache[key] = walletID
+		}
+		walletIDs[i] = walletID
+	}
+	return walletIDs
+}
+
+// Reconcile 对账：逐笔核对已迁移的货款代扣与代扣计划
+func (s *GoodsDeductionMigrationService) Reconcile() (*models.GoodsDeductionReconcileReport, error) {
+	report := &models.GoodsDeductionReconcileReport{
+		Items:     make([]*models.GoodsDeductionMigrationItem, 0),
+		CheckedAt: time.Now(),
+	}
+
+	var lastID int64
+	for {
+		deductions, err := s.migrationRepo.FindMigrated(lastID, goodsDeductionMigrationBatch)
+		if err != nil {
+			return nil, fmt.Errorf("查询已迁移货款代扣失败: %w", err)
+		}
+		if len(deductions) == 0 {
+			break
+		}
+
+		for _, gd := range deductions {
+			lastID = gd.ID
+			details, err := s.migrationRepo.FindAllDetails(gd.ID)
+			if err != nil {
+				return nil, fmt.Errorf("查询扣款明细失败: %w", err)
+			}
+
+			report.CheckedCount++
+			issues := s.reconcileOne(gd, details)
+			if len(issues) == 0 {
+				report.MatchedCount++
+				continue
+			}
+
+			report.MismatchCount++
+			item := &models.GoodsDeductionMigrationItem{
+				DeductionID:     gd.ID,
+				DeductionNo:     gd.DeductionNo,
+				LegacyStatus:    gd.Status,
+				PlanID:          *gd.MigratedPlanID,
+				Result:          models.GoodsDeductionMigrationResultMismatch,
+				TotalAmount:     gd.TotalAmount,
+				DeductedAmount:  gd.DeductedAmount,
+				RemainingAmount: gd.RemainingAmount,
+				DetailCount:     len(details),
+				Issues:          issues,
+			}
+			for _, d := range details {
+				item.DetailAmount += d.Amount
+			}
+			report.Items = append(report.Items, item)
+		}
+	}
+
+	unmigrated, err := s.migrationRepo.CountUnmigrated()
+	if err != nil {
+		return nil, fmt.Errorf("统计未迁移货款代扣失败: %w", err)
+	}
+	report.UnmigratedCount = unmigrated
+
+	return report, nil
+}
+
+// reconcileOne 核对一笔已迁移的货款代扣
+func (s *GoodsDeductionMigrationService) reconcileOne(gd *models.GoodsDeduction, details []*models.GoodsDeductionDetail) []string {
+	var planID int64
+	if gd.MigratedPlanID != nil {
+		planID = *gd.MigratedPlanID
+	} else if plan, err := s.migrationRepo.FindPlanByNo(gd.DeductionNo); err == nil && plan != nil {
+		planID = plan.ID
+	}
+
+	plan, err := s.migrationRepo.FindPlanByID(planID)
+	if err != nil {
+		return []string{"查询代扣计划失败: " + err.Error()}
+	}
+	var legacyRecords []*models.DeductionRecord
+	if plan != nil {
+		if legacyRecords, err = s.migrationRepo.FindLegacyRecords(plan.ID); err != nil {
+			return []string{"查询迁移记录失败: " + err.Error()}
+		}
+	}
+	return reconcileMigratedPlan(gd, details, plan, legacyRecords)
+}
+
+// ListRuns 获取迁移批次列表
+func (s *GoodsDeductionMigrationService) ListRuns(page, pageSize int) ([]*models.GoodsDeductionMigrationRun, int64, error) {
+	offset := (page - 1) * pageSize
+	return s.migrationRepo.ListRuns(pageSize, offset)
+}
+
+// GetRun 获取迁移批次及逐笔报告
+func (s *GoodsDeductionMigrationService) GetRun(id int64) (*models.GoodsDeductionMigrationRun, error) {
+	run, err := s.migrationRepo.GetRun(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移批次失败: %w", err)
+	}
+	if run == nil {
+		return nil, fmt.Errorf("迁移批次不存在")
+	}
+	if run.Report != "" {
+		if err := json.Unmarshal([]byte(run.Report), &run.Items); err != nil {
+			return nil, fmt.Errorf("解析迁移报告失败: %w", err)
+		}
+	}
+	return run, nil
+}
+
+// isGoodsDeductionInFlight 是否为待接收/进行中的货款代扣
+func isGoodsDeductionInFlight(status int16) bool {
+	return status == models.GoodsDeductionStatusPendingAccept || status == models.GoodsDeductionStatusInProgress
+}
+
+// goodsStatusToPlanStatus 货款代扣状态转换为代扣计划状态（进行中但已无剩余视为已完成）
+func goodsStatusToPlanStatus(gd *models.GoodsDeduction) int16 {
+	switch gd.Status {
+	case models.GoodsDeductionStatusPendingAccept:
+		return models.DeductionPlanStatusPendingAccept
+	case models.GoodsDeductionStatusInProgress:
+		if gd.RemainingAmount <= 0 {
+			return models.DeductionPlanStatusCompleted
+		}
+		return models.DeductionPlanStatusActive
+	case models.GoodsDeductionStatusCompleted:
+		return models.DeductionPlanStatusCompleted
+	default:
+		return models.DeductionPlanStatusRejected
+	}
+}
+
+// checkGoodsDeduction 迁移前核对原货款代扣自身数据
+func checkGoodsDeduction(gd *models.GoodsDeduction, details []*models.GoodsDeductionDetail) []string {
+	var issues []string
+	if gd.TotalAmount <= 0 {
+		issues = append(issues, fmt.Sprintf("代扣总金额无效: %d", gd.TotalAmount))
+	}
+	if gd.DeductedAmount+gd.RemainingAmount != gd.TotalAmount {
+		issues = append(issues, fmt.Sprintf("已扣%d+剩余%d≠总额%d", gd.DeductedAmount, gd.RemainingAmount, gd.TotalAmount))
+	}
+
+	var sum int64
+	for _, d := range details {
+		if d.Amount <= 0 {
+			issues = append(issues, fmt.Sprintf("明细%d金额无效: %d", d.ID, d.Amount))
+		}
+		sum += d.Amount
+	}
+	if sum != gd.DeductedAmount {
+		issues = append(issues, fmt.Sprintf("明细合计%d≠已扣金额%d", sum, gd.DeductedAmount))
+	}
+	if n := len(details); n > 0 && details[n-1].CumulativeDeducted != gd.DeductedAmount {
+		issues = append(issues, fmt.Sprintf("末笔明细累计已扣%d≠已扣金额%d", details[n-1].CumulativeDeducted, gd.DeductedAmount))
+	}
+
+	switch gd.Status {
+	case models.GoodsDeductionStatusPendingAccept, models.GoodsDeductionStatusRejected:
+		if gd.DeductedAmount > 0 {
+			issues = append(issues, fmt.Sprintf("%s状态已扣款%d", models.GetGoodsDeductionStatusName(gd.Status), gd.DeductedAmount))
+		}
+	case models.GoodsDeductionStatusCompleted:
+		if gd.RemainingAmount > 0 {
+			issues = append(issues, fmt.Sprintf("已完成但仍有剩余%d", gd.RemainingAmount))
+		}
+	case models.GoodsDeductionStatusInProgress:
+	default:
+		issues = append(issues, fmt.Sprintf("未知状态%d", gd.Status))
+	}
+	return issues
+}
+
+// checkAdoptablePlan 检查031脚本插入的同编号计划能否接管
+func checkAdoptablePlan(plan *models.DeductionPlan, gd *models.GoodsDeduction, recordCount int64) []string {
+	var issues []string
+	if plan.RelatedType != "" {
+		issues = append(issues, fmt.Sprintf("同编号计划%d已关联%s", plan.ID, plan.RelatedType))
+	}
+	if recordCount > 0 {
+		issues = append(issues, fmt.Sprintf("同编号计划%d已有%d条代扣记录", plan.ID, recordCount))
+	}
+	if plan.DeductorID != gd.FromAgentID || plan.DeducteeID != gd.ToAgentID {
+		issues = append(issues, fmt.Sprintf("同编号计划%d扣款双方不一致", plan.ID))
+	}
+	if plan.TotalAmount != gd.TotalAmount || plan.DeductedAmount != gd.DeductedAmount {
+		issues = append(issues, fmt.Sprintf("同编号计划%d金额不一致", plan.ID))
+	}
+	return issues
+}
+
+// buildMigratedPlan 由货款代扣及其明细构建代扣计划和代扣记录，walletIDs 与明细一一对应（可为nil）
+func buildMigratedPlan(gd *models.GoodsDeduction, details []*models.GoodsDeductionDetail, walletIDs []int64, now time.Time) (*models.DeductionPlan, []*models.DeductionRecord) {
+	source := gd.DeductionSource
+	if source == 0 {
+		source = models.DeductionSourceBoth
+	}
+
+	plan := &models.DeductionPlan{
+		PlanNo:          gd.DeductionNo,
+		DeductorID:      gd.FromAgentID,
+		DeducteeID:      gd.ToAgentID,
+		PlanType:        models.DeductionPlanTypeGoods,
+		TotalAmount:     gd.TotalAmount,
+		DeductedAmount:  gd.DeductedAmount,
+		RemainingAmount: gd.RemainingAmount,
+		Status:          goodsStatusToPlanStatus(gd),
+		RelatedType:     models.GoodsDeductionRelatedType,
+		RelatedID:       gd.ID,
+		Remark:          gd.Remark,
+		CreatedBy:       gd.CreatedBy,
+		CreatedAt:       gd.CreatedAt,
+		UpdatedAt:       now,
+		CompletedAt:     gd.CompletedAt,
+		NeedAccept:      true,
+		AcceptedAt:      gd.AcceptedAt,
+		DeductionSource: source,
+	}
+
+	// 待接收/已拒绝：与新建的一期货款代扣一致，接收时再生成代扣记录
+	if plan.Status == models.DeductionPlanStatusPendingAccept || plan.Status == models.DeductionPlanStatusRejected {
+		plan.TotalPeriods = 1
+		plan.PeriodAmount = gd.TotalAmount
+		return plan, nil
+	}
+
+	records := make([]*models.DeductionRecord, 0, len(details)+1)
+	for i, d := range details {
+		var walletID int64
+		if i < len(walletIDs) {
+			walletID = walletIDs[i]
+		}
+		walletDetails, _ := json.Marshal([]models.WalletDeductDetail{{
+			WalletID:      walletID,
+			WalletType:    d.WalletType,
+			WalletName:    getWalletTypeName(d.WalletType),
+			BalanceBefore: d.WalletBalanceBefore,
+			DeductAmount:  d.Amount,
+			BalanceAfter:  d.WalletBalanceAfter,
+		}})
+		deductedAt := d.CreatedAt
+		detailID := d.ID
+		records = append(records, &models.DeductionRecord{
+			PlanNo:         plan.PlanNo,
+			DeductorID:     plan.DeductorID,
+			DeducteeID:     plan.DeducteeID,
+			PeriodNum:      i + 1,
+			Amount:         d.Amount,
+			ActualAmount:   d.Amount,
+			Status:         models.DeductionRecordStatusSuccess,
+			WalletDetails:  string(walletDetails),
+			ScheduledAt:    d.CreatedAt,
+			DeductedAt:     &deductedAt,
+			CreatedAt:      d.CreatedAt,
+			LegacyDetailID: &detailID,
+		})
+	}
+	plan.CurrentPeriod = len(details)
+
+	if plan.Status == models.DeductionPlanStatusActive {
+		// 剩余金额作为一期，次日起按每日代扣执行
+		records = append(records, &models.DeductionRecord{
+			PlanNo:      plan.PlanNo,
+			DeductorID:  plan.DeductorID,
+			DeducteeID:  plan.DeducteeID,
+			PeriodNum:   len(details) + 1,
+			Amount:      gd.RemainingAmount,
+			Status:      models.DeductionRecordStatusPending,
+			ScheduledAt: firstDeductionTime(now),
+			CreatedAt:   now,
+		})
+		plan.TotalPeriods = len(details) + 1
+		plan.PeriodAmount = gd.RemainingAmount
+		return plan, records
+	}
+
+	plan.TotalPeriods = len(details)
+	if plan.TotalPeriods == 0 {
+		plan.TotalPeriods = 1
+	}
+	plan.PeriodAmount = gd.TotalAmount / int64(plan.TotalPeriods)
+	if plan.CompletedAt == nil {
+		completedAt := gd.UpdatedAt
+		plan.CompletedAt = &completedAt
+	}
+	return plan, records
+}
+
+// reconcileMigratedPlan 核对迁移后的代扣计划与原货款代扣
+// 迁移后计划会继续扣款，因此只核对迁移的历史部分与金额恒等关系
+func reconcileMigratedPlan(gd *models.GoodsDeduction, details []*models.GoodsDeductionDetail, plan *models.DeductionPlan, legacyRecords []*models.DeductionRecord) []string {
+	if plan == nil {
+		return []string{"代扣计划不存在"}
+	}
+
+	var issues []string
+	if plan.PlanNo != gd.DeductionNo {
+		issues = append(issues, fmt.Sprintf("计划编号%s≠代扣编号%s", plan.PlanNo, gd.DeductionNo))
+	}
+	if plan.DeductorID != gd.FromAgentID || plan.DeducteeID != gd.ToAgentID {
+		issues = append(issues, "扣款双方不一致")
+	}
+	if plan.TotalAmount != gd.TotalAmount {
+		issues = append(issues, fmt.Sprintf("计划总额%d≠货款代扣总额%d", plan.TotalAmount, gd.TotalAmount))
+	}
+	if plan.DeductedAmount+plan.RemainingAmount != plan.TotalAmount {
+		issues = append(issues, fmt.Sprintf("计划已扣%d+剩余%d≠总额%d", plan.DeductedAmount, plan.RemainingAmount, plan.TotalAmount))
+	}
+	if plan.DeductedAmount < gd.DeductedAmount {
+		issues = append(issues, fmt.Sprintf("计划已扣%d少于迁移前已扣%d", plan.DeductedAmount, gd.DeductedAmount))
+	}
+
+	if len(legacyRecords) != len(details) {
+		issues = append(issues, fmt.Sprintf("迁移记录%d条≠扣款明细%d条", len(legacyRecords), len(details)))
+	}
+	var sum int64
+	for _, r := range legacyRecords {
+		sum += r.ActualAmount
+	}
+	if sum != gd.DeductedAmount {
+		issues = append(issues, fmt.Sprintf("迁移记录合计%d≠迁移前已扣%d", sum, gd.DeductedAmount))
+	}
+	return issues
+}
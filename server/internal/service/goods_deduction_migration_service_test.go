@@ -0,0 +1,237 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+)
+
+func newLegacyGoodsDeduction(status int16, total, deducted int64) *models.GoodsDeduction {
+	return &models.GoodsDeduction{
+		ID:              7,
+		DeductionNo:     "GD20260301000001",
+		FromAgentID:     1,
+		ToAgentID:       2,
+		TotalAmount:     total,
+		DeductedAmount:  deducted,
+		RemainingAmount: total - deducted,
+		DeductionSource: models.GoodsDeductionSourceBoth,
+		Status:          status,
+		CreatedAt:       time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local),
+	}
+}
+
+func newLegacyDetails(amounts ...int64) []*models.GoodsDeductionDetail {
+	details := make([]*models.GoodsDeductionDetail, 0, len(amounts))
+	var cumulative int64
+	base := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	for i, amount := range amounts {
+		cumulative += amount
+		details = append(details, &models.GoodsDeductionDetail{
+			ID:                  int64(100 + i),
+			Amount:              amount,
+			WalletType:          1,
+			WalletBalanceBefore: 10000,
+			WalletBalanceAfter:  10000 - amount,
+			CumulativeDeducted:  cumulative,
+			CreatedAt:           base.AddDate(0, 0, i),
+		})
+	}
+	return details
+}
+
+func TestCheckGoodsDeduction(t *testing.T) {
+	gd := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 3000)
+	if issues := checkGoodsDeduction(gd, newLegacyDetails(1000, 2000)); len(issues) != 0 {
+		t.Errorf("一致数据不应报错: %v", issues)
+	}
+
+	if issues := checkGoodsDeduction(gd, newLegacyDetails(1000)); len(issues) != 2 {
+		t.Errorf("明细合计与末笔累计不符应报2项: %v", issues)
+	}
+
+	broken := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 3000)
+	broken.RemainingAmount = 6000
+	if issues := checkGoodsDeduction(broken, newLegacyDetails(1000, 2000)); len(issues) != 1 {
+		t.Errorf("已扣+剩余≠总额应报错: %v", issues)
+	}
+
+	pending := newLegacyGoodsDeduction(models.GoodsDeductionStatusPendingAccept, 10000, 500)
+	if issues := checkGoodsDeduction(pending, newLegacyDetails(500)); len(issues) != 1 {
+		t.Errorf("待接收状态已扣款应报错: %v", issues)
+	}
+
+	completed := newLegacyGoodsDeduction(models.GoodsDeductionStatusCompleted, 10000, 3000)
+	if issues := checkGoodsDeduction(completed, newLegacyDetails(1000, 2000)); len(issues) != 1 {
+		t.Errorf("已完成仍有剩余应报错: %v", issues)
+	}
+}
+
+func TestBuildMigratedPlanInProgress(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	gd := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 3000)
+	details := newLegacyDetails(1000, 2000)
+
+	plan, records := buildMigratedPlan(gd, details, []int64{55, 0}, now)
+	if plan.PlanNo != gd.DeductionNo || plan.RelatedType != models.GoodsDeductionRelatedType || plan.RelatedID != gd.ID {
+		t.Errorf("计划编号/关联错误: %+v", plan)
+	}
+	if plan.Status != models.DeductionPlanStatusActive || plan.DeductedAmount != 3000 || plan.RemainingAmount != 7000 {
+		t.Errorf("计划状态/金额错误: status=%d deducted=%d remaining=%d", plan.Status, plan.DeductedAmount, plan.RemainingAmount)
+	}
+	if plan.TotalPeriods != 3 || plan.CurrentPeriod != 2 || plan.PeriodAmount != 7000 {
+		t.Errorf("期数错误: total=%d current=%d period=%d", plan.TotalPeriods, plan.CurrentPeriod, plan.PeriodAmount)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+
+	first := records[0]
+	if first.Status != models.DeductionRecordStatusSuccess || first.ActualAmount != 1000 ||
+		first.LegacyDetailID == nil || *first.LegacyDetailID != 100 || !first.ScheduledAt.Equal(details[0].CreatedAt) {
+		t.Errorf("历史记录转换错误: %+v", first)
+	}
+	if first.WalletDetails != `[{"wallet_id":55,"wallet_type":1,"wallet_name":"分润钱包","balance_before":10000,"deduct_amount":1000,"balance_after":9000}]` {
+		t.Errorf("WalletDetails = %s", first.WalletDetails)
+	}
+
+	tail := records[2]
+	if tail.Status != models.DeductionRecordStatusPending || tail.Amount != 7000 || tail.PeriodNum != 3 ||
+		tail.LegacyDetailID != nil || !tail.ScheduledAt.Equal(firstDeductionTime(now)) {
+		t.Errorf("剩余期记录错误: %+v", tail)
+	}
+}
+
+func TestBuildMigratedPlanOtherStatuses(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+
+	pending := newLegacyGoodsDeduction(models.GoodsDeductionStatusPendingAccept, 10000, 0)
+	plan, records := buildMigratedPlan(pending, nil, nil, now)
+	if plan.Status != models.DeductionPlanStatusPendingAccept || !plan.NeedAccept || len(records) != 0 ||
+		plan.TotalPeriods != 1 || plan.PeriodAmount != 10000 {
+		t.Errorf("待接收转换错误: %+v records=%d", plan, len(records))
+	}
+
+	rejected := newLegacyGoodsDeduction(models.GoodsDeductionStatusRejected, 10000, 0)
+	if plan, _ := buildMigratedPlan(rejected, nil, nil, now); plan.Status != models.DeductionPlanStatusRejected {
+		t.Errorf("已拒绝转换错误: status=%d", plan.Status)
+	}
+
+	completed := newLegacyGoodsDeduction(models.GoodsDeductionStatusCompleted, 10000, 10000)
+	plan, records = buildMigratedPlan(completed, newLegacyDetails(4000, 6000), nil, now)
+	if plan.Status != models.DeductionPlanStatusCompleted || plan.TotalPeriods != 2 || len(records) != 2 || plan.CompletedAt == nil {
+		t.Errorf("已完成转换错误: %+v records=%d", plan, len(records))
+	}
+
+	// 进行中但已扣完视为已完成，不再生成待扣记录
+	finished := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 10000)
+	plan, records = buildMigratedPlan(finished, newLegacyDetails(10000), nil, now)
+	if plan.Status != models.DeductionPlanStatusCompleted || len(records) != 1 {
+		t.Errorf("已扣完的进行中转换错误: status=%d records=%d", plan.Status, len(records))
+	}
+}
+
+func TestReconcileMigratedPlan(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	gd := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 3000)
+	details := newLegacyDetails(1000, 2000)
+	plan, records := buildMigratedPlan(gd, details, nil, now)
+	legacy := records[:2]
+
+	if issues := reconcileMigratedPlan(gd, details, plan, legacy); len(issues) != 0 {
+		t.Errorf("迁移结果应一致: %v", issues)
+	}
+
+	// 迁移后继续扣款不影响核对
+	plan.DeductedAmount, plan.RemainingAmount = 5000, 5000
+	if issues := reconcileMigratedPlan(gd, details, plan, legacy); len(issues) != 0 {
+		t.Errorf("迁移后继续扣款应一致: %v", issues)
+	}
+
+	plan.RemainingAmount = 4000
+	if issues := reconcileMigratedPlan(gd, details, plan, legacy[:1]); len(issues) != 3 {
+		t.Errorf("金额恒等、记录数、记录合计应报3项: %v", issues)
+	}
+
+	if issues := reconcileMigratedPlan(gd, details, nil, nil); !reflect.DeepEqual(issues, []string{"代扣计划不存在"}) {
+		t.Errorf("计划不存在: %v", issues)
+	}
+}
+
+func TestCheckAdoptablePlan(t *testing.T) {
+	gd := newLegacyGoodsDeduction(models.GoodsDeductionStatusInProgress, 10000, 3000)
+	plan := &models.DeductionPlan{ID: 9, DeductorID: 1, DeducteeID: 2, TotalAmount: 10000, DeductedAmount: 3000}
+	if issues := checkAdoptablePlan(plan, gd, 0); len(issues) != 0 {
+		t.Errorf("031脚本计划应可接管: %v", issues)
+	}
+	if issues := checkAdoptablePlan(plan, gd, 2); len(issues) != 1 {
+		t.Errorf("已有代扣记录不可接管: %v", issues)
+	}
+	plan.RelatedType = "terminal_distribute"
+	if issues := checkAdoptablePlan(plan, gd, 0); len(issues) != 1 {
+		t.Errorf("已关联其他业务不可接管: %v", issues)
+	}
+}
+
+func TestGoodsStatusFilterToPlan(t *testing.T) {
+	if got := goodsStatusFilterToPlan(nil); got != nil {
+		t.Errorf("无筛选 = %v", got)
+	}
+	want := []int16{models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused}
+	if got := goodsStatusFilterToPlan([]int16{models.GoodsDeductionStatusInProgress}); !reflect.DeepEqual(got, want) {
+		t.Errorf("进行中 = %v", got)
+	}
+	if got := goodsStatusFilterToPlan([]int16{99}); !reflect.DeepEqual(got, []int16{-1}) {
+		t.Errorf("未知状态 = %v", got)
+	}
+	for planStatus, goodsStatus := range map[int16]int16{
+		models.DeductionPlanStatusPendingAccept: models.GoodsDeductionStatusPendingAccept,
+		models.DeductionPlanStatusPaused:        models.GoodsDeductionStatusInProgress,
+		models.DeductionPlanStatusCompleted:     models.GoodsDeductionStatusCompleted,
+		models.DeductionPlanStatusRejected:      models.GoodsDeductionStatusRejected,
+		models.DeductionPlanStatusCancelled:     models.GoodsDeductionStatusCancelled,
+	} {
+		if got := planStatusToGoodsStatus(planStatus); got != goodsStatus {
+			t.Errorf("planStatusToGoodsStatus(%d) = %d, want %d", planStatus, got, goodsStatus)
+		}
+	}
+}
+
+func TestGoodsDetailsFromRecords(t *testing.T) {
+	plan := &models.DeductionPlan{ID: 9, PlanNo: "GD1", TotalAmount: 10000}
+	at := time.Date(2026, 3, 5, 8, 0, 0, 0, time.Local)
+	records := []*models.DeductionRecord{
+		{ID: 1, ActualAmount: 1000, ScheduledAt: at, WalletDetails: `[{"wallet_id":5,"wallet_type":1,"balance_before":3000,"deduct_amount":500,"balance_after":2500},{"wallet_id":6,"wallet_type":2,"balance_before":800,"deduct_amount":500,"balance_after":300}]`},
+		{ID: 2, ActualAmount: 2500, ScheduledAt: at.AddDate(0, 0, 1), DeductedAt: &at},
+	}
+
+	details := goodsDetailsFromRecords(3, plan, records)
+	if len(details) != 2 {
+		t.Fatalf("details = %d", len(details))
+	}
+	if d := details[0]; d.DeductionID != 3 || d.CumulativeDeducted != 1000 || d.RemainingAfter != 9000 ||
+		d.WalletType != 1 || d.WalletBalanceBefore != 3000 || d.WalletBalanceAfter != 300 {
+		t.Errorf("首笔明细错误: %+v", d)
+	}
+	if d := details[1]; d.CumulativeDeducted != 3500 || d.RemainingAfter != 6500 || !d.CreatedAt.Equal(at) {
+		t.Errorf("次笔明细错误: %+v", d)
+	}
+}
+
+func TestGoodsDeductionFromPlanKeepsDeductionID(t *testing.T) {
+	distributeID := int64(7)
+	plan := &models.DeductionPlan{ID: 9, PlanNo: "DP1", TotalAmount: 10000, RemainingAmount: 10000,
+		Status: models.DeductionPlanStatusActive}
+	legacy := &models.GoodsDeduction{ID: 3, TerminalCount: 2, UnitPrice: 5000, DistributeID: &distributeID}
+
+	// /goods-deduction 兼容接口按货款代扣ID寻址，计划ID通过 migrated_plan_id 返回
+	d := goodsDeductionFromPlan(plan, legacy)
+	if d.ID != 3 || d.MigratedPlanID == nil || *d.MigratedPlanID != 9 {
+		t.Errorf("ID = %d, MigratedPlanID = %v, want 3 / 9", d.ID, d.MigratedPlanID)
+	}
+	if d.TerminalCount != 2 || d.UnitPrice != 5000 || d.Status != models.GoodsDeductionStatusInProgress {
+		t.Errorf("货款信息错误: %+v", d)
+	}
+}
@@ -1,63 +1,55 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"xiangshoufu/internal/models"
 	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
 )
 
-// GoodsDeductionService 货款代扣服务
-// 业务规则：
-//   1. 扣款规则: 分润优先 - 先扣分润钱包，扣完再扣服务费钱包
-//   2. 扣款时机: 实时扣款 - 钱包入账时立即触发扣款
-//   3. 部分扣款: 有多少扣多少 - 余额不足时部分扣除，剩余下次继续扣
-//   4. 扣款上限: 无上限 - 每次入账时全部扣除，直到扣完为止
-//   5. 扣款优先级: 货款代扣 > 上级代扣 > 伙伴代扣
-//   6. 与提现关系: 待扣金额占用钱包余额，影响可提现金额
-//   7. 接收确认: 下级拒绝接收则终端划拨失败
-//   8. 修改/取消: 设置后不可修改，只能扣完或线下协商
+// GoodsDeductionService 货款代扣服务（兼容层）
+// 货款代扣已并入统一代扣计划：
+//  1. 新建货款代扣即创建一期待接收的代扣计划，goods_deductions 仅保存终端、单价、协议等货款信息
+//  2. 接收/拒绝、冻结与扣款均由 DeductionService 处理
+//  3. /goods-deduction 接口按原响应格式读取货款类代扣计划，ID 仍为货款代扣ID（migrated_plan_id 为代扣计划ID）
+//  4. 历史货款代扣由 GoodsDeductionMigrationService 迁移为代扣计划
 type GoodsDeductionService struct {
-	deductionRepo     repository.GoodsDeductionRepository
-	detailRepo        repository.GoodsDeductionDetailRepository
-	terminalRepo      repository.GoodsDeductionTerminalRepository
-	notificationRepo  repository.GoodsDeductionNotificationRepository
-	walletRepo        repository.WalletRepository
-	walletLogRepo     repository.WalletLogRepository
-	agentRepo         repository.AgentRepository
+	deductionRepo    repository.GoodsDeductionRepository
+	terminalRepo     repository.GoodsDeductionTerminalRepository
+	notificationRepo repository.GoodsDeductionNotificationRepository
+	migrationRepo    *repository.GormGoodsDeductionMigrationRepository
+	agentRepo        repository.AgentRepository
+	deductionService *DeductionService
 }
 
 // NewGoodsDeductionService 创建货款代扣服务
 func NewGoodsDeductionService(
 	deductionRepo repository.GoodsDeductionRepository,
-	detailRepo repository.GoodsDeductionDetailRepository,
 	terminalRepo repository.GoodsDeductionTerminalRepository,
 	notificationRepo repository.GoodsDeductionNotificationRepository,
-	walletRepo repository.WalletRepository,
-	walletLogRepo repository.WalletLogRepository,
+	migrationRepo *repository.GormGoodsDeductionMigrationRepository,
 	agentRepo repository.AgentRepository,
+	deductionService *DeductionService,
 ) *GoodsDeductionService {
 	return &GoodsDeductionService{
 		deductionRepo:    deductionRepo,
-		detailRepo:       detailRepo,
 		terminalRepo:     terminalRepo,
 		notificationRepo: notificationRepo,
-		walletRepo:       walletRepo,
-		walletLogRepo:    walletLogRepo,
+		migrationRepo:    migrationRepo,
 		agentRepo:        agentRepo,
+		deductionService: deductionService,
 	}
 }
 
 // CreateGoodsDeduction 创建货款代扣（终端划拨时调用）
+// 在同一事务内创建一期待接收的代扣计划、货款信息与终端关联
 func (s *GoodsDeductionService) CreateGoodsDeduction(req *models.CreateGoodsDeductionRequest, fromAgentID int64, createdBy int64) (*models.GoodsDeduction, error) {
-	// 验证发起方代理商
-	fromAgent, err := s.agentRepo.FindByID(fromAgentID)
-	if err != nil || fromAgent == nil {
-		return nil, fmt.Errorf("发起方代理商不存在: %d", fromAgentID)
-	}
-
 	// 验证接收方代理商
 	toAgent, err := s.agentRepo.FindByID(req.ToAgentID)
 	if err != nil || toAgent == nil {
@@ -74,11 +66,6 @@ func (s *GoodsDeductionService) CreateGoodsDeduction(req *models.CreateGoodsDedu
 		return nil, fmt.Errorf("终端列表不能为空")
 	}
 
-	// 验证扣款来源
-	if req.DeductionSource < 1 || req.DeductionSource > 3 {
-		return nil, fmt.Errorf("无效的扣款来源")
-	}
-
 	// 计算总金额
 	terminalCount := len(req.Terminals)
 	totalAmount := req.UnitPrice * int64(terminalCount)
@@ -86,12 +73,23 @@ func (s *GoodsDeductionService) CreateGoodsDeduction(req *models.CreateGoodsDedu
 		return nil, fmt.Errorf("代扣总金额必须大于0")
 	}
 
-	// 生成代扣编号
-	deductionNo := fmt.Sprintf("GD%s%06d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000000)
+	// 一期待接收的统一代扣计划（校验发起方与扣款来源）
+	plan, err := s.deductionService.newPlanWithAccept(&models.CreateDeductionPlanWithAcceptRequest{
+		DeductorID:      fromAgentID,
+		DeducteeID:      req.ToAgentID,
+		PlanType:        models.DeductionPlanTypeGoods,
+		TotalAmount:     totalAmount,
+		TotalPeriods:    1,
+		DeductionSource: req.DeductionSource,
+		Remark:          req.Remark,
+	}, createdBy)
+	if err != nil {
+		return nil, err
+	}
 
-	// 创建货款代扣
+	now := time.Now()
 	deduction := &models.GoodsDeduction{
-		DeductionNo:     deductionNo,
+		DeductionNo:     plan.PlanNo,
 		FromAgentID:     fromAgentID,
 		ToAgentID:       req.ToAgentID,
 		TotalAmount:     totalAmount,
@@ -106,33 +104,56 @@ func (s *GoodsDeductionService) CreateGoodsDeduction(req *models.CreateGoodsDedu
 		DistributeID:    req.DistributeID,
 		Remark:          req.Remark,
 		CreatedBy:       createdBy,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		MigratedAt:      &now,
 	}
 
-	if err := s.deductionRepo.Create(deduction); err != nil {
-		return nil, fmt.Errorf("创建货款代扣失败: %w", err)
-	}
-
-	// 创建终端关联
 	terminals := make([]*models.GoodsDeductionTerminal, 0, len(req.Terminals))
 	for _, t := range req.Terminals {
 		unitPrice := req.UnitPrice
 		if t.UnitPrice > 0 {
 			unitPrice = t.UnitPrice
 		}
-		terminal := &models.GoodsDeductionTerminal{
-			DeductionID: deduction.ID,
-			TerminalID:  t.TerminalID,
-			TerminalSN:  t.TerminalSN,
-			UnitPrice:   unitPrice,
-			CreatedAt:   time.Now(),
-		}
-		terminals = append(terminals, terminal)
+		terminals = append(terminals, &models.GoodsDeductionTerminal{
+			TerminalID: t.TerminalID,
+			TerminalSN: t.TerminalSN,
+			UnitPrice:  unitPrice,
+			CreatedAt:  now,
+		})
 	}
 
-	if err := s.terminalRepo.BatchCreate(terminals); err != nil {
-		return nil, fmt.Errorf("创建终端关联失败: %w", err)
+	err = s.migrationRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(plan).Error; err != nil {
+			return fmt.Errorf("创建代扣计划失败: %w", err)
+		}
+
+		// 保存货款信息
+		deduction.MigratedPlanID = &plan.ID
+		if err := tx.Create(deduction).Error; err != nil {
+			return fmt.Errorf("保存货款信息失败: %w", err)
+		}
+
+		plan.RelatedType = models.GoodsDeductionRelatedType
+		plan.RelatedID = deduction.ID
+		if err := tx.Model(&models.DeductionPlan{}).Where("id = ?", plan.ID).Updates(map[string]interface{}{
+			"related_type": plan.RelatedType,
+			"related_id":   plan.RelatedID,
+		}).Error; err != nil {
+			return fmt.Errorf("关联代扣计划失败: %w", err)
+		}
+
+		// 创建终端关联
+		for _, t := range terminals {
+			t.DeductionID = deduction.ID
+		}
+		if err := tx.Create(&terminals).Error; err != nil {
+			return fmt.Errorf("创建终端关联失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// 发送待接收通知给接收方
@@ -143,87 +164,49 @@ func (s *GoodsDeductionService) CreateGoodsDeduction(req *models.CreateGoodsDedu
 		Title:       "货款代扣待接收",
 		Content:     fmt.Sprintf("您有一笔货款代扣待接收，金额：%.2f元，终端数量：%d台", float64(totalAmount)/100, terminalCount),
 		IsRead:      false,
-		CreatedAt:   time.Now(),
+		CreatedAt:   now,
 	}
 	s.notificationRepo.Create(notification)
 
-	log.Printf("[GoodsDeductionService] Created goods deduction: %s, from: %d, to: %d, amount: %d, terminals: %d",
-		deductionNo, fromAgentID, req.ToAgentID, totalAmount, terminalCount)
+	log.Printf("[GoodsDeductionService] Created goods deduction %d with plan: %s, from: %d, to: %d, amount: %d, terminals: %d",
+		deduction.ID, plan.PlanNo, fromAgentID, req.ToAgentID, totalAmount, terminalCount)
 
-	return deduction, nil
+	result := goodsDeductionFromPlan(plan, deduction)
+	result.Terminals = terminals
+	return result, nil
 }
 
 // AcceptGoodsDeduction 接收货款代扣
 func (s *GoodsDeductionService) AcceptGoodsDeduction(deductionID int64, agentID int64) error {
-	deduction, err := s.deductionRepo.FindByID(deductionID)
-	if err != nil || deduction == nil {
-		return fmt.Errorf("货款代扣不存在: %d", deductionID)
-	}
-
-	// 验证是否为接收方
-	if deduction.ToAgentID != agentID {
-		return fmt.Errorf("无权操作此货款代扣")
-	}
-
-	// 验证状态
-	if deduction.Status != models.GoodsDeductionStatusPendingAccept {
-		return fmt.Errorf("货款代扣状态不允许接收")
-	}
-
-	// 更新状态为进行中
-	if err := s.deductionRepo.UpdateStatus(deductionID, models.GoodsDeductionStatusInProgress); err != nil {
-		return fmt.Errorf("更新状态失败: %w", err)
+	_, plan, err := s.getGoodsPlan(deductionID)
+	if err != nil {
+		return err
 	}
-
-	log.Printf("[GoodsDeductionService] Accepted goods deduction: %d, agent: %d", deductionID, agentID)
-
-	return nil
+	return s.deductionService.AcceptDeductionPlan(plan.ID, agentID)
 }
 
 // RejectGoodsDeduction 拒绝货款代扣
 func (s *GoodsDeductionService) RejectGoodsDeduction(deductionID int64, agentID int64) error {
-	deduction, err := s.deductionRepo.FindByID(deductionID)
-	if err != nil || deduction == nil {
-		return fmt.Errorf("货款代扣不存在: %d", deductionID)
-	}
-
-	// 验证是否为接收方
-	if deduction.ToAgentID != agentID {
-		return fmt.Errorf("无权操作此货款代扣")
-	}
-
-	// 验证状态
-	if deduction.Status != models.GoodsDeductionStatusPendingAccept {
-		return fmt.Errorf("货款代扣状态不允许拒绝")
-	}
-
-	// 更新状态为已拒绝
-	if err := s.deductionRepo.UpdateStatus(deductionID, models.GoodsDeductionStatusRejected); err != nil {
-		return fmt.Errorf("更新状态失败: %w", err)
+	_, plan, err := s.getGoodsPlan(deductionID)
+	if err != nil {
+		return err
 	}
-
-	log.Printf("[GoodsDeductionService] Rejected goods deduction: %d, agent: %d", deductionID, agentID)
-
-	return nil
+	return s.deductionService.RejectDeductionPlan(plan.ID, agentID)
 }
 
 // GetGoodsDeductionByID 获取货款代扣详情
 func (s *GoodsDeductionService) GetGoodsDeductionByID(deductionID int64) (*models.GoodsDeduction, error) {
-	deduction, err := s.deductionRepo.FindByID(deductionID)
+	legacy, plan, err := s.getGoodsPlan(deductionID)
 	if err != nil {
-		return nil, fmt.Errorf("查询货款代扣失败: %w", err)
-	}
-
-	if deduction == nil {
-		return nil, fmt.Errorf("货款代扣不存在")
+		return nil, err
 	}
 
-	// 填充代理商名称
+	deduction := goodsDeductionFromPlan(plan, legacy)
 	s.fillAgentNames(deduction)
 
 	// 获取终端列表
-	terminals, err := s.terminalRepo.FindByDeductionID(deductionID)
-	if err == nil {
+	deduction.Terminals = []*models.GoodsDeductionTerminal{}
+	if terminals, err := s.terminalRepo.FindByDeductionID(legacy.ID); err == nil {
 		deduction.Terminals = terminals
 	}
 
@@ -232,312 +215,124 @@ func (s *GoodsDeductionService) GetGoodsDeductionByID(deductionID int64) (*model
 
 // GetSentList 获取我发起的货款代扣列表
 func (s *GoodsDeductionService) GetSentList(agentID int64, status []int16, page, pageSize int) ([]*models.GoodsDeductionListResponse, int64, error) {
-	offset := (page - 1) * pageSize
-	deductions, total, err := s.deductionRepo.FindByFromAgent(agentID, status, pageSize, offset)
-	if err != nil {
-		return nil, 0, fmt.Errorf("查询失败: %w", err)
-	}
-
-	// 转换为响应格式
-	result := make([]*models.GoodsDeductionListResponse, 0, len(deductions))
-	for _, d := range deductions {
-		s.fillAgentNames(d)
-		result = append(result, d.ToListResponse())
-	}
-
-	return result, total, nil
+	return s.listPlans(agentID, 0, status, page, pageSize)
 }
 
 // GetReceivedList 获取我接收的货款代扣列表
 func (s *GoodsDeductionService) GetReceivedList(agentID int64, status []int16, page, pageSize int) ([]*models.GoodsDeductionListResponse, int64, error) {
+	return s.listPlans(0, agentID, status, page, pageSize)
+}
+
+func (s *GoodsDeductionService) listPlans(deductorID, deducteeID int64, status []int16, page, pageSize int) ([]*models.GoodsDeductionListResponse, int64, error) {
 	offset := (page - 1) * pageSize
-	deductions, total, err := s.deductionRepo.FindByToAgent(agentID, status, pageSize, offset)
+	plans, total, err := s.migrationRepo.FindGoodsPlans(deductorID, deducteeID, goodsStatusFilterToPlan(status), pageSize, offset)
 	if err != nil {
 		return nil, 0, fmt.Errorf("查询失败: %w", err)
 	}
 
-	// 转换为响应格式
-	result := make([]*models.GoodsDeductionListResponse, 0, len(deductions))
-	for _, d := range deductions {
-		s.fillAgentNames(d)
-		result = append(result, d.ToListResponse())
+	legacyByPlan, err := s.findLegacy(plans)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询货款信息失败: %w", err)
+	}
+	result := make([]*models.GoodsDeductionListResponse, 0, len(plans))
+	for _, plan := range plans {
+		legacy, ok := legacyByPlan[plan.ID]
+		if !ok {
+			continue
+		}
+		deduction := goodsDeductionFromPlan(plan, legacy)
+		s.fillAgentNames(deduction)
+		result = append(result, deduction.ToListResponse())
 	}
 
 	return result, total, nil
 }
 
-// GetDeductionDetails 获取扣款明细列表
+// GetDeductionDetails 获取扣款明细列表（由代扣计划的实扣记录生成，按时间倒序）
 func (s *GoodsDeductionService) GetDeductionDetails(deductionID int64, page, pageSize int) ([]*models.GoodsDeductionDetail, int64, error) {
-	offset := (page - 1) * pageSize
-	details, total, err := s.detailRepo.FindByDeductionID(deductionID, pageSize, offset)
+	legacy, plan, err := s.getGoodsPlan(deductionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records, err := s.migrationRepo.FindDeductedRecords(plan.ID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("查询扣款明细失败: %w", err)
 	}
 
-	// 填充钱包类型名称
-	for _, d := range details {
-		d.WalletTypeName = models.WalletTypeName(d.WalletType)
+	details := goodsDetailsFromRecords(legacy.ID, plan, records)
+	for i, j := 0, len(details)-1; i < j; i, j = i+1, j-1 {
+		details[i], details[j] = details[j], details[i]
 	}
 
-	return details, total, nil
+	total := int64(len(details))
+	start := (page - 1) * pageSize
+	if start >= len(details) {
+		return []*models.GoodsDeductionDetail{}, total, nil
+	}
+	end := start + pageSize
+	if end > len(details) {
+		end = len(details)
+	}
+	return details[start:end], total, nil
 }
 
 // GetSummary 获取货款代扣统计
 func (s *GoodsDeductionService) GetSummary(agentID int64, isSent bool) (*models.GoodsDeductionSummary, error) {
 	if isSent {
-		return s.deductionRepo.GetSummaryByFromAgent(agentID)
+		return s.migrationRepo.GetGoodsPlanSummary(agentID, 0)
 	}
-	return s.deductionRepo.GetSummaryByToAgent(agentID)
+	return s.migrationRepo.GetGoodsPlanSummary(0, agentID)
 }
 
-// TriggerRealtimeDeduction 触发实时扣款（钱包入账时调用）
-// 业务规则：
-//   1. 扣款优先级：货款代扣 > 上级代扣 > 伙伴代扣
-//   2. 钱包优先级：分润钱包 > 服务费钱包
-//   3. 每次入账时全部扣除，直到扣完为止
-func (s *GoodsDeductionService) TriggerRealtimeDeduction(
-	agentID int64,
-	channelID int64,
-	walletType int16,
-	incomeAmount int64,
-	triggerType string,
-	triggerTransactionID *int64,
-	triggerProfitID *int64,
-) (int64, error) {
-	// 获取该代理商所有进行中的货款代扣
-	deductions, err := s.deductionRepo.FindActiveByToAgent(agentID)
+// GetPendingDeductionAmount 获取代理商待扣货款金额（影响可提现金额）
+func (s *GoodsDeductionService) GetPendingDeductionAmount(agentID int64) (int64, error) {
+	summary, err := s.migrationRepo.GetGoodsPlanSummary(0, agentID)
 	if err != nil {
-		return 0, fmt.Errorf("查询货款代扣失败: %w", err)
-	}
-
-	if len(deductions) == 0 {
-		return 0, nil // 无待扣款
-	}
-
-	// 获取钱包余额
-	wallet, err := s.walletRepo.FindByAgentAndType(agentID, channelID, walletType)
-	if err != nil || wallet == nil {
-		return 0, nil // 钱包不存在
-	}
-
-	// 可扣金额 = 入账金额（刚入账的钱可以全部用于扣款）
-	availableAmount := incomeAmount
-	if availableAmount <= 0 {
-		return 0, nil
-	}
-
-	var totalDeducted int64
-
-	// 遍历所有进行中的货款代扣
-	for _, deduction := range deductions {
-		if availableAmount <= 0 {
-			break
-		}
-
-		// 检查扣款来源是否匹配
-		if !s.isWalletTypeAllowed(deduction.DeductionSource, walletType) {
-			continue
-		}
-
-		// 计算本次可扣金额
-		deductAmount := deduction.RemainingAmount
-		if deductAmount > availableAmount {
-			deductAmount = availableAmount
-		}
-
-		if deductAmount <= 0 {
-			continue
-		}
-
-		// 执行扣款
-		deducted, err := s.executeDeduction(deduction, wallet, deductAmount, triggerType, triggerTransactionID, triggerProfitID)
-		if err != nil {
-			log.Printf("[GoodsDeductionService] Deduction failed for %d: %v", deduction.ID, err)
-			continue
-		}
-
-		totalDeducted += deducted
-		availableAmount -= deducted
-	}
-
-	return totalDeducted, nil
-}
-
-// isWalletTypeAllowed 检查钱包类型是否允许扣款
-func (s *GoodsDeductionService) isWalletTypeAllowed(deductionSource int16, walletType int16) bool {
-	switch deductionSource {
-	case models.GoodsDeductionSourceProfit:
-		return walletType == models.WalletTypeProfit
-	case models.GoodsDeductionSourceServiceFee:
-		return walletType == models.WalletTypeServiceFee
-	case models.GoodsDeductionSourceBoth:
-		return walletType == models.WalletTypeProfit || walletType == models.WalletTypeServiceFee
-	default:
-		return false
+		return 0, err
 	}
+	return summary.RemainingAmount, nil
 }
 
-// executeDeduction 执行单笔扣款
-func (s *GoodsDeductionService) executeDeduction(
-	deduction *models.GoodsDeduction,
-	wallet *repository.Wallet,
-	amount int64,
-	triggerType string,
-	triggerTransactionID *int64,
-	triggerProfitID *int64,
-) (int64, error) {
-	// 检查钱包余额
-	if wallet.Balance < amount {
-		amount = wallet.Balance
-	}
-
-	if amount <= 0 {
-		return 0, nil
-	}
-
-	// 扣减钱包余额
-	if err := s.walletRepo.UpdateBalance(wallet.ID, -amount); err != nil {
-		return 0, fmt.Errorf("扣减钱包余额失败: %w", err)
-	}
-
-	// 更新货款代扣已扣金额
-	if err := s.deductionRepo.UpdateDeductedAmount(deduction.ID, amount); err != nil {
-		// 回滚钱包余额
-		s.walletRepo.UpdateBalance(wallet.ID, amount)
-		return 0, fmt.Errorf("更新已扣金额失败: %w", err)
-	}
-
-	// 计算新的累计已扣和剩余待扣
-	newDeducted := deduction.DeductedAmount + amount
-	newRemaining := deduction.RemainingAmount - amount
-
-	// 创建扣款明细
-	detail := &models.GoodsDeductionDetail{
-		DeductionID:          deduction.ID,
-		DeductionNo:          deduction.DeductionNo,
-		Amount:               amount,
-		WalletType:           wallet.WalletType,
-		ChannelID:            &wallet.ChannelID,
-		WalletBalanceBefore:  wallet.Balance,
-		WalletBalanceAfter:   wallet.Balance - amount,
-		CumulativeDeducted:   newDeducted,
-		RemainingAfter:       newRemaining,
-		TriggerType:          triggerType,
-		TriggerTransactionID: triggerTransactionID,
-		TriggerProfitID:      triggerProfitID,
-		CreatedAt:            time.Now(),
+// getGoodsPlan 按货款代扣ID获取货款信息及其并入的代扣计划
+func (s *GoodsDeductionService) getGoodsPlan(deductionID int64) (*models.GoodsDeduction, *models.DeductionPlan, error) {
+	deduction, err := s.deductionRepo.FindByID(deductionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("货款代扣不存在")
 	}
-
-	if err := s.detailRepo.Create(detail); err != nil {
-		log.Printf("[GoodsDeductionService] Create detail failed: %v", err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询货款代扣失败: %w", err)
 	}
-
-	// 记录钱包流水
-	walletLog := &repository.WalletLog{
-		WalletID:      wallet.ID,
-		AgentID:       wallet.AgentID,
-		WalletType:    wallet.WalletType,
-		LogType:       7, // 货款代扣
-		Amount:        -amount,
-		BalanceBefore: wallet.Balance,
-		BalanceAfter:  wallet.Balance - amount,
-		RefType:       "goods_deduction_detail",
-		RefID:         detail.ID,
-		Remark:        fmt.Sprintf("货款代扣 - %s", deduction.DeductionNo),
-		CreatedAt:     time.Now(),
+	if deduction.MigratedPlanID == nil {
+		return nil, nil, fmt.Errorf("货款代扣尚未并入代扣计划，请稍后重试")
 	}
-	s.walletLogRepo.Create(walletLog)
 
-	// 将扣款金额转入发起方钱包
-	if err := s.transferToFromAgent(deduction.FromAgentID, wallet.ChannelID, amount, detail.ID); err != nil {
-		log.Printf("[GoodsDeductionService] Transfer to from agent failed: %v", err)
+	plan, err := s.migrationRepo.FindPlanByID(*deduction.MigratedPlanID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询货款代扣失败: %w", err)
 	}
-
-	// 发送扣款通知
-	s.sendDeductionNotification(deduction, detail, amount)
-
-	// 检查是否已完成
-	if newRemaining <= 0 {
-		s.deductionRepo.UpdateStatus(deduction.ID, models.GoodsDeductionStatusCompleted)
-		s.sendCompletionNotification(deduction)
+	if plan == nil || plan.PlanType != models.DeductionPlanTypeGoods {
+		return nil, nil, fmt.Errorf("货款代扣不存在")
 	}
-
-	log.Printf("[GoodsDeductionService] Deduction executed: deduction=%d, amount=%d, remaining=%d",
-		deduction.ID, amount, newRemaining)
-
-	return amount, nil
-}
-
-// transferToFromAgent 将扣款金额转入发起方钱包
-func (s *GoodsDeductionService) transferToFromAgent(agentID int64, channelID int64, amount int64, detailID int64) error {
-	// 转入发起方的分润钱包
-	wallet, err := s.walletRepo.FindByAgentAndType(agentID, channelID, models.WalletTypeProfit)
-	if err != nil || wallet == nil {
-		return fmt.Errorf("发起方钱包不存在")
-	}
-
-	// 增加余额
-	if err := s.walletRepo.UpdateBalance(wallet.ID, amount); err != nil {
-		return fmt.Errorf("增加发起方余额失败: %w", err)
-	}
-
-	// 记录钱包流水
-	walletLog := &repository.WalletLog{
-		WalletID:      wallet.ID,
-		AgentID:       agentID,
-		WalletType:    wallet.WalletType,
-		LogType:       7, // 货款代扣
-		Amount:        amount,
-		BalanceBefore: wallet.Balance,
-		BalanceAfter:  wallet.Balance + amount,
-		RefType:       "goods_deduction_detail",
-		RefID:         detailID,
-		Remark:        "货款代扣收款",
-		CreatedAt:     time.Now(),
-	}
-	return s.walletLogRepo.Create(walletLog)
+	return deduction, plan, nil
 }
 
-// sendDeductionNotification 发送扣款通知
-func (s *GoodsDeductionService) sendDeductionNotification(deduction *models.GoodsDeduction, detail *models.GoodsDeductionDetail, amount int64) {
-	notification := &models.GoodsDeductionNotification{
-		DeductionID: deduction.ID,
-		DetailID:    &detail.ID,
-		AgentID:     deduction.ToAgentID,
-		NotifyType:  models.GoodsDeductionNotifyTypeDeduction,
-		Title:       "货款代扣扣款通知",
-		Content:     fmt.Sprintf("您的货款代扣已扣款%.2f元，累计已扣%.2f元，剩余%.2f元", float64(amount)/100, float64(detail.CumulativeDeducted)/100, float64(detail.RemainingAfter)/100),
-		IsRead:      false,
-		CreatedAt:   time.Now(),
+// findLegacy 批量获取计划对应的货款信息
+func (s *GoodsDeductionService) findLegacy(plans []*models.DeductionPlan) (map[int64]*models.GoodsDeduction, error) {
+	planIDs := make([]int64, 0, len(plans))
+	for _, plan := range plans {
+		planIDs = append(planIDs, plan.ID)
 	}
-	s.notificationRepo.Create(notification)
-}
 
-// sendCompletionNotification 发送完成通知
-func (s *GoodsDeductionService) sendCompletionNotification(deduction *models.GoodsDeduction) {
-	// 通知接收方
-	notification := &models.GoodsDeductionNotification{
-		DeductionID: deduction.ID,
-		AgentID:     deduction.ToAgentID,
-		NotifyType:  models.GoodsDeductionNotifyTypeCompleted,
-		Title:       "货款代扣已完成",
-		Content:     fmt.Sprintf("您的货款代扣已全部扣完，总金额%.2f元", float64(deduction.TotalAmount)/100),
-		IsRead:      false,
-		CreatedAt:   time.Now(),
+	deductions, err := s.migrationRepo.FindByPlanIDs(planIDs)
+	if err != nil {
+		return nil, err
 	}
-	s.notificationRepo.Create(notification)
-
-	// 通知发起方
-	notificationFrom := &models.GoodsDeductionNotification{
-		DeductionID: deduction.ID,
-		AgentID:     deduction.FromAgentID,
-		NotifyType:  models.GoodsDeductionNotifyTypeCompleted,
-		Title:       "货款代扣已完成",
-		Content:     fmt.Sprintf("您发起的货款代扣已全部扣完，总金额%.2f元", float64(deduction.TotalAmount)/100),
-		IsRead:      false,
-		CreatedAt:   time.Now(),
+	result := make(map[int64]*models.GoodsDeduction, len(deductions))
+	for _, d := range deductions {
+		result[*d.MigratedPlanID] = d
 	}
-	s.notificationRepo.Create(notificationFrom)
+	return result, nil
 }
 
 // fillAgentNames 填充代理商名称
@@ -550,15 +345,6 @@ func (s *GoodsDeductionService) fillAgentNames(deduction *models.GoodsDeduction)
 	}
 }
 
-// GetPendingDeductionAmount 获取代理商待扣货款金额（影响可提现金额）
-func (s *GoodsDeductionService) GetPendingDeductionAmount(agentID int64) (int64, error) {
-	summary, err := s.deductionRepo.GetSummaryByToAgent(agentID)
-	if err != nil {
-		return 0, err
-	}
-	return summary.RemainingAmount, nil
-}
-
 // GetNotifications 获取通知列表
 func (s *GoodsDeductionService) GetNotifications(agentID int64, isRead *bool, page, pageSize int) ([]*models.GoodsDeductionNotification, int64, error) {
 	offset := (page - 1) * pageSize
@@ -579,3 +365,110 @@ func (s *GoodsDeductionService) MarkNotificationAsRead(notificationID int64) err
 func (s *GoodsDeductionService) MarkAllNotificationsAsRead(agentID int64) error {
 	return s.notificationRepo.MarkAllAsRead(agentID)
 }
+
+// planStatusToGoodsStatus 代扣计划状态转换为货款代扣状态
+func planStatusToGoodsStatus(status int16) int16 {
+	switch status {
+	case models.DeductionPlanStatusPendingAccept:
+		return models.GoodsDeductionStatusPendingAccept
+	case models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused:
+		return models.GoodsDeductionStatusInProgress
+	case models.DeductionPlanStatusCompleted:
+		return models.GoodsDeductionStatusCompleted
+	case models.DeductionPlanStatusRejected:
+		return models.GoodsDeductionStatusRejected
+	default:
+		return models.GoodsDeductionStatusCancelled
+	}
+}
+
+// goodsStatusFilterToPlan 货款代扣状态筛选转换为代扣计划状态
+func goodsStatusFilterToPlan(status []int16) []int16 {
+	if len(status) == 0 {
+		return nil
+	}
+	result := make([]int16, 0, len(status)+1)
+	for _, st := range status {
+		switch st {
+		case models.GoodsDeductionStatusPendingAccept:
+			result = append(result, models.DeductionPlanStatusPendingAccept)
+		case models.GoodsDeductionStatusInProgress:
+			result = append(result, models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused)
+		case models.GoodsDeductionStatusCompleted:
+			result = append(result, models.DeductionPlanStatusCompleted)
+		case models.GoodsDeductionStatusRejected:
+			result = append(result, models.DeductionPlanStatusRejected)
+		case models.GoodsDeductionStatusCancelled:
+			result = append(result, models.DeductionPlanStatusCancelled)
+		}
+	}
+	if len(result) == 0 {
+		return []int16{-1} // 未知状态不匹配任何计划
+	}
+	return result
+}
+
+// goodsDeductionFromPlan 将货款类代扣计划转换为货款代扣响应，ID 取货款信息的货款代扣ID
+func goodsDeductionFromPlan(plan *models.DeductionPlan, legacy *models.GoodsDeduction) *models.GoodsDeduction {
+	deduction := &models.GoodsDeduction{
+		ID:              legacy.ID,
+		DeductionNo:     plan.PlanNo,
+		FromAgentID:     plan.DeductorID,
+		ToAgentID:       plan.DeducteeID,
+		TotalAmount:     plan.TotalAmount,
+		DeductedAmount:  plan.DeductedAmount,
+		RemainingAmount: plan.RemainingAmount,
+		DeductionSource: plan.DeductionSource,
+		Status:          planStatusToGoodsStatus(plan.Status),
+		Remark:          plan.Remark,
+		CreatedBy:       plan.CreatedBy,
+		AcceptedAt:      plan.AcceptedAt,
+		CompletedAt:     plan.CompletedAt,
+		CreatedAt:       plan.CreatedAt,
+		UpdatedAt:       plan.UpdatedAt,
+		MigratedPlanID:  &plan.ID,
+	}
+
+	deduction.TerminalCount = legacy.TerminalCount
+	deduction.UnitPrice = legacy.UnitPrice
+	deduction.AgreementSigned = legacy.AgreementSigned
+	deduction.AgreementURL = legacy.AgreementURL
+	deduction.DistributeID = legacy.DistributeID
+	deduction.MigratedAt = legacy.MigratedAt
+	return deduction
+}
+
+// goodsDetailsFromRecords 将代扣计划的实扣记录转换为货款代扣明细（按时间正序，累计已扣逐笔递增）
+func goodsDetailsFromRecords(deductionID int64, plan *models.DeductionPlan, records []*models.DeductionRecord) []*models.GoodsDeductionDetail {
+	details := make([]*models.GoodsDeductionDetail, 0, len(records))
+	var cumulative int64
+	for _, record := range records {
+		cumulative += record.ActualAmount
+		createdAt := record.ScheduledAt
+		if record.DeductedAt != nil {
+			createdAt = *record.DeductedAt
+		}
+
+		detail := &models.GoodsDeductionDetail{
+			ID:                 record.ID,
+			DeductionID:        deductionID,
+			DeductionNo:        plan.PlanNo,
+			Amount:             record.ActualAmount,
+			CumulativeDeducted: cumulative,
+			RemainingAfter:     plan.TotalAmount - cumulative,
+			TriggerType:        models.GoodsDeductionTriggerTypePlan,
+			CreatedAt:          createdAt,
+		}
+
+		var walletDetails []models.WalletDeductDetail
+		if record.WalletDetails != "" && json.Unmarshal([]byte(record.WalletDetails), &walletDetails) == nil && len(walletDetails) > 0 {
+			first, last := walletDetails[0], walletDetails[len(walletDetails)-1]
+			detail.WalletType = first.WalletType
+			detail.WalletTypeName = models.WalletTypeName(first.WalletType)
+			detail.WalletBalanceBefore = first.BalanceBefore
+			detail.WalletBalanceAfter = last.BalanceAfter
+		}
+		details = append(details, detail)
+	}
+	return details
+}
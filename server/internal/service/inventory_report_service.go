@@ -0,0 +1,569 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// inventoryHQAlertLimit 总部告警中列出的代理商数量上限
+const inventoryHQAlertLimit = 20
+
+// InventoryReportService 终端库存报表服务
+// 基于 terminals 与 terminal_distributes/terminal_recalls 统计各代理商持有库存、
+// 未绑定库存账龄（自入库或下发到达当前持有者起算）、已绑定未激活台数及下级动销率，
+// 每日扫描呆滞库存并按配置通知代理商、上级及总部告警通道
+type InventoryReportService struct {
+	reportRepo     *repository.GormInventoryReportRepository
+	agentRepo      repository.AgentRepository
+	hierarchyRepo  repository.HierarchyRepository
+	messageService *MessageService
+	alertService   *AlertService
+}
+
+// NewInventoryReportService 创建终端库存报表服务
+func NewInventoryReportService(
+	reportRepo *repository.GormInventoryReportRepository,
+	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+) *InventoryReportService {
+	return &InventoryReportService{
+		reportRepo:    reportRepo,
+		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（呆滞库存通知代理商）
+func (s *InventoryReportService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// SetAlertService 设置告警服务（呆滞库存推送总部）
+func (s *InventoryReportService) SetAlertService(alertService *AlertService) {
+	s.alertService = alertService
+}
+
+// InventoryQuery 库存报表查询条件
+type InventoryQuery struct {
+	OperatorAgentID int64  // 当前代理商
+	IsAdmin         bool   // 是否管理员
+	AgentID         int64  // 查询的代理商，0表示管理员查全部/代理商查本人
+	Scope           string // direct:仅本人持有 team:本人及全部下级（默认）
+	ChannelID       int64
+	BrandCode       string
+	ModelCode       string
+}
+
+// resolveFilter 校验查询权限并生成筛选条件：管理员可查全部，代理商只能查本人及下级
+func (s *InventoryReportService) resolveFilter(q *InventoryQuery) (*repository.InventoryFilter, error) {
+	filter := &repository.InventoryFilter{
+		AgentID:   q.AgentID,
+		Scope:     q.Scope,
+		ChannelID: q.ChannelID,
+		BrandCode: strings.TrimSpace(q.BrandCode),
+		ModelCode: strings.TrimSpace(q.ModelCode),
+	}
+	if filter.Scope != models.StatScopeDirect {
+		filter.Scope = models.StatScopeTeam
+	}
+	if !q.IsAdmin && filter.AgentID == 0 {
+		filter.AgentID = q.OperatorAgentID
+	}
+	if filter.AgentID == 0 {
+		return filter, nil
+	}
+
+	agent, err := s.agentRepo.FindByID(filter.AgentID)
+	if err != nil || agent == nil {
+		return nil, errors.New("代理商不存在")
+	}
+	if !q.IsAdmin && agent.ID != q.OperatorAgentID {
+		ok, err := s.hierarchyRepo.IsDescendant(q.OperatorAgentID, agent.ID)
+		if err != nil {
+			return nil, fmt.Errorf("查询代理商层级失败: %w", err)
+		}
+		if !ok {
+			return nil, errors.New("无权查看该代理商库存")
+		}
+	}
+	return filter, nil
+}
+
+// InventoryStockItem 库存汇总行
+type InventoryStockItem struct {
+	*repository.InventoryStockRow
+	SellThroughRate float64 `json:"sell_through_rate"` // 动销率(%)：已绑定+已激活 / 持有总数
+}
+
+// InventoryStockReport 库存汇总报表
+type InventoryStockReport struct {
+	Totals *InventoryStockItem   `json:"totals"` // 合计
+	List   []*InventoryStockItem `json:"list"`
+	Total  int64                 `json:"total"`
+}
+
+// GetStockReport 按代理商/通道/品牌/型号汇总库存与账龄，pageSize<=0时返回全部
+func (s *InventoryReportService) GetStockReport(q *InventoryQuery, page, pageSize int, now time.Time) (*InventoryStockReport, error) {
+	filter, err := s.resolveFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.reportRepo.GetStockSummary(filter, inventoryAgingCutoffs(now))
+	if err != nil {
+		return nil, fmt.Errorf("查询库存汇总失败: %w", err)
+	}
+
+	totals := &repository.InventoryStockRow{}
+	items := make([]*InventoryStockItem, 0, len(rows))
+	for _, row := range rows {
+		addInventoryStockRow(totals, row)
+		items = append(items, newInventoryStockItem(row))
+	}
+
+	report := &InventoryStockReport{
+		Totals: newInventoryStockItem(totals),
+		List:   items,
+		Total:  int64(len(items)),
+	}
+	if pageSize > 0 {
+		start := min((page-1)*pageSize, len(items))
+		end := min(start+pageSize, len(items))
+		report.List = items[start:end]
+	}
+	return report, nil
+}
+
+// InventorySubordinateItem 直属下级库存与动销
+type InventorySubordinateItem struct {
+	*repository.InventorySubordinateRow
+	SellThroughRate float64 `json:"sell_through_rate"` // 动销率(%)：已绑定+已激活 / 持有总数
+	ActivationRate  float64 `json:"activation_rate"`   // 激活率(%)：已激活 / 已绑定+已激活
+}
+
+// GetSubordinateReport 统计直属下级（含其团队）的库存、呆滞库存与动销率
+// 管理员未指定代理商时统计顶级代理商
+func (s *InventoryReportService) GetSubordinateReport(q *InventoryQuery, now time.Time) ([]*InventorySubordinateItem, error) {
+	filter, err := s.resolveFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.GetAlertConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.reportRepo.GetSubordinateStats(filter.AgentID, filter.ChannelID, inventoryStaleBefore(now, config.StaleDays))
+	if err != nil {
+		return nil, fmt.Errorf("查询下级库存失败: %w", err)
+	}
+
+	items := make([]*InventorySubordinateItem, 0, len(rows))
+	for _, row := range rows {
+		sold := row.BoundInactiveCount + row.ActivatedCount
+		items = append(items, &InventorySubordinateItem{
+			InventorySubordinateRow: row,
+			SellThroughRate:         inventoryRate(sold, row.TotalCount),
+			ActivationRate:          inventoryRate(row.ActivatedCount, sold),
+		})
+	}
+	return items, nil
+}
+
+// InventoryStaleItem 呆滞终端明细
+type InventoryStaleItem struct {
+	*repository.InventoryStaleTerminal
+	StatusName string `json:"status_name"`
+	Days       int    `json:"days"`        // 库龄/绑定未激活天数
+	AgingLabel string `json:"aging_label"` // 账龄分段
+}
+
+// ListStaleTerminals 分页查询呆滞终端
+// kind=stock 为库龄达到 minDays 的未绑定库存，kind=bound_inactive 为绑定后 minDays 天仍未激活的终端；
+// minDays<=0 时使用预警配置的呆滞天数，pageSize<=0 时返回全部
+func (s *InventoryReportService) ListStaleTerminals(q *InventoryQuery, kind string, minDays, page, pageSize int, now time.Time) ([]*InventoryStaleItem, int64, error) {
+	if kind == "" {
+		kind = models.InventoryStaleKindStock
+	}
+	if kind != models.InventoryStaleKindStock && kind != models.InventoryStaleKindBoundInactive {
+		return nil, 0, errors.New("无效的呆滞类型")
+	}
+	filter, err := s.resolveFilter(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	if minDays <= 0 {
+		config, err := s.GetAlertConfig()
+		if err != nil {
+			return nil, 0, err
+		}
+		minDays = config.StaleDays
+	}
+
+	limit, offset := 0, 0
+	if pageSize > 0 {
+		limit, offset = pageSize, (page-1)*pageSize
+	}
+	list, total, err := s.reportRepo.ListStaleTerminals(filter, kind, inventoryStaleBefore(now, minDays), limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询呆滞终端失败: %w", err)
+	}
+
+	items := make([]*InventoryStaleItem, 0, len(list))
+	for _, t := range list {
+		days := inventoryAgeDays(t.SinceAt, now)
+		items = append(items, &InventoryStaleItem{
+			InventoryStaleTerminal: t,
+			StatusName:             getTerminalLifecycleStatusName(t.Status),
+			Days:                   days,
+			AgingLabel:             inventoryAgingLabel(days),
+		})
+	}
+	return items, total, nil
+}
+
+// ExportStockReport 导出库存汇总（CSV）
+func (s *InventoryReportService) ExportStockReport(q *InventoryQuery, now time.Time) ([]byte, error) {
+	report, err := s.GetStockReport(q, 0, 0, now)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{{"代理商ID", "代理商", "通道", "品牌", "型号", "持有总数", "未绑定库存",
+		"库龄0-30天", "库龄30-60天", "库龄60-90天", "库龄90天以上", "已绑定未激活", "已激活", "动销率(%)"}}
+	for _, item := range append(report.List, report.Totals) {
+		agentID, agentName := strconv.FormatInt(item.AgentID, 10), item.AgentName
+		if item == report.Totals {
+			agentID, agentName = "合计", ""
+		}
+		records = append(records, []string{
+			agentID, agentName, item.ChannelName, item.BrandCode, item.ModelCode,
+			strconv.FormatInt(item.TotalCount, 10), strconv.FormatInt(item.StockCount, 10),
+			strconv.FormatInt(item.Age0To30, 10), strconv.FormatInt(item.Age30To60, 10),
+			strconv.FormatInt(item.Age60To90, 10), strconv.FormatInt(item.Age90Plus, 10),
+			strconv.FormatInt(item.BoundInactiveCount, 10), strconv.FormatInt(item.ActivatedCount, 10),
+			strconv.FormatFloat(item.SellThroughRate, 'f', 2, 64),
+		})
+	}
+	return writeInventoryCSV(records)
+}
+
+// ExportSubordinateReport 导出下级动销报表（CSV）
+func (s *InventoryReportService) ExportSubordinateReport(q *InventoryQuery, now time.Time) ([]byte, error) {
+	items, err := s.GetSubordinateReport(q, now)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{{"代理商ID", "代理商", "团队持有总数", "未绑定库存", "呆滞库存", "已绑定未激活", "已激活", "动销率(%)", "激活率(%)"}}
+	for _, item := range items {
+		records = append(records, []string{
+			strconv.FormatInt(item.AgentID, 10), item.AgentName,
+			strconv.FormatInt(item.TotalCount, 10), strconv.FormatInt(item.StockCount, 10),
+			strconv.FormatInt(item.StaleCount, 10), strconv.FormatInt(item.BoundInactiveCount, 10),
+			strconv.FormatInt(item.ActivatedCount, 10),
+			strconv.FormatFloat(item.SellThroughRate, 'f', 2, 64),
+			strconv.FormatFloat(item.ActivationRate, 'f', 2, 64),
+		})
+	}
+	return writeInventoryCSV(records)
+}
+
+// ExportStaleTerminals 导出呆滞终端明细（CSV）
+func (s *InventoryReportService) ExportStaleTerminals(q *InventoryQuery, kind string, minDays int, now time.Time) ([]byte, error) {
+	items, _, err := s.ListStaleTerminals(q, kind, minDays, 0, 0, now)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{{"终端SN", "代理商ID", "代理商", "通道", "品牌", "型号", "状态", "商户号", "起算时间", "天数", "账龄分段"}}
+	for _, item := range items {
+		records = append(records, []string{
+			item.TerminalSN, strconv.FormatInt(item.AgentID, 10), item.AgentName,
+			item.ChannelCode, item.BrandCode, item.ModelCode, item.StatusName, item.MerchantNo,
+			item.SinceAt.Format("2006-01-02 15:04:05"), strconv.Itoa(item.Days), item.AgingLabel,
+		})
+	}
+	return writeInventoryCSV(records)
+}
+
+// writeInventoryCSV 生成带BOM的CSV（便于Excel直接打开）
+func writeInventoryCSV(records [][]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("生成导出文件失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultInventoryAlertConfig 默认预警配置
+func defaultInventoryAlertConfig() *models.InventoryAlertConfig {
+	return &models.InventoryAlertConfig{
+		Enabled:             true,
+		StaleDays:           90,
+		StaleCountThreshold: 20,
+		RemindIntervalDays:  7,
+		NotifyParent:        true,
+	}
+}
+
+// GetAlertConfig 获取呆滞库存预警配置
+func (s *InventoryReportService) GetAlertConfig() (*models.InventoryAlertConfig, error) {
+	config, err := s.reportRepo.GetAlertConfig()
+	if err != nil {
+		return nil, fmt.Errorf("查询库存预警配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultInventoryAlertConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateInventoryAlertConfigRequest 更新呆滞库存预警配置请求
+type UpdateInventoryAlertConfigRequest struct {
+	Enabled             bool `json:"enabled"`
+	StaleDays           int  `json:"stale_days"`
+	StaleCountThreshold int  `json:"stale_count_threshold"`
+	RemindIntervalDays  int  `json:"remind_interval_days"`
+	NotifyParent        bool `json:"notify_parent"`
+	AlertHQ             bool `json:"alert_hq"`
+}
+
+// UpdateAlertConfig 更新呆滞库存预警配置
+func (s *InventoryReportService) UpdateAlertConfig(req *UpdateInventoryAlertConfigRequest, operatorID int64, operatorName string) (*models.InventoryAlertConfig, error) {
+	if req.StaleDays <= 0 || req.StaleDays > 3650 {
+		return nil, errors.New("呆滞天数需在1-3650之间")
+	}
+	if req.StaleCountThreshold <= 0 {
+		return nil, errors.New("预警台数阈值必须大于0")
+	}
+	if req.RemindIntervalDays <= 0 {
+		req.RemindIntervalDays = 1
+	}
+
+	config, err := s.reportRepo.GetAlertConfig()
+	if err != nil {
+		return nil, fmt.Errorf("查询库存预警配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.InventoryAlertConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.StaleDays = req.StaleDays
+	config.StaleCountThreshold = req.StaleCountThreshold
+	config.RemindIntervalDays = req.RemindIntervalDays
+	config.NotifyParent = req.NotifyParent
+	config.AlertHQ = req.AlertHQ
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+	if err := s.reportRepo.SaveAlertConfig(config); err != nil {
+		return nil, fmt.Errorf("保存库存预警配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// InventoryAlertRunResult 呆滞库存扫描结果
+type InventoryAlertRunResult struct {
+	OverThreshold int `json:"over_threshold"` // 超过阈值的代理商数
+	Alerted       int `json:"alerted"`        // 本次预警数
+	Skipped       int `json:"skipped"`        // 提醒间隔内跳过数
+	Failed        int `json:"failed"`         // 失败数
+}
+
+// CheckStaleStock 扫描呆滞库存并预警（定时任务每日调用）
+// 同一代理商在提醒间隔内只预警一次；通知本人，可选通知直属上级，并汇总推送总部告警通道
+func (s *InventoryReportService) CheckStaleStock(now time.Time) (*InventoryAlertRunResult, error) {
+	config, err := s.GetAlertConfig()
+	if err != nil {
+		return nil, err
+	}
+	result := &InventoryAlertRunResult{}
+	if !config.Enabled {
+		return result, nil
+	}
+
+	stales, err := s.reportRepo.FindAgentsOverStaleThreshold(inventoryStaleBefore(now, config.StaleDays), config.StaleCountThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("统计呆滞库存失败: %w", err)
+	}
+	result.OverThreshold = len(stales)
+
+	today := startOfDay(now)
+	var hqLines []string
+	for _, stale := range stales {
+		last, err := s.reportRepo.FindLastAlert(stale.AgentID)
+		if err != nil {
+			log.Printf("[InventoryReportService] Find last alert of agent %d failed: %v", stale.AgentID, err)
+			result.Failed++
+			continue
+		}
+		if last != nil && !inventoryAlertDue(last.AlertDate, today, config.RemindIntervalDays) {
+			result.Skipped++
+			continue
+		}
+
+		created, err := s.reportRepo.CreateAlert(&models.InventoryAlert{
+			AgentID:    stale.AgentID,
+			AlertDate:  today,
+			StaleDays:  config.StaleDays,
+			StaleCount: int(stale.StaleCount),
+			StockCount: int(stale.StockCount),
+			Threshold:  config.StaleCountThreshold,
+			CreatedAt:  now,
+		})
+		if err != nil {
+			log.Printf("[InventoryReportService] Create alert of agent %d failed: %v", stale.AgentID, err)
+			result.Failed++
+			continue
+		}
+		if !created {
+			result.Skipped++
+			continue
+		}
+		result.Alerted++
+
+		agentName := ""
+		agent, _ := s.agentRepo.FindByID(stale.AgentID)
+		if agent != nil {
+			agentName = agent.AgentName
+		}
+		s.sendMessage(stale.AgentID, "呆滞库存提醒",
+			fmt.Sprintf("您有%d台终端入库或下发超过%d天仍未绑定（当前库存%d台），请及时下发或回拨。",
+				stale.StaleCount, config.StaleDays, stale.StockCount))
+		if config.NotifyParent && agent != nil && agent.ParentID > 0 {
+			s.sendMessage(agent.ParentID, "下级呆滞库存提醒",
+				fmt.Sprintf("下级代理商%s有%d台终端超过%d天未绑定（库存%d台），请关注动销情况。",
+					agentName, stale.StaleCount, config.StaleDays, stale.StockCount))
+		}
+		if len(hqLines) < inventoryHQAlertLimit {
+			hqLines = append(hqLines, fmt.Sprintf("- 代理商: %s(%d), 呆滞: %d台, 库存: %d台",
+				agentName, stale.AgentID, stale.StaleCount, stale.StockCount))
+		}
+	}
+
+	if config.AlertHQ && s.alertService != nil && result.Alerted > 0 {
+		message := fmt.Sprintf("共%d个代理商呆滞库存（超过%d天未绑定）达到%d台:\n%s",
+			result.Alerted, config.StaleDays, config.StaleCountThreshold, strings.Join(hqLines, "\n"))
+		if result.Alerted > len(hqLines) {
+			message += fmt.Sprintf("\n... 其余%d个代理商请在库存报表中查看", result.Alerted-len(hqLines))
+		}
+		if err := s.alertService.SendAlert(&models.AlertRequest{
+			JobName:   "InventoryAlertJob",
+			AlertType: models.AlertTypeInventoryStale,
+			Title:     "【呆滞库存预警】代理商呆滞库存超过阈值",
+			Message:   message,
+		}); err != nil {
+			log.Printf("[InventoryReportService] Send HQ alert failed: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// ListAlerts 分页查询呆滞库存预警记录：管理员查看全部，代理商查看本人及下级
+func (s *InventoryReportService) ListAlerts(agentID int64, isAdmin bool, page, pageSize int) ([]*models.InventoryAlert, int64, error) {
+	var scopeAgentID int64
+	if !isAdmin {
+		agent, err := s.agentRepo.FindByID(agentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		scopeAgentID = agent.ID
+	}
+	return s.reportRepo.ListAlerts(scopeAgentID, pageSize, (page-1)*pageSize)
+}
+
+// sendMessage 发送库存预警消息
+func (s *InventoryReportService) sendMessage(agentID int64, title, content string) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeInventoryAlert,
+		Title:       title,
+		Content:     content,
+		RelatedType: "inventory_alert",
+	}); err != nil {
+		log.Printf("[InventoryReportService] Send message to agent %d failed: %v", agentID, err)
+	}
+}
+
+// inventoryAgingCutoffs 各账龄分段的起点时间，到达时间晚于 cutoffs[0] 为0-30天，依此类推
+func inventoryAgingCutoffs(now time.Time) [3]time.Time {
+	var cutoffs [3]time.Time
+	for i, days := range models.InventoryAgingBounds {
+		cutoffs[i] = inventoryStaleBefore(now, days)
+	}
+	return cutoffs
+}
+
+// inventoryStaleBefore 库龄达到 days 天的到达时间上限
+func inventoryStaleBefore(now time.Time, days int) time.Time {
+	return now.Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+// inventoryAgeDays 库龄（满24小时计1天，与账龄分段口径一致）
+func inventoryAgeDays(since, now time.Time) int {
+	if !now.After(since) {
+		return 0
+	}
+	return int(now.Sub(since).Hours() / 24)
+}
+
+// inventoryAgingLabel 账龄分段名称
+func inventoryAgingLabel(days int) string {
+	lower := 0
+	for _, bound := range models.InventoryAgingBounds {
+		if days < bound {
+			return fmt.Sprintf("%d-%d天", lower, bound)
+		}
+		lower = bound
+	}
+	return fmt.Sprintf("%d天以上", lower)
+}
+
+// inventoryRate 百分比，保留两位小数
+func inventoryRate(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
+
+// inventoryAlertDue 距上次预警是否已满提醒间隔
+// alert_date 为DATE类型，读出时的时区与 today 可能不同，按日期部分比较
+func inventoryAlertDue(lastAlertDate, today time.Time, intervalDays int) bool {
+	last := time.Date(lastAlertDate.Year(), lastAlertDate.Month(), lastAlertDate.Day(), 0, 0, 0, 0, today.Location())
+	return daysBetween(last, today) >= max(intervalDays, 1)
+}
+
+func newInventoryStockItem(row *repository.InventoryStockRow) *InventoryStockItem {
+	return &InventoryStockItem{
+		InventoryStockRow: row,
+		SellThroughRate:   inventoryRate(row.BoundInactiveCount+row.ActivatedCount, row.TotalCount),
+	}
+}
+
+// addInventoryStockRow 累加到合计行
+func addInventoryStockRow(totals, row *repository.InventoryStockRow) {
+	totals.TotalCount += row.TotalCount
+	totals.StockCount += row.StockCount
+	totals.BoundInactiveCount += row.BoundInactiveCount
+	totals.ActivatedCount += row.ActivatedCount
+	totals.Age0To30 += row.Age0To30
+	totals.Age30To60 += row.Age30To60
+	totals.Age60To90 += row.Age60To90
+	totals.Age90Plus += row.Age90Plus
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/repository"
+)
+
+func TestInventoryAgingLabel(t *testing.T) {
+	cases := map[int]string{
+		0:   "0-30天",
+		29:  "0-30天",
+		30:  "30-60天",
+		59:  "30-60天",
+		60:  "60-90天",
+		89:  "60-90天",
+		90:  "90天以上",
+		400: "90天以上",
+	}
+	for days, want := range cases {
+		if got := inventoryAgingLabel(days); got != want {
+			t.Errorf("inventoryAgingLabel(%d) = %s, want %s", days, got, want)
+		}
+	}
+}
+
+func TestInventoryAgingCutoffsMatchAgeDays(t *testing.T) {
+	now := time.Date(2026, 3, 31, 10, 0, 0, 0, time.Local)
+	cutoffs := inventoryAgingCutoffs(now)
+
+	// 恰好在分段起点上的终端计入更老的分段，与 inventoryAgeDays 的口径一致
+	for i, bound := range []int{30, 60, 90} {
+		if got := inventoryAgeDays(cutoffs[i], now); got != bound {
+			t.Errorf("cutoffs[%d] 库龄 = %d, want %d", i, got, bound)
+		}
+		if got := inventoryAgeDays(cutoffs[i].Add(time.Minute), now); got != bound-1 {
+			t.Errorf("cutoffs[%d]+1分钟 库龄 = %d, want %d", i, got, bound-1)
+		}
+	}
+
+	if got := inventoryAgeDays(now.Add(time.Hour), now); got != 0 {
+		t.Errorf("未来时间库龄 = %d, want 0", got)
+	}
+}
+
+func TestInventoryRate(t *testing.T) {
+	if got := inventoryRate(2, 3); got != 66.67 {
+		t.Errorf("inventoryRate(2, 3) = %v", got)
+	}
+	if got := inventoryRate(5, 0); got != 0 {
+		t.Errorf("总数为0时应返回0: %v", got)
+	}
+
+	item := newInventoryStockItem(&repository.InventoryStockRow{
+		TotalCount: 8, StockCount: 4, BoundInactiveCount: 1, ActivatedCount: 3,
+	})
+	if item.SellThroughRate != 50 {
+		t.Errorf("动销率 = %v, want 50", item.SellThroughRate)
+	}
+}
+
+func TestAddInventoryStockRow(t *testing.T) {
+	totals := &repository.InventoryStockRow{}
+	addInventoryStockRow(totals, &repository.InventoryStockRow{TotalCount: 5, StockCount: 3, Age0To30: 1, Age90Plus: 2, ActivatedCount: 2})
+	addInventoryStockRow(totals, &repository.InventoryStockRow{TotalCount: 4, StockCount: 2, Age30To60: 1, Age60To90: 1, BoundInactiveCount: 2})
+
+	if totals.TotalCount != 9 || totals.StockCount != 5 || totals.BoundInactiveCount != 2 || totals.ActivatedCount != 2 {
+		t.Errorf("合计数量错误: %+v", totals)
+	}
+	if totals.Age0To30+totals.Age30To60+totals.Age60To90+totals.Age90Plus != totals.StockCount {
+		t.Errorf("账龄合计应等于未绑定库存: %+v", totals)
+	}
+}
+
+func TestInventoryAlertDue(t *testing.T) {
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.Local)
+	last := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	if !inventoryAlertDue(last, today, 7) {
+		t.Error("满7天应再次预警")
+	}
+	if inventoryAlertDue(last, today, 8) {
+		t.Error("未满8天不应预警")
+	}
+	if inventoryAlertDue(today, today, 0) {
+		t.Error("同一天不应重复预警")
+	}
+}
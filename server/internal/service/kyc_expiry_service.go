@@ -0,0 +1,397 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+const (
+	kycScanBatchSize      = 500
+	kycMaxWindowDays      = 365
+	kycMaxWindows         = 5
+	kycNotifyMerchantsMax = 10 // 通知内容最多列出的商户数
+)
+
+// idCardLongTermValues 通道表示长期有效的取值
+var idCardLongTermValues = []string{"长期", "长期有效", "永久", "9999-12-31", "99991231", "9999/12/31"}
+
+// idCardDateLayouts 通道证件日期格式
+var idCardDateLayouts = []string{"2006-01-02", "20060102", "2006/01/02", "2006.01.02"}
+
+// KycExpiryService 商户证件到期服务
+// 法人身份证有效期由商户入网回调写入，每日按提醒窗口通知所属代理商，并提供到期看板
+type KycExpiryService struct {
+	kycRepo        *repository.GormKycExpiryRepository
+	agentRepo      *repository.GormAgentRepository
+	messageService *MessageService
+}
+
+// NewKycExpiryService 创建商户证件到期服务
+func NewKycExpiryService(kycRepo *repository.GormKycExpiryRepository, agentRepo *repository.GormAgentRepository) *KycExpiryService {
+	return &KycExpiryService{
+		kycRepo:   kycRepo,
+		agentRepo: agentRepo,
+	}
+}
+
+// SetMessageService 设置消息服务
+func (s *KycExpiryService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// ============================================================
+// 配置
+// ============================================================
+
+// defaultKycExpiryConfig 默认配置：到期前60/30/7天提醒，过期再提醒一次
+func defaultKycExpiryConfig() *models.KycExpiryConfig {
+	return &models.KycExpiryConfig{
+		Enabled:       true,
+		WindowDays:    models.Int64List{60, 30, 7},
+		NotifyExpired: true,
+	}
+}
+
+// GetConfig 获取提醒配置
+func (s *KycExpiryService) GetConfig() (*models.KycExpiryConfig, error) {
+	config, err := s.kycRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取证件到期提醒配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultKycExpiryConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateKycExpiryConfigRequest 更新提醒配置请求
+type UpdateKycExpiryConfigRequest struct {
+	Enabled       bool    `json:"enabled"`
+	WindowDays    []int64 `json:"window_days"` // 到期前提醒窗口（天），如[60,30,7]
+	NotifyExpired bool    `json:"notify_expired"`
+}
+
+// UpdateConfig 更新提醒配置
+func (s *KycExpiryService) UpdateConfig(req *UpdateKycExpiryConfigRequest, operatorID int64, operatorName string) (*models.KycExpiryConfig, error) {
+	windows, err := normalizeKycWindows(req.WindowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := s.kycRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取证件到期提醒配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.KycExpiryConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.WindowDays = windows
+	config.NotifyExpired = req.NotifyExpired
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+	if err := s.kycRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存证件到期提醒配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// normalizeKycWindows 校验提醒窗口并去重降序排列
+func normalizeKycWindows(windows []int64) (models.Int64List, error) {
+	if len(windows) == 0 {
+		return nil, errors.New("请至少设置一个提醒窗口")
+	}
+	seen := make(map[int64]bool, len(windows))
+	result := make(models.Int64List, 0, len(windows))
+	for _, days := range windows {
+		if days <= 0 || days > kycMaxWindowDays {
+			return nil, fmt.Errorf("提醒窗口范围为1-%d天", kycMaxWindowDays)
+		}
+		if !seen[days] {
+			seen[days] = true
+			result = append(result, days)
+		}
+	}
+	if len(result) > kycMaxWindows {
+		return nil, fmt.Errorf("提醒窗口最多%d个", kycMaxWindows)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] > result[j] })
+	return result, nil
+}
+
+// ============================================================
+// 证件有效期解析
+// ============================================================
+
+// parseIDCardValidity 解析通道回调的身份证有效期，返回开始、结束日期和是否长期有效
+// 结束日期无法解析且非长期时ok为false，不更新商户数据
+func parseIDCardValidity(startStr, endStr string) (start, end *time.Time, longTerm, ok bool) {
+	start = parseIDCardDate(startStr)
+	endStr = strings.TrimSpace(endStr)
+	for _, value := range idCardLongTermValues {
+		if endStr == value {
+			return start, nil, true, true
+		}
+	}
+	end = parseIDCardDate(endStr)
+	if end == nil {
+		return nil, nil, false, false
+	}
+	return start, end, false, true
+}
+
+// parseIDCardDate 解析证件日期，无法解析时返回nil
+func parseIDCardDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for _, layout := range idCardDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// 到期提醒
+// ============================================================
+
+// KycExpiryScanResult 到期扫描结果
+type KycExpiryScanResult struct {
+	Scanned  int `json:"scanned"`
+	Reminded int `json:"reminded"`
+	Agents   int `json:"agents"`
+}
+
+// kycReminderItem 待通知的商户
+type kycReminderItem struct {
+	merchantNo   string
+	merchantName string
+	daysLeft     int
+}
+
+// Scan 扫描证件即将到期和已过期的商户，按命中的最小提醒窗口通知所属代理商（同一到期日每个窗口只通知一次）
+func (s *KycExpiryService) Scan(now time.Time) (*KycExpiryScanResult, error) {
+	result := &KycExpiryScanResult{}
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled || len(config.WindowDays) == 0 {
+		return result, nil
+	}
+
+	today := startOfDay(now)
+	maxWindow := config.WindowDays[0]
+	for _, days := range config.WindowDays {
+		maxWindow = max(maxWindow, days)
+	}
+	until := today.AddDate(0, 0, int(maxWindow))
+
+	// 代理商 → 窗口 → 商户
+	pending := make(map[int64]map[int][]kycReminderItem)
+	var afterID int64
+	for {
+		merchants, err := s.kycRepo.FindExpiringMerchants(until, afterID, kycScanBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("查询证件到期商户失败: %w", err)
+		}
+		if len(merchants) == 0 {
+			break
+		}
+		afterID = merchants[len(merchants)-1].ID
+
+		for _, merchant := range merchants {
+			result.Scanned++
+			daysLeft := daysBetween(today, merchant.IDCardEndDate)
+			window, ok := kycReminderWindow(daysLeft, config.WindowDays, config.NotifyExpired)
+			if !ok {
+				continue
+			}
+
+			created, err := s.kycRepo.CreateReminder(&models.KycExpiryReminder{
+				MerchantID: merchant.ID,
+				AgentID:    merchant.AgentID,
+				EndDate:    merchant.IDCardEndDate,
+				WindowDays: window,
+				DaysLeft:   daysLeft,
+				CreatedAt:  now,
+			})
+			if err != nil {
+				log.Printf("[KycExpiryService] Create reminder for merchant %d failed: %v", merchant.ID, err)
+				continue
+			}
+			if !created {
+				continue
+			}
+
+			result.Reminded++
+			if pending[merchant.AgentID] == nil {
+				pending[merchant.AgentID] = make(map[int][]kycReminderItem)
+			}
+			pending[merchant.AgentID][window] = append(pending[merchant.AgentID][window], kycReminderItem{
+				merchantNo:   merchant.MerchantNo,
+				merchantName: merchant.MerchantName,
+				daysLeft:     daysLeft,
+			})
+		}
+	}
+
+	for agentID, windows := range pending {
+		result.Agents++
+		for window, items := range windows {
+			s.notify(agentID, window, items)
+		}
+	}
+	return result, nil
+}
+
+// kycReminderWindow 命中的提醒窗口：已过期返回0（开启过期提醒时），否则返回包含剩余天数的最小窗口
+func kycReminderWindow(daysLeft int, windows models.Int64List, notifyExpired bool) (int, bool) {
+	if daysLeft < 0 {
+		return 0, notifyExpired
+	}
+	window := 0
+	for _, days := range windows {
+		if int64(daysLeft) <= days && (window == 0 || int(days) < window) {
+			window = int(days)
+		}
+	}
+	return window, window > 0
+}
+
+// notify 按窗口汇总通知代理商
+func (s *KycExpiryService) notify(agentID int64, window int, items []kycReminderItem) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+
+	names := make([]string, 0, min(len(items), kycNotifyMerchantsMax))
+	for i, item := range items {
+		if i >= kycNotifyMerchantsMax {
+			break
+		}
+		name := item.merchantName
+		if name == "" {
+			name = item.merchantNo
+		}
+		if window == 0 {
+			names = append(names, fmt.Sprintf("%s（已过期%d天）", name, -item.daysLeft))
+		} else {
+			names = append(names, fmt.Sprintf("%s（剩余%d天）", name, item.daysLeft))
+		}
+	}
+	list := strings.Join(names, "、")
+	if len(items) > kycNotifyMerchantsMax {
+		list += "等"
+	}
+
+	title := fmt.Sprintf("商户证件将在%d天内到期", window)
+	content := fmt.Sprintf("您有%d个商户的法人身份证将在%d天内到期：%s。证件过期后通道会冻结商户，请提醒商户及时更新证件。", len(items), window, list)
+	if window == 0 {
+		title = "商户证件已过期"
+		content = fmt.Sprintf("您有%d个商户的法人身份证已过期：%s。通道可能已冻结商户交易，请尽快协助商户更新证件。", len(items), list)
+	}
+
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeKycExpiry,
+		Title:       title,
+		Content:     content,
+		RelatedType: "kyc_expiry",
+	}); err != nil {
+		log.Printf("[KycExpiryService] Send notification to agent %d failed: %v", agentID, err)
+	}
+}
+
+// ============================================================
+// 到期看板
+// ============================================================
+
+// KycExpiryMerchantItem 证件到期商户明细
+type KycExpiryMerchantItem struct {
+	*repository.KycExpiryMerchant
+	DaysLeft *int   `json:"days_left"` // 剩余天数，负数为已过期天数，长期或未获取时为空
+	Bucket   string `json:"bucket"`
+}
+
+// GetSummary 证件到期分档统计，代理商仅统计本人及下级商户
+func (s *KycExpiryService) GetSummary(filter *repository.KycExpiryFilter, agentID int64, isAdmin bool, now time.Time) (*repository.KycExpirySummary, error) {
+	if err := s.scopeFilter(filter, agentID, isAdmin); err != nil {
+		return nil, err
+	}
+	summary, err := s.kycRepo.GetSummary(filter, startOfDay(now))
+	if err != nil {
+		return nil, fmt.Errorf("查询证件到期统计失败: %w", err)
+	}
+	return summary, nil
+}
+
+// ListMerchants 按分档查询商户，代理商仅查看本人及下级商户
+func (s *KycExpiryService) ListMerchants(filter *repository.KycExpiryFilter, agentID int64, isAdmin bool, now time.Time, page, pageSize int) ([]*KycExpiryMerchantItem, int64, error) {
+	if err := s.scopeFilter(filter, agentID, isAdmin); err != nil {
+		return nil, 0, err
+	}
+
+	today := startOfDay(now)
+	list, total, err := s.kycRepo.ListMerchants(filter, today, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询证件到期商户失败: %w", err)
+	}
+
+	items := make([]*KycExpiryMerchantItem, 0, len(list))
+	for _, merchant := range list {
+		item := &KycExpiryMerchantItem{KycExpiryMerchant: merchant}
+		switch {
+		case merchant.IDCardLongTerm:
+			item.Bucket = models.KycBucketLongTerm
+		case merchant.IDCardEndDate == nil:
+			item.Bucket = models.KycBucketMissing
+		default:
+			daysLeft := daysBetween(today, *merchant.IDCardEndDate)
+			item.DaysLeft = &daysLeft
+			item.Bucket = kycBucket(daysLeft)
+		}
+		items = append(items, item)
+	}
+	return items, total, nil
+}
+
+// kycBucket 按剩余天数分档
+func kycBucket(daysLeft int) string {
+	switch {
+	case daysLeft < 0:
+		return models.KycBucketExpired
+	case daysLeft <= 7:
+		return models.KycBucketWithin7
+	case daysLeft <= 30:
+		return models.KycBucketWithin30
+	case daysLeft <= 60:
+		return models.KycBucketWithin60
+	default:
+		return models.KycBucketValid
+	}
+}
+
+// scopeFilter 非管理员限定为本人及下级商户
+func (s *KycExpiryService) scopeFilter(filter *repository.KycExpiryFilter, agentID int64, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+	agent, err := s.agentRepo.FindByIDFull(agentID)
+	if err != nil || agent == nil {
+		return errors.New("代理商不存在")
+	}
+	filter.ScopeAgentID = agent.ID
+	return nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+)
+
+func TestParseIDCardValidity(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name         string
+		start, end   string
+		wantStart    *time.Time
+		wantEnd      *time.Time
+		wantLongTerm bool
+		wantOK       bool
+	}{
+		{"横线格式", "2016-03-01", "2036-03-01", ptrTime(date(2016, 3, 1)), ptrTime(date(2036, 3, 1)), false, true},
+		{"紧凑格式", "20160301", "20260301", ptrTime(date(2016, 3, 1)), ptrTime(date(2026, 3, 1)), false, true},
+		{"长期", "20160301", "长期", ptrTime(date(2016, 3, 1)), nil, true, true},
+		{"9999长期", "", "99991231", nil, nil, true, true},
+		{"开始日期无效", "abc", "2030/01/02", nil, ptrTime(date(2030, 1, 2)), false, true},
+		{"结束日期为空", "20160301", "", nil, nil, false, false},
+		{"结束日期无效", "20160301", "2030-13-01", nil, nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, longTerm, ok := parseIDCardValidity(tt.start, tt.end)
+			if ok != tt.wantOK || longTerm != tt.wantLongTerm {
+				t.Fatalf("parseIDCardValidity(%q, %q) longTerm=%v ok=%v, want %v %v", tt.start, tt.end, longTerm, ok, tt.wantLongTerm, tt.wantOK)
+			}
+			if !equalTimePtr(start, tt.wantStart) || !equalTimePtr(end, tt.wantEnd) {
+				t.Errorf("parseIDCardValidity(%q, %q) = (%v, %v), want (%v, %v)", tt.start, tt.end, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestKycReminderWindow(t *testing.T) {
+	windows := models.Int64List{60, 30, 7}
+
+	tests := []struct {
+		daysLeft      int
+		notifyExpired bool
+		want          int
+		wantOK        bool
+	}{
+		{61, true, 0, false},
+		{60, true, 60, true},
+		{45, true, 60, true},
+		{30, true, 30, true},
+		{8, true, 30, true},
+		{7, true, 7, true},
+		{0, true, 7, true},
+		{-1, true, 0, true},
+		{-1, false, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := kycReminderWindow(tt.daysLeft, windows, tt.notifyExpired)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("kycReminderWindow(%d, %v) = (%d, %v), want (%d, %v)", tt.daysLeft, tt.notifyExpired, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalizeKycWindows(t *testing.T) {
+	got, err := normalizeKycWindows([]int64{7, 60, 30, 7})
+	if err != nil {
+		t.Fatalf("normalizeKycWindows error: %v", err)
+	}
+	want := models.Int64List{60, 30, 7}
+	if len(got) != len(want) {
+		t.Fatalf("normalizeKycWindows = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("normalizeKycWindows = %v, want %v", got, want)
+		}
+	}
+
+	for _, invalid := range [][]int64{nil, {0}, {400}, {1, 2, 3, 4, 5, 6}} {
+		if _, err := normalizeKycWindows(invalid); err == nil {
+			t.Errorf("normalizeKycWindows(%v) expected error", invalid)
+		}
+	}
+}
+
+func TestKycBucket(t *testing.T) {
+	tests := map[int]string{
+		-3: models.KycBucketExpired,
+		0:  models.KycBucketWithin7,
+		7:  models.KycBucketWithin7,
+		8:  models.KycBucketWithin30,
+		30: models.KycBucketWithin30,
+		60: models.KycBucketWithin60,
+		61: models.KycBucketValid,
+	}
+	for daysLeft, want := range tests {
+		if got := kycBucket(daysLeft); got != want {
+			t.Errorf("kycBucket(%d) = %s, want %s", daysLeft, got, want)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,676 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// 商户分类处理上限
+const (
+	merchantClassBatchSize       = 500
+	merchantClassPreviewMax      = 50000
+	merchantClassMaxRules        = 20
+	merchantClassMaxConditions   = 10
+	merchantClassPreviewMaxTrans = 50
+)
+
+// merchantClassCodePattern 分类编码：小写字母开头，最多20位
+var merchantClassCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+// MerchantClassMetricDef 分类指标说明
+type MerchantClassMetricDef struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+}
+
+// merchantClassMetricDefs 可用的分类指标
+var merchantClassMetricDefs = []MerchantClassMetricDef{
+	{models.MerchantMetricAmount30d, "30天交易额", "分"},
+	{models.MerchantMetricAmount60d, "60天交易额", "分"},
+	{models.MerchantMetricAmount90d, "90天交易额", "分"},
+	{models.MerchantMetricCount30d, "30天交易笔数", "笔"},
+	{models.MerchantMetricCount60d, "60天交易笔数", "笔"},
+	{models.MerchantMetricCount90d, "90天交易笔数", "笔"},
+	{models.MerchantMetricActiveDays30d, "30天有交易天数", "天"},
+	{models.MerchantMetricActiveDays60d, "60天有交易天数", "天"},
+	{models.MerchantMetricActiveDays90d, "90天有交易天数", "天"},
+	{models.MerchantMetricAvgTicket30d, "30天笔均", "分"},
+	{models.MerchantMetricAvgTicket90d, "90天笔均", "分"},
+}
+
+// MerchantClassService 商户分类规则服务
+// 管理员按通道/一级代理商配置有序分类规则，商户类型计算任务按规则分类并记录变更历史；
+// 商户适用的规则集按 一级代理商+通道 > 一级代理商 > 通道 > 全部 匹配，都没有时使用内置默认规则
+type MerchantClassService struct {
+	classRepo *repository.GormMerchantClassRepository
+	agentRepo repository.AgentRepository
+}
+
+// NewMerchantClassService 创建商户分类规则服务
+func NewMerchantClassService(classRepo *repository.GormMerchantClassRepository, agentRepo repository.AgentRepository) *MerchantClassService {
+	return &MerchantClassService{
+		classRepo: classRepo,
+		agentRepo: agentRepo,
+	}
+}
+
+// ============================================================
+// 规则集管理
+// ============================================================
+
+// MerchantClassRuleSetRequest 规则集请求
+type MerchantClassRuleSetRequest struct {
+	Name        string                    `json:"name" binding:"required"`
+	ChannelID   int64                     `json:"channel_id"` // 适用通道，0为全部通道
+	AgentID     int64                     `json:"agent_id"`   // 适用一级代理商，0为全部代理商
+	Rules       models.MerchantClassRules `json:"rules" binding:"required,min=1"`
+	Enabled     bool                      `json:"enabled"`
+	Description string                    `json:"description"`
+}
+
+// GetMetricDefs 获取可用的分类指标
+func (s *MerchantClassService) GetMetricDefs() []MerchantClassMetricDef {
+	return merchantClassMetricDefs
+}
+
+// GetDefaultRules 获取内置默认规则
+func (s *MerchantClassService) GetDefaultRules() models.MerchantClassRules {
+	return defaultMerchantClassRules()
+}
+
+// ListRuleSets 查询规则集
+func (s *MerchantClassService) ListRuleSets(agentID, channelID *int64, page, pageSize int) ([]*models.MerchantClassRuleSet, int64, error) {
+	return s.classRepo.ListRuleSets(agentID, channelID, pageSize, (page-1)*pageSize)
+}
+
+// GetRuleSet 获取规则集
+func (s *MerchantClassService) GetRuleSet(id int64) (*models.MerchantClassRuleSet, error) {
+	set, err := s.classRepo.FindRuleSetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询规则集失败: %w", err)
+	}
+	if set == nil {
+		return nil, errors.New("规则集不存在")
+	}
+	return set, nil
+}
+
+// CreateRuleSet 新增规则集
+func (s *MerchantClassService) CreateRuleSet(req *MerchantClassRuleSetRequest, operatorID int64) (*models.MerchantClassRuleSet, error) {
+	if err := s.validateRuleSetRequest(req, 0); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	set := &models.MerchantClassRuleSet{
+		Name:        strings.TrimSpace(req.Name),
+		ChannelID:   req.ChannelID,
+		AgentID:     req.AgentID,
+		Rules:       normalizeMerchantClassRules(req.Rules),
+		Enabled:     req.Enabled,
+		Description: req.Description,
+		CreatedBy:   operatorID,
+		UpdatedBy:   operatorID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.classRepo.CreateRuleSet(set); err != nil {
+		return nil, fmt.Errorf("创建规则集失败: %w", err)
+	}
+	return set, nil
+}
+
+// UpdateRuleSet 更新规则集（下次分类任务生效）
+func (s *MerchantClassService) UpdateRuleSet(id int64, req *MerchantClassRuleSetRequest, operatorID int64) (*models.MerchantClassRuleSet, error) {
+	set, err := s.GetRuleSet(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateRuleSetRequest(req, id); err != nil {
+		return nil, err
+	}
+
+	set.Name = strings.TrimSpace(req.Name)
+	set.ChannelID = req.ChannelID
+	set.AgentID = req.AgentID
+	set.Rules = normalizeMerchantClassRules(req.Rules)
+	set.Enabled = req.Enabled
+	set.Description = req.Description
+	set.UpdatedBy = operatorID
+	if err := s.classRepo.SaveRuleSet(set); err != nil {
+		return nil, fmt.Errorf("更新规则集失败: %w", err)
+	}
+	return set, nil
+}
+
+// DeleteRuleSet 删除规则集，已分类的商户在下次分类任务时按其他规则重新分类
+func (s *MerchantClassService) DeleteRuleSet(id int64) error {
+	if _, err := s.GetRuleSet(id); err != nil {
+		return err
+	}
+	if err := s.classRepo.DeleteRuleSet(id); err != nil {
+		return fmt.Errorf("删除规则集失败: %w", err)
+	}
+	return nil
+}
+
+// validateRuleSetRequest 校验规则集范围及规则
+func (s *MerchantClassService) validateRuleSetRequest(req *MerchantClassRuleSetRequest, excludeID int64) error {
+	if err := s.validateScope(req.AgentID, req.ChannelID); err != nil {
+		return err
+	}
+	if err := validateMerchantClassRules(req.Rules); err != nil {
+		return err
+	}
+	if !req.Enabled {
+		return nil
+	}
+	exists, err := s.classRepo.ExistsEnabledScope(req.AgentID, req.ChannelID, excludeID)
+	if err != nil {
+		return fmt.Errorf("查询规则集失败: %w", err)
+	}
+	if exists {
+		return errors.New("该代理商、通道范围已有启用的规则集")
+	}
+	return nil
+}
+
+// validateScope 校验适用范围，代理商须为一级代理商
+func (s *MerchantClassService) validateScope(agentID, channelID int64) error {
+	if agentID < 0 || channelID < 0 {
+		return errors.New("适用范围无效")
+	}
+	if agentID == 0 {
+		return nil
+	}
+	agent, err := s.agentRepo.FindByID(agentID)
+	if err != nil || agent == nil {
+		return fmt.Errorf("代理商不存在: %d", agentID)
+	}
+	if agent.ParentID != 0 {
+		return errors.New("规则集只能按一级代理商配置")
+	}
+	return nil
+}
+
+// ============================================================
+// 分类
+// ============================================================
+
+// merchantClassResult 单个商户的分类结果
+type merchantClassResult struct {
+	target    *repository.MerchantClassTarget
+	ruleSetID int64
+	rule      *models.MerchantClassRule
+	metrics   *repository.MerchantClassMetrics
+}
+
+// ClassifyMerchant 按规则计算单个商户的类型
+func (s *MerchantClassService) ClassifyMerchant(merchantID int64, now time.Time) (string, error) {
+	results, err := s.evaluate(&repository.MerchantClassTargetFilter{IDs: []int64{merchantID}}, nil, 0, 1, now)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "", fmt.Errorf("商户不存在或已禁用: %d", merchantID)
+	}
+	if err := s.apply(results[0]); err != nil {
+		return "", err
+	}
+	return results[0].rule.Code, nil
+}
+
+// ClassifyBatch 按规则批量计算商户类型，类型变化时记录历史
+func (s *MerchantClassService) ClassifyBatch(merchantIDs []int64, now time.Time) (success, fail int) {
+	if len(merchantIDs) == 0 {
+		return 0, 0
+	}
+	results, err := s.evaluate(&repository.MerchantClassTargetFilter{IDs: merchantIDs}, nil, 0, len(merchantIDs), now)
+	if err != nil {
+		log.Printf("[MerchantClassService] Evaluate batch failed: %v", err)
+		return 0, len(merchantIDs)
+	}
+	for _, result := range results {
+		if err := s.apply(result); err != nil {
+			log.Printf("[MerchantClassService] Apply class for merchant %d failed: %v", result.target.ID, err)
+			fail++
+			continue
+		}
+		success++
+	}
+	return success, fail
+}
+
+// apply 写入分类结果，类型变化时记录历史
+func (s *MerchantClassService) apply(result *merchantClassResult) error {
+	target := result.target
+	if target.MerchantType == result.rule.Code && target.MerchantTypeLabel == result.rule.Label {
+		return nil
+	}
+
+	var history *models.MerchantClassHistory
+	if target.MerchantType != result.rule.Code {
+		metrics, _ := json.Marshal(merchantClassMetricValues(result.metrics))
+		history = &models.MerchantClassHistory{
+			MerchantID: target.ID,
+			RuleSetID:  result.ruleSetID,
+			OldType:    target.MerchantType,
+			NewType:    result.rule.Code,
+			NewLabel:   result.rule.Label,
+			Metrics:    string(metrics),
+			CreatedAt:  time.Now(),
+		}
+	}
+	if err := s.classRepo.ApplyClass(target.ID, result.rule.Code, result.rule.Label, history); err != nil {
+		return fmt.Errorf("更新商户类型失败: %w", err)
+	}
+	return nil
+}
+
+// evaluate 按ID升序取一批商户计算分类（不写入），candidate不为空时以其替换同ID或同范围的规则集
+func (s *MerchantClassService) evaluate(filter *repository.MerchantClassTargetFilter, candidate *models.MerchantClassRuleSet, afterID int64, limit int, now time.Time) ([]*merchantClassResult, error) {
+	targets, err := s.classRepo.FindClassTargets(filter, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询商户失败: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	sets, err := s.classRepo.FindEnabledRuleSets()
+	if err != nil {
+		return nil, fmt.Errorf("查询规则集失败: %w", err)
+	}
+	if candidate != nil {
+		sets = replaceMerchantClassRuleSet(sets, candidate)
+	}
+
+	ids := make([]int64, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+	}
+	metricsMap, err := s.classRepo.GetClassMetrics(ids, now)
+	if err != nil {
+		return nil, fmt.Errorf("统计商户交易指标失败: %w", err)
+	}
+
+	defaults := defaultMerchantClassRules()
+	results := make([]*merchantClassResult, 0, len(targets))
+	for _, target := range targets {
+		metrics := metricsMap[target.ID]
+		if metrics == nil {
+			metrics = &repository.MerchantClassMetrics{MerchantID: target.ID}
+		}
+
+		result := &merchantClassResult{target: target, metrics: metrics}
+		rules := defaults
+		if set := resolveMerchantClassRuleSet(sets, topAgentIDFromPath(target.AgentID, target.AgentPath), target.ChannelID); set != nil {
+			result.ruleSetID = set.ID
+			rules = set.Rules
+		}
+		if result.rule = matchMerchantClassRule(rules, metrics); result.rule == nil {
+			// 规则集缺少兜底规则时按内置默认规则分类
+			result.ruleSetID = 0
+			result.rule = matchMerchantClassRule(defaults, metrics)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ============================================================
+// 预览
+// ============================================================
+
+// MerchantClassPreviewRequest 规则集预览请求
+// 填写rule_set_id时预览已保存的规则集，否则按请求中的范围和规则预览
+type MerchantClassPreviewRequest struct {
+	RuleSetID int64                     `json:"rule_set_id"`
+	ChannelID int64                     `json:"channel_id"`
+	AgentID   int64                     `json:"agent_id"`
+	Rules     models.MerchantClassRules `json:"rules"`
+}
+
+// MerchantClassTransition 分类变化
+type MerchantClassTransition struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int    `json:"count"`
+}
+
+// MerchantClassPreviewResult 规则集预览结果
+type MerchantClassPreviewResult struct {
+	Scanned     int                        `json:"scanned"`      // 扫描的范围内商户数
+	Applicable  int                        `json:"applicable"`   // 适用该规则集的商户数（排除被更具体规则集覆盖的）
+	Changed     int                        `json:"changed"`      // 分类会变化的商户数
+	ByClass     map[string]int             `json:"by_class"`     // 按新分类统计
+	Transitions []*MerchantClassTransition `json:"transitions"`  // 分类变化明细（按数量降序）
+	Truncated   bool                       `json:"truncated"`    // 商户过多，仅预览了前50000个
+	EvaluatedAt time.Time                  `json:"evaluated_at"` // 指标统计截止时间
+}
+
+// Preview 预览规则集生效后各分类商户数及会变化分类的商户数（不写入）
+func (s *MerchantClassService) Preview(req *MerchantClassPreviewRequest, now time.Time) (*MerchantClassPreviewResult, error) {
+	candidate, err := s.previewCandidate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &repository.MerchantClassTargetFilter{ChannelID: candidate.ChannelID}
+	if candidate.AgentID > 0 {
+		agent, err := s.agentRepo.FindByID(candidate.AgentID)
+		if err != nil || agent == nil {
+			return nil, fmt.Errorf("代理商不存在: %d", candidate.AgentID)
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	result := &MerchantClassPreviewResult{ByClass: make(map[string]int), EvaluatedAt: now}
+	transitions := make(map[[2]string]int)
+	var afterID int64
+	for {
+		if result.Scanned >= merchantClassPreviewMax {
+			result.Truncated = true
+			break
+		}
+		results, err := s.evaluate(filter, candidate, afterID, merchantClassBatchSize, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			break
+		}
+		for _, r := range results {
+			result.Scanned++
+			afterID = r.target.ID
+			if r.ruleSetID != candidate.ID {
+				continue
+			}
+			result.Applicable++
+			result.ByClass[r.rule.Code]++
+			if r.target.MerchantType != r.rule.Code {
+				result.Changed++
+				transitions[[2]string{r.target.MerchantType, r.rule.Code}]++
+			}
+		}
+		if len(results) < merchantClassBatchSize {
+			break
+		}
+	}
+
+	result.Transitions = sortMerchantClassTransitions(transitions)
+	return result, nil
+}
+
+// previewCandidate 构造待预览的规则集，未保存的规则集使用ID -1
+func (s *MerchantClassService) previewCandidate(req *MerchantClassPreviewRequest) (*models.MerchantClassRuleSet, error) {
+	if req.RuleSetID > 0 {
+		set, err := s.GetRuleSet(req.RuleSetID)
+		if err != nil {
+			return nil, err
+		}
+		set.Enabled = true
+		return set, nil
+	}
+
+	if err := s.validateScope(req.AgentID, req.ChannelID); err != nil {
+		return nil, err
+	}
+	if err := validateMerchantClassRules(req.Rules); err != nil {
+		return nil, err
+	}
+	return &models.MerchantClassRuleSet{
+		ID:        -1,
+		ChannelID: req.ChannelID,
+		AgentID:   req.AgentID,
+		Rules:     normalizeMerchantClassRules(req.Rules),
+		Enabled:   true,
+	}, nil
+}
+
+// ListHistory 查询商户分类变更历史
+func (s *MerchantClassService) ListHistory(merchantID int64, page, pageSize int) ([]*models.MerchantClassHistory, int64, error) {
+	return s.classRepo.ListHistory(merchantID, pageSize, (page-1)*pageSize)
+}
+
+// ============================================================
+// 工具函数
+// ============================================================
+
+// defaultMerchantClassRules 内置默认规则（与原5档分类一致）
+func defaultMerchantClassRules() models.MerchantClassRules {
+	return models.MerchantClassRules{
+		{Code: models.MerchantTypeChurned, Label: "流失商户", Conditions: []models.MerchantClassCondition{
+			{Metric: models.MerchantMetricCount60d, Op: models.MerchantClassOpEQ, Value: 0},
+		}},
+		{Code: models.MerchantTypeWarning, Label: "预警商户", Conditions: []models.MerchantClassCondition{
+			{Metric: models.MerchantMetricCount30d, Op: models.MerchantClassOpEQ, Value: 0},
+		}},
+		{Code: models.MerchantTypeQuality, Label: "优质商户", Conditions: []models.MerchantClassCondition{
+			{Metric: models.MerchantMetricAmount30d, Op: models.MerchantClassOpGTE, Value: 5000000},
+		}},
+		{Code: models.MerchantTypeMedium, Label: "中等商户", Conditions: []models.MerchantClassCondition{
+			{Metric: models.MerchantMetricAmount30d, Op: models.MerchantClassOpGTE, Value: 3000000},
+		}},
+		{Code: models.MerchantTypeNormal, Label: "普通商户"},
+	}
+}
+
+// validateMerchantClassRules 校验规则：编码唯一，条件有效，最后一条为无条件的兜底规则
+func validateMerchantClassRules(rules models.MerchantClassRules) error {
+	if len(rules) == 0 {
+		return errors.New("至少配置一条规则")
+	}
+	if len(rules) > merchantClassMaxRules {
+		return fmt.Errorf("最多配置%d条规则", merchantClassMaxRules)
+	}
+
+	codes := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		code := strings.TrimSpace(rule.Code)
+		if !merchantClassCodePattern.MatchString(code) {
+			return fmt.Errorf("第%d条规则编码无效，须为小写字母开头的字母、数字或下划线，最多20位", i+1)
+		}
+		if codes[code] {
+			return fmt.Errorf("第%d条规则编码重复: %s", i+1, code)
+		}
+		codes[code] = true
+
+		label := []rune(strings.TrimSpace(rule.Label))
+		if len(label) == 0 || len(label) > 50 {
+			return fmt.Errorf("第%d条规则名称不能为空且最多50字", i+1)
+		}
+
+		last := i == len(rules)-1
+		if last && len(rule.Conditions) > 0 {
+			return errors.New("最后一条规则须为无条件的兜底规则")
+		}
+		if !last && len(rule.Conditions) == 0 {
+			return fmt.Errorf("第%d条规则缺少条件，只有最后一条规则可以不设条件", i+1)
+		}
+		if len(rule.Conditions) > merchantClassMaxConditions {
+			return fmt.Errorf("第%d条规则最多%d个条件", i+1, merchantClassMaxConditions)
+		}
+		for j, cond := range rule.Conditions {
+			if !isMerchantClassMetric(cond.Metric) {
+				return fmt.Errorf("第%d条规则第%d个条件指标无效: %s", i+1, j+1, cond.Metric)
+			}
+			switch cond.Op {
+			case models.MerchantClassOpGTE, models.MerchantClassOpGT, models.MerchantClassOpLTE, models.MerchantClassOpLT, models.MerchantClassOpEQ:
+			default:
+				return fmt.Errorf("第%d条规则第%d个条件运算符无效: %s", i+1, j+1, cond.Op)
+			}
+			if cond.Value < 0 {
+				return fmt.Errorf("第%d条规则第%d个条件阈值不能为负数", i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeMerchantClassRules 去除编码和名称的空白
+func normalizeMerchantClassRules(rules models.MerchantClassRules) models.MerchantClassRules {
+	out := make(models.MerchantClassRules, 0, len(rules))
+	for _, rule := range rules {
+		rule.Code = strings.TrimSpace(rule.Code)
+		rule.Label = strings.TrimSpace(rule.Label)
+		out = append(out, rule)
+	}
+	return out
+}
+
+// isMerchantClassMetric 是否为可用的分类指标
+func isMerchantClassMetric(metric string) bool {
+	for _, def := range merchantClassMetricDefs {
+		if def.Code == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// merchantClassMetricValues 计算各指标的值（笔均按笔数折算，无交易为0）
+func merchantClassMetricValues(m *repository.MerchantClassMetrics) map[string]int64 {
+	avg := func(amount, count int64) int64 {
+		if count == 0 {
+			return 0
+		}
+		return amount / count
+	}
+	return map[string]int64{
+		models.MerchantMetricAmount30d:     m.Amount30d,
+		models.MerchantMetricAmount60d:     m.Amount60d,
+		models.MerchantMetricAmount90d:     m.Amount90d,
+		models.MerchantMetricCount30d:      m.Count30d,
+		models.MerchantMetricCount60d:      m.Count60d,
+		models.MerchantMetricCount90d:      m.Count90d,
+		models.MerchantMetricActiveDays30d: m.ActiveDays30d,
+		models.MerchantMetricActiveDays60d: m.ActiveDays60d,
+		models.MerchantMetricActiveDays90d: m.ActiveDays90d,
+		models.MerchantMetricAvgTicket30d:  avg(m.Amount30d, m.Count30d),
+		models.MerchantMetricAvgTicket90d:  avg(m.Amount90d, m.Count90d),
+	}
+}
+
+// matchMerchantClassRule 返回首条所有条件都满足的规则，都不满足时返回nil
+func matchMerchantClassRule(rules models.MerchantClassRules, metrics *repository.MerchantClassMetrics) *models.MerchantClassRule {
+	values := merchantClassMetricValues(metrics)
+	for i := range rules {
+		if merchantClassConditionsMet(rules[i].Conditions, values) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// merchantClassConditionsMet 按顺序判断条件，全部满足返回true
+func merchantClassConditionsMet(conditions []models.MerchantClassCondition, values map[string]int64) bool {
+	for _, cond := range conditions {
+		v, ok := values[cond.Metric]
+		if !ok {
+			return false
+		}
+		var met bool
+		switch cond.Op {
+		case models.MerchantClassOpGTE:
+			met = v >= cond.Value
+		case models.MerchantClassOpGT:
+			met = v > cond.Value
+		case models.MerchantClassOpLTE:
+			met = v <= cond.Value
+		case models.MerchantClassOpLT:
+			met = v < cond.Value
+		case models.MerchantClassOpEQ:
+			met = v == cond.Value
+		}
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveMerchantClassRuleSet 选择商户适用的规则集：一级代理商+通道 > 一级代理商 > 通道 > 全部
+func resolveMerchantClassRuleSet(sets []*models.MerchantClassRuleSet, topAgentID, channelID int64) *models.MerchantClassRuleSet {
+	var best *models.MerchantClassRuleSet
+	bestScore := -1
+	for _, set := range sets {
+		if !set.Enabled {
+			continue
+		}
+		if set.AgentID != 0 && set.AgentID != topAgentID {
+			continue
+		}
+		if set.ChannelID != 0 && set.ChannelID != channelID {
+			continue
+		}
+		score := 0
+		if set.AgentID != 0 {
+			score += 2
+		}
+		if set.ChannelID != 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = set, score
+		}
+	}
+	return best
+}
+
+// replaceMerchantClassRuleSet 用候选规则集替换同ID或同范围的规则集
+func replaceMerchantClassRuleSet(sets []*models.MerchantClassRuleSet, candidate *models.MerchantClassRuleSet) []*models.MerchantClassRuleSet {
+	out := make([]*models.MerchantClassRuleSet, 0, len(sets)+1)
+	for _, set := range sets {
+		if set.ID == candidate.ID || (set.AgentID == candidate.AgentID && set.ChannelID == candidate.ChannelID) {
+			continue
+		}
+		out = append(out, set)
+	}
+	return append(out, candidate)
+}
+
+// topAgentIDFromPath 从物化路径（/1/5/12/）取一级代理商ID，路径为空时为代理商自身
+func topAgentIDFromPath(agentID int64, path string) int64 {
+	for _, part := range strings.Split(path, "/") {
+		if part == "" {
+			continue
+		}
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			return id
+		}
+	}
+	return agentID
+}
+
+// sortMerchantClassTransitions 按数量降序排列分类变化，最多返回50项
+func sortMerchantClassTransitions(transitions map[[2]string]int) []*MerchantClassTransition {
+	list := make([]*MerchantClassTransition, 0, len(transitions))
+	for k, count := range transitions {
+		list = append(list, &MerchantClassTransition{From: k[0], To: k[1], Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		if list[i].From != list[j].From {
+			return list[i].From < list[j].From
+		}
+		return list[i].To < list[j].To
+	})
+	if len(list) > merchantClassPreviewMaxTrans {
+		list = list[:merchantClassPreviewMaxTrans]
+	}
+	return list
+}
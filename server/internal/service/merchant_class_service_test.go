@@ -0,0 +1,143 @@
+package service
+
+import (
+	"testing"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestDefaultMerchantClassRules(t *testing.T) {
+	rules := defaultMerchantClassRules()
+	if err := validateMerchantClassRules(rules); err != nil {
+		t.Fatalf("内置默认规则校验失败: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		metrics repository.MerchantClassMetrics
+		want    string
+	}{
+		{"60天无交易流失", repository.MerchantClassMetrics{}, models.MerchantTypeChurned},
+		{"30天无交易预警", repository.MerchantClassMetrics{Count60d: 3, Amount60d: 900000}, models.MerchantTypeWarning},
+		{"5万优质", repository.MerchantClassMetrics{Count30d: 10, Count60d: 10, Amount30d: 5000000}, models.MerchantTypeQuality},
+		{"3万中等", repository.MerchantClassMetrics{Count30d: 10, Count60d: 10, Amount30d: 3000000}, models.MerchantTypeMedium},
+		{"不足3万普通", repository.MerchantClassMetrics{Count30d: 1, Count60d: 1, Amount30d: 2999999}, models.MerchantTypeNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matchMerchantClassRule(rules, &tt.metrics)
+			if got == nil || got.Code != tt.want {
+				t.Errorf("matchMerchantClassRule() = %+v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchMerchantClassRuleAvgTicket(t *testing.T) {
+	rules := models.MerchantClassRules{
+		{Code: "big_ticket", Label: "大额商户", Conditions: []models.MerchantClassCondition{
+			{Metric: models.MerchantMetricActiveDays30d, Op: models.MerchantClassOpGTE, Value: 5},
+			{Metric: models.MerchantMetricAvgTicket30d, Op: models.MerchantClassOpGT, Value: 100000},
+		}},
+		{Code: "other", Label: "其他"},
+	}
+
+	metrics := &repository.MerchantClassMetrics{Count30d: 4, Amount30d: 800000, ActiveDays30d: 5}
+	if got := matchMerchantClassRule(rules, metrics); got.Code != "big_ticket" {
+		t.Errorf("笔均2000元应为大额商户, got %s", got.Code)
+	}
+	metrics.ActiveDays30d = 4
+	if got := matchMerchantClassRule(rules, metrics); got.Code != "other" {
+		t.Errorf("活跃天数不足应走兜底, got %s", got.Code)
+	}
+	if got := matchMerchantClassRule(rules, &repository.MerchantClassMetrics{ActiveDays30d: 9}); got.Code != "other" {
+		t.Errorf("无交易笔均为0, got %s", got.Code)
+	}
+}
+
+func TestValidateMerchantClassRules(t *testing.T) {
+	cond := []models.MerchantClassCondition{{Metric: models.MerchantMetricCount30d, Op: models.MerchantClassOpGT, Value: 0}}
+	tests := []struct {
+		name    string
+		rules   models.MerchantClassRules
+		wantErr bool
+	}{
+		{"正常", models.MerchantClassRules{{Code: "active", Label: "活跃", Conditions: cond}, {Code: "idle", Label: "沉默"}}, false},
+		{"空规则", nil, true},
+		{"缺少兜底", models.MerchantClassRules{{Code: "active", Label: "活跃", Conditions: cond}}, true},
+		{"中间规则无条件", models.MerchantClassRules{{Code: "a", Label: "A"}, {Code: "b", Label: "B"}}, true},
+		{"编码重复", models.MerchantClassRules{{Code: "a", Label: "A", Conditions: cond}, {Code: "a", Label: "B"}}, true},
+		{"编码格式错误", models.MerchantClassRules{{Code: "Active", Label: "活跃"}}, true},
+		{"名称为空", models.MerchantClassRules{{Code: "a", Label: " "}}, true},
+		{"指标无效", models.MerchantClassRules{{Code: "a", Label: "A", Conditions: []models.MerchantClassCondition{{Metric: "amount_7d", Op: "gte"}}}, {Code: "b", Label: "B"}}, true},
+		{"运算符无效", models.MerchantClassRules{{Code: "a", Label: "A", Conditions: []models.MerchantClassCondition{{Metric: "count_30d", Op: "ne"}}}, {Code: "b", Label: "B"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMerchantClassRules(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("validateMerchantClassRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveMerchantClassRuleSet(t *testing.T) {
+	sets := []*models.MerchantClassRuleSet{
+		{ID: 1, Enabled: true},
+		{ID: 2, ChannelID: 8, Enabled: true},
+		{ID: 3, AgentID: 1, Enabled: true},
+		{ID: 4, AgentID: 1, ChannelID: 8, Enabled: true},
+		{ID: 5, AgentID: 2, ChannelID: 9, Enabled: false},
+	}
+
+	tests := []struct {
+		name      string
+		agentID   int64
+		channelID int64
+		want      int64
+	}{
+		{"代理商+通道", 1, 8, 4},
+		{"代理商优先于通道", 1, 9, 3},
+		{"通道", 2, 8, 2},
+		{"停用的不生效", 2, 9, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveMerchantClassRuleSet(sets, tt.agentID, tt.channelID)
+			if got == nil || got.ID != tt.want {
+				t.Errorf("resolveMerchantClassRuleSet() = %+v, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := resolveMerchantClassRuleSet(sets[1:2], 3, 9); got != nil {
+		t.Errorf("无匹配时应返回nil, got %+v", got)
+	}
+
+	candidate := &models.MerchantClassRuleSet{ID: -1, AgentID: 1, Enabled: true}
+	replaced := replaceMerchantClassRuleSet(sets, candidate)
+	if got := resolveMerchantClassRuleSet(replaced, 1, 9); got != candidate {
+		t.Errorf("候选规则集应替换同范围规则集, got %+v", got)
+	}
+	if len(replaced) != len(sets) {
+		t.Errorf("replaceMerchantClassRuleSet() len = %d, want %d", len(replaced), len(sets))
+	}
+}
+
+func TestTopAgentIDFromPath(t *testing.T) {
+	tests := []struct {
+		agentID int64
+		path    string
+		want    int64
+	}{
+		{12, "/1/5/12/", 1},
+		{1, "/1/", 1},
+		{7, "", 7},
+	}
+	for _, tt := range tests {
+		if got := topAgentIDFromPath(tt.agentID, tt.path); got != tt.want {
+			t.Errorf("topAgentIDFromPath(%d, %q) = %d, want %d", tt.agentID, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,786 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/channel"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/pkg/crypto"
+	"xiangshoufu/pkg/sms"
+)
+
+// MerchantOnboardingService 商户进件服务
+// 流程：代理商录入进件资料（资料图片先通过上传接口上传）→ 通过支持进件的通道适配器提交 → 审核中
+// 审核结果由状态同步任务查询通道或merc_income回调对账，审核通过后创建本地商户并关联申请
+type MerchantOnboardingService struct {
+	appRepo           *repository.GormMerchantApplicationRepository
+	merchantRepo      *repository.GormMerchantRepository
+	agentRepo         *repository.GormAgentRepository
+	hierarchyRepo     repository.HierarchyRepository
+	channelRepo       *repository.GormChannelRepository
+	terminalRepo      *repository.GormTerminalRepository
+	uploadRepo        repository.UploadedFileRepository
+	adapterFactory    *channel.AdapterFactory
+	rateChangeService *MerchantRateChangeService
+	messageService    *MessageService
+}
+
+// NewMerchantOnboardingService 创建商户进件服务
+func NewMerchantOnboardingService(
+	appRepo *repository.GormMerchantApplicationRepository,
+	merchantRepo *repository.GormMerchantRepository,
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	channelRepo *repository.GormChannelRepository,
+	terminalRepo *repository.GormTerminalRepository,
+	uploadRepo repository.UploadedFileRepository,
+	adapterFactory *channel.AdapterFactory,
+) *MerchantOnboardingService {
+	return &MerchantOnboardingService{
+		appRepo:        appRepo,
+		merchantRepo:   merchantRepo,
+		agentRepo:      agentRepo,
+		hierarchyRepo:  hierarchyRepo,
+		channelRepo:    channelRepo,
+		terminalRepo:   terminalRepo,
+		uploadRepo:     uploadRepo,
+		adapterFactory: adapterFactory,
+	}
+}
+
+// SetRateChangeService 设置费率修改服务（用于校验进件费率的通道底价/上限和政策费率）
+func (s *MerchantOnboardingService) SetRateChangeService(rateChangeService *MerchantRateChangeService) {
+	s.rateChangeService = rateChangeService
+}
+
+// SetMessageService 设置消息服务（用于审核结果通知）
+func (s *MerchantOnboardingService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// ============================================================
+// 录入与提交
+// ============================================================
+
+// MerchantApplicationInput 进件资料，费率为小数形式（0.006表示0.6%）
+type MerchantApplicationInput struct {
+	AgentID    int64  `json:"agent_id"` // 平台代录时指定所属代理商，代理商录入时为本人
+	ChannelID  int64  `json:"channel_id" binding:"required"`
+	TerminalSN string `json:"terminal_sn" binding:"required"`
+
+	LegalName       string `json:"legal_name" binding:"required"`
+	LegalIDCard     string `json:"legal_id_card" binding:"required"`
+	LegalPhone      string `json:"legal_phone" binding:"required"`
+	IDCardStartDate string `json:"id_card_start_date" binding:"required"`
+	IDCardEndDate   string `json:"id_card_end_date" binding:"required"` // yyyy-mm-dd或"长期"
+
+	SettleAccountName string `json:"settle_account_name" binding:"required"`
+	SettleBankName    string `json:"settle_bank_name" binding:"required"`
+	SettleBranchName  string `json:"settle_branch_name"`
+	SettleCardNo      string `json:"settle_card_no" binding:"required"`
+
+	MerchantName string  `json:"merchant_name" binding:"required"`
+	MCC          string  `json:"mcc"`
+	DistrictCode string  `json:"district_code"`
+	Address      string  `json:"address"`
+	CreditRate   float64 `json:"credit_rate" binding:"required"`
+	DebitRate    float64 `json:"debit_rate" binding:"required"`
+
+	Documents []models.ApplicationDocument `json:"documents" binding:"required"`
+	Submit    bool                         `json:"submit"` // 保存后立即提交通道
+}
+
+// requiredApplicationDocs 必传资料
+var requiredApplicationDocs = []string{channel.DocIDCardFront, channel.DocIDCardBack, channel.DocSettleCard}
+
+// applicationDocNames 支持的资料类型
+var applicationDocNames = map[string]string{
+	channel.DocIDCardFront:     "身份证人像面",
+	channel.DocIDCardBack:      "身份证国徽面",
+	channel.DocSettleCard:      "结算卡正面",
+	channel.DocHandHeldIDCard:  "手持身份证",
+	channel.DocBusinessLicense: "营业执照",
+	channel.DocStoreFront:      "门头照",
+	channel.DocStoreInside:     "经营场所内景",
+}
+
+var legalIDCardPattern = regexp.MustCompile(`^\d{17}[\dXx]$`)
+
+// checkApplicationDocuments 校验资料：类型合法、不重复、必传资料齐全
+func checkApplicationDocuments(docs []models.ApplicationDocument) error {
+	seen := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if _, ok := applicationDocNames[doc.Type]; !ok {
+			return fmt.Errorf("不支持的资料类型: %s", doc.Type)
+		}
+		if doc.FileID <= 0 {
+			return fmt.Errorf("%s未上传", applicationDocNames[doc.Type])
+		}
+		if seen[doc.Type] {
+			return fmt.Errorf("%s重复上传", applicationDocNames[doc.Type])
+		}
+		seen[doc.Type] = true
+	}
+	for _, docType := range requiredApplicationDocs {
+		if !seen[docType] {
+			return fmt.Errorf("请上传%s", applicationDocNames[docType])
+		}
+	}
+	return nil
+}
+
+// validateApplicationInput 校验进件资料格式，返回规范化后的结算卡号
+func validateApplicationInput(req *MerchantApplicationInput, today time.Time) (string, error) {
+	if !legalIDCardPattern.MatchString(strings.TrimSpace(req.LegalIDCard)) {
+		return "", errors.New("法人身份证号格式不正确")
+	}
+	if err := sms.ValidatePhone(strings.TrimSpace(req.LegalPhone)); err != nil {
+		return "", errors.New("法人手机号格式不正确")
+	}
+	_, end, longTerm, ok := parseIDCardValidity(req.IDCardStartDate, req.IDCardEndDate)
+	if !ok {
+		return "", errors.New("身份证有效期格式不正确")
+	}
+	if !longTerm && end.Before(startOfDay(today)) {
+		return "", errors.New("法人身份证已过期")
+	}
+	cardNo, err := normalizeSettleCardNo(req.SettleCardNo)
+	if err != nil {
+		return "", err
+	}
+	if req.CreditRate <= 0 || req.CreditRate >= 0.1 || req.DebitRate <= 0 || req.DebitRate >= 0.1 {
+		return "", errors.New("费率须为小数形式，如0.006表示0.6%")
+	}
+	if err := checkApplicationDocuments(req.Documents); err != nil {
+		return "", err
+	}
+	return cardNo, nil
+}
+
+// CreateApplication 录入进件申请，submit为true时保存后立即提交通道
+func (s *MerchantOnboardingService) CreateApplication(req *MerchantApplicationInput, operator *Operator) (*MerchantApplicationItem, error) {
+	app := &models.MerchantApplication{
+		Status:        models.ApplicationStatusPending,
+		CreatedBy:     operator.UserID,
+		CreatedByName: operator.Name,
+	}
+	if err := s.fillApplication(app, req, operator); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	app.ApplyNo = fmt.Sprintf("MA%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000)
+	app.CreatedAt = now
+	app.UpdatedAt = now
+	if err := s.appRepo.Create(app); err != nil {
+		return nil, fmt.Errorf("保存进件申请失败: %w", err)
+	}
+	s.linkDocuments(app)
+
+	if req.Submit {
+		return s.submit(app)
+	}
+	return s.toItem(app, false), nil
+}
+
+// UpdateApplication 修改待提交或已驳回的申请，submit为true时保存后立即提交通道
+func (s *MerchantOnboardingService) UpdateApplication(id int64, req *MerchantApplicationInput, operator *Operator) (*MerchantApplicationItem, error) {
+	app, err := s.getAccessible(id, operator)
+	if err != nil {
+		return nil, err
+	}
+	if !app.IsEditable() {
+		return nil, errors.New("只能修改待提交或已驳回的申请")
+	}
+	if err := s.fillApplication(app, req, operator); err != nil {
+		return nil, err
+	}
+	if err := s.appRepo.Save(app); err != nil {
+		return nil, fmt.Errorf("保存进件申请失败: %w", err)
+	}
+	s.linkDocuments(app)
+
+	if req.Submit {
+		return s.submit(app)
+	}
+	return s.toItem(app, false), nil
+}
+
+// Submit 提交待提交或已驳回的申请到通道
+func (s *MerchantOnboardingService) Submit(id int64, operator *Operator) (*MerchantApplicationItem, error) {
+	app, err := s.getAccessible(id, operator)
+	if err != nil {
+		return nil, err
+	}
+	if !app.IsEditable() {
+		return nil, errors.New("只能提交待提交或已驳回的申请")
+	}
+	return s.submit(app)
+}
+
+// Cancel 撤销待提交或已驳回的申请（审核中的申请须在通道侧撤回）
+func (s *MerchantOnboardingService) Cancel(id int64, operator *Operator) (*MerchantApplicationItem, error) {
+	app, err := s.getAccessible(id, operator)
+	if err != nil {
+		return nil, err
+	}
+	if !app.IsEditable() {
+		return nil, errors.New("只能撤销待提交或已驳回的申请")
+	}
+	now := time.Now()
+	app.Status = models.ApplicationStatusCancelled
+	app.FinishedAt = &now
+	if err := s.appRepo.Save(app); err != nil {
+		return nil, fmt.Errorf("撤销进件申请失败: %w", err)
+	}
+	return s.toItem(app, false), nil
+}
+
+// fillApplication 校验并填充进件资料（敏感信息加密）
+func (s *MerchantOnboardingService) fillApplication(app *models.MerchantApplication, req *MerchantApplicationInput, operator *Operator) error {
+	cardNo, err := validateApplicationInput(req, time.Now())
+	if err != nil {
+		return err
+	}
+
+	agentID := operator.AgentID
+	if operator.IsAdmin {
+		if req.AgentID <= 0 {
+			return errors.New("请选择所属代理商")
+		}
+		agentID = req.AgentID
+	}
+	if agent, err := s.agentRepo.FindByID(agentID); err != nil || agent == nil {
+		return errors.New("代理商不存在")
+	}
+
+	ch, err := s.channelRepo.FindByID(req.ChannelID)
+	if err != nil || ch == nil {
+		return errors.New("通道不存在")
+	}
+	if _, err := s.onboardingAdapter(ch.ChannelCode); err != nil {
+		return err
+	}
+
+	terminalSN := strings.TrimSpace(req.TerminalSN)
+	terminal, err := s.terminalRepo.FindBySN(terminalSN)
+	if err != nil || terminal == nil {
+		return errors.New("终端不存在")
+	}
+	if terminal.ChannelID != ch.ID {
+		return errors.New("终端不属于该通道")
+	}
+	if terminal.OwnerAgentID != agentID {
+		return errors.New("终端不属于该代理商")
+	}
+	if terminal.Status == models.TerminalStatusBound || terminal.Status == models.TerminalStatusActivated ||
+		terminal.Status == models.TerminalStatusRecycled {
+		return errors.New("终端已绑定商户或已回收，不能进件")
+	}
+	open, err := s.appRepo.FindOpenByTerminal(ch.ChannelCode, terminalSN)
+	if err != nil {
+		return fmt.Errorf("系统错误: %w", err)
+	}
+	if open != nil && open.ID != app.ID {
+		return fmt.Errorf("终端已有进行中的进件申请 %s", open.ApplyNo)
+	}
+
+	if err := s.checkRates(agentID, ch.ID, req.CreditRate, req.DebitRate, operator); err != nil {
+		return err
+	}
+	if err := s.checkDocumentFiles(req.Documents, operator); err != nil {
+		return err
+	}
+
+	idCard, err := crypto.EncryptIDCard(strings.ToUpper(strings.TrimSpace(req.LegalIDCard)))
+	if err != nil {
+		return fmt.Errorf("身份证加密失败: %w", err)
+	}
+	phone, err := crypto.EncryptPhone(strings.TrimSpace(req.LegalPhone))
+	if err != nil {
+		return fmt.Errorf("手机号加密失败: %w", err)
+	}
+	card, err := crypto.GetDefaultCrypto().Encrypt(cardNo)
+	if err != nil {
+		return fmt.Errorf("银行卡号加密失败: %w", err)
+	}
+
+	app.AgentID = agentID
+	app.ChannelID = ch.ID
+	app.ChannelCode = ch.ChannelCode
+	app.TerminalSN = terminalSN
+	app.BrandCode = terminal.BrandCode
+	app.LegalName = strings.TrimSpace(req.LegalName)
+	app.LegalIDCard = idCard
+	app.LegalPhone = phone
+	app.IDCardStartDate = strings.TrimSpace(req.IDCardStartDate)
+	app.IDCardEndDate = strings.TrimSpace(req.IDCardEndDate)
+	app.SettleAccountName = strings.TrimSpace(req.SettleAccountName)
+	app.SettleBankName = strings.TrimSpace(req.SettleBankName)
+	app.SettleBranchName = strings.TrimSpace(req.SettleBranchName)
+	app.SettleCardEncrypted = card
+	app.SettleCardMasked = maskBankAccount(cardNo)
+	app.MerchantName = strings.TrimSpace(req.MerchantName)
+	app.MCC = req.MCC
+	app.DistrictCode = req.DistrictCode
+	app.Address = req.Address
+	app.CreditRate = formatMerchantRate(req.CreditRate)
+	app.DebitRate = formatMerchantRate(req.DebitRate)
+	app.Documents = models.ApplicationDocuments(req.Documents)
+	return nil
+}
+
+// checkRates 校验进件费率：不能超出通道底价/上限，代理商不能低于自身政策费率
+func (s *MerchantOnboardingService) checkRates(agentID, channelID int64, creditRate, debitRate float64, operator *Operator) error {
+	if s.rateChangeService == nil {
+		return nil
+	}
+	limits, err := s.rateChangeService.loadRateLimits(&models.Merchant{AgentID: agentID, ChannelID: channelID})
+	if err != nil {
+		return err
+	}
+	reason, err := checkRateChangeLimits(creditRate, debitRate, limits)
+	if err != nil {
+		return err
+	}
+	if reason != "" && !operator.IsAdmin {
+		return fmt.Errorf("%s，低于政策费率的商户须由平台进件", reason)
+	}
+	return nil
+}
+
+// checkDocumentFiles 校验资料图片存在，代理商只能使用本人上传的图片
+func (s *MerchantOnboardingService) checkDocumentFiles(docs []models.ApplicationDocument, operator *Operator) error {
+	for _, doc := range docs {
+		file, err := s.uploadRepo.FindByID(doc.FileID)
+		if err != nil || file == nil {
+			return fmt.Errorf("%s图片不存在，请重新上传", applicationDocNames[doc.Type])
+		}
+		if !operator.IsAdmin && file.UploadedBy != operator.UserID {
+			return fmt.Errorf("%s图片不是本人上传", applicationDocNames[doc.Type])
+		}
+	}
+	return nil
+}
+
+// linkDocuments 资料图片关联到申请
+func (s *MerchantOnboardingService) linkDocuments(app *models.MerchantApplication) {
+	for _, doc := range app.Documents {
+		if err := s.uploadRepo.UpdateRefID(doc.FileID, app.ID); err != nil {
+			log.Printf("[MerchantOnboardingService] Link file %d to application %s failed: %v", doc.FileID, app.ApplyNo, err)
+		}
+	}
+}
+
+// onboardingAdapter 获取支持进件的通道适配器
+func (s *MerchantOnboardingService) onboardingAdapter(channelCode string) (channel.MerchantOnboardingAdapter, error) {
+	adapter, err := s.adapterFactory.GetAdapter(channelCode)
+	if err != nil {
+		return nil, errors.New("通道未接入")
+	}
+	onboarding, ok := adapter.(channel.MerchantOnboardingAdapter)
+	if !ok {
+		return nil, errors.New("该通道暂不支持线上进件，请在通道APP进件")
+	}
+	return onboarding, nil
+}
+
+// submit 提交通道：网络异常或通道未受理时保持待提交并记录原因，受理后按通道返回的状态更新
+func (s *MerchantOnboardingService) submit(app *models.MerchantApplication) (*MerchantApplicationItem, error) {
+	onboarding, err := s.onboardingAdapter(app.ChannelCode)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.buildChannelRequest(app)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	app.SubmitAttempts++
+	app.SubmittedAt = &now
+	resp, err := onboarding.SubmitMerchantApplication(req)
+	var submitErr error
+	switch {
+	case err != nil:
+		app.Status = models.ApplicationStatusPending
+		app.StatusMessage = "提交失败: " + err.Error()
+		submitErr = fmt.Errorf("提交通道失败: %v", err)
+	case !resp.Accepted:
+		app.Status = models.ApplicationStatusPending
+		app.StatusMessage = "通道未受理: " + resp.Message
+		submitErr = errors.New(app.StatusMessage)
+	default:
+		app.ChannelApplyNo = resp.ChannelApplyNo
+		app.StatusMessage = ""
+		app.FinishedAt = nil
+		app.Status = models.ApplicationStatusReviewing
+		if status := channelApplicationStatus(resp.Status); status != 0 && status != models.ApplicationStatusReviewing {
+			s.applyResult(app, status, resp.MerchantNo, resp.Message, models.ApplicationSourceSubmit, now)
+		} else {
+			app.StatusSource = models.ApplicationSourceSubmit
+		}
+	}
+	if err := s.appRepo.Save(app); err != nil {
+		return nil, fmt.Errorf("保存进件申请失败: %w", err)
+	}
+	if submitErr != nil {
+		log.Printf("[MerchantOnboardingService] Submit application %s failed: %v", app.ApplyNo, submitErr)
+		return nil, submitErr
+	}
+	log.Printf("[MerchantOnboardingService] Application %s submitted, channel apply no %s", app.ApplyNo, app.ChannelApplyNo)
+	return s.toItem(app, false), nil
+}
+
+// buildChannelRequest 组装通道进件请求（解密敏感信息、解析资料图片地址）
+func (s *MerchantOnboardingService) buildChannelRequest(app *models.MerchantApplication) (*channel.MerchantApplicationRequest, error) {
+	idCard, err := crypto.DecryptIDCard(app.LegalIDCard)
+	if err != nil {
+		return nil, fmt.Errorf("身份证解密失败: %w", err)
+	}
+	phone, err := crypto.DecryptPhone(app.LegalPhone)
+	if err != nil {
+		return nil, fmt.Errorf("手机号解密失败: %w", err)
+	}
+	cardNo, err := crypto.GetDefaultCrypto().Decrypt(app.SettleCardEncrypted)
+	if err != nil {
+		return nil, fmt.Errorf("银行卡号解密失败: %w", err)
+	}
+
+	docs := make([]channel.MerchantDocument, 0, len(app.Documents))
+	for _, doc := range app.Documents {
+		file, err := s.uploadRepo.FindByID(doc.FileID)
+		if err != nil || file == nil {
+			return nil, fmt.Errorf("%s图片不存在，请重新上传", applicationDocNames[doc.Type])
+		}
+		docs = append(docs, channel.MerchantDocument{
+			Type:     doc.Type,
+			FileURL:  file.FileURL,
+			FileName: file.OriginalName,
+			MimeType: file.MimeType,
+		})
+	}
+
+	return &channel.MerchantApplicationRequest{
+		ApplyNo:           app.ApplyNo,
+		TerminalSN:        app.TerminalSN,
+		BrandCode:         app.BrandCode,
+		LegalName:         app.LegalName,
+		LegalIDCard:       idCard,
+		LegalPhone:        phone,
+		IDCardStartDate:   app.IDCardStartDate,
+		IDCardEndDate:     app.IDCardEndDate,
+		SettleAccountName: app.SettleAccountName,
+		SettleCardNo:      cardNo,
+		SettleBankName:    app.SettleBankName,
+		SettleBranchName:  app.SettleBranchName,
+		MerchantName:      app.MerchantName,
+		MCC:               app.MCC,
+		DistrictCode:      app.DistrictCode,
+		Address:           app.Address,
+		CreditRate:        parseRate(app.CreditRate),
+		DebitRate:         parseRate(app.DebitRate),
+		Documents:         docs,
+	}, nil
+}
+
+// ============================================================
+// 审核状态跟踪
+// ============================================================
+
+// channelApplicationStatus 通道审核状态转换为申请状态，无法识别时返回0
+func channelApplicationStatus(status channel.ApplicationStatus) int16 {
+	switch status {
+	case channel.ApplicationReviewing:
+		return models.ApplicationStatusReviewing
+	case channel.ApplicationApproved:
+		return models.ApplicationStatusApproved
+	case channel.ApplicationRejected:
+		return models.ApplicationStatusRejected
+	}
+	return 0
+}
+
+// incomeApplicationStatus merc_income回调审核状态（1审核中 2审核通过 3审核失败 4商户停用）转换为申请状态，
+// 商户停用与进件无关，返回0
+func incomeApplicationStatus(approveStatus int) int16 {
+	switch approveStatus {
+	case 1:
+		return models.ApplicationStatusReviewing
+	case 2:
+		return models.ApplicationStatusApproved
+	case 3:
+		return models.ApplicationStatusRejected
+	}
+	return 0
+}
+
+// nextApplicationStatus 计算状态流转：已通过、已撤销为终态不再变化
+func nextApplicationStatus(current, incoming int16) (int16, bool) {
+	if incoming == 0 || incoming == current || incoming == models.ApplicationStatusPending ||
+		incoming == models.ApplicationStatusCancelled {
+		return current, false
+	}
+	if current == models.ApplicationStatusApproved || current == models.ApplicationStatusCancelled {
+		return current, false
+	}
+	return incoming, true
+}
+
+// applyResult 应用审核结果（调用方负责保存申请），审核通过时创建本地商户，返回状态是否变化
+func (s *MerchantOnboardingService) applyResult(app *models.MerchantApplication, incoming int16, merchantNo, message, source string, now time.Time) bool {
+	if merchantNo != "" && app.MerchantNo == "" {
+		app.MerchantNo = merchantNo
+	}
+	next, changed := nextApplicationStatus(app.Status, incoming)
+	if !changed {
+		// 已通过但商户尚未创建（如通过时未返回商户号）时补建
+		if app.Status == models.ApplicationStatusApproved && app.MerchantID == nil && app.MerchantNo != "" {
+			s.ensureMerchant(app, now)
+		}
+		return false
+	}
+
+	app.Status = next
+	app.StatusSource = source
+	switch next {
+	case models.ApplicationStatusApproved:
+		app.StatusMessage = ""
+		app.FinishedAt = &now
+		s.ensureMerchant(app, now)
+		s.notify(app.AgentID, "商户进件审核通过",
+			fmt.Sprintf("商户%s（终端%s）进件审核通过，商户号%s。", app.MerchantName, app.TerminalSN, app.MerchantNo))
+	case models.ApplicationStatusRejected:
+		if message == "" {
+			message = "通道审核未通过"
+		}
+		app.StatusMessage = message
+		app.FinishedAt = &now
+		s.notify(app.AgentID, "商户进件审核未通过",
+			fmt.Sprintf("商户%s（终端%s）进件审核未通过：%s。请修改资料后重新提交。", app.MerchantName, app.TerminalSN, message))
+	default:
+		app.StatusMessage = message
+		app.FinishedAt = nil
+	}
+	log.Printf("[MerchantOnboardingService] Application %s status -> %s (%s)", app.ApplyNo, models.GetApplicationStatusName(next), source)
+	return true
+}
+
+// ensureMerchant 审核通过后关联本地商户，不存在时按申请资料创建
+func (s *MerchantOnboardingService) ensureMerchant(app *models.MerchantApplication, now time.Time) {
+	if app.MerchantNo == "" {
+		app.StatusMessage = "审核已通过，等待通道返回商户号"
+		return
+	}
+	if merchant, err := s.merchantRepo.FindByMerchantNo(app.MerchantNo); err == nil && merchant != nil {
+		app.MerchantID = &merchant.ID
+		return
+	}
+
+	merchant := &models.Merchant{
+		MerchantNo:      app.MerchantNo,
+		MerchantName:    app.MerchantName,
+		AgentID:         app.AgentID,
+		ChannelID:       app.ChannelID,
+		TerminalSN:      app.TerminalSN,
+		LegalName:       app.LegalName,
+		LegalIDCard:     app.LegalIDCard,
+		MCC:             app.MCC,
+		CreditRate:      app.CreditRate,
+		DebitRate:       app.DebitRate,
+		IsDirect:        true,
+		MerchantType:    models.MerchantTypeNormal,
+		Status:          models.MerchantStatusActive,
+		ApproveStatus:   models.MerchantApproveStatusApproved,
+		RegisteredPhone: app.LegalPhone,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if start, end, longTerm, ok := parseIDCardValidity(app.IDCardStartDate, app.IDCardEndDate); ok {
+		merchant.IDCardStartDate = start
+		merchant.IDCardEndDate = end
+		merchant.IDCardLongTerm = longTerm
+		merchant.KycUpdatedAt = &now
+	}
+	if err := s.merchantRepo.Create(merchant); err != nil {
+		log.Printf("[MerchantOnboardingService] Create merchant %s for application %s failed: %v", app.MerchantNo, app.ApplyNo, err)
+		app.StatusMessage = "审核已通过，创建本地商户失败，将在下次回调或同步时重试"
+		return
+	}
+	app.MerchantID = &merchant.ID
+}
+
+// ReconcileIncome merc_income回调对账：按通道商户号或终端匹配本系统提交的申请并更新状态
+// 未匹配到申请（商户在通道APP进件）时不处理
+func (s *MerchantOnboardingService) ReconcileIncome(income *channel.UnifiedMerchantIncome) error {
+	var app *models.MerchantApplication
+	var err error
+	if income.MerchantNo != "" {
+		if app, err = s.appRepo.FindByMerchantNo(income.ChannelCode, income.MerchantNo); err != nil {
+			return fmt.Errorf("查询进件申请失败: %w", err)
+		}
+	}
+	if app == nil && income.TerminalSN != "" {
+		if app, err = s.appRepo.FindOpenByTerminal(income.ChannelCode, income.TerminalSN); err != nil {
+			return fmt.Errorf("查询进件申请失败: %w", err)
+		}
+	}
+	if app == nil {
+		return nil
+	}
+
+	before := app.MerchantID
+	changed := s.applyResult(app, incomeApplicationStatus(income.ApproveStatus), income.MerchantNo, "", models.ApplicationSourceCallback, time.Now())
+	if !changed && before == app.MerchantID && app.MerchantNo == income.MerchantNo {
+		return nil
+	}
+	return s.appRepo.Save(app)
+}
+
+// ApplicationSyncResult 状态同步结果
+type ApplicationSyncResult struct {
+	Queried int `json:"queried"`
+	Updated int `json:"updated"`
+	Failed  int `json:"failed"`
+}
+
+// applicationQueryInterval 同一申请两次查询的最小间隔
+const applicationQueryInterval = 10 * time.Minute
+
+// SyncStatuses 查询审核中申请的通道审核状态
+func (s *MerchantOnboardingService) SyncStatuses(now time.Time, limit int) (*ApplicationSyncResult, error) {
+	apps, err := s.appRepo.FindReviewingToQuery(now.Add(-applicationQueryInterval), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询审核中申请失败: %w", err)
+	}
+
+	result := &ApplicationSyncResult{}
+	for _, app := range apps {
+		result.Queried++
+		app.LastQueriedAt = &now
+		onboarding, err := s.onboardingAdapter(app.ChannelCode)
+		if err == nil {
+			var resp *channel.MerchantApplicationResponse
+			if resp, err = onboarding.QueryMerchantApplication(app.ChannelApplyNo); err == nil &&
+				s.applyResult(app, channelApplicationStatus(resp.Status), resp.MerchantNo, resp.Message, models.ApplicationSourceQuery, now) {
+				result.Updated++
+			}
+		}
+		if err != nil {
+			result.Failed++
+			log.Printf("[MerchantOnboardingService] Query application %s failed: %v", app.ApplyNo, err)
+		}
+		if err := s.appRepo.Save(app); err != nil {
+			log.Printf("[MerchantOnboardingService] Save application %s failed: %v", app.ApplyNo, err)
+		}
+	}
+	return result, nil
+}
+
+// ============================================================
+// 查询
+// ============================================================
+
+// ApplicationDocumentItem 资料图片
+type ApplicationDocumentItem struct {
+	Type     string `json:"type"`
+	TypeName string `json:"type_name"`
+	FileID   int64  `json:"file_id"`
+	FileURL  string `json:"file_url"`
+}
+
+// MerchantApplicationItem 进件申请
+type MerchantApplicationItem struct {
+	*models.MerchantApplication
+	StatusName   string                     `json:"status_name"`
+	LegalIDCard  string                     `json:"legal_id_card"` // 脱敏
+	LegalPhone   string                     `json:"legal_phone"`   // 脱敏
+	DocumentList []*ApplicationDocumentItem `json:"document_list,omitempty"`
+}
+
+// ListApplications 进件申请列表，代理商仅查看本人及下级的申请
+func (s *MerchantOnboardingService) ListApplications(filter *repository.MerchantApplicationFilter, operator *Operator, page, pageSize int) ([]*MerchantApplicationItem, int64, error) {
+	if !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(operator.AgentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	list, total, err := s.appRepo.List(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询进件申请失败: %w", err)
+	}
+	items := make([]*MerchantApplicationItem, 0, len(list))
+	for _, app := range list {
+		items = append(items, s.toItem(app, false))
+	}
+	return items, total, nil
+}
+
+// GetApplication 进件申请详情（含资料图片）
+func (s *MerchantOnboardingService) GetApplication(id int64, operator *Operator) (*MerchantApplicationItem, error) {
+	app, err := s.getAccessible(id, operator)
+	if err != nil {
+		return nil, err
+	}
+	return s.toItem(app, true), nil
+}
+
+// getAccessible 查询操作人有权访问的申请
+func (s *MerchantOnboardingService) getAccessible(id int64, operator *Operator) (*models.MerchantApplication, error) {
+	app, err := s.appRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询进件申请失败: %w", err)
+	}
+	if app == nil {
+		return nil, errors.New("进件申请不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin).canAccess(app.AgentID) {
+		return nil, errors.New("无权操作此申请")
+	}
+	return app, nil
+}
+
+// toItem 转换为申请项，withDocuments为true时附带资料图片地址
+func (s *MerchantOnboardingService) toItem(app *models.MerchantApplication, withDocuments bool) *MerchantApplicationItem {
+	item := &MerchantApplicationItem{
+		MerchantApplication: app,
+		StatusName:          models.GetApplicationStatusName(app.Status),
+		LegalIDCard:         maskIDCard(app.LegalIDCard),
+		LegalPhone:          maskPhone(app.LegalPhone),
+	}
+	if withDocuments {
+		for _, doc := range app.Documents {
+			docItem := &ApplicationDocumentItem{Type: doc.Type, TypeName: applicationDocNames[doc.Type], FileID: doc.FileID}
+			if file, err := s.uploadRepo.FindByID(doc.FileID); err == nil && file != nil {
+				docItem.FileURL = file.FileURL
+			}
+			item.DocumentList = append(item.DocumentList, docItem)
+		}
+	}
+	return item
+}
+
+// notify 发送进件消息
+func (s *MerchantOnboardingService) notify(agentID int64, title, content string) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeOnboarding,
+		Title:       title,
+		Content:     content,
+		RelatedType: "merchant_application",
+	}); err != nil {
+		log.Printf("[MerchantOnboardingService] Send notification to agent %d failed: %v", agentID, err)
+	}
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/channel"
+	"xiangshoufu/internal/channel/mock"
+	"xiangshoufu/internal/models"
+)
+
+func TestCheckApplicationDocuments(t *testing.T) {
+	required := []models.ApplicationDocument{
+		{Type: channel.DocIDCardFront, FileID: 1},
+		{Type: channel.DocIDCardBack, FileID: 2},
+		{Type: channel.DocSettleCard, FileID: 3},
+	}
+	tests := []struct {
+		name    string
+		docs    []models.ApplicationDocument
+		wantErr bool
+	}{
+		{"必传齐全", required, false},
+		{"附加门头照", append(append([]models.ApplicationDocument{}, required...), models.ApplicationDocument{Type: channel.DocStoreFront, FileID: 4}), false},
+		{"缺少结算卡", required[:2], true},
+		{"未知类型", append(append([]models.ApplicationDocument{}, required...), models.ApplicationDocument{Type: "selfie", FileID: 4}), true},
+		{"重复上传", append(append([]models.ApplicationDocument{}, required...), models.ApplicationDocument{Type: channel.DocIDCardFront, FileID: 5}), true},
+		{"文件ID为空", []models.ApplicationDocument{required[0], required[1], {Type: channel.DocSettleCard}}, true},
+		{"无资料", nil, true},
+	}
+	for _, tt := range tests {
+		if err := checkApplicationDocuments(tt.docs); (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkApplicationDocuments() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateApplicationInput(t *testing.T) {
+	today := time.Date(2026, 10, 19, 9, 0, 0, 0, time.Local)
+	valid := func() *MerchantApplicationInput {
+		return &MerchantApplicationInput{
+			LegalIDCard:     "11010119900101123X",
+			LegalPhone:      "13800138000",
+			IDCardStartDate: "2020-01-01",
+			IDCardEndDate:   "2040-01-01",
+			SettleCardNo:    "6222 0202 0000 1234 567",
+			CreditRate:      0.006,
+			DebitRate:       0.005,
+			Documents: []models.ApplicationDocument{
+				{Type: channel.DocIDCardFront, FileID: 1},
+				{Type: channel.DocIDCardBack, FileID: 2},
+				{Type: channel.DocSettleCard, FileID: 3},
+			},
+		}
+	}
+
+	cardNo, err := validateApplicationInput(valid(), today)
+	if err != nil {
+		t.Fatalf("valid input: unexpected error %v", err)
+	}
+	if cardNo != "6222020200001234567" {
+		t.Errorf("card no should be normalized, got %s", cardNo)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *MerchantApplicationInput)
+	}{
+		{"身份证号位数不对", func(req *MerchantApplicationInput) { req.LegalIDCard = "1101011990010112" }},
+		{"手机号错误", func(req *MerchantApplicationInput) { req.LegalPhone = "1380013800" }},
+		{"身份证已过期", func(req *MerchantApplicationInput) { req.IDCardEndDate = "2026-10-18" }},
+		{"有效期格式错误", func(req *MerchantApplicationInput) { req.IDCardEndDate = "永久有效" }},
+		{"卡号过短", func(req *MerchantApplicationInput) { req.SettleCardNo = "62220202" }},
+		{"费率按百分比填写", func(req *MerchantApplicationInput) { req.CreditRate = 0.6 }},
+		{"借记卡费率为0", func(req *MerchantApplicationInput) { req.DebitRate = 0 }},
+		{"缺少资料", func(req *MerchantApplicationInput) { req.Documents = req.Documents[:1] }},
+	}
+	for _, tt := range tests {
+		req := valid()
+		tt.modify(req)
+		if _, err := validateApplicationInput(req, today); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	longTerm := valid()
+	longTerm.IDCardEndDate = "长期"
+	if _, err := validateApplicationInput(longTerm, today); err != nil {
+		t.Errorf("long term id card: unexpected error %v", err)
+	}
+}
+
+func TestApplicationStatusMapping(t *testing.T) {
+	channelTests := []struct {
+		status channel.ApplicationStatus
+		want   int16
+	}{
+		{channel.ApplicationReviewing, models.ApplicationStatusReviewing},
+		{channel.ApplicationApproved, models.ApplicationStatusApproved},
+		{channel.ApplicationRejected, models.ApplicationStatusRejected},
+		{"unknown", 0},
+	}
+	for _, tt := range channelTests {
+		if got := channelApplicationStatus(tt.status); got != tt.want {
+			t.Errorf("channelApplicationStatus(%s) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+
+	incomeTests := []struct {
+		approveStatus int
+		want          int16
+	}{
+		{1, models.ApplicationStatusReviewing},
+		{2, models.ApplicationStatusApproved},
+		{3, models.ApplicationStatusRejected},
+		{4, 0}, // 商户停用与进件无关
+		{0, 0},
+	}
+	for _, tt := range incomeTests {
+		if got := incomeApplicationStatus(tt.approveStatus); got != tt.want {
+			t.Errorf("incomeApplicationStatus(%d) = %d, want %d", tt.approveStatus, got, tt.want)
+		}
+	}
+}
+
+func TestNextApplicationStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     int16
+		incoming    int16
+		want        int16
+		wantChanged bool
+	}{
+		{"审核中->通过", models.ApplicationStatusReviewing, models.ApplicationStatusApproved, models.ApplicationStatusApproved, true},
+		{"审核中->驳回", models.ApplicationStatusReviewing, models.ApplicationStatusRejected, models.ApplicationStatusRejected, true},
+		{"重复审核中", models.ApplicationStatusReviewing, models.ApplicationStatusReviewing, models.ApplicationStatusReviewing, false},
+		{"驳回后通道重新审核", models.ApplicationStatusRejected, models.ApplicationStatusReviewing, models.ApplicationStatusReviewing, true},
+		{"驳回后回调通过", models.ApplicationStatusRejected, models.ApplicationStatusApproved, models.ApplicationStatusApproved, true},
+		{"已通过为终态", models.ApplicationStatusApproved, models.ApplicationStatusRejected, models.ApplicationStatusApproved, false},
+		{"已撤销为终态", models.ApplicationStatusCancelled, models.ApplicationStatusApproved, models.ApplicationStatusCancelled, false},
+		{"无法识别的状态", models.ApplicationStatusReviewing, 0, models.ApplicationStatusReviewing, false},
+	}
+	for _, tt := range tests {
+		got, changed := nextApplicationStatus(tt.current, tt.incoming)
+		if got != tt.want || changed != tt.wantChanged {
+			t.Errorf("%s: nextApplicationStatus() = (%d, %v), want (%d, %v)", tt.name, got, changed, tt.want, tt.wantChanged)
+		}
+	}
+}
+
+// plainAdapter 不支持进件的通道
+type plainAdapter struct {
+	channel.ChannelAdapter
+}
+
+func TestOnboardingAdapter(t *testing.T) {
+	factory := channel.GetFactory()
+	onboarding := mock.NewOnboardingAdapter()
+	onboarding.ChannelCode = "MOCK_ONBOARDING"
+	factory.Register(onboarding)
+	plain := mock.NewOnboardingAdapter()
+	plain.ChannelCode = "MOCK_PLAIN"
+	factory.Register(plainAdapter{plain})
+
+	s := &MerchantOnboardingService{adapterFactory: factory}
+	adapter, err := s.onboardingAdapter("MOCK_ONBOARDING")
+	if err != nil {
+		t.Fatalf("onboarding adapter: unexpected error %v", err)
+	}
+	resp, err := adapter.SubmitMerchantApplication(&channel.MerchantApplicationRequest{ApplyNo: "MA001"})
+	if err != nil || !resp.Accepted || channelApplicationStatus(resp.Status) != models.ApplicationStatusReviewing {
+		t.Errorf("mock submit should be accepted and reviewing, got %+v, %v", resp, err)
+	}
+
+	onboarding.SetResult(resp.ChannelApplyNo, channel.ApplicationApproved, "M10001", "")
+	result, err := adapter.QueryMerchantApplication(resp.ChannelApplyNo)
+	if err != nil || channelApplicationStatus(result.Status) != models.ApplicationStatusApproved || result.MerchantNo != "M10001" {
+		t.Errorf("mock query should return approved with merchant no, got %+v, %v", result, err)
+	}
+
+	if _, err := s.onboardingAdapter("MOCK_PLAIN"); err == nil {
+		t.Errorf("adapter without onboarding support should be rejected")
+	}
+	if _, err := s.onboardingAdapter("NOT_EXIST"); err == nil {
+		t.Errorf("unknown channel should be rejected")
+	}
+}
+
+func TestApplyResultRejected(t *testing.T) {
+	s := &MerchantOnboardingService{}
+	now := time.Now()
+	app := &models.MerchantApplication{ApplyNo: "MA001", Status: models.ApplicationStatusReviewing}
+
+	if !s.applyResult(app, models.ApplicationStatusRejected, "", "", models.ApplicationSourceQuery, now) {
+		t.Fatalf("reviewing -> rejected should change status")
+	}
+	if app.Status != models.ApplicationStatusRejected || app.StatusMessage == "" || app.FinishedAt == nil {
+		t.Errorf("rejected application should record message and finish time, got %+v", app)
+	}
+	if !app.IsEditable() {
+		t.Errorf("rejected application should be editable")
+	}
+
+	// 通过但通道未返回商户号时等待后续回调/同步补建商户
+	if !s.applyResult(app, models.ApplicationStatusApproved, "", "", models.ApplicationSourceCallback, now) {
+		t.Fatalf("rejected -> approved should change status")
+	}
+	if app.Status != models.ApplicationStatusApproved || app.MerchantID != nil || app.StatusMessage == "" {
+		t.Errorf("approved without merchant no should wait for merchant no, got %+v", app)
+	}
+}
@@ -0,0 +1,753 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/pkg/crypto"
+	"xiangshoufu/pkg/sms"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 商户令牌签发方，与代理商/管理员令牌区分
+const merchantTokenIssuer = "xiangshoufu-merchant"
+
+// MerchantPortalConfig 商户自助服务配置
+type MerchantPortalConfig struct {
+	JWTSecret          string        // 商户令牌密钥，与代理商/管理员令牌密钥不同
+	AccessTokenExpiry  time.Duration // 访问令牌有效期
+	RefreshTokenExpiry time.Duration // 刷新令牌有效期
+	CodeExpiry         time.Duration // 验证码有效期
+	CodeMaxAttempts    int           // 单个验证码最多校验次数
+	CodeResendInterval time.Duration // 验证码重发间隔
+	CodeDailyLimit     int           // 每个商户每天最多发送验证码次数
+}
+
+// DefaultMerchantPortalConfig 默认配置，商户令牌密钥由代理商/管理员令牌密钥派生
+func DefaultMerchantPortalConfig(authConfig *AuthConfig) *MerchantPortalConfig {
+	if authConfig == nil {
+		authConfig = DefaultAuthConfig()
+	}
+	return &MerchantPortalConfig{
+		JWTSecret:          authConfig.JWTSecret + ":merchant",
+		AccessTokenExpiry:  2 * time.Hour,
+		RefreshTokenExpiry: 7 * 24 * time.Hour,
+		CodeExpiry:         5 * time.Minute,
+		CodeMaxAttempts:    5,
+		CodeResendInterval: time.Minute,
+		CodeDailyLimit:     10,
+	}
+}
+
+// MerchantPortalService 商户自助服务
+// 商户用商户号+登记手机号短信验证码登录，所有查询都限定在令牌中的商户ID
+type MerchantPortalService struct {
+	config          *MerchantPortalConfig
+	portalRepo      *repository.GormMerchantPortalRepository
+	merchantRepo    *repository.GormMerchantRepository
+	transactionRepo *repository.GormTransactionRepository
+	agentRepo       *repository.GormAgentRepository
+	hierarchyRepo   repository.HierarchyRepository
+	smsProvider     sms.Provider
+	messageService  *MessageService
+}
+
+// NewMerchantPortalService 创建商户自助服务
+func NewMerchantPortalService(
+	config *MerchantPortalConfig,
+	portalRepo *repository.GormMerchantPortalRepository,
+	merchantRepo *repository.GormMerchantRepository,
+	transactionRepo *repository.GormTransactionRepository,
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	smsProvider sms.Provider,
+) *MerchantPortalService {
+	if config == nil {
+		config = DefaultMerchantPortalConfig(nil)
+	}
+	return &MerchantPortalService{
+		config:          config,
+		portalRepo:      portalRepo,
+		merchantRepo:    merchantRepo,
+		transactionRepo: transactionRepo,
+		agentRepo:       agentRepo,
+		hierarchyRepo:   hierarchyRepo,
+		smsProvider:     smsProvider,
+	}
+}
+
+// SetMessageService 设置消息服务（用于结算卡变更通知代理商）
+func (s *MerchantPortalService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// ============================================================
+// 登录
+// ============================================================
+
+// errMerchantLogin 商户号或手机号不匹配时统一返回，避免暴露商户号是否存在
+var errMerchantLogin = errors.New("商户号或手机号不正确")
+
+// MerchantSmsCodeRequest 发送登录验证码请求
+type MerchantSmsCodeRequest struct {
+	MerchantNo string `json:"merchant_no" binding:"required"`
+	Phone      string `json:"phone" binding:"required"`
+	IP         string `json:"-"`
+}
+
+// MerchantLoginRequest 商户登录请求
+type MerchantLoginRequest struct {
+	MerchantNo string `json:"merchant_no" binding:"required"`
+	Phone      string `json:"phone" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+}
+
+// MerchantLoginResponse 商户登录响应
+type MerchantLoginResponse struct {
+	AccessToken  string                 `json:"access_token"`
+	RefreshToken string                 `json:"refresh_token"`
+	ExpiresIn    int64                  `json:"expires_in"` // 秒
+	TokenType    string                 `json:"token_type"`
+	Merchant     *MerchantPortalProfile `json:"merchant"`
+}
+
+// MerchantClaims 商户令牌声明
+type MerchantClaims struct {
+	MerchantID int64  `json:"merchant_id"`
+	MerchantNo string `json:"merchant_no"`
+	jwt.RegisteredClaims
+}
+
+// SendLoginCode 发送登录验证码到商户登记手机号
+func (s *MerchantPortalService) SendLoginCode(req *MerchantSmsCodeRequest) error {
+	merchant, err := s.findLoginMerchant(req.MerchantNo, req.Phone)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	last, err := s.portalRepo.FindLatestSmsCode(merchant.ID, models.MerchantSmsSceneLogin)
+	if err != nil {
+		return fmt.Errorf("系统错误: %w", err)
+	}
+	if last != nil && now.Sub(last.CreatedAt) < s.config.CodeResendInterval {
+		return errors.New("验证码发送过于频繁，请稍后再试")
+	}
+	count, err := s.portalRepo.CountSmsCodesSince(merchant.ID, models.MerchantSmsSceneLogin, startOfDay(now))
+	if err != nil {
+		return fmt.Errorf("系统错误: %w", err)
+	}
+	if int(count) >= s.config.CodeDailyLimit {
+		return errors.New("今日验证码发送次数已达上限")
+	}
+
+	code, err := generateSmsCode()
+	if err != nil {
+		return fmt.Errorf("生成验证码失败: %w", err)
+	}
+	record := &models.MerchantSmsCode{
+		MerchantID: merchant.ID,
+		Scene:      models.MerchantSmsSceneLogin,
+		CodeHash:   hashMerchantSecret(fmt.Sprintf("%d:%s", merchant.ID, code)),
+		ExpiresAt:  now.Add(s.config.CodeExpiry),
+		RequestIP:  req.IP,
+		CreatedAt:  now,
+	}
+	if err := s.portalRepo.CreateSmsCode(record); err != nil {
+		return fmt.Errorf("保存验证码失败: %w", err)
+	}
+
+	content := fmt.Sprintf("您的商户登录验证码为%s，%d分钟内有效，请勿泄露给他人。", code, int(s.config.CodeExpiry.Minutes()))
+	if err := s.smsProvider.Send(req.Phone, content); err != nil {
+		log.Printf("[MerchantPortalService] Send login code to merchant %d failed: %v", merchant.ID, err)
+		return errors.New("短信发送失败，请稍后再试")
+	}
+	return nil
+}
+
+// Login 验证码登录
+func (s *MerchantPortalService) Login(req *MerchantLoginRequest) (*MerchantLoginResponse, error) {
+	merchant, err := s.findLoginMerchant(req.MerchantNo, req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.portalRepo.FindLatestSmsCode(merchant.ID, models.MerchantSmsSceneLogin)
+	if err != nil {
+		return nil, fmt.Errorf("系统错误: %w", err)
+	}
+	if err := s.verifySmsCode(code, merchant.ID, req.Code, time.Now()); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[MerchantPortalService] Merchant %s logged in", merchant.MerchantNo)
+	return s.issueTokens(merchant)
+}
+
+// verifySmsCode 校验验证码：先以条件更新占用一次尝试次数再比对，成功时以条件更新标记已使用
+// 并发请求不会突破错误次数上限，同一验证码也只能使用一次
+func (s *MerchantPortalService) verifySmsCode(code *models.MerchantSmsCode, merchantID int64, input string, now time.Time) error {
+	if code == nil || code.UsedAt != nil || !now.Before(code.ExpiresAt) {
+		return errors.New("验证码已失效，请重新获取")
+	}
+	if code.Attempts >= s.config.CodeMaxAttempts {
+		return errors.New("验证码错误次数过多，请重新获取")
+	}
+
+	ok, err := s.portalRepo.UseSmsCodeAttempt(code.ID, s.config.CodeMaxAttempts)
+	if err != nil {
+		return fmt.Errorf("系统错误: %w", err)
+	}
+	if !ok {
+		return errors.New("验证码错误次数过多，请重新获取")
+	}
+
+	expected := hashMerchantSecret(fmt.Sprintf("%d:%s", merchantID, strings.TrimSpace(input)))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(code.CodeHash)) != 1 {
+		return errors.New("验证码错误")
+	}
+
+	ok, err = s.portalRepo.MarkSmsCodeUsed(code.ID, now)
+	if err != nil {
+		return fmt.Errorf("系统错误: %w", err)
+	}
+	if !ok {
+		return errors.New("验证码已失效，请重新获取")
+	}
+	return nil
+}
+
+// RefreshAccessToken 刷新访问令牌（滚动刷新，旧刷新令牌作废）
+// 先删除旧令牌再签发，并发刷新时只有一个请求能成功
+func (s *MerchantPortalService) RefreshAccessToken(refreshToken string) (*MerchantLoginResponse, error) {
+	tokenHash := hashMerchantSecret(refreshToken)
+	rt, err := s.portalRepo.FindRefreshToken(tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("系统错误: %w", err)
+	}
+	if rt == nil {
+		return nil, errors.New("刷新令牌无效或已过期")
+	}
+	consumed, err := s.portalRepo.ConsumeRefreshToken(tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("系统错误: %w", err)
+	}
+	if !consumed {
+		return nil, errors.New("刷新令牌无效或已过期")
+	}
+
+	merchant, err := s.merchantRepo.FindByID(rt.MerchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+	if merchant.Status != models.MerchantStatusActive {
+		return nil, errors.New("商户已被禁用")
+	}
+
+	return s.issueTokens(merchant)
+}
+
+// Logout 登出
+func (s *MerchantPortalService) Logout(refreshToken string) error {
+	return s.portalRepo.DeleteRefreshToken(hashMerchantSecret(refreshToken))
+}
+
+// ValidateToken 验证商户访问令牌，代理商/管理员令牌无法通过
+func (s *MerchantPortalService) ValidateToken(tokenString string) (*MerchantClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MerchantClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.config.JWTSecret), nil
+	}, jwt.WithIssuer(merchantTokenIssuer))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*MerchantClaims); ok && token.Valid && claims.MerchantID > 0 {
+		return claims, nil
+	}
+
+	return nil, errors.New("invalid token")
+}
+
+// CleanupExpiredTokens 清理过期的商户刷新令牌
+func (s *MerchantPortalService) CleanupExpiredTokens() (int64, error) {
+	return s.portalRepo.DeleteExpiredRefreshTokens()
+}
+
+// findLoginMerchant 按商户号查询正常商户并校验登记手机号
+func (s *MerchantPortalService) findLoginMerchant(merchantNo, phone string) (*models.Merchant, error) {
+	merchantNo = strings.TrimSpace(merchantNo)
+	phone = strings.TrimSpace(phone)
+	if err := sms.ValidatePhone(phone); err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.merchantRepo.FindByMerchantNo(merchantNo)
+	if err != nil || merchant == nil {
+		return nil, errMerchantLogin
+	}
+	registered := merchantRegisteredPhone(merchant)
+	if registered == "" || registered != phone {
+		return nil, errMerchantLogin
+	}
+	if merchant.Status != models.MerchantStatusActive {
+		return nil, errors.New("商户已被禁用")
+	}
+	return merchant, nil
+}
+
+// issueTokens 签发访问令牌和刷新令牌
+func (s *MerchantPortalService) issueTokens(merchant *models.Merchant) (*MerchantLoginResponse, error) {
+	now := time.Now()
+	claims := MerchantClaims{
+		MerchantID: merchant.ID,
+		MerchantNo: merchant.MerchantNo,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    merchantTokenIssuer,
+		},
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.JWTSecret))
+	if err != nil {
+		return nil, fmt.Errorf("生成令牌失败: %w", err)
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+	refreshToken := hex.EncodeToString(bytes)
+	if err := s.portalRepo.CreateRefreshToken(&models.MerchantRefreshToken{
+		MerchantID: merchant.ID,
+		TokenHash:  hashMerchantSecret(refreshToken),
+		ExpiresAt:  now.Add(s.config.RefreshTokenExpiry),
+	}); err != nil {
+		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+
+	return &MerchantLoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.config.AccessTokenExpiry.Seconds()),
+		TokenType:    "Bearer",
+		Merchant:     s.toProfile(merchant, 0),
+	}, nil
+}
+
+// ============================================================
+// 商户查询（均限定为令牌中的商户）
+// ============================================================
+
+// MerchantPortalProfile 商户资料与当前费率
+type MerchantPortalProfile struct {
+	MerchantNo        string     `json:"merchant_no"`
+	MerchantName      string     `json:"merchant_name"`
+	LegalName         string     `json:"legal_name"`
+	Phone             string     `json:"phone"` // 脱敏
+	Status            int16      `json:"status"`
+	CreditRate        string     `json:"credit_rate"`         // 贷记卡费率（小数形式）
+	DebitRate         string     `json:"debit_rate"`          // 借记卡费率（小数形式）
+	CreditRatePercent string     `json:"credit_rate_percent"` // 贷记卡费率（百分比，如0.60）
+	DebitRatePercent  string     `json:"debit_rate_percent"`  // 借记卡费率（百分比）
+	TerminalCount     int        `json:"terminal_count"`
+	IDCardEndDate     *time.Time `json:"id_card_end_date"`
+	IDCardLongTerm    bool       `json:"id_card_long_term"`
+	ActivatedAt       *time.Time `json:"activated_at"`
+}
+
+// GetProfile 商户资料与当前费率
+func (s *MerchantPortalService) GetProfile(merchantID int64) (*MerchantPortalProfile, error) {
+	merchant, err := s.getMerchant(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	terminals, err := s.portalRepo.FindTerminalsByMerchant(merchant.ID, merchant.MerchantNo)
+	if err != nil {
+		return nil, fmt.Errorf("查询终端失败: %w", err)
+	}
+	return s.toProfile(merchant, len(terminals)), nil
+}
+
+// ListTransactions 分页查询本商户交易，endTime不含
+func (s *MerchantPortalService) ListTransactions(merchantID int64, startTime, endTime *time.Time, page, pageSize int) ([]*repository.Transaction, int64, error) {
+	if _, err := s.getMerchant(merchantID); err != nil {
+		return nil, 0, err
+	}
+	list, total, err := s.transactionRepo.FindByMerchantID(merchantID, startTime, endTime, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询交易失败: %w", err)
+	}
+	return list, total, nil
+}
+
+// maxSettlementDays 日结汇总最多查询天数
+const maxSettlementDays = 93
+
+// ListDailySettlements 按交易日汇总本商户消费交易，日期均为当天零点，endDate包含在内；默认近30天
+func (s *MerchantPortalService) ListDailySettlements(merchantID int64, startDate, endDate *time.Time, now time.Time) ([]*repository.MerchantDailySettlement, error) {
+	if _, err := s.getMerchant(merchantID); err != nil {
+		return nil, err
+	}
+	start, end, err := settlementDateRange(startDate, endDate, now)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.portalRepo.GetDailySettlements(merchantID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("查询日结汇总失败: %w", err)
+	}
+	return list, nil
+}
+
+// settlementDateRange 日结汇总查询区间[start, end)
+func settlementDateRange(startDate, endDate *time.Time, now time.Time) (time.Time, time.Time, error) {
+	end := startOfDay(now).AddDate(0, 0, 1)
+	if endDate != nil {
+		end = startOfDay(*endDate).AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -30)
+	if startDate != nil {
+		start = startOfDay(*startDate)
+	}
+	if !start.Before(end) {
+		return start, end, errors.New("开始日期不能晚于结束日期")
+	}
+	if daysBetween(start, end) > maxSettlementDays {
+		return start, end, fmt.Errorf("查询区间不能超过%d天", maxSettlementDays)
+	}
+	return start, end, nil
+}
+
+// ListTerminals 本商户绑定的终端
+func (s *MerchantPortalService) ListTerminals(merchantID int64) ([]*models.Terminal, error) {
+	merchant, err := s.getMerchant(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.portalRepo.FindTerminalsByMerchant(merchant.ID, merchant.MerchantNo)
+	if err != nil {
+		return nil, fmt.Errorf("查询终端失败: %w", err)
+	}
+	return list, nil
+}
+
+// getMerchant 查询令牌中的商户
+func (s *MerchantPortalService) getMerchant(merchantID int64) (*models.Merchant, error) {
+	merchant, err := s.merchantRepo.FindByID(merchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+	if merchant.Status != models.MerchantStatusActive {
+		return nil, errors.New("商户已被禁用")
+	}
+	return merchant, nil
+}
+
+// toProfile 转换为商户资料
+func (s *MerchantPortalService) toProfile(merchant *models.Merchant, terminalCount int) *MerchantPortalProfile {
+	return &MerchantPortalProfile{
+		MerchantNo:        merchant.MerchantNo,
+		MerchantName:      merchant.MerchantName,
+		LegalName:         merchant.LegalName,
+		Phone:             maskPhone(merchant.RegisteredPhone),
+		Status:            merchant.Status,
+		CreditRate:        merchant.CreditRate,
+		DebitRate:         merchant.DebitRate,
+		CreditRatePercent: ratePercentText(merchant.CreditRate),
+		DebitRatePercent:  ratePercentText(merchant.DebitRate),
+		TerminalCount:     terminalCount,
+		IDCardEndDate:     merchant.IDCardEndDate,
+		IDCardLongTerm:    merchant.IDCardLongTerm,
+		ActivatedAt:       merchant.ActivatedAt,
+	}
+}
+
+// ============================================================
+// 结算卡变更申请
+// ============================================================
+
+var settleCardNoPattern = regexp.MustCompile(`^\d{12,30}$`)
+
+// CreateSettleCardRequest 提交结算卡变更申请请求
+type CreateSettleCardRequest struct {
+	AccountName string `json:"account_name" binding:"required"`
+	BankName    string `json:"bank_name" binding:"required"`
+	BranchName  string `json:"branch_name"`
+	CardNo      string `json:"card_no" binding:"required"`
+	Reason      string `json:"reason"`
+}
+
+// normalizeSettleCardNo 去除卡号中的空格并校验格式
+func normalizeSettleCardNo(cardNo string) (string, error) {
+	cardNo = strings.ReplaceAll(strings.TrimSpace(cardNo), " ", "")
+	if !settleCardNoPattern.MatchString(cardNo) {
+		return "", errors.New("银行卡号格式不正确")
+	}
+	return cardNo, nil
+}
+
+// CreateSettleCardRequest 商户提交结算卡变更申请，同一商户同时只能有一个待审核申请
+func (s *MerchantPortalService) CreateSettleCardRequest(merchantID int64, req *CreateSettleCardRequest) (*models.MerchantSettleCardRequest, error) {
+	merchant, err := s.getMerchant(merchantID)
+	if err != nil {
+		return nil, err
+	}
+	cardNo, err := normalizeSettleCardNo(req.CardNo)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.portalRepo.ExistsPendingSettleCardRequest(merchant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("系统错误: %w", err)
+	}
+	if pending {
+		return nil, errors.New("已有待审核的结算卡变更申请")
+	}
+
+	encrypted, err := crypto.GetDefaultCrypto().Encrypt(cardNo)
+	if err != nil {
+		return nil, fmt.Errorf("银行卡号加密失败: %w", err)
+	}
+	now := time.Now()
+	request := &models.MerchantSettleCardRequest{
+		RequestNo:       fmt.Sprintf("SC%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		MerchantID:      merchant.ID,
+		MerchantNo:      merchant.MerchantNo,
+		MerchantName:    merchant.MerchantName,
+		AgentID:         merchant.AgentID,
+		AccountName:     strings.TrimSpace(req.AccountName),
+		BankName:        strings.TrimSpace(req.BankName),
+		BranchName:      strings.TrimSpace(req.BranchName),
+		CardNoEncrypted: encrypted,
+		CardNoMasked:    maskBankAccount(cardNo),
+		Reason:          req.Reason,
+		Status:          models.SettleCardRequestPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.portalRepo.CreateSettleCardRequest(request); err != nil {
+		return nil, fmt.Errorf("提交结算卡变更申请失败: %w", err)
+	}
+
+	s.notify(merchant.AgentID, "商户申请变更结算卡",
+		fmt.Sprintf("商户%s（%s）申请将结算卡变更为%s %s，请关注平台审核。",
+			merchant.MerchantName, merchant.MerchantNo, request.BankName, request.CardNoMasked))
+	return request, nil
+}
+
+// ListMerchantSettleCardRequests 本商户的结算卡变更申请
+func (s *MerchantPortalService) ListMerchantSettleCardRequests(merchantID int64, page, pageSize int) ([]*SettleCardRequestItem, int64, error) {
+	filter := &repository.SettleCardRequestFilter{MerchantID: merchantID}
+	list, total, err := s.portalRepo.ListSettleCardRequests(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询结算卡变更申请失败: %w", err)
+	}
+	items := make([]*SettleCardRequestItem, 0, len(list))
+	for _, request := range list {
+		items = append(items, toSettleCardRequestItem(request, false))
+	}
+	return items, total, nil
+}
+
+// CancelSettleCardRequest 商户撤销待审核的申请
+func (s *MerchantPortalService) CancelSettleCardRequest(merchantID, id int64) (*SettleCardRequestItem, error) {
+	request, err := s.portalRepo.FindSettleCardRequest(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询结算卡变更申请失败: %w", err)
+	}
+	if request == nil || request.MerchantID != merchantID {
+		return nil, errors.New("结算卡变更申请不存在")
+	}
+	if request.Status != models.SettleCardRequestPending {
+		return nil, errors.New("只能撤销待审核的申请")
+	}
+	request.Status = models.SettleCardRequestCancelled
+	if err := s.portalRepo.SaveSettleCardRequest(request); err != nil {
+		return nil, fmt.Errorf("撤销结算卡变更申请失败: %w", err)
+	}
+	return toSettleCardRequestItem(request, false), nil
+}
+
+// SettleCardRequestItem 结算卡变更申请
+type SettleCardRequestItem struct {
+	*models.MerchantSettleCardRequest
+	StatusName string `json:"status_name"`
+	CardNo     string `json:"card_no,omitempty"` // 完整卡号，仅平台审核时返回
+}
+
+// toSettleCardRequestItem 转换为申请项，withCardNo为true时解密完整卡号
+func toSettleCardRequestItem(request *models.MerchantSettleCardRequest, withCardNo bool) *SettleCardRequestItem {
+	item := &SettleCardRequestItem{
+		MerchantSettleCardRequest: request,
+		StatusName:                models.GetSettleCardRequestStatusName(request.Status),
+	}
+	if withCardNo {
+		cardNo, err := crypto.GetDefaultCrypto().Decrypt(request.CardNoEncrypted)
+		if err != nil {
+			log.Printf("[MerchantPortalService] Decrypt card no of request %s failed: %v", request.RequestNo, err)
+		} else {
+			item.CardNo = cardNo
+		}
+	}
+	return item
+}
+
+// ListSettleCardRequests 平台/代理商查询结算卡变更申请，代理商仅查看本人及下级商户的申请
+func (s *MerchantPortalService) ListSettleCardRequests(filter *repository.SettleCardRequestFilter, operator *Operator, page, pageSize int) ([]*SettleCardRequestItem, int64, error) {
+	if !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(operator.AgentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	list, total, err := s.portalRepo.ListSettleCardRequests(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询结算卡变更申请失败: %w", err)
+	}
+	items := make([]*SettleCardRequestItem, 0, len(list))
+	for _, request := range list {
+		items = append(items, toSettleCardRequestItem(request, false))
+	}
+	return items, total, nil
+}
+
+// GetSettleCardRequest 结算卡变更申请详情，平台可查看完整卡号
+func (s *MerchantPortalService) GetSettleCardRequest(id int64, operator *Operator) (*SettleCardRequestItem, error) {
+	request, err := s.portalRepo.FindSettleCardRequest(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询结算卡变更申请失败: %w", err)
+	}
+	if request == nil {
+		return nil, errors.New("结算卡变更申请不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin).canAccess(request.AgentID) {
+		return nil, errors.New("无权查看此申请")
+	}
+	return toSettleCardRequestItem(request, operator.IsAdmin), nil
+}
+
+// ReviewSettleCardRequest 平台审核结算卡变更申请，通过后由平台在通道侧完成变更
+func (s *MerchantPortalService) ReviewSettleCardRequest(id int64, approve bool, remark string, operator *Operator) (*SettleCardRequestItem, error) {
+	if !operator.IsAdmin {
+		return nil, errors.New("仅平台可审核结算卡变更申请")
+	}
+	request, err := s.portalRepo.FindSettleCardRequest(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询结算卡变更申请失败: %w", err)
+	}
+	if request == nil {
+		return nil, errors.New("结算卡变更申请不存在")
+	}
+	if request.Status != models.SettleCardRequestPending {
+		return nil, errors.New("申请不是待审核状态")
+	}
+	if !approve && strings.TrimSpace(remark) == "" {
+		return nil, errors.New("驳回时请填写原因")
+	}
+
+	now := time.Now()
+	request.Status = models.SettleCardRequestRejected
+	if approve {
+		request.Status = models.SettleCardRequestApproved
+	}
+	request.ReviewedBy = &operator.UserID
+	request.ReviewedByName = operator.Name
+	request.ReviewRemark = remark
+	request.ReviewedAt = &now
+	if err := s.portalRepo.SaveSettleCardRequest(request); err != nil {
+		return nil, fmt.Errorf("保存审核结果失败: %w", err)
+	}
+
+	statusName := models.GetSettleCardRequestStatusName(request.Status)
+	s.notify(request.AgentID, "结算卡变更申请"+statusName,
+		fmt.Sprintf("商户%s（%s）的结算卡变更申请%s。%s", request.MerchantName, request.MerchantNo, statusName, remark))
+	if merchant, err := s.merchantRepo.FindByID(request.MerchantID); err == nil && merchant != nil {
+		content := fmt.Sprintf("您提交的结算卡变更申请（%s %s）%s。", request.BankName, request.CardNoMasked, statusName)
+		if !approve {
+			content += "原因：" + remark
+		}
+		if err := s.NotifyMerchant(merchant, "结算卡变更", content); err != nil {
+			log.Printf("[MerchantPortalService] Notify merchant %d failed: %v", merchant.ID, err)
+		}
+	}
+	return toSettleCardRequestItem(request, true), nil
+}
+
+// ============================================================
+// 通知
+// ============================================================
+
+// NotifyMerchant 短信通知商户登记手机号（实现MerchantNotifier）
+func (s *MerchantPortalService) NotifyMerchant(merchant *models.Merchant, title, content string) error {
+	phone := merchantRegisteredPhone(merchant)
+	if phone == "" {
+		return errors.New("商户未登记手机号")
+	}
+	return s.smsProvider.Send(phone, fmt.Sprintf("【%s】%s", title, content))
+}
+
+// notify 发送结算卡变更消息给代理商
+func (s *MerchantPortalService) notify(agentID int64, title, content string) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeSettleCard,
+		Title:       title,
+		Content:     content,
+		RelatedType: "settle_card_request",
+	}); err != nil {
+		log.Printf("[MerchantPortalService] Send notification to agent %d failed: %v", agentID, err)
+	}
+}
+
+// ============================================================
+// 工具函数
+// ============================================================
+
+// merchantRegisteredPhone 商户登记手机号明文（兼容未加密的历史数据）
+func merchantRegisteredPhone(merchant *models.Merchant) string {
+	phone := merchant.RegisteredPhone
+	if crypto.IsEncrypted(phone) {
+		decrypted, err := crypto.DecryptPhone(phone)
+		if err != nil {
+			return ""
+		}
+		phone = decrypted
+	}
+	return strings.TrimSpace(phone)
+}
+
+// generateSmsCode 生成6位数字验证码
+func generateSmsCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashMerchantSecret 验证码和刷新令牌只存SHA256哈希
+func hashMerchantSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
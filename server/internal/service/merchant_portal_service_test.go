@@ -0,0 +1,255 @@
+package service
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/channel"
+	"xiangshoufu/internal/channel/hengxintong"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMerchantTokenSeparation(t *testing.T) {
+	authConfig := DefaultAuthConfig()
+	authService := NewAuthService(authConfig, nil, nil, nil, nil)
+	portalService := NewMerchantPortalService(DefaultMerchantPortalConfig(authConfig), nil, nil, nil, nil, nil, nil)
+
+	agentToken, err := authService.generateAccessToken(&models.User{ID: 1, Username: "admin", AgentID: 2, RoleType: models.UserRoleTypeAdmin})
+	if err != nil {
+		t.Fatalf("generateAccessToken failed: %v", err)
+	}
+	if _, err := portalService.ValidateToken(agentToken); err == nil {
+		t.Errorf("agent token should not pass merchant validation")
+	}
+
+	now := time.Now()
+	sign := func(secret, issuer string, merchantID int64) string {
+		claims := MerchantClaims{
+			MerchantID: merchantID,
+			MerchantNo: "M001",
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+				IssuedAt:  jwt.NewNumericDate(now),
+				Issuer:    issuer,
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("sign failed: %v", err)
+		}
+		return token
+	}
+
+	merchantSecret := portalService.config.JWTSecret
+	merchantToken := sign(merchantSecret, merchantTokenIssuer, 10)
+	claims, err := portalService.ValidateToken(merchantToken)
+	if err != nil || claims.MerchantID != 10 {
+		t.Fatalf("ValidateToken = %+v, %v, want merchant 10", claims, err)
+	}
+	if _, err := authService.ValidateToken(merchantToken); err == nil {
+		t.Errorf("merchant token should not pass agent validation")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"签发方错误", sign(merchantSecret, "xiangshoufu", 10)},
+		{"代理商密钥签名", sign(authConfig.JWTSecret, merchantTokenIssuer, 10)},
+		{"缺少商户ID", sign(merchantSecret, merchantTokenIssuer, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := portalService.ValidateToken(tt.token); err == nil {
+				t.Errorf("ValidateToken should fail")
+			}
+		})
+	}
+}
+
+func TestSettlementDateRange(t *testing.T) {
+	loc := time.Local
+	now := time.Date(2026, 3, 15, 14, 30, 0, 0, loc)
+	day := func(m time.Month, d int) *time.Time {
+		v := time.Date(2026, m, d, 0, 0, 0, 0, loc)
+		return &v
+	}
+
+	tests := []struct {
+		name      string
+		start     *time.Time
+		end       *time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{"默认近30天", nil, nil, time.Date(2026, 2, 14, 0, 0, 0, 0, loc), time.Date(2026, 3, 16, 0, 0, 0, 0, loc), false},
+		{"指定区间含结束日", day(3, 1), day(3, 10), *day(3, 1), *day(3, 11), false},
+		{"同一天", day(3, 10), day(3, 10), *day(3, 10), *day(3, 11), false},
+		{"开始晚于结束", day(3, 11), day(3, 10), time.Time{}, time.Time{}, true},
+		{"超过93天", day(1, 1), day(4, 30), time.Time{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := settlementDateRange(tt.start, tt.end, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("range = [%v, %v), want [%v, %v)", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestNormalizeSettleCardNo(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"6222021234567890123", "6222021234567890123", false},
+		{" 6222 0212 3456 7890 123 ", "6222021234567890123", false},
+		{"62220212345", "", true},
+		{"6222-0212-3456-7890", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeSettleCardNo(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeSettleCardNo(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeSettleCardNo(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestVerifySmsCodeRejectsInvalidCode(t *testing.T) {
+	s := NewMerchantPortalService(DefaultMerchantPortalConfig(nil), nil, nil, nil, nil, nil, nil)
+	now := time.Now()
+	used := now.Add(-time.Minute)
+	hash := hashMerchantSecret("10:123456")
+
+	tests := []struct {
+		name string
+		code *models.MerchantSmsCode
+	}{
+		{"未发送", nil},
+		{"已过期", &models.MerchantSmsCode{CodeHash: hash, ExpiresAt: now.Add(-time.Second)}},
+		{"已使用", &models.MerchantSmsCode{CodeHash: hash, ExpiresAt: now.Add(time.Minute), UsedAt: &used}},
+		{"错误次数过多", &models.MerchantSmsCode{CodeHash: hash, ExpiresAt: now.Add(time.Minute), Attempts: 5}},
+	}
+
+	// 以上情况即使验证码正确也不能通过，且不写库
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.verifySmsCode(tt.code, 10, "123456", now); err == nil {
+				t.Errorf("verifySmsCode should fail")
+			}
+		})
+	}
+}
+
+// openTestDB 连接TEST_DATABASE_DSN指定的PostgreSQL，未配置时跳过；返回的连接处于事务中，测试结束后回滚
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN未配置，跳过数据库测试")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database failed: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("begin transaction failed: %v", tx.Error)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// TestIngestedTransactionVisibleInPortal 交易回调入库后可在商户端交易列表和日结汇总中查到
+func TestIngestedTransactionVisibleInPortal(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Exec(`
+		CREATE TEMP TABLE transactions (
+			id BIGSERIAL PRIMARY KEY,
+			trade_no VARCHAR(64),
+			order_no VARCHAR(64) NOT NULL UNIQUE,
+			channel_id BIGINT NOT NULL,
+			channel_code VARCHAR(32),
+			terminal_sn VARCHAR(50) NOT NULL,
+			merchant_id BIGINT,
+			agent_id BIGINT NOT NULL,
+			trade_type SMALLINT NOT NULL,
+			pay_type SMALLINT NOT NULL,
+			card_type SMALLINT,
+			amount BIGINT NOT NULL,
+			fee BIGINT,
+			rate VARCHAR(20),
+			d0_fee BIGINT DEFAULT 0,
+			high_rate DECIMAL(10,4),
+			card_no VARCHAR(32),
+			profit_status SMALLINT DEFAULT 0,
+			refund_status SMALLINT DEFAULT 0,
+			trade_time TIMESTAMPTZ NOT NULL,
+			received_at TIMESTAMPTZ DEFAULT NOW(),
+			ext_data JSONB
+		) ON COMMIT DROP
+	`).Error; err != nil {
+		t.Fatalf("create transactions table failed: %v", err)
+	}
+
+	adapter, _ := hengxintong.NewAdapter(&channel.ChannelConfig{})
+	factory := channel.GetFactory()
+	factory.Register(adapter)
+	callbackRepo := &processorMockCallbackRepository{statuses: map[int64]int16{}, errors: map[int64]string{}}
+	merchantRepo := &processorMockMerchantRepository{merchants: []*models.Merchant{
+		{ID: 201, MerchantNo: "M-PORTAL-001", ChannelID: 3, TerminalSN: "SN-PORTAL-001"},
+	}}
+	transactionRepo := repository.NewGormTransactionRepository(db)
+	processor := NewCallbackProcessor(factory, callbackRepo, transactionRepo, nil, nil,
+		merchantRepo, NewMockTerminalRepository(), nil, NewProfitMockMessageQueue())
+
+	rawBody := []byte(`{"action":"pos_order","brandCode":"HXT001","tusn":"SN-PORTAL-001",` +
+		`"transTime":"2024-01-15 10:30:00","orderNo":"ORDER-PORTAL-1","transCardType":"02",` +
+		`"cardNo":"6228480402564890018","amount":"10000","transactionFee":"0.60","feeExt":"300",` +
+		`"merchantNo":"M-PORTAL-001","agentId":"10","highRate":"0.05"}`)
+	if err := processor.ProcessCallback(1, channel.ChannelCodeHengxintong, string(channel.ActionTransaction), rawBody); err != nil {
+		t.Fatalf("ProcessCallback() error = %v", err)
+	}
+	if callbackRepo.statuses[1] != models.ProcessStatusSuccess {
+		t.Fatalf("callback status = %d, err = %s", callbackRepo.statuses[1], callbackRepo.errors[1])
+	}
+
+	list, total, err := transactionRepo.FindByMerchantID(201, nil, nil, 20, 0)
+	if err != nil {
+		t.Fatalf("FindByMerchantID() error = %v", err)
+	}
+	if total != 1 || len(list) != 1 || list[0].OrderNo != "ORDER-PORTAL-1" {
+		t.Errorf("FindByMerchantID() total = %d, len = %d, want 1 transaction", total, len(list))
+	}
+
+	start := time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local)
+	settlements, err := repository.NewGormMerchantPortalRepository(db).GetDailySettlements(201, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("GetDailySettlements() error = %v", err)
+	}
+	if len(settlements) != 1 || settlements[0].TradeCount != 1 || settlements[0].TradeAmount != 10000 {
+		t.Errorf("GetDailySettlements() = %+v, want 1 trade of 10000", settlements)
+	}
+}
@@ -0,0 +1,594 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// MerchantRateChangeService 商户费率修改申请服务
+// 流程：代理商提交申请 → 校验通道底价/上限（硬限制）和自身政策费率（超出须上级审批）→ 同步到通道 → 通道成功后更新本地费率
+// 通道同步失败时申请保持同步中，由重试任务按退避策略重试，重试用完后标记同步失败，可人工重新同步
+type MerchantRateChangeService struct {
+	changeRepo        *repository.GormMerchantRateChangeRepository
+	merchantRepo      *repository.GormMerchantRepository
+	agentRepo         *repository.GormAgentRepository
+	hierarchyRepo     repository.HierarchyRepository
+	channelRepo       *repository.GormChannelRepository
+	channelConfigRepo *repository.GormChannelConfigRepository
+	rateSyncService   *RateSyncService
+	messageService    *MessageService
+}
+
+// NewMerchantRateChangeService 创建商户费率修改申请服务
+func NewMerchantRateChangeService(
+	changeRepo *repository.GormMerchantRateChangeRepository,
+	merchantRepo *repository.GormMerchantRepository,
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	channelRepo *repository.GormChannelRepository,
+	channelConfigRepo *repository.GormChannelConfigRepository,
+	rateSyncService *RateSyncService,
+) *MerchantRateChangeService {
+	return &MerchantRateChangeService{
+		changeRepo:        changeRepo,
+		merchantRepo:      merchantRepo,
+		agentRepo:         agentRepo,
+		hierarchyRepo:     hierarchyRepo,
+		channelRepo:       channelRepo,
+		channelConfigRepo: channelConfigRepo,
+		rateSyncService:   rateSyncService,
+	}
+}
+
+// SetMessageService 设置消息服务（用于审批通知）
+func (s *MerchantRateChangeService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// RateChangeOperator 当前操作人
+type RateChangeOperator struct {
+	UserID  int64
+	Name    string
+	AgentID int64
+	IsAdmin bool
+}
+
+// ============================================================
+// 申请与审批
+// ============================================================
+
+// CreateRateChangeRequest 提交费率修改申请请求，费率为小数形式（0.006表示0.6%）
+type CreateRateChangeRequest struct {
+	MerchantID int64   `json:"merchant_id" binding:"required"`
+	CreditRate float64 `json:"credit_rate" binding:"required"`
+	DebitRate  float64 `json:"debit_rate" binding:"required"`
+	Reason     string  `json:"reason"`
+}
+
+// CreateRequest 提交费率修改申请，未超出政策限制（或平台提交）时直接同步生效
+func (s *MerchantRateChangeService) CreateRequest(req *CreateRateChangeRequest, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	merchant, err := s.merchantRepo.FindByID(req.MerchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+	if !operator.IsAdmin && merchant.AgentID != operator.AgentID {
+		return nil, errors.New("无权修改此商户费率")
+	}
+	if req.CreditRate <= 0 || req.CreditRate > 0.1 {
+		return nil, errors.New("贷记卡费率范围无效")
+	}
+	if req.DebitRate <= 0 || req.DebitRate > 0.1 {
+		return nil, errors.New("借记卡费率范围无效")
+	}
+
+	creditRate := formatMerchantRate(req.CreditRate)
+	debitRate := formatMerchantRate(req.DebitRate)
+	if creditRate == formatMerchantRate(parseRate(merchant.CreditRate)) && debitRate == formatMerchantRate(parseRate(merchant.DebitRate)) {
+		return nil, errors.New("费率未变化")
+	}
+
+	exists, err := s.changeRepo.ExistsOpenByMerchant(merchant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+	if exists {
+		return nil, errors.New("该商户已有未完成的费率修改申请")
+	}
+
+	limits, err := s.loadRateLimits(merchant)
+	if err != nil {
+		return nil, err
+	}
+	approvalReason, err := checkRateChangeLimits(req.CreditRate, req.DebitRate, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change := &models.MerchantRateChangeRequest{
+		RequestNo:       fmt.Sprintf("RC%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		MerchantID:      merchant.ID,
+		MerchantNo:      merchant.MerchantNo,
+		MerchantName:    merchant.MerchantName,
+		ChannelID:       merchant.ChannelID,
+		AgentID:         merchant.AgentID,
+		OldCreditRate:   merchant.CreditRate,
+		OldDebitRate:    merchant.DebitRate,
+		NewCreditRate:   creditRate,
+		NewDebitRate:    debitRate,
+		Reason:          req.Reason,
+		Status:          models.RateChangeStatusSyncing,
+		RequestedBy:     operator.UserID,
+		RequestedByName: operator.Name,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if approvalReason != "" && !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(merchant.AgentID)
+		if err != nil || agent == nil {
+			return nil, errors.New("商户所属代理商不存在")
+		}
+		change.Status = models.RateChangeStatusPending
+		change.ApprovalReason = approvalReason
+		change.ApproverAgentID = agent.ParentID
+	}
+
+	if err := s.changeRepo.Create(change); err != nil {
+		return nil, fmt.Errorf("创建费率修改申请失败: %w", err)
+	}
+
+	if change.Status == models.RateChangeStatusPending {
+		if change.ApproverAgentID > 0 {
+			s.notify(change.ApproverAgentID, "费率修改待审批",
+				fmt.Sprintf("下级代理商申请将商户[%s]费率调整为贷记卡%s%%、借记卡%s%%，%s，请及时审批。",
+					change.MerchantNo, ratePercentText(creditRate), ratePercentText(debitRate), approvalReason))
+		}
+		return change, nil
+	}
+
+	s.apply(change, merchant)
+	return change, nil
+}
+
+// ReviewRateChangeRequest 审批请求
+type ReviewRateChangeRequest struct {
+	Remark string `json:"remark"`
+}
+
+// Approve 审批通过并同步到通道
+func (s *MerchantRateChangeService) Approve(id int64, remark string, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	change, err := s.findPendingForReview(id, operator)
+	if err != nil {
+		return nil, err
+	}
+	merchant, err := s.merchantRepo.FindByID(change.MerchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+
+	// 通道底价/上限可能在审批期间调整，审批时重新校验硬限制
+	limits, err := s.loadRateLimits(merchant)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRateChangeLimits(parseRate(change.NewCreditRate), parseRate(change.NewDebitRate), limits); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change.Status = models.RateChangeStatusSyncing
+	change.OldCreditRate = merchant.CreditRate
+	change.OldDebitRate = merchant.DebitRate
+	change.ReviewedBy = &operator.UserID
+	change.ReviewedByName = operator.Name
+	change.ReviewRemark = remark
+	change.ReviewedAt = &now
+	if err := s.changeRepo.Save(change); err != nil {
+		return nil, fmt.Errorf("保存费率修改申请失败: %w", err)
+	}
+
+	s.notify(change.AgentID, "费率修改已审批通过", fmt.Sprintf("商户[%s]费率修改申请已审批通过，正在同步到支付通道。", change.MerchantNo))
+	s.apply(change, merchant)
+	return change, nil
+}
+
+// Reject 驳回申请
+func (s *MerchantRateChangeService) Reject(id int64, remark string, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	change, err := s.findPendingForReview(id, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change.Status = models.RateChangeStatusRejected
+	change.ReviewedBy = &operator.UserID
+	change.ReviewedByName = operator.Name
+	change.ReviewRemark = remark
+	change.ReviewedAt = &now
+	if err := s.changeRepo.Save(change); err != nil {
+		return nil, fmt.Errorf("保存费率修改申请失败: %w", err)
+	}
+
+	content := fmt.Sprintf("商户[%s]费率修改申请已被驳回。", change.MerchantNo)
+	if remark != "" {
+		content += "驳回原因：" + remark
+	}
+	s.notify(change.AgentID, "费率修改已驳回", content)
+	return change, nil
+}
+
+// Cancel 取消待审批或同步失败的申请
+func (s *MerchantRateChangeService) Cancel(id int64, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	change, err := s.changeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+	if change == nil {
+		return nil, errors.New("费率修改申请不存在")
+	}
+	if !operator.IsAdmin && change.AgentID != operator.AgentID {
+		return nil, errors.New("无权取消此申请")
+	}
+	if change.Status != models.RateChangeStatusPending && change.Status != models.RateChangeStatusSyncFailed {
+		return nil, errors.New("仅待审批或同步失败的申请可取消")
+	}
+
+	change.Status = models.RateChangeStatusCancelled
+	if err := s.changeRepo.Save(change); err != nil {
+		return nil, fmt.Errorf("保存费率修改申请失败: %w", err)
+	}
+	return change, nil
+}
+
+// Resync 同步失败的申请重新同步到通道
+func (s *MerchantRateChangeService) Resync(id int64, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	change, err := s.changeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+	if change == nil {
+		return nil, errors.New("费率修改申请不存在")
+	}
+	if !operator.IsAdmin && change.AgentID != operator.AgentID {
+		return nil, errors.New("无权操作此申请")
+	}
+	if change.Status != models.RateChangeStatusSyncFailed {
+		return nil, errors.New("仅同步失败的申请可重新同步")
+	}
+	merchant, err := s.merchantRepo.FindByID(change.MerchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+
+	change.Status = models.RateChangeStatusSyncing
+	s.apply(change, merchant)
+	return change, nil
+}
+
+// findPendingForReview 查询待审批申请并校验审批权限：上级代理商审批，一级代理商的申请由平台审批，平台可审批全部
+func (s *MerchantRateChangeService) findPendingForReview(id int64, operator *RateChangeOperator) (*models.MerchantRateChangeRequest, error) {
+	change, err := s.changeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+	if change == nil {
+		return nil, errors.New("费率修改申请不存在")
+	}
+	if change.Status != models.RateChangeStatusPending {
+		return nil, errors.New("该申请不是待审批状态")
+	}
+	if !operator.IsAdmin && (change.ApproverAgentID == 0 || change.ApproverAgentID != operator.AgentID) {
+		return nil, errors.New("无权审批此申请")
+	}
+	return change, nil
+}
+
+// ============================================================
+// 通道同步
+// ============================================================
+
+// apply 同步到通道，通道成功后更新本地费率；失败时保持同步中等待自动重试
+func (s *MerchantRateChangeService) apply(change *models.MerchantRateChangeRequest, merchant *models.Merchant) {
+	channelCode := ""
+	if channel, err := s.channelRepo.FindByID(merchant.ChannelID); err == nil && channel != nil {
+		channelCode = channel.ChannelCode
+	}
+	if s.rateSyncService == nil || channelCode == "" {
+		s.applyLocal(change, "通道编码未配置，跳过同步")
+		return
+	}
+
+	result, err := s.rateSyncService.SyncRateToChannel(context.Background(), &RateUpdateParams{
+		MerchantID:  merchant.ID,
+		MerchantNo:  merchant.MerchantNo,
+		TerminalSN:  merchant.TerminalSN,
+		ChannelCode: channelCode,
+		AgentID:     merchant.AgentID,
+		RequestID:   change.ID,
+		OldRates: &RateInfo{
+			CreditRate: parseRate(change.OldCreditRate),
+			DebitRate:  parseRate(change.OldDebitRate),
+		},
+		NewRates: &RateInfo{
+			CreditRate: parseRate(change.NewCreditRate),
+			DebitRate:  parseRate(change.NewDebitRate),
+		},
+	})
+	if err != nil {
+		log.Printf("[MerchantRateChangeService] Sync request %s failed: %v", change.RequestNo, err)
+		change.Status = models.RateChangeStatusSyncFailed
+		change.SyncMessage = fmt.Sprintf("同步失败: %v", err)
+		s.save(change)
+		return
+	}
+
+	change.SyncLogID = result.LogID
+	if result.Success {
+		s.applyLocal(change, result.Message)
+		return
+	}
+	if result.LogID == 0 {
+		change.Status = models.RateChangeStatusSyncFailed
+		change.SyncMessage = result.Message
+	} else {
+		change.SyncMessage = result.Message + "，将自动重试"
+	}
+	s.save(change)
+}
+
+// applyLocal 通道同步成功（或无需同步）后更新本地费率
+func (s *MerchantRateChangeService) applyLocal(change *models.MerchantRateChangeRequest, syncMessage string) {
+	if err := s.merchantRepo.UpdateRate(change.MerchantID, change.NewCreditRate, change.NewDebitRate); err != nil {
+		// 通道已生效而本地未更新，保持同步中以便排查；不一致报表会列出该商户
+		log.Printf("[MerchantRateChangeService] Update local rate for request %s failed: %v", change.RequestNo, err)
+		change.SyncMessage = fmt.Sprintf("通道同步成功但本地更新失败: %v", err)
+		s.save(change)
+		return
+	}
+
+	now := time.Now()
+	change.Status = models.RateChangeStatusApplied
+	change.SyncMessage = syncMessage
+	change.AppliedAt = &now
+	change.SyncedAt = &now
+	s.save(change)
+}
+
+// save 保存申请，失败只记录日志
+func (s *MerchantRateChangeService) save(change *models.MerchantRateChangeRequest) {
+	if err := s.changeRepo.Save(change); err != nil {
+		log.Printf("[MerchantRateChangeService] Save request %s failed: %v", change.RequestNo, err)
+	}
+}
+
+// RateSyncRetryResult 同步重试结果
+type RateSyncRetryResult struct {
+	Retried   int `json:"retried"`
+	Succeeded int `json:"succeeded"`
+	GaveUp    int `json:"gave_up"` // 重试次数用完标记为同步失败的申请数
+}
+
+// RetrySyncs 重试到期的失败同步，成功后更新本地费率，重试用完后标记申请同步失败
+func (s *MerchantRateChangeService) RetrySyncs(now time.Time, limit int) (*RateSyncRetryResult, error) {
+	result := &RateSyncRetryResult{}
+	if s.rateSyncService == nil {
+		return result, nil
+	}
+
+	logs, err := s.changeRepo.FindRetryableSyncLogs(now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询待重试同步日志失败: %w", err)
+	}
+
+	for _, syncLog := range logs {
+		change, err := s.changeRepo.FindByID(syncLog.RequestID)
+		if err != nil || change == nil || change.Status != models.RateChangeStatusSyncing || change.SyncLogID != syncLog.ID {
+			continue
+		}
+
+		syncResult, err := s.rateSyncService.RetrySync(context.Background(), syncLog)
+		if err != nil {
+			log.Printf("[MerchantRateChangeService] Retry sync log %d failed: %v", syncLog.ID, err)
+			continue
+		}
+		result.Retried++
+
+		if syncResult.Success {
+			result.Succeeded++
+			s.applyLocal(change, syncResult.Message)
+			continue
+		}
+		change.SyncMessage = syncResult.Message
+		if !syncLog.CanRetry() {
+			result.GaveUp++
+			change.Status = models.RateChangeStatusSyncFailed
+			s.notify(change.AgentID, "费率修改同步失败",
+				fmt.Sprintf("商户[%s]费率修改多次同步到支付通道失败，本地费率未变更，请联系平台处理或重新同步。", change.MerchantNo))
+		} else {
+			change.SyncMessage += "，将自动重试"
+		}
+		s.save(change)
+	}
+	return result, nil
+}
+
+// ============================================================
+// 查询
+// ============================================================
+
+// RateChangeItem 费率修改申请列表项
+type RateChangeItem struct {
+	*models.MerchantRateChangeRequest
+	StatusName string `json:"status_name"`
+	CanReview  bool   `json:"can_review"` // 当前操作人可审批
+}
+
+// ListRequests 费率修改申请列表，代理商可查看本人及下级商户的申请和待本人审批的申请
+func (s *MerchantRateChangeService) ListRequests(filter *repository.RateChangeFilter, operator *RateChangeOperator, page, pageSize int) ([]*RateChangeItem, int64, error) {
+	if !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(operator.AgentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+		filter.ApproverID = operator.AgentID
+	}
+
+	list, total, err := s.changeRepo.List(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+
+	items := make([]*RateChangeItem, 0, len(list))
+	for _, change := range list {
+		items = append(items, s.toItem(change, operator))
+	}
+	return items, total, nil
+}
+
+// GetRequest 费率修改申请详情
+func (s *MerchantRateChangeService) GetRequest(id int64, operator *RateChangeOperator) (*RateChangeItem, error) {
+	change, err := s.changeRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率修改申请失败: %w", err)
+	}
+	if change == nil {
+		return nil, errors.New("费率修改申请不存在")
+	}
+	if change.ApproverAgentID != operator.AgentID &&
+		!newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin).canAccess(change.AgentID) {
+		return nil, errors.New("无权查看此申请")
+	}
+	return s.toItem(change, operator), nil
+}
+
+// toItem 转换为列表项
+func (s *MerchantRateChangeService) toItem(change *models.MerchantRateChangeRequest, operator *RateChangeOperator) *RateChangeItem {
+	return &RateChangeItem{
+		MerchantRateChangeRequest: change,
+		StatusName:                models.GetRateChangeStatusName(change.Status),
+		CanReview: change.Status == models.RateChangeStatusPending &&
+			(operator.IsAdmin || (change.ApproverAgentID > 0 && change.ApproverAgentID == operator.AgentID)),
+	}
+}
+
+// ListMismatches 本地与通道费率不一致的商户，代理商仅查看本人及下级商户
+func (s *MerchantRateChangeService) ListMismatches(filter *repository.RateMismatchFilter, operator *RateChangeOperator, page, pageSize int) ([]*repository.RateMismatch, int64, error) {
+	if !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(operator.AgentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	list, total, err := s.changeRepo.ListRateMismatches(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询费率不一致商户失败: %w", err)
+	}
+	return list, total, nil
+}
+
+// ============================================================
+// 限制校验
+// ============================================================
+
+// rateChangeLimits 费率限制，均为小数形式，0表示未配置
+type rateChangeLimits struct {
+	ChannelMinCredit float64 // 通道底价
+	ChannelMaxCredit float64 // 通道上限
+	ChannelMinDebit  float64
+	ChannelMaxDebit  float64
+	PolicyCredit     float64 // 商户所属代理商政策费率
+	PolicyDebit      float64
+}
+
+// loadRateLimits 加载商户所在通道的费率配置和所属代理商政策费率（均为百分比，换算为小数）
+func (s *MerchantRateChangeService) loadRateLimits(merchant *models.Merchant) (*rateChangeLimits, error) {
+	limits := &rateChangeLimits{}
+	ctx := context.Background()
+	if config, err := s.channelConfigRepo.GetRateConfigByCode(ctx, merchant.ChannelID, "CREDIT"); err == nil && config != nil {
+		limits.ChannelMinCredit = models.ParseRateToFloat(config.MinRate) / 100
+		limits.ChannelMaxCredit = models.ParseRateToFloat(config.MaxRate) / 100
+	}
+	if config, err := s.channelConfigRepo.GetRateConfigByCode(ctx, merchant.ChannelID, "DEBIT"); err == nil && config != nil {
+		limits.ChannelMinDebit = models.ParseRateToFloat(config.MinRate) / 100
+		limits.ChannelMaxDebit = models.ParseRateToFloat(config.MaxRate) / 100
+	}
+
+	policy, err := s.changeRepo.FindAgentPolicyRate(merchant.AgentID, merchant.ChannelID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代理商政策费率失败: %w", err)
+	}
+	if policy != nil {
+		limits.PolicyCredit = parseRate(policy.CreditRate) / 100
+		limits.PolicyDebit = parseRate(policy.DebitRate) / 100
+	}
+	return limits, nil
+}
+
+// checkRateChangeLimits 校验费率：超出通道底价/上限直接拒绝，低于代理商政策费率时返回需要审批的原因
+func checkRateChangeLimits(creditRate, debitRate float64, limits *rateChangeLimits) (string, error) {
+	// 比较前统一到4位小数，避免浮点误差
+	credit, debit := roundMerchantRate(creditRate), roundMerchantRate(debitRate)
+
+	if limits.ChannelMinCredit > 0 && credit < roundMerchantRate(limits.ChannelMinCredit) {
+		return "", fmt.Errorf("贷记卡费率不能低于通道底价 %s%%", ratePercentText(formatMerchantRate(limits.ChannelMinCredit)))
+	}
+	if limits.ChannelMaxCredit > 0 && credit > roundMerchantRate(limits.ChannelMaxCredit) {
+		return "", fmt.Errorf("贷记卡费率不能超过通道上限 %s%%", ratePercentText(formatMerchantRate(limits.ChannelMaxCredit)))
+	}
+	if limits.ChannelMinDebit > 0 && debit < roundMerchantRate(limits.ChannelMinDebit) {
+		return "", fmt.Errorf("借记卡费率不能低于通道底价 %s%%", ratePercentText(formatMerchantRate(limits.ChannelMinDebit)))
+	}
+	if limits.ChannelMaxDebit > 0 && debit > roundMerchantRate(limits.ChannelMaxDebit) {
+		return "", fmt.Errorf("借记卡费率不能超过通道上限 %s%%", ratePercentText(formatMerchantRate(limits.ChannelMaxDebit)))
+	}
+
+	switch {
+	case limits.PolicyCredit > 0 && credit < roundMerchantRate(limits.PolicyCredit):
+		return fmt.Sprintf("贷记卡费率低于政策费率%s%%", ratePercentText(formatMerchantRate(limits.PolicyCredit))), nil
+	case limits.PolicyDebit > 0 && debit < roundMerchantRate(limits.PolicyDebit):
+		return fmt.Sprintf("借记卡费率低于政策费率%s%%", ratePercentText(formatMerchantRate(limits.PolicyDebit))), nil
+	}
+	return "", nil
+}
+
+// roundMerchantRate 费率保留4位小数
+func roundMerchantRate(rate float64) float64 {
+	return parseRate(formatMerchantRate(rate))
+}
+
+// formatMerchantRate 格式化商户费率（小数形式，4位小数）
+func formatMerchantRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 4, 64)
+}
+
+// ratePercentText 小数形式费率转为百分比文本，如0.0060 → 0.60
+func ratePercentText(rate string) string {
+	return strconv.FormatFloat(parseRate(rate)*100, 'f', 2, 64)
+}
+
+// notify 发送费率修改消息
+func (s *MerchantRateChangeService) notify(agentID int64, title, content string) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeRateChange,
+		Title:       title,
+		Content:     content,
+		RelatedType: "rate_change_request",
+	}); err != nil {
+		log.Printf("[MerchantRateChangeService] Send notification to agent %d failed: %v", agentID, err)
+	}
+}
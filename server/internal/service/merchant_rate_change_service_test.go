@@ -0,0 +1,58 @@
+package service
+
+import "testing"
+
+func TestCheckRateChangeLimits(t *testing.T) {
+	limits := &rateChangeLimits{
+		ChannelMinCredit: 0.0050,
+		ChannelMaxCredit: 0.0070,
+		ChannelMinDebit:  0.0045,
+		ChannelMaxDebit:  0.0065,
+		PolicyCredit:     0.0055,
+		PolicyDebit:      0.0050,
+	}
+
+	tests := []struct {
+		name         string
+		credit       float64
+		debit        float64
+		wantErr      bool
+		wantApproval bool
+	}{
+		{"政策范围内", 0.0060, 0.0055, false, false},
+		{"等于政策费率", 0.0055, 0.0050, false, false},
+		{"贷记卡低于政策费率", 0.0052, 0.0055, false, true},
+		{"借记卡低于政策费率", 0.0060, 0.0048, false, true},
+		{"等于通道底价", 0.0050, 0.0045, false, true},
+		{"贷记卡低于通道底价", 0.0049, 0.0055, true, false},
+		{"贷记卡超过通道上限", 0.0071, 0.0055, true, false},
+		{"借记卡低于通道底价", 0.0060, 0.0040, true, false},
+		{"借记卡超过通道上限", 0.0060, 0.0066, true, false},
+		{"浮点误差不影响比较", 0.0055000000001, 0.00499999999, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approval, err := checkRateChangeLimits(tt.credit, tt.debit, limits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkRateChangeLimits(%v, %v) error = %v, wantErr %v", tt.credit, tt.debit, err, tt.wantErr)
+			}
+			if (approval != "") != tt.wantApproval {
+				t.Errorf("checkRateChangeLimits(%v, %v) approval = %q, wantApproval %v", tt.credit, tt.debit, approval, tt.wantApproval)
+			}
+		})
+	}
+}
+
+func TestCheckRateChangeLimitsUnconfigured(t *testing.T) {
+	approval, err := checkRateChangeLimits(0.0010, 0.0010, &rateChangeLimits{})
+	if err != nil || approval != "" {
+		t.Errorf("未配置限制时应直接通过, got (%q, %v)", approval, err)
+	}
+}
+
+func TestRatePercentText(t *testing.T) {
+	if got := ratePercentText("0.0060"); got != "0.60" {
+		t.Errorf("ratePercentText = %s, want 0.60", got)
+	}
+}
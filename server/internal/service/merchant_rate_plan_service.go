@@ -0,0 +1,631 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// 费率计划限制
+const (
+	ratePlanMaxSteps       = 12
+	ratePlanDefaultRemind  = 3
+	ratePlanMaxRemindDays  = 30
+	ratePlanOperatorName   = "费率计划"
+	ratePlanImmediateSlack = time.Minute // 生效时间早于当前不超过该值视为立即生效
+)
+
+// MerchantNotifier 商户通知（短信等），未设置时只通知代理商
+type MerchantNotifier interface {
+	NotifyMerchant(merchant *models.Merchant, title, content string) error
+}
+
+// MerchantRatePlanService 商户费率计划服务
+// 限时优惠费率到期自动恢复，或按阶梯在指定时间调整；每个步骤通过费率修改申请执行，走同一条通道同步和失败重试链路
+type MerchantRatePlanService struct {
+	planRepo          *repository.GormMerchantRatePlanRepository
+	changeRepo        *repository.GormMerchantRateChangeRepository
+	merchantRepo      *repository.GormMerchantRepository
+	agentRepo         *repository.GormAgentRepository
+	hierarchyRepo     repository.HierarchyRepository
+	rateChangeService *MerchantRateChangeService
+	messageService    *MessageService
+	merchantNotifier  MerchantNotifier
+}
+
+// NewMerchantRatePlanService 创建商户费率计划服务
+func NewMerchantRatePlanService(
+	planRepo *repository.GormMerchantRatePlanRepository,
+	changeRepo *repository.GormMerchantRateChangeRepository,
+	merchantRepo *repository.GormMerchantRepository,
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	rateChangeService *MerchantRateChangeService,
+) *MerchantRatePlanService {
+	return &MerchantRatePlanService{
+		planRepo:          planRepo,
+		changeRepo:        changeRepo,
+		merchantRepo:      merchantRepo,
+		agentRepo:         agentRepo,
+		hierarchyRepo:     hierarchyRepo,
+		rateChangeService: rateChangeService,
+	}
+}
+
+// SetMessageService 设置消息服务（用于通知代理商）
+func (s *MerchantRatePlanService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// SetMerchantNotifier 设置商户通知
+func (s *MerchantRatePlanService) SetMerchantNotifier(notifier MerchantNotifier) {
+	s.merchantNotifier = notifier
+}
+
+// ============================================================
+// 创建与取消
+// ============================================================
+
+// RatePlanStepInput 阶梯步骤，费率为小数形式
+type RatePlanStepInput struct {
+	EffectiveAt time.Time `json:"effective_at" binding:"required"`
+	CreditRate  float64   `json:"credit_rate" binding:"required"`
+	DebitRate   float64   `json:"debit_rate" binding:"required"`
+}
+
+// CreateRatePlanRequest 创建费率计划请求，费率为小数形式（0.006表示0.6%）
+type CreateRatePlanRequest struct {
+	MerchantID int64  `json:"merchant_id" binding:"required"`
+	PlanType   string `json:"plan_type" binding:"required,oneof=promo steps"`
+	Name       string `json:"name"`
+	RemindDays *int   `json:"remind_days"` // 调整前提前提醒天数，默认3天
+	Remark     string `json:"remark"`
+	// 限时优惠
+	PromoCreditRate  float64    `json:"promo_credit_rate"`
+	PromoDebitRate   float64    `json:"promo_debit_rate"`
+	StartAt          *time.Time `json:"start_at"`           // 优惠开始时间，为空立即生效
+	EndAt            *time.Time `json:"end_at"`             // 优惠结束时间，到期恢复
+	RevertCreditRate float64    `json:"revert_credit_rate"` // 到期恢复费率，为0时恢复为当前费率
+	RevertDebitRate  float64    `json:"revert_debit_rate"`
+	// 阶梯调整
+	Steps []RatePlanStepInput `json:"steps"`
+}
+
+// CreatePlan 创建费率计划，已到生效时间的首个步骤立即执行
+func (s *MerchantRatePlanService) CreatePlan(req *CreateRatePlanRequest, operator *RateChangeOperator) (*RatePlanDetail, error) {
+	merchant, err := s.merchantRepo.FindByID(req.MerchantID)
+	if err != nil || merchant == nil {
+		return nil, errors.New("商户不存在")
+	}
+	if !operator.IsAdmin && merchant.AgentID != operator.AgentID {
+		return nil, errors.New("无权为此商户设置费率计划")
+	}
+
+	remindDays := ratePlanDefaultRemind
+	if req.RemindDays != nil {
+		remindDays = *req.RemindDays
+	}
+	if remindDays < 0 || remindDays > ratePlanMaxRemindDays {
+		return nil, fmt.Errorf("提醒天数范围为0-%d天", ratePlanMaxRemindDays)
+	}
+
+	now := time.Now()
+	steps, err := buildRatePlanSteps(req, merchant.CreditRate, merchant.DebitRate, now)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.planRepo.FindActiveByMerchant(merchant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率计划失败: %w", err)
+	}
+	if existing != nil {
+		return nil, errors.New("该商户已有执行中的费率计划，请先取消")
+	}
+
+	// 每个步骤都须在通道底价/上限内；低于政策费率的步骤只能由平台设置（恢复为当前费率的步骤除外）
+	limits, err := s.rateChangeService.loadRateLimits(merchant)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		approval, err := checkRateChangeLimits(parseRate(step.CreditRate), parseRate(step.DebitRate), limits)
+		if err != nil {
+			return nil, fmt.Errorf("第%d步: %w", step.Seq, err)
+		}
+		unchanged := step.CreditRate == formatMerchantRate(parseRate(merchant.CreditRate)) &&
+			step.DebitRate == formatMerchantRate(parseRate(merchant.DebitRate))
+		if approval != "" && !operator.IsAdmin && !unchanged {
+			return nil, fmt.Errorf("第%d步%s，请联系平台设置", step.Seq, approval)
+		}
+	}
+
+	name := req.Name
+	if name == "" {
+		name = models.GetRatePlanTypeName(req.PlanType)
+	}
+	plan := &models.MerchantRatePlan{
+		PlanNo:        fmt.Sprintf("RP%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		PlanType:      req.PlanType,
+		Name:          name,
+		MerchantID:    merchant.ID,
+		MerchantNo:    merchant.MerchantNo,
+		MerchantName:  merchant.MerchantName,
+		ChannelID:     merchant.ChannelID,
+		AgentID:       merchant.AgentID,
+		RemindDays:    remindDays,
+		Status:        models.RatePlanStatusActive,
+		Remark:        req.Remark,
+		CreatedBy:     operator.UserID,
+		CreatedByName: operator.Name,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.planRepo.CreateWithSteps(plan, steps); err != nil {
+		return nil, fmt.Errorf("创建费率计划失败: %w", err)
+	}
+
+	if _, err := s.processPlan(plan.ID, now); err != nil {
+		log.Printf("[MerchantRatePlanService] Process plan %s failed: %v", plan.PlanNo, err)
+	}
+	return s.GetPlan(plan.ID, operator)
+}
+
+// CancelPlan 取消费率计划，未执行的步骤不再执行（已执行的费率不回退）
+func (s *MerchantRatePlanService) CancelPlan(id int64, operator *RateChangeOperator) (*RatePlanDetail, error) {
+	plan, err := s.planRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率计划失败: %w", err)
+	}
+	if plan == nil {
+		return nil, errors.New("费率计划不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin).canAccess(plan.AgentID) {
+		return nil, errors.New("无权取消此费率计划")
+	}
+	if plan.Status != models.RatePlanStatusActive {
+		return nil, errors.New("仅执行中的费率计划可取消")
+	}
+
+	now := time.Now()
+	plan.Status = models.RatePlanStatusCancelled
+	plan.CancelledBy = &operator.UserID
+	plan.CancelledByName = operator.Name
+	plan.CancelledAt = &now
+	if err := s.planRepo.CancelPlan(plan); err != nil {
+		return nil, fmt.Errorf("取消费率计划失败: %w", err)
+	}
+	return s.GetPlan(plan.ID, operator)
+}
+
+// buildRatePlanSteps 根据请求生成计划步骤：限时优惠生成优惠和恢复两个步骤，阶梯调整按时间顺序校验
+func buildRatePlanSteps(req *CreateRatePlanRequest, currentCredit, currentDebit string, now time.Time) ([]*models.MerchantRatePlanStep, error) {
+	var inputs []RatePlanStepInput
+	revertSeq := 0
+
+	switch req.PlanType {
+	case models.RatePlanTypePromo:
+		if req.EndAt == nil {
+			return nil, errors.New("请设置优惠结束时间")
+		}
+		startAt := now
+		if req.StartAt != nil {
+			startAt = *req.StartAt
+		}
+		revertCredit, revertDebit := req.RevertCreditRate, req.RevertDebitRate
+		if revertCredit == 0 {
+			revertCredit = parseRate(currentCredit)
+		}
+		if revertDebit == 0 {
+			revertDebit = parseRate(currentDebit)
+		}
+		inputs = []RatePlanStepInput{
+			{EffectiveAt: startAt, CreditRate: req.PromoCreditRate, DebitRate: req.PromoDebitRate},
+			{EffectiveAt: *req.EndAt, CreditRate: revertCredit, DebitRate: revertDebit},
+		}
+		revertSeq = 2
+	case models.RatePlanTypeSteps:
+		if len(req.Steps) == 0 {
+			return nil, errors.New("请至少设置一个费率步骤")
+		}
+		if len(req.Steps) > ratePlanMaxSteps {
+			return nil, fmt.Errorf("费率步骤最多%d个", ratePlanMaxSteps)
+		}
+		inputs = req.Steps
+	default:
+		return nil, errors.New("无效的计划类型")
+	}
+
+	steps := make([]*models.MerchantRatePlanStep, 0, len(inputs))
+	var prev time.Time
+	for i, input := range inputs {
+		seq := i + 1
+		if input.CreditRate <= 0 || input.CreditRate > 0.1 {
+			return nil, fmt.Errorf("第%d步贷记卡费率范围无效", seq)
+		}
+		if input.DebitRate <= 0 || input.DebitRate > 0.1 {
+			return nil, fmt.Errorf("第%d步借记卡费率范围无效", seq)
+		}
+
+		effectiveAt := input.EffectiveAt
+		if effectiveAt.Before(now) {
+			// 只有首个步骤可以立即生效
+			if i > 0 || now.Sub(effectiveAt) > ratePlanImmediateSlack {
+				return nil, fmt.Errorf("第%d步生效时间不能早于当前时间", seq)
+			}
+			effectiveAt = now
+		}
+		if i > 0 && !effectiveAt.After(prev) {
+			return nil, fmt.Errorf("第%d步生效时间须晚于第%d步", seq, seq-1)
+		}
+		prev = effectiveAt
+
+		steps = append(steps, &models.MerchantRatePlanStep{
+			Seq:         seq,
+			EffectiveAt: effectiveAt,
+			CreditRate:  formatMerchantRate(input.CreditRate),
+			DebitRate:   formatMerchantRate(input.DebitRate),
+			IsRevert:    seq == revertSeq,
+			Status:      models.RatePlanStepPending,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+	return steps, nil
+}
+
+// ============================================================
+// 定时执行
+// ============================================================
+
+// RatePlanRunResult 费率计划执行结果
+type RatePlanRunResult struct {
+	Plans     int `json:"plans"`
+	Applied   int `json:"applied"`   // 已生效步骤数
+	Failed    int `json:"failed"`    // 执行失败步骤数
+	Completed int `json:"completed"` // 已完成计划数
+	Reminded  int `json:"reminded"`  // 已提醒步骤数
+}
+
+// Run 执行到期步骤、跟踪执行中步骤的同步结果，并提醒即将调整的步骤
+func (s *MerchantRatePlanService) Run(now time.Time, limit int) (*RatePlanRunResult, error) {
+	result := &RatePlanRunResult{}
+
+	planIDs, err := s.planRepo.FindActivePlanIDsWithOpenSteps(now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询待执行费率计划失败: %w", err)
+	}
+	for _, planID := range planIDs {
+		planResult, err := s.processPlan(planID, now)
+		if err != nil {
+			log.Printf("[MerchantRatePlanService] Process plan %d failed: %v", planID, err)
+			continue
+		}
+		result.Plans++
+		result.Applied += planResult.Applied
+		result.Failed += planResult.Failed
+		result.Completed += planResult.Completed
+	}
+
+	reminded, err := s.remindSteps(now, limit)
+	if err != nil {
+		return nil, err
+	}
+	result.Reminded = reminded
+	return result, nil
+}
+
+// processPlan 按顺序推进计划：前一步骤结束后才执行下一步骤，全部结束后计划完成
+func (s *MerchantRatePlanService) processPlan(planID int64, now time.Time) (*RatePlanRunResult, error) {
+	result := &RatePlanRunResult{}
+	plan, err := s.planRepo.FindByID(planID)
+	if err != nil || plan == nil || plan.Status != models.RatePlanStatusActive {
+		return result, err
+	}
+	steps, err := s.planRepo.FindSteps(plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		if step.IsFinished() {
+			continue
+		}
+		if step.Status == models.RatePlanStepPending {
+			if step.EffectiveAt.After(now) {
+				break
+			}
+			s.executeStep(plan, step, now)
+		}
+		if step.Status == models.RatePlanStepExecuting {
+			s.trackStep(plan, step, now)
+		}
+		if !step.IsFinished() {
+			break
+		}
+		if step.Status == models.RatePlanStepApplied {
+			result.Applied++
+		} else {
+			result.Failed++
+		}
+	}
+
+	if len(steps) > 0 && steps[len(steps)-1].IsFinished() {
+		plan.Status = models.RatePlanStatusCompleted
+		plan.CompletedAt = &now
+		if err := s.planRepo.SavePlan(plan); err != nil {
+			return nil, err
+		}
+		result.Completed++
+	}
+	return result, nil
+}
+
+// executeStep 通过费率修改申请执行步骤；商户有未完成的费率修改申请时等待下次执行
+func (s *MerchantRatePlanService) executeStep(plan *models.MerchantRatePlan, step *models.MerchantRatePlanStep, now time.Time) {
+	merchant, err := s.merchantRepo.FindByID(plan.MerchantID)
+	if err != nil || merchant == nil {
+		s.finishStep(plan, step, models.RatePlanStepFailed, "商户不存在", now)
+		return
+	}
+	if step.CreditRate == formatMerchantRate(parseRate(merchant.CreditRate)) &&
+		step.DebitRate == formatMerchantRate(parseRate(merchant.DebitRate)) {
+		s.finishStep(plan, step, models.RatePlanStepApplied, "费率未变化，无需调整", now)
+		return
+	}
+
+	open, err := s.changeRepo.ExistsOpenByMerchant(merchant.ID)
+	if err != nil {
+		log.Printf("[MerchantRatePlanService] Check open requests for merchant %d failed: %v", merchant.ID, err)
+		return
+	}
+	if open {
+		if step.Message == "" {
+			step.Message = "商户有未完成的费率修改申请，等待其完成后执行"
+			s.saveStep(step)
+		}
+		return
+	}
+
+	reason := fmt.Sprintf("费率计划%s第%d步", plan.PlanNo, step.Seq)
+	if step.IsRevert {
+		reason = fmt.Sprintf("费率计划%s优惠到期恢复", plan.PlanNo)
+	}
+	change, err := s.rateChangeService.CreateRequest(&CreateRateChangeRequest{
+		MerchantID: merchant.ID,
+		CreditRate: parseRate(step.CreditRate),
+		DebitRate:  parseRate(step.DebitRate),
+		Reason:     reason,
+	}, &RateChangeOperator{Name: ratePlanOperatorName, IsAdmin: true})
+	if err != nil {
+		s.finishStep(plan, step, models.RatePlanStepFailed, err.Error(), now)
+		return
+	}
+
+	step.ChangeRequestID = change.ID
+	step.Status = models.RatePlanStepExecuting
+	step.Message = change.SyncMessage
+	s.applyChangeStatus(plan, step, change, now)
+}
+
+// trackStep 跟踪执行中步骤的费率修改申请结果
+func (s *MerchantRatePlanService) trackStep(plan *models.MerchantRatePlan, step *models.MerchantRatePlanStep, now time.Time) {
+	change, err := s.changeRepo.FindByID(step.ChangeRequestID)
+	if err != nil {
+		log.Printf("[MerchantRatePlanService] Find change request %d failed: %v", step.ChangeRequestID, err)
+		return
+	}
+	if change == nil {
+		s.finishStep(plan, step, models.RatePlanStepFailed, "费率修改申请不存在", now)
+		return
+	}
+	s.applyChangeStatus(plan, step, change, now)
+}
+
+// applyChangeStatus 根据费率修改申请状态更新步骤：生效即完成，同步失败、取消或驳回即失败，其余继续等待
+func (s *MerchantRatePlanService) applyChangeStatus(plan *models.MerchantRatePlan, step *models.MerchantRatePlanStep, change *models.MerchantRateChangeRequest, now time.Time) {
+	switch change.Status {
+	case models.RateChangeStatusApplied:
+		s.finishStep(plan, step, models.RatePlanStepApplied, change.SyncMessage, now)
+	case models.RateChangeStatusSyncFailed, models.RateChangeStatusCancelled, models.RateChangeStatusRejected:
+		message := change.SyncMessage
+		if message == "" {
+			message = "费率修改申请" + models.GetRateChangeStatusName(change.Status)
+		}
+		s.finishStep(plan, step, models.RatePlanStepFailed, message, now)
+	default:
+		s.saveStep(step)
+	}
+}
+
+// finishStep 结束步骤并通知
+func (s *MerchantRatePlanService) finishStep(plan *models.MerchantRatePlan, step *models.MerchantRatePlanStep, status int16, message string, now time.Time) {
+	step.Status = status
+	step.Message = message
+	step.ExecutedAt = &now
+	s.saveStep(step)
+
+	rateText := fmt.Sprintf("贷记卡%s%%、借记卡%s%%", ratePercentText(step.CreditRate), ratePercentText(step.DebitRate))
+	if status == models.RatePlanStepFailed {
+		s.notifyAgent(plan.AgentID, "费率计划执行失败",
+			fmt.Sprintf("商户[%s]费率计划%s第%d步（调整为%s）执行失败：%s。", plan.MerchantNo, plan.PlanNo, step.Seq, rateText, message))
+		return
+	}
+
+	title, content := "商户费率已调整", fmt.Sprintf("商户[%s]费率已按计划调整为%s。", plan.MerchantNo, rateText)
+	if step.IsRevert {
+		title, content = "优惠费率已到期", fmt.Sprintf("商户[%s]优惠费率已到期，已恢复为%s。", plan.MerchantNo, rateText)
+	}
+	s.notifyAgent(plan.AgentID, title, content)
+	s.notifyMerchant(plan.MerchantID, title, content)
+}
+
+// remindSteps 提醒即将执行的步骤
+func (s *MerchantRatePlanService) remindSteps(now time.Time, limit int) (int, error) {
+	steps, err := s.planRepo.FindStepsToRemind(now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("查询待提醒费率计划步骤失败: %w", err)
+	}
+
+	reminded := 0
+	for _, step := range steps {
+		plan, err := s.planRepo.FindByID(step.PlanID)
+		if err != nil || plan == nil {
+			continue
+		}
+
+		rateText := fmt.Sprintf("贷记卡%s%%、借记卡%s%%", ratePercentText(step.CreditRate), ratePercentText(step.DebitRate))
+		effective := step.EffectiveAt.Format("2006-01-02 15:04")
+		title, content := "商户费率即将调整", fmt.Sprintf("商户[%s]费率将于%s按计划调整为%s。", plan.MerchantNo, effective, rateText)
+		if step.IsRevert {
+			title, content = "优惠费率即将到期", fmt.Sprintf("商户[%s]优惠费率将于%s到期，届时恢复为%s。", plan.MerchantNo, effective, rateText)
+		}
+		s.notifyAgent(plan.AgentID, title, content)
+		s.notifyMerchant(plan.MerchantID, title, content)
+
+		step.RemindedAt = &now
+		s.saveStep(step)
+		reminded++
+	}
+	return reminded, nil
+}
+
+// saveStep 保存步骤，失败只记录日志
+func (s *MerchantRatePlanService) saveStep(step *models.MerchantRatePlanStep) {
+	if err := s.planRepo.SaveStep(step); err != nil {
+		log.Printf("[MerchantRatePlanService] Save step %d failed: %v", step.ID, err)
+	}
+}
+
+// ============================================================
+// 查询
+// ============================================================
+
+// RatePlanStepItem 费率计划步骤
+type RatePlanStepItem struct {
+	*models.MerchantRatePlanStep
+	StatusName string `json:"status_name"`
+}
+
+// RatePlanDetail 费率计划详情
+type RatePlanDetail struct {
+	*models.MerchantRatePlan
+	PlanTypeName string              `json:"plan_type_name"`
+	StatusName   string              `json:"status_name"`
+	Steps        []*RatePlanStepItem `json:"steps"`
+	NextStep     *RatePlanStepItem   `json:"next_step"` // 下一个待执行步骤
+}
+
+// ListPlans 费率计划列表，代理商仅查看本人及下级商户的计划
+func (s *MerchantRatePlanService) ListPlans(filter *repository.RatePlanFilter, operator *RateChangeOperator, page, pageSize int) ([]*RatePlanDetail, int64, error) {
+	if !operator.IsAdmin {
+		agent, err := s.agentRepo.FindByIDFull(operator.AgentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		filter.ScopeAgentID = agent.ID
+	}
+
+	plans, total, err := s.planRepo.List(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询费率计划失败: %w", err)
+	}
+
+	list := make([]*RatePlanDetail, 0, len(plans))
+	for _, plan := range plans {
+		detail, err := s.toDetail(plan)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, detail)
+	}
+	return list, total, nil
+}
+
+// GetPlan 费率计划详情
+func (s *MerchantRatePlanService) GetPlan(id int64, operator *RateChangeOperator) (*RatePlanDetail, error) {
+	plan, err := s.planRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率计划失败: %w", err)
+	}
+	if plan == nil {
+		return nil, errors.New("费率计划不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin).canAccess(plan.AgentID) {
+		return nil, errors.New("无权查看此费率计划")
+	}
+	return s.toDetail(plan)
+}
+
+// GetActivePlan 商户执行中的费率计划，没有时返回nil（用于商户详情展示）
+func (s *MerchantRatePlanService) GetActivePlan(merchantID int64) (*RatePlanDetail, error) {
+	plan, err := s.planRepo.FindActiveByMerchant(merchantID)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return s.toDetail(plan)
+}
+
+// toDetail 组装计划详情
+func (s *MerchantRatePlanService) toDetail(plan *models.MerchantRatePlan) (*RatePlanDetail, error) {
+	steps, err := s.planRepo.FindSteps(plan.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询费率计划步骤失败: %w", err)
+	}
+
+	detail := &RatePlanDetail{
+		MerchantRatePlan: plan,
+		PlanTypeName:     models.GetRatePlanTypeName(plan.PlanType),
+		StatusName:       models.GetRatePlanStatusName(plan.Status),
+		Steps:            make([]*RatePlanStepItem, 0, len(steps)),
+	}
+	for _, step := range steps {
+		item := &RatePlanStepItem{
+			MerchantRatePlanStep: step,
+			StatusName:           models.GetRatePlanStepStatusName(step.Status),
+		}
+		detail.Steps = append(detail.Steps, item)
+		if detail.NextStep == nil && step.Status == models.RatePlanStepPending {
+			detail.NextStep = item
+		}
+	}
+	return detail, nil
+}
+
+// ============================================================
+// 通知
+// ============================================================
+
+// notifyAgent 发送费率计划消息给代理商
+func (s *MerchantRatePlanService) notifyAgent(agentID int64, title, content string) {
+	if s.messageService == nil || agentID == 0 {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeRatePlan,
+		Title:       title,
+		Content:     content,
+		RelatedType: "merchant_rate_plan",
+	}); err != nil {
+		log.Printf("[MerchantRatePlanService] Send notification to agent %d failed: %v", agentID, err)
+	}
+}
+
+// notifyMerchant 通知商户
+func (s *MerchantRatePlanService) notifyMerchant(merchantID int64, title, content string) {
+	if s.merchantNotifier == nil {
+		return
+	}
+	merchant, err := s.merchantRepo.FindByID(merchantID)
+	if err != nil || merchant == nil {
+		return
+	}
+	if err := s.merchantNotifier.NotifyMerchant(merchant, title, content); err != nil {
+		log.Printf("[MerchantRatePlanService] Notify merchant %d failed: %v", merchantID, err)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+)
+
+func TestBuildRatePlanStepsPromo(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.Local)
+	endAt := now.AddDate(0, 0, 60)
+	req := &CreateRatePlanRequest{
+		PlanType:        models.RatePlanTypePromo,
+		PromoCreditRate: 0.0038,
+		PromoDebitRate:  0.0035,
+		EndAt:           &endAt,
+	}
+
+	steps, err := buildRatePlanSteps(req, "0.0060", "0.0055", now)
+	if err != nil {
+		t.Fatalf("buildRatePlanSteps error: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("len(steps) = %d, want 2", len(steps))
+	}
+	if !steps[0].EffectiveAt.Equal(now) || steps[0].CreditRate != "0.0038" || steps[0].IsRevert {
+		t.Errorf("优惠步骤错误: %+v", steps[0])
+	}
+	if !steps[1].EffectiveAt.Equal(endAt) || steps[1].CreditRate != "0.0060" || steps[1].DebitRate != "0.0055" || !steps[1].IsRevert {
+		t.Errorf("恢复步骤应恢复为当前费率: %+v", steps[1])
+	}
+
+	req.RevertCreditRate = 0.0058
+	steps, err = buildRatePlanSteps(req, "0.0060", "0.0055", now)
+	if err != nil || steps[1].CreditRate != "0.0058" || steps[1].DebitRate != "0.0055" {
+		t.Errorf("指定恢复费率错误: %+v, %v", steps, err)
+	}
+}
+
+func TestBuildRatePlanStepsInvalid(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.Local)
+	past := now.Add(-time.Hour)
+	later := now.Add(24 * time.Hour)
+
+	tests := []struct {
+		name string
+		req  *CreateRatePlanRequest
+	}{
+		{"优惠未设置结束时间", &CreateRatePlanRequest{PlanType: models.RatePlanTypePromo, PromoCreditRate: 0.004, PromoDebitRate: 0.004}},
+		{"优惠结束早于开始", &CreateRatePlanRequest{PlanType: models.RatePlanTypePromo, PromoCreditRate: 0.004, PromoDebitRate: 0.004, StartAt: &later, EndAt: &later}},
+		{"优惠费率无效", &CreateRatePlanRequest{PlanType: models.RatePlanTypePromo, PromoCreditRate: 0, PromoDebitRate: 0.004, EndAt: &later}},
+		{"未设置步骤", &CreateRatePlanRequest{PlanType: models.RatePlanTypeSteps}},
+		{"步骤时间未递增", &CreateRatePlanRequest{PlanType: models.RatePlanTypeSteps, Steps: []RatePlanStepInput{
+			{EffectiveAt: later, CreditRate: 0.005, DebitRate: 0.005},
+			{EffectiveAt: later, CreditRate: 0.006, DebitRate: 0.005},
+		}}},
+		{"首步早于当前过久", &CreateRatePlanRequest{PlanType: models.RatePlanTypeSteps, Steps: []RatePlanStepInput{
+			{EffectiveAt: past, CreditRate: 0.005, DebitRate: 0.005},
+		}}},
+		{"无效类型", &CreateRatePlanRequest{PlanType: "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildRatePlanSteps(tt.req, "0.0060", "0.0055", now); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestBuildRatePlanStepsImmediateFirstStep(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.Local)
+	req := &CreateRatePlanRequest{PlanType: models.RatePlanTypeSteps, Steps: []RatePlanStepInput{
+		{EffectiveAt: now.Add(-10 * time.Second), CreditRate: 0.0045, DebitRate: 0.0045},
+		{EffectiveAt: now.AddDate(0, 1, 0), CreditRate: 0.0052, DebitRate: 0.0050},
+		{EffectiveAt: now.AddDate(0, 3, 0), CreditRate: 0.0060, DebitRate: 0.0055},
+	}}
+
+	steps, err := buildRatePlanSteps(req, "0.0060", "0.0055", now)
+	if err != nil {
+		t.Fatalf("buildRatePlanSteps error: %v", err)
+	}
+	if !steps[0].EffectiveAt.Equal(now) {
+		t.Errorf("首步应立即生效, got %v", steps[0].EffectiveAt)
+	}
+	for i, step := range steps {
+		if step.Seq != i+1 || step.IsRevert || step.Status != models.RatePlanStepPending {
+			t.Errorf("步骤%d错误: %+v", i+1, step)
+		}
+	}
+}
@@ -14,11 +14,14 @@ import (
 
 // MerchantService 商户服务
 type MerchantService struct {
-	merchantRepo    *repository.GormMerchantRepository
-	agentRepo       *repository.GormAgentRepository
-	transactionRepo *repository.GormTransactionRepository
-	terminalRepo    *repository.GormTerminalRepository
-	rateSyncService *RateSyncService
+	merchantRepo      *repository.GormMerchantRepository
+	agentRepo         *repository.GormAgentRepository
+	transactionRepo   *repository.GormTransactionRepository
+	terminalRepo      *repository.GormTerminalRepository
+	rateSyncService   *RateSyncService
+	classService      *MerchantClassService
+	rateChangeService *MerchantRateChangeService
+	ratePlanService   *MerchantRatePlanService
 }
 
 // NewMerchantService 创建商户服务
@@ -41,6 +44,21 @@ func (s *MerchantService) SetRateSyncService(rateSyncService *RateSyncService) {
 	s.rateSyncService = rateSyncService
 }
 
+// SetRateChangeService 设置费率修改申请服务，设置后修改费率走申请流程（超出政策需审批、同步失败自动重试）
+func (s *MerchantService) SetRateChangeService(rateChangeService *MerchantRateChangeService) {
+	s.rateChangeService = rateChangeService
+}
+
+// SetRatePlanService 设置商户费率计划服务，设置后商户详情展示执行中的费率计划
+func (s *MerchantService) SetRatePlanService(ratePlanService *MerchantRatePlanService) {
+	s.ratePlanService = ratePlanService
+}
+
+// SetClassService 设置商户分类规则服务，设置后商户类型按配置的分类规则计算
+func (s *MerchantService) SetClassService(classService *MerchantClassService) {
+	s.classService = classService
+}
+
 // ==================== 请求/响应结构体 ====================
 
 // CreateMerchantRequest 创建商户请求
@@ -111,10 +129,14 @@ type MerchantDetailResponse struct {
 	ActivatedAt     *time.Time `json:"activated_at"`
 	RegisteredPhone string     `json:"registered_phone"`
 	RegisterRemark  string     `json:"register_remark"`
+	IDCardEndDate   *time.Time `json:"id_card_end_date"`  // 法人身份证有效期结束
+	IDCardLongTerm  bool       `json:"id_card_long_term"` // 法人身份证长期有效
 	// 统计数据
 	MonthAmount     int64      `json:"month_amount"`
 	MonthCount      int64      `json:"month_count"`
 	TerminalCount   int        `json:"terminal_count"`
+	// 执行中的费率计划
+	RatePlan        *RatePlanDetail `json:"rate_plan,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
@@ -208,6 +230,10 @@ type RateUpdateResult struct {
 // UpdateRate 修改费率（校验费率范围，并同步到通道）
 // 返回更新结果，包含本地更新和通道同步状态
 func (s *MerchantService) UpdateRate(id int64, agentID int64, creditRate, debitRate float64) (*RateUpdateResult, error) {
+	if s.rateChangeService != nil {
+		return s.updateRateByRequest(id, agentID, creditRate, debitRate)
+	}
+
 	merchant, err := s.merchantRepo.FindByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("商户不存在: %w", err)
@@ -283,6 +309,30 @@ func (s *MerchantService) UpdateRate(id int64, agentID int64, creditRate, debitR
 	return result, nil
 }
 
+// updateRateByRequest 通过费率修改申请修改费率
+func (s *MerchantService) updateRateByRequest(id int64, agentID int64, creditRate, debitRate float64) (*RateUpdateResult, error) {
+	change, err := s.rateChangeService.CreateRequest(&CreateRateChangeRequest{
+		MerchantID: id,
+		CreditRate: creditRate,
+		DebitRate:  debitRate,
+	}, &RateChangeOperator{AgentID: agentID})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RateUpdateResult{Success: true}
+	switch change.Status {
+	case models.RateChangeStatusApplied:
+		result.SyncSuccess = true
+		result.SyncMessage = "费率更新成功"
+	case models.RateChangeStatusPending:
+		result.SyncMessage = "超出政策限制，已提交上级审批"
+	default:
+		result.SyncMessage = change.SyncMessage
+	}
+	return result, nil
+}
+
 // syncRateToChannel 同步费率到通道（同步调用）
 func (s *MerchantService) syncRateToChannel(merchant *models.Merchant, agentID int64, oldCreditRate, oldDebitRate, newCreditRate, newDebitRate float64) *SyncResult {
 	// 获取通道编码
@@ -401,7 +451,12 @@ func (s *MerchantService) DeleteMerchant(id int64, agentID int64) error {
 // 1. 先判断60天无交易 → churned（流失）
 // 2. 再判断30天无交易 → warning（预警）
 // 3. 有交易按金额分档：≥5万→quality，3-5万→medium，<3万→normal
+// 设置了分类规则服务时按管理员配置的规则集计算（未配置规则集的商户使用等价的内置默认规则）
 func (s *MerchantService) CalculateMerchantType(merchantID int64) (string, error) {
+	if s.classService != nil {
+		return s.classService.ClassifyMerchant(merchantID, time.Now())
+	}
+
 	endTime := time.Now()
 
 	// 先检查60天内是否有交易
@@ -455,6 +510,23 @@ func (s *MerchantService) CalculateMerchantType(merchantID int64) (string, error
 	return merchantType, nil
 }
 
+// CalculateMerchantTypes 批量计算商户类型，返回成功和失败数
+func (s *MerchantService) CalculateMerchantTypes(merchantIDs []int64) (success, fail int) {
+	if s.classService != nil {
+		return s.classService.ClassifyBatch(merchantIDs, time.Now())
+	}
+
+	for _, merchantID := range merchantIDs {
+		if _, err := s.CalculateMerchantType(merchantID); err != nil {
+			log.Printf("[MerchantService] Calculate type failed for merchant %d: %v", merchantID, err)
+			fail++
+			continue
+		}
+		success++
+	}
+	return success, fail
+}
+
 // GetMerchantDetail 获取商户详情（包含关联信息）
 func (s *MerchantService) GetMerchantDetail(id int64, agentID int64) (*MerchantDetailResponse, error) {
 	merchant, err := s.merchantRepo.FindByID(id)
@@ -511,12 +583,14 @@ func (s *MerchantService) GetMerchantDetail(id int64, agentID int64) (*MerchantD
 		CreditRate:        merchant.CreditRate,
 		DebitRate:         merchant.DebitRate,
 		MerchantType:      merchant.MerchantType,
-		MerchantTypeName:  getMerchantTypeName(merchant.MerchantType),
+		MerchantTypeName:  merchantTypeDisplayName(merchant),
 		IsDirect:          merchant.IsDirect,
 		OwnerType:         getOwnerType(merchant.IsDirect),
 		ActivatedAt:       merchant.ActivatedAt,
 		RegisteredPhone:   maskPhone(merchant.RegisteredPhone),
 		RegisterRemark:    merchant.RegisterRemark,
+		IDCardEndDate:     merchant.IDCardEndDate,
+		IDCardLongTerm:    merchant.IDCardLongTerm,
 		MonthAmount:       monthAmount,
 		MonthCount:        monthCount,
 		TerminalCount:     terminalCount,
@@ -524,6 +598,12 @@ func (s *MerchantService) GetMerchantDetail(id int64, agentID int64) (*MerchantD
 		UpdatedAt:         merchant.UpdatedAt,
 	}
 
+	if s.ratePlanService != nil {
+		if plan, err := s.ratePlanService.GetActivePlan(merchant.ID); err == nil {
+			resp.RatePlan = plan
+		}
+	}
+
 	return resp, nil
 }
 
@@ -575,6 +655,14 @@ func getMerchantTypeName(merchantType string) string {
 	}
 }
 
+// merchantTypeDisplayName 商户类型名称，优先使用分类规则的标签
+func merchantTypeDisplayName(merchant *models.Merchant) string {
+	if merchant.MerchantTypeLabel != "" {
+		return merchant.MerchantTypeLabel
+	}
+	return getMerchantTypeName(merchant.MerchantType)
+}
+
 func getOwnerType(isDirect bool) string {
 	if isDirect {
 		return "direct"
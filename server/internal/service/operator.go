@@ -0,0 +1,9 @@
+package service
+
+// Operator 当前操作人，由处理器从登录上下文构建
+type Operator struct {
+	UserID  int64
+	Name    string
+	AgentID int64
+	IsAdmin bool
+}
@@ -22,9 +22,9 @@ type ProfitService struct {
 	messageService    *MessageService
 	queue             async.MessageQueue
 	rateStagingService *RateStagingService // 费率阶梯服务
-	goodsDeductionService *GoodsDeductionService // 货款代扣服务（保留兼容）
 	deductionService *DeductionService // 统一代扣服务
 	settlementPriceService *SettlementPriceService // 结算价服务（用于获取高调/P+0配置）
+	txRiskService *TxRiskService // 交易风控服务
 }
 
 // NewProfitService 创建分润服务
@@ -55,11 +55,6 @@ func (s *ProfitService) SetRateStagingService(rss *RateStagingService) {
 	s.rateStagingService = rss
 }
 
-// SetGoodsDeductionService 设置货款代扣服务（延迟注入，避免循环依赖）
-func (s *ProfitService) SetGoodsDeductionService(gds *GoodsDeductionService) {
-	s.goodsDeductionService = gds
-}
-
 // SetDeductionService 设置统一代扣服务（延迟注入，避免循环依赖）
 func (s *ProfitService) SetDeductionService(ds *DeductionService) {
 	s.deductionService = ds
@@ -70,6 +65,11 @@ func (s *ProfitService) SetSettlementPriceService(sps *SettlementPriceService) {
 	s.settlementPriceService = sps
 }
 
+// SetTxRiskService 设置交易风控服务（延迟注入，避免循环依赖）
+func (s *ProfitService) SetTxRiskService(trs *TxRiskService) {
+	s.txRiskService = trs
+}
+
 // ProcessMessage 处理分润计算消息
 func (s *ProfitService) ProcessMessage(msgBytes []byte) error {
 	var msg ProfitMessage
@@ -232,32 +232,17 @@ func (s *ProfitService) CalculateProfit(txID int64) error {
 			}
 		}
 	}
-	// 【已停用】货款代扣实时扣款逻辑，统一使用代扣管理模块
-	// } else if s.goodsDeductionService != nil && len(profitRecords) > 0 {
-	// 	// 兼容旧的货款代扣服务
-	// 	for _, record := range profitRecords {
-	// 		deducted, err := s.goodsDeductionService.TriggerRealtimeDeduction(
-	// 			record.AgentID,
-	// 			record.ChannelID,
-	// 			int16(record.WalletType),
-	// 			record.ProfitAmount,
-	// 			"profit_income",
-	// 			&tx.ID,
-	// 			&record.ID,
-	// 		)
-	// 		if err != nil {
-	// 			log.Printf("[ProfitService] Trigger goods deduction failed for agent %d: %v", record.AgentID, err)
-	// 		} else if deducted > 0 {
-	// 			log.Printf("[ProfitService] Goods deduction triggered: agent=%d, deducted=%d", record.AgentID, deducted)
-	// 		}
-	// 	}
-	// }
 
 	// 8. 更新交易分润状态
 	if err := s.transactionRepo.UpdateProfitStatus(tx.ID, 1); err != nil {
 		return fmt.Errorf("update profit status failed: %w", err)
 	}
 
+	// 8.1 交易风控检测（命中高分规则时冻结本笔分润）
+	if s.txRiskService != nil {
+		s.txRiskService.EvaluateTransaction(tx, profitRecords)
+	}
+
 	// 9. 发送消息通知
 	s.sendProfitNotifications(profitRecords)
 
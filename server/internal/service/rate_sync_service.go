@@ -10,6 +10,9 @@ import (
 	"xiangshoufu/internal/repository"
 )
 
+// 费率修改申请同步失败后的最大尝试次数（含首次同步）
+const rateSyncRequestMaxRetries = 6
+
 // RateSyncService 费率同步服务
 type RateSyncService struct {
 	rateSyncRepo   repository.RateSyncLogRepository
@@ -40,6 +43,7 @@ type RateUpdateParams struct {
 	TerminalSN   string
 	ChannelCode  string
 	AgentID      int64
+	RequestID    int64 // 关联费率修改申请，非0时同步失败后自动重试
 	OldRates     *RateInfo
 	NewRates     *RateInfo
 }
@@ -85,8 +89,12 @@ func (s *RateSyncService) SyncRateToChannel(ctx context.Context, params *RateUpd
 		TerminalSN:  params.TerminalSN,
 		ChannelCode: params.ChannelCode,
 		AgentID:     params.AgentID,
+		RequestID:   params.RequestID,
 		SyncStatus:  models.RateSyncStatusSyncing,
 	}
+	if params.RequestID > 0 {
+		syncLog.MaxRetries = rateSyncRequestMaxRetries
+	}
 
 	// 设置原费率
 	if params.OldRates != nil {
@@ -176,6 +184,69 @@ func (s *RateSyncService) SyncRateToChannel(ctx context.Context, params *RateUpd
 	}, nil
 }
 
+// RetrySync 按同步日志中的新费率重试同步，成功或重试次数用完时发送通知
+func (s *RateSyncService) RetrySync(ctx context.Context, syncLog *models.RateSyncLog) (*SyncResult, error) {
+	if !syncLog.CanRetry() {
+		return nil, fmt.Errorf("同步日志%d不可重试", syncLog.ID)
+	}
+	adapter, err := s.adapterFactory.GetAdapter(syncLog.ChannelCode)
+	if err != nil {
+		return nil, fmt.Errorf("获取通道适配器失败: %w", err)
+	}
+
+	req := &channel.RateUpdateRequest{
+		MerchantNo: syncLog.MerchantNo,
+		TerminalSN: syncLog.TerminalSN,
+	}
+	if syncLog.NewCreditRate != nil {
+		req.CreditRate = *syncLog.NewCreditRate
+	}
+	if syncLog.NewDebitRate != nil {
+		req.DebitRate = *syncLog.NewDebitRate
+	}
+	if syncLog.NewDebitCap != nil {
+		req.DebitCap = *syncLog.NewDebitCap
+	}
+	if syncLog.NewWechatRate != nil {
+		req.WechatRate = *syncLog.NewWechatRate
+	}
+	if syncLog.NewAlipayRate != nil {
+		req.AlipayRate = *syncLog.NewAlipayRate
+	}
+	if syncLog.NewUnionpayRate != nil {
+		req.UnionpayRate = *syncLog.NewUnionpayRate
+	}
+
+	syncLog.MarkSyncing()
+	resp, err := adapter.UpdateMerchantRate(req)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	} else if !resp.Success {
+		errMsg = resp.Message
+	}
+
+	result := &SyncResult{LogID: syncLog.ID}
+	if errMsg != "" {
+		syncLog.MarkFailed(errMsg)
+		result.Message = fmt.Sprintf("通道同步失败: %s", errMsg)
+		if !syncLog.CanRetry() {
+			s.sendRateSyncNotification(syncLog.AgentID, syncLog.MerchantNo, false, errMsg)
+		}
+	} else {
+		syncLog.MarkSuccess(resp.TradeNo)
+		result.Success = true
+		result.TradeNo = resp.TradeNo
+		result.Message = "费率同步成功"
+		s.sendRateSyncNotification(syncLog.AgentID, syncLog.MerchantNo, true, "")
+	}
+
+	if updateErr := s.rateSyncRepo.Update(ctx, syncLog); updateErr != nil {
+		log.Printf("[RateSyncService] 更新同步日志失败: %v", updateErr)
+	}
+	return result, nil
+}
+
 // sendRateSyncNotification 发送费率同步结果通知
 func (s *RateSyncService) sendRateSyncNotification(agentID int64, merchantNo string, success bool, errMsg string) {
 	if s.messageService == nil {
@@ -395,6 +395,11 @@ func (s *RewardService) validateAgentRewardAmount(agent *repository.Agent, templ
 
 // InitTerminalRewardProgress 初始化终端奖励进度（终端绑定时调用）
 func (s *RewardService) InitTerminalRewardProgress(terminalSN string, terminalID *int64, agentID int64, templateID int64) (*models.TerminalRewardProgress, error) {
+	return s.InitTerminalRewardProgressAt(terminalSN, terminalID, agentID, templateID, time.Now())
+}
+
+// InitTerminalRewardProgressAt 按指定绑定时间初始化终端奖励进度（用于绑定事件及存量终端补建）
+func (s *RewardService) InitTerminalRewardProgressAt(terminalSN string, terminalID *int64, agentID int64, templateID int64, bindTime time.Time) (*models.TerminalRewardProgress, error) {
 	// 1. 检查是否已有进行中的进度
 	existing, err := s.progressRepo.FindActiveByTerminalSN(terminalSN)
 	if err == nil && existing != nil {
@@ -429,8 +434,6 @@ func (s *RewardService) InitTerminalRewardProgress(terminalSN string, terminalID
 		Stages:        stages,
 	}
 
-	bindTime := time.Now()
-
 	// 4. 创建进度记录
 	progress := &models.TerminalRewardProgress{
 		TerminalSN:        terminalSN,
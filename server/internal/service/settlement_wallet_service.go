@@ -17,6 +17,8 @@ type SettlementWalletService struct {
 	walletRepo     *repository.GormWalletRepository
 	walletLogRepo  *repository.GormWalletLogRepository
 	agentRepo      *repository.GormAgentRepository
+
+	riskHoldService *WalletRiskHoldService
 }
 
 // NewSettlementWalletService 创建沉淀钱包服务
@@ -36,6 +38,11 @@ func NewSettlementWalletService(
 	}
 }
 
+// SetRiskHoldService 设置风控冻结服务（可选注入）
+func (s *SettlementWalletService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
 // ========== 钱包配置 ==========
 
 // EnableSettlementWalletRequest 开通沉淀钱包请求
@@ -249,6 +256,13 @@ func (s *SettlementWalletService) UseSettlement(req *UseSettlementRequest) (*mod
 		return nil, fmt.Errorf("沉淀钱包未开通")
 	}
 
+	// 检查风控冻结
+	if s.riskHoldService != nil {
+		if err := s.riskHoldService.CheckSettlementUse(req.AgentID); err != nil {
+			return nil, err
+		}
+	}
+
 	// 获取可用沉淀额度
 	summary, err := s.GetSettlementWalletSummary(req.AgentID)
 	if err != nil {
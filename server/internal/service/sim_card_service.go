@@ -0,0 +1,987 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 流量卡批量处理上限
+const (
+	simCardBatchMaxSize      = 1000
+	simCardBatchMaxErrors    = 50
+	simRenewalPredictBatch   = 500
+	simRenewalRemindBatch    = 2000
+	simRenewalMessageMaxList = 5
+)
+
+// iccidPattern ICCID：89开头的19-20位数字，部分运营商末位为校验字母
+var iccidPattern = regexp.MustCompile(`^89[0-9]{16,18}[0-9A-Z]?$`)
+
+// SimCardService 流量卡服务
+// 管理流量卡库存及与终端的装卡/换卡记录；流量费按扣费时装在终端上的流量卡归属并累计该卡的缴费次数，
+// 按激活时间、缴费记录预测下次续费时间，到期前提醒终端所属代理商
+type SimCardService struct {
+	simRepo        *repository.GormSimCardRepository
+	terminalRepo   repository.TerminalRepository
+	agentRepo      repository.AgentRepository
+	hierarchyRepo  repository.HierarchyRepository
+	messageService *MessageService
+}
+
+// NewSimCardService 创建流量卡服务
+func NewSimCardService(
+	simRepo *repository.GormSimCardRepository,
+	terminalRepo repository.TerminalRepository,
+	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+) *SimCardService {
+	return &SimCardService{
+		simRepo:       simRepo,
+		terminalRepo:  terminalRepo,
+		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（续费提醒）
+func (s *SimCardService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// SimCardOperator 流量卡操作人
+type SimCardOperator struct {
+	UserID  int64
+	Name    string
+	AgentID int64
+	IsAdmin bool
+}
+
+// ============================================================
+// 配置
+// ============================================================
+
+// defaultSimCardConfig 默认续费预测及提醒配置
+func defaultSimCardConfig() *models.SimCardConfig {
+	return &models.SimCardConfig{
+		FirstFeeDays:     180,
+		RenewalCycleDays: 365,
+		RemindEnabled:    true,
+		RemindDaysBefore: 15,
+	}
+}
+
+// GetConfig 获取续费预测及提醒配置（未配置时返回默认值）
+func (s *SimCardService) GetConfig() (*models.SimCardConfig, error) {
+	config, err := s.simRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取流量卡配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultSimCardConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateSimCardConfigRequest 更新续费预测及提醒配置请求
+type UpdateSimCardConfigRequest struct {
+	FirstFeeDays     int  `json:"first_fee_days"`     // 首次扣费距激活天数
+	RenewalCycleDays int  `json:"renewal_cycle_days"` // 续费周期（天）
+	RemindEnabled    bool `json:"remind_enabled"`     // 是否提醒代理商
+	RemindDaysBefore int  `json:"remind_days_before"` // 提前提醒天数
+}
+
+// UpdateConfig 更新续费预测及提醒配置（新配置在下次缴费或重新预测时生效）
+func (s *SimCardService) UpdateConfig(req *UpdateSimCardConfigRequest, operatorID int64, operatorName string) (*models.SimCardConfig, error) {
+	if req.FirstFeeDays < 0 || req.FirstFeeDays > 1095 {
+		return nil, errors.New("首次扣费天数必须在0-1095之间")
+	}
+	if req.RenewalCycleDays < 1 || req.RenewalCycleDays > 1095 {
+		return nil, errors.New("续费周期必须在1-1095天之间")
+	}
+	if req.RemindDaysBefore < 0 || req.RemindDaysBefore > 90 {
+		return nil, errors.New("提前提醒天数必须在0-90之间")
+	}
+
+	config, err := s.simRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取流量卡配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.SimCardConfig{}
+	}
+	config.FirstFeeDays = req.FirstFeeDays
+	config.RenewalCycleDays = req.RenewalCycleDays
+	config.RemindEnabled = req.RemindEnabled
+	config.RemindDaysBefore = req.RemindDaysBefore
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+
+	if err := s.simRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存流量卡配置失败: %w", err)
+	}
+	return config, nil
+}
+
+// ============================================================
+// 流量卡库存
+// ============================================================
+
+// SimCardItem 流量卡信息
+type SimCardItem struct {
+	ICCID            string `json:"iccid" binding:"required"`
+	MSISDN           string `json:"msisdn"`
+	Carrier          string `json:"carrier"`            // cmcc/cucc/ctcc/other
+	ChannelID        int64  `json:"channel_id"`         // 所属通道
+	OwnerAgentID     int64  `json:"owner_agent_id"`     // 库存持有代理商，0为平台
+	ActivatedAt      string `json:"activated_at"`       // 开卡日期 yyyy-MM-dd
+	ExpireAt         string `json:"expire_at"`          // 卡有效期 yyyy-MM-dd
+	RenewalCycleDays int    `json:"renewal_cycle_days"` // 续费周期（天），0使用全局配置
+	TerminalSN       string `json:"terminal_sn"`        // 入库同时装卡的终端
+	Remark           string `json:"remark"`
+}
+
+// CreateSimCardsRequest 流量卡入库请求
+type CreateSimCardsRequest struct {
+	Cards []SimCardItem `json:"cards" binding:"required,min=1"`
+}
+
+// SimCardBatchResult 流量卡入库结果
+type SimCardBatchResult struct {
+	Total   int      `json:"total"`
+	Created int      `json:"created"`
+	Bound   int      `json:"bound"`            // 同时装卡数
+	Failed  int      `json:"failed"`           // 校验失败或重复
+	Errors  []string `json:"errors,omitempty"` // 失败原因（最多50条）
+}
+
+func (r *SimCardBatchResult) fail(msg string) {
+	r.Failed++
+	if len(r.Errors) < simCardBatchMaxErrors {
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// CreateCards 流量卡批量入库（仅管理员），ICCID重复或校验失败的跳过
+func (s *SimCardService) CreateCards(req *CreateSimCardsRequest, operator *SimCardOperator) (*SimCardBatchResult, error) {
+	if len(req.Cards) > simCardBatchMaxSize {
+		return nil, fmt.Errorf("单次最多入库%d张流量卡", simCardBatchMaxSize)
+	}
+
+	result := &SimCardBatchResult{Total: len(req.Cards)}
+	now := time.Now()
+	seen := make(map[string]bool, len(req.Cards))
+	sims := make([]*models.SimCard, 0, len(req.Cards))
+	bindSNs := make(map[string]string)
+	for i := range req.Cards {
+		item := &req.Cards[i]
+		iccid := normalizeICCID(item.ICCID)
+		sim, err := newSimCardFromItem(item, iccid, now)
+		if err != nil {
+			result.fail(fmt.Sprintf("%s: %v", item.ICCID, err))
+			continue
+		}
+		if seen[iccid] {
+			result.fail(fmt.Sprintf("%s: ICCID重复", iccid))
+			continue
+		}
+		seen[iccid] = true
+		sims = append(sims, sim)
+		if sn := strings.TrimSpace(item.TerminalSN); sn != "" {
+			bindSNs[iccid] = sn
+		}
+	}
+	if len(sims) == 0 {
+		return result, nil
+	}
+
+	iccids := make([]string, 0, len(sims))
+	for _, sim := range sims {
+		iccids = append(iccids, sim.ICCID)
+	}
+	existing, err := s.simRepo.FindExistingICCIDs(iccids)
+	if err != nil {
+		return nil, fmt.Errorf("查询已有流量卡失败: %w", err)
+	}
+	existed := make(map[string]bool, len(existing))
+	for _, iccid := range existing {
+		existed[iccid] = true
+	}
+	toCreate := sims[:0]
+	for _, sim := range sims {
+		if existed[sim.ICCID] {
+			result.fail(fmt.Sprintf("%s: 流量卡已存在", sim.ICCID))
+			continue
+		}
+		toCreate = append(toCreate, sim)
+	}
+	if len(toCreate) == 0 {
+		return result, nil
+	}
+
+	if err := s.simRepo.BatchCreate(toCreate); err != nil {
+		return nil, fmt.Errorf("流量卡入库失败: %w", err)
+	}
+	result.Created = len(toCreate)
+
+	for _, sim := range toCreate {
+		sn, ok := bindSNs[sim.ICCID]
+		if !ok {
+			continue
+		}
+		if _, err := s.bind(sim.ID, sn, models.SimBindSourceManual, "入库装卡", operator, now); err != nil {
+			result.fail(fmt.Sprintf("%s: 装卡失败: %v", sim.ICCID, err))
+			continue
+		}
+		result.Bound++
+	}
+
+	log.Printf("[SimCardService] Created %d sim cards, bound=%d, failed=%d", result.Created, result.Bound, result.Failed)
+	return result, nil
+}
+
+// newSimCardFromItem 校验并构造流量卡
+func newSimCardFromItem(item *SimCardItem, iccid string, now time.Time) (*models.SimCard, error) {
+	if !iccidPattern.MatchString(iccid) {
+		return nil, errors.New("ICCID格式错误")
+	}
+	carrier, err := normalizeSimCarrier(item.Carrier)
+	if err != nil {
+		return nil, err
+	}
+	if item.RenewalCycleDays < 0 || item.RenewalCycleDays > 1095 {
+		return nil, errors.New("续费周期必须在0-1095天之间")
+	}
+	activatedAt, err := parseSimDate(item.ActivatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("开卡日期%w", err)
+	}
+	expireAt, err := parseSimDate(item.ExpireAt)
+	if err != nil {
+		return nil, fmt.Errorf("有效期%w", err)
+	}
+
+	return &models.SimCard{
+		ICCID:            iccid,
+		MSISDN:           strings.TrimSpace(item.MSISDN),
+		Carrier:          carrier,
+		ChannelID:        item.ChannelID,
+		OwnerAgentID:     item.OwnerAgentID,
+		Status:           models.SimCardStatusStock,
+		ActivatedAt:      activatedAt,
+		ExpireAt:         expireAt,
+		RenewalCycleDays: item.RenewalCycleDays,
+		Remark:           item.Remark,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}, nil
+}
+
+// UpdateSimCardRequest 更新流量卡请求
+type UpdateSimCardRequest struct {
+	MSISDN           string `json:"msisdn"`
+	Carrier          string `json:"carrier"`
+	ChannelID        int64  `json:"channel_id"`
+	OwnerAgentID     int64  `json:"owner_agent_id"`     // 库存持有代理商（已装机的卡以终端归属为准）
+	ActivatedAt      string `json:"activated_at"`       // yyyy-MM-dd
+	ExpireAt         string `json:"expire_at"`          // yyyy-MM-dd
+	RenewalCycleDays int    `json:"renewal_cycle_days"` // 0使用全局配置
+	Disabled         bool   `json:"disabled"`           // 停用（须先拆卡）
+	Remark           string `json:"remark"`
+}
+
+// UpdateCard 更新流量卡信息（仅管理员），并重新预测续费时间
+func (s *SimCardService) UpdateCard(id int64, req *UpdateSimCardRequest) (*models.SimCard, error) {
+	sim, err := s.simRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	if sim == nil {
+		return nil, errors.New("流量卡不存在")
+	}
+
+	carrier, err := normalizeSimCarrier(req.Carrier)
+	if err != nil {
+		return nil, err
+	}
+	if req.RenewalCycleDays < 0 || req.RenewalCycleDays > 1095 {
+		return nil, errors.New("续费周期必须在0-1095天之间")
+	}
+	activatedAt, err := parseSimDate(req.ActivatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("开卡日期%w", err)
+	}
+	expireAt, err := parseSimDate(req.ExpireAt)
+	if err != nil {
+		return nil, fmt.Errorf("有效期%w", err)
+	}
+
+	switch {
+	case req.Disabled && sim.Status == models.SimCardStatusInstalled:
+		return nil, errors.New("流量卡已装机，请先拆卡再停用")
+	case req.Disabled:
+		sim.Status = models.SimCardStatusDisabled
+	case sim.Status == models.SimCardStatusDisabled:
+		sim.Status = models.SimCardStatusStock
+	}
+
+	sim.MSISDN = strings.TrimSpace(req.MSISDN)
+	sim.Carrier = carrier
+	sim.ChannelID = req.ChannelID
+	sim.OwnerAgentID = req.OwnerAgentID
+	sim.ActivatedAt = activatedAt
+	sim.ExpireAt = expireAt
+	sim.RenewalCycleDays = req.RenewalCycleDays
+	sim.Remark = req.Remark
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	sim.NextRenewalAt = predictSimRenewal(sim, config, s.terminalActivatedAt(sim))
+
+	if err := s.simRepo.Save(sim); err != nil {
+		return nil, fmt.Errorf("更新流量卡失败: %w", err)
+	}
+	return sim, nil
+}
+
+// SimCardListItem 流量卡列表项
+type SimCardListItem struct {
+	*models.SimCard
+	StatusName  string `json:"status_name"`
+	CarrierName string `json:"carrier_name"`
+}
+
+func newSimCardListItem(sim *models.SimCard) *SimCardListItem {
+	return &SimCardListItem{
+		SimCard:     sim,
+		StatusName:  models.GetSimCardStatusName(sim.Status),
+		CarrierName: models.GetSimCarrierName(sim.Carrier),
+	}
+}
+
+// ListCards 查询流量卡，代理商只能查看本人及下级持有或装在其终端上的流量卡
+func (s *SimCardService) ListCards(filter *repository.SimCardFilter, agentID int64, isAdmin bool, page, pageSize int) ([]*SimCardListItem, int64, error) {
+	var scopeAgentID int64
+	if !isAdmin {
+		agent, err := s.agentRepo.FindByID(agentID)
+		if err != nil || agent == nil {
+			return nil, 0, errors.New("代理商不存在")
+		}
+		scopeAgentID = agent.ID
+	}
+
+	sims, total, err := s.simRepo.List(filter, scopeAgentID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	items := make([]*SimCardListItem, 0, len(sims))
+	for _, sim := range sims {
+		items = append(items, newSimCardListItem(sim))
+	}
+	return items, total, nil
+}
+
+// ListRenewals 查询days天内（含已过期未缴费）预计续费的已装机流量卡
+func (s *SimCardService) ListRenewals(days int, agentID int64, isAdmin bool, page, pageSize int, now time.Time) ([]*SimCardListItem, int64, error) {
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+	until := now.AddDate(0, 0, days)
+	filter := &repository.SimCardFilter{
+		Status:       models.SimCardStatusInstalled,
+		RenewalUntil: &until,
+	}
+	return s.ListCards(filter, agentID, isAdmin, page, pageSize)
+}
+
+// SimCardDetail 流量卡详情
+type SimCardDetail struct {
+	*SimCardListItem
+	Bindings []*models.SimCardBinding `json:"bindings"` // 装卡/换卡记录（新到旧）
+}
+
+// GetCard 获取流量卡详情及装卡记录
+func (s *SimCardService) GetCard(id int64, agentID int64, isAdmin bool) (*SimCardDetail, error) {
+	sim, err := s.simRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	if sim == nil {
+		return nil, errors.New("流量卡不存在")
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, agentID, isAdmin).canAccess(s.simHolderAgentID(sim)) {
+		return nil, errors.New("无权查看该流量卡")
+	}
+
+	bindings, err := s.simRepo.FindBindings(sim.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询装卡记录失败: %w", err)
+	}
+	return &SimCardDetail{SimCardListItem: newSimCardListItem(sim), Bindings: bindings}, nil
+}
+
+// TerminalSimCards 终端的流量卡
+type TerminalSimCards struct {
+	TerminalSN string                   `json:"terminal_sn"`
+	Current    *SimCardListItem         `json:"current"`  // 当前装的流量卡，未装卡为null
+	Bindings   []*models.SimCardBinding `json:"bindings"` // 装卡/换卡记录（新到旧）
+}
+
+// GetTerminalCards 获取终端当前流量卡及换卡记录
+func (s *SimCardService) GetTerminalCards(terminalSN string, agentID int64, isAdmin bool) (*TerminalSimCards, error) {
+	terminal, err := s.terminalRepo.FindBySN(terminalSN)
+	if err != nil || terminal == nil {
+		return nil, fmt.Errorf("终端不存在: %s", terminalSN)
+	}
+	if !newTerminalAccessChecker(s.hierarchyRepo, agentID, isAdmin).canAccess(terminal.OwnerAgentID) {
+		return nil, errors.New("无权查看该终端")
+	}
+
+	result := &TerminalSimCards{TerminalSN: terminal.TerminalSN}
+	sim, err := s.simRepo.FindByTerminalSN(terminal.TerminalSN)
+	if err != nil {
+		return nil, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	if sim != nil {
+		result.Current = newSimCardListItem(sim)
+	}
+	if result.Bindings, err = s.simRepo.FindBindingsByTerminal(terminal.TerminalSN); err != nil {
+		return nil, fmt.Errorf("查询装卡记录失败: %w", err)
+	}
+	return result, nil
+}
+
+// ============================================================
+// 装卡/换卡
+// ============================================================
+
+// BindSimCardRequest 装卡请求，终端已装其他流量卡时视为换卡，原卡退回终端所属代理商库存
+type BindSimCardRequest struct {
+	ICCID      string `json:"iccid" binding:"required"`
+	TerminalSN string `json:"terminal_sn" binding:"required"`
+	Remark     string `json:"remark"`
+}
+
+// BindCard 将流量卡装到终端，代理商只能操作本人及下级的流量卡和终端
+func (s *SimCardService) BindCard(req *BindSimCardRequest, operator *SimCardOperator) (*models.SimCard, error) {
+	sim, err := s.simRepo.FindByICCID(normalizeICCID(req.ICCID))
+	if err != nil {
+		return nil, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	if sim == nil {
+		return nil, errors.New("流量卡不存在")
+	}
+	return s.bind(sim.ID, strings.TrimSpace(req.TerminalSN), models.SimBindSourceManual, req.Remark, operator, time.Now())
+}
+
+// UnbindCard 拆卡，流量卡退回终端所属代理商库存
+func (s *SimCardService) UnbindCard(id int64, remark string, operator *SimCardOperator) (*models.SimCard, error) {
+	var sim models.SimCard
+	err := s.simRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sim, id).Error; err != nil {
+			return errors.New("流量卡不存在")
+		}
+		if sim.Status != models.SimCardStatusInstalled {
+			return errors.New("流量卡未装机")
+		}
+		checker := newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin)
+		if !checker.canAccess(s.simHolderAgentID(&sim)) {
+			return errors.New("无权操作该流量卡")
+		}
+		return s.removeTx(tx, &sim, s.simHolderAgentID(&sim), remark, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[SimCardService] Sim card %s unbound by %d", sim.ICCID, operator.UserID)
+	return &sim, nil
+}
+
+// bind 装卡（事务内锁定流量卡），终端已装其他卡时原卡退回库存
+func (s *SimCardService) bind(simID int64, terminalSN, source, remark string, operator *SimCardOperator, at time.Time) (*models.SimCard, error) {
+	terminal, err := s.terminalRepo.FindBySN(terminalSN)
+	if err != nil || terminal == nil {
+		return nil, fmt.Errorf("终端不存在: %s", terminalSN)
+	}
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var sim models.SimCard
+	err = s.simRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sim, simID).Error; err != nil {
+			return errors.New("流量卡不存在")
+		}
+		if sim.Status == models.SimCardStatusDisabled {
+			return errors.New("流量卡已停用")
+		}
+		if sim.Status == models.SimCardStatusInstalled && sim.TerminalSN == terminal.TerminalSN {
+			return errors.New("流量卡已装在该终端")
+		}
+		if operator != nil {
+			checker := newTerminalAccessChecker(s.hierarchyRepo, operator.AgentID, operator.IsAdmin)
+			if !checker.canAccess(s.simHolderAgentID(&sim)) || !checker.canAccess(terminal.OwnerAgentID) {
+				return errors.New("无权操作该流量卡或终端")
+			}
+		}
+
+		// 原卡从其他终端拆下
+		if sim.Status == models.SimCardStatusInstalled {
+			if err := closeSimBindingTx(tx, sim.ID, at); err != nil {
+				return err
+			}
+		}
+
+		// 终端上的其他卡退回库存
+		var replaced []*models.SimCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("terminal_sn = ? AND status = ? AND id <> ?", terminal.TerminalSN, models.SimCardStatusInstalled, sim.ID).
+			Find(&replaced).Error; err != nil {
+			return fmt.Errorf("查询终端流量卡失败: %w", err)
+		}
+		for _, old := range replaced {
+			if err := s.removeTx(tx, old, terminal.OwnerAgentID, fmt.Sprintf("换卡：更换为%s", sim.ICCID), at); err != nil {
+				return err
+			}
+		}
+		if len(replaced) > 0 && source == models.SimBindSourceManual {
+			source = models.SimBindSourceSwap
+		}
+
+		binding := &models.SimCardBinding{
+			SimCardID:  sim.ID,
+			ICCID:      sim.ICCID,
+			TerminalID: terminal.ID,
+			TerminalSN: terminal.TerminalSN,
+			BoundAt:    at,
+			Source:     source,
+			Remark:     remark,
+		}
+		if operator != nil {
+			binding.OperatorID = operator.UserID
+			binding.OperatorName = operator.Name
+		}
+		if err := tx.Create(binding).Error; err != nil {
+			return fmt.Errorf("记录装卡失败: %w", err)
+		}
+
+		terminalID := terminal.ID
+		sim.TerminalID = &terminalID
+		sim.TerminalSN = terminal.TerminalSN
+		sim.Status = models.SimCardStatusInstalled
+		sim.OwnerAgentID = terminal.OwnerAgentID
+		if sim.ChannelID == 0 {
+			sim.ChannelID = terminal.ChannelID
+		}
+		sim.NextRenewalAt = predictSimRenewal(&sim, config, terminal.ActivatedAt)
+		sim.UpdatedAt = time.Now()
+		return tx.Save(&sim).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SimCardService] Sim card %s bound to terminal %s (%s)", sim.ICCID, terminal.TerminalSN, source)
+	return &sim, nil
+}
+
+// removeTx 拆卡并退回指定代理商库存
+func (s *SimCardService) removeTx(tx *gorm.DB, sim *models.SimCard, ownerAgentID int64, remark string, at time.Time) error {
+	if err := closeSimBindingTx(tx, sim.ID, at); err != nil {
+		return err
+	}
+	if remark != "" {
+		if err := tx.Model(&models.SimCardBinding{}).
+			Where("sim_card_id = ? AND unbound_at = ?", sim.ID, at).
+			Update("remark", truncateSimRemark(remark)).Error; err != nil {
+			return fmt.Errorf("记录拆卡备注失败: %w", err)
+		}
+	}
+
+	sim.TerminalID = nil
+	sim.TerminalSN = ""
+	sim.Status = models.SimCardStatusStock
+	sim.OwnerAgentID = ownerAgentID
+	sim.UpdatedAt = time.Now()
+	return tx.Save(sim).Error
+}
+
+// closeSimBindingTx 结束流量卡当前的装卡记录
+func closeSimBindingTx(tx *gorm.DB, simID int64, at time.Time) error {
+	if err := tx.Model(&models.SimCardBinding{}).
+		Where("sim_card_id = ? AND unbound_at IS NULL", simID).
+		Update("unbound_at", at).Error; err != nil {
+		return fmt.Errorf("结束装卡记录失败: %w", err)
+	}
+	return nil
+}
+
+// simHolderAgentID 流量卡当前归属代理商：已装机以终端归属为准，否则为库存持有代理商
+func (s *SimCardService) simHolderAgentID(sim *models.SimCard) int64 {
+	if sim.Status == models.SimCardStatusInstalled && sim.TerminalSN != "" {
+		if terminal, err := s.terminalRepo.FindBySN(sim.TerminalSN); err == nil && terminal != nil {
+			return terminal.OwnerAgentID
+		}
+	}
+	return sim.OwnerAgentID
+}
+
+// terminalActivatedAt 流量卡所在终端的激活时间
+func (s *SimCardService) terminalActivatedAt(sim *models.SimCard) *time.Time {
+	if sim.Status != models.SimCardStatusInstalled || sim.TerminalSN == "" {
+		return nil
+	}
+	terminal, err := s.terminalRepo.FindBySN(sim.TerminalSN)
+	if err != nil || terminal == nil {
+		return nil
+	}
+	return terminal.ActivatedAt
+}
+
+// ============================================================
+// 流量费归属
+// ============================================================
+
+// SimFeeAttribution 流量费的流量卡归属
+type SimFeeAttribution struct {
+	SimCard  *models.SimCard
+	Terminal *models.Terminal // 扣费时装有该卡的终端
+	FeeCount int              // 该卡第几次缴费
+}
+
+// AttributeFee 将流量费归属到流量卡并累计该卡缴费次数
+// 回调带ICCID时按ICCID（未登记的卡自动登记并装到回调终端），否则按扣费时终端所装的卡；无法归属时返回nil
+// 已归属过的流量费（重试）直接返回原归属，不重复累计
+func (s *SimCardService) AttributeFee(fee *models.DeviceFee, terminal *models.Terminal) (*SimFeeAttribution, error) {
+	chargedAt := fee.ChargingTime
+	if chargedAt.IsZero() {
+		chargedAt = fee.ReceivedAt
+	}
+
+	if fee.SimCardID != nil {
+		sim, err := s.simRepo.FindByID(*fee.SimCardID)
+		if err != nil || sim == nil {
+			return nil, fmt.Errorf("流量卡不存在: %d", *fee.SimCardID)
+		}
+		return &SimFeeAttribution{SimCard: sim, Terminal: s.holderAt(sim, terminal, chargedAt), FeeCount: fee.SimFeeCount}, nil
+	}
+
+	sim, err := s.findFeeSim(fee, terminal, chargedAt)
+	if err != nil || sim == nil {
+		return nil, err
+	}
+	holder := s.holderAt(sim, terminal, chargedAt)
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var feeCount int
+	err = s.simRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var locked models.SimCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, sim.ID).Error; err != nil {
+			return fmt.Errorf("锁定流量卡失败: %w", err)
+		}
+		feeCount = locked.FeeCount + 1
+		locked.FeeCount = feeCount
+		if locked.LastFeeAt == nil || chargedAt.After(*locked.LastFeeAt) {
+			locked.LastFeeAt = &chargedAt
+		}
+		locked.NextRenewalAt = predictSimRenewal(&locked, config, holder.ActivatedAt)
+		locked.UpdatedAt = time.Now()
+		if err := tx.Save(&locked).Error; err != nil {
+			return fmt.Errorf("更新流量卡缴费次数失败: %w", err)
+		}
+		*sim = locked
+
+		return tx.Model(&models.DeviceFee{}).Where("id = ?", fee.ID).Updates(map[string]interface{}{
+			"sim_card_id":   sim.ID,
+			"iccid":         sim.ICCID,
+			"sim_fee_count": feeCount,
+			"updated_at":    time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fee.SimCardID = &sim.ID
+	fee.ICCID = sim.ICCID
+	fee.SimFeeCount = feeCount
+	return &SimFeeAttribution{SimCard: sim, Terminal: holder, FeeCount: feeCount}, nil
+}
+
+// findFeeSim 查找流量费对应的流量卡
+func (s *SimCardService) findFeeSim(fee *models.DeviceFee, terminal *models.Terminal, chargedAt time.Time) (*models.SimCard, error) {
+	iccid := normalizeICCID(fee.ICCID)
+	if iccid == "" {
+		binding, err := s.simRepo.FindTerminalBindingAt(terminal.TerminalSN, chargedAt)
+		if err != nil {
+			return nil, fmt.Errorf("查询终端装卡记录失败: %w", err)
+		}
+		if binding == nil {
+			return nil, nil
+		}
+		return s.simRepo.FindByID(binding.SimCardID)
+	}
+
+	sim, err := s.simRepo.FindByICCID(iccid)
+	if err != nil {
+		return nil, fmt.Errorf("查询流量卡失败: %w", err)
+	}
+	if sim != nil {
+		return sim, nil
+	}
+
+	// 未登记的卡：登记并按扣费时间装到回调终端
+	now := time.Now()
+	sim = &models.SimCard{
+		ICCID:        iccid,
+		Carrier:      models.SimCarrierOther,
+		ChannelID:    terminal.ChannelID,
+		OwnerAgentID: terminal.OwnerAgentID,
+		Status:       models.SimCardStatusStock,
+		Remark:       "流量费回调登记",
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.simRepo.BatchCreate([]*models.SimCard{sim}); err != nil {
+		return nil, fmt.Errorf("登记流量卡失败: %w", err)
+	}
+	return s.bind(sim.ID, terminal.TerminalSN, models.SimBindSourceCallback, "流量费回调登记", nil, chargedAt)
+}
+
+// holderAt 扣费时装有流量卡的终端，查不到装卡记录时为回调终端
+func (s *SimCardService) holderAt(sim *models.SimCard, terminal *models.Terminal, at time.Time) *models.Terminal {
+	binding, err := s.simRepo.FindBindingAt(sim.ID, at)
+	if err != nil || binding == nil || binding.TerminalSN == terminal.TerminalSN {
+		return terminal
+	}
+	holder, err := s.terminalRepo.FindBySN(binding.TerminalSN)
+	if err != nil || holder == nil {
+		return terminal
+	}
+	return holder
+}
+
+// ============================================================
+// 续费提醒
+// ============================================================
+
+// SimRenewalRemindResult 续费提醒结果
+type SimRenewalRemindResult struct {
+	Predicted int `json:"predicted"` // 补充续费预测的流量卡数
+	Due       int `json:"due"`       // 本次提醒的流量卡数
+	Agents    int `json:"agents"`    // 提醒的代理商数
+}
+
+// RemindRenewals 补充续费预测，并提醒终端所属代理商即将续费扣费的流量卡（每期只提醒一次）
+func (s *SimCardService) RemindRenewals(now time.Time) (*SimRenewalRemindResult, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SimRenewalRemindResult{}
+	sims, err := s.simRepo.FindUnpredicted(simRenewalPredictBatch)
+	if err != nil {
+		return nil, fmt.Errorf("查询待预测流量卡失败: %w", err)
+	}
+	for _, sim := range sims {
+		sim.NextRenewalAt = predictSimRenewal(sim, config, s.terminalActivatedAt(sim))
+		if sim.NextRenewalAt == nil {
+			continue
+		}
+		if err := s.simRepo.Save(sim); err != nil {
+			log.Printf("[SimCardService] Save renewal prediction for %s failed: %v", sim.ICCID, err)
+			continue
+		}
+		result.Predicted++
+	}
+
+	if !config.RemindEnabled {
+		return result, nil
+	}
+
+	rows, err := s.simRepo.FindDueRenewals(now.AddDate(0, 0, config.RemindDaysBefore), simRenewalRemindBatch)
+	if err != nil {
+		return nil, fmt.Errorf("查询待续费流量卡失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	groups := groupSimRenewals(rows)
+	for _, agentID := range sortedSimRenewalAgents(groups) {
+		if agentID == 0 {
+			continue
+		}
+		title, content := simRenewalMessage(groups[agentID], now)
+		s.sendRenewalMessage(agentID, title, content)
+		result.Agents++
+	}
+
+	ids := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.SimCardID)
+	}
+	if err := s.simRepo.MarkReminded(ids); err != nil {
+		return nil, fmt.Errorf("标记已提醒失败: %w", err)
+	}
+	result.Due = len(rows)
+	return result, nil
+}
+
+// sendRenewalMessage 发送续费提醒消息
+func (s *SimCardService) sendRenewalMessage(agentID int64, title, content string) {
+	if s.messageService == nil {
+		return
+	}
+	if err := s.messageService.SendNotification(&NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeSimRenewal,
+		Title:       title,
+		Content:     content,
+		RelatedType: "sim_renewal",
+	}); err != nil {
+		log.Printf("[SimCardService] Send renewal message to agent %d failed: %v", agentID, err)
+	}
+}
+
+// ============================================================
+// 工具函数
+// ============================================================
+
+// normalizeICCID 去除空白并转大写
+func normalizeICCID(iccid string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iccid), ""))
+}
+
+// normalizeSimCarrier 校验运营商，空为其他
+func normalizeSimCarrier(carrier string) (string, error) {
+	carrier = strings.ToLower(strings.TrimSpace(carrier))
+	switch carrier {
+	case "":
+		return models.SimCarrierOther, nil
+	case models.SimCarrierCMCC, models.SimCarrierCUCC, models.SimCarrierCTCC, models.SimCarrierOther:
+		return carrier, nil
+	default:
+		return "", fmt.Errorf("不支持的运营商: %s", carrier)
+	}
+}
+
+// parseSimDate 解析yyyy-MM-dd日期，空返回nil
+func parseSimDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, errors.New("格式错误，应为yyyy-MM-dd")
+	}
+	return &t, nil
+}
+
+// truncateSimRemark 截断备注
+func truncateSimRemark(remark string) string {
+	runes := []rune(remark)
+	if len(runes) > 200 {
+		return string(runes[:200])
+	}
+	return remark
+}
+
+// predictSimRenewal 预测下次续费时间
+// 已缴过费：最近缴费时间 + 续费周期；未缴费：开卡（无则终端激活）时间 + 首次扣费天数；卡有效期更早时取有效期
+func predictSimRenewal(sim *models.SimCard, config *models.SimCardConfig, terminalActivatedAt *time.Time) *time.Time {
+	var next *time.Time
+	switch {
+	case sim.LastFeeAt != nil:
+		cycle := sim.RenewalCycleDays
+		if cycle <= 0 {
+			cycle = config.RenewalCycleDays
+		}
+		t := sim.LastFeeAt.AddDate(0, 0, cycle)
+		next = &t
+	case sim.ActivatedAt != nil:
+		t := sim.ActivatedAt.AddDate(0, 0, config.FirstFeeDays)
+		next = &t
+	case terminalActivatedAt != nil:
+		t := terminalActivatedAt.AddDate(0, 0, config.FirstFeeDays)
+		next = &t
+	}
+
+	if sim.ExpireAt != nil && (next == nil || sim.ExpireAt.Before(*next)) {
+		t := *sim.ExpireAt
+		next = &t
+	}
+	return next
+}
+
+// groupSimRenewals 按终端所属代理商分组
+func groupSimRenewals(rows []*repository.SimRenewalDue) map[int64][]*repository.SimRenewalDue {
+	groups := make(map[int64][]*repository.SimRenewalDue)
+	for _, row := range rows {
+		groups[row.OwnerAgentID] = append(groups[row.OwnerAgentID], row)
+	}
+	return groups
+}
+
+// sortedSimRenewalAgents 按代理商ID排序
+func sortedSimRenewalAgents(groups map[int64][]*repository.SimRenewalDue) []int64 {
+	agentIDs := make([]int64, 0, len(groups))
+	for agentID := range groups {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Slice(agentIDs, func(i, j int) bool { return agentIDs[i] < agentIDs[j] })
+	return agentIDs
+}
+
+// simRenewalMessage 生成续费提醒消息，列出最早到期的几张卡
+func simRenewalMessage(rows []*repository.SimRenewalDue, now time.Time) (string, string) {
+	overdue := 0
+	for _, row := range rows {
+		if row.NextRenewalAt.Before(now) {
+			overdue++
+		}
+	}
+
+	title := fmt.Sprintf("%d张流量卡即将续费", len(rows))
+	if overdue > 0 {
+		title = fmt.Sprintf("%d张流量卡即将续费（%d张已到期）", len(rows), overdue)
+	}
+
+	parts := make([]string, 0, simRenewalMessageMaxList)
+	for i, row := range rows {
+		if i >= simRenewalMessageMaxList {
+			break
+		}
+		iccid := row.ICCID
+		if len(iccid) > 6 {
+			iccid = iccid[len(iccid)-6:]
+		}
+		parts = append(parts, fmt.Sprintf("终端%s（卡尾号%s，%s）", row.TerminalSN, iccid, row.NextRenewalAt.Format("2006-01-02")))
+	}
+	content := "以下终端的流量卡即将扣取流量费：" + strings.Join(parts, "、")
+	if len(rows) > simRenewalMessageMaxList {
+		content += fmt.Sprintf("等%d台", len(rows))
+	}
+	content += "。请提醒商户保持结算账户余额充足，避免影响终端使用。"
+	return title, content
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestPredictSimRenewal(t *testing.T) {
+	config := &models.SimCardConfig{FirstFeeDays: 180, RenewalCycleDays: 365}
+	day := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		sim       *models.SimCard
+		activated *time.Time
+		want      *time.Time
+	}{
+		{"已缴费按缴费时间加全局周期", &models.SimCard{LastFeeAt: day(2026, 1, 1), ActivatedAt: day(2025, 1, 1)}, nil, day(2027, 1, 1)},
+		{"已缴费按卡自定义周期", &models.SimCard{LastFeeAt: day(2026, 1, 1), RenewalCycleDays: 30}, nil, day(2026, 1, 31)},
+		{"未缴费按开卡日期", &models.SimCard{ActivatedAt: day(2026, 1, 1)}, day(2026, 3, 1), day(2026, 6, 30)},
+		{"未缴费无开卡日期按终端激活", &models.SimCard{}, day(2026, 1, 1), day(2026, 6, 30)},
+		{"有效期更早取有效期", &models.SimCard{ActivatedAt: day(2026, 1, 1), ExpireAt: day(2026, 3, 1)}, nil, day(2026, 3, 1)},
+		{"仅有效期", &models.SimCard{ExpireAt: day(2026, 3, 1)}, nil, day(2026, 3, 1)},
+		{"无法预测", &models.SimCard{}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := predictSimRenewal(tt.sim, config, tt.activated)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("predictSimRenewal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSimCardFromItem(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		item    SimCardItem
+		wantErr bool
+	}{
+		{"正常", SimCardItem{ICCID: "89860 1234567890 12345", Carrier: "CMCC", ActivatedAt: "2026-01-01"}, false},
+		{"末位校验字母", SimCardItem{ICCID: "8986011234567890123f"}, false},
+		{"非89开头", SimCardItem{ICCID: "1234567890123456789"}, true},
+		{"长度不足", SimCardItem{ICCID: "898601"}, true},
+		{"运营商错误", SimCardItem{ICCID: "89860112345678901234", Carrier: "abc"}, true},
+		{"日期格式错误", SimCardItem{ICCID: "89860112345678901234", ExpireAt: "2026/01/01"}, true},
+		{"续费周期超限", SimCardItem{ICCID: "89860112345678901234", RenewalCycleDays: 2000}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, err := newSimCardFromItem(&tt.item, normalizeICCID(tt.item.ICCID), now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newSimCardFromItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (sim.Status != models.SimCardStatusStock || strings.ContainsAny(sim.ICCID, " f")) {
+				t.Errorf("newSimCardFromItem() = %+v", sim)
+			}
+		})
+	}
+}
+
+func TestSimRenewalMessage(t *testing.T) {
+	now := time.Date(2026, 5, 10, 0, 0, 0, 0, time.Local)
+	rows := make([]*repository.SimRenewalDue, 0, 7)
+	for i := 0; i < 7; i++ {
+		rows = append(rows, &repository.SimRenewalDue{
+			ICCID:         "89860112345678900" + string(rune('0'+i)) + "00",
+			TerminalSN:    "SN00" + string(rune('0'+i)),
+			NextRenewalAt: now.AddDate(0, 0, i-1),
+		})
+	}
+
+	title, content := simRenewalMessage(rows, now)
+	if title != "7张流量卡即将续费（1张已到期）" {
+		t.Errorf("title = %s", title)
+	}
+	if !strings.Contains(content, "终端SN000（卡尾号900000，2026-05-09）") {
+		t.Errorf("content应包含首张卡, got %s", content)
+	}
+	if strings.Contains(content, "SN005") || !strings.Contains(content, "等7台") {
+		t.Errorf("content应只列出前5台, got %s", content)
+	}
+
+	title, _ = simRenewalMessage(rows[2:3], now)
+	if title != "1张流量卡即将续费" {
+		t.Errorf("title = %s", title)
+	}
+}
@@ -24,6 +24,7 @@ type SimCashbackService struct {
 	agentPolicyRepo repository.AgentPolicyRepository
 	messageService  *MessageService
 	queue           async.MessageQueue
+	simCardService  *SimCardService // 流量卡归属（可选）
 }
 
 // NewSimCashbackService 创建流量费返现服务
@@ -53,6 +54,11 @@ func NewSimCashbackService(
 	}
 }
 
+// SetSimCardService 设置流量卡服务，设置后按流量卡的缴费次数确定返现档次
+func (s *SimCashbackService) SetSimCardService(simCardService *SimCardService) {
+	s.simCardService = simCardService
+}
+
 // ProcessSimFee 处理流量费缴费并计算返现
 // 业务规则：
 // - 流量费返现三档：首次/2次/2+N次
@@ -70,8 +76,20 @@ func (s *SimCashbackService) ProcessSimFee(deviceFee *models.DeviceFee) error {
 		log.Printf("[SimCashbackService] Update terminal sim fee count failed: %v", err)
 	}
 
-	// 3. 确定返现档次
-	cashbackTier := models.GetCashbackTier(newSimFeeCount)
+	// 3. 确定返现档次（流量费能归属到流量卡时按该卡的缴费次数，换卡后不沿用终端的次数）
+	feeCount := newSimFeeCount
+	var simCard *models.SimCard
+	if s.simCardService != nil {
+		attr, err := s.simCardService.AttributeFee(deviceFee, terminal)
+		if err != nil {
+			log.Printf("[SimCashbackService] Attribute sim fee to sim card failed: %v", err)
+		} else if attr != nil {
+			simCard = attr.SimCard
+			terminal = attr.Terminal
+			feeCount = attr.FeeCount
+		}
+	}
+	cashbackTier := models.GetCashbackTier(feeCount)
 
 	// 4. 获取直属代理商
 	if terminal.OwnerAgentID == 0 {
@@ -127,10 +145,10 @@ func (s *SimCashbackService) ProcessSimFee(deviceFee *models.DeviceFee) error {
 		// 创建返现记录
 		record := &models.SimCashbackRecord{
 			DeviceFeeID:    deviceFee.ID,
-			TerminalSN:     deviceFee.TerminalSN,
+			TerminalSN:     terminal.TerminalSN,
 			ChannelID:      deviceFee.ChannelID,
 			AgentID:        currentAgent.ID,
-			SimFeeCount:    newSimFeeCount,
+			SimFeeCount:    feeCount,
 			SimFeeAmount:   deviceFee.FeeAmount,
 			CashbackTier:   cashbackTier,
 			SelfCashback:   selfCashback,
@@ -141,6 +159,10 @@ func (s *SimCashbackService) ProcessSimFee(deviceFee *models.DeviceFee) error {
 			WalletStatus:   0,                        // 待入账
 			CreatedAt:      time.Now(),
 		}
+		if simCard != nil {
+			record.SimCardID = &simCard.ID
+			record.ICCID = simCard.ICCID
+		}
 		cashbackRecords = append(cashbackRecords, record)
 
 		// 获取钱包并记录更新
@@ -206,7 +228,7 @@ func (s *SimCashbackService) ProcessSimFee(deviceFee *models.DeviceFee) error {
 	s.sendCashbackNotifications(cashbackRecords)
 
 	log.Printf("[SimCashbackService] Processed sim fee: terminal=%s, count=%d, tier=%d, records=%d",
-		deviceFee.TerminalSN, newSimFeeCount, cashbackTier, len(cashbackRecords))
+		terminal.TerminalSN, feeCount, cashbackTier, len(cashbackRecords))
 
 	return nil
 }
@@ -0,0 +1,718 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/pkg/xlsx"
+)
+
+const (
+	terminalBatchMaxRows      = 50000 // 单个文件展开后最多SN数
+	terminalBatchMaxRange     = 10000 // 单个号段最多SN数
+	terminalBatchSyncRows     = 200   // 不超过该行数时上传请求内直接处理
+	terminalBatchChunkSize    = 1000  // 查询/入库分批大小
+	terminalBatchProgressStep = 500   // 后台处理进度刷新间隔
+	terminalBatchDetailErrors = 200   // 任务详情返回的错误行数上限（完整内容见错误报告）
+)
+
+// TerminalBatchService 终端批量入库/下发/回拨服务
+// 上传的XLSX/CSV在创建任务时解析并展开SN号段；行数较少时在请求内处理，否则转为后台任务，
+// 后台任务由协程立即执行，服务重启遗留的待处理任务由定时任务补处理
+type TerminalBatchService struct {
+	jobRepo           *repository.GormTerminalBatchJobRepository
+	terminalRepo      *repository.GormTerminalRepository
+	terminalTypeRepo  repository.TerminalTypeRepository
+	agentRepo         repository.AgentRepository
+	terminalService   *TerminalService
+	distributeService *TerminalDistributeService
+}
+
+// NewTerminalBatchService 创建终端批量服务
+func NewTerminalBatchService(
+	jobRepo *repository.GormTerminalBatchJobRepository,
+	terminalRepo *repository.GormTerminalRepository,
+	terminalTypeRepo repository.TerminalTypeRepository,
+	agentRepo repository.AgentRepository,
+	terminalService *TerminalService,
+	distributeService *TerminalDistributeService,
+) *TerminalBatchService {
+	return &TerminalBatchService{
+		jobRepo:           jobRepo,
+		terminalRepo:      terminalRepo,
+		terminalTypeRepo:  terminalTypeRepo,
+		agentRepo:         agentRepo,
+		terminalService:   terminalService,
+		distributeService: distributeService,
+	}
+}
+
+// TerminalBatchUploadRequest 批量上传请求
+type TerminalBatchUploadRequest struct {
+	JobType          string // import/distribute/recall
+	FileName         string // 文件名（按扩展名识别XLSX/CSV）
+	Data             []byte // 文件内容
+	AgentID          int64  // 操作代理商
+	CreatedBy        int64  // 操作人
+	Source           int16  // 1:APP 2:PC
+	ChannelID        int64  // 通道（入库必填，下发/回拨为筛选条件）
+	ChannelCode      string // 通道编码
+	BrandCode        string // 品牌编码
+	ModelCode        string // 型号编码
+	ToAgentID        int64  // 下发/回拨接收方
+	GoodsPrice       int64  // 下发货款（分）
+	DeductionType    int16  // 下发代扣类型
+	DeductionPeriods int    // 分期期数
+	DeductionSource  int16  // 货款代扣来源
+	Remark           string // 备注
+}
+
+// CreateJob 解析上传文件并创建批量任务
+func (s *TerminalBatchService) CreateJob(req *TerminalBatchUploadRequest) (*models.TerminalBatchJob, error) {
+	if err := s.validateUploadRequest(req); err != nil {
+		return nil, err
+	}
+
+	records, err := readTerminalSheet(req.FileName, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	rows, rowErrors, err := parseTerminalBatchRows(records)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		return nil, fmt.Errorf("文件中没有SN数据")
+	}
+
+	rowsJSON, _ := json.Marshal(rows)
+	errorsJSON, _ := json.Marshal(rowErrors)
+	now := time.Now()
+	job := &models.TerminalBatchJob{
+		JobNo:            fmt.Sprintf("TB%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		JobType:          req.JobType,
+		Status:           models.TerminalBatchJobStatusPending,
+		FileName:         req.FileName,
+		AgentID:          req.AgentID,
+		CreatedBy:        req.CreatedBy,
+		Source:           req.Source,
+		ChannelID:        req.ChannelID,
+		ChannelCode:      req.ChannelCode,
+		BrandCode:        req.BrandCode,
+		ModelCode:        req.ModelCode,
+		ToAgentID:        req.ToAgentID,
+		GoodsPrice:       req.GoodsPrice,
+		DeductionType:    req.DeductionType,
+		DeductionPeriods: req.DeductionPeriods,
+		DeductionSource:  req.DeductionSource,
+		Remark:           req.Remark,
+		Rows:             string(rowsJSON),
+		TotalCount:       len(rows) + len(rowErrors),
+		FailedCount:      len(rowErrors),
+		Errors:           string(errorsJSON),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, fmt.Errorf("创建批量任务失败: %w", err)
+	}
+
+	log.Printf("[TerminalBatchService] Created job %s: type=%s, rows=%d, parseErrors=%d",
+		job.JobNo, job.JobType, len(rows), len(rowErrors))
+
+	if len(rows) > terminalBatchSyncRows {
+		go func() {
+			if err := s.Process(job.ID); err != nil {
+				log.Printf("[TerminalBatchService] Process job %s failed: %v", job.JobNo, err)
+			}
+		}()
+		return job, nil
+	}
+
+	if err := s.Process(job.ID); err != nil {
+		return nil, err
+	}
+	return s.GetJob(job.ID, req.AgentID, true)
+}
+
+// validateUploadRequest 校验任务参数
+func (s *TerminalBatchService) validateUploadRequest(req *TerminalBatchUploadRequest) error {
+	switch req.JobType {
+	case models.TerminalBatchJobTypeImport:
+		if req.ChannelID <= 0 || req.BrandCode == "" || req.ModelCode == "" {
+			return fmt.Errorf("入库须指定通道、品牌和型号")
+		}
+		terminalType, err := s.terminalTypeRepo.FindByChannelAndCodes(context.Background(), req.ChannelID, req.BrandCode, req.ModelCode)
+		if err != nil {
+			return fmt.Errorf("查询终端类型失败: %w", err)
+		}
+		if terminalType == nil || terminalType.Status != 1 {
+			return fmt.Errorf("通道下不存在启用的终端类型: %s/%s", req.BrandCode, req.ModelCode)
+		}
+		if req.ChannelCode == "" {
+			req.ChannelCode = terminalType.ChannelCode
+		}
+	case models.TerminalBatchJobTypeDistribute, models.TerminalBatchJobTypeRecall:
+		if req.ToAgentID <= 0 || req.ToAgentID == req.AgentID {
+			return fmt.Errorf("接收方代理商无效")
+		}
+		toAgent, err := s.agentRepo.FindByID(req.ToAgentID)
+		if err != nil || toAgent == nil {
+			return fmt.Errorf("接收方代理商不存在")
+		}
+		if req.JobType == models.TerminalBatchJobTypeDistribute {
+			if req.GoodsPrice < 0 {
+				return fmt.Errorf("货款金额不能为负数")
+			}
+			if req.DeductionType < DistributeDeductionTypeOnce || req.DeductionType > DistributeDeductionTypeRealtime {
+				return fmt.Errorf("代扣类型无效")
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的任务类型: %s", req.JobType)
+	}
+	return nil
+}
+
+// Process 处理任务（仅处理待处理状态，重复调用直接返回）
+func (s *TerminalBatchService) Process(jobID int64) error {
+	claimed, err := s.jobRepo.Claim(jobID, time.Now())
+	if err != nil {
+		return fmt.Errorf("抢占任务失败: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+
+	job, err := s.jobRepo.FindByID(jobID)
+	if err != nil || job == nil {
+		return fmt.Errorf("批量任务不存在")
+	}
+
+	var rows []models.TerminalBatchRow
+	var rowErrors []*models.TerminalBatchRowError
+	if err := json.Unmarshal([]byte(job.Rows), &rows); err != nil {
+		return s.finish(job, 0, nil, "", fmt.Errorf("解析任务数据失败: %w", err))
+	}
+	if job.Errors != "" {
+		_ = json.Unmarshal([]byte(job.Errors), &rowErrors)
+	}
+
+	run := &terminalBatchRun{service: s, job: job, errors: rowErrors}
+	execErr := run.execute(rows)
+	return s.finish(job, run.success, run.errors, strings.Join(run.resultNos, ","), execErr)
+}
+
+// finish 保存任务结果
+func (s *TerminalBatchService) finish(job *models.TerminalBatchJob, success int, rowErrors []*models.TerminalBatchRowError, resultNos string, execErr error) error {
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+	errorsJSON, _ := json.Marshal(rowErrors)
+
+	now := time.Now()
+	job.Status = models.TerminalBatchJobStatusCompleted
+	if execErr != nil {
+		job.Status = models.TerminalBatchJobStatusFailed
+		job.ErrorMessage = execErr.Error()
+	}
+	job.SuccessCount = success
+	job.FailedCount = len(rowErrors)
+	job.Errors = string(errorsJSON)
+	job.ResultNos = resultNos
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+	if err := s.jobRepo.Save(job); err != nil {
+		return fmt.Errorf("保存任务结果失败: %w", err)
+	}
+
+	log.Printf("[TerminalBatchService] Finished job %s: success=%d, failed=%d, err=%v",
+		job.JobNo, job.SuccessCount, job.FailedCount, execErr)
+	return nil
+}
+
+// ProcessPending 补处理服务重启遗留的任务（定时任务调用）
+func (s *TerminalBatchService) ProcessPending(now time.Time) (int, error) {
+	if n, err := s.jobRepo.FailStale(now.Add(-2*time.Hour), "处理中断，请重新上传"); err != nil {
+		return 0, err
+	} else if n > 0 {
+		log.Printf("[TerminalBatchService] Marked %d stale jobs as failed", n)
+	}
+
+	ids, err := s.jobRepo.FindPendingBefore(now.Add(-time.Minute), 10)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := s.Process(id); err != nil {
+			log.Printf("[TerminalBatchService] Process job %d failed: %v", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// GetJob 获取任务详情（含前若干条错误行）
+func (s *TerminalBatchService) GetJob(id, agentID int64, isAdmin bool) (*models.TerminalBatchJob, error) {
+	job, err := s.getJob(id, agentID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if len(job.RowErrors) > terminalBatchDetailErrors {
+		job.RowErrors = job.RowErrors[:terminalBatchDetailErrors]
+	}
+	job.Rows = ""
+	return job, nil
+}
+
+func (s *TerminalBatchService) getJob(id, agentID int64, isAdmin bool) (*models.TerminalBatchJob, error) {
+	job, err := s.jobRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询批量任务失败: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("批量任务不存在")
+	}
+	if !isAdmin && job.AgentID != agentID {
+		return nil, fmt.Errorf("无权查看该任务")
+	}
+	if job.Errors != "" {
+		if err := json.Unmarshal([]byte(job.Errors), &job.RowErrors); err != nil {
+			return nil, fmt.Errorf("解析错误明细失败: %w", err)
+		}
+	}
+	return job, nil
+}
+
+// ListJobs 分页获取任务列表（agentID 为0时不限）
+func (s *TerminalBatchService) ListJobs(agentID int64, jobType string, page, pageSize int) ([]*models.TerminalBatchJob, int64, error) {
+	return s.jobRepo.List(agentID, jobType, pageSize, (page-1)*pageSize)
+}
+
+// ExportErrorReport 导出逐行错误报告CSV（带UTF-8 BOM，兼容Excel）
+func (s *TerminalBatchService) ExportErrorReport(id, agentID int64, isAdmin bool) (*models.TerminalBatchJob, []byte, error) {
+	job, err := s.getJob(id, agentID, isAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(buf)
+	records := [][]string{{"行号", "终端SN", "错误原因"}}
+	for _, e := range job.RowErrors {
+		records = append(records, []string{strconv.Itoa(e.Row), e.SN, e.Reason})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, nil, fmt.Errorf("生成错误报告失败: %w", err)
+	}
+	return job, buf.Bytes(), nil
+}
+
+// terminalBatchRun 单次任务执行上下文
+type terminalBatchRun struct {
+	service   *TerminalBatchService
+	job       *models.TerminalBatchJob
+	success   int
+	errors    []*models.TerminalBatchRowError
+	resultNos []string
+	processed int
+}
+
+func (r *terminalBatchRun) fail(row models.TerminalBatchRow, reason string) {
+	r.errors = append(r.errors, &models.TerminalBatchRowError{Row: row.Row, SN: row.SN, Reason: reason})
+	r.progress()
+}
+
+func (r *terminalBatchRun) succeed(n int) {
+	r.success += n
+	r.progress()
+}
+
+// progress 每处理一定行数刷新一次进度，便于前端轮询
+func (r *terminalBatchRun) progress() {
+	r.processed++
+	if r.processed%terminalBatchProgressStep == 0 {
+		if err := r.service.jobRepo.UpdateProgress(r.job.ID, r.success, len(r.errors)); err != nil {
+			log.Printf("[TerminalBatchService] Update progress failed: %v", err)
+		}
+	}
+}
+
+// execute 逐行校验并执行
+func (r *terminalBatchRun) execute(rows []models.TerminalBatchRow) error {
+	rows = r.dedupe(rows)
+
+	terminals, err := r.loadTerminals(rows)
+	if err != nil {
+		return err
+	}
+
+	if r.job.JobType == models.TerminalBatchJobTypeImport {
+		return r.executeImport(rows, terminals)
+	}
+
+	for _, row := range rows {
+		terminal := terminals[row.SN]
+		if reason := checkBatchTerminal(r.job, row, terminal); reason != "" {
+			r.fail(row, reason)
+			continue
+		}
+
+		var err error
+		switch r.job.JobType {
+		case models.TerminalBatchJobTypeDistribute:
+			_, err = r.service.distributeService.DistributeTerminal(&DistributeTerminalRequest{
+				FromAgentID:      r.job.AgentID,
+				ToAgentID:        r.job.ToAgentID,
+				TerminalSN:       row.SN,
+				ChannelID:        terminal.ChannelID,
+				GoodsPrice:       r.job.GoodsPrice,
+				DeductionType:    r.job.DeductionType,
+				DeductionPeriods: r.job.DeductionPeriods,
+				DeductionSource:  r.job.DeductionSource,
+				Source:           r.job.Source,
+				Remark:           r.job.Remark,
+				CreatedBy:        r.job.CreatedBy,
+			})
+		case models.TerminalBatchJobTypeRecall:
+			_, err = r.service.terminalService.RecallTerminal(&RecallTerminalRequest{
+				FromAgentID: r.job.AgentID,
+				ToAgentID:   r.job.ToAgentID,
+				TerminalSN:  row.SN,
+				ChannelID:   terminal.ChannelID,
+				Source:      r.job.Source,
+				Remark:      r.job.Remark,
+				CreatedBy:   r.job.CreatedBy,
+			})
+		}
+		if err != nil {
+			r.fail(row, err.Error())
+			continue
+		}
+		r.succeed(1)
+	}
+	return nil
+}
+
+// executeImport 入库：已存在的SN逐行报错，其余按批调用入库
+func (r *terminalBatchRun) executeImport(rows []models.TerminalBatchRow, terminals map[string]*models.Terminal) error {
+	valid := make([]models.TerminalBatchRow, 0, len(rows))
+	for _, row := range rows {
+		if reason := checkBatchImportRow(r.job, row, terminals[row.SN]); reason != "" {
+			r.fail(row, reason)
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	for start := 0; start < len(valid); start += terminalBatchChunkSize {
+		chunk := valid[start:min(start+terminalBatchChunkSize, len(valid))]
+		sns := make([]string, 0, len(chunk))
+		for _, row := range chunk {
+			sns = append(sns, row.SN)
+		}
+
+		result, err := r.service.terminalService.ImportTerminals(&ImportTerminalsRequest{
+			ChannelID:    r.job.ChannelID,
+			ChannelCode:  r.job.ChannelCode,
+			BrandCode:    r.job.BrandCode,
+			ModelCode:    r.job.ModelCode,
+			SNList:       sns,
+			OwnerAgentID: r.job.AgentID,
+			CreatedBy:    r.job.CreatedBy,
+		})
+		if err != nil {
+			for _, row := range chunk {
+				r.fail(row, err.Error())
+			}
+			continue
+		}
+
+		failed := make(map[string]bool, len(result.FailedSNs))
+		for _, sn := range result.FailedSNs {
+			failed[sn] = true
+		}
+		for _, row := range chunk {
+			if failed[row.SN] {
+				r.fail(row, "终端已存在")
+			}
+		}
+		r.succeed(result.SuccessCount)
+		r.resultNos = append(r.resultNos, result.ImportNo)
+	}
+	return nil
+}
+
+// dedupe 文件内重复的SN保留首次出现，其余逐行报错
+func (r *terminalBatchRun) dedupe(rows []models.TerminalBatchRow) []models.TerminalBatchRow {
+	first := make(map[string]int, len(rows))
+	unique := make([]models.TerminalBatchRow, 0, len(rows))
+	for _, row := range rows {
+		if firstRow, ok := first[row.SN]; ok {
+			r.fail(row, fmt.Sprintf("文件内SN重复（首次出现在第%d行）", firstRow))
+			continue
+		}
+		first[row.SN] = row.Row
+		unique = append(unique, row)
+	}
+	return unique
+}
+
+// loadTerminals 分批查询行内SN对应的终端
+func (r *terminalBatchRun) loadTerminals(rows []models.TerminalBatchRow) (map[string]*models.Terminal, error) {
+	terminals := make(map[string]*models.Terminal, len(rows))
+	for start := 0; start < len(rows); start += terminalBatchChunkSize {
+		chunk := rows[start:min(start+terminalBatchChunkSize, len(rows))]
+		sns := make([]string, 0, len(chunk))
+		for _, row := range chunk {
+			sns = append(sns, row.SN)
+		}
+		found, err := r.service.terminalRepo.FindBySNs(sns)
+		if err != nil {
+			return nil, fmt.Errorf("查询终端失败: %w", err)
+		}
+		for _, t := range found {
+			terminals[t.TerminalSN] = t
+		}
+	}
+	return terminals, nil
+}
+
+// checkBatchImportRow 校验入库行，返回错误原因（空字符串表示通过）
+func checkBatchImportRow(job *models.TerminalBatchJob, row models.TerminalBatchRow, existing *models.Terminal) string {
+	if existing != nil {
+		return "终端已存在"
+	}
+	if row.ChannelCode != "" && !strings.EqualFold(row.ChannelCode, job.ChannelCode) {
+		return fmt.Sprintf("通道编码 %s 与入库通道 %s 不符", row.ChannelCode, job.ChannelCode)
+	}
+	if row.BrandCode != "" && !strings.EqualFold(row.BrandCode, job.BrandCode) {
+		return fmt.Sprintf("品牌编码 %s 与入库品牌 %s 不符", row.BrandCode, job.BrandCode)
+	}
+	if row.ModelCode != "" && !strings.EqualFold(row.ModelCode, job.ModelCode) {
+		return fmt.Sprintf("型号编码 %s 与入库型号 %s 不符", row.ModelCode, job.ModelCode)
+	}
+	return ""
+}
+
+// checkBatchTerminal 校验下发/回拨行，返回错误原因（空字符串表示通过）
+// 任务和行内指定的通道/品牌/型号都须与终端一致，终端须属于操作代理商且状态允许对应事件
+func checkBatchTerminal(job *models.TerminalBatchJob, row models.TerminalBatchRow, terminal *models.Terminal) string {
+	if terminal == nil {
+		return "终端不存在"
+	}
+	if terminal.OwnerAgentID != job.AgentID {
+		return "终端不属于当前代理商"
+	}
+	if job.ChannelID > 0 && terminal.ChannelID != job.ChannelID {
+		return "终端通道与任务不符"
+	}
+	for _, code := range []string{job.ChannelCode, row.ChannelCode} {
+		if code != "" && !strings.EqualFold(code, terminal.ChannelCode) {
+			return fmt.Sprintf("终端通道为 %s，与指定的 %s 不符", terminal.ChannelCode, code)
+		}
+	}
+	for _, code := range []string{job.BrandCode, row.BrandCode} {
+		if code != "" && !strings.EqualFold(code, terminal.BrandCode) {
+			return fmt.Sprintf("终端品牌为 %s，与指定的 %s 不符", terminal.BrandCode, code)
+		}
+	}
+	for _, code := range []string{job.ModelCode, row.ModelCode} {
+		if code != "" && !strings.EqualFold(code, terminal.ModelCode) {
+			return fmt.Sprintf("终端型号为 %s，与指定的 %s 不符", terminal.ModelCode, code)
+		}
+	}
+
+	event := models.TerminalEventDistributeConfirm
+	if job.JobType == models.TerminalBatchJobTypeRecall {
+		event = models.TerminalEventRecallConfirm
+	}
+	if _, _, err := checkTerminalTransition(terminal.Status, event); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// readTerminalSheet 按扩展名读取XLSX/CSV，返回的行下标与表格行号（从1开始）对应
+func readTerminalSheet(fileName string, data []byte) ([][]string, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".xlsx":
+		return xlsx.ReadFirstSheet(data)
+	case ".csv":
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))))
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		reader.TrimLeadingSpace = true
+
+		var records [][]string
+		for {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("解析CSV失败: %w", err)
+			}
+			// csv跳过空行，按记录所在行号补齐
+			line, _ := reader.FieldPos(0)
+			for len(records) < line-1 {
+				records = append(records, []string{})
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	default:
+		return nil, fmt.Errorf("仅支持XLSX或CSV文件")
+	}
+}
+
+// terminalBatchColumns 表格列位置（-1表示无该列）
+type terminalBatchColumns struct {
+	prefix, sn, end, channel, brand, model int
+}
+
+// terminalBatchHeaders 表头名称（忽略大小写、空格和下划线）
+var terminalBatchHeaders = map[string]string{
+	"sn": "sn", "终端sn": "sn", "机具sn": "sn", "起始sn": "sn", "开始sn": "sn", "startsn": "sn",
+	"结束sn": "end", "截止sn": "end", "endsn": "end",
+	"前缀": "prefix", "sn前缀": "prefix", "prefix": "prefix",
+	"通道编码": "channel", "通道": "channel", "channelcode": "channel",
+	"品牌编码": "brand", "品牌": "brand", "brandcode": "brand",
+	"型号编码": "model", "型号": "model", "modelcode": "model",
+}
+
+// detectTerminalBatchHeader 识别表头行，须包含SN列
+func detectTerminalBatchHeader(record []string) (terminalBatchColumns, bool) {
+	cols := terminalBatchColumns{prefix: -1, sn: -1, end: -1, channel: -1, brand: -1, model: -1}
+	for i, cell := range record {
+		name := strings.ToLower(strings.NewReplacer(" ", "", "_", "", "　", "").Replace(strings.TrimSpace(cell)))
+		switch terminalBatchHeaders[name] {
+		case "sn":
+			cols.sn = i
+		case "end":
+			cols.end = i
+		case "prefix":
+			cols.prefix = i
+		case "channel":
+			cols.channel = i
+		case "brand":
+			cols.brand = i
+		case "model":
+			cols.model = i
+		}
+	}
+	return cols, cols.sn >= 0
+}
+
+// parseTerminalBatchRows 将表格行转换为SN行并展开号段
+// 有表头时按表头识别列；无表头时第1列为SN（或号段起始SN），第2列为号段结束SN；
+// 有“前缀”列时起止列只填数字部分；展开过程中累计超过单文件上限时立即停止并返回错误
+func parseTerminalBatchRows(records [][]string) ([]models.TerminalBatchRow, []*models.TerminalBatchRowError, error) {
+	cols := terminalBatchColumns{prefix: -1, sn: 0, end: 1, channel: -1, brand: -1, model: -1}
+	start := 0
+	if len(records) > 0 {
+		if header, ok := detectTerminalBatchHeader(records[0]); ok {
+			cols, start = header, 1
+		}
+	}
+
+	cell := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []models.TerminalBatchRow
+	var rowErrors []*models.TerminalBatchRowError
+	for i := start; i < len(records); i++ {
+		record := records[i]
+		rowNum := i + 1
+		prefix, sn, end := cell(record, cols.prefix), cell(record, cols.sn), cell(record, cols.end)
+		if sn == "" && end == "" {
+			continue
+		}
+		if sn == "" {
+			rowErrors = append(rowErrors, &models.TerminalBatchRowError{Row: rowNum, SN: end, Reason: "缺少起始SN"})
+			continue
+		}
+
+		sns := []string{prefix + sn}
+		if end != "" && end != sn {
+			expanded, err := expandSNRange(prefix+sn, prefix+end)
+			if err != nil {
+				rowErrors = append(rowErrors, &models.TerminalBatchRowError{Row: rowNum, SN: prefix + sn + "~" + prefix + end, Reason: err.Error()})
+				continue
+			}
+			sns = expanded
+		}
+		if len(rows)+len(sns) > terminalBatchMaxRows {
+			return nil, nil, fmt.Errorf("单个文件不能超过%d台终端（第%d行起超出）", terminalBatchMaxRows, rowNum)
+		}
+
+		for _, v := range sns {
+			rows = append(rows, models.TerminalBatchRow{
+				Row:         rowNum,
+				SN:          v,
+				ChannelCode: cell(record, cols.channel),
+				BrandCode:   cell(record, cols.brand),
+				ModelCode:   cell(record, cols.model),
+			})
+		}
+	}
+	return rows, rowErrors, nil
+}
+
+// expandSNRange 展开SN号段：起止SN须前缀相同、以等长数字结尾，如 AB0001~AB0100
+func expandSNRange(start, end string) ([]string, error) {
+	startPrefix, startDigits := splitSNDigits(start)
+	endPrefix, endDigits := splitSNDigits(end)
+	if startDigits == "" || endDigits == "" {
+		return nil, fmt.Errorf("号段起止SN须以数字结尾")
+	}
+	if startPrefix != endPrefix {
+		return nil, fmt.Errorf("号段起止SN前缀不一致")
+	}
+	if len(startDigits) != len(endDigits) {
+		return nil, fmt.Errorf("号段起止SN数字位数不一致")
+	}
+
+	from, _ := strconv.ParseUint(startDigits, 10, 64)
+	to, _ := strconv.ParseUint(endDigits, 10, 64)
+	if to < from {
+		return nil, fmt.Errorf("结束SN小于起始SN")
+	}
+	if to-from+1 > terminalBatchMaxRange {
+		return nil, fmt.Errorf("单个号段不能超过%d台", terminalBatchMaxRange)
+	}
+
+	width := len(startDigits)
+	sns := make([]string, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		sns = append(sns, fmt.Sprintf("%s%0*d", startPrefix, width, n))
+	}
+	return sns, nil
+}
+
+// splitSNDigits 拆分SN的前缀与末尾数字（数字部分最多18位，超出部分计入前缀）
+func splitSNDigits(sn string) (string, string) {
+	i := len(sn)
+	for i > 0 && sn[i-1] >= '0' && sn[i-1] <= '9' {
+		i--
+	}
+	if len(sn)-i > 18 {
+		i = len(sn) - 18
+	}
+	return sn[:i], sn[i:]
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"xiangshoufu/internal/models"
+)
+
+func TestExpandSNRange(t *testing.T) {
+	sns, err := expandSNRange("AB0998", "AB1001")
+	if err != nil {
+		t.Fatalf("expandSNRange: %v", err)
+	}
+	if !reflect.DeepEqual(sns, []string{"AB0998", "AB0999", "AB1000", "AB1001"}) {
+		t.Errorf("sns = %v", sns)
+	}
+
+	long, err := expandSNRange("0000000000000000000001", "0000000000000000000003")
+	if err != nil || len(long) != 3 || long[2] != "0000000000000000000003" {
+		t.Errorf("超长数字号段 = %v, err=%v", long, err)
+	}
+
+	errCases := [][2]string{
+		{"AB0001", "AC0002"}, // 前缀不一致
+		{"AB001", "AB0002"},  // 位数不一致
+		{"AB0005", "AB0001"}, // 结束小于起始
+		{"ABX", "ABY"},       // 非数字结尾
+		{"A00000", "A10000"}, // 超过单个号段上限
+	}
+	for _, c := range errCases {
+		if _, err := expandSNRange(c[0], c[1]); err == nil {
+			t.Errorf("expandSNRange(%s, %s) 应报错", c[0], c[1])
+		}
+	}
+}
+
+func TestParseTerminalBatchRows(t *testing.T) {
+	records := [][]string{
+		{"终端SN", "结束SN", "品牌编码"},
+		{"SN001", "", "B1"},
+		{},
+		{" SN010 ", "SN012", ""},
+		{"", "SN020"},
+		{"SN030", "SN029"},
+	}
+
+	rows, rowErrors, err := parseTerminalBatchRows(records)
+	if err != nil {
+		t.Fatalf("parseTerminalBatchRows: %v", err)
+	}
+	wantSNs := []string{"SN001", "SN010", "SN011", "SN012"}
+	if len(rows) != len(wantSNs) {
+		t.Fatalf("rows = %+v", rows)
+	}
+	for i, sn := range wantSNs {
+		if rows[i].SN != sn {
+			t.Errorf("rows[%d].SN = %s, want %s", i, rows[i].SN, sn)
+		}
+	}
+	if rows[0].Row != 2 || rows[0].BrandCode != "B1" || rows[3].Row != 4 {
+		t.Errorf("行号/品牌错误: %+v", rows)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 5 || rowErrors[1].Row != 6 || rowErrors[1].SN != "SN030~SN029" {
+		t.Errorf("rowErrors = %+v", rowErrors)
+	}
+}
+
+func TestParseTerminalBatchRowsWithoutHeader(t *testing.T) {
+	rows, rowErrors, _ := parseTerminalBatchRows([][]string{{"P100", "P101"}, {"P200"}})
+	if len(rowErrors) != 0 || len(rows) != 3 || rows[2].SN != "P200" || rows[2].Row != 2 {
+		t.Errorf("rows = %+v, errors = %+v", rows, rowErrors)
+	}
+
+	rows, _, _ = parseTerminalBatchRows([][]string{{"前缀", "起始SN", "结束SN"}, {"XS", "08", "10"}})
+	if len(rows) != 3 || rows[0].SN != "XS08" || rows[2].SN != "XS10" {
+		t.Errorf("前缀号段 = %+v", rows)
+	}
+}
+
+func TestParseTerminalBatchRowsMaxRows(t *testing.T) {
+	// 每个号段1万台，第6个号段展开后超出单文件上限
+	var records [][]string
+	for i := 0; i < 8; i++ {
+		records = append(records, []string{fmt.Sprintf("R%d00000", i), fmt.Sprintf("R%d09999", i)})
+	}
+	rows, _, err := parseTerminalBatchRows(records)
+	if err == nil || rows != nil {
+		t.Fatalf("len(rows) = %d, err = %v, want max rows error", len(rows), err)
+	}
+	if !strings.Contains(err.Error(), "第6行") {
+		t.Errorf("err = %v, want stop at row 6", err)
+	}
+
+	rows, _, err = parseTerminalBatchRows(records[:5])
+	if err != nil || len(rows) != terminalBatchMaxRows {
+		t.Errorf("len(rows) = %d, err = %v, want exactly %d rows", len(rows), err, terminalBatchMaxRows)
+	}
+}
+
+func TestReadTerminalSheetCSV(t *testing.T) {
+	data := []byte("\xEF\xBB\xBFSN,结束SN\nA1\n\nA2,A3\n")
+	records, err := readTerminalSheet("终端.CSV", data)
+	if err != nil {
+		t.Fatalf("readTerminalSheet: %v", err)
+	}
+	want := [][]string{{"SN", "结束SN"}, {"A1"}, {}, {"A2", "A3"}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+
+	if _, err := readTerminalSheet("终端.xls", data); err == nil {
+		t.Error("不支持的格式应报错")
+	}
+}
+
+func TestCheckBatchTerminal(t *testing.T) {
+	job := &models.TerminalBatchJob{JobType: models.TerminalBatchJobTypeDistribute, AgentID: 1, ChannelID: 3, BrandCode: "B1"}
+	terminal := &models.Terminal{TerminalSN: "SN1", OwnerAgentID: 1, ChannelID: 3, ChannelCode: "HXT", BrandCode: "B1", ModelCode: "M1", Status: models.TerminalStatusAllocated}
+	row := models.TerminalBatchRow{Row: 2, SN: "SN1"}
+
+	if reason := checkBatchTerminal(job, row, terminal); reason != "" {
+		t.Errorf("应通过校验: %s", reason)
+	}
+	if reason := checkBatchTerminal(job, row, nil); reason != "终端不存在" {
+		t.Errorf("终端不存在: %s", reason)
+	}
+
+	other := *terminal
+	other.OwnerAgentID = 2
+	if reason := checkBatchTerminal(job, row, &other); reason != "终端不属于当前代理商" {
+		t.Errorf("归属校验: %s", reason)
+	}
+
+	if reason := checkBatchTerminal(job, models.TerminalBatchRow{SN: "SN1", ModelCode: "M2"}, terminal); !strings.Contains(reason, "型号") {
+		t.Errorf("行内型号校验: %s", reason)
+	}
+
+	bound := *terminal
+	bound.Status = models.TerminalStatusActivated
+	if reason := checkBatchTerminal(job, row, &bound); !strings.Contains(reason, "不允许") {
+		t.Errorf("状态校验: %s", reason)
+	}
+
+	job.JobType = models.TerminalBatchJobTypeRecall
+	if reason := checkBatchTerminal(job, row, &bound); !strings.Contains(reason, "回拨") {
+		t.Errorf("回拨状态校验: %s", reason)
+	}
+}
+
+func TestCheckBatchImportRow(t *testing.T) {
+	job := &models.TerminalBatchJob{JobType: models.TerminalBatchJobTypeImport, ChannelCode: "HXT", BrandCode: "B1", ModelCode: "M1"}
+	if reason := checkBatchImportRow(job, models.TerminalBatchRow{SN: "SN1", BrandCode: "b1"}, nil); reason != "" {
+		t.Errorf("应通过校验: %s", reason)
+	}
+	if reason := checkBatchImportRow(job, models.TerminalBatchRow{SN: "SN1"}, &models.Terminal{}); reason != "终端已存在" {
+		t.Errorf("已存在校验: %s", reason)
+	}
+	if reason := checkBatchImportRow(job, models.TerminalBatchRow{SN: "SN1", ChannelCode: "LKL"}, nil); !strings.Contains(reason, "通道") {
+		t.Errorf("通道校验: %s", reason)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,11 +16,14 @@ import (
 // - Q16: 跨级下发时系统自动按层级生成A→B→C的货款代扣链
 // - Q29: APP不能跨级，PC可以跨级（保留整个层级关系）
 type TerminalDistributeService struct {
-	terminalRepo          repository.TerminalRepository
-	distributeRepo        repository.TerminalDistributeRepository
-	agentRepo             repository.AgentRepository
-	deductionService      *DeductionService
-	goodsDeductionService *GoodsDeductionService // 货款代扣服务
+	terminalRepo           repository.TerminalRepository
+	distributeRepo         repository.TerminalDistributeRepository
+	agentRepo              repository.AgentRepository
+	hierarchyRepo          repository.HierarchyRepository
+	deductionService       *DeductionService
+	goodsDeductionService  *GoodsDeductionService          // 货款代扣服务
+	lifecycleService       *TerminalLifecycleService       // 终端生命周期服务
+	rewardLifecycleService *TerminalRewardLifecycleService // 终端奖励进度自动化服务
 }
 
 // NewTerminalDistributeService 创建终端下发服务
@@ -27,12 +31,14 @@ func NewTerminalDistributeService(
 	terminalRepo repository.TerminalRepository,
 	distributeRepo repository.TerminalDistributeRepository,
 	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
 	deductionService *DeductionService,
 ) *TerminalDistributeService {
 	return &TerminalDistributeService{
 		terminalRepo:     terminalRepo,
 		distributeRepo:   distributeRepo,
 		agentRepo:        agentRepo,
+		hierarchyRepo:    hierarchyRepo,
 		deductionService: deductionService,
 	}
 }
@@ -42,6 +48,16 @@ func (s *TerminalDistributeService) SetGoodsDeductionService(gds *GoodsDeduction
 	s.goodsDeductionService = gds
 }
 
+// SetLifecycleService 设置终端生命周期服务
+func (s *TerminalDistributeService) SetLifecycleService(lifecycleService *TerminalLifecycleService) {
+	s.lifecycleService = lifecycleService
+}
+
+// SetRewardLifecycleService 设置终端奖励进度自动化服务
+func (s *TerminalDistributeService) SetRewardLifecycleService(rewardLifecycleService *TerminalRewardLifecycleService) {
+	s.rewardLifecycleService = rewardLifecycleService
+}
+
 // DistributeTerminalRequest 终端下发请求
 type DistributeTerminalRequest struct {
 	FromAgentID      int64  `json:"from_agent_id"`      // 下发方代理商ID
@@ -76,8 +92,8 @@ func (s *TerminalDistributeService) DistributeTerminal(req *DistributeTerminalRe
 		return nil, fmt.Errorf("终端不属于当前代理商")
 	}
 
-	if terminal.Status != models.TerminalStatusPending && terminal.Status != models.TerminalStatusAllocated {
-		return nil, fmt.Errorf("终端状态不允许下发")
+	if _, _, err := checkTerminalTransition(terminal.Status, models.TerminalEventDistributeConfirm); err != nil {
+		return nil, fmt.Errorf("终端状态不允许下发: %w", err)
 	}
 
 	// 2. 验证下发方和接收方
@@ -140,27 +156,28 @@ func (s *TerminalDistributeService) checkCrossLevel(fromAgent, toAgent *reposito
 		return false, "", nil // 非跨级
 	}
 
-	// 检查toAgent是否在fromAgent的下级链中
-	// toAgent.Path应该包含fromAgent.ID
-	if toAgent.Path == "" {
-		return false, "", fmt.Errorf("接收方代理商路径为空")
+	// 检查toAgent是否在fromAgent的下级链中（按层级关系表取fromAgent到toAgent的路径）
+	path, err := s.hierarchyRepo.GetPathBetween(fromAgent.ID, toAgent.ID)
+	if err != nil {
+		return false, "", err
 	}
-
-	fromIDStr := fmt.Sprintf("/%d/", fromAgent.ID)
-	if !strings.Contains(toAgent.Path, fromIDStr) {
+	if len(path) < 2 {
 		return false, "", fmt.Errorf("接收方不是下发方的下级")
 	}
 
-	// 是跨级下发，提取路径
-	// 路径格式: /1/5/12/，toAgent.ID是12
-	// 从fromAgent.ID开始的子路径
-	idx := strings.Index(toAgent.Path, fromIDStr)
-	if idx == -1 {
-		return false, "", fmt.Errorf("无法解析跨级路径")
-	}
+	// 是跨级下发，路径格式: /1/5/12/，从fromAgent到toAgent
+	return true, formatCrossLevelPath(path), nil
+}
 
-	crossPath := toAgent.Path[idx:]
-	return true, crossPath, nil
+// formatCrossLevelPath 将代理商ID路径格式化为 /A/B/C/
+func formatCrossLevelPath(agentIDs []int64) string {
+	var b strings.Builder
+	b.WriteString("/")
+	for _, id := range agentIDs {
+		b.WriteString(strconv.FormatInt(id, 10))
+		b.WriteString("/")
+	}
+	return b.String()
 }
 
 // ConfirmDistribute 确认接收终端下发
@@ -181,15 +198,35 @@ func (s *TerminalDistributeService) ConfirmDistribute(distributeID int64, confir
 		return fmt.Errorf("终端不存在")
 	}
 
-	if err := s.terminalRepo.UpdateOwner(terminal.ID, distribute.ToAgentID); err != nil {
+	if s.lifecycleService == nil {
+		return fmt.Errorf("终端生命周期服务未初始化")
+	}
+	history, err := s.lifecycleService.Transition(terminal.TerminalSN, &TerminalTransitionRequest{
+		Event:           models.TerminalEventDistributeConfirm,
+		ExpectedOwnerID: distribute.FromAgentID,
+		ToOwnerID:       distribute.ToAgentID,
+		ActorType:       models.TerminalActorUser,
+		ActorID:         confirmedBy,
+		SourceType:      models.TerminalSourceDistribute,
+		SourceID:        distribute.ID,
+		SourceNo:        distribute.DistributeNo,
+	})
+	if err != nil {
 		return fmt.Errorf("更新终端所有权失败: %w", err)
 	}
 
+	// 换绑沿用进度时，解绑后下发给其他代理商的终端需终止原进度
+	if s.rewardLifecycleService != nil {
+		if err := s.rewardLifecycleService.OnTerminalEvent(history); err != nil {
+			log.Printf("[TerminalDistributeService] Terminate reward progress for %s failed: %v", terminal.TerminalSN, err)
+		}
+	}
+
 	// 3. 处理货款代扣
 	if distribute.GoodsPrice > 0 {
 		switch distribute.DeductionType {
 		case DistributeDeductionTypeRealtime:
-			// 货款代扣（实时扣款）- 使用新的GoodsDeductionService
+			// 货款代扣 - 由GoodsDeductionService创建一期待接收的统一代扣计划
 			if err := s.createGoodsDeductionForDistribute(distribute, terminal, confirmedBy); err != nil {
 				log.Printf("[TerminalDistributeService] Create goods deduction failed: %v", err)
 				// 货款代扣创建失败不阻塞终端下发
@@ -277,7 +314,7 @@ func (s *TerminalDistributeService) createDeductionPlanForDistribute(distribute
 	return s.distributeRepo.Update(distribute)
 }
 
-// createGoodsDeductionForDistribute 为终端下发创建货款代扣（实时扣款）
+// createGoodsDeductionForDistribute 为终端下发创建货款代扣（一期待接收的统一代扣计划）
 func (s *TerminalDistributeService) createGoodsDeductionForDistribute(distribute *models.TerminalDistribute, terminal *models.Terminal, createdBy int64) error {
 	if s.goodsDeductionService == nil {
 		return fmt.Errorf("货款代扣服务未初始化")
@@ -310,13 +347,14 @@ func (s *TerminalDistributeService) createGoodsDeductionForDistribute(distribute
 		return fmt.Errorf("创建货款代扣失败: %w", err)
 	}
 
-	// 更新下发记录的货款代扣ID
-	distribute.GoodsDeductionID = &deduction.ID
+	// 货款代扣已并入统一代扣计划，下发记录关联计划ID
+	distribute.DeductionPlanID = deduction.MigratedPlanID
 	if err := s.distributeRepo.Update(distribute); err != nil {
-		log.Printf("[TerminalDistributeService] Update distribute goods_deduction_id failed: %v", err)
+		log.Printf("[TerminalDistributeService] Update distribute deduction_plan_id failed: %v", err)
 	}
 
-	log.Printf("[TerminalDistributeService] Created goods deduction %d for distribute %d", deduction.ID, distribute.ID)
+	log.Printf("[TerminalDistributeService] Created goods deduction %d (plan %d) for distribute %d",
+		deduction.ID, *deduction.MigratedPlanID, distribute.ID)
 	return nil
 }
 
@@ -74,21 +74,6 @@ func (m *MockTerminalRepository) FindByOwner(ownerAgentID int64, status []int16,
 	return result, int64(len(result)), nil
 }
 
-func (m *MockTerminalRepository) UpdateOwner(id int64, newOwnerID int64) error {
-	if terminal, ok := m.terminals[id]; ok {
-		terminal.OwnerAgentID = newOwnerID
-		terminal.Status = models.TerminalStatusAllocated
-	}
-	return nil
-}
-
-func (m *MockTerminalRepository) UpdateStatus(id int64, status int16) error {
-	if terminal, ok := m.terminals[id]; ok {
-		terminal.Status = status
-	}
-	return nil
-}
-
 func (m *MockTerminalRepository) UpdateSimFeeCount(id int64, count int) error {
 	if terminal, ok := m.terminals[id]; ok {
 		terminal.SimFeeCount = count
@@ -213,6 +198,7 @@ func TestDistributeTerminal_Success_DirectLevel(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil, // deductionService可以为nil，因为这里只测试非分期场景
 	)
 
@@ -264,6 +250,7 @@ func TestDistributeTerminal_AppCrossLevel_Denied(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -308,6 +295,7 @@ func TestDistributeTerminal_PCCrossLevel_Allowed(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -351,6 +339,7 @@ func TestDistributeTerminal_TerminalNotFound(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -389,6 +378,7 @@ func TestDistributeTerminal_TerminalNotOwned(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -426,6 +416,7 @@ func TestRejectDistribute(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -469,6 +460,7 @@ func TestCancelDistribute(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -512,6 +504,7 @@ func TestCancelDistribute_NotOwner(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -551,6 +544,7 @@ func TestGetDistributeList(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -609,6 +603,7 @@ func TestCheckCrossLevel(t *testing.T) {
 		terminalRepo,
 		distributeRepo,
 		agentRepo,
+		&MockHierarchyRepository{agentRepo: agentRepo},
 		nil,
 	)
 
@@ -617,10 +612,11 @@ func TestCheckCrossLevel(t *testing.T) {
 		fromID         int64
 		toID           int64
 		wantCrossLevel bool
+		wantPath       string
 	}{
-		{"Direct: A->B", 1, 2, false},
-		{"Direct: B->C", 2, 3, false},
-		{"Cross: A->C", 1, 3, true},
+		{"Direct: A->B", 1, 2, false, ""},
+		{"Direct: B->C", 2, 3, false, ""},
+		{"Cross: A->C", 1, 3, true, "/1/2/3/"},
 	}
 
 	for _, tc := range testCases {
@@ -628,7 +624,7 @@ func TestCheckCrossLevel(t *testing.T) {
 			fromAgent, _ := agentRepo.FindByID(tc.fromID)
 			toAgent, _ := agentRepo.FindByID(tc.toID)
 
-			isCross, _, err := service.checkCrossLevel(fromAgent, toAgent)
+			isCross, path, err := service.checkCrossLevel(fromAgent, toAgent)
 			if err != nil {
 				t.Fatalf("checkCrossLevel failed: %v", err)
 			}
@@ -636,6 +632,9 @@ func TestCheckCrossLevel(t *testing.T) {
 			if isCross != tc.wantCrossLevel {
 				t.Errorf("isCrossLevel = %v, want %v", isCross, tc.wantCrossLevel)
 			}
+			if path != tc.wantPath {
+				t.Errorf("crossLevelPath = %q, want %q", path, tc.wantPath)
+			}
 		})
 	}
 }
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+	"xiangshoufu/pkg/label"
+)
+
+// 标签打印限制
+const (
+	maxTerminalLabels       = 1000 // 单次最多生成标签数（与单次入库上限一致）
+	defaultTerminalLabelDPI = 203  // 默认PNG分辨率（热敏标签打印机常用203dpi）
+)
+
+// TerminalLabelConfig 终端标签配置
+type TerminalLabelConfig struct {
+	VerifyBaseURL string // 二维码中的标签校验地址
+	Secret        string // 标签签名密钥
+}
+
+// DefaultTerminalLabelConfig 默认终端标签配置
+func DefaultTerminalLabelConfig() *TerminalLabelConfig {
+	return &TerminalLabelConfig{
+		VerifyBaseURL: "https://m.xiangshoufu.com/terminal/verify",
+		Secret:        "xiangshoufu-terminal-label-2026",
+	}
+}
+
+// TerminalLabelService 终端标签服务
+// 为入库批次或指定SN生成可打印的标签（Code128条码 + 含校验地址的二维码），并支持扫码反查终端
+type TerminalLabelService struct {
+	terminalRepo     *repository.GormTerminalRepository
+	importRecordRepo *repository.GormTerminalImportRecordRepository
+	historyRepo      *repository.GormTerminalStatusHistoryRepository
+	agentRepo        repository.AgentRepository
+	hierarchyRepo    repository.HierarchyRepository
+	config           *TerminalLabelConfig
+}
+
+// NewTerminalLabelService 创建终端标签服务
+func NewTerminalLabelService(
+	terminalRepo *repository.GormTerminalRepository,
+	importRecordRepo *repository.GormTerminalImportRecordRepository,
+	historyRepo *repository.GormTerminalStatusHistoryRepository,
+	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	config *TerminalLabelConfig,
+) *TerminalLabelService {
+	if config == nil {
+		config = DefaultTerminalLabelConfig()
+	}
+	return &TerminalLabelService{
+		terminalRepo:     terminalRepo,
+		importRecordRepo: importRecordRepo,
+		historyRepo:      historyRepo,
+		agentRepo:        agentRepo,
+		hierarchyRepo:    hierarchyRepo,
+		config:           config,
+	}
+}
+
+// TerminalLabelRequest 生成终端标签请求（入库批次与SN列表二选一）
+type TerminalLabelRequest struct {
+	ImportID int64         `json:"import_id"` // 入库批次ID
+	SNs      []string      `json:"sns"`       // 指定终端SN
+	Layout   *label.Layout `json:"layout"`    // 排版（毫米），默认60×40mm、每页3列6行
+	DPI      int           `json:"dpi"`       // PNG分辨率，默认203
+}
+
+// TerminalLabelFile 生成的标签文件
+type TerminalLabelFile struct {
+	Filename string
+	Data     []byte
+	Count    int
+}
+
+// GeneratePDF 生成A4标签页PDF
+func (s *TerminalLabelService) GeneratePDF(req *TerminalLabelRequest, operatorAgentID int64, isAdmin bool) (*TerminalLabelFile, error) {
+	items, name, err := s.buildLabels(req, operatorAgentID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := label.PDF(items, s.layout(req))
+	if err != nil {
+		return nil, err
+	}
+	return &TerminalLabelFile{Filename: name + ".pdf", Data: data, Count: len(items)}, nil
+}
+
+// GenerateZIP 生成单张标签PNG的压缩包，文件名为终端SN
+func (s *TerminalLabelService) GenerateZIP(req *TerminalLabelRequest, operatorAgentID int64, isAdmin bool) (*TerminalLabelFile, error) {
+	items, name, err := s.buildLabels(req, operatorAgentID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+	dpi := req.DPI
+	if dpi == 0 {
+		dpi = defaultTerminalLabelDPI
+	}
+
+	layout := s.layout(req)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, item := range items {
+		data, err := label.PNG(item, layout, dpi)
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.Create(item.Code + ".png")
+		if err != nil {
+			return nil, fmt.Errorf("写入压缩包失败: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("写入压缩包失败: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("写入压缩包失败: %w", err)
+	}
+	return &TerminalLabelFile{Filename: name + ".zip", Data: buf.Bytes(), Count: len(items)}, nil
+}
+
+// layout 请求排版，未指定时使用默认排版
+func (s *TerminalLabelService) layout(req *TerminalLabelRequest) label.Layout {
+	if req.Layout == nil {
+		return label.DefaultLayout()
+	}
+	return *req.Layout
+}
+
+// buildLabels 解析标签对应的终端并生成标签内容，返回标签及文件名（不含扩展名）
+func (s *TerminalLabelService) buildLabels(req *TerminalLabelRequest, operatorAgentID int64, isAdmin bool) ([]label.Label, string, error) {
+	if err := s.layout(req).Validate(); err != nil {
+		return nil, "", err
+	}
+
+	var sns []string
+	var name string
+	switch {
+	case req.ImportID > 0:
+		record, err := s.importRecordRepo.FindByID(req.ImportID)
+		if err != nil || record == nil {
+			return nil, "", errors.New("入库批次不存在")
+		}
+		if sns, err = s.historyRepo.FindSNsBySource(models.TerminalSourceImport, record.ImportNo); err != nil {
+			return nil, "", fmt.Errorf("查询入库批次终端失败: %w", err)
+		}
+		if len(sns) == 0 {
+			return nil, "", errors.New("入库批次没有可打印的终端")
+		}
+		name = "labels_" + record.ImportNo
+	case len(req.SNs) > 0:
+		sns = cleanTerminalSNs(req.SNs)
+		name = "labels_" + time.Now().Format("20060102150405")
+	default:
+		return nil, "", errors.New("请选择入库批次或终端SN")
+	}
+	if len(sns) > maxTerminalLabels {
+		return nil, "", fmt.Errorf("单次最多生成%d张标签", maxTerminalLabels)
+	}
+
+	terminals, err := s.terminalRepo.FindBySNs(sns)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询终端失败: %w", err)
+	}
+	bySN := make(map[string]*models.Terminal, len(terminals))
+	for _, t := range terminals {
+		bySN[t.TerminalSN] = t
+	}
+
+	access := newTerminalAccessChecker(s.hierarchyRepo, operatorAgentID, isAdmin)
+	items := make([]label.Label, 0, len(sns))
+	for _, sn := range sns {
+		terminal, ok := bySN[sn]
+		if !ok {
+			return nil, "", fmt.Errorf("终端不存在: %s", sn)
+		}
+		if !access.canAccess(terminal.OwnerAgentID) {
+			return nil, "", fmt.Errorf("无权打印终端%s的标签", sn)
+		}
+		items = append(items, label.Label{
+			Code:      terminal.TerminalSN,
+			QRContent: s.labelURL(terminal.TerminalSN, terminal.ChannelCode),
+			Lines:     terminalLabelLines(terminal),
+		})
+	}
+	return items, name, nil
+}
+
+// labelURL 生成二维码内容：校验地址携带SN、通道编码及签名
+func (s *TerminalLabelService) labelURL(sn, channelCode string) string {
+	query := url.Values{}
+	query.Set("sn", sn)
+	query.Set("ch", channelCode)
+	query.Set("sig", s.sign(sn, channelCode))
+	return s.config.VerifyBaseURL + "?" + query.Encode()
+}
+
+// sign 标签签名：HMAC-SHA256(SN|通道编码) 取前16位，防止伪造标签
+func (s *TerminalLabelService) sign(sn, channelCode string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.Secret))
+	mac.Write([]byte(sn + "|" + channelCode))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// TerminalLabelScanResult 扫码反查结果
+type TerminalLabelScanResult struct {
+	Source         string `json:"source"`   // qrcode:二维码 barcode:条码
+	Verified       bool   `json:"verified"` // 二维码签名校验通过（条码无签名，为false）
+	TerminalSN     string `json:"terminal_sn"`
+	ChannelID      int64  `json:"channel_id"`
+	ChannelCode    string `json:"channel_code"`
+	BrandCode      string `json:"brand_code"`
+	ModelCode      string `json:"model_code"`
+	Status         int16  `json:"status"`
+	StatusName     string `json:"status_name"`
+	OwnerAgentID   int64  `json:"owner_agent_id"`
+	OwnerAgentName string `json:"owner_agent_name"`
+	MerchantNo     string `json:"merchant_no"`
+}
+
+// Scan 根据扫码内容（二维码校验地址或条码SN）反查终端
+func (s *TerminalLabelService) Scan(content string, operatorAgentID int64, isAdmin bool) (*TerminalLabelScanResult, error) {
+	parsed, err := parseTerminalLabelContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	terminal, err := s.terminalRepo.FindBySN(parsed.SN)
+	if err != nil || terminal == nil {
+		return nil, fmt.Errorf("终端不存在: %s", parsed.SN)
+	}
+
+	result := &TerminalLabelScanResult{
+		Source:       parsed.Source,
+		TerminalSN:   terminal.TerminalSN,
+		ChannelID:    terminal.ChannelID,
+		ChannelCode:  terminal.ChannelCode,
+		BrandCode:    terminal.BrandCode,
+		ModelCode:    terminal.ModelCode,
+		Status:       terminal.Status,
+		StatusName:   getTerminalLifecycleStatusName(terminal.Status),
+		OwnerAgentID: terminal.OwnerAgentID,
+		MerchantNo:   terminal.MerchantNo,
+	}
+	if parsed.Source == terminalLabelSourceQRCode {
+		if !hmac.Equal([]byte(parsed.Sig), []byte(s.sign(parsed.SN, parsed.ChannelCode))) {
+			return nil, errors.New("标签校验失败，疑似伪造标签")
+		}
+		if parsed.ChannelCode != terminal.ChannelCode {
+			return nil, errors.New("标签通道与终端不一致")
+		}
+		result.Verified = true
+	}
+
+	if !newTerminalAccessChecker(s.hierarchyRepo, operatorAgentID, isAdmin).canAccess(terminal.OwnerAgentID) {
+		return nil, errors.New("无权查看该终端")
+	}
+	if owner, err := s.agentRepo.FindByID(terminal.OwnerAgentID); err == nil && owner != nil {
+		result.OwnerAgentName = owner.AgentName
+	}
+	return result, nil
+}
+
+// TerminalLabelVerifyResult 标签公开校验结果（不含归属信息）
+type TerminalLabelVerifyResult struct {
+	Valid       bool   `json:"valid"`
+	TerminalSN  string `json:"terminal_sn"`
+	ChannelCode string `json:"channel_code"`
+	BrandCode   string `json:"brand_code,omitempty"`
+	ModelCode   string `json:"model_code,omitempty"`
+}
+
+// Verify 公开校验标签真伪（二维码校验地址落地页使用）
+func (s *TerminalLabelService) Verify(sn, channelCode, sig string) *TerminalLabelVerifyResult {
+	result := &TerminalLabelVerifyResult{TerminalSN: sn, ChannelCode: channelCode}
+	if sn == "" || !hmac.Equal([]byte(sig), []byte(s.sign(sn, channelCode))) {
+		return result
+	}
+	terminal, err := s.terminalRepo.FindBySN(sn)
+	if err != nil || terminal == nil || terminal.ChannelCode != channelCode {
+		return result
+	}
+	result.Valid = true
+	result.BrandCode = terminal.BrandCode
+	result.ModelCode = terminal.ModelCode
+	return result
+}
+
+// 扫码来源
+const (
+	terminalLabelSourceQRCode  = "qrcode"
+	terminalLabelSourceBarcode = "barcode"
+)
+
+// terminalLabelContent 解析后的扫码内容
+type terminalLabelContent struct {
+	Source      string
+	SN          string
+	ChannelCode string
+	Sig         string
+}
+
+// parseTerminalLabelContent 解析扫码内容：带sn参数的URL视为二维码，否则视为条码SN
+func parseTerminalLabelContent(content string) (*terminalLabelContent, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, errors.New("扫码内容不能为空")
+	}
+
+	if strings.Contains(content, "://") {
+		u, err := url.Parse(content)
+		if err != nil {
+			return nil, errors.New("无法识别的标签二维码")
+		}
+		query := u.Query()
+		sn := strings.TrimSpace(query.Get("sn"))
+		if sn == "" {
+			return nil, errors.New("无法识别的标签二维码")
+		}
+		return &terminalLabelContent{
+			Source:      terminalLabelSourceQRCode,
+			SN:          sn,
+			ChannelCode: query.Get("ch"),
+			Sig:         query.Get("sig"),
+		}, nil
+	}
+	return &terminalLabelContent{Source: terminalLabelSourceBarcode, SN: content}, nil
+}
+
+// terminalLabelLines 标签附加文字：通道编码、品牌型号（点阵字体仅支持ASCII，使用编码而非中文名称）
+func terminalLabelLines(terminal *models.Terminal) []string {
+	lines := []string{terminal.ChannelCode}
+	if model := strings.TrimSpace(terminal.BrandCode + " " + terminal.ModelCode); model != "" {
+		lines = append(lines, model)
+	}
+	return lines
+}
+
+// cleanTerminalSNs 去除空白并去重，保持原有顺序
+func cleanTerminalSNs(sns []string) []string {
+	seen := make(map[string]bool, len(sns))
+	cleaned := make([]string, 0, len(sns))
+	for _, sn := range sns {
+		sn = strings.TrimSpace(sn)
+		if sn != "" && !seen[sn] {
+			seen[sn] = true
+			cleaned = append(cleaned, sn)
+		}
+	}
+	return cleaned
+}
+
+// terminalAccessChecker 校验终端归属是否在操作人本人或下级范围内（结果按代理商缓存）
+type terminalAccessChecker struct {
+	hierarchyRepo   repository.HierarchyRepository
+	operatorAgentID int64
+	isAdmin         bool
+	cache           map[int64]bool
+}
+
+func newTerminalAccessChecker(hierarchyRepo repository.HierarchyRepository, operatorAgentID int64, isAdmin bool) *terminalAccessChecker {
+	return &terminalAccessChecker{
+		hierarchyRepo:   hierarchyRepo,
+		operatorAgentID: operatorAgentID,
+		isAdmin:         isAdmin,
+		cache:           make(map[int64]bool),
+	}
+}
+
+func (c *terminalAccessChecker) canAccess(ownerAgentID int64) bool {
+	if c.isAdmin || ownerAgentID == c.operatorAgentID {
+		return true
+	}
+	if allowed, ok := c.cache[ownerAgentID]; ok {
+		return allowed
+	}
+	allowed, err := c.hierarchyRepo.IsDescendant(c.operatorAgentID, ownerAgentID)
+	if err != nil {
+		allowed = false
+	}
+	c.cache[ownerAgentID] = allowed
+	return allowed
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerminalLabelURLRoundTrip(t *testing.T) {
+	s := NewTerminalLabelService(nil, nil, nil, nil, nil, nil)
+	content := s.labelURL("SN 001&x", "HXT")
+	if !strings.HasPrefix(content, DefaultTerminalLabelConfig().VerifyBaseURL+"?") {
+		t.Fatalf("二维码内容应为校验地址: %s", content)
+	}
+
+	parsed, err := parseTerminalLabelContent(content)
+	if err != nil {
+		t.Fatalf("parseTerminalLabelContent error: %v", err)
+	}
+	if parsed.Source != terminalLabelSourceQRCode || parsed.SN != "SN 001&x" || parsed.ChannelCode != "HXT" {
+		t.Errorf("解析结果错误: %+v", parsed)
+	}
+	if parsed.Sig != s.sign("SN 001&x", "HXT") {
+		t.Errorf("签名不一致: %s", parsed.Sig)
+	}
+
+	other := NewTerminalLabelService(nil, nil, nil, nil, nil, &TerminalLabelConfig{Secret: "another"})
+	if other.sign("SN 001&x", "HXT") == parsed.Sig {
+		t.Error("不同密钥的签名不应相同")
+	}
+	if s.sign("SN 001&x", "LKL") == parsed.Sig {
+		t.Error("不同通道的签名不应相同")
+	}
+}
+
+func TestParseTerminalLabelContent(t *testing.T) {
+	parsed, err := parseTerminalLabelContent("  SN0001 \n")
+	if err != nil {
+		t.Fatalf("parseTerminalLabelContent error: %v", err)
+	}
+	if parsed.Source != terminalLabelSourceBarcode || parsed.SN != "SN0001" {
+		t.Errorf("条码内容解析错误: %+v", parsed)
+	}
+
+	if _, err := parseTerminalLabelContent(" "); err == nil {
+		t.Error("空内容应返回错误")
+	}
+	if _, err := parseTerminalLabelContent("https://m.xiangshoufu.com/register?code=abc"); err == nil {
+		t.Error("不含sn的二维码应返回错误")
+	}
+}
+
+func TestCleanTerminalSNs(t *testing.T) {
+	got := cleanTerminalSNs([]string{" B ", "A", "", "B", "C"})
+	if strings.Join(got, ",") != "B,A,C" {
+		t.Errorf("cleanTerminalSNs = %v", got)
+	}
+}
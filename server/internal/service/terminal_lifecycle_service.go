@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// terminalTransitionRule 终端状态流转规则
+type terminalTransitionRule struct {
+	from        []int16 // 允许流转的当前状态
+	to          int16   // 流转后状态
+	idempotent  []int16 // 已处于这些状态时视为重复事件，不流转也不报错
+	changeOwner bool    // 是否变更所属代理商
+}
+
+// terminalTransitions 终端状态机
+// 入库 → 待分配；下发确认 → 已分配；绑定 → 已绑定 → 激活 → 已激活；解绑 → 已解绑；回拨确认 → 已回收
+// 已解绑、已回收的终端回到代理商库存，可再次下发、回拨或绑定
+var terminalTransitions = map[string]terminalTransitionRule{
+	models.TerminalEventImport: {
+		from: []int16{0},
+		to:   models.TerminalStatusPending,
+	},
+	models.TerminalEventDistributeConfirm: {
+		from:        []int16{models.TerminalStatusPending, models.TerminalStatusAllocated, models.TerminalStatusUnbound, models.TerminalStatusRecycled},
+		to:          models.TerminalStatusAllocated,
+		changeOwner: true,
+	},
+	models.TerminalEventBind: {
+		from:       []int16{models.TerminalStatusPending, models.TerminalStatusAllocated, models.TerminalStatusUnbound, models.TerminalStatusRecycled},
+		to:         models.TerminalStatusBound,
+		idempotent: []int16{models.TerminalStatusBound, models.TerminalStatusActivated},
+	},
+	models.TerminalEventActivate: {
+		from:       []int16{models.TerminalStatusBound},
+		to:         models.TerminalStatusActivated,
+		idempotent: []int16{models.TerminalStatusActivated},
+	},
+	models.TerminalEventUnbind: {
+		from:       []int16{models.TerminalStatusBound, models.TerminalStatusActivated},
+		to:         models.TerminalStatusUnbound,
+		idempotent: []int16{models.TerminalStatusPending, models.TerminalStatusAllocated, models.TerminalStatusUnbound, models.TerminalStatusRecycled},
+	},
+	models.TerminalEventRecallConfirm: {
+		from:        []int16{models.TerminalStatusPending, models.TerminalStatusAllocated, models.TerminalStatusUnbound, models.TerminalStatusRecycled},
+		to:          models.TerminalStatusRecycled,
+		changeOwner: true,
+	},
+}
+
+// TerminalLifecycleService 终端生命周期服务
+// 终端状态只能通过本服务按事件流转，每次流转写入 terminal_status_history
+type TerminalLifecycleService struct {
+	historyRepo *repository.GormTerminalStatusHistoryRepository
+}
+
+// NewTerminalLifecycleService 创建终端生命周期服务
+func NewTerminalLifecycleService(historyRepo *repository.GormTerminalStatusHistoryRepository) *TerminalLifecycleService {
+	return &TerminalLifecycleService{
+		historyRepo: historyRepo,
+	}
+}
+
+// TerminalTransitionRequest 终端状态流转请求
+type TerminalTransitionRequest struct {
+	Event           string // 事件
+	ExpectedOwnerID int64  // 要求的当前所属代理商，0不校验
+	ToOwnerID       int64  // 下发/回拨的接收方代理商
+	MerchantNo      string // 绑定/解绑的商户号
+	ActorType       string // 操作方类型
+	ActorID         int64  // 操作人ID
+	ActorName       string // 操作人名称/通道编码
+	SourceType      string // 来源单据类型
+	SourceID        int64  // 来源单据ID
+	SourceNo        string // 来源单据编号
+	Remark          string // 备注
+}
+
+// TerminalTimelineItem 终端时间线节点
+type TerminalTimelineItem struct {
+	*models.TerminalStatusHistory
+	EventName      string `json:"event_name"`
+	FromStatusName string `json:"from_status_name"`
+	ToStatusName   string `json:"to_status_name"`
+}
+
+// Import 入库终端并记录入库流转
+func (s *TerminalLifecycleService) Import(terminals []*models.Terminal, req *TerminalTransitionRequest) error {
+	if len(terminals) == 0 {
+		return nil
+	}
+
+	req.Event = models.TerminalEventImport
+	rule := terminalTransitions[models.TerminalEventImport]
+	now := time.Now()
+	for _, t := range terminals {
+		t.Status = rule.to
+	}
+
+	return s.historyRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(terminals, 100).Error; err != nil {
+			return err
+		}
+
+		histories := make([]*models.TerminalStatusHistory, 0, len(terminals))
+		for _, t := range terminals {
+			histories = append(histories, newTerminalStatusHistory(t, req, rule.to, t.OwnerAgentID, now))
+		}
+		return tx.CreateInBatches(histories, 100).Error
+	})
+}
+
+// Transition 按事件流转终端状态，重复事件返回nil记录
+func (s *TerminalLifecycleService) Transition(terminalSN string, req *TerminalTransitionRequest) (*models.TerminalStatusHistory, error) {
+	var history *models.TerminalStatusHistory
+	err := s.historyRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		history, err = s.TransitionTx(tx, terminalSN, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// TransitionTx 在事务内流转终端状态（锁定终端行）
+func (s *TerminalLifecycleService) TransitionTx(tx *gorm.DB, terminalSN string, req *TerminalTransitionRequest) (*models.TerminalStatusHistory, error) {
+	var terminal models.Terminal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("terminal_sn = ?", terminalSN).First(&terminal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("终端不存在: %s", terminalSN)
+		}
+		return nil, fmt.Errorf("查询终端失败: %w", err)
+	}
+
+	if req.ExpectedOwnerID > 0 && terminal.OwnerAgentID != req.ExpectedOwnerID {
+		return nil, fmt.Errorf("终端 %s 已不属于代理商 %d", terminalSN, req.ExpectedOwnerID)
+	}
+
+	to, noop, err := checkTerminalTransition(terminal.Status, req.Event)
+	if err != nil {
+		return nil, fmt.Errorf("终端 %s %w", terminalSN, err)
+	}
+	if noop {
+		log.Printf("[TerminalLifecycle] Duplicate event %s ignored: %s, status: %d", req.Event, terminalSN, terminal.Status)
+		return nil, nil
+	}
+
+	toOwnerID := terminal.OwnerAgentID
+	if terminalTransitions[req.Event].changeOwner {
+		if req.ToOwnerID <= 0 {
+			return nil, fmt.Errorf("缺少接收方代理商")
+		}
+		toOwnerID = req.ToOwnerID
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.Terminal{}).Where("id = ?", terminal.ID).
+		Updates(terminalTransitionUpdates(&terminal, req, to, toOwnerID, now)).Error; err != nil {
+		return nil, fmt.Errorf("更新终端状态失败: %w", err)
+	}
+
+	history := newTerminalStatusHistory(&terminal, req, to, toOwnerID, now)
+	if err := tx.Create(history).Error; err != nil {
+		return nil, fmt.Errorf("记录终端状态流转失败: %w", err)
+	}
+
+	log.Printf("[TerminalLifecycle] %s: %s, status %d -> %d", req.Event, terminalSN, terminal.Status, to)
+	return history, nil
+}
+
+// GetTimeline 按SN获取终端时间线
+func (s *TerminalLifecycleService) GetTimeline(terminalSN string, page, pageSize int) ([]*TerminalTimelineItem, int64, error) {
+	histories, total, err := s.historyRepo.FindBySN(terminalSN, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]*TerminalTimelineItem, 0, len(histories))
+	for _, h := range histories {
+		items = append(items, &TerminalTimelineItem{
+			TerminalStatusHistory: h,
+			EventName:             getTerminalEventName(h.Event),
+			FromStatusName:        getTerminalLifecycleStatusName(h.FromStatus),
+			ToStatusName:          getTerminalLifecycleStatusName(h.ToStatus),
+		})
+	}
+	return items, total, nil
+}
+
+// checkTerminalTransition 校验事件能否作用于当前状态，返回流转后状态；重复事件返回 noop=true
+func checkTerminalTransition(status int16, event string) (int16, bool, error) {
+	rule, ok := terminalTransitions[event]
+	if !ok {
+		return 0, false, fmt.Errorf("未知的终端事件: %s", event)
+	}
+	for _, st := range rule.from {
+		if st == status {
+			return rule.to, false, nil
+		}
+	}
+	for _, st := range rule.idempotent {
+		if st == status {
+			return status, true, nil
+		}
+	}
+	return 0, false, fmt.Errorf("当前状态「%s」不允许%s",
+		getTerminalLifecycleStatusName(status), getTerminalEventName(event))
+}
+
+// terminalTransitionUpdates 生成终端流转的字段更新
+func terminalTransitionUpdates(terminal *models.Terminal, req *TerminalTransitionRequest, to int16, toOwnerID int64, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": now,
+	}
+	if toOwnerID != terminal.OwnerAgentID {
+		updates["owner_agent_id"] = toOwnerID
+	}
+
+	switch req.Event {
+	case models.TerminalEventBind:
+		updates["bound_at"] = now
+		if req.MerchantNo != "" {
+			updates["merchant_no"] = req.MerchantNo
+		}
+	case models.TerminalEventActivate:
+		if terminal.ActivatedAt == nil {
+			updates["activated_at"] = now
+		}
+	case models.TerminalEventUnbind:
+		updates["merchant_id"] = nil
+		updates["merchant_no"] = ""
+	}
+	return updates
+}
+
+// newTerminalStatusHistory 构造状态流转记录
+func newTerminalStatusHistory(terminal *models.Terminal, req *TerminalTransitionRequest, to int16, toOwnerID int64, now time.Time) *models.TerminalStatusHistory {
+	var from int16
+	fromOwnerID := terminal.OwnerAgentID
+	if req.Event == models.TerminalEventImport {
+		fromOwnerID = 0
+	} else {
+		from = terminal.Status
+	}
+
+	merchantNo := req.MerchantNo
+	if merchantNo == "" && req.Event == models.TerminalEventUnbind {
+		merchantNo = terminal.MerchantNo
+	}
+
+	return &models.TerminalStatusHistory{
+		TerminalID:  terminal.ID,
+		TerminalSN:  terminal.TerminalSN,
+		Event:       req.Event,
+		FromStatus:  from,
+		ToStatus:    to,
+		FromOwnerID: fromOwnerID,
+		ToOwnerID:   toOwnerID,
+		MerchantNo:  merchantNo,
+		ActorType:   req.ActorType,
+		ActorID:     req.ActorID,
+		ActorName:   req.ActorName,
+		SourceType:  req.SourceType,
+		SourceID:    req.SourceID,
+		SourceNo:    req.SourceNo,
+		Remark:      req.Remark,
+		CreatedAt:   now,
+	}
+}
+
+// getTerminalEventName 终端事件名称
+func getTerminalEventName(event string) string {
+	switch event {
+	case models.TerminalEventImport:
+		return "入库"
+	case models.TerminalEventDistributeConfirm:
+		return "下发确认"
+	case models.TerminalEventBind:
+		return "绑定"
+	case models.TerminalEventActivate:
+		return "激活"
+	case models.TerminalEventUnbind:
+		return "解绑"
+	case models.TerminalEventRecallConfirm:
+		return "回拨确认"
+	default:
+		return event
+	}
+}
+
+// getTerminalLifecycleStatusName 终端状态名称
+func getTerminalLifecycleStatusName(status int16) string {
+	switch status {
+	case 0:
+		return "未入库"
+	case models.TerminalStatusPending:
+		return "待分配"
+	case models.TerminalStatusAllocated:
+		return "已分配"
+	case models.TerminalStatusBound:
+		return "已绑定"
+	case models.TerminalStatusActivated:
+		return "已激活"
+	case models.TerminalStatusUnbound:
+		return "已解绑"
+	case models.TerminalStatusRecycled:
+		return "已回收"
+	default:
+		return "未知"
+	}
+}
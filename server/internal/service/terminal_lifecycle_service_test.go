@@ -0,0 +1,99 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+)
+
+func TestCheckTerminalTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int16
+		event   string
+		want    int16
+		noop    bool
+		wantErr bool
+	}{
+		{"入库", 0, models.TerminalEventImport, models.TerminalStatusPending, false, false},
+		{"重复入库", models.TerminalStatusPending, models.TerminalEventImport, 0, false, true},
+		{"待分配下发", models.TerminalStatusPending, models.TerminalEventDistributeConfirm, models.TerminalStatusAllocated, false, false},
+		{"已回收再下发", models.TerminalStatusRecycled, models.TerminalEventDistributeConfirm, models.TerminalStatusAllocated, false, false},
+		{"已绑定不可下发", models.TerminalStatusBound, models.TerminalEventDistributeConfirm, 0, false, true},
+		{"已分配绑定", models.TerminalStatusAllocated, models.TerminalEventBind, models.TerminalStatusBound, false, false},
+		{"已解绑再绑定", models.TerminalStatusUnbound, models.TerminalEventBind, models.TerminalStatusBound, false, false},
+		{"重复绑定", models.TerminalStatusActivated, models.TerminalEventBind, models.TerminalStatusActivated, true, false},
+		{"绑定后激活", models.TerminalStatusBound, models.TerminalEventActivate, models.TerminalStatusActivated, false, false},
+		{"未绑定不可激活", models.TerminalStatusAllocated, models.TerminalEventActivate, 0, false, true},
+		{"重复激活", models.TerminalStatusActivated, models.TerminalEventActivate, models.TerminalStatusActivated, true, false},
+		{"已激活解绑", models.TerminalStatusActivated, models.TerminalEventUnbind, models.TerminalStatusUnbound, false, false},
+		{"未绑定解绑", models.TerminalStatusAllocated, models.TerminalEventUnbind, models.TerminalStatusAllocated, true, false},
+		{"已分配回拨", models.TerminalStatusAllocated, models.TerminalEventRecallConfirm, models.TerminalStatusRecycled, false, false},
+		{"已激活不可回拨", models.TerminalStatusActivated, models.TerminalEventRecallConfirm, 0, false, true},
+		{"未知事件", models.TerminalStatusAllocated, "unknown", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		got, noop, err := checkTerminalTransition(tt.status, tt.event)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || noop != tt.noop {
+			t.Errorf("%s: got (%d, %v), want (%d, %v)", tt.name, got, noop, tt.want, tt.noop)
+		}
+	}
+}
+
+func TestTerminalTransitionUpdates(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	terminal := &models.Terminal{ID: 1, TerminalSN: "SN001", OwnerAgentID: 10, Status: models.TerminalStatusAllocated}
+
+	updates := terminalTransitionUpdates(terminal, &TerminalTransitionRequest{Event: models.TerminalEventBind, MerchantNo: "M001"}, models.TerminalStatusBound, 10, now)
+	if updates["status"] != int16(models.TerminalStatusBound) || updates["bound_at"] != now || updates["merchant_no"] != "M001" {
+		t.Errorf("绑定更新错误: %v", updates)
+	}
+	if _, ok := updates["owner_agent_id"]; ok {
+		t.Errorf("绑定不应变更所属代理商: %v", updates)
+	}
+
+	updates = terminalTransitionUpdates(terminal, &TerminalTransitionRequest{Event: models.TerminalEventRecallConfirm}, models.TerminalStatusRecycled, 5, now)
+	if updates["owner_agent_id"] != int64(5) || updates["status"] != int16(models.TerminalStatusRecycled) {
+		t.Errorf("回拨更新错误: %v", updates)
+	}
+
+	terminal.ActivatedAt = &now
+	updates = terminalTransitionUpdates(terminal, &TerminalTransitionRequest{Event: models.TerminalEventActivate}, models.TerminalStatusActivated, 10, now.Add(time.Hour))
+	if _, ok := updates["activated_at"]; ok {
+		t.Errorf("已有激活时间不应覆盖: %v", updates)
+	}
+
+	updates = terminalTransitionUpdates(terminal, &TerminalTransitionRequest{Event: models.TerminalEventUnbind}, models.TerminalStatusUnbound, 10, now)
+	if v, ok := updates["merchant_id"]; !ok || v != nil || updates["merchant_no"] != "" {
+		t.Errorf("解绑应清空商户: %v", updates)
+	}
+}
+
+func TestNewTerminalStatusHistory(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.Local)
+	terminal := &models.Terminal{ID: 1, TerminalSN: "SN001", OwnerAgentID: 10, Status: models.TerminalStatusActivated, MerchantNo: "M001"}
+
+	h := newTerminalStatusHistory(terminal, &TerminalTransitionRequest{
+		Event:      models.TerminalEventUnbind,
+		ActorType:  models.TerminalActorChannel,
+		ActorName:  "HENGXINTONG",
+		SourceType: models.TerminalSourceCallback,
+		SourceID:   99,
+	}, models.TerminalStatusUnbound, 10, now)
+	if h.FromStatus != models.TerminalStatusActivated || h.ToStatus != models.TerminalStatusUnbound ||
+		h.FromOwnerID != 10 || h.ToOwnerID != 10 || h.MerchantNo != "M001" || h.SourceID != 99 {
+		t.Errorf("解绑记录错误: %+v", h)
+	}
+
+	imported := &models.Terminal{ID: 2, TerminalSN: "SN002", OwnerAgentID: 10, Status: models.TerminalStatusPending}
+	h = newTerminalStatusHistory(imported, &TerminalTransitionRequest{Event: models.TerminalEventImport, SourceNo: "IMP1"}, models.TerminalStatusPending, 10, now)
+	if h.FromStatus != 0 || h.FromOwnerID != 0 || h.ToOwnerID != 10 || h.SourceNo != "IMP1" {
+		t.Errorf("入库记录错误: %+v", h)
+	}
+}
@@ -0,0 +1,893 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 回拨结算流水类型
+const (
+	WalletLogTypeRecallRefundOut int16 = 23 // 回拨退货款转出
+	WalletLogTypeRecallRefundIn  int16 = 24 // 回拨退货款转入
+	WalletLogTypeRecallClawback  int16 = 25 // 回拨奖励追回
+)
+
+// TerminalRecallSettlementService 终端回拨结算服务
+// 回拨确认后，按规则冲减下发时生成的货款代扣计划、退还已扣货款，并可追回已发放的激活奖励/押金返现
+type TerminalRecallSettlementService struct {
+	settlementRepo   *repository.GormTerminalRecallSettlementRepository
+	deductionService *DeductionService
+	riskHoldService  *WalletRiskHoldService
+}
+
+// NewTerminalRecallSettlementService 创建终端回拨结算服务
+func NewTerminalRecallSettlementService(
+	settlementRepo *repository.GormTerminalRecallSettlementRepository,
+	deductionService *DeductionService,
+) *TerminalRecallSettlementService {
+	return &TerminalRecallSettlementService{
+		settlementRepo:   settlementRepo,
+		deductionService: deductionService,
+	}
+}
+
+// SetRiskHoldService 设置风控冻结服务（退还货款时校验扣款方钱包冻结）
+func (s *TerminalRecallSettlementService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
+// defaultTerminalRecallSettlementConfig 默认回拨结算规则
+func defaultTerminalRecallSettlementConfig() *models.TerminalRecallSettlementConfig {
+	return &models.TerminalRecallSettlementConfig{
+		Enabled:           true,
+		ReduceOutstanding: true,
+		RefundPaid:        true,
+		RefundRate:        100,
+	}
+}
+
+// GetConfig 获取回拨结算规则（未配置时返回默认值）
+func (s *TerminalRecallSettlementService) GetConfig() (*models.TerminalRecallSettlementConfig, error) {
+	config, err := s.settlementRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取回拨结算规则失败: %w", err)
+	}
+	if config == nil {
+		return defaultTerminalRecallSettlementConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateTerminalRecallSettlementConfigRequest 更新回拨结算规则请求
+type UpdateTerminalRecallSettlementConfigRequest struct {
+	Enabled            bool `json:"enabled"`
+	ReduceOutstanding  bool `json:"reduce_outstanding"`
+	RefundPaid         bool `json:"refund_paid"`
+	RefundRate         int  `json:"refund_rate"`
+	ClawbackActivation bool `json:"clawback_activation"`
+	ClawbackDeposit    bool `json:"clawback_deposit"`
+}
+
+// UpdateConfig 更新回拨结算规则
+func (s *TerminalRecallSettlementService) UpdateConfig(req *UpdateTerminalRecallSettlementConfigRequest, operatorID int64, operatorName string) (*models.TerminalRecallSettlementConfig, error) {
+	if req.RefundRate < 0 || req.RefundRate > 100 {
+		return nil, errors.New("退还比例必须在0-100之间")
+	}
+
+	config, err := s.settlementRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取回拨结算规则失败: %w", err)
+	}
+	if config == nil {
+		config = &models.TerminalRecallSettlementConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.ReduceOutstanding = req.ReduceOutstanding
+	config.RefundPaid = req.RefundPaid
+	config.RefundRate = req.RefundRate
+	config.ClawbackActivation = req.ClawbackActivation
+	config.ClawbackDeposit = req.ClawbackDeposit
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+
+	if err := s.settlementRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存回拨结算规则失败: %w", err)
+	}
+	return config, nil
+}
+
+// pendingRecallRefund 扣款方余额不足、需事务提交后生成退款代扣计划的退款
+type pendingRecallRefund struct {
+	item       *models.TerminalRecallSettlementItem
+	payerID    int64 // 退款付款方（原货款扣款方）
+	receiverID int64 // 退款收款方（回拨方）
+	amount     int64
+}
+
+// recallSettlementRun 单次回拨结算的事务上下文
+type recallSettlementRun struct {
+	s       *TerminalRecallSettlementService
+	tx      *gorm.DB
+	recall  *models.TerminalRecall
+	config  *models.TerminalRecallSettlementConfig
+	now     time.Time
+	items   []*models.TerminalRecallSettlementItem
+	refunds []*pendingRecallRefund
+}
+
+// SettleRecall 结算已确认的终端回拨
+// 同一回拨只结算一次；结算失败时保持未结算状态，可由管理员重试
+func (s *TerminalRecallSettlementService) SettleRecall(recallID int64, operatorID int64) (*models.TerminalRecall, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var recall models.TerminalRecall
+	var refunds []*pendingRecallRefund
+	err = s.settlementRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&recall, recallID).Error; err != nil {
+			return fmt.Errorf("回拨记录不存在: %d", recallID)
+		}
+		if recall.Status != models.TerminalRecallStatusConfirmed {
+			return errors.New("回拨未确认，不能结算")
+		}
+		if recall.SettlementStatus != models.TerminalRecallSettlementPending {
+			return errors.New("回拨已结算")
+		}
+
+		run := &recallSettlementRun{s: s, tx: tx, recall: &recall, config: config, now: time.Now()}
+		if config.Enabled {
+			if err := run.settleGoods(); err != nil {
+				return err
+			}
+			if config.ClawbackActivation {
+				if err := run.clawbackActivationRewards(); err != nil {
+					return err
+				}
+			}
+			if config.ClawbackDeposit {
+				if err := run.clawbackDepositCashbacks(); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, item := range run.items {
+			if err := tx.Create(item).Error; err != nil {
+				return fmt.Errorf("创建结算明细失败: %w", err)
+			}
+		}
+
+		summary := summarizeRecallSettlement(run.items)
+		if !config.Enabled {
+			summary.Summary = "回拨结算规则未开启"
+		}
+		recall.SettlementStatus = summary.Status
+		recall.PlanReducedAmount = summary.PlanReducedAmount
+		recall.GoodsRefundAmount = summary.GoodsRefundAmount
+		recall.RewardClawbackAmount = summary.RewardClawbackAmount
+		recall.SettlementSummary = summary.Summary
+		recall.SettledAt = &run.now
+		if err := tx.Model(&models.TerminalRecall{}).Where("id = ?", recall.ID).Updates(map[string]interface{}{
+			"settlement_status":      recall.SettlementStatus,
+			"plan_reduced_amount":    recall.PlanReducedAmount,
+			"goods_refund_amount":    recall.GoodsRefundAmount,
+			"reward_clawback_amount": recall.RewardClawbackAmount,
+			"settlement_summary":     recall.SettlementSummary,
+			"settled_at":             recall.SettledAt,
+		}).Error; err != nil {
+			return fmt.Errorf("更新回拨结算状态失败: %w", err)
+		}
+
+		refunds = run.refunds
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 扣款方余额不足的部分，生成由原扣款方向回拨方还款的代扣计划
+	for _, refund := range refunds {
+		s.createRefundPlan(&recall, refund, operatorID)
+	}
+
+	log.Printf("[TerminalRecallSettlement] Settled recall %s: status=%d, reduced=%d, refund=%d, clawback=%d",
+		recall.RecallNo, recall.SettlementStatus, recall.PlanReducedAmount, recall.GoodsRefundAmount, recall.RewardClawbackAmount)
+
+	return &recall, nil
+}
+
+// RetryRewardClawback 继续追回回拨结算时因钱包余额不足未追回完的激活奖励/押金返现
+func (s *TerminalRecallSettlementService) RetryRewardClawback(recallID int64) (*models.TerminalRecall, error) {
+	var recall models.TerminalRecall
+	var recovered int64
+	err := s.settlementRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&recall, recallID).Error; err != nil {
+			return fmt.Errorf("回拨记录不存在: %d", recallID)
+		}
+
+		var items []*models.TerminalRecallSettlementItem
+		if err := tx.Where("recall_id = ? AND item_type IN ? AND status = ?", recall.ID,
+			[]string{models.RecallSettlementItemActivationClawback, models.RecallSettlementItemDepositClawback},
+			models.RecallSettlementItemStatusPartial).
+			Order("id ASC").Find(&items).Error; err != nil {
+			return fmt.Errorf("查询结算明细失败: %w", err)
+		}
+		if len(items) == 0 {
+			return errors.New("没有待继续追回的奖励")
+		}
+
+		run := &recallSettlementRun{s: s, tx: tx, recall: &recall, now: time.Now()}
+		for _, item := range items {
+			before := item.SettledAmount
+			if err := run.retryClawback(item); err != nil {
+				return err
+			}
+			recovered += item.SettledAmount - before
+		}
+		if recovered == 0 {
+			return errors.New("钱包可用余额不足，暂无可追回金额")
+		}
+
+		var all []*models.TerminalRecallSettlementItem
+		if err := tx.Where("recall_id = ?", recall.ID).Order("id ASC").Find(&all).Error; err != nil {
+			return fmt.Errorf("查询结算明细失败: %w", err)
+		}
+		summary := summarizeRecallSettlement(all)
+		recall.SettlementStatus = summary.Status
+		recall.RewardClawbackAmount = summary.RewardClawbackAmount
+		recall.SettlementSummary = summary.Summary
+		if err := tx.Model(&models.TerminalRecall{}).Where("id = ?", recall.ID).Updates(map[string]interface{}{
+			"settlement_status":      recall.SettlementStatus,
+			"reward_clawback_amount": recall.RewardClawbackAmount,
+			"settlement_summary":     recall.SettlementSummary,
+		}).Error; err != nil {
+			return fmt.Errorf("更新回拨结算状态失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[TerminalRecallSettlement] Retried reward clawback for recall %s: recovered=%d, status=%d",
+		recall.RecallNo, recovered, recall.SettlementStatus)
+
+	return &recall, nil
+}
+
+// createRefundPlan 为未能即时退还的货款生成退款代扣计划
+func (s *TerminalRecallSettlementService) createRefundPlan(recall *models.TerminalRecall, refund *pendingRecallRefund, operatorID int64) {
+	plan, err := s.deductionService.CreateDeductionPlan(&CreateDeductionPlanRequest{
+		DeductorID:   refund.receiverID,
+		DeducteeID:   refund.payerID,
+		PlanType:     models.DeductionPlanTypeGoods,
+		TotalAmount:  refund.amount,
+		TotalPeriods: 1,
+		RelatedType:  "terminal_recall",
+		RelatedID:    recall.ID,
+		Remark:       fmt.Sprintf("终端%s回拨退还货款", recall.TerminalSN),
+		CreatedBy:    operatorID,
+	})
+	if err != nil {
+		log.Printf("[TerminalRecallSettlement] Create refund plan failed for recall %s: %v", recall.RecallNo, err)
+		if err := s.settlementRepo.UpdateItem(refund.item.ID, map[string]interface{}{
+			"status": models.RecallSettlementItemStatusFailed,
+			"remark": truncateRecallRemark(fmt.Sprintf("%s；生成退款代扣计划失败，请人工处理", refund.item.Remark)),
+		}); err != nil {
+			log.Printf("[TerminalRecallSettlement] Update settlement item %d failed: %v", refund.item.ID, err)
+		}
+		return
+	}
+
+	if err := s.settlementRepo.UpdateItem(refund.item.ID, map[string]interface{}{
+		"refund_plan_id": plan.ID,
+		"remark":         truncateRecallRemark(fmt.Sprintf("%s；已生成退款代扣计划%s", refund.item.Remark, plan.PlanNo)),
+	}); err != nil {
+		log.Printf("[TerminalRecallSettlement] Update settlement item %d failed: %v", refund.item.ID, err)
+	}
+}
+
+// settleGoods 处理下发时的货款：冲减未扣部分、退还已扣部分
+func (r *recallSettlementRun) settleGoods() error {
+	var distribute models.TerminalDistribute
+	err := r.tx.Where("terminal_sn = ? AND to_agent_id = ? AND status = ?",
+		r.recall.TerminalSN, r.recall.FromAgentID, models.TerminalDistributeStatusConfirmed).
+		Order("confirmed_at DESC, id DESC").First(&distribute).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询终端下发记录失败: %w", err)
+	}
+	if distribute.GoodsPrice <= 0 {
+		return nil
+	}
+
+	if distribute.DeductionPlanID == nil {
+		item := r.newItem(models.RecallSettlementItemGoodsRefund, "terminal_distribute", distribute.ID,
+			distribute.FromAgentID, r.recall.FromAgentID, distribute.GoodsPrice)
+		item.Status = models.RecallSettlementItemStatusSkipped
+		if distribute.ChainID != nil {
+			item.RefType = "deduction_chain"
+			item.RefID = *distribute.ChainID
+			item.Remark = "跨级下发代扣链暂不支持自动结算，请人工处理"
+		} else {
+			item.Remark = "一次性付款货款未经系统代扣，请线下退还"
+		}
+		return nil
+	}
+
+	var plan models.DeductionPlan
+	if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, *distribute.DeductionPlanID).Error; err != nil {
+		return fmt.Errorf("代扣计划不存在: %d", *distribute.DeductionPlanID)
+	}
+	if plan.DeducteeID != r.recall.FromAgentID {
+		item := r.newItem(models.RecallSettlementItemPlanReduce, "deduction_plan", plan.ID,
+			plan.DeducteeID, plan.DeductorID, distribute.GoodsPrice)
+		item.Status = models.RecallSettlementItemStatusSkipped
+		item.Remark = "货款代扣计划的被扣款方不是回拨方，请人工处理"
+		return nil
+	}
+
+	var reduced int64
+	if r.config.ReduceOutstanding {
+		var err error
+		if reduced, err = r.reducePlan(&plan, distribute.GoodsPrice); err != nil {
+			return err
+		}
+	}
+
+	if r.config.RefundPaid {
+		refund := recallRefundAmount(distribute.GoodsPrice, reduced, plan.DeductedAmount, r.config.RefundRate)
+		if refund > 0 {
+			if err := r.refundGoods(&plan, refund); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reducePlan 按终端货款冲减代扣计划的未扣金额，返回冲减金额
+// 待接收计划直接调减总额；进行中/暂停计划从最后一期待扣记录向前冲减，逾期未扣的记录不冲减
+func (r *recallSettlementRun) reducePlan(plan *models.DeductionPlan, goodsPrice int64) (int64, error) {
+	switch plan.Status {
+	case models.DeductionPlanStatusPendingAccept, models.DeductionPlanStatusActive, models.DeductionPlanStatusPaused:
+	default:
+		return 0, nil
+	}
+
+	var pending []*models.DeductionRecord
+	if err := r.tx.Where("plan_id = ? AND status = ?", plan.ID, models.DeductionRecordStatusPending).
+		Order("period_num DESC").Find(&pending).Error; err != nil {
+		return 0, fmt.Errorf("查询待扣记录失败: %w", err)
+	}
+
+	reduce := min(goodsPrice, plan.RemainingAmount)
+	if plan.Status != models.DeductionPlanStatusPendingAccept {
+		amounts := make([]int64, len(pending))
+		var pendingTotal int64
+		for i, record := range pending {
+			amounts[i] = record.Amount
+			pendingTotal += record.Amount
+		}
+		reduce = min(reduce, pendingTotal)
+		if reduce <= 0 {
+			return 0, nil
+		}
+
+		cuts := allocateRecallReduction(amounts, reduce)
+		for i, record := range pending {
+			if cuts[i] <= 0 {
+				continue
+			}
+			updates := map[string]interface{}{"amount": record.Amount - cuts[i]}
+			if record.Amount == cuts[i] {
+				updates["status"] = models.DeductionRecordStatusSettled
+				updates["fail_reason"] = "终端回拨冲减"
+			}
+			if err := r.tx.Model(&models.DeductionRecord{}).Where("id = ?", record.ID).Updates(updates).Error; err != nil {
+				return 0, fmt.Errorf("冲减代扣记录失败: %w", err)
+			}
+		}
+	}
+	if reduce <= 0 {
+		return 0, nil
+	}
+
+	oldRemaining := plan.RemainingAmount
+	plan.TotalAmount -= reduce
+	plan.RemainingAmount -= reduce
+	plan.UpdatedAt = r.now
+	if plan.Status == models.DeductionPlanStatusPendingAccept && plan.RemainingAmount > 0 && plan.TotalPeriods > 0 {
+		plan.PeriodAmount = max(plan.RemainingAmount/int64(plan.TotalPeriods), 1)
+	}
+	if plan.RemainingAmount == 0 {
+		if plan.DeductedAmount > 0 {
+			plan.Status = models.DeductionPlanStatusCompleted
+			plan.CompletedAt = &r.now
+		} else {
+			plan.Status = models.DeductionPlanStatusCancelled
+		}
+		if err := r.tx.Model(&models.DeductionPlanChange{}).
+			Where("plan_id = ? AND status = ?", plan.ID, models.DeductionPlanChangeStatusPending).
+			Updates(map[string]interface{}{
+				"status":     models.DeductionPlanChangeStatusExpired,
+				"updated_at": r.now,
+			}).Error; err != nil {
+			return 0, fmt.Errorf("关闭重组提议失败: %w", err)
+		}
+	}
+
+	// 冻结金额超出剩余待扣金额的部分释放回被扣款方钱包
+	if excess := plan.FrozenAmount - plan.RemainingAmount; excess > 0 {
+		var wallets []*repository.Wallet
+		if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ?", plan.DeducteeID).Order("id ASC").Find(&wallets).Error; err != nil {
+			return 0, fmt.Errorf("锁定钱包失败: %w", err)
+		}
+		walletByID := make(map[int64]*repository.Wallet, len(wallets))
+		for _, w := range wallets {
+			walletByID[w.ID] = w
+		}
+		released, err := r.s.deductionService.releasePlanFrozenAmount(r.tx, plan, walletByID, excess, models.DeductionFreezeTriggerTypeRecall)
+		if err != nil {
+			return 0, err
+		}
+		plan.FrozenAmount -= released
+	}
+
+	if err := r.tx.Save(plan).Error; err != nil {
+		return 0, fmt.Errorf("更新代扣计划失败: %w", err)
+	}
+
+	item := r.newItem(models.RecallSettlementItemPlanReduce, "deduction_plan", plan.ID, plan.DeducteeID, plan.DeductorID, reduce)
+	item.SettledAmount = reduce
+	item.Remark = fmt.Sprintf("代扣计划%s剩余待扣%.2f元冲减为%.2f元", plan.PlanNo, float64(oldRemaining)/100, float64(plan.RemainingAmount)/100)
+	return reduce, nil
+}
+
+// refundGoods 由原扣款方退还已扣货款给回拨方
+// 优先从扣款方分润钱包（通道1，与代扣收款钱包一致）即时划转，余额不足部分事务提交后生成退款代扣计划
+func (r *recallSettlementRun) refundGoods(plan *models.DeductionPlan, refund int64) error {
+	payerID, receiverID := plan.DeductorID, plan.DeducteeID
+	item := r.newItem(models.RecallSettlementItemGoodsRefund, "deduction_plan", plan.ID, payerID, receiverID, refund)
+
+	var payerWallet repository.Wallet
+	err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("agent_id = ? AND channel_id = ? AND wallet_type = ?", payerID, 1, models.WalletTypeProfit).
+		First(&payerWallet).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("查询扣款方钱包失败: %w", err)
+	}
+
+	var transfer int64
+	var holdErr error
+	if err == nil {
+		transfer = min(refund, max(payerWallet.Balance-payerWallet.FrozenAmount, 0))
+	}
+	// 扣款方钱包风控冻结时不即时退还，全部转为后续代扣
+	if transfer > 0 && r.s.riskHoldService != nil {
+		if holdErr = r.s.riskHoldService.CheckWithdraw(payerID, &payerWallet, transfer); holdErr != nil {
+			transfer = 0
+		}
+	}
+	if transfer > 0 {
+		var receiverWallet repository.Wallet
+		if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("agent_id = ? AND channel_id = ? AND wallet_type = ?", receiverID, 1, models.WalletTypeProfit).
+			First(&receiverWallet).Error; err != nil {
+			return fmt.Errorf("回拨方钱包不存在: %w", err)
+		}
+
+		result := r.tx.Model(&repository.Wallet{}).
+			Where("id = ? AND balance - frozen_amount >= ?", payerWallet.ID, transfer).
+			Updates(map[string]interface{}{
+				"balance": gorm.Expr("balance - ?", transfer),
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("扣减扣款方余额失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("扣款方可用余额不足")
+		}
+		if err := r.tx.Model(&repository.Wallet{}).
+			Where("id = ?", receiverWallet.ID).
+			Updates(map[string]interface{}{
+				"balance": gorm.Expr("balance + ?", transfer),
+				"version": gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return fmt.Errorf("增加回拨方余额失败: %w", err)
+		}
+
+		remark := fmt.Sprintf("终端%s回拨退还货款", r.recall.TerminalSN)
+		logs := []*repository.WalletLog{
+			{
+				WalletID:      payerWallet.ID,
+				AgentID:       payerID,
+				WalletType:    payerWallet.WalletType,
+				LogType:       WalletLogTypeRecallRefundOut,
+				Amount:        -transfer,
+				BalanceBefore: payerWallet.Balance,
+				BalanceAfter:  payerWallet.Balance - transfer,
+				RefType:       "terminal_recall",
+				RefID:         r.recall.ID,
+				Remark:        remark,
+				CreatedAt:     r.now,
+			},
+			{
+				WalletID:      receiverWallet.ID,
+				AgentID:       receiverID,
+				WalletType:    receiverWallet.WalletType,
+				LogType:       WalletLogTypeRecallRefundIn,
+				Amount:        transfer,
+				BalanceBefore: receiverWallet.Balance,
+				BalanceAfter:  receiverWallet.Balance + transfer,
+				RefType:       "terminal_recall",
+				RefID:         r.recall.ID,
+				Remark:        remark,
+				CreatedAt:     r.now,
+			},
+		}
+		if err := r.tx.Create(&logs).Error; err != nil {
+			return fmt.Errorf("创建退款流水失败: %w", err)
+		}
+	}
+
+	item.SettledAmount = transfer
+	if shortfall := refund - transfer; shortfall > 0 {
+		item.Status = models.RecallSettlementItemStatusPartial
+		item.Remark = fmt.Sprintf("即时退还%.2f元，扣款方余额不足%.2f元", float64(transfer)/100, float64(shortfall)/100)
+		if holdErr != nil {
+			item.Remark = fmt.Sprintf("扣款方钱包风控冻结，%.2f元转为后续代扣", float64(shortfall)/100)
+		}
+		r.refunds = append(r.refunds, &pendingRecallRefund{
+			item:       item,
+			payerID:    payerID,
+			receiverID: receiverID,
+			amount:     shortfall,
+		})
+	} else {
+		item.Remark = fmt.Sprintf("已退还%.2f元至回拨方分润钱包", float64(transfer)/100)
+	}
+	return nil
+}
+
+// clawbackActivationRewards 追回回拨方在该终端上获得的激活奖励
+func (r *recallSettlementRun) clawbackActivationRewards() error {
+	var records []*models.ActivationRewardRecord
+	if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("terminal_sn = ? AND agent_id = ? AND wallet_status IN ?", r.recall.TerminalSN, r.recall.FromAgentID,
+			[]int16{models.RewardWalletStatusPending, models.RewardWalletStatusCredited}).
+		Order("id ASC").Find(&records).Error; err != nil {
+		return fmt.Errorf("查询激活奖励记录失败: %w", err)
+	}
+	for _, record := range records {
+		if err := r.clawback(models.RecallSettlementItemActivationClawback, "activation_reward", &models.ActivationRewardRecord{},
+			record.ID, record.AgentID, record.ChannelID, record.WalletType, record.WalletStatus, record.ActualReward); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clawbackDepositCashbacks 追回回拨方在该终端上获得的押金返现
+func (r *recallSettlementRun) clawbackDepositCashbacks() error {
+	var records []*models.DepositCashbackRecord
+	if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("terminal_sn = ? AND agent_id = ? AND wallet_status IN ?", r.recall.TerminalSN, r.recall.FromAgentID,
+			[]int16{models.RewardWalletStatusPending, models.RewardWalletStatusCredited}).
+		Order("id ASC").Find(&records).Error; err != nil {
+		return fmt.Errorf("查询押金返现记录失败: %w", err)
+	}
+	for _, record := range records {
+		if err := r.clawback(models.RecallSettlementItemDepositClawback, "deposit_cashback", &models.DepositCashbackRecord{},
+			record.ID, record.AgentID, record.ChannelID, record.WalletType, record.WalletStatus, record.ActualCashback); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clawback 追回单条奖励记录：未入账的直接作废，已入账的从对应钱包扣回
+// 余额不足时扣至可用余额为止，奖励记录置为部分追回，可由管理员通过RetryRewardClawback继续追回
+func (r *recallSettlementRun) clawback(itemType, refType string, recordModel interface{}, recordID, agentID, channelID int64,
+	walletType, walletStatus int16, amount int64) error {
+	if amount <= 0 {
+		return nil
+	}
+	item := r.newItem(itemType, refType, recordID, agentID, 0, amount)
+
+	status := models.RewardWalletStatusClawedBack
+	if walletStatus == models.RewardWalletStatusPending {
+		item.SettledAmount = amount
+		item.Remark = "奖励未入账，已作废"
+	} else {
+		debit, err := r.debitReward(itemType, agentID, channelID, walletType, amount)
+		if err != nil {
+			return err
+		}
+		item.SettledAmount = debit
+		if !setClawbackItemResult(item, walletType) {
+			status = models.RewardWalletStatusClawbackPartial
+		}
+	}
+
+	if err := r.tx.Model(recordModel).Where("id = ?", recordID).
+		Update("wallet_status", status).Error; err != nil {
+		return fmt.Errorf("更新奖励记录状态失败: %w", err)
+	}
+	return nil
+}
+
+// retryClawback 继续追回部分追回明细的剩余金额，全部追回后奖励记录置为已追回
+func (r *recallSettlementRun) retryClawback(item *models.TerminalRecallSettlementItem) error {
+	var recordModel interface{}
+	var channelID int64
+	var walletType, walletStatus int16
+	switch item.ItemType {
+	case models.RecallSettlementItemActivationClawback:
+		var record models.ActivationRewardRecord
+		if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, item.RefID).Error; err != nil {
+			return fmt.Errorf("激活奖励记录不存在: %d", item.RefID)
+		}
+		recordModel, channelID, walletType, walletStatus = &models.ActivationRewardRecord{}, record.ChannelID, record.WalletType, record.WalletStatus
+	case models.RecallSettlementItemDepositClawback:
+		var record models.DepositCashbackRecord
+		if err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record, item.RefID).Error; err != nil {
+			return fmt.Errorf("押金返现记录不存在: %d", item.RefID)
+		}
+		recordModel, channelID, walletType, walletStatus = &models.DepositCashbackRecord{}, record.ChannelID, record.WalletType, record.WalletStatus
+	default:
+		return nil
+	}
+	if walletStatus != models.RewardWalletStatusClawbackPartial {
+		return nil
+	}
+
+	debit, err := r.debitReward(item.ItemType, item.AgentID, channelID, walletType, item.Amount-item.SettledAmount)
+	if err != nil {
+		return err
+	}
+	if debit == 0 {
+		return nil
+	}
+	item.SettledAmount += debit
+	done := setClawbackItemResult(item, walletType)
+
+	if err := r.tx.Model(&models.TerminalRecallSettlementItem{}).Where("id = ?", item.ID).Updates(map[string]interface{}{
+		"settled_amount": item.SettledAmount,
+		"status":         item.Status,
+		"remark":         item.Remark,
+	}).Error; err != nil {
+		return fmt.Errorf("更新结算明细失败: %w", err)
+	}
+	if done {
+		if err := r.tx.Model(recordModel).Where("id = ?", item.RefID).
+			Update("wallet_status", models.RewardWalletStatusClawedBack).Error; err != nil {
+			return fmt.Errorf("更新奖励记录状态失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// debitReward 从奖励入账钱包扣回奖励，可用余额不足时扣至可用余额为止，返回实际扣回金额
+func (r *recallSettlementRun) debitReward(itemType string, agentID, channelID int64, walletType int16, amount int64) (int64, error) {
+	var wallet repository.Wallet
+	err := r.tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("agent_id = ? AND channel_id = ? AND wallet_type = ?", agentID, channelID, walletType).
+		First(&wallet).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询钱包失败: %w", err)
+	}
+
+	debit := min(amount, max(wallet.Balance-wallet.FrozenAmount, 0))
+	if debit <= 0 {
+		return 0, nil
+	}
+	result := r.tx.Model(&repository.Wallet{}).
+		Where("id = ? AND balance - frozen_amount >= ?", wallet.ID, debit).
+		Updates(map[string]interface{}{
+			"balance": gorm.Expr("balance - ?", debit),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("扣回奖励失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return 0, errors.New("钱包可用余额不足")
+	}
+	walletLog := &repository.WalletLog{
+		WalletID:      wallet.ID,
+		AgentID:       agentID,
+		WalletType:    wallet.WalletType,
+		LogType:       WalletLogTypeRecallClawback,
+		Amount:        -debit,
+		BalanceBefore: wallet.Balance,
+		BalanceAfter:  wallet.Balance - debit,
+		RefType:       "terminal_recall",
+		RefID:         r.recall.ID,
+		Remark:        fmt.Sprintf("终端%s回拨%s", r.recall.TerminalSN, models.GetRecallSettlementItemTypeName(itemType)),
+		CreatedAt:     r.now,
+	}
+	if err := r.tx.Create(walletLog).Error; err != nil {
+		return 0, fmt.Errorf("创建追回流水失败: %w", err)
+	}
+	return debit, nil
+}
+
+// setClawbackItemResult 按已追回金额设置追回明细的状态与备注，全部追回时返回true
+func setClawbackItemResult(item *models.TerminalRecallSettlementItem, walletType int16) bool {
+	if shortfall := item.Amount - item.SettledAmount; shortfall > 0 {
+		item.Status = models.RecallSettlementItemStatusPartial
+		item.Remark = fmt.Sprintf("钱包余额不足，已追回%.2f元，待追回%.2f元", float64(item.SettledAmount)/100, float64(shortfall)/100)
+		return false
+	}
+	item.Status = models.RecallSettlementItemStatusDone
+	item.Remark = fmt.Sprintf("已从%s追回", getWalletTypeName(walletType))
+	return true
+}
+
+// newItem 创建一条结算明细（默认已完成），事务末尾统一写入
+func (r *recallSettlementRun) newItem(itemType, refType string, refID, agentID, counterpartyID, amount int64) *models.TerminalRecallSettlementItem {
+	item := &models.TerminalRecallSettlementItem{
+		RecallID:       r.recall.ID,
+		RecallNo:       r.recall.RecallNo,
+		TerminalSN:     r.recall.TerminalSN,
+		ItemType:       itemType,
+		RefType:        refType,
+		RefID:          refID,
+		AgentID:        agentID,
+		CounterpartyID: counterpartyID,
+		Amount:         amount,
+		Status:         models.RecallSettlementItemStatusDone,
+		CreatedAt:      r.now,
+	}
+	r.items = append(r.items, item)
+	return item
+}
+
+// TerminalRecallSettlementDetail 回拨结算详情
+type TerminalRecallSettlementDetail struct {
+	Recall     *models.TerminalRecall                 `json:"recall"`
+	StatusName string                                 `json:"status_name"`
+	Items      []*models.TerminalRecallSettlementItem `json:"items"`
+}
+
+// GetSettlement 获取回拨结算详情（回拨双方或管理员可查看）
+func (s *TerminalRecallSettlementService) GetSettlement(recallID int64, agentID int64, isAdmin bool) (*TerminalRecallSettlementDetail, error) {
+	var recall models.TerminalRecall
+	if err := s.settlementRepo.GetDB().First(&recall, recallID).Error; err != nil {
+		return nil, fmt.Errorf("回拨记录不存在: %d", recallID)
+	}
+	if !isAdmin && recall.FromAgentID != agentID && recall.ToAgentID != agentID {
+		return nil, errors.New("无权查看此回拨结算")
+	}
+
+	items, err := s.settlementRepo.FindItemsByRecallID(recallID)
+	if err != nil {
+		return nil, fmt.Errorf("查询结算明细失败: %w", err)
+	}
+	for _, item := range items {
+		item.ItemTypeName = models.GetRecallSettlementItemTypeName(item.ItemType)
+		item.StatusName = models.GetRecallSettlementItemStatusName(item.Status)
+	}
+
+	return &TerminalRecallSettlementDetail{
+		Recall:     &recall,
+		StatusName: models.GetTerminalRecallSettlementStatusName(recall.SettlementStatus),
+		Items:      items,
+	}, nil
+}
+
+// recallSettlementSummary 回拨结算汇总
+type recallSettlementSummary struct {
+	Status               int16
+	PlanReducedAmount    int64
+	GoodsRefundAmount    int64
+	RewardClawbackAmount int64
+	Summary              string
+}
+
+// summarizeRecallSettlement 汇总结算明细
+// 退还货款按应退金额计入（含待生成退款代扣计划的部分），冲减与追回按实际处理金额计入
+func summarizeRecallSettlement(items []*models.TerminalRecallSettlementItem) recallSettlementSummary {
+	summary := recallSettlementSummary{Status: models.TerminalRecallSettlementNotRequired}
+	if len(items) == 0 {
+		summary.Summary = "无需结算"
+		return summary
+	}
+
+	summary.Status = models.TerminalRecallSettlementDone
+	var pendingRefund int64
+	var skipped int
+	for _, item := range items {
+		if item.Status != models.RecallSettlementItemStatusDone {
+			summary.Status = models.TerminalRecallSettlementPartial
+		}
+		if item.Status == models.RecallSettlementItemStatusSkipped {
+			skipped++
+			continue
+		}
+		switch item.ItemType {
+		case models.RecallSettlementItemPlanReduce:
+			summary.PlanReducedAmount += item.SettledAmount
+		case models.RecallSettlementItemGoodsRefund:
+			summary.GoodsRefundAmount += item.Amount
+			pendingRefund += item.Amount - item.SettledAmount
+		case models.RecallSettlementItemActivationClawback, models.RecallSettlementItemDepositClawback:
+			summary.RewardClawbackAmount += item.SettledAmount
+		}
+	}
+
+	var parts []string
+	if summary.PlanReducedAmount > 0 {
+		parts = append(parts, fmt.Sprintf("冲减未扣货款%.2f元", float64(summary.PlanReducedAmount)/100))
+	}
+	if summary.GoodsRefundAmount > 0 {
+		part := fmt.Sprintf("退还已扣货款%.2f元", float64(summary.GoodsRefundAmount)/100)
+		if pendingRefund > 0 {
+			part += fmt.Sprintf("（其中%.2f元转为退款代扣）", float64(pendingRefund)/100)
+		}
+		parts = append(parts, part)
+	}
+	if summary.RewardClawbackAmount > 0 {
+		parts = append(parts, fmt.Sprintf("追回奖励%.2f元", float64(summary.RewardClawbackAmount)/100))
+	}
+	if skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d项需人工处理", skipped))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "无可结算金额")
+	}
+	summary.Summary = truncateRecallRemark(strings.Join(parts, "；"))
+	return summary
+}
+
+// allocateRecallReduction 将冲减金额按顺序分摊到各期待扣金额上（调用方按期数倒序传入）
+func allocateRecallReduction(amounts []int64, reduce int64) []int64 {
+	cuts := make([]int64, len(amounts))
+	for i, amount := range amounts {
+		if reduce <= 0 {
+			break
+		}
+		cuts[i] = min(amount, reduce)
+		reduce -= cuts[i]
+	}
+	return cuts
+}
+
+// recallRefundAmount 计算应退还的已扣货款
+// 该终端未被冲减的货款中，最多按计划已扣金额视为已付，再按退还比例折算
+func recallRefundAmount(goodsPrice, reduced, deducted int64, refundRate int) int64 {
+	paid := min(goodsPrice-reduced, deducted)
+	if paid <= 0 || refundRate <= 0 {
+		return 0
+	}
+	return paid * int64(refundRate) / 100
+}
+
+// truncateRecallRemark 截断备注，避免超出字段长度
+func truncateRecallRemark(remark string) string {
+	runes := []rune(remark)
+	if len(runes) > 250 {
+		return string(runes[:250])
+	}
+	return remark
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"xiangshoufu/internal/models"
+)
+
+func TestAllocateRecallReduction(t *testing.T) {
+	// 按期数倒序传入：先冲减最后一期
+	cuts := allocateRecallReduction([]int64{3000, 3000, 4000}, 5000)
+	want := []int64{3000, 2000, 0}
+	for i := range want {
+		if cuts[i] != want[i] {
+			t.Errorf("cuts[%d] = %d, want %d", i, cuts[i], want[i])
+		}
+	}
+
+	cuts = allocateRecallReduction([]int64{1000, 1000}, 5000)
+	if cuts[0]+cuts[1] != 2000 {
+		t.Errorf("冲减金额不应超过待扣合计: %v", cuts)
+	}
+}
+
+func TestRecallRefundAmount(t *testing.T) {
+	cases := []struct {
+		name                          string
+		goodsPrice, reduced, deducted int64
+		rate                          int
+		want                          int64
+	}{
+		{"未扣部分全部冲减", 10000, 6000, 4000, 100, 4000},
+		{"已扣金额为上限", 10000, 0, 3000, 100, 3000},
+		{"按比例退还", 10000, 6000, 4000, 50, 2000},
+		{"全部冲减无需退还", 10000, 10000, 0, 100, 0},
+		{"比例为0", 10000, 0, 10000, 0, 0},
+		{"多台合并计划只退本台货款", 5000, 0, 20000, 100, 5000},
+	}
+	for _, tc := range cases {
+		if got := recallRefundAmount(tc.goodsPrice, tc.reduced, tc.deducted, tc.rate); got != tc.want {
+			t.Errorf("%s: recallRefundAmount = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSummarizeRecallSettlement(t *testing.T) {
+	summary := summarizeRecallSettlement(nil)
+	if summary.Status != models.TerminalRecallSettlementNotRequired {
+		t.Errorf("无明细应为无需结算，got %d", summary.Status)
+	}
+
+	summary = summarizeRecallSettlement([]*models.TerminalRecallSettlementItem{
+		{ItemType: models.RecallSettlementItemPlanReduce, Amount: 6000, SettledAmount: 6000, Status: models.RecallSettlementItemStatusDone},
+		{ItemType: models.RecallSettlementItemGoodsRefund, Amount: 4000, SettledAmount: 4000, Status: models.RecallSettlementItemStatusDone},
+		{ItemType: models.RecallSettlementItemActivationClawback, Amount: 5000, SettledAmount: 5000, Status: models.RecallSettlementItemStatusDone},
+	})
+	if summary.Status != models.TerminalRecallSettlementDone {
+		t.Errorf("全部完成应为已结算，got %d", summary.Status)
+	}
+	if summary.PlanReducedAmount != 6000 || summary.GoodsRefundAmount != 4000 || summary.RewardClawbackAmount != 5000 {
+		t.Errorf("汇总金额错误: %+v", summary)
+	}
+	if summary.Summary != "冲减未扣货款60.00元；退还已扣货款40.00元；追回奖励50.00元" {
+		t.Errorf("摘要错误: %s", summary.Summary)
+	}
+
+	summary = summarizeRecallSettlement([]*models.TerminalRecallSettlementItem{
+		{ItemType: models.RecallSettlementItemGoodsRefund, Amount: 4000, SettledAmount: 1000, Status: models.RecallSettlementItemStatusPartial},
+		{ItemType: models.RecallSettlementItemDepositClawback, Amount: 3000, SettledAmount: 0, Status: models.RecallSettlementItemStatusSkipped},
+	})
+	if summary.Status != models.TerminalRecallSettlementPartial {
+		t.Errorf("存在部分完成明细应为部分完成，got %d", summary.Status)
+	}
+	if summary.GoodsRefundAmount != 4000 || summary.RewardClawbackAmount != 0 {
+		t.Errorf("汇总金额错误: %+v", summary)
+	}
+	if !strings.Contains(summary.Summary, "其中30.00元转为退款代扣") || !strings.Contains(summary.Summary, "1项需人工处理") {
+		t.Errorf("摘要错误: %s", summary.Summary)
+	}
+}
+
+func TestSetClawbackItemResult(t *testing.T) {
+	partial := &models.TerminalRecallSettlementItem{Amount: 5000, SettledAmount: 2000}
+	if setClawbackItemResult(partial, models.WalletTypeReward) {
+		t.Errorf("partial clawback should not be done")
+	}
+	if partial.Status != models.RecallSettlementItemStatusPartial || !strings.Contains(partial.Remark, "待追回30.00元") {
+		t.Errorf("partial item = %d %q", partial.Status, partial.Remark)
+	}
+
+	// 继续追回剩余金额后置为已完成
+	partial.SettledAmount += 3000
+	if !setClawbackItemResult(partial, models.WalletTypeReward) {
+		t.Errorf("full clawback should be done")
+	}
+	if partial.Status != models.RecallSettlementItemStatusDone {
+		t.Errorf("status = %d, want %d", partial.Status, models.RecallSettlementItemStatusDone)
+	}
+}
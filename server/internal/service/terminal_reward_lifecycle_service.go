@@ -0,0 +1,518 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// rewardProgressAction 终端事件对奖励进度的处理动作
+type rewardProgressAction int
+
+const (
+	rewardProgressNone      rewardProgressAction = iota // 不处理
+	rewardProgressStart                                 // 开启奖励进度
+	rewardProgressTerminate                             // 终止奖励进度
+)
+
+// 存量补建单次处理上限
+const (
+	rewardBackfillDefaultLimit = 1000
+	rewardBackfillMaxLimit     = 10000
+	rewardBackfillBatchSize    = 200
+	rewardBackfillMaxErrors    = 20
+)
+
+// TerminalRewardLifecycleService 终端奖励进度自动化服务
+// 终端绑定/激活时按所属代理商、通道匹配奖励政策模版自动开启奖励进度，解绑、改变归属时按换绑规则终止，并支持存量终端补建
+type TerminalRewardLifecycleService struct {
+	ruleRepo      *repository.GormRewardProgressRuleRepository
+	terminalRepo  repository.TerminalRepository
+	agentRepo     repository.AgentRepository
+	hierarchyRepo repository.HierarchyRepository
+	rewardService *RewardService
+}
+
+// NewTerminalRewardLifecycleService 创建终端奖励进度自动化服务
+func NewTerminalRewardLifecycleService(
+	ruleRepo *repository.GormRewardProgressRuleRepository,
+	terminalRepo repository.TerminalRepository,
+	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+	rewardService *RewardService,
+) *TerminalRewardLifecycleService {
+	return &TerminalRewardLifecycleService{
+		ruleRepo:      ruleRepo,
+		terminalRepo:  terminalRepo,
+		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
+		rewardService: rewardService,
+	}
+}
+
+// defaultRewardProgressConfig 默认自动化规则
+func defaultRewardProgressConfig() *models.RewardProgressConfig {
+	return &models.RewardProgressConfig{
+		Enabled:    true,
+		StartEvent: models.RewardProgressStartBind,
+		RebindMode: models.RewardRebindRestart,
+	}
+}
+
+// GetConfig 获取自动化规则（未配置时返回默认值）
+func (s *TerminalRewardLifecycleService) GetConfig() (*models.RewardProgressConfig, error) {
+	config, err := s.ruleRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取奖励进度规则失败: %w", err)
+	}
+	if config == nil {
+		return defaultRewardProgressConfig(), nil
+	}
+	return config, nil
+}
+
+// UpdateRewardProgressConfigRequest 更新自动化规则请求
+type UpdateRewardProgressConfigRequest struct {
+	Enabled    bool   `json:"enabled"`
+	StartEvent string `json:"start_event" binding:"required"` // bind/activate
+	RebindMode string `json:"rebind_mode" binding:"required"` // restart/continue/once
+}
+
+// UpdateConfig 更新自动化规则
+func (s *TerminalRewardLifecycleService) UpdateConfig(req *UpdateRewardProgressConfigRequest, operatorID int64, operatorName string) (*models.RewardProgressConfig, error) {
+	switch req.StartEvent {
+	case models.RewardProgressStartBind, models.RewardProgressStartActivate:
+	default:
+		return nil, errors.New("开启时机只能为bind或activate")
+	}
+	switch req.RebindMode {
+	case models.RewardRebindRestart, models.RewardRebindContinue, models.RewardRebindOnce:
+	default:
+		return nil, errors.New("换绑规则只能为restart、continue或once")
+	}
+
+	config, err := s.ruleRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取奖励进度规则失败: %w", err)
+	}
+	if config == nil {
+		config = &models.RewardProgressConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.StartEvent = req.StartEvent
+	config.RebindMode = req.RebindMode
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+
+	if err := s.ruleRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存奖励进度规则失败: %w", err)
+	}
+	return config, nil
+}
+
+// ============================================================
+// 模版适用范围
+// ============================================================
+
+// RewardTemplateAssignmentRequest 模版适用范围请求
+type RewardTemplateAssignmentRequest struct {
+	AgentID    int64  `json:"agent_id"`                       // 代理商（含其下级），0为平台默认
+	ChannelID  int64  `json:"channel_id"`                     // 通道，0为全部通道
+	TemplateID int64  `json:"template_id" binding:"required"` // 奖励政策模版
+	Priority   int    `json:"priority"`                       // 优先级，大者优先
+	Enabled    *bool  `json:"enabled"`                        // 默认启用
+	Remark     string `json:"remark"`
+}
+
+// validateAssignment 校验模版、代理商是否存在
+func (s *TerminalRewardLifecycleService) validateAssignment(req *RewardTemplateAssignmentRequest) error {
+	if req.AgentID < 0 || req.ChannelID < 0 {
+		return errors.New("代理商或通道ID无效")
+	}
+	if _, err := s.rewardService.templateRepo.FindByID(req.TemplateID); err != nil {
+		return errors.New("奖励模版不存在")
+	}
+	if req.AgentID > 0 {
+		if agent, err := s.agentRepo.FindByID(req.AgentID); err != nil || agent == nil {
+			return errors.New("代理商不存在")
+		}
+	}
+	return nil
+}
+
+// CreateAssignment 创建模版适用范围
+func (s *TerminalRewardLifecycleService) CreateAssignment(req *RewardTemplateAssignmentRequest, operatorID int64) (*models.RewardTemplateAssignment, error) {
+	if err := s.validateAssignment(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	assignment := &models.RewardTemplateAssignment{
+		AgentID:    req.AgentID,
+		ChannelID:  req.ChannelID,
+		TemplateID: req.TemplateID,
+		Priority:   req.Priority,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		Remark:     req.Remark,
+		CreatedBy:  operatorID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.ruleRepo.CreateAssignment(assignment); err != nil {
+		return nil, fmt.Errorf("创建模版适用范围失败: %w", err)
+	}
+	return assignment, nil
+}
+
+// UpdateAssignment 更新模版适用范围
+func (s *TerminalRewardLifecycleService) UpdateAssignment(id int64, req *RewardTemplateAssignmentRequest) (*models.RewardTemplateAssignment, error) {
+	assignment, err := s.ruleRepo.FindAssignmentByID(id)
+	if err != nil {
+		return nil, errors.New("模版适用范围不存在")
+	}
+	if err := s.validateAssignment(req); err != nil {
+		return nil, err
+	}
+
+	assignment.AgentID = req.AgentID
+	assignment.ChannelID = req.ChannelID
+	assignment.TemplateID = req.TemplateID
+	assignment.Priority = req.Priority
+	if req.Enabled != nil {
+		assignment.Enabled = *req.Enabled
+	}
+	assignment.Remark = req.Remark
+	assignment.UpdatedAt = time.Now()
+	if err := s.ruleRepo.SaveAssignment(assignment); err != nil {
+		return nil, fmt.Errorf("更新模版适用范围失败: %w", err)
+	}
+	return assignment, nil
+}
+
+// DeleteAssignment 删除模版适用范围（已开启的奖励进度使用模版快照，不受影响）
+func (s *TerminalRewardLifecycleService) DeleteAssignment(id int64) error {
+	if _, err := s.ruleRepo.FindAssignmentByID(id); err != nil {
+		return errors.New("模版适用范围不存在")
+	}
+	return s.ruleRepo.DeleteAssignment(id)
+}
+
+// ListAssignments 查询模版适用范围
+func (s *TerminalRewardLifecycleService) ListAssignments(agentID, channelID, templateID *int64, page, pageSize int) ([]*models.RewardTemplateAssignment, int64, error) {
+	return s.ruleRepo.ListAssignments(agentID, channelID, templateID, pageSize, (page-1)*pageSize)
+}
+
+// ResolveAssignment 为代理商、通道匹配生效的模版适用范围，未匹配返回nil
+func (s *TerminalRewardLifecycleService) ResolveAssignment(agentID, channelID int64) (*models.RewardTemplateAssignment, error) {
+	var ancestors []*repository.Agent
+	if agentID > 0 {
+		var err error
+		ancestors, err = s.hierarchyRepo.FindAncestors(agentID, true)
+		if err != nil {
+			return nil, fmt.Errorf("查询代理商上级失败: %w", err)
+		}
+		if len(ancestors) == 0 {
+			return nil, fmt.Errorf("代理商不存在: %d", agentID)
+		}
+	}
+
+	chain := rewardAgentChain(agentID, ancestors)
+	candidates, err := s.ruleRepo.FindEnabledAssignments(chain, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("查询模版适用范围失败: %w", err)
+	}
+	return pickRewardTemplateAssignment(candidates, chain, channelID), nil
+}
+
+// TerminalRewardResolveResult 终端模版匹配结果
+type TerminalRewardResolveResult struct {
+	TerminalSN     string                           `json:"terminal_sn"`
+	OwnerAgentID   int64                            `json:"owner_agent_id"`
+	ChannelID      int64                            `json:"channel_id"`
+	Assignment     *models.RewardTemplateAssignment `json:"assignment"`      // 生效的适用范围，未匹配为null
+	ActiveProgress int64                            `json:"active_progress"` // 进行中的奖励进度数
+	TotalProgress  int64                            `json:"total_progress"`  // 历史奖励进度数
+}
+
+// ResolveTerminal 预览终端当前会匹配到的奖励模版
+func (s *TerminalRewardLifecycleService) ResolveTerminal(terminalSN string) (*TerminalRewardResolveResult, error) {
+	terminal, err := s.terminalRepo.FindBySN(terminalSN)
+	if err != nil || terminal == nil {
+		return nil, fmt.Errorf("终端不存在: %s", terminalSN)
+	}
+
+	assignment, err := s.ResolveAssignment(terminal.OwnerAgentID, terminal.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	active, total, err := s.ruleRepo.CountProgress(terminalSN)
+	if err != nil {
+		return nil, fmt.Errorf("查询奖励进度失败: %w", err)
+	}
+
+	return &TerminalRewardResolveResult{
+		TerminalSN:     terminal.TerminalSN,
+		OwnerAgentID:   terminal.OwnerAgentID,
+		ChannelID:      terminal.ChannelID,
+		Assignment:     assignment,
+		ActiveProgress: active,
+		TotalProgress:  total,
+	}, nil
+}
+
+// ============================================================
+// 终端事件
+// ============================================================
+
+// OnTerminalEvent 终端状态流转后按规则开启/终止奖励进度
+func (s *TerminalRewardLifecycleService) OnTerminalEvent(history *models.TerminalStatusHistory) error {
+	if history == nil {
+		return nil
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	if !config.Enabled {
+		return nil
+	}
+
+	active, total, err := s.ruleRepo.CountProgress(history.TerminalSN)
+	if err != nil {
+		return fmt.Errorf("查询奖励进度失败: %w", err)
+	}
+
+	switch decideRewardProgressAction(config, history.Event, active > 0, total > 0) {
+	case rewardProgressStart:
+		terminal, err := s.terminalRepo.FindBySN(history.TerminalSN)
+		if err != nil || terminal == nil {
+			return fmt.Errorf("终端不存在: %s", history.TerminalSN)
+		}
+		_, err = s.startProgress(terminal, history.ToOwnerID, history.CreatedAt)
+		return err
+	case rewardProgressTerminate:
+		return s.rewardService.TerminateTerminalRewardProgress(history.TerminalSN)
+	}
+	return nil
+}
+
+// startProgress 匹配模版并开启奖励进度，未匹配到模版返回nil
+func (s *TerminalRewardLifecycleService) startProgress(terminal *models.Terminal, agentID int64, bindTime time.Time) (*models.TerminalRewardProgress, error) {
+	assignment, err := s.ResolveAssignment(agentID, terminal.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		log.Printf("[TerminalRewardLifecycle] No reward template for terminal %s, agent %d, channel %d",
+			terminal.TerminalSN, agentID, terminal.ChannelID)
+		return nil, nil
+	}
+
+	terminalID := terminal.ID
+	return s.rewardService.InitTerminalRewardProgressAt(terminal.TerminalSN, &terminalID, agentID, assignment.TemplateID, bindTime)
+}
+
+// ============================================================
+// 存量终端补建
+// ============================================================
+
+// RewardProgressBackfillRequest 存量终端补建请求
+type RewardProgressBackfillRequest struct {
+	AfterID int64 `json:"after_id"` // 从该终端ID之后继续（上次结果的last_id）
+	Limit   int   `json:"limit"`    // 本次最多处理终端数，默认1000，最大10000
+	DryRun  bool  `json:"dry_run"`  // 仅预览匹配结果，不创建奖励进度
+}
+
+// RewardProgressBackfillResult 存量终端补建结果
+type RewardProgressBackfillResult struct {
+	DryRun    bool     `json:"dry_run"`
+	Scanned   int      `json:"scanned"`          // 扫描终端数
+	Created   int      `json:"created"`          // 开启奖励进度数（预览时为可开启数）
+	Unmatched int      `json:"unmatched"`        // 未匹配到模版
+	Failed    int      `json:"failed"`           // 失败数
+	Errors    []string `json:"errors,omitempty"` // 失败原因（最多20条）
+	LastID    int64    `json:"last_id"`          // 本次处理的最后一个终端ID
+	HasMore   bool     `json:"has_more"`         // 是否还有待处理终端
+}
+
+// Backfill 为已绑定/已激活但没有进行中奖励进度的存量终端补建奖励进度
+// 绑定时间取终端的绑定（激活）时间，阶段已结束的奖励由奖励检查任务按历史交易核算
+func (s *TerminalRewardLifecycleService) Backfill(req *RewardProgressBackfillRequest) (*RewardProgressBackfillResult, error) {
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = rewardBackfillDefaultLimit
+	}
+	limit = min(limit, rewardBackfillMaxLimit)
+
+	statuses := []int16{models.TerminalStatusBound, models.TerminalStatusActivated}
+	if config.StartEvent == models.RewardProgressStartActivate {
+		statuses = []int16{models.TerminalStatusActivated}
+	}
+	withoutAny := config.RebindMode == models.RewardRebindOnce
+
+	result := &RewardProgressBackfillResult{DryRun: req.DryRun}
+	afterID := req.AfterID
+	for result.Scanned < limit {
+		terminals, err := s.ruleRepo.FindTerminalsWithoutProgress(statuses, withoutAny, afterID, min(rewardBackfillBatchSize, limit-result.Scanned+1))
+		if err != nil {
+			return nil, fmt.Errorf("查询待补建终端失败: %w", err)
+		}
+		for _, terminal := range terminals {
+			if result.Scanned >= limit {
+				result.HasMore = true
+				break
+			}
+			result.Scanned++
+			afterID = terminal.ID
+			result.LastID = terminal.ID
+			s.backfillTerminal(terminal, config, result)
+		}
+		if result.HasMore || len(terminals) < rewardBackfillBatchSize {
+			break
+		}
+	}
+
+	log.Printf("[TerminalRewardLifecycle] Backfill done: dry_run=%v, scanned=%d, created=%d, unmatched=%d, failed=%d",
+		result.DryRun, result.Scanned, result.Created, result.Unmatched, result.Failed)
+	return result, nil
+}
+
+// backfillTerminal 补建单个终端的奖励进度
+func (s *TerminalRewardLifecycleService) backfillTerminal(terminal *models.Terminal, config *models.RewardProgressConfig, result *RewardProgressBackfillResult) {
+	fail := func(err error) {
+		result.Failed++
+		if len(result.Errors) < rewardBackfillMaxErrors {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", terminal.TerminalSN, err))
+		}
+	}
+
+	if result.DryRun {
+		assignment, err := s.ResolveAssignment(terminal.OwnerAgentID, terminal.ChannelID)
+		switch {
+		case err != nil:
+			fail(err)
+		case assignment == nil:
+			result.Unmatched++
+		default:
+			result.Created++
+		}
+		return
+	}
+
+	progress, err := s.startProgress(terminal, terminal.OwnerAgentID, rewardBackfillBindTime(terminal, config.StartEvent))
+	switch {
+	case err != nil:
+		fail(err)
+	case progress == nil:
+		result.Unmatched++
+	default:
+		result.Created++
+	}
+}
+
+// ============================================================
+// 规则计算
+// ============================================================
+
+// decideRewardProgressAction 根据规则及终端当前奖励进度决定事件的处理动作
+func decideRewardProgressAction(config *models.RewardProgressConfig, event string, hasActive, hasAny bool) rewardProgressAction {
+	switch event {
+	case models.TerminalEventRecallConfirm, models.TerminalEventDistributeConfirm:
+		// 终端归属变更，原绑定代理商不再享受奖励
+		if hasActive {
+			return rewardProgressTerminate
+		}
+		return rewardProgressNone
+	case models.TerminalEventUnbind:
+		if hasActive && config.RebindMode != models.RewardRebindContinue {
+			return rewardProgressTerminate
+		}
+		return rewardProgressNone
+	}
+
+	if event != config.StartEvent || hasActive {
+		return rewardProgressNone
+	}
+	if config.RebindMode == models.RewardRebindOnce && hasAny {
+		return rewardProgressNone
+	}
+	return rewardProgressStart
+}
+
+// rewardAgentChain 代理商自身及逐级上级（由近及远），最后为平台默认0
+// ancestors为层级仓库返回的上级链（从顶级到直属上级，可含自身）
+func rewardAgentChain(agentID int64, ancestors []*repository.Agent) []int64 {
+	chain := make([]int64, 0, 8)
+	seen := map[int64]bool{0: true}
+	if agentID > 0 {
+		chain = append(chain, agentID)
+		seen[agentID] = true
+	}
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		id := ancestors[i].ID
+		if seen[id] {
+			continue
+		}
+		chain = append(chain, id)
+		seen[id] = true
+	}
+	return append(chain, 0)
+}
+
+// pickRewardTemplateAssignment 按代理商链路由近及远匹配适用范围
+// 同一代理商下指定通道优先于全部通道，其次优先级高者、最后新建者优先
+func pickRewardTemplateAssignment(candidates []*models.RewardTemplateAssignment, chain []int64, channelID int64) *models.RewardTemplateAssignment {
+	for _, agentID := range chain {
+		var best *models.RewardTemplateAssignment
+		for _, a := range candidates {
+			if !a.Enabled || a.AgentID != agentID || (a.ChannelID != channelID && a.ChannelID != 0) {
+				continue
+			}
+			if best == nil || rewardAssignmentBetter(a, best) {
+				best = a
+			}
+		}
+		if best != nil {
+			return best
+		}
+	}
+	return nil
+}
+
+// rewardAssignmentBetter a是否优先于b
+func rewardAssignmentBetter(a, b *models.RewardTemplateAssignment) bool {
+	if (a.ChannelID != 0) != (b.ChannelID != 0) {
+		return a.ChannelID != 0
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.ID > b.ID
+}
+
+// rewardBackfillBindTime 存量终端补建时的绑定时间
+func rewardBackfillBindTime(terminal *models.Terminal, startEvent string) time.Time {
+	first, second := terminal.BoundAt, terminal.ActivatedAt
+	if startEvent == models.RewardProgressStartActivate {
+		first, second = second, first
+	}
+	if first != nil {
+		return *first
+	}
+	if second != nil {
+		return *second
+	}
+	return terminal.UpdatedAt
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestDecideRewardProgressAction(t *testing.T) {
+	restart := &models.RewardProgressConfig{Enabled: true, StartEvent: models.RewardProgressStartBind, RebindMode: models.RewardRebindRestart}
+	cont := &models.RewardProgressConfig{Enabled: true, StartEvent: models.RewardProgressStartBind, RebindMode: models.RewardRebindContinue}
+	once := &models.RewardProgressConfig{Enabled: true, StartEvent: models.RewardProgressStartBind, RebindMode: models.RewardRebindOnce}
+	onActivate := &models.RewardProgressConfig{Enabled: true, StartEvent: models.RewardProgressStartActivate, RebindMode: models.RewardRebindRestart}
+
+	tests := []struct {
+		name      string
+		config    *models.RewardProgressConfig
+		event     string
+		hasActive bool
+		hasAny    bool
+		want      rewardProgressAction
+	}{
+		{"首次绑定开启", restart, models.TerminalEventBind, false, false, rewardProgressStart},
+		{"已有进度不重复开启", restart, models.TerminalEventBind, true, true, rewardProgressNone},
+		{"绑定开启时激活不处理", restart, models.TerminalEventActivate, false, false, rewardProgressNone},
+		{"激活开启时绑定不处理", onActivate, models.TerminalEventBind, false, false, rewardProgressNone},
+		{"激活开启", onActivate, models.TerminalEventActivate, false, false, rewardProgressStart},
+		{"解绑终止", restart, models.TerminalEventUnbind, true, true, rewardProgressTerminate},
+		{"重新绑定重新开始", restart, models.TerminalEventBind, false, true, rewardProgressStart},
+		{"沿用进度时解绑不终止", cont, models.TerminalEventUnbind, true, true, rewardProgressNone},
+		{"沿用进度时换绑不重新开始", cont, models.TerminalEventBind, true, true, rewardProgressNone},
+		{"仅一次时解绑终止", once, models.TerminalEventUnbind, true, true, rewardProgressTerminate},
+		{"仅一次时重新绑定不开启", once, models.TerminalEventBind, false, true, rewardProgressNone},
+		{"回拨终止", cont, models.TerminalEventRecallConfirm, true, true, rewardProgressTerminate},
+		{"下发给其他代理商终止", cont, models.TerminalEventDistributeConfirm, true, true, rewardProgressTerminate},
+		{"无进度时解绑不处理", restart, models.TerminalEventUnbind, false, true, rewardProgressNone},
+		{"入库不处理", restart, models.TerminalEventImport, false, false, rewardProgressNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideRewardProgressAction(tt.config, tt.event, tt.hasActive, tt.hasAny); got != tt.want {
+				t.Errorf("decideRewardProgressAction() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewardAgentChain(t *testing.T) {
+	agents := func(ids ...int64) []*repository.Agent {
+		list := make([]*repository.Agent, 0, len(ids))
+		for _, id := range ids {
+			list = append(list, &repository.Agent{ID: id})
+		}
+		return list
+	}
+	tests := []struct {
+		name      string
+		agentID   int64
+		ancestors []*repository.Agent
+		want      []int64
+	}{
+		{"上级链含自身", 12, agents(1, 5, 12), []int64{12, 5, 1, 0}},
+		{"上级链不含自身", 12, agents(1, 5), []int64{12, 5, 1, 0}},
+		{"顶级代理商", 1, agents(1), []int64{1, 0}},
+		{"无代理商", 0, nil, []int64{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rewardAgentChain(tt.agentID, tt.ancestors)
+			if len(got) != len(tt.want) {
+				t.Fatalf("rewardAgentChain() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("rewardAgentChain() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPickRewardTemplateAssignment(t *testing.T) {
+	chain := []int64{12, 5, 1, 0}
+	candidates := []*models.RewardTemplateAssignment{
+		{ID: 1, AgentID: 0, ChannelID: 0, TemplateID: 100, Enabled: true},
+		{ID: 2, AgentID: 1, ChannelID: 0, TemplateID: 101, Enabled: true},
+		{ID: 3, AgentID: 1, ChannelID: 8, TemplateID: 102, Enabled: true},
+		{ID: 4, AgentID: 5, ChannelID: 9, TemplateID: 103, Enabled: true},
+		{ID: 5, AgentID: 12, ChannelID: 8, TemplateID: 104, Enabled: false},
+		{ID: 6, AgentID: 1, ChannelID: 0, TemplateID: 105, Priority: 10, Enabled: true},
+	}
+
+	tests := []struct {
+		name      string
+		channelID int64
+		want      int64
+	}{
+		{"上级指定通道优先于全部通道", 8, 102},
+		{"近级代理商优先", 9, 103},
+		{"全部通道按优先级", 7, 105},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pickRewardTemplateAssignment(candidates, chain, tt.channelID)
+			if got == nil || got.TemplateID != tt.want {
+				t.Errorf("pickRewardTemplateAssignment() = %+v, want template %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := pickRewardTemplateAssignment(candidates[:1], []int64{0}, 3); got == nil || got.TemplateID != 100 {
+		t.Errorf("应匹配平台默认模版, got %+v", got)
+	}
+	if got := pickRewardTemplateAssignment(candidates[3:5], chain, 8); got != nil {
+		t.Errorf("不应匹配到模版, got %+v", got)
+	}
+}
+
+func TestRewardBackfillBindTime(t *testing.T) {
+	bound := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	activated := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	updated := time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)
+
+	terminal := &models.Terminal{BoundAt: &bound, ActivatedAt: &activated, UpdatedAt: updated}
+	if got := rewardBackfillBindTime(terminal, models.RewardProgressStartBind); !got.Equal(bound) {
+		t.Errorf("绑定开启应取绑定时间, got %v", got)
+	}
+	if got := rewardBackfillBindTime(terminal, models.RewardProgressStartActivate); !got.Equal(activated) {
+		t.Errorf("激活开启应取激活时间, got %v", got)
+	}
+
+	terminal.BoundAt = nil
+	if got := rewardBackfillBindTime(terminal, models.RewardProgressStartBind); !got.Equal(activated) {
+		t.Errorf("无绑定时间应取激活时间, got %v", got)
+	}
+	terminal.ActivatedAt = nil
+	if got := rewardBackfillBindTime(terminal, models.RewardProgressStartActivate); !got.Equal(updated) {
+		t.Errorf("均无时应取更新时间, got %v", got)
+	}
+}
@@ -14,11 +14,15 @@ import (
 
 // TerminalService 终端服务
 type TerminalService struct {
-	terminalRepo      *repository.GormTerminalRepository
-	recallRepo        *repository.GormTerminalRecallRepository
-	importRecordRepo  *repository.GormTerminalImportRecordRepository
-	agentRepo         repository.AgentRepository
-	rateSyncService   *RateSyncService // 费率同步服务（用于通道实时交互）
+	terminalRepo            *repository.GormTerminalRepository
+	recallRepo              *repository.GormTerminalRecallRepository
+	importRecordRepo        *repository.GormTerminalImportRecordRepository
+	agentRepo               repository.AgentRepository
+	hierarchyRepo           repository.HierarchyRepository
+	rateSyncService         *RateSyncService                 // 费率同步服务（用于通道实时交互）
+	lifecycleService        *TerminalLifecycleService        // 终端生命周期服务
+	recallSettlementService *TerminalRecallSettlementService // 终端回拨结算服务
+	rewardLifecycleService  *TerminalRewardLifecycleService  // 终端奖励进度自动化服务
 }
 
 // NewTerminalService 创建终端服务
@@ -27,12 +31,14 @@ func NewTerminalService(
 	recallRepo *repository.GormTerminalRecallRepository,
 	importRecordRepo *repository.GormTerminalImportRecordRepository,
 	agentRepo repository.AgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
 ) *TerminalService {
 	return &TerminalService{
 		terminalRepo:     terminalRepo,
 		recallRepo:       recallRepo,
 		importRecordRepo: importRecordRepo,
 		agentRepo:        agentRepo,
+		hierarchyRepo:    hierarchyRepo,
 	}
 }
 
@@ -41,6 +47,29 @@ func (s *TerminalService) SetRateSyncService(rateSyncService *RateSyncService) {
 	s.rateSyncService = rateSyncService
 }
 
+// SetLifecycleService 设置终端生命周期服务
+func (s *TerminalService) SetLifecycleService(lifecycleService *TerminalLifecycleService) {
+	s.lifecycleService = lifecycleService
+}
+
+// SetRecallSettlementService 设置终端回拨结算服务（可选注入）
+func (s *TerminalService) SetRecallSettlementService(recallSettlementService *TerminalRecallSettlementService) {
+	s.recallSettlementService = recallSettlementService
+}
+
+// SetRewardLifecycleService 设置终端奖励进度自动化服务（可选注入）
+func (s *TerminalService) SetRewardLifecycleService(rewardLifecycleService *TerminalRewardLifecycleService) {
+	s.rewardLifecycleService = rewardLifecycleService
+}
+
+// GetTerminalTimeline 获取终端状态流转时间线
+func (s *TerminalService) GetTerminalTimeline(terminalSN string, page, pageSize int) ([]*TerminalTimelineItem, int64, error) {
+	if s.lifecycleService == nil {
+		return nil, 0, fmt.Errorf("终端生命周期服务未初始化")
+	}
+	return s.lifecycleService.GetTimeline(terminalSN, page, pageSize)
+}
+
 // ImportTerminalsRequest 终端入库请求
 type ImportTerminalsRequest struct {
 	ChannelID    int64    `json:"channel_id"`    // 通道ID
@@ -127,7 +156,15 @@ func (s *TerminalService) ImportTerminals(req *ImportTerminalsRequest) (*ImportT
 	}
 
 	if len(terminals) > 0 {
-		if err := s.terminalRepo.BatchCreate(terminals); err != nil {
+		if s.lifecycleService == nil {
+			return nil, fmt.Errorf("终端生命周期服务未初始化")
+		}
+		if err := s.lifecycleService.Import(terminals, &TerminalTransitionRequest{
+			ActorType:  models.TerminalActorUser,
+			ActorID:    req.CreatedBy,
+			SourceType: models.TerminalSourceImport,
+			SourceNo:   importNo,
+		}); err != nil {
 			return nil, fmt.Errorf("批量创建终端失败: %w", err)
 		}
 	}
@@ -189,9 +226,9 @@ func (s *TerminalService) RecallTerminal(req *RecallTerminalRequest) (*models.Te
 		return nil, fmt.Errorf("终端不属于当前代理商")
 	}
 
-	// 只有未激活的终端才能回拨
-	if terminal.Status == models.TerminalStatusActivated {
-		return nil, fmt.Errorf("已激活的终端不能回拨")
+	// 只有在库（未绑定商户）的终端才能回拨
+	if _, _, err := checkTerminalTransition(terminal.Status, models.TerminalEventRecallConfirm); err != nil {
+		return nil, fmt.Errorf("终端状态不允许回拨: %w", err)
 	}
 
 	// 2. 验证回拨方和接收方
@@ -253,26 +290,17 @@ func (s *TerminalService) checkCrossLevelRecall(fromAgent, toAgent *repository.A
 		return false, "", nil // 非跨级
 	}
 
-	// 检查toAgent是否在fromAgent的上级链中
-	// fromAgent.Path应该包含toAgent.ID
-	if fromAgent.Path == "" {
-		return false, "", fmt.Errorf("回拨方代理商路径为空")
+	// 检查toAgent是否在fromAgent的上级链中（按层级关系表取toAgent到fromAgent的路径）
+	path, err := s.hierarchyRepo.GetPathBetween(toAgent.ID, fromAgent.ID)
+	if err != nil {
+		return false, "", err
 	}
-
-	toIDStr := fmt.Sprintf("/%d/", toAgent.ID)
-	if !strings.Contains(fromAgent.Path, toIDStr) {
+	if len(path) < 2 {
 		return false, "", fmt.Errorf("接收方不是回拨方的上级")
 	}
 
-	// 是跨级回拨，提取路径
-	// 从toAgent.ID到fromAgent.ID的路径
-	idx := strings.Index(fromAgent.Path, toIDStr)
-	if idx == -1 {
-		return false, "", fmt.Errorf("无法解析跨级路径")
-	}
-
-	crossPath := fromAgent.Path[idx:]
-	return true, crossPath, nil
+	// 是跨级回拨，路径从toAgent到fromAgent
+	return true, formatCrossLevelPath(path), nil
 }
 
 // ConfirmRecall 确认接收终端回拨
@@ -293,7 +321,20 @@ func (s *TerminalService) ConfirmRecall(recallID int64, confirmedBy int64) error
 		return fmt.Errorf("终端不存在")
 	}
 
-	if err := s.terminalRepo.UpdateOwner(terminal.ID, recall.ToAgentID); err != nil {
+	if s.lifecycleService == nil {
+		return fmt.Errorf("终端生命周期服务未初始化")
+	}
+	history, err := s.lifecycleService.Transition(terminal.TerminalSN, &TerminalTransitionRequest{
+		Event:           models.TerminalEventRecallConfirm,
+		ExpectedOwnerID: recall.FromAgentID,
+		ToOwnerID:       recall.ToAgentID,
+		ActorType:       models.TerminalActorUser,
+		ActorID:         confirmedBy,
+		SourceType:      models.TerminalSourceRecall,
+		SourceID:        recall.ID,
+		SourceNo:        recall.RecallNo,
+	})
+	if err != nil {
 		return fmt.Errorf("更新终端所有权失败: %w", err)
 	}
 
@@ -302,6 +343,20 @@ func (s *TerminalService) ConfirmRecall(recallID int64, confirmedBy int64) error
 		return fmt.Errorf("更新回拨记录状态失败: %w", err)
 	}
 
+	// 4. 回拨结算（冲减/退还货款、追回奖励），失败不影响回拨确认，可由管理员重试
+	if s.recallSettlementService != nil {
+		if _, err := s.recallSettlementService.SettleRecall(recallID, confirmedBy); err != nil {
+			log.Printf("[TerminalService] Settle recall %d failed: %v", recallID, err)
+		}
+	}
+
+	// 5. 终止回拨终端的奖励进度
+	if s.rewardLifecycleService != nil {
+		if err := s.rewardLifecycleService.OnTerminalEvent(history); err != nil {
+			log.Printf("[TerminalService] Terminate reward progress for recall %d failed: %v", recallID, err)
+		}
+	}
+
 	log.Printf("[TerminalService] Confirmed recall: %d", recallID)
 	return nil
 }
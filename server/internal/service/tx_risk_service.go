@@ -0,0 +1,661 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// 风控配置缓存时间，多实例部署时配置修改最迟在该时间后生效
+const txRiskConfigTTL = time.Minute
+
+// 展示给代理商的冻结说明
+const txRiskHoldAgentMessage = "您有分润涉及交易风控核查，核查期间该部分分润暂不可提现或划转，如有疑问请联系客服。"
+
+// TxRiskService 交易风控服务
+// 分润计算完成后按配置的规则对消费交易打分，达到阈值生成风控事件，高分事件可冻结该笔交易产生的分润
+type TxRiskService struct {
+	riskRepo        *repository.GormTxRiskRepository
+	transactionRepo repository.TransactionRepository
+	profitRepo      repository.ProfitRecordRepository
+	walletRepo      *repository.GormWalletRepository
+	holdService     *WalletRiskHoldService
+
+	mu          sync.Mutex
+	config      *models.TxRiskConfig
+	configUntil time.Time
+}
+
+// NewTxRiskService 创建交易风控服务
+func NewTxRiskService(
+	riskRepo *repository.GormTxRiskRepository,
+	transactionRepo repository.TransactionRepository,
+	profitRepo repository.ProfitRecordRepository,
+	walletRepo *repository.GormWalletRepository,
+	holdService *WalletRiskHoldService,
+) *TxRiskService {
+	return &TxRiskService{
+		riskRepo:        riskRepo,
+		transactionRepo: transactionRepo,
+		profitRepo:      profitRepo,
+		walletRepo:      walletRepo,
+		holdService:     holdService,
+	}
+}
+
+// ============================================================
+// 配置
+// ============================================================
+
+// defaultTxRiskConfig 默认风控配置（与迁移脚本一致）
+func defaultTxRiskConfig() *models.TxRiskConfig {
+	return &models.TxRiskConfig{
+		Enabled:            true,
+		EventScore:         20,
+		HoldEnabled:        false,
+		HoldScore:          60,
+		SameCardEnabled:    true,
+		SameCardHours:      24,
+		SameCardCount:      5,
+		SameCardScore:      30,
+		NearLimitEnabled:   true,
+		NearLimitAmounts:   models.Int64List{1000000, 2000000, 5000000},
+		NearLimitMargin:    50000,
+		NearLimitRoundUnit: 10000,
+		NearLimitScore:     20,
+		NightEnabled:       true,
+		NightStartHour:     0,
+		NightEndHour:       5,
+		NightCount:         3,
+		NightScore:         20,
+		SpikeEnabled:       true,
+		SpikeHistoryDays:   30,
+		SpikeMinHistory:    10,
+		SpikeMultiple:      5,
+		SpikeMinAmount:     500000,
+		SpikeScore:         25,
+		SharedIDEnabled:    true,
+		SharedIDCount:      3,
+		SharedIDScore:      30,
+	}
+}
+
+// GetConfig 获取风控配置（未配置时返回默认值）
+func (s *TxRiskService) GetConfig() (*models.TxRiskConfig, error) {
+	config, err := s.riskRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易风控配置失败: %w", err)
+	}
+	if config == nil {
+		return defaultTxRiskConfig(), nil
+	}
+	return config, nil
+}
+
+// cachedConfig 获取缓存的风控配置，供分润链路逐笔检测使用
+func (s *TxRiskService) cachedConfig(now time.Time) (*models.TxRiskConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.config != nil && now.Before(s.configUntil) {
+		return s.config, nil
+	}
+
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.config = config
+	s.configUntil = now.Add(txRiskConfigTTL)
+	return config, nil
+}
+
+// UpdateTxRiskConfigRequest 更新风控配置请求，金额单位均为分
+type UpdateTxRiskConfigRequest struct {
+	Enabled     bool `json:"enabled"`
+	EventScore  int  `json:"event_score"`  // 命中规则总分达到该值时生成风控事件
+	HoldEnabled bool `json:"hold_enabled"` // 是否冻结高分事件关联的分润
+	HoldScore   int  `json:"hold_score"`   // 事件总分达到该值时冻结分润
+
+	SameCardEnabled bool `json:"same_card_enabled"`
+	SameCardHours   int  `json:"same_card_hours"`
+	SameCardCount   int  `json:"same_card_count"`
+	SameCardScore   int  `json:"same_card_score"`
+
+	NearLimitEnabled   bool    `json:"near_limit_enabled"`
+	NearLimitAmounts   []int64 `json:"near_limit_amounts"`
+	NearLimitMargin    int64   `json:"near_limit_margin"`
+	NearLimitRoundUnit int64   `json:"near_limit_round_unit"`
+	NearLimitScore     int     `json:"near_limit_score"`
+
+	NightEnabled   bool `json:"night_enabled"`
+	NightStartHour int  `json:"night_start_hour"`
+	NightEndHour   int  `json:"night_end_hour"`
+	NightCount     int  `json:"night_count"`
+	NightScore     int  `json:"night_score"`
+
+	SpikeEnabled     bool  `json:"spike_enabled"`
+	SpikeHistoryDays int   `json:"spike_history_days"`
+	SpikeMinHistory  int   `json:"spike_min_history"`
+	SpikeMultiple    int   `json:"spike_multiple"`
+	SpikeMinAmount   int64 `json:"spike_min_amount"`
+	SpikeScore       int   `json:"spike_score"`
+
+	SharedIDEnabled bool `json:"shared_id_enabled"`
+	SharedIDCount   int  `json:"shared_id_count"`
+	SharedIDScore   int  `json:"shared_id_score"`
+}
+
+// UpdateConfig 更新风控配置，立即对本实例生效
+func (s *TxRiskService) UpdateConfig(req *UpdateTxRiskConfigRequest, operatorID int64, operatorName string) (*models.TxRiskConfig, error) {
+	config, err := s.riskRepo.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易风控配置失败: %w", err)
+	}
+	if config == nil {
+		config = &models.TxRiskConfig{}
+	}
+	config.Enabled = req.Enabled
+	config.EventScore = req.EventScore
+	config.HoldEnabled = req.HoldEnabled
+	config.HoldScore = req.HoldScore
+	config.SameCardEnabled = req.SameCardEnabled
+	config.SameCardHours = req.SameCardHours
+	config.SameCardCount = req.SameCardCount
+	config.SameCardScore = req.SameCardScore
+	config.NearLimitEnabled = req.NearLimitEnabled
+	config.NearLimitAmounts = models.Int64List(req.NearLimitAmounts)
+	config.NearLimitMargin = req.NearLimitMargin
+	config.NearLimitRoundUnit = req.NearLimitRoundUnit
+	config.NearLimitScore = req.NearLimitScore
+	config.NightEnabled = req.NightEnabled
+	config.NightStartHour = req.NightStartHour
+	config.NightEndHour = req.NightEndHour
+	config.NightCount = req.NightCount
+	config.NightScore = req.NightScore
+	config.SpikeEnabled = req.SpikeEnabled
+	config.SpikeHistoryDays = req.SpikeHistoryDays
+	config.SpikeMinHistory = req.SpikeMinHistory
+	config.SpikeMultiple = req.SpikeMultiple
+	config.SpikeMinAmount = req.SpikeMinAmount
+	config.SpikeScore = req.SpikeScore
+	config.SharedIDEnabled = req.SharedIDEnabled
+	config.SharedIDCount = req.SharedIDCount
+	config.SharedIDScore = req.SharedIDScore
+
+	if err := validateTxRiskConfig(config); err != nil {
+		return nil, err
+	}
+
+	config.UpdatedBy = operatorID
+	config.UpdatedByName = operatorName
+	config.UpdatedAt = time.Now()
+	if err := s.riskRepo.SaveConfig(config); err != nil {
+		return nil, fmt.Errorf("保存交易风控配置失败: %w", err)
+	}
+
+	s.mu.Lock()
+	s.config = nil
+	s.mu.Unlock()
+	return config, nil
+}
+
+// ============================================================
+// 检测
+// ============================================================
+
+// EvaluateTransaction 分润计算完成后检测交易，命中规则总分达到阈值时生成风控事件并按配置冻结分润
+// 检测失败只记录日志，不影响分润入账
+func (s *TxRiskService) EvaluateTransaction(tx *repository.Transaction, records []*repository.ProfitRecord) {
+	if !isTxRiskCandidate(tx) {
+		return
+	}
+	config, err := s.cachedConfig(time.Now())
+	if err != nil {
+		log.Printf("[TxRiskService] Load config failed: %v", err)
+		return
+	}
+	if !config.Enabled {
+		return
+	}
+
+	merchant, err := s.riskRepo.FindMerchant(tx.MerchantID)
+	if err != nil {
+		log.Printf("[TxRiskService] Find merchant %d failed: %v", tx.MerchantID, err)
+		return
+	}
+
+	hits := s.collectHits(tx, merchant, config)
+	score := txRiskScore(hits)
+	if len(hits) == 0 || score < config.EventScore {
+		return
+	}
+
+	now := time.Now()
+	event := &models.TxRiskEvent{
+		EventNo:       fmt.Sprintf("TR%s%06d", now.Format("20060102150405"), tx.ID%1000000),
+		TransactionID: tx.ID,
+		OrderNo:       tx.OrderNo,
+		ChannelID:     tx.ChannelID,
+		MerchantID:    tx.MerchantID,
+		AgentID:       tx.AgentID,
+		TerminalSN:    tx.TerminalSN,
+		CardNo:        tx.CardNo,
+		Amount:        tx.Amount,
+		TradeTime:     tx.TradeTime,
+		Score:         score,
+		Hits:          hits,
+		Status:        models.TxRiskStatusPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if merchant != nil {
+		event.MerchantNo = merchant.MerchantNo
+		event.MerchantName = merchant.MerchantName
+	}
+
+	created, err := s.riskRepo.CreateEvent(event)
+	if err != nil {
+		log.Printf("[TxRiskService] Create event for transaction %d failed: %v", tx.ID, err)
+		return
+	}
+	if !created {
+		return
+	}
+	log.Printf("[TxRiskService] Risk event %s: transaction=%d, merchant=%d, score=%d, hits=%d",
+		event.EventNo, tx.ID, tx.MerchantID, score, len(hits))
+
+	if config.HoldEnabled && score >= config.HoldScore {
+		if err := s.holdProfit(event, records, fmt.Sprintf("交易风控自动冻结（%d分）", score)); err != nil {
+			log.Printf("[TxRiskService] Hold profit for event %s failed: %v", event.EventNo, err)
+		}
+	}
+}
+
+// collectHits 逐条规则检测交易，单条规则查询失败时跳过该规则
+func (s *TxRiskService) collectHits(tx *repository.Transaction, merchant *repository.TxRiskMerchant, config *models.TxRiskConfig) models.TxRiskHits {
+	hits := models.TxRiskHits{}
+	add := func(rule string, score int, detail string) {
+		hits = append(hits, models.TxRiskHit{
+			Rule:   rule,
+			Name:   models.GetTxRiskRuleName(rule),
+			Score:  score,
+			Detail: detail,
+		})
+	}
+
+	if config.SameCardEnabled && tx.CardNo != "" {
+		since := tx.TradeTime.Add(-time.Duration(config.SameCardHours) * time.Hour)
+		count, err := s.riskRepo.CountSameCardTrades(tx.MerchantID, tx.CardNo, since, tx.TradeTime)
+		if err != nil {
+			log.Printf("[TxRiskService] Count same card trades failed: tx=%d, err=%v", tx.ID, err)
+		} else if count >= int64(config.SameCardCount) {
+			add(models.TxRiskRuleSameCard, config.SameCardScore,
+				fmt.Sprintf("%d小时内同卡%s交易%d笔", config.SameCardHours, tx.CardNo, count))
+		}
+	}
+
+	if config.NearLimitEnabled {
+		if limit, ok := matchNearLimit(tx.Amount, config); ok {
+			add(models.TxRiskRuleNearLimit, config.NearLimitScore,
+				fmt.Sprintf("金额%.2f元，略低于限额%.2f元", float64(tx.Amount)/100, float64(limit)/100))
+		}
+	}
+
+	if config.NightEnabled {
+		if start, ok := nightWindowStart(tx.TradeTime, config.NightStartHour, config.NightEndHour); ok {
+			count, err := s.riskRepo.CountMerchantTrades(tx.MerchantID, start, tx.TradeTime)
+			if err != nil {
+				log.Printf("[TxRiskService] Count night trades failed: tx=%d, err=%v", tx.ID, err)
+			} else if count >= int64(config.NightCount) {
+				add(models.TxRiskRuleNight, config.NightScore,
+					fmt.Sprintf("%s起夜间时段交易%d笔", start.Format("01-02 15:04"), count))
+			}
+		}
+	}
+
+	if config.SpikeEnabled && tx.Amount >= config.SpikeMinAmount {
+		since := tx.TradeTime.AddDate(0, 0, -config.SpikeHistoryDays)
+		history, err := s.riskRepo.GetMerchantTradeHistory(tx.MerchantID, tx.ID, since, tx.TradeTime)
+		if err != nil {
+			log.Printf("[TxRiskService] Get trade history failed: tx=%d, err=%v", tx.ID, err)
+		} else if isAmountSpike(tx.Amount, history, config) {
+			add(models.TxRiskRuleSpike, config.SpikeScore,
+				fmt.Sprintf("金额为近%d天笔均%.2f元的%.1f倍", config.SpikeHistoryDays,
+					float64(history.AvgAmount)/100, float64(tx.Amount)/float64(history.AvgAmount)))
+		}
+	}
+
+	if config.SharedIDEnabled && merchant != nil && merchant.LegalIDCard != "" {
+		count, err := s.riskRepo.CountMerchantsByLegalID(merchant.LegalIDCard)
+		if err != nil {
+			log.Printf("[TxRiskService] Count shared legal id failed: tx=%d, err=%v", tx.ID, err)
+		} else if count >= int64(config.SharedIDCount) {
+			add(models.TxRiskRuleSharedID, config.SharedIDScore,
+				fmt.Sprintf("同一法人身份证名下%d户商户", count))
+		}
+	}
+
+	return hits
+}
+
+// holdProfit 冻结事件交易产生的分润，冻结关联事件编号，审核排除时解除
+func (s *TxRiskService) holdProfit(event *models.TxRiskEvent, records []*repository.ProfitRecord, reason string) error {
+	if s.holdService == nil {
+		return errors.New("未配置钱包风控冻结服务")
+	}
+
+	var held int64
+	for _, record := range records {
+		if record.ProfitAmount <= 0 || record.IsRevoked {
+			continue
+		}
+		wallet, err := s.walletRepo.FindByAgentAndType(record.AgentID, record.ChannelID, record.WalletType)
+		if err != nil || wallet == nil {
+			log.Printf("[TxRiskService] Wallet not found for hold: agent=%d, channel=%d, type=%d",
+				record.AgentID, record.ChannelID, record.WalletType)
+			continue
+		}
+		_, err = s.holdService.CreateHold(&CreateRiskHoldRequest{
+			AgentID:       record.AgentID,
+			Scope:         models.RiskHoldScopeAmount,
+			WalletID:      wallet.ID,
+			Amount:        record.ProfitAmount,
+			Reason:        fmt.Sprintf("%s，交易%s", reason, event.OrderNo),
+			AgentMessage:  txRiskHoldAgentMessage,
+			CaseRef:       event.EventNo,
+			CreatedByName: "交易风控",
+		})
+		if err != nil {
+			log.Printf("[TxRiskService] Create hold failed: event=%s, agent=%d, err=%v", event.EventNo, record.AgentID, err)
+			continue
+		}
+		held += record.ProfitAmount
+	}
+
+	if held == 0 {
+		return nil
+	}
+	event.Held = true
+	event.HeldAmount += held
+	event.UpdatedAt = time.Now()
+	return s.riskRepo.SaveEvent(event)
+}
+
+// PreviewTransaction 按当前配置试算交易命中的规则，不生成事件
+func (s *TxRiskService) PreviewTransaction(txID int64) (*TxRiskPreview, error) {
+	tx, err := s.transactionRepo.FindByID(txID)
+	if err != nil || tx == nil {
+		return nil, errors.New("交易不存在")
+	}
+	config, err := s.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	merchant, err := s.riskRepo.FindMerchant(tx.MerchantID)
+	if err != nil {
+		return nil, fmt.Errorf("查询商户失败: %w", err)
+	}
+
+	hits := s.collectHits(tx, merchant, config)
+	score := txRiskScore(hits)
+	return &TxRiskPreview{
+		TransactionID: tx.ID,
+		Score:         score,
+		Hits:          hits,
+		WouldCreate:   len(hits) > 0 && score >= config.EventScore,
+		WouldHold:     len(hits) > 0 && config.HoldEnabled && score >= config.EventScore && score >= config.HoldScore,
+	}, nil
+}
+
+// TxRiskPreview 交易风控试算结果
+type TxRiskPreview struct {
+	TransactionID int64             `json:"transaction_id"`
+	Score         int               `json:"score"`
+	Hits          models.TxRiskHits `json:"hits"`
+	WouldCreate   bool              `json:"would_create"` // 是否会生成风控事件
+	WouldHold     bool              `json:"would_hold"`   // 是否会冻结分润
+}
+
+// ============================================================
+// 案件审核
+// ============================================================
+
+// TxRiskEventItem 风控事件列表项
+type TxRiskEventItem struct {
+	*models.TxRiskEvent
+	StatusName string `json:"status_name"`
+}
+
+// ListEvents 风控事件列表
+func (s *TxRiskService) ListEvents(filter *repository.TxRiskEventFilter, page, pageSize int) ([]*TxRiskEventItem, int64, error) {
+	if filter.Rule != "" && models.GetTxRiskRuleName(filter.Rule) == "未知" {
+		return nil, 0, errors.New("无效的规则")
+	}
+	events, total, err := s.riskRepo.ListEvents(filter, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询风控事件失败: %w", err)
+	}
+
+	items := make([]*TxRiskEventItem, 0, len(events))
+	for _, event := range events {
+		items = append(items, &TxRiskEventItem{
+			TxRiskEvent: event,
+			StatusName:  models.GetTxRiskStatusName(event.Status),
+		})
+	}
+	return items, total, nil
+}
+
+// TxRiskEventDetail 风控事件详情
+type TxRiskEventDetail struct {
+	*models.TxRiskEvent
+	StatusName string                     `json:"status_name"`
+	Profits    []*repository.ProfitRecord `json:"profits"` // 该笔交易产生的分润
+	Holds      []*models.WalletRiskHold   `json:"holds"`   // 关联的未解除冻结
+}
+
+// GetEvent 风控事件详情
+func (s *TxRiskService) GetEvent(id int64) (*TxRiskEventDetail, error) {
+	event, err := s.riskRepo.FindEventByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询风控事件失败: %w", err)
+	}
+	if event == nil {
+		return nil, errors.New("风控事件不存在")
+	}
+
+	detail := &TxRiskEventDetail{
+		TxRiskEvent: event,
+		StatusName:  models.GetTxRiskStatusName(event.Status),
+	}
+	if detail.Profits, err = s.profitRepo.FindByTransactionID(event.TransactionID); err != nil {
+		return nil, fmt.Errorf("查询分润记录失败: %w", err)
+	}
+	if event.Held && s.holdService != nil {
+		if detail.Holds, err = s.holdService.GetCaseHolds(event.EventNo); err != nil {
+			return nil, fmt.Errorf("查询冻结记录失败: %w", err)
+		}
+	}
+	return detail, nil
+}
+
+// 审核动作
+const (
+	TxRiskActionConfirm = "confirm" // 确认风险
+	TxRiskActionDismiss = "dismiss" // 排除风险
+)
+
+// ReviewTxRiskEventRequest 审核风控事件请求
+type ReviewTxRiskEventRequest struct {
+	Action string `json:"action" binding:"required"` // confirm确认风险 dismiss排除风险
+	Remark string `json:"remark"`
+	Hold   bool   `json:"hold"` // 确认风险时冻结尚未冻结的分润
+}
+
+// ReviewEvent 审核风控事件：排除风险时解除关联冻结，确认风险时可补充冻结分润
+// 确认风险后冻结保留，由风控人员在钱包风控冻结中按处理结果解除
+func (s *TxRiskService) ReviewEvent(id int64, req *ReviewTxRiskEventRequest, operatorID int64, operatorName string) (*models.TxRiskEvent, error) {
+	event, err := s.riskRepo.FindEventByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询风控事件失败: %w", err)
+	}
+	if event == nil {
+		return nil, errors.New("风控事件不存在")
+	}
+	if event.Status != models.TxRiskStatusPending {
+		return nil, errors.New("该风控事件已审核")
+	}
+
+	now := time.Now()
+	switch req.Action {
+	case TxRiskActionConfirm:
+		event.Status = models.TxRiskStatusConfirmed
+		if req.Hold && !event.Held {
+			records, err := s.profitRepo.FindByTransactionID(event.TransactionID)
+			if err != nil {
+				return nil, fmt.Errorf("查询分润记录失败: %w", err)
+			}
+			if err := s.holdProfit(event, records, "交易风控确认风险冻结"); err != nil {
+				return nil, fmt.Errorf("冻结分润失败: %w", err)
+			}
+		}
+	case TxRiskActionDismiss:
+		event.Status = models.TxRiskStatusDismissed
+		if event.Held && s.holdService != nil {
+			released, err := s.holdService.ReleaseCaseHolds(event.EventNo, operatorID, operatorName, "交易风控审核排除")
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("[TxRiskService] Event %s dismissed, released holds: %d", event.EventNo, released)
+		}
+	default:
+		return nil, errors.New("无效的审核动作")
+	}
+
+	event.ReviewRemark = req.Remark
+	event.ReviewedBy = &operatorID
+	event.ReviewedByName = operatorName
+	event.ReviewedAt = &now
+	event.UpdatedAt = now
+	if err := s.riskRepo.SaveEvent(event); err != nil {
+		return nil, fmt.Errorf("保存风控事件失败: %w", err)
+	}
+	return event, nil
+}
+
+// ============================================================
+// 规则计算（纯函数）
+// ============================================================
+
+// validateTxRiskConfig 校验风控配置
+func validateTxRiskConfig(config *models.TxRiskConfig) error {
+	switch {
+	case config.EventScore < 1 || config.EventScore > 100:
+		return errors.New("事件分数阈值必须在1-100之间")
+	case config.HoldScore < config.EventScore:
+		return errors.New("冻结分数阈值不能低于事件分数阈值")
+	case config.SameCardHours < 1 || config.SameCardHours > 720:
+		return errors.New("同卡统计窗口必须在1-720小时之间")
+	case config.SameCardCount < 2:
+		return errors.New("同卡交易笔数阈值不能小于2")
+	case config.NearLimitMargin <= 0:
+		return errors.New("临界金额差值必须大于0")
+	case config.NearLimitRoundUnit < 0:
+		return errors.New("整数金额单位不能为负数")
+	case config.NearLimitEnabled && len(config.NearLimitAmounts) == 0:
+		return errors.New("请配置至少一个限额")
+	case config.NightStartHour < 0 || config.NightStartHour > 23 || config.NightEndHour < 0 || config.NightEndHour > 23:
+		return errors.New("夜间时段小时必须在0-23之间")
+	case config.NightStartHour == config.NightEndHour:
+		return errors.New("夜间开始和结束小时不能相同")
+	case config.NightCount < 1:
+		return errors.New("夜间交易笔数阈值不能小于1")
+	case config.SpikeHistoryDays < 1 || config.SpikeHistoryDays > 180:
+		return errors.New("历史笔均统计天数必须在1-180之间")
+	case config.SpikeMinHistory < 1:
+		return errors.New("历史交易笔数下限不能小于1")
+	case config.SpikeMultiple < 2:
+		return errors.New("金额突增倍数不能小于2")
+	case config.SpikeMinAmount < 0:
+		return errors.New("金额突增起算金额不能为负数")
+	case config.SharedIDCount < 2:
+		return errors.New("法人身份证共用商户数阈值不能小于2")
+	}
+
+	for _, limit := range config.NearLimitAmounts {
+		if limit <= config.NearLimitMargin {
+			return errors.New("限额必须大于临界金额差值")
+		}
+	}
+	for _, score := range []int{config.SameCardScore, config.NearLimitScore, config.NightScore, config.SpikeScore, config.SharedIDScore} {
+		if score < 0 || score > 100 {
+			return errors.New("规则分数必须在0-100之间")
+		}
+	}
+	return nil
+}
+
+// txRiskScore 命中规则总分，最高100
+func txRiskScore(hits models.TxRiskHits) int {
+	score := 0
+	for _, hit := range hits {
+		score += hit.Score
+	}
+	return min(score, 100)
+}
+
+// isTxRiskCandidate 仅检测已关联商户的消费交易
+func isTxRiskCandidate(tx *repository.Transaction) bool {
+	return tx != nil && tx.TradeType == 1 && tx.MerchantID != 0
+}
+
+// matchNearLimit 判断金额是否为略低于限额的整数金额，返回命中的限额
+func matchNearLimit(amount int64, config *models.TxRiskConfig) (int64, bool) {
+	if amount <= 0 {
+		return 0, false
+	}
+	if config.NearLimitRoundUnit > 0 && amount%config.NearLimitRoundUnit != 0 {
+		return 0, false
+	}
+	for _, limit := range config.NearLimitAmounts {
+		if amount < limit && limit-amount <= config.NearLimitMargin {
+			return limit, true
+		}
+	}
+	return 0, false
+}
+
+// nightWindowStart 判断交易时间是否在夜间时段，返回所在夜间时段的开始时间
+// endHour小于startHour时表示跨零点，如23点至次日5点
+func nightWindowStart(t time.Time, startHour, endHour int) (time.Time, bool) {
+	hour := t.Hour()
+	dayStart := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	if startHour < endHour {
+		if hour >= startHour && hour < endHour {
+			return dayStart.Add(time.Duration(startHour) * time.Hour), true
+		}
+		return time.Time{}, false
+	}
+
+	if hour >= startHour {
+		return dayStart.Add(time.Duration(startHour) * time.Hour), true
+	}
+	if hour < endHour {
+		return dayStart.AddDate(0, 0, -1).Add(time.Duration(startHour) * time.Hour), true
+	}
+	return time.Time{}, false
+}
+
+// isAmountSpike 判断金额是否超过商户历史笔均的配置倍数
+func isAmountSpike(amount int64, history *repository.MerchantTradeHistory, config *models.TxRiskConfig) bool {
+	if history == nil || history.TradeCount < int64(config.SpikeMinHistory) || history.AvgAmount <= 0 {
+		return false
+	}
+	return amount >= config.SpikeMinAmount && amount >= history.AvgAmount*int64(config.SpikeMultiple)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+func TestMatchNearLimit(t *testing.T) {
+	config := defaultTxRiskConfig()
+
+	tests := []struct {
+		name      string
+		amount    int64
+		wantLimit int64
+		wantOK    bool
+	}{
+		{"整数略低于1万", 990000, 1000000, true},
+		{"差值正好500元", 950000, 1000000, true},
+		{"差值超过500元", 940000, 0, false},
+		{"非百元整数", 995050, 0, false},
+		{"等于限额", 1000000, 0, false},
+		{"略低于5万", 4980000, 5000000, true},
+		{"普通金额", 12300, 0, false},
+		{"零金额", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, ok := matchNearLimit(tt.amount, config)
+			if ok != tt.wantOK || limit != tt.wantLimit {
+				t.Errorf("matchNearLimit(%d) = (%d, %v), want (%d, %v)", tt.amount, limit, ok, tt.wantLimit, tt.wantOK)
+			}
+		})
+	}
+
+	config.NearLimitRoundUnit = 0
+	if _, ok := matchNearLimit(995050, config); !ok {
+		t.Errorf("不要求整数时应命中")
+	}
+}
+
+func TestNightWindowStart(t *testing.T) {
+	day := func(d, h, m int) time.Time {
+		return time.Date(2026, 3, d, h, m, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name       string
+		t          time.Time
+		start, end int
+		wantStart  time.Time
+		wantOK     bool
+	}{
+		{"0-5点内", day(10, 2, 30), 0, 5, day(10, 0, 0), true},
+		{"0-5点外", day(10, 5, 0), 0, 5, time.Time{}, false},
+		{"跨零点当晚", day(10, 23, 10), 23, 5, day(10, 23, 0), true},
+		{"跨零点次日凌晨", day(11, 3, 0), 23, 5, day(10, 23, 0), true},
+		{"跨零点白天", day(11, 12, 0), 23, 5, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, ok := nightWindowStart(tt.t, tt.start, tt.end)
+			if ok != tt.wantOK || !start.Equal(tt.wantStart) {
+				t.Errorf("nightWindowStart(%v) = (%v, %v), want (%v, %v)", tt.t, start, ok, tt.wantStart, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsAmountSpike(t *testing.T) {
+	config := defaultTxRiskConfig()
+
+	tests := []struct {
+		name    string
+		amount  int64
+		history *repository.MerchantTradeHistory
+		want    bool
+	}{
+		{"超过笔均5倍", 1000000, &repository.MerchantTradeHistory{TradeCount: 30, AvgAmount: 100000}, true},
+		{"不足5倍", 500000, &repository.MerchantTradeHistory{TradeCount: 30, AvgAmount: 200000}, false},
+		{"历史笔数不足", 1000000, &repository.MerchantTradeHistory{TradeCount: 5, AvgAmount: 100000}, false},
+		{"低于起算金额", 400000, &repository.MerchantTradeHistory{TradeCount: 30, AvgAmount: 10000}, false},
+		{"无历史", 1000000, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAmountSpike(tt.amount, tt.history, config); got != tt.want {
+				t.Errorf("isAmountSpike(%d) = %v, want %v", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTxRiskScore(t *testing.T) {
+	hits := models.TxRiskHits{{Score: 30}, {Score: 20}}
+	if got := txRiskScore(hits); got != 50 {
+		t.Errorf("txRiskScore = %d, want 50", got)
+	}
+
+	hits = append(hits, models.TxRiskHit{Score: 30}, models.TxRiskHit{Score: 30})
+	if got := txRiskScore(hits); got != 100 {
+		t.Errorf("txRiskScore capped = %d, want 100", got)
+	}
+}
+
+func TestValidateTxRiskConfig(t *testing.T) {
+	if err := validateTxRiskConfig(defaultTxRiskConfig()); err != nil {
+		t.Fatalf("默认配置校验失败: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *models.TxRiskConfig)
+	}{
+		{"冻结阈值低于事件阈值", func(c *models.TxRiskConfig) { c.HoldScore = 10 }},
+		{"夜间起止相同", func(c *models.TxRiskConfig) { c.NightEndHour = c.NightStartHour }},
+		{"限额不大于差值", func(c *models.TxRiskConfig) { c.NearLimitAmounts = models.Int64List{50000} }},
+		{"未配置限额", func(c *models.TxRiskConfig) { c.NearLimitAmounts = nil }},
+		{"突增倍数过小", func(c *models.TxRiskConfig) { c.SpikeMultiple = 1 }},
+		{"规则分数越界", func(c *models.TxRiskConfig) { c.SharedIDScore = 120 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := defaultTxRiskConfig()
+			tt.modify(config)
+			if err := validateTxRiskConfig(config); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"xiangshoufu/internal/repository"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // WalletAdjustmentService 钱包调账服务
@@ -40,6 +41,9 @@ const (
 	WalletLogTypeAdjustmentOut int16 = 12 // 调账扣减
 )
 
+// 默认调账金额达到1000元需审批
+const defaultAdjustmentApprovalThreshold int64 = 100000
+
 // CreateAdjustmentRequest 创建调账请求
 type CreateAdjustmentRequest struct {
 	AgentID      int64  `json:"agent_id" binding:"required"`
@@ -52,7 +56,12 @@ type CreateAdjustmentRequest struct {
 }
 
 // CreateAdjustment 创建调账
+// 调账金额绝对值达到审批金额时进入待审批状态，不变更余额，需另一名管理员审批通过后生效
 func (s *WalletAdjustmentService) CreateAdjustment(req *CreateAdjustmentRequest) (*AdjustmentInfo, error) {
+	if req.Amount == 0 {
+		return nil, fmt.Errorf("调账金额不能为0")
+	}
+
 	// 1. 验证代理商存在
 	agent, err := s.agentRepo.FindByIDFull(req.AgentID)
 	if err != nil || agent == nil {
@@ -65,7 +74,17 @@ func (s *WalletAdjustmentService) CreateAdjustment(req *CreateAdjustmentRequest)
 		return nil, fmt.Errorf("钱包不存在，请确认代理商和钱包类型")
 	}
 
-	// 3. 扣减时检查余额
+	adjustment, err := s.submitAdjustment(req, wallet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toAdjustmentInfo(adjustment, agent.AgentName), nil
+}
+
+// submitAdjustment 提交调账（普通调账与冲正共用）
+func (s *WalletAdjustmentService) submitAdjustment(req *CreateAdjustmentRequest, wallet *repository.Wallet, reversalOfID *int64) (*models.WalletAdjustment, error) {
+	// 1. 扣减时检查余额（审批通过时会在锁内再次校验）
 	if req.Amount < 0 {
 		availableBalance := wallet.Balance - wallet.FrozenAmount
 		if availableBalance < -req.Amount {
@@ -73,120 +92,386 @@ func (s *WalletAdjustmentService) CreateAdjustment(req *CreateAdjustmentRequest)
 		}
 	}
 
-	// 4. 生成调账单号
-	adjustmentNo := fmt.Sprintf("ADJ%s%06d", time.Now().Format("20060102150405"), time.Now().UnixNano()%1000000)
-
+	// 2. 生成调账单号
 	now := time.Now()
-	balanceBefore := wallet.Balance
-	balanceAfter := wallet.Balance + req.Amount
-
-	// 5. 使用事务执行调账
-	db := s.adjustmentRepo.GetDB()
-	var adjustment *models.WalletAdjustment
-	var walletLogID int64
-
-	err = db.Transaction(func(tx *gorm.DB) error {
-		// 5.1 创建调账记录
-		adjustment = &models.WalletAdjustment{
-			AdjustmentNo:  adjustmentNo,
-			AgentID:       req.AgentID,
-			WalletID:      wallet.ID,
-			WalletType:    req.WalletType,
-			ChannelID:     req.ChannelID,
-			Amount:        req.Amount,
-			BalanceBefore: balanceBefore,
-			BalanceAfter:  balanceAfter,
-			Reason:        req.Reason,
-			OperatorID:    req.OperatorID,
-			OperatorName:  req.OperatorName,
-			Status:        models.AdjustmentStatusEffective,
-			CreatedAt:     now,
-			UpdatedAt:     now,
+	adjustment := &models.WalletAdjustment{
+		AdjustmentNo:  fmt.Sprintf("ADJ%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		AgentID:       req.AgentID,
+		WalletID:      wallet.ID,
+		WalletType:    req.WalletType,
+		ChannelID:     req.ChannelID,
+		Amount:        req.Amount,
+		BalanceBefore: wallet.Balance,
+		BalanceAfter:  wallet.Balance + req.Amount,
+		Reason:        req.Reason,
+		OperatorID:    req.OperatorID,
+		OperatorName:  req.OperatorName,
+		Status:        models.AdjustmentStatusEffective,
+		ReversalOfID:  reversalOfID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// 3. 超过审批金额：仅创建待审批记录，不变更余额
+	if needAdjustmentApproval(req.Amount, s.getApprovalThreshold(req.WalletType)) {
+		adjustment.Status = models.AdjustmentStatusPending
+		if err := s.adjustmentRepo.Create(adjustment); err != nil {
+			return nil, fmt.Errorf("创建调账记录失败: %w", err)
 		}
+		log.Printf("[WalletAdjustmentService] Adjustment pending approval: %s, agent=%d, wallet_type=%d, amount=%d",
+			adjustment.AdjustmentNo, req.AgentID, req.WalletType, req.Amount)
+		return adjustment, nil
+	}
 
+	// 4. 未超过审批金额：使用事务立即生效
+	err := s.adjustmentRepo.GetDB().Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(adjustment).Error; err != nil {
 			return fmt.Errorf("创建调账记录失败: %w", err)
 		}
+		return s.applyAdjustment(tx, adjustment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[WalletAdjustmentService] Created adjustment: %s, agent=%d, wallet_type=%d, amount=%d",
+		adjustment.AdjustmentNo, req.AgentID, req.WalletType, req.Amount)
+
+	return adjustment, nil
+}
 
-		// 5.2 更新钱包余额
-		if err := tx.Model(&repository.Wallet{}).
-			Where("id = ?", wallet.ID).
+// applyAdjustment 在事务内变更钱包余额并记录流水
+// 锁定钱包后以实际余额为准重新计算调账前后余额
+func (s *WalletAdjustmentService) applyAdjustment(tx *gorm.DB, adjustment *models.WalletAdjustment) error {
+	// 1. 锁定钱包
+	var wallet repository.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, adjustment.WalletID).Error; err != nil {
+		return fmt.Errorf("锁定钱包失败: %w", err)
+	}
+	if adjustment.Amount < 0 && wallet.Balance-wallet.FrozenAmount < -adjustment.Amount {
+		return fmt.Errorf("可用余额不足，当前可用余额%.2f元", float64(wallet.Balance-wallet.FrozenAmount)/100)
+	}
+
+	now := time.Now()
+	adjustment.BalanceBefore = wallet.Balance
+	adjustment.BalanceAfter = wallet.Balance + adjustment.Amount
+
+	// 2. 更新钱包余额
+	if err := tx.Model(&repository.Wallet{}).
+		Where("id = ?", wallet.ID).
+		Updates(map[string]interface{}{
+			"balance":      gorm.Expr("balance + ?", adjustment.Amount),
+			"total_income": gorm.Expr("total_income + ?", adjustment.Amount),
+			"version":      gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("更新钱包余额失败: %w", err)
+	}
+
+	// 3. 创建钱包流水
+	logType := WalletLogTypeAdjustmentIn
+	if adjustment.Amount < 0 {
+		logType = WalletLogTypeAdjustmentOut
+	}
+	remark := fmt.Sprintf("手动调账: %s", adjustment.Reason)
+	if adjustment.ReversalOfID != nil {
+		remark = fmt.Sprintf("调账冲正: %s", adjustment.Reason)
+	}
+
+	walletLog := &repository.WalletLog{
+		WalletID:      wallet.ID,
+		AgentID:       adjustment.AgentID,
+		WalletType:    adjustment.WalletType,
+		LogType:       logType,
+		Amount:        adjustment.Amount,
+		BalanceBefore: adjustment.BalanceBefore,
+		BalanceAfter:  adjustment.BalanceAfter,
+		RefType:       "wallet_adjustment",
+		RefID:         adjustment.ID,
+		Remark:        remark,
+		CreatedAt:     now,
+	}
+	if err := tx.Create(walletLog).Error; err != nil {
+		return fmt.Errorf("创建钱包流水失败: %w", err)
+	}
+	adjustment.WalletLogID = &walletLog.ID
+	adjustment.Status = models.AdjustmentStatusEffective
+
+	// 4. 回写调账记录
+	if err := tx.Model(&models.WalletAdjustment{}).
+		Where("id = ?", adjustment.ID).
+		Updates(map[string]interface{}{
+			"status":         models.AdjustmentStatusEffective,
+			"balance_before": adjustment.BalanceBefore,
+			"balance_after":  adjustment.BalanceAfter,
+			"wallet_log_id":  walletLog.ID,
+			"updated_at":     now,
+		}).Error; err != nil {
+		return fmt.Errorf("更新调账记录失败: %w", err)
+	}
+
+	// 5. 冲正单生效时标记原调账为已冲正
+	if adjustment.ReversalOfID != nil {
+		result := tx.Model(&models.WalletAdjustment{}).
+			Where("id = ? AND status = ?", *adjustment.ReversalOfID, models.AdjustmentStatusEffective).
 			Updates(map[string]interface{}{
-				"balance":      gorm.Expr("balance + ?", req.Amount),
-				"total_income": gorm.Expr("total_income + ?", req.Amount),
-				"version":      gorm.Expr("version + 1"),
-			}).Error; err != nil {
-			return fmt.Errorf("更新钱包余额失败: %w", err)
+				"status":         models.AdjustmentStatusReversed,
+				"reversed_by_id": adjustment.ID,
+				"reversed_at":    now,
+				"updated_at":     now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新原调账记录失败: %w", result.Error)
 		}
-
-		// 5.3 创建钱包流水
-		logType := WalletLogTypeAdjustmentIn
-		if req.Amount < 0 {
-			logType = WalletLogTypeAdjustmentOut
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("原调账状态已变更，无法冲正")
 		}
+	}
 
-		walletLog := &repository.WalletLog{
-			WalletID:      wallet.ID,
-			AgentID:       req.AgentID,
-			WalletType:    req.WalletType,
-			LogType:       logType,
-			Amount:        req.Amount,
-			BalanceBefore: balanceBefore,
-			BalanceAfter:  balanceAfter,
-			RefType:       "wallet_adjustment",
-			RefID:         adjustment.ID,
-			Remark:        fmt.Sprintf("手动调账: %s", req.Reason),
-			CreatedAt:     now,
-		}
+	return nil
+}
 
-		if err := tx.Create(walletLog).Error; err != nil {
-			return fmt.Errorf("创建钱包流水失败: %w", err)
-		}
-		walletLogID = walletLog.ID
+// ApproveAdjustment 审批通过调账
+// 审批人不能是调账发起人，审批通过后才变更余额
+func (s *WalletAdjustmentService) ApproveAdjustment(id int64, approverID int64, approverName string) (*AdjustmentInfo, error) {
+	adjustment, err := s.getPendingAdjustment(id, approverID)
+	if err != nil {
+		return nil, err
+	}
 
-		// 5.4 更新调账记录的流水ID
-		if err := tx.Model(&models.WalletAdjustment{}).
-			Where("id = ?", adjustment.ID).
-			Update("wallet_log_id", walletLogID).Error; err != nil {
-			return fmt.Errorf("更新调账记录失败: %w", err)
+	now := time.Now()
+	err = s.adjustmentRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 条件更新防止重复审批
+		result := tx.Model(&models.WalletAdjustment{}).
+			Where("id = ? AND status = ?", id, models.AdjustmentStatusPending).
+			Updates(map[string]interface{}{
+				"approved_by":   approverID,
+				"approver_name": approverName,
+				"approved_at":   now,
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新调账记录失败: %w", result.Error)
 		}
-
-		return nil
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("调账状态已变更，请刷新后重试")
+		}
+		return s.applyAdjustment(tx, adjustment)
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment.ApprovedBy = &approverID
+	adjustment.ApproverName = approverName
+	adjustment.ApprovedAt = &now
+
+	log.Printf("[WalletAdjustmentService] Approved adjustment: %s, approver=%d, amount=%d",
+		adjustment.AdjustmentNo, approverID, adjustment.Amount)
+
+	return s.toAdjustmentInfo(adjustment, s.getAgentName(adjustment.AgentID)), nil
+}
+
+// RejectAdjustment 驳回调账
+func (s *WalletAdjustmentService) RejectAdjustment(id int64, approverID int64, approverName string, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("请填写驳回原因")
+	}
+	adjustment, err := s.getPendingAdjustment(id, approverID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result := s.adjustmentRepo.GetDB().Model(&models.WalletAdjustment{}).
+		Where("id = ? AND status = ?", id, models.AdjustmentStatusPending).
+		Updates(map[string]interface{}{
+			"status":        models.AdjustmentStatusRejected,
+			"approved_by":   approverID,
+			"approver_name": approverName,
+			"approved_at":   now,
+			"reject_reason": reason,
+			"updated_at":    now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("驳回调账失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("调账状态已变更，请刷新后重试")
+	}
+
+	log.Printf("[WalletAdjustmentService] Rejected adjustment: %s, approver=%d, reason=%s",
+		adjustment.AdjustmentNo, approverID, reason)
+	return nil
+}
+
+// getPendingAdjustment 获取待审批调账并校验审批人
+func (s *WalletAdjustmentService) getPendingAdjustment(id int64, approverID int64) (*models.WalletAdjustment, error) {
+	adjustment, err := s.adjustmentRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询调账记录失败: %w", err)
+	}
+	if adjustment == nil {
+		return nil, fmt.Errorf("调账记录不存在")
+	}
+	if adjustment.Status != models.AdjustmentStatusPending {
+		return nil, fmt.Errorf("该调账不是待审批状态")
+	}
+	if adjustment.OperatorID == approverID {
+		return nil, fmt.Errorf("不能审批自己发起的调账，请由其他管理员审批")
+	}
+	return adjustment, nil
+}
+
+// ReverseAdjustmentRequest 冲正请求
+type ReverseAdjustmentRequest struct {
+	AdjustmentID int64  `json:"-"`
+	Reason       string `json:"reason" binding:"required"`
+	OperatorID   int64  `json:"-"`
+	OperatorName string `json:"-"`
+}
 
+// ReverseAdjustment 冲正已生效的调账
+// 生成一笔反向调账，同样遵循审批金额规则；冲正单生效后原调账标记为已冲正
+func (s *WalletAdjustmentService) ReverseAdjustment(req *ReverseAdjustmentRequest) (*AdjustmentInfo, error) {
+	original, err := s.adjustmentRepo.GetByID(req.AdjustmentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询调账记录失败: %w", err)
+	}
+	if original == nil {
+		return nil, fmt.Errorf("调账记录不存在")
+	}
+	if original.Status != models.AdjustmentStatusEffective {
+		return nil, fmt.Errorf("只能冲正已生效的调账")
+	}
+	if original.ReversalOfID != nil {
+		return nil, fmt.Errorf("冲正单不能再次冲正")
+	}
+
+	existing, err := s.adjustmentRepo.FindActiveReversal(original.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询冲正记录失败: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("该调账已存在冲正单%s（%s）", existing.AdjustmentNo, models.GetAdjustmentStatusName(existing.Status))
+	}
+
+	var wallet repository.Wallet
+	if err := s.adjustmentRepo.GetDB().First(&wallet, original.WalletID).Error; err != nil {
+		return nil, fmt.Errorf("钱包不存在")
+	}
+
+	adjustment, err := s.submitAdjustment(&CreateAdjustmentRequest{
+		AgentID:      original.AgentID,
+		WalletType:   original.WalletType,
+		ChannelID:    original.ChannelID,
+		Amount:       -original.Amount,
+		Reason:       fmt.Sprintf("冲正%s: %s", original.AdjustmentNo, req.Reason),
+		OperatorID:   req.OperatorID,
+		OperatorName: req.OperatorName,
+	}, &wallet, &original.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Printf("[WalletAdjustmentService] Created adjustment: %s, agent=%d, wallet_type=%d, amount=%d",
-		adjustmentNo, req.AgentID, req.WalletType, req.Amount)
+	return s.toAdjustmentInfo(adjustment, s.getAgentName(adjustment.AgentID)), nil
+}
 
-	return s.toAdjustmentInfo(adjustment, agent.AgentName), nil
+// ========== 审批金额配置 ==========
+
+// needAdjustmentApproval 判断调账是否需要审批（金额绝对值达到审批金额）
+func needAdjustmentApproval(amount, threshold int64) bool {
+	if amount < 0 {
+		amount = -amount
+	}
+	return amount >= threshold
+}
+
+// getApprovalThreshold 获取钱包类型生效的审批金额（特定钱包类型 > 通用配置 > 系统默认）
+func (s *WalletAdjustmentService) getApprovalThreshold(walletType int16) int64 {
+	if threshold, err := s.adjustmentRepo.GetApprovalThreshold(walletType); err == nil && threshold != nil {
+		return threshold.ThresholdAmount
+	}
+	if threshold, err := s.adjustmentRepo.GetApprovalThreshold(0); err == nil && threshold != nil {
+		return threshold.ThresholdAmount
+	}
+	return defaultAdjustmentApprovalThreshold
+}
+
+// GetApprovalThresholds 获取审批金额配置列表
+func (s *WalletAdjustmentService) GetApprovalThresholds() ([]*models.WalletAdjustmentApprovalThreshold, error) {
+	return s.adjustmentRepo.ListApprovalThresholds()
+}
+
+// UpdateApprovalThresholdRequest 更新审批金额配置请求
+type UpdateApprovalThresholdRequest struct {
+	WalletType      int16  `json:"wallet_type"`                         // 0表示通用
+	ThresholdAmount *int64 `json:"threshold_amount" binding:"required"` // 分，0表示所有调账均需审批
+	UpdatedBy       int64  `json:"-"`
+}
+
+// UpdateApprovalThreshold 更新审批金额配置
+func (s *WalletAdjustmentService) UpdateApprovalThreshold(req *UpdateApprovalThresholdRequest) (*models.WalletAdjustmentApprovalThreshold, error) {
+	if *req.ThresholdAmount < 0 {
+		return nil, fmt.Errorf("审批金额不能为负数")
+	}
+	if req.WalletType < 0 || req.WalletType > models.WalletTypeSettlement {
+		return nil, fmt.Errorf("无效的钱包类型")
+	}
+
+	threshold, err := s.adjustmentRepo.GetApprovalThreshold(req.WalletType)
+	if err != nil {
+		return nil, fmt.Errorf("查询审批金额配置失败: %w", err)
+	}
+	if threshold == nil {
+		threshold = &models.WalletAdjustmentApprovalThreshold{WalletType: req.WalletType, CreatedAt: time.Now()}
+	}
+	threshold.ThresholdAmount = *req.ThresholdAmount
+	threshold.UpdatedBy = &req.UpdatedBy
+	threshold.UpdatedAt = time.Now()
+
+	if err := s.adjustmentRepo.SaveApprovalThreshold(threshold); err != nil {
+		return nil, fmt.Errorf("保存审批金额配置失败: %w", err)
+	}
+	return threshold, nil
+}
+
+func (s *WalletAdjustmentService) getAgentName(agentID int64) string {
+	if agent, _ := s.agentRepo.FindByIDFull(agentID); agent != nil {
+		return agent.AgentName
+	}
+	return ""
 }
 
 // AdjustmentInfo 调账信息
 type AdjustmentInfo struct {
-	ID               int64      `json:"id"`
-	AdjustmentNo     string     `json:"adjustment_no"`
-	AgentID          int64      `json:"agent_id"`
-	AgentName        string     `json:"agent_name"`
-	WalletID         int64      `json:"wallet_id"`
-	WalletType       int16      `json:"wallet_type"`
-	WalletTypeName   string     `json:"wallet_type_name"`
-	ChannelID        int64      `json:"channel_id"`
-	Amount           int64      `json:"amount"`
-	AmountYuan       float64    `json:"amount_yuan"`
-	AdjustmentType   string     `json:"adjustment_type"` // 充入/扣减
-	BalanceBefore    int64      `json:"balance_before"`
-	BalanceBeforeYuan float64   `json:"balance_before_yuan"`
-	BalanceAfter     int64      `json:"balance_after"`
-	BalanceAfterYuan float64    `json:"balance_after_yuan"`
-	Reason           string     `json:"reason"`
-	OperatorID       int64      `json:"operator_id"`
-	OperatorName     string     `json:"operator_name"`
-	Status           int16      `json:"status"`
-	StatusName       string     `json:"status_name"`
-	CreatedAt        time.Time  `json:"created_at"`
+	ID                int64      `json:"id"`
+	AdjustmentNo      string     `json:"adjustment_no"`
+	AgentID           int64      `json:"agent_id"`
+	AgentName         string     `json:"agent_name"`
+	WalletID          int64      `json:"wallet_id"`
+	WalletType        int16      `json:"wallet_type"`
+	WalletTypeName    string     `json:"wallet_type_name"`
+	ChannelID         int64      `json:"channel_id"`
+	Amount            int64      `json:"amount"`
+	AmountYuan        float64    `json:"amount_yuan"`
+	AdjustmentType    string     `json:"adjustment_type"` // 充入/扣减
+	BalanceBefore     int64      `json:"balance_before"`
+	BalanceBeforeYuan float64    `json:"balance_before_yuan"`
+	BalanceAfter      int64      `json:"balance_after"`
+	BalanceAfterYuan  float64    `json:"balance_after_yuan"`
+	Reason            string     `json:"reason"`
+	OperatorID        int64      `json:"operator_id"`
+	OperatorName      string     `json:"operator_name"`
+	Status            int16      `json:"status"`
+	StatusName        string     `json:"status_name"`
+	ApprovedBy        *int64     `json:"approved_by"`
+	ApproverName      string     `json:"approver_name"`
+	ApprovedAt        *time.Time `json:"approved_at"`
+	RejectReason      string     `json:"reject_reason"`
+	ReversalOfID      *int64     `json:"reversal_of_id"`
+	ReversedByID      *int64     `json:"reversed_by_id"`
+	ReversedAt        *time.Time `json:"reversed_at"`
+	CreatedAt         time.Time  `json:"created_at"`
 }
 
 func (s *WalletAdjustmentService) toAdjustmentInfo(adj *models.WalletAdjustment, agentName string) *AdjustmentInfo {
@@ -211,6 +496,13 @@ func (s *WalletAdjustmentService) toAdjustmentInfo(adj *models.WalletAdjustment,
 		OperatorName:      adj.OperatorName,
 		Status:            adj.Status,
 		StatusName:        models.GetAdjustmentStatusName(adj.Status),
+		ApprovedBy:        adj.ApprovedBy,
+		ApproverName:      adj.ApproverName,
+		ApprovedAt:        adj.ApprovedAt,
+		RejectReason:      adj.RejectReason,
+		ReversalOfID:      adj.ReversalOfID,
+		ReversedByID:      adj.ReversedByID,
+		ReversedAt:        adj.ReversedAt,
 		CreatedAt:         adj.CreatedAt,
 	}
 }
@@ -220,6 +512,7 @@ type AdjustmentListParams struct {
 	AgentID    int64
 	WalletType *int16
 	ChannelID  *int64
+	Status     *int16
 	StartTime  *time.Time
 	EndTime    *time.Time
 	Page       int
@@ -234,6 +527,7 @@ func (s *WalletAdjustmentService) GetAdjustmentList(params *AdjustmentListParams
 		AgentID:    params.AgentID,
 		WalletType: params.WalletType,
 		ChannelID:  params.ChannelID,
+		Status:     params.Status,
 		StartTime:  params.StartTime,
 		EndTime:    params.EndTime,
 		Limit:      params.PageSize,
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNeedAdjustmentApproval 测试调账审批金额判断
+func TestNeedAdjustmentApproval(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		threshold int64
+		want      bool
+	}{
+		{"充入低于审批金额", 99999, 100000, false},
+		{"充入等于审批金额", 100000, 100000, true},
+		{"扣减按绝对值判断", -100000, 100000, true},
+		{"扣减低于审批金额", -50000, 100000, false},
+		{"审批金额为0时全部需审批", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, needAdjustmentApproval(tt.amount, tt.threshold))
+		})
+	}
+}
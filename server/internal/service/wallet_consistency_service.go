@@ -0,0 +1,533 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// WalletConsistencyService 钱包余额一致性检查服务
+// 按钱包回放钱包流水、交易分润、代扣记录和调账记录，定位首个分歧条目，
+// 对余额差异生成修正调账建议，由管理员采纳（走正常调账流程）或忽略
+type WalletConsistencyService struct {
+	checkRepo         *repository.GormWalletBalanceCheckRepository
+	adjustmentService *WalletAdjustmentService
+	agentRepo         *repository.GormAgentRepository
+}
+
+// NewWalletConsistencyService 创建钱包余额一致性检查服务
+func NewWalletConsistencyService(
+	checkRepo *repository.GormWalletBalanceCheckRepository,
+	adjustmentService *WalletAdjustmentService,
+	agentRepo *repository.GormAgentRepository,
+) *WalletConsistencyService {
+	return &WalletConsistencyService{
+		checkRepo:         checkRepo,
+		adjustmentService: adjustmentService,
+		agentRepo:         agentRepo,
+	}
+}
+
+// 回放条目来源
+const (
+	ledgerSourceWalletLog  = "wallet_log"
+	ledgerSourceProfit     = "profit_record"
+	ledgerSourceRevoke     = "profit_revoke"
+	ledgerSourceAdjustment = "wallet_adjustment"
+	ledgerSourceDeduction  = "deduction_record"
+)
+
+// ledgerEntry 回放条目
+type ledgerEntry struct {
+	Source        string
+	RefID         int64
+	LogID         int64 // 对应钱包流水ID，0表示无流水
+	OccurredAt    time.Time
+	Delta         int64 // 对余额的影响(分)
+	Chained       bool  // 是否参与流水余额链校验（冻结/退回流水不改变余额，不参与）
+	BalanceBefore int64
+	BalanceAfter  int64
+	MissingLog    bool // 业务记录没有对应的钱包流水
+	Remark        string
+}
+
+// ledgerDivergence 首个分歧
+type ledgerDivergence struct {
+	Kind   string
+	Entry  *ledgerEntry
+	Detail string
+}
+
+// ledgerReplay 回放结果
+type ledgerReplay struct {
+	ExpectedBalance int64
+	EntryCount      int
+	Divergence      *ledgerDivergence
+}
+
+// replayLedger 按时间顺序回放条目，返回回放余额及首个分歧
+// 分歧判定顺序：业务记录缺少流水 → 流水变动前余额与回放余额不一致 → 流水金额与前后余额不符 → 最终余额不符
+func replayLedger(entries []*ledgerEntry, openingBalance, currentBalance int64) *ledgerReplay {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].OccurredAt.Equal(entries[j].OccurredAt) {
+			return entries[i].OccurredAt.Before(entries[j].OccurredAt)
+		}
+		return entries[i].LogID < entries[j].LogID
+	})
+
+	result := &ledgerReplay{EntryCount: len(entries)}
+	running := openingBalance
+	for _, e := range entries {
+		if result.Divergence == nil {
+			switch {
+			case e.MissingLog:
+				result.Divergence = &ledgerDivergence{
+					Kind:   models.DivergenceMissingLog,
+					Entry:  e,
+					Detail: fmt.Sprintf("%s未生成钱包流水，影响金额%.2f元", e.Remark, float64(e.Delta)/100),
+				}
+			case e.Chained && e.BalanceBefore != running:
+				result.Divergence = &ledgerDivergence{
+					Kind:  models.DivergenceBalanceChain,
+					Entry: e,
+					Detail: fmt.Sprintf("流水#%d变动前余额%.2f元，回放余额%.2f元，相差%.2f元",
+						e.LogID, float64(e.BalanceBefore)/100, float64(running)/100, float64(e.BalanceBefore-running)/100),
+				}
+			case e.Chained && e.BalanceBefore+e.Delta != e.BalanceAfter:
+				result.Divergence = &ledgerDivergence{
+					Kind:  models.DivergenceAmountMismatch,
+					Entry: e,
+					Detail: fmt.Sprintf("流水#%d金额%.2f元与前后余额%.2f→%.2f元不符",
+						e.LogID, float64(e.Delta)/100, float64(e.BalanceBefore)/100, float64(e.BalanceAfter)/100),
+				}
+			}
+		}
+		running += e.Delta
+	}
+
+	result.ExpectedBalance = running
+	if result.Divergence == nil && running != currentBalance {
+		result.Divergence = &ledgerDivergence{
+			Kind: models.DivergenceFinalBalance,
+			Detail: fmt.Sprintf("回放余额%.2f元，钱包余额%.2f元，存在未记录的余额变更",
+				float64(running)/100, float64(currentBalance)/100),
+		}
+	}
+	return result
+}
+
+// buildLedgerEntries 将原始数据转换为回放条目
+// 钱包流水为主干；交易分润入账/撤销不写流水，以分润记录补入；调账、代扣按业务记录核对是否存在对应流水
+func buildLedgerEntries(src *repository.WalletLedgerSources) []*ledgerEntry {
+	entries := make([]*ledgerEntry, 0, len(src.Logs)+len(src.ProfitRecords))
+
+	adjustmentLogs := make(map[int64]bool)
+	deductionDebitLogs := make(map[int64]bool)
+	deductionCreditLogs := make(map[int64]bool)
+	for _, l := range src.Logs {
+		entry := &ledgerEntry{
+			Source:        ledgerSourceWalletLog,
+			RefID:         l.RefID,
+			LogID:         l.ID,
+			OccurredAt:    l.CreatedAt,
+			Delta:         l.Amount,
+			Chained:       true,
+			BalanceBefore: l.BalanceBefore,
+			BalanceAfter:  l.BalanceAfter,
+		}
+		if l.LogType == WalletLogTypeWithdrawFreeze || l.LogType == WalletLogTypeWithdrawReturn {
+			entry.Delta = 0
+			entry.Chained = false
+		}
+		entries = append(entries, entry)
+
+		switch l.RefType {
+		case "wallet_adjustment":
+			adjustmentLogs[l.RefID] = true
+		case "deduction_record":
+			if l.Amount < 0 {
+				deductionDebitLogs[l.RefID] = true
+			} else {
+				deductionCreditLogs[l.RefID] = true
+			}
+		}
+	}
+
+	for _, p := range src.ProfitRecords {
+		// 回放起点之前入账、之后撤销的分润只计撤销
+		if src.Since == nil || p.CreatedAt.After(*src.Since) {
+			entries = append(entries, &ledgerEntry{
+				Source:     ledgerSourceProfit,
+				RefID:      p.ID,
+				OccurredAt: p.CreatedAt,
+				Delta:      p.ProfitAmount,
+			})
+		}
+		if p.IsRevoked && p.RevokedAt != nil && (src.Since == nil || p.RevokedAt.After(*src.Since)) {
+			entries = append(entries, &ledgerEntry{
+				Source:     ledgerSourceRevoke,
+				RefID:      p.ID,
+				OccurredAt: *p.RevokedAt,
+				Delta:      -p.ProfitAmount,
+			})
+		}
+	}
+
+	for _, adj := range src.Adjustments {
+		if adjustmentLogs[adj.ID] {
+			continue
+		}
+		occurredAt := adj.CreatedAt
+		if adj.ApprovedAt != nil {
+			occurredAt = *adj.ApprovedAt
+		}
+		entries = append(entries, &ledgerEntry{
+			Source:     ledgerSourceAdjustment,
+			RefID:      adj.ID,
+			OccurredAt: occurredAt,
+			Delta:      adj.Amount,
+			MissingLog: true,
+			Remark:     "调账" + adj.AdjustmentNo,
+		})
+	}
+
+	for _, rec := range src.DeducteeRecords {
+		if deductionDebitLogs[rec.ID] || rec.DeductedAt == nil {
+			continue
+		}
+		var details []models.WalletDeductDetail
+		if err := json.Unmarshal([]byte(rec.WalletDetails), &details); err != nil {
+			continue
+		}
+		for _, d := range details {
+			if d.WalletID != src.Wallet.ID {
+				continue
+			}
+			entries = append(entries, &ledgerEntry{
+				Source:     ledgerSourceDeduction,
+				RefID:      rec.ID,
+				OccurredAt: *rec.DeductedAt,
+				Delta:      -d.DeductAmount,
+				MissingLog: true,
+				Remark:     fmt.Sprintf("代扣记录#%d扣款", rec.ID),
+			})
+		}
+	}
+
+	for _, rec := range src.DeductorRecords {
+		if deductionCreditLogs[rec.ID] || rec.DeductedAt == nil {
+			continue
+		}
+		entries = append(entries, &ledgerEntry{
+			Source:     ledgerSourceDeduction,
+			RefID:      rec.ID,
+			OccurredAt: *rec.DeductedAt,
+			Delta:      rec.ActualAmount,
+			MissingLog: true,
+			Remark:     fmt.Sprintf("代扣记录#%d收款", rec.ID),
+		})
+	}
+
+	return entries
+}
+
+// CheckWallet 检查单个钱包
+// 手动检查会使该钱包仍待处理的旧检查失效
+func (s *WalletConsistencyService) CheckWallet(walletID int64, triggerType int16) (*models.WalletBalanceCheck, error) {
+	baseline, err := s.checkRepo.FindLatestClosed(walletID)
+	if err != nil {
+		return nil, fmt.Errorf("查询历史检查失败: %w", err)
+	}
+
+	var since *time.Time
+	var openingBalance int64
+	if baseline != nil {
+		since = &baseline.CheckedAt
+		openingBalance = s.baselineBalance(baseline)
+	}
+
+	sources, err := s.checkRepo.LoadLedgerSources(walletID, since)
+	if err != nil {
+		return nil, fmt.Errorf("加载钱包回放数据失败: %w", err)
+	}
+	wallet := sources.Wallet
+	// 以快照时间作为检查时间，下次检查从这里开始回放
+	checkedAt := sources.CheckedAt
+
+	replay := replayLedger(buildLedgerEntries(sources), openingBalance, wallet.Balance)
+
+	check := &models.WalletBalanceCheck{
+		CheckNo:         fmt.Sprintf("WBC%s%06d", checkedAt.Format("20060102150405"), checkedAt.UnixNano()%1000000),
+		WalletID:        wallet.ID,
+		AgentID:         wallet.AgentID,
+		WalletType:      wallet.WalletType,
+		ChannelID:       wallet.ChannelID,
+		TriggerType:     triggerType,
+		BaselineAt:      since,
+		OpeningBalance:  openingBalance,
+		CurrentBalance:  wallet.Balance,
+		ExpectedBalance: replay.ExpectedBalance,
+		Difference:      replay.ExpectedBalance - wallet.Balance,
+		EntryCount:      replay.EntryCount,
+		Status:          models.BalanceCheckStatusConsistent,
+		CheckedAt:       checkedAt,
+		CreatedAt:       checkedAt,
+		UpdatedAt:       checkedAt,
+	}
+	if d := replay.Divergence; d != nil {
+		check.Status = models.BalanceCheckStatusPending
+		check.DivergenceKind = d.Kind
+		check.DivergenceDetail = d.Detail
+		check.ProposedAmount = check.Difference
+		if d.Entry != nil {
+			check.DivergentSource = d.Entry.Source
+			refID, at := d.Entry.RefID, d.Entry.OccurredAt
+			check.DivergentRefID = &refID
+			check.DivergentAt = &at
+			if d.Entry.LogID > 0 {
+				logID := d.Entry.LogID
+				check.DivergentLogID = &logID
+			}
+		}
+	}
+
+	if triggerType == models.BalanceCheckTriggerManual {
+		if err := s.checkRepo.SupersedePending(walletID); err != nil {
+			return nil, fmt.Errorf("更新历史检查失败: %w", err)
+		}
+	}
+	if err := s.checkRepo.Create(check); err != nil {
+		return nil, fmt.Errorf("保存检查记录失败: %w", err)
+	}
+
+	if check.Status == models.BalanceCheckStatusPending {
+		log.Printf("[WalletConsistencyService] Discrepancy found: wallet=%d, kind=%s, expected=%d, current=%d",
+			walletID, check.DivergenceKind, check.ExpectedBalance, check.CurrentBalance)
+	}
+	return check, nil
+}
+
+// baselineBalance 计算回放期初余额
+// 取上次检查时的钱包余额；若上次采纳的修正调账被驳回，修正未生效，差异需重新计入
+func (s *WalletConsistencyService) baselineBalance(baseline *models.WalletBalanceCheck) int64 {
+	if baseline.Status != models.BalanceCheckStatusAccepted || baseline.AdjustmentID == nil {
+		return baseline.CurrentBalance
+	}
+	adj, err := s.adjustmentService.GetAdjustmentDetail(*baseline.AdjustmentID)
+	if err == nil && adj.Status == models.AdjustmentStatusRejected {
+		return baseline.CurrentBalance + baseline.ProposedAmount
+	}
+	return baseline.CurrentBalance
+}
+
+// CheckAll 检查全部钱包，已有待处理检查的钱包跳过
+// 返回检查的钱包数及新发现的差异
+func (s *WalletConsistencyService) CheckAll(batchSize int) (int, []*models.WalletBalanceCheck, error) {
+	var checked int
+	var discrepancies []*models.WalletBalanceCheck
+
+	var lastID int64
+	for {
+		walletIDs, err := s.checkRepo.FindWalletIDsAfter(lastID, batchSize)
+		if err != nil {
+			return checked, discrepancies, fmt.Errorf("查询钱包失败: %w", err)
+		}
+		if len(walletIDs) == 0 {
+			break
+		}
+		lastID = walletIDs[len(walletIDs)-1]
+
+		for _, walletID := range walletIDs {
+			pending, err := s.checkRepo.HasPending(walletID)
+			if err != nil || pending {
+				continue
+			}
+			check, err := s.CheckWallet(walletID, models.BalanceCheckTriggerScheduled)
+			if err != nil {
+				log.Printf("[WalletConsistencyService] Check wallet %d failed: %v", walletID, err)
+				continue
+			}
+			checked++
+			if check.Status == models.BalanceCheckStatusPending {
+				discrepancies = append(discrepancies, check)
+			}
+		}
+	}
+
+	return checked, discrepancies, nil
+}
+
+// HandleBalanceCheckRequest 处理检查请求
+type HandleBalanceCheckRequest struct {
+	ID           int64  `json:"-"`
+	Remark       string `json:"remark"`
+	OperatorID   int64  `json:"-"`
+	OperatorName string `json:"-"`
+}
+
+// AcceptProposal 采纳修正建议，按建议金额创建调账（达到审批金额时进入调账审批）
+func (s *WalletConsistencyService) AcceptProposal(req *HandleBalanceCheckRequest) (*BalanceCheckInfo, error) {
+	check, err := s.getPendingCheck(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if check.ProposedAmount == 0 {
+		return nil, fmt.Errorf("该检查余额无差异，无需修正调账，请选择忽略")
+	}
+
+	// 先占用检查记录，避免重复采纳生成多笔调账
+	ok, err := s.checkRepo.Close(check.ID, models.BalanceCheckStatusAccepted, nil, req.OperatorID, req.OperatorName, req.Remark)
+	if err != nil {
+		return nil, fmt.Errorf("更新检查记录失败: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("检查记录已被处理")
+	}
+
+	adjustment, err := s.adjustmentService.CreateAdjustment(&CreateAdjustmentRequest{
+		AgentID:      check.AgentID,
+		WalletType:   check.WalletType,
+		ChannelID:    check.ChannelID,
+		Amount:       check.ProposedAmount,
+		Reason:       fmt.Sprintf("余额一致性检查%s修正：%s", check.CheckNo, check.DivergenceDetail),
+		OperatorID:   req.OperatorID,
+		OperatorName: req.OperatorName,
+	})
+	if err != nil {
+		if reopenErr := s.checkRepo.Reopen(check.ID); reopenErr != nil {
+			log.Printf("[WalletConsistencyService] Reopen check %d failed: %v", check.ID, reopenErr)
+		}
+		return nil, err
+	}
+
+	if err := s.checkRepo.SetAdjustmentID(check.ID, adjustment.ID); err != nil {
+		log.Printf("[WalletConsistencyService] Link adjustment %d to check %d failed: %v", adjustment.ID, check.ID, err)
+	}
+
+	log.Printf("[WalletConsistencyService] Proposal accepted: check=%s, adjustment=%s, amount=%d",
+		check.CheckNo, adjustment.AdjustmentNo, check.ProposedAmount)
+
+	return s.GetCheckDetail(check.ID)
+}
+
+// DismissProposal 忽略检查结果，以当前钱包余额作为后续回放起点
+func (s *WalletConsistencyService) DismissProposal(req *HandleBalanceCheckRequest) error {
+	if req.Remark == "" {
+		return fmt.Errorf("请填写忽略原因")
+	}
+	check, err := s.getPendingCheck(req.ID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.checkRepo.Close(check.ID, models.BalanceCheckStatusDismissed, nil, req.OperatorID, req.OperatorName, req.Remark)
+	if err != nil {
+		return fmt.Errorf("更新检查记录失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("检查记录已被处理")
+	}
+	return nil
+}
+
+func (s *WalletConsistencyService) getPendingCheck(id int64) (*models.WalletBalanceCheck, error) {
+	check, err := s.checkRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询检查记录失败: %w", err)
+	}
+	if check == nil {
+		return nil, fmt.Errorf("检查记录不存在")
+	}
+	if check.Status != models.BalanceCheckStatusPending {
+		return nil, fmt.Errorf("检查记录状态为%s，无法处理", models.GetBalanceCheckStatusName(check.Status))
+	}
+	return check, nil
+}
+
+// BalanceCheckInfo 检查记录信息
+type BalanceCheckInfo struct {
+	*models.WalletBalanceCheck
+	AgentName           string  `json:"agent_name"`
+	WalletTypeName      string  `json:"wallet_type_name"`
+	StatusName          string  `json:"status_name"`
+	DivergenceKindName  string  `json:"divergence_kind_name"`
+	CurrentBalanceYuan  float64 `json:"current_balance_yuan"`
+	ExpectedBalanceYuan float64 `json:"expected_balance_yuan"`
+	DifferenceYuan      float64 `json:"difference_yuan"`
+	ProposedAmountYuan  float64 `json:"proposed_amount_yuan"`
+}
+
+func (s *WalletConsistencyService) toCheckInfo(check *models.WalletBalanceCheck, agentName string) *BalanceCheckInfo {
+	return &BalanceCheckInfo{
+		WalletBalanceCheck:  check,
+		AgentName:           agentName,
+		WalletTypeName:      models.GetWalletTypeName(check.WalletType),
+		StatusName:          models.GetBalanceCheckStatusName(check.Status),
+		DivergenceKindName:  models.GetDivergenceKindName(check.DivergenceKind),
+		CurrentBalanceYuan:  float64(check.CurrentBalance) / 100,
+		ExpectedBalanceYuan: float64(check.ExpectedBalance) / 100,
+		DifferenceYuan:      float64(check.Difference) / 100,
+		ProposedAmountYuan:  float64(check.ProposedAmount) / 100,
+	}
+}
+
+// BalanceCheckListParams 检查记录列表查询参数
+type BalanceCheckListParams struct {
+	WalletID  int64
+	AgentID   int64
+	Status    *int16
+	StartTime *time.Time
+	EndTime   *time.Time
+	Page      int
+	PageSize  int
+}
+
+// GetCheckList 获取检查记录列表（按钱包过滤即为该钱包的检查历史）
+func (s *WalletConsistencyService) GetCheckList(params *BalanceCheckListParams) ([]*BalanceCheckInfo, int64, error) {
+	checks, total, err := s.checkRepo.List(&repository.WalletBalanceCheckQueryParams{
+		WalletID:  params.WalletID,
+		AgentID:   params.AgentID,
+		Status:    params.Status,
+		StartTime: params.StartTime,
+		EndTime:   params.EndTime,
+		Limit:     params.PageSize,
+		Offset:    (params.Page - 1) * params.PageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询检查记录失败: %w", err)
+	}
+
+	agentNames := make(map[int64]string)
+	list := make([]*BalanceCheckInfo, 0, len(checks))
+	for _, check := range checks {
+		if _, ok := agentNames[check.AgentID]; !ok {
+			agentNames[check.AgentID] = s.getAgentName(check.AgentID)
+		}
+		list = append(list, s.toCheckInfo(check, agentNames[check.AgentID]))
+	}
+	return list, total, nil
+}
+
+// GetCheckDetail 获取检查记录详情
+func (s *WalletConsistencyService) GetCheckDetail(id int64) (*BalanceCheckInfo, error) {
+	check, err := s.checkRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询检查记录失败: %w", err)
+	}
+	if check == nil {
+		return nil, fmt.Errorf("检查记录不存在")
+	}
+	return s.toCheckInfo(check, s.getAgentName(check.AgentID)), nil
+}
+
+func (s *WalletConsistencyService) getAgentName(agentID int64) string {
+	if agent, _ := s.agentRepo.FindByIDFull(agentID); agent != nil {
+		return agent.AgentName
+	}
+	return ""
+}
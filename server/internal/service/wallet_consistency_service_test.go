@@ -0,0 +1,149 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// TestReplayLedger 测试钱包回放与首个分歧定位
+func TestReplayLedger(t *testing.T) {
+	base := time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)
+	logEntry := func(id int64, minute int, delta, before, after int64) *ledgerEntry {
+		return &ledgerEntry{
+			Source: ledgerSourceWalletLog, LogID: id, OccurredAt: base.Add(time.Duration(minute) * time.Minute),
+			Delta: delta, Chained: true, BalanceBefore: before, BalanceAfter: after,
+		}
+	}
+	profitEntry := func(id int64, minute int, delta int64) *ledgerEntry {
+		return &ledgerEntry{Source: ledgerSourceProfit, RefID: id, OccurredAt: base.Add(time.Duration(minute) * time.Minute), Delta: delta}
+	}
+
+	t.Run("流水与分润一致", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			profitEntry(1, 0, 1000),
+			logEntry(10, 1, -300, 1000, 700),
+			profitEntry(2, 2, 500),
+			logEntry(11, 3, 200, 1200, 1400),
+		}
+		result := replayLedger(entries, 0, 1400)
+		assert.Equal(t, int64(1400), result.ExpectedBalance)
+		assert.Equal(t, 4, result.EntryCount)
+		assert.Nil(t, result.Divergence)
+	})
+
+	t.Run("乱序输入按时间回放", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(11, 3, 200, 1200, 1400),
+			profitEntry(1, 0, 1200),
+		}
+		result := replayLedger(entries, 0, 1400)
+		assert.Nil(t, result.Divergence)
+	})
+
+	t.Run("流水余额断链", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(10, 1, 1000, 0, 1000),
+			logEntry(11, 2, -300, 1500, 1200), // 之前有500未记录入账
+			logEntry(12, 3, 100, 1200, 1300),
+		}
+		result := replayLedger(entries, 0, 1300)
+		assert.NotNil(t, result.Divergence)
+		assert.Equal(t, models.DivergenceBalanceChain, result.Divergence.Kind)
+		assert.Equal(t, int64(11), result.Divergence.Entry.LogID)
+		assert.Equal(t, int64(800), result.ExpectedBalance)
+	})
+
+	t.Run("流水金额与前后余额不符", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(10, 1, 1000, 0, 900),
+		}
+		result := replayLedger(entries, 0, 1000)
+		assert.Equal(t, models.DivergenceAmountMismatch, result.Divergence.Kind)
+	})
+
+	t.Run("业务记录缺少流水", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(10, 1, 1000, 0, 1000),
+			{Source: ledgerSourceDeduction, RefID: 5, OccurredAt: base.Add(2 * time.Minute), Delta: -200, MissingLog: true, Remark: "代扣记录#5扣款"},
+			logEntry(11, 3, 100, 800, 900),
+		}
+		result := replayLedger(entries, 0, 900)
+		assert.Equal(t, models.DivergenceMissingLog, result.Divergence.Kind)
+		assert.Equal(t, int64(5), result.Divergence.Entry.RefID)
+		assert.Equal(t, int64(900), result.ExpectedBalance)
+	})
+
+	t.Run("最终余额不符", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(10, 1, 1000, 0, 1000),
+		}
+		result := replayLedger(entries, 0, 1500)
+		assert.Equal(t, models.DivergenceFinalBalance, result.Divergence.Kind)
+		assert.Nil(t, result.Divergence.Entry)
+		assert.Equal(t, int64(1000), result.ExpectedBalance)
+	})
+
+	t.Run("从期初余额开始回放", func(t *testing.T) {
+		entries := []*ledgerEntry{
+			logEntry(20, 1, -500, 3000, 2500),
+		}
+		result := replayLedger(entries, 3000, 2500)
+		assert.Nil(t, result.Divergence)
+	})
+}
+
+// TestBuildLedgerEntries 测试回放条目构建
+func TestBuildLedgerEntries(t *testing.T) {
+	base := time.Date(2026, 10, 1, 10, 0, 0, 0, time.Local)
+	since := base
+	revokedAt := base.Add(2 * time.Hour)
+	deductedAt := base.Add(3 * time.Hour)
+
+	src := &repository.WalletLedgerSources{
+		Since:  &since,
+		Wallet: &repository.Wallet{ID: 7, AgentID: 3, ChannelID: 1, WalletType: 1},
+		Logs: []*repository.WalletLog{
+			{ID: 1, WalletID: 7, LogType: WalletLogTypeWithdrawFreeze, Amount: -100, BalanceBefore: 500, BalanceAfter: 500, CreatedAt: base.Add(time.Hour)},
+			{ID: 2, WalletID: 7, LogType: WalletLogTypeAdjustmentIn, Amount: 300, BalanceBefore: 500, BalanceAfter: 800, RefType: "wallet_adjustment", RefID: 40, CreatedAt: base.Add(time.Hour)},
+		},
+		ProfitRecords: []*repository.ProfitRecord{
+			{ID: 50, ProfitAmount: 200, CreatedAt: base.Add(-time.Hour), IsRevoked: true, RevokedAt: &revokedAt},
+		},
+		Adjustments: []*models.WalletAdjustment{
+			{ID: 40, Amount: 300, CreatedAt: base.Add(time.Hour)},
+			{ID: 41, AdjustmentNo: "ADJ41", Amount: -50, CreatedAt: base.Add(90 * time.Minute)},
+		},
+		DeducteeRecords: []*models.DeductionRecord{
+			{ID: 60, ActualAmount: 80, DeductedAt: &deductedAt, WalletDetails: `[{"wallet_id":7,"deduct_amount":30},{"wallet_id":8,"deduct_amount":50}]`},
+		},
+	}
+
+	entries := buildLedgerEntries(src)
+	bySource := make(map[string][]*ledgerEntry)
+	for _, e := range entries {
+		bySource[e.Source] = append(bySource[e.Source], e)
+	}
+
+	// 提现冻结流水不改变余额
+	assert.Len(t, bySource[ledgerSourceWalletLog], 2)
+	assert.Equal(t, int64(0), bySource[ledgerSourceWalletLog][0].Delta)
+	assert.False(t, bySource[ledgerSourceWalletLog][0].Chained)
+
+	// 回放起点前入账、之后撤销的分润只计撤销
+	assert.Empty(t, bySource[ledgerSourceProfit])
+	assert.Len(t, bySource[ledgerSourceRevoke], 1)
+	assert.Equal(t, int64(-200), bySource[ledgerSourceRevoke][0].Delta)
+
+	// 已有流水的调账不重复计入，缺少流水的调账作为分歧条目
+	assert.Len(t, bySource[ledgerSourceAdjustment], 1)
+	assert.Equal(t, int64(41), bySource[ledgerSourceAdjustment][0].RefID)
+	assert.True(t, bySource[ledgerSourceAdjustment][0].MissingLog)
+
+	// 代扣仅计入本钱包的扣款明细
+	assert.Len(t, bySource[ledgerSourceDeduction], 1)
+	assert.Equal(t, int64(-30), bySource[ledgerSourceDeduction][0].Delta)
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// 展示给代理商的默认客服提示
+const riskHoldSupportMessage = "您的钱包存在风控冻结，冻结期间暂不可提现、划转或使用沉淀款，如有疑问请联系客服处理。"
+
+// WalletRiskHoldService 钱包风控冻结服务
+// 冻结不改变钱包余额和 FrozenAmount，由提现、划转、沉淀款使用等出款路径调用校验
+type WalletRiskHoldService struct {
+	holdRepo       *repository.GormWalletRiskHoldRepository
+	walletRepo     *repository.GormWalletRepository
+	agentRepo      *repository.GormAgentRepository
+	messageService *MessageService
+}
+
+// NewWalletRiskHoldService 创建钱包风控冻结服务
+func NewWalletRiskHoldService(
+	holdRepo *repository.GormWalletRiskHoldRepository,
+	walletRepo *repository.GormWalletRepository,
+	agentRepo *repository.GormAgentRepository,
+) *WalletRiskHoldService {
+	return &WalletRiskHoldService{
+		holdRepo:   holdRepo,
+		walletRepo: walletRepo,
+		agentRepo:  agentRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（用于冻结/解除通知）
+func (s *WalletRiskHoldService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// CreateRiskHoldRequest 创建风控冻结请求
+type CreateRiskHoldRequest struct {
+	AgentID       int64      `json:"agent_id" binding:"required"`
+	Scope         int16      `json:"scope" binding:"required"` // 1指定金额 2整个钱包 3全部钱包
+	WalletID      int64      `json:"wallet_id"`                // 范围1、2必填
+	Amount        int64      `json:"amount"`                   // 范围1必填（分）
+	Reason        string     `json:"reason" binding:"required"`
+	AgentMessage  string     `json:"agent_message"` // 展示给代理商的说明
+	CaseRef       string     `json:"case_ref"`      // 关联风控案件
+	ExpireAt      *time.Time `json:"expire_at"`     // 到期自动解除，空表示需人工解除
+	CreatedBy     int64      `json:"-"`
+	CreatedByName string     `json:"-"`
+}
+
+// CreateHold 创建风控冻结
+func (s *WalletRiskHoldService) CreateHold(req *CreateRiskHoldRequest) (*RiskHoldInfo, error) {
+	agent, err := s.agentRepo.FindByIDFull(req.AgentID)
+	if err != nil || agent == nil {
+		return nil, errors.New("代理商不存在")
+	}
+
+	now := time.Now()
+	if req.ExpireAt != nil && !req.ExpireAt.After(now) {
+		return nil, errors.New("到期时间必须晚于当前时间")
+	}
+
+	hold := &models.WalletRiskHold{
+		HoldNo:        fmt.Sprintf("RH%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		AgentID:       req.AgentID,
+		Scope:         req.Scope,
+		Reason:        req.Reason,
+		AgentMessage:  req.AgentMessage,
+		CaseRef:       req.CaseRef,
+		ExpireAt:      req.ExpireAt,
+		Status:        models.RiskHoldStatusActive,
+		CreatedBy:     req.CreatedBy,
+		CreatedByName: req.CreatedByName,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	switch req.Scope {
+	case models.RiskHoldScopeAmount, models.RiskHoldScopeWallet:
+		wallet, err := s.walletRepo.FindByID(req.WalletID)
+		if err != nil || wallet == nil {
+			return nil, errors.New("钱包不存在")
+		}
+		if wallet.AgentID != req.AgentID {
+			return nil, errors.New("钱包不属于该代理商")
+		}
+		if req.Scope == models.RiskHoldScopeAmount && req.Amount <= 0 {
+			return nil, errors.New("冻结金额必须大于0")
+		}
+		hold.WalletID = wallet.ID
+		hold.WalletType = wallet.WalletType
+		hold.ChannelID = wallet.ChannelID
+		if req.Scope == models.RiskHoldScopeAmount {
+			hold.Amount = req.Amount
+		}
+	case models.RiskHoldScopeAgent:
+		// 全部钱包冻结不关联具体钱包
+	default:
+		return nil, errors.New("无效的冻结范围")
+	}
+
+	if err := s.holdRepo.Create(hold); err != nil {
+		return nil, fmt.Errorf("创建冻结记录失败: %w", err)
+	}
+
+	log.Printf("[WalletRiskHoldService] Created hold: %s, agent=%d, scope=%d, wallet=%d, amount=%d, case=%s",
+		hold.HoldNo, hold.AgentID, hold.Scope, hold.WalletID, hold.Amount, hold.CaseRef)
+
+	s.notify(hold, "钱包风控冻结", fmt.Sprintf("您的%s已被风控冻结。%s", s.describeHoldTarget(hold), s.agentMessageOf(hold)))
+
+	return s.toRiskHoldInfo(hold, agent.AgentName), nil
+}
+
+// ReleaseHold 人工解除冻结
+func (s *WalletRiskHoldService) ReleaseHold(id int64, operatorID int64, operatorName string, reason string) error {
+	hold, err := s.holdRepo.GetByID(id)
+	if err != nil {
+		return fmt.Errorf("查询冻结记录失败: %w", err)
+	}
+	if hold == nil {
+		return errors.New("冻结记录不存在")
+	}
+	if hold.Status != models.RiskHoldStatusActive {
+		return errors.New("该冻结已解除")
+	}
+
+	ok, err := s.holdRepo.Release(id, models.RiskHoldStatusReleased, &operatorID, operatorName, reason, time.Now())
+	if err != nil {
+		return fmt.Errorf("解除冻结失败: %w", err)
+	}
+	if !ok {
+		return errors.New("该冻结已解除")
+	}
+
+	log.Printf("[WalletRiskHoldService] Released hold: %s, operator=%d", hold.HoldNo, operatorID)
+	s.notify(hold, "钱包风控冻结已解除", fmt.Sprintf("您的%s风控冻结已解除。", s.describeHoldTarget(hold)))
+	return nil
+}
+
+// GetCaseHolds 获取关联指定风控案件且未解除的冻结
+func (s *WalletRiskHoldService) GetCaseHolds(caseRef string) ([]*models.WalletRiskHold, error) {
+	return s.holdRepo.FindActiveByCaseRef(caseRef)
+}
+
+// ReleaseCaseHolds 解除关联指定风控案件的全部冻结，返回解除数量
+func (s *WalletRiskHoldService) ReleaseCaseHolds(caseRef string, operatorID int64, operatorName string, reason string) (int, error) {
+	holds, err := s.holdRepo.FindActiveByCaseRef(caseRef)
+	if err != nil {
+		return 0, fmt.Errorf("查询冻结记录失败: %w", err)
+	}
+
+	count := 0
+	for _, hold := range holds {
+		if err := s.ReleaseHold(hold.ID, operatorID, operatorName, reason); err != nil {
+			log.Printf("[WalletRiskHoldService] Release case hold %s failed: %v", hold.HoldNo, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ExpireHolds 到期自动解除冻结，返回处理数量
+func (s *WalletRiskHoldService) ExpireHolds(batchSize int) (int, error) {
+	now := time.Now()
+	holds, err := s.holdRepo.FindExpiredActive(now, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("查询到期冻结失败: %w", err)
+	}
+
+	count := 0
+	for _, hold := range holds {
+		ok, err := s.holdRepo.Release(hold.ID, models.RiskHoldStatusExpired, nil, "系统", "到期自动解除", now)
+		if err != nil {
+			log.Printf("[WalletRiskHoldService] Expire hold %s failed: %v", hold.HoldNo, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		count++
+		s.notify(hold, "钱包风控冻结已到期", fmt.Sprintf("您的%s风控冻结已到期自动解除。", s.describeHoldTarget(hold)))
+	}
+	return count, nil
+}
+
+// ========== 出款校验 ==========
+
+// CheckWithdraw 校验钱包是否允许出款（提现、划转转出）
+func (s *WalletRiskHoldService) CheckWithdraw(agentID int64, wallet *repository.Wallet, amount int64) error {
+	holds, err := s.holdRepo.FindEffectiveByAgent(agentID, time.Now())
+	if err != nil {
+		return fmt.Errorf("查询风控状态失败: %w", err)
+	}
+	return checkRiskHolds(holds, wallet, amount)
+}
+
+// CheckSettlementUse 校验代理商是否允许使用沉淀款
+// 沉淀款来源于下级钱包余额，代理商存在任一生效冻结时均不允许使用
+func (s *WalletRiskHoldService) CheckSettlementUse(agentID int64) error {
+	holds, err := s.holdRepo.FindEffectiveByAgent(agentID, time.Now())
+	if err != nil {
+		return fmt.Errorf("查询风控状态失败: %w", err)
+	}
+	if len(holds) > 0 {
+		return errors.New("账户存在风控冻结，暂不可使用沉淀款，请联系客服")
+	}
+	return nil
+}
+
+// GetEffectiveHolds 获取代理商当前生效的冻结
+func (s *WalletRiskHoldService) GetEffectiveHolds(agentID int64) ([]*models.WalletRiskHold, error) {
+	return s.holdRepo.FindEffectiveByAgent(agentID, time.Now())
+}
+
+// walletHoldState 计算指定钱包的冻结情况：指定金额冻结合计，以及是否整体冻结
+func walletHoldState(holds []*models.WalletRiskHold, walletID int64) (heldAmount int64, fullyHeld bool) {
+	for _, h := range holds {
+		switch h.Scope {
+		case models.RiskHoldScopeAgent:
+			fullyHeld = true
+		case models.RiskHoldScopeWallet:
+			if h.WalletID == walletID {
+				fullyHeld = true
+			}
+		case models.RiskHoldScopeAmount:
+			if h.WalletID == walletID {
+				heldAmount += h.Amount
+			}
+		}
+	}
+	return heldAmount, fullyHeld
+}
+
+// checkRiskHolds 根据生效冻结校验钱包出款
+func checkRiskHolds(holds []*models.WalletRiskHold, wallet *repository.Wallet, amount int64) error {
+	heldAmount, fullyHeld := walletHoldState(holds, wallet.ID)
+	if fullyHeld {
+		return errors.New("该钱包已被风控冻结，暂不可提现或划转，请联系客服")
+	}
+
+	if heldAmount > 0 {
+		available := wallet.Balance - wallet.FrozenAmount - heldAmount
+		if available < amount {
+			if available < 0 {
+				available = 0
+			}
+			return fmt.Errorf("可用余额不足，其中%.2f元已被风控冻结，当前可出款%.2f元，请联系客服",
+				float64(heldAmount)/100, float64(available)/100)
+		}
+	}
+	return nil
+}
+
+// ========== 查询 ==========
+
+// RiskHoldInfo 冻结信息（管理端）
+type RiskHoldInfo struct {
+	ID             int64      `json:"id"`
+	HoldNo         string     `json:"hold_no"`
+	AgentID        int64      `json:"agent_id"`
+	AgentName      string     `json:"agent_name"`
+	Scope          int16      `json:"scope"`
+	ScopeName      string     `json:"scope_name"`
+	WalletID       int64      `json:"wallet_id"`
+	WalletType     int16      `json:"wallet_type"`
+	WalletTypeName string     `json:"wallet_type_name"`
+	ChannelID      int64      `json:"channel_id"`
+	Amount         int64      `json:"amount"`
+	AmountYuan     float64    `json:"amount_yuan"`
+	Reason         string     `json:"reason"`
+	AgentMessage   string     `json:"agent_message"`
+	CaseRef        string     `json:"case_ref"`
+	ExpireAt       *time.Time `json:"expire_at"`
+	Status         int16      `json:"status"`
+	StatusName     string     `json:"status_name"`
+	CreatedBy      int64      `json:"created_by"`
+	CreatedByName  string     `json:"created_by_name"`
+	ReleasedByName string     `json:"released_by_name"`
+	ReleasedAt     *time.Time `json:"released_at"`
+	ReleaseReason  string     `json:"release_reason"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (s *WalletRiskHoldService) toRiskHoldInfo(h *models.WalletRiskHold, agentName string) *RiskHoldInfo {
+	info := &RiskHoldInfo{
+		ID:             h.ID,
+		HoldNo:         h.HoldNo,
+		AgentID:        h.AgentID,
+		AgentName:      agentName,
+		Scope:          h.Scope,
+		ScopeName:      models.GetRiskHoldScopeName(h.Scope),
+		WalletID:       h.WalletID,
+		WalletType:     h.WalletType,
+		ChannelID:      h.ChannelID,
+		Amount:         h.Amount,
+		AmountYuan:     float64(h.Amount) / 100,
+		Reason:         h.Reason,
+		AgentMessage:   h.AgentMessage,
+		CaseRef:        h.CaseRef,
+		ExpireAt:       h.ExpireAt,
+		Status:         h.Status,
+		StatusName:     models.GetRiskHoldStatusName(h.Status),
+		CreatedBy:      h.CreatedBy,
+		CreatedByName:  h.CreatedByName,
+		ReleasedByName: h.ReleasedByName,
+		ReleasedAt:     h.ReleasedAt,
+		ReleaseReason:  h.ReleaseReason,
+		CreatedAt:      h.CreatedAt,
+	}
+	if h.WalletType > 0 {
+		info.WalletTypeName = models.GetWalletTypeName(h.WalletType)
+	}
+	// 已到期但定时任务尚未处理的冻结按已到期展示
+	if h.Status == models.RiskHoldStatusActive && !h.IsEffective(time.Now()) {
+		info.StatusName = models.GetRiskHoldStatusName(models.RiskHoldStatusExpired)
+	}
+	return info
+}
+
+// RiskHoldListParams 冻结列表查询参数
+type RiskHoldListParams struct {
+	AgentID   int64
+	Status    *int16
+	Scope     *int16
+	CaseRef   string
+	StartTime *time.Time
+	EndTime   *time.Time
+	Page      int
+	PageSize  int
+}
+
+// GetHoldList 获取冻结列表（管理端）
+func (s *WalletRiskHoldService) GetHoldList(params *RiskHoldListParams) ([]*RiskHoldInfo, int64, error) {
+	holds, total, err := s.holdRepo.List(&repository.WalletRiskHoldQueryParams{
+		AgentID:   params.AgentID,
+		Status:    params.Status,
+		Scope:     params.Scope,
+		CaseRef:   params.CaseRef,
+		StartTime: params.StartTime,
+		EndTime:   params.EndTime,
+		Limit:     params.PageSize,
+		Offset:    (params.Page - 1) * params.PageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询冻结列表失败: %w", err)
+	}
+
+	agentNames := make(map[int64]string)
+	list := make([]*RiskHoldInfo, 0, len(holds))
+	for _, h := range holds {
+		if _, ok := agentNames[h.AgentID]; !ok {
+			if agent, _ := s.agentRepo.FindByIDFull(h.AgentID); agent != nil {
+				agentNames[h.AgentID] = agent.AgentName
+			}
+		}
+		list = append(list, s.toRiskHoldInfo(h, agentNames[h.AgentID]))
+	}
+	return list, total, nil
+}
+
+// GetHoldDetail 获取冻结详情（管理端）
+func (s *WalletRiskHoldService) GetHoldDetail(id int64) (*RiskHoldInfo, error) {
+	hold, err := s.holdRepo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("查询冻结记录失败: %w", err)
+	}
+	if hold == nil {
+		return nil, errors.New("冻结记录不存在")
+	}
+
+	agentName := ""
+	if agent, _ := s.agentRepo.FindByIDFull(hold.AgentID); agent != nil {
+		agentName = agent.AgentName
+	}
+	return s.toRiskHoldInfo(hold, agentName), nil
+}
+
+// AgentRiskHoldItem 代理商可见的冻结信息（不含内部原因与案件）
+type AgentRiskHoldItem struct {
+	HoldNo         string     `json:"hold_no"`
+	ScopeName      string     `json:"scope_name"`
+	WalletTypeName string     `json:"wallet_type_name"`
+	ChannelID      int64      `json:"channel_id"`
+	AmountYuan     float64    `json:"amount_yuan"`
+	Message        string     `json:"message"`
+	ExpireAt       *time.Time `json:"expire_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// AgentRiskHoldView 代理商风控冻结视图
+type AgentRiskHoldView struct {
+	HasHold        bool                 `json:"has_hold"`
+	SupportMessage string               `json:"support_message"`
+	Holds          []*AgentRiskHoldItem `json:"holds"`
+}
+
+// GetAgentHoldView 获取代理商当前生效的冻结（代理商端展示）
+func (s *WalletRiskHoldService) GetAgentHoldView(agentID int64) (*AgentRiskHoldView, error) {
+	holds, err := s.holdRepo.FindEffectiveByAgent(agentID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("查询风控状态失败: %w", err)
+	}
+
+	view := &AgentRiskHoldView{
+		HasHold: len(holds) > 0,
+		Holds:   make([]*AgentRiskHoldItem, 0, len(holds)),
+	}
+	if view.HasHold {
+		view.SupportMessage = riskHoldSupportMessage
+	}
+	for _, h := range holds {
+		item := &AgentRiskHoldItem{
+			HoldNo:     h.HoldNo,
+			ScopeName:  models.GetRiskHoldScopeName(h.Scope),
+			ChannelID:  h.ChannelID,
+			AmountYuan: float64(h.Amount) / 100,
+			Message:    s.agentMessageOf(h),
+			ExpireAt:   h.ExpireAt,
+			CreatedAt:  h.CreatedAt,
+		}
+		if h.WalletType > 0 {
+			item.WalletTypeName = models.GetWalletTypeName(h.WalletType)
+		}
+		view.Holds = append(view.Holds, item)
+	}
+	return view, nil
+}
+
+func (s *WalletRiskHoldService) agentMessageOf(h *models.WalletRiskHold) string {
+	if h.AgentMessage != "" {
+		return h.AgentMessage
+	}
+	return riskHoldSupportMessage
+}
+
+func (s *WalletRiskHoldService) describeHoldTarget(h *models.WalletRiskHold) string {
+	switch h.Scope {
+	case models.RiskHoldScopeAmount:
+		return fmt.Sprintf("%s中%.2f元", models.GetWalletTypeName(h.WalletType), float64(h.Amount)/100)
+	case models.RiskHoldScopeWallet:
+		return models.GetWalletTypeName(h.WalletType)
+	default:
+		return "全部钱包"
+	}
+}
+
+func (s *WalletRiskHoldService) notify(hold *models.WalletRiskHold, title, content string) {
+	if s.messageService == nil {
+		return
+	}
+	msg := &NotificationMessage{
+		AgentID:     hold.AgentID,
+		MessageType: models.MessageTypeRiskHold,
+		Title:       title,
+		Content:     content,
+		RelatedID:   hold.ID,
+		RelatedType: "wallet_risk_hold",
+	}
+	if err := s.messageService.SendNotification(msg); err != nil {
+		log.Printf("[WalletRiskHoldService] Send notification failed: %v", err)
+	}
+}
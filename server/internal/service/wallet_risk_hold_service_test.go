@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+)
+
+// TestCheckRiskHolds 测试风控冻结出款校验
+func TestCheckRiskHolds(t *testing.T) {
+	wallet := &repository.Wallet{ID: 10, Balance: 100000, FrozenAmount: 20000}
+
+	t.Run("无冻结", func(t *testing.T) {
+		assert.NoError(t, checkRiskHolds(nil, wallet, 80000))
+	})
+
+	t.Run("全部钱包冻结", func(t *testing.T) {
+		holds := []*models.WalletRiskHold{{Scope: models.RiskHoldScopeAgent}}
+		assert.Error(t, checkRiskHolds(holds, wallet, 100))
+	})
+
+	t.Run("冻结本钱包", func(t *testing.T) {
+		holds := []*models.WalletRiskHold{{Scope: models.RiskHoldScopeWallet, WalletID: 10}}
+		assert.Error(t, checkRiskHolds(holds, wallet, 100))
+	})
+
+	t.Run("冻结其他钱包不影响", func(t *testing.T) {
+		holds := []*models.WalletRiskHold{{Scope: models.RiskHoldScopeWallet, WalletID: 11}}
+		assert.NoError(t, checkRiskHolds(holds, wallet, 80000))
+	})
+
+	t.Run("指定金额冻结扣减可出款额度", func(t *testing.T) {
+		holds := []*models.WalletRiskHold{
+			{Scope: models.RiskHoldScopeAmount, WalletID: 10, Amount: 30000},
+			{Scope: models.RiskHoldScopeAmount, WalletID: 10, Amount: 10000},
+		}
+		// 可出款 = 100000 - 20000 - 40000 = 40000
+		assert.NoError(t, checkRiskHolds(holds, wallet, 40000))
+		assert.Error(t, checkRiskHolds(holds, wallet, 40001))
+	})
+}
+
+// TestWalletRiskHoldIsEffective 测试冻结生效判断
+func TestWalletRiskHoldIsEffective(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	assert.True(t, (&models.WalletRiskHold{Status: models.RiskHoldStatusActive}).IsEffective(now))
+	assert.True(t, (&models.WalletRiskHold{Status: models.RiskHoldStatusActive, ExpireAt: &future}).IsEffective(now))
+	assert.False(t, (&models.WalletRiskHold{Status: models.RiskHoldStatusActive, ExpireAt: &past}).IsEffective(now))
+	assert.False(t, (&models.WalletRiskHold{Status: models.RiskHoldStatusReleased}).IsEffective(now))
+}
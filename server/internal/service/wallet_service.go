@@ -18,6 +18,7 @@ type WalletService struct {
 	splitConfigRepo *repository.GormWalletSplitConfigRepository
 	thresholdRepo   *repository.GormPolicyWithdrawThresholdRepository
 	agentPolicyRepo *repository.GormAgentPolicyRepository
+	riskHoldService *WalletRiskHoldService
 }
 
 // NewWalletService 创建钱包服务
@@ -48,6 +49,11 @@ func (s *WalletService) SetAgentPolicyRepo(repo *repository.GormAgentPolicyRepos
 	s.agentPolicyRepo = repo
 }
 
+// SetRiskHoldService 设置风控冻结服务（可选注入）
+func (s *WalletService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
 // WalletInfoDetail 钱包详细信息
 type WalletInfoDetail struct {
 	ID                int64   `json:"id"`
@@ -62,6 +68,8 @@ type WalletInfoDetail struct {
 	TotalWithdraw     int64   `json:"total_withdraw"`      // 累计提现
 	WithdrawThreshold int64   `json:"withdraw_threshold"`  // 提现门槛
 	CanWithdraw       bool    `json:"can_withdraw"`        // 是否可提现
+	RiskHoldAmount    int64   `json:"risk_hold_amount"`    // 风控冻结金额
+	RiskHeld          bool    `json:"risk_held"`           // 是否整个钱包被风控冻结
 }
 
 // GetWalletList 获取钱包列表
@@ -71,6 +79,11 @@ func (s *WalletService) GetWalletList(agentID int64) ([]*WalletInfoDetail, error
 		return nil, fmt.Errorf("查询钱包失败: %w", err)
 	}
 
+	var holds []*models.WalletRiskHold
+	if s.riskHoldService != nil {
+		holds, _ = s.riskHoldService.GetEffectiveHolds(agentID)
+	}
+
 	list := make([]*WalletInfoDetail, 0, len(wallets))
 	for _, w := range wallets {
 		info := &WalletInfoDetail{
@@ -87,6 +100,10 @@ func (s *WalletService) GetWalletList(agentID int64) ([]*WalletInfoDetail, error
 			WithdrawThreshold: w.WithdrawThreshold,
 			CanWithdraw:       w.Balance-w.FrozenAmount >= w.WithdrawThreshold,
 		}
+		if len(holds) > 0 {
+			info.RiskHoldAmount, info.RiskHeld = walletHoldState(holds, w.ID)
+			info.CanWithdraw = !info.RiskHeld && w.Balance-w.FrozenAmount-info.RiskHoldAmount >= w.WithdrawThreshold
+		}
 		list = append(list, info)
 	}
 
@@ -233,6 +250,16 @@ func getLogTypeName(logType int16) string {
 		return "收到奖励"
 	case WalletLogTypeActivationReward:
 		return "激活奖励"
+	case WalletLogTypeTransferOut:
+		return "划转转出"
+	case WalletLogTypeTransferIn:
+		return "划转转入"
+	case WalletLogTypeRecallRefundOut:
+		return "回拨退货款转出"
+	case WalletLogTypeRecallRefundIn:
+		return "回拨退货款转入"
+	case WalletLogTypeRecallClawback:
+		return "回拨奖励追回"
 	default:
 		return "未知"
 	}
@@ -288,6 +315,13 @@ func (s *WalletService) Withdraw(req *WithdrawRequest) error {
 		return fmt.Errorf("提现金额不能低于%d元", wallet.WithdrawThreshold/100)
 	}
 
+	// 检查风控冻结
+	if s.riskHoldService != nil {
+		if err := s.riskHoldService.CheckWithdraw(req.AgentID, wallet, req.Amount); err != nil {
+			return err
+		}
+	}
+
 	// P0修复：奖励钱包提现需检查上级充值钱包余额
 	// 业务规则：奖励钱包的资金来源于上级代理商的充值钱包，提现需上级充值钱包有足够余额
 	if wallet.WalletType == models.WalletTypeReward {
@@ -613,6 +647,13 @@ func (s *WalletService) WithdrawWithChannel(req *WithdrawWithChannelRequest) err
 		return fmt.Errorf("提现金额不能低于%d元", threshold/100)
 	}
 
+	// 检查风控冻结
+	if s.riskHoldService != nil {
+		if err := s.riskHoldService.CheckWithdraw(req.AgentID, wallet, req.Amount); err != nil {
+			return err
+		}
+	}
+
 	// 奖励钱包提现需检查上级充值钱包余额
 	if wallet.WalletType == models.WalletTypeReward {
 		if err := s.checkParentChargingWalletBalance(req.AgentID, req.Amount); err != nil {
@@ -0,0 +1,728 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"xiangshoufu/internal/models"
+	"xiangshoufu/internal/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 划转流水类型
+const (
+	WalletLogTypeTransferOut int16 = 21 // 划转转出
+	WalletLogTypeTransferIn  int16 = 22 // 划转转入
+)
+
+// 划转默认配置
+const (
+	defaultTransferSingleMax   int64 = 5000000  // 默认单笔上限5万元
+	defaultTransferDailyMax    int64 = 20000000 // 默认每日上限20万元
+	defaultTransferDailyCount        = 20       // 默认每日20笔
+	transferConfirmExpireHours       = 24       // 待确认划转24小时后过期
+)
+
+// WalletTransferService 代理商钱包划转服务
+// 业务规则：只允许同一链路上的上下级之间划转（上级→任意下级 或 下级→任意上级）
+type WalletTransferService struct {
+	transferRepo    *repository.GormWalletTransferRepository
+	walletRepo      *repository.GormWalletRepository
+	walletLogRepo   *repository.GormWalletLogRepository
+	agentRepo       *repository.GormAgentRepository
+	hierarchyRepo   repository.HierarchyRepository
+	messageService  *MessageService
+	riskHoldService *WalletRiskHoldService
+}
+
+// NewWalletTransferService 创建钱包划转服务
+func NewWalletTransferService(
+	transferRepo *repository.GormWalletTransferRepository,
+	walletRepo *repository.GormWalletRepository,
+	walletLogRepo *repository.GormWalletLogRepository,
+	agentRepo *repository.GormAgentRepository,
+	hierarchyRepo repository.HierarchyRepository,
+) *WalletTransferService {
+	return &WalletTransferService{
+		transferRepo:  transferRepo,
+		walletRepo:    walletRepo,
+		walletLogRepo: walletLogRepo,
+		agentRepo:     agentRepo,
+		hierarchyRepo: hierarchyRepo,
+	}
+}
+
+// SetMessageService 设置消息服务（用于划转通知）
+func (s *WalletTransferService) SetMessageService(messageService *MessageService) {
+	s.messageService = messageService
+}
+
+// SetRiskHoldService 设置风控冻结服务（风控冻结期间禁止划转转出）
+func (s *WalletTransferService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
+// CreateTransferRequest 创建划转请求
+type CreateTransferRequest struct {
+	FromAgentID    int64  `json:"-"`
+	ToAgentID      int64  `json:"to_agent_id" binding:"required"`
+	FromWalletType int16  `json:"from_wallet_type" binding:"required"` // 1分润 2服务费 3奖励
+	FromChannelID  int64  `json:"from_channel_id"`
+	ToWalletType   int16  `json:"to_wallet_type" binding:"required"`
+	ToChannelID    int64  `json:"to_channel_id"`
+	Amount         int64  `json:"amount" binding:"required,min=1"` // 分
+	NeedConfirm    bool   `json:"need_confirm"`                    // 是否需要接收方确认
+	Remark         string `json:"remark"`
+	CreatedBy      int64  `json:"-"`
+}
+
+// CreateTransfer 创建划转
+// 无需确认时立即双边记账；需要确认时先冻结转出金额，等待接收方确认
+func (s *WalletTransferService) CreateTransfer(req *CreateTransferRequest) (*TransferInfo, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("划转金额必须大于0")
+	}
+	if req.FromAgentID == req.ToAgentID {
+		return nil, errors.New("不能向自己划转")
+	}
+	if !models.IsTransferableWalletType(req.FromWalletType) || !models.IsTransferableWalletType(req.ToWalletType) {
+		return nil, errors.New("该钱包类型不支持划转")
+	}
+
+	// 1. 校验上下级关系
+	fromAgent, err := s.agentRepo.FindByIDFull(req.FromAgentID)
+	if err != nil || fromAgent == nil {
+		return nil, errors.New("转出代理商不存在")
+	}
+	toAgent, err := s.agentRepo.FindByIDFull(req.ToAgentID)
+	if err != nil || toAgent == nil {
+		return nil, errors.New("接收方代理商不存在")
+	}
+	if toAgent.Status != 1 {
+		return nil, errors.New("接收方代理商状态异常")
+	}
+	direction, ok, err := s.resolveTransferDirection(fromAgent.ID, toAgent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询代理商层级失败: %w", err)
+	}
+	if !ok {
+		return nil, errors.New("只能在同一链路的上下级之间划转")
+	}
+
+	// 2. 校验限额（事务内持有转出钱包锁后复核）
+	limit := s.GetEffectiveLimit(req.FromAgentID)
+	dailyAmount, dailyCount, err := s.transferRepo.GetDailyOutStats(req.FromAgentID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("查询当日划转统计失败: %w", err)
+	}
+	if err := checkTransferLimit(limit, req.Amount, dailyAmount, dailyCount); err != nil {
+		return nil, err
+	}
+
+	// 3. 获取双方钱包
+	fromWallet, err := s.walletRepo.FindByAgentAndType(req.FromAgentID, req.FromChannelID, req.FromWalletType)
+	if err != nil || fromWallet == nil {
+		return nil, errors.New("转出钱包不存在")
+	}
+	toWallet, err := s.walletRepo.FindByAgentAndType(req.ToAgentID, req.ToChannelID, req.ToWalletType)
+	if err != nil || toWallet == nil {
+		return nil, errors.New("接收方钱包不存在")
+	}
+	if fromWallet.Balance-fromWallet.FrozenAmount < req.Amount {
+		return nil, fmt.Errorf("可用余额不足，当前可用余额%.2f元", float64(fromWallet.Balance-fromWallet.FrozenAmount)/100)
+	}
+	if s.riskHoldService != nil {
+		if err := s.riskHoldService.CheckWithdraw(req.FromAgentID, fromWallet, req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	needConfirm := req.NeedConfirm || limit.RequireConfirm
+	transfer := &models.WalletTransfer{
+		TransferNo:     fmt.Sprintf("TRF%s%06d", now.Format("20060102150405"), now.UnixNano()%1000000),
+		FromAgentID:    req.FromAgentID,
+		ToAgentID:      req.ToAgentID,
+		Direction:      direction,
+		FromWalletID:   fromWallet.ID,
+		FromWalletType: req.FromWalletType,
+		FromChannelID:  req.FromChannelID,
+		ToWalletID:     toWallet.ID,
+		ToWalletType:   req.ToWalletType,
+		ToChannelID:    req.ToChannelID,
+		Amount:         req.Amount,
+		NeedConfirm:    needConfirm,
+		Status:         models.TransferStatusPending,
+		Remark:         req.Remark,
+		CreatedBy:      req.CreatedBy,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if needConfirm {
+		expireAt := now.Add(transferConfirmExpireHours * time.Hour)
+		transfer.ExpireAt = &expireAt
+	}
+
+	// 4. 事务内创建记录并冻结/记账
+	err = s.transferRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		// 锁定双方钱包后复核当日限额，避免并发划转同时通过校验而突破限额
+		if _, err := lockTransferWallets(tx, fromWallet.ID, toWallet.ID); err != nil {
+			return err
+		}
+		dailyAmount, dailyCount, err := s.transferRepo.GetDailyOutStatsTx(tx, req.FromAgentID, now)
+		if err != nil {
+			return fmt.Errorf("查询当日划转统计失败: %w", err)
+		}
+		if err := checkTransferLimit(limit, req.Amount, dailyAmount, dailyCount); err != nil {
+			return err
+		}
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return fmt.Errorf("创建划转记录失败: %w", err)
+		}
+
+		if needConfirm {
+			result := tx.Model(&repository.Wallet{}).
+				Where("id = ? AND balance - frozen_amount >= ?", fromWallet.ID, req.Amount).
+				Updates(map[string]interface{}{
+					"frozen_amount": gorm.Expr("frozen_amount + ?", req.Amount),
+					"version":       gorm.Expr("version + 1"),
+				})
+			if result.Error != nil {
+				return fmt.Errorf("冻结转出金额失败: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("可用余额不足")
+			}
+			return nil
+		}
+
+		return s.settleTransfer(tx, transfer, fromAgent.AgentName, toAgent.AgentName, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[WalletTransferService] Created transfer: %s, from=%d, to=%d, amount=%d, need_confirm=%v",
+		transfer.TransferNo, req.FromAgentID, req.ToAgentID, req.Amount, needConfirm)
+
+	if needConfirm {
+		s.notify(transfer.ToAgentID, transfer, "待确认划转",
+			fmt.Sprintf("%s向您划转%.2f元，请在%d小时内确认", fromAgent.AgentName, float64(transfer.Amount)/100, transferConfirmExpireHours))
+	} else {
+		s.notifyCompleted(transfer, fromAgent.AgentName, toAgent.AgentName)
+	}
+
+	return s.toTransferInfo(transfer, fromAgent.AgentName, toAgent.AgentName), nil
+}
+
+// ConfirmTransfer 接收方确认划转
+func (s *WalletTransferService) ConfirmTransfer(transferID int64, agentID int64) (*TransferInfo, error) {
+	transfer, err := s.getPendingTransfer(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToAgentID != agentID {
+		return nil, errors.New("无权确认该划转")
+	}
+	if isTransferExpired(transfer, time.Now()) {
+		return nil, errors.New("划转已过期，无法确认")
+	}
+
+	fromName, toName := s.getAgentName(transfer.FromAgentID), s.getAgentName(transfer.ToAgentID)
+	err = s.transferRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		return s.settleTransfer(tx, transfer, fromName, toName, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[WalletTransferService] Confirmed transfer: %s by agent %d", transfer.TransferNo, agentID)
+	s.notifyCompleted(transfer, fromName, toName)
+
+	return s.toTransferInfo(transfer, fromName, toName), nil
+}
+
+// RejectTransfer 接收方拒绝划转
+func (s *WalletTransferService) RejectTransfer(transferID int64, agentID int64, reason string) error {
+	transfer, err := s.getPendingTransfer(transferID)
+	if err != nil {
+		return err
+	}
+	if transfer.ToAgentID != agentID {
+		return errors.New("无权拒绝该划转")
+	}
+
+	if err := s.closePendingTransfer(transfer, models.TransferStatusRejected, reason); err != nil {
+		return err
+	}
+
+	s.notify(transfer.FromAgentID, transfer, "划转被拒绝",
+		fmt.Sprintf("您发起的%.2f元划转已被%s拒绝，冻结金额已退回", float64(transfer.Amount)/100, s.getAgentName(transfer.ToAgentID)))
+	return nil
+}
+
+// CancelTransfer 发起方取消划转
+func (s *WalletTransferService) CancelTransfer(transferID int64, agentID int64, reason string) error {
+	transfer, err := s.getPendingTransfer(transferID)
+	if err != nil {
+		return err
+	}
+	if transfer.FromAgentID != agentID {
+		return errors.New("无权取消该划转")
+	}
+
+	if err := s.closePendingTransfer(transfer, models.TransferStatusCancelled, reason); err != nil {
+		return err
+	}
+
+	s.notify(transfer.ToAgentID, transfer, "划转已取消",
+		fmt.Sprintf("%s已取消向您划转的%.2f元", s.getAgentName(transfer.FromAgentID), float64(transfer.Amount)/100))
+	return nil
+}
+
+// ExpirePendingTransfers 处理过期未确认的划转（定时任务调用）
+func (s *WalletTransferService) ExpirePendingTransfers(batchSize int) (int, error) {
+	transfers, err := s.transferRepo.FindExpiredPending(time.Now(), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期划转失败: %w", err)
+	}
+
+	expired := 0
+	for _, transfer := range transfers {
+		if err := s.closePendingTransfer(transfer, models.TransferStatusExpired, "接收方超时未确认"); err != nil {
+			log.Printf("[WalletTransferService] Expire transfer %s failed: %v", transfer.TransferNo, err)
+			continue
+		}
+		expired++
+		s.notify(transfer.FromAgentID, transfer, "划转已过期",
+			fmt.Sprintf("您发起的%.2f元划转因对方超时未确认已自动退回", float64(transfer.Amount)/100))
+	}
+
+	return expired, nil
+}
+
+// getPendingTransfer 获取待确认划转
+func (s *WalletTransferService) getPendingTransfer(transferID int64) (*models.WalletTransfer, error) {
+	transfer, err := s.transferRepo.GetByID(transferID)
+	if err != nil {
+		return nil, fmt.Errorf("查询划转记录失败: %w", err)
+	}
+	if transfer == nil {
+		return nil, errors.New("划转记录不存在")
+	}
+	if transfer.Status != models.TransferStatusPending {
+		return nil, fmt.Errorf("划转状态为%s，无法操作", models.GetTransferStatusName(transfer.Status))
+	}
+	return transfer, nil
+}
+
+// closePendingTransfer 关闭待确认划转并解冻转出金额
+func (s *WalletTransferService) closePendingTransfer(transfer *models.WalletTransfer, status int16, reason string) error {
+	now := time.Now()
+	return s.transferRepo.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WalletTransfer{}).
+			Where("id = ? AND status = ?", transfer.ID, models.TransferStatusPending).
+			Updates(map[string]interface{}{
+				"status":        status,
+				"reject_reason": reason,
+				"updated_at":    now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("更新划转状态失败: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("划转状态已变更，请刷新后重试")
+		}
+
+		if err := tx.Model(&repository.Wallet{}).
+			Where("id = ? AND frozen_amount >= ?", transfer.FromWalletID, transfer.Amount).
+			Updates(map[string]interface{}{
+				"frozen_amount": gorm.Expr("frozen_amount - ?", transfer.Amount),
+				"version":       gorm.Expr("version + 1"),
+			}).Error; err != nil {
+			return fmt.Errorf("解冻转出金额失败: %w", err)
+		}
+
+		transfer.Status = status
+		transfer.RejectReason = reason
+		transfer.UpdatedAt = now
+		return nil
+	})
+}
+
+// settleTransfer 双边记账（必须在事务内调用）
+// fromFrozen=true 表示转出金额此前已冻结，需同时扣减冻结金额
+func (s *WalletTransferService) settleTransfer(tx *gorm.DB, transfer *models.WalletTransfer, fromName, toName string, fromFrozen bool) error {
+	wallets, err := lockTransferWallets(tx, transfer.FromWalletID, transfer.ToWalletID)
+	if err != nil {
+		return err
+	}
+	fromWallet, toWallet := wallets[transfer.FromWalletID], wallets[transfer.ToWalletID]
+
+	// 1. 扣减转出钱包
+	fromUpdates := map[string]interface{}{
+		"balance": gorm.Expr("balance - ?", transfer.Amount),
+		"version": gorm.Expr("version + 1"),
+	}
+	fromQuery := tx.Model(&repository.Wallet{}).Where("id = ?", fromWallet.ID)
+	if fromFrozen {
+		fromUpdates["frozen_amount"] = gorm.Expr("frozen_amount - ?", transfer.Amount)
+		fromQuery = fromQuery.Where("frozen_amount >= ? AND balance >= ?", transfer.Amount, transfer.Amount)
+	} else {
+		fromQuery = fromQuery.Where("balance - frozen_amount >= ?", transfer.Amount)
+	}
+	result := fromQuery.Updates(fromUpdates)
+	if result.Error != nil {
+		return fmt.Errorf("扣减转出钱包失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("转出钱包余额不足")
+	}
+
+	// 2. 增加转入钱包
+	if err := tx.Model(&repository.Wallet{}).
+		Where("id = ?", toWallet.ID).
+		Updates(map[string]interface{}{
+			"balance":      gorm.Expr("balance + ?", transfer.Amount),
+			"total_income": gorm.Expr("total_income + ?", transfer.Amount),
+			"version":      gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return fmt.Errorf("增加转入钱包失败: %w", err)
+	}
+
+	// 3. 双边流水
+	now := time.Now()
+	fromLog := &repository.WalletLog{
+		WalletID:      fromWallet.ID,
+		AgentID:       transfer.FromAgentID,
+		WalletType:    fromWallet.WalletType,
+		LogType:       WalletLogTypeTransferOut,
+		Amount:        -transfer.Amount,
+		BalanceBefore: fromWallet.Balance,
+		BalanceAfter:  fromWallet.Balance - transfer.Amount,
+		RefType:       "wallet_transfer",
+		RefID:         transfer.ID,
+		Remark:        fmt.Sprintf("划转给%s，金额%.2f元", toName, float64(transfer.Amount)/100),
+		CreatedAt:     now,
+	}
+	if err := tx.Create(fromLog).Error; err != nil {
+		return fmt.Errorf("创建转出流水失败: %w", err)
+	}
+	toLog := &repository.WalletLog{
+		WalletID:      toWallet.ID,
+		AgentID:       transfer.ToAgentID,
+		WalletType:    toWallet.WalletType,
+		LogType:       WalletLogTypeTransferIn,
+		Amount:        transfer.Amount,
+		BalanceBefore: toWallet.Balance,
+		BalanceAfter:  toWallet.Balance + transfer.Amount,
+		RefType:       "wallet_transfer",
+		RefID:         transfer.ID,
+		Remark:        fmt.Sprintf("收到%s划转，金额%.2f元", fromName, float64(transfer.Amount)/100),
+		CreatedAt:     now,
+	}
+	if err := tx.Create(toLog).Error; err != nil {
+		return fmt.Errorf("创建转入流水失败: %w", err)
+	}
+
+	// 4. 更新划转状态
+	updates := map[string]interface{}{
+		"status":             models.TransferStatusCompleted,
+		"completed_at":       now,
+		"from_wallet_log_id": fromLog.ID,
+		"to_wallet_log_id":   toLog.ID,
+		"updated_at":         now,
+	}
+	if fromFrozen {
+		updates["confirmed_at"] = now
+	}
+	statusQuery := tx.Model(&models.WalletTransfer{}).
+		Where("id = ? AND status = ?", transfer.ID, models.TransferStatusPending)
+	if fromFrozen {
+		// 确认时复核过期时间，避免与过期任务并发
+		statusQuery = statusQuery.Where("(expire_at IS NULL OR expire_at > ?)", now)
+	}
+	result = statusQuery.Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("更新划转状态失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("划转状态已变更，请刷新后重试")
+	}
+
+	transfer.Status = models.TransferStatusCompleted
+	transfer.CompletedAt = &now
+	if fromFrozen {
+		transfer.ConfirmedAt = &now
+	}
+	transfer.FromWalletLogID = &fromLog.ID
+	transfer.ToWalletLogID = &toLog.ID
+	return nil
+}
+
+// isTransferExpired 待确认划转是否已超过确认期限
+func isTransferExpired(transfer *models.WalletTransfer, now time.Time) bool {
+	return transfer.ExpireAt != nil && now.After(*transfer.ExpireAt)
+}
+
+// lockTransferWallets 按ID顺序锁定划转双方钱包（必须在事务内调用），避免并发互转死锁
+func lockTransferWallets(tx *gorm.DB, fromWalletID, toWalletID int64) (map[int64]*repository.Wallet, error) {
+	lockIDs := []int64{fromWalletID, toWalletID}
+	if lockIDs[0] > lockIDs[1] {
+		lockIDs[0], lockIDs[1] = lockIDs[1], lockIDs[0]
+	}
+	wallets := make(map[int64]*repository.Wallet, 2)
+	for _, id := range lockIDs {
+		var w repository.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&w, id).Error; err != nil {
+			return nil, fmt.Errorf("锁定钱包失败: %w", err)
+		}
+		wallets[id] = &w
+	}
+	return wallets, nil
+}
+
+// resolveTransferDirection 按层级关系表判断双方是否在同一链路，并返回划转方向
+func (s *WalletTransferService) resolveTransferDirection(fromID, toID int64) (int16, bool, error) {
+	isDown, err := s.hierarchyRepo.IsDescendant(fromID, toID)
+	if err != nil {
+		return 0, false, err
+	}
+	if isDown {
+		return models.TransferDirectionDown, true, nil
+	}
+	isUp, err := s.hierarchyRepo.IsDescendant(toID, fromID)
+	if err != nil {
+		return 0, false, err
+	}
+	if isUp {
+		return models.TransferDirectionUp, true, nil
+	}
+	return 0, false, nil
+}
+
+// checkTransferLimit 校验单笔及每日限额
+func checkTransferLimit(limit *models.WalletTransferLimit, amount, dailyAmount, dailyCount int64) error {
+	if limit.SingleMaxAmount > 0 && amount > limit.SingleMaxAmount {
+		return fmt.Errorf("单笔划转不能超过%.2f元", float64(limit.SingleMaxAmount)/100)
+	}
+	if limit.DailyMaxAmount > 0 && dailyAmount+amount > limit.DailyMaxAmount {
+		return fmt.Errorf("超出每日划转限额，今日剩余额度%.2f元", float64(limit.DailyMaxAmount-dailyAmount)/100)
+	}
+	if limit.DailyMaxCount > 0 && dailyCount >= int64(limit.DailyMaxCount) {
+		return fmt.Errorf("今日划转次数已达上限%d笔", limit.DailyMaxCount)
+	}
+	return nil
+}
+
+// ========== 限额配置 ==========
+
+// GetEffectiveLimit 获取代理商生效的限额配置（代理商配置 > 全局配置 > 系统默认）
+func (s *WalletTransferService) GetEffectiveLimit(agentID int64) *models.WalletTransferLimit {
+	if limit, err := s.transferRepo.GetLimit(agentID); err == nil && limit != nil {
+		return limit
+	}
+	if limit, err := s.transferRepo.GetLimit(0); err == nil && limit != nil {
+		return limit
+	}
+	return &models.WalletTransferLimit{
+		SingleMaxAmount: defaultTransferSingleMax,
+		DailyMaxAmount:  defaultTransferDailyMax,
+		DailyMaxCount:   defaultTransferDailyCount,
+	}
+}
+
+// UpdateTransferLimitRequest 更新限额请求
+type UpdateTransferLimitRequest struct {
+	AgentID         int64 `json:"agent_id"` // 0表示全局默认
+	SingleMaxAmount int64 `json:"single_max_amount" binding:"min=0"`
+	DailyMaxAmount  int64 `json:"daily_max_amount" binding:"min=0"`
+	DailyMaxCount   int   `json:"daily_max_count" binding:"min=0"`
+	RequireConfirm  bool  `json:"require_confirm"`
+	UpdatedBy       int64 `json:"-"`
+}
+
+// UpdateTransferLimit 更新限额配置（管理员）
+func (s *WalletTransferService) UpdateTransferLimit(req *UpdateTransferLimitRequest) (*models.WalletTransferLimit, error) {
+	if req.SingleMaxAmount > 0 && req.DailyMaxAmount > 0 && req.SingleMaxAmount > req.DailyMaxAmount {
+		return nil, errors.New("单笔上限不能大于每日上限")
+	}
+
+	limit, err := s.transferRepo.GetLimit(req.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("查询限额配置失败: %w", err)
+	}
+	if limit == nil {
+		limit = &models.WalletTransferLimit{AgentID: req.AgentID, CreatedAt: time.Now()}
+	}
+
+	limit.SingleMaxAmount = req.SingleMaxAmount
+	limit.DailyMaxAmount = req.DailyMaxAmount
+	limit.DailyMaxCount = req.DailyMaxCount
+	limit.RequireConfirm = req.RequireConfirm
+	limit.UpdatedBy = &req.UpdatedBy
+	limit.UpdatedAt = time.Now()
+
+	if err := s.transferRepo.SaveLimit(limit); err != nil {
+		return nil, fmt.Errorf("保存限额配置失败: %w", err)
+	}
+	return limit, nil
+}
+
+// ========== 查询 ==========
+
+// TransferInfo 划转信息
+type TransferInfo struct {
+	ID             int64      `json:"id"`
+	TransferNo     string     `json:"transfer_no"`
+	FromAgentID    int64      `json:"from_agent_id"`
+	FromAgentName  string     `json:"from_agent_name"`
+	ToAgentID      int64      `json:"to_agent_id"`
+	ToAgentName    string     `json:"to_agent_name"`
+	Direction      int16      `json:"direction"`
+	DirectionName  string     `json:"direction_name"`
+	FromWalletType int16      `json:"from_wallet_type"`
+	FromWalletName string     `json:"from_wallet_name"`
+	FromChannelID  int64      `json:"from_channel_id"`
+	ToWalletType   int16      `json:"to_wallet_type"`
+	ToWalletName   string     `json:"to_wallet_name"`
+	ToChannelID    int64      `json:"to_channel_id"`
+	Amount         int64      `json:"amount"`
+	AmountYuan     float64    `json:"amount_yuan"`
+	NeedConfirm    bool       `json:"need_confirm"`
+	Status         int16      `json:"status"`
+	StatusName     string     `json:"status_name"`
+	Remark         string     `json:"remark"`
+	RejectReason   string     `json:"reject_reason,omitempty"`
+	ExpireAt       *time.Time `json:"expire_at,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (s *WalletTransferService) toTransferInfo(t *models.WalletTransfer, fromName, toName string) *TransferInfo {
+	return &TransferInfo{
+		ID:             t.ID,
+		TransferNo:     t.TransferNo,
+		FromAgentID:    t.FromAgentID,
+		FromAgentName:  fromName,
+		ToAgentID:      t.ToAgentID,
+		ToAgentName:    toName,
+		Direction:      t.Direction,
+		DirectionName:  models.GetTransferDirectionName(t.Direction),
+		FromWalletType: t.FromWalletType,
+		FromWalletName: models.GetWalletTypeName(t.FromWalletType),
+		FromChannelID:  t.FromChannelID,
+		ToWalletType:   t.ToWalletType,
+		ToWalletName:   models.GetWalletTypeName(t.ToWalletType),
+		ToChannelID:    t.ToChannelID,
+		Amount:         t.Amount,
+		AmountYuan:     float64(t.Amount) / 100,
+		NeedConfirm:    t.NeedConfirm,
+		Status:         t.Status,
+		StatusName:     models.GetTransferStatusName(t.Status),
+		Remark:         t.Remark,
+		RejectReason:   t.RejectReason,
+		ExpireAt:       t.ExpireAt,
+		ConfirmedAt:    t.ConfirmedAt,
+		CompletedAt:    t.CompletedAt,
+		CreatedAt:      t.CreatedAt,
+	}
+}
+
+// TransferListParams 划转列表查询参数
+type TransferListParams struct {
+	AgentID   int64
+	Direction string // out/in/空
+	Status    *int16
+	StartTime *time.Time
+	EndTime   *time.Time
+	Page      int
+	PageSize  int
+}
+
+// GetTransferList 获取划转列表
+func (s *WalletTransferService) GetTransferList(params *TransferListParams) ([]*TransferInfo, int64, error) {
+	transfers, total, err := s.transferRepo.List(&repository.WalletTransferQueryParams{
+		AgentID:   params.AgentID,
+		Direction: params.Direction,
+		Status:    params.Status,
+		StartTime: params.StartTime,
+		EndTime:   params.EndTime,
+		Limit:     params.PageSize,
+		Offset:    (params.Page - 1) * params.PageSize,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询划转列表失败: %w", err)
+	}
+
+	agentNames := make(map[int64]string)
+	nameOf := func(id int64) string {
+		if name, ok := agentNames[id]; ok {
+			return name
+		}
+		name := s.getAgentName(id)
+		agentNames[id] = name
+		return name
+	}
+
+	list := make([]*TransferInfo, 0, len(transfers))
+	for _, t := range transfers {
+		list = append(list, s.toTransferInfo(t, nameOf(t.FromAgentID), nameOf(t.ToAgentID)))
+	}
+	return list, total, nil
+}
+
+// GetTransferDetail 获取划转详情（agentID>0 时校验归属）
+func (s *WalletTransferService) GetTransferDetail(transferID int64, agentID int64) (*TransferInfo, error) {
+	transfer, err := s.transferRepo.GetByID(transferID)
+	if err != nil {
+		return nil, fmt.Errorf("查询划转记录失败: %w", err)
+	}
+	if transfer == nil {
+		return nil, errors.New("划转记录不存在")
+	}
+	if agentID > 0 && transfer.FromAgentID != agentID && transfer.ToAgentID != agentID {
+		return nil, errors.New("无权查看该划转")
+	}
+	return s.toTransferInfo(transfer, s.getAgentName(transfer.FromAgentID), s.getAgentName(transfer.ToAgentID)), nil
+}
+
+// ========== 辅助方法 ==========
+
+func (s *WalletTransferService) getAgentName(agentID int64) string {
+	if agent, _ := s.agentRepo.FindByIDFull(agentID); agent != nil {
+		return agent.AgentName
+	}
+	return ""
+}
+
+// notifyCompleted 划转完成后通知双方
+func (s *WalletTransferService) notifyCompleted(transfer *models.WalletTransfer, fromName, toName string) {
+	s.notify(transfer.FromAgentID, transfer, "划转成功",
+		fmt.Sprintf("您已向%s划转%.2f元", toName, float64(transfer.Amount)/100))
+	s.notify(transfer.ToAgentID, transfer, "收到划转",
+		fmt.Sprintf("%s向您划转%.2f元，已入账%s", fromName, float64(transfer.Amount)/100, models.GetWalletTypeName(transfer.ToWalletType)))
+}
+
+func (s *WalletTransferService) notify(agentID int64, transfer *models.WalletTransfer, title, content string) {
+	if s.messageService == nil {
+		return
+	}
+	msg := &NotificationMessage{
+		AgentID:     agentID,
+		MessageType: models.MessageTypeWalletTransfer,
+		Title:       title,
+		Content:     content,
+		RelatedID:   transfer.ID,
+		RelatedType: "wallet_transfer",
+	}
+	if err := s.messageService.SendNotification(msg); err != nil {
+		log.Printf("[WalletTransferService] Send notification failed: %v", err)
+	}
+}
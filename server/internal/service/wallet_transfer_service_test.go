@@ -0,0 +1,86 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xiangshoufu/internal/models"
+)
+
+// TestResolveTransferDirection 测试划转方向判断
+func TestResolveTransferDirection(t *testing.T) {
+	agentRepo := NewMockAgentRepository()
+	agentRepo.AddAgent(1, "A1", 0, "/1/", 1)
+	agentRepo.AddAgent(5, "A5", 1, "/1/5/", 2)
+	agentRepo.AddAgent(6, "A6", 1, "/1/6/", 2)
+	agentRepo.AddAgent(12, "A12", 5, "/1/5/12/", 3)
+	agentRepo.AddAgent(2, "A2", 0, "/2/", 1)
+	agentRepo.AddAgent(15, "A15", 2, "/2/15/", 2)
+	agentRepo.AddAgent(20, "A20", 2, "/2/20/", 2)
+	service := &WalletTransferService{hierarchyRepo: &MockHierarchyRepository{agentRepo: agentRepo}}
+
+	tests := []struct {
+		name          string
+		fromID        int64
+		toID          int64
+		wantDirection int16
+		wantOK        bool
+	}{
+		{"上级转直属下级", 1, 5, models.TransferDirectionDown, true},
+		{"上级转跨级下级", 1, 12, models.TransferDirectionDown, true},
+		{"下级转直属上级", 12, 5, models.TransferDirectionUp, true},
+		{"下级转顶级上级", 12, 1, models.TransferDirectionUp, true},
+		{"同级代理商", 5, 6, 0, false},
+		{"不同链路", 12, 20, 0, false},
+		{"ID前缀不误判", 1, 15, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			direction, ok, err := service.resolveTransferDirection(tt.fromID, tt.toID)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantDirection, direction)
+		})
+	}
+}
+
+// TestCheckTransferLimit 测试划转限额校验
+func TestCheckTransferLimit(t *testing.T) {
+	limit := &models.WalletTransferLimit{
+		SingleMaxAmount: 100000, // 1000元
+		DailyMaxAmount:  300000, // 3000元
+		DailyMaxCount:   3,
+	}
+
+	t.Run("限额内", func(t *testing.T) {
+		assert.NoError(t, checkTransferLimit(limit, 100000, 100000, 1))
+	})
+
+	t.Run("超过单笔上限", func(t *testing.T) {
+		assert.Error(t, checkTransferLimit(limit, 100001, 0, 0))
+	})
+
+	t.Run("超过每日累计额度", func(t *testing.T) {
+		assert.Error(t, checkTransferLimit(limit, 50000, 260000, 1))
+	})
+
+	t.Run("超过每日笔数", func(t *testing.T) {
+		assert.Error(t, checkTransferLimit(limit, 100, 1000, 3))
+	})
+
+	t.Run("0表示不限制", func(t *testing.T) {
+		assert.NoError(t, checkTransferLimit(&models.WalletTransferLimit{}, 99999999, 99999999, 999))
+	})
+}
+
+// TestIsTransferExpired 测试待确认划转过期判断
+func TestIsTransferExpired(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Second), now.Add(time.Hour)
+
+	assert.False(t, isTransferExpired(&models.WalletTransfer{}, now), "无需确认的划转没有过期时间")
+	assert.False(t, isTransferExpired(&models.WalletTransfer{ExpireAt: &future}, now))
+	assert.True(t, isTransferExpired(&models.WalletTransfer{ExpireAt: &past}, now))
+}
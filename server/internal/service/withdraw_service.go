@@ -18,6 +18,7 @@ type WithdrawService struct {
 	walletLogRepo   *repository.GormWalletLogRepository
 	agentRepo       *repository.GormAgentRepository
 	taxChannelRepo  *repository.GormTaxChannelRepository
+	riskHoldService *WalletRiskHoldService
 }
 
 // NewWithdrawService 创建提现服务
@@ -37,6 +38,11 @@ func NewWithdrawService(
 	}
 }
 
+// SetRiskHoldService 设置风控冻结服务（可选注入）
+func (s *WithdrawService) SetRiskHoldService(riskHoldService *WalletRiskHoldService) {
+	s.riskHoldService = riskHoldService
+}
+
 // CreateWithdrawRequest 创建提现请求
 type CreateWithdrawRequest struct {
 	AgentID  int64 `json:"-"`
@@ -101,6 +107,13 @@ func (s *WithdrawService) CreateWithdraw(req *CreateWithdrawRequest) (*models.Wi
 		}
 	}
 
+	// 5.1 检查风控冻结
+	if s.riskHoldService != nil {
+		if err := s.riskHoldService.CheckWithdraw(req.AgentID, wallet, req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
 	// 6. 获取代理商结算卡信息
 	agent, err := s.agentRepo.FindByIDFull(req.AgentID)
 	if err != nil || agent == nil {
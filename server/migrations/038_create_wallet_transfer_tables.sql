@@ -0,0 +1,60 @@
+-- 038_create_wallet_transfer_tables.sql
+-- 代理商间钱包划转（上下级同一链路）
+
+-- 划转记录表
+CREATE TABLE IF NOT EXISTS wallet_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    transfer_no VARCHAR(50) NOT NULL UNIQUE,             -- 划转单号
+    from_agent_id BIGINT NOT NULL,                       -- 转出代理商ID
+    to_agent_id BIGINT NOT NULL,                         -- 转入代理商ID
+    direction SMALLINT NOT NULL,                         -- 方向: 1上级转下级 2下级转上级
+    from_wallet_id BIGINT NOT NULL,                      -- 转出钱包ID
+    from_wallet_type SMALLINT NOT NULL,                  -- 转出钱包类型
+    from_channel_id BIGINT NOT NULL DEFAULT 0,           -- 转出钱包通道ID
+    to_wallet_id BIGINT NOT NULL,                        -- 转入钱包ID
+    to_wallet_type SMALLINT NOT NULL,                    -- 转入钱包类型
+    to_channel_id BIGINT NOT NULL DEFAULT 0,             -- 转入钱包通道ID
+    amount BIGINT NOT NULL,                              -- 划转金额(分)
+    need_confirm BOOLEAN NOT NULL DEFAULT FALSE,         -- 是否需要接收方确认
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 状态: 1待确认 2已完成 3已拒绝 4已取消 5已过期
+    remark VARCHAR(500),                                 -- 备注
+    reject_reason VARCHAR(500),                          -- 拒绝/取消原因
+    expire_at TIMESTAMP,                                 -- 待确认过期时间
+    confirmed_at TIMESTAMP,                              -- 确认时间
+    completed_at TIMESTAMP,                              -- 完成时间
+    from_wallet_log_id BIGINT,                           -- 转出流水ID
+    to_wallet_log_id BIGINT,                             -- 转入流水ID
+    created_by BIGINT NOT NULL,                          -- 操作人用户ID
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_transfers_from_agent ON wallet_transfers(from_agent_id, created_at DESC);
+CREATE INDEX idx_wallet_transfers_to_agent ON wallet_transfers(to_agent_id, created_at DESC);
+CREATE INDEX idx_wallet_transfers_status ON wallet_transfers(status);
+CREATE INDEX idx_wallet_transfers_pending_expire ON wallet_transfers(expire_at) WHERE status = 1;
+
+COMMENT ON TABLE wallet_transfers IS '代理商钱包划转记录表';
+COMMENT ON COLUMN wallet_transfers.direction IS '方向: 1上级转下级 2下级转上级';
+COMMENT ON COLUMN wallet_transfers.status IS '状态: 1待确认 2已完成 3已拒绝 4已取消 5已过期';
+COMMENT ON COLUMN wallet_transfers.need_confirm IS '是否需要接收方确认，待确认期间转出金额处于冻结状态';
+
+-- 划转限额配置表（agent_id=0 为全局默认配置）
+CREATE TABLE IF NOT EXISTS wallet_transfer_limits (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL DEFAULT 0 UNIQUE,           -- 代理商ID，0表示全局默认
+    single_max_amount BIGINT NOT NULL DEFAULT 5000000,   -- 单笔上限(分)
+    daily_max_amount BIGINT NOT NULL DEFAULT 20000000,   -- 每日累计上限(分)
+    daily_max_count INT NOT NULL DEFAULT 20,             -- 每日笔数上限
+    require_confirm BOOLEAN NOT NULL DEFAULT FALSE,      -- 是否强制接收方确认
+    updated_by BIGINT,                                   -- 最后修改人
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE wallet_transfer_limits IS '钱包划转限额配置表';
+COMMENT ON COLUMN wallet_transfer_limits.agent_id IS '代理商ID，0表示全局默认配置';
+
+INSERT INTO wallet_transfer_limits (agent_id, single_max_amount, daily_max_amount, daily_max_count, require_confirm)
+VALUES (0, 5000000, 20000000, 20, FALSE)
+ON CONFLICT (agent_id) DO NOTHING;
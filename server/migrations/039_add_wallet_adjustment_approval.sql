@@ -0,0 +1,37 @@
+-- 039_add_wallet_adjustment_approval.sql
+-- 钱包调账双人复核（maker-checker）
+-- 超过审批金额的调账先进入待审批，由另一名管理员审批通过后才变更余额；支持对已生效调账进行冲正
+
+-- 1. 调账记录增加审批及冲正字段
+ALTER TABLE wallet_adjustments ADD COLUMN IF NOT EXISTS approver_name VARCHAR(50);   -- 审批人名称
+ALTER TABLE wallet_adjustments ADD COLUMN IF NOT EXISTS reversal_of_id BIGINT;       -- 冲正单对应的原调账ID
+ALTER TABLE wallet_adjustments ADD COLUMN IF NOT EXISTS reversed_by_id BIGINT;       -- 原调账被冲正时对应的冲正单ID
+ALTER TABLE wallet_adjustments ADD COLUMN IF NOT EXISTS reversed_at TIMESTAMP;       -- 冲正时间
+
+CREATE INDEX IF NOT EXISTS idx_wallet_adjustments_reversal_of ON wallet_adjustments(reversal_of_id) WHERE reversal_of_id IS NOT NULL;
+
+COMMENT ON COLUMN wallet_adjustments.status IS '状态: 1已生效 2待审批 3已驳回 4已冲正';
+COMMENT ON COLUMN wallet_adjustments.approver_name IS '审批人名称';
+COMMENT ON COLUMN wallet_adjustments.reversal_of_id IS '冲正单对应的原调账ID';
+COMMENT ON COLUMN wallet_adjustments.reversed_by_id IS '冲正该调账的冲正单ID';
+COMMENT ON COLUMN wallet_adjustments.reversed_at IS '冲正时间';
+
+-- 2. 调账审批金额配置表
+CREATE TABLE IF NOT EXISTS wallet_adjustment_approval_thresholds (
+    id               BIGSERIAL PRIMARY KEY,
+    wallet_type      SMALLINT NOT NULL DEFAULT 0,        -- 钱包类型: 0表示通用
+    threshold_amount BIGINT NOT NULL DEFAULT 100000,     -- 审批金额（分），调账金额绝对值达到该值需审批
+    updated_by       BIGINT,
+    created_at       TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(wallet_type)
+);
+
+COMMENT ON TABLE wallet_adjustment_approval_thresholds IS '钱包调账审批金额配置';
+COMMENT ON COLUMN wallet_adjustment_approval_thresholds.wallet_type IS '钱包类型: 0表示通用，其他值表示特定钱包类型（优先级高于通用）';
+COMMENT ON COLUMN wallet_adjustment_approval_thresholds.threshold_amount IS '审批金额（分），调账金额绝对值大于等于该值时需另一名管理员审批';
+
+-- 3. 初始化默认配置：通用审批金额1000元
+INSERT INTO wallet_adjustment_approval_thresholds (wallet_type, threshold_amount) VALUES
+    (0, 100000)
+ON CONFLICT (wallet_type) DO NOTHING;
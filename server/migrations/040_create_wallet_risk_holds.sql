@@ -0,0 +1,41 @@
+-- 040_create_wallet_risk_holds.sql
+-- 钱包风控冻结
+-- 疑似欺诈时由管理员对代理商钱包实施冻结：冻结指定金额、整个钱包或代理商全部钱包
+-- 冻结期间禁止提现、划转转出及使用沉淀款；可人工解除或到期自动解除
+
+CREATE TABLE IF NOT EXISTS wallet_risk_holds (
+    id BIGSERIAL PRIMARY KEY,
+    hold_no VARCHAR(50) NOT NULL UNIQUE,                 -- 冻结单号
+    agent_id BIGINT NOT NULL,                            -- 代理商ID
+    scope SMALLINT NOT NULL,                             -- 冻结范围: 1指定金额 2整个钱包 3全部钱包
+    wallet_id BIGINT NOT NULL DEFAULT 0,                 -- 钱包ID（范围3时为0）
+    wallet_type SMALLINT NOT NULL DEFAULT 0,             -- 钱包类型
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 通道ID
+    amount BIGINT NOT NULL DEFAULT 0,                    -- 冻结金额(分)，仅范围1有效
+    reason VARCHAR(500) NOT NULL,                        -- 冻结原因（内部）
+    agent_message VARCHAR(500),                          -- 展示给代理商的说明
+    case_ref VARCHAR(200),                               -- 关联风控案件编号/链接
+    expire_at TIMESTAMP,                                 -- 到期时间，空表示长期有效
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 状态: 1冻结中 2已解除 3已到期
+    created_by BIGINT NOT NULL,                          -- 操作人ID
+    created_by_name VARCHAR(50),                         -- 操作人名称
+    released_by BIGINT,                                  -- 解除人ID
+    released_by_name VARCHAR(50),                        -- 解除人名称
+    released_at TIMESTAMP,                               -- 解除时间
+    release_reason VARCHAR(500),                         -- 解除原因
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_risk_holds_agent_status ON wallet_risk_holds(agent_id, status);
+CREATE INDEX idx_wallet_risk_holds_expire ON wallet_risk_holds(expire_at) WHERE status = 1;
+CREATE INDEX idx_wallet_risk_holds_created_at ON wallet_risk_holds(created_at DESC);
+
+COMMENT ON TABLE wallet_risk_holds IS '钱包风控冻结记录表';
+COMMENT ON COLUMN wallet_risk_holds.scope IS '冻结范围: 1指定金额 2整个钱包 3全部钱包';
+COMMENT ON COLUMN wallet_risk_holds.amount IS '冻结金额(分)，仅指定金额冻结有效';
+COMMENT ON COLUMN wallet_risk_holds.reason IS '冻结原因（仅内部可见）';
+COMMENT ON COLUMN wallet_risk_holds.agent_message IS '展示给代理商的说明';
+COMMENT ON COLUMN wallet_risk_holds.case_ref IS '关联风控案件编号/链接';
+COMMENT ON COLUMN wallet_risk_holds.expire_at IS '到期时间，到期后自动解除，空表示需人工解除';
+COMMENT ON COLUMN wallet_risk_holds.status IS '状态: 1冻结中 2已解除 3已到期';
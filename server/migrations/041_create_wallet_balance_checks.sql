@@ -0,0 +1,52 @@
+-- 041_create_wallet_balance_checks.sql
+-- 钱包余额一致性检查记录
+-- 按钱包回放钱包流水、分润记录、代扣记录和调账记录，定位首个分歧条目并生成修正调账建议
+-- 建议由管理员采纳（走正常调账流程）或忽略；每次检查均保留历史
+
+CREATE TABLE IF NOT EXISTS wallet_balance_checks (
+    id BIGSERIAL PRIMARY KEY,
+    check_no VARCHAR(50) NOT NULL UNIQUE,                -- 检查单号
+    wallet_id BIGINT NOT NULL,                           -- 钱包ID
+    agent_id BIGINT NOT NULL,                            -- 代理商ID
+    wallet_type SMALLINT NOT NULL,                       -- 钱包类型
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 通道ID
+    trigger_type SMALLINT NOT NULL DEFAULT 1,            -- 触发方式: 1定时任务 2手动
+    baseline_at TIMESTAMP,                               -- 回放起点（上次已关闭检查的时间），空表示从头回放
+    opening_balance BIGINT NOT NULL DEFAULT 0,           -- 回放期初余额(分)
+    current_balance BIGINT NOT NULL DEFAULT 0,           -- 检查时钱包余额(分)
+    expected_balance BIGINT NOT NULL DEFAULT 0,          -- 回放得到的余额(分)
+    difference BIGINT NOT NULL DEFAULT 0,                -- 差异 = 回放余额 - 钱包余额(分)
+    entry_count INT NOT NULL DEFAULT 0,                  -- 回放条目数
+    divergence_kind VARCHAR(30),                         -- 分歧类型
+    divergent_source VARCHAR(30),                        -- 分歧条目来源
+    divergent_ref_id BIGINT,                             -- 分歧条目业务ID
+    divergent_log_id BIGINT,                             -- 分歧条目流水ID
+    divergent_at TIMESTAMP,                              -- 分歧条目发生时间
+    divergence_detail VARCHAR(500),                      -- 分歧说明
+    proposed_amount BIGINT NOT NULL DEFAULT 0,           -- 建议修正调账金额(分)，0表示无需调账
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 状态: 1一致 2待处理 3已采纳 4已忽略 5已失效
+    adjustment_id BIGINT,                                -- 采纳后生成的调账ID
+    handled_by BIGINT,                                   -- 处理人ID
+    handled_by_name VARCHAR(50),                         -- 处理人名称
+    handled_at TIMESTAMP,                                -- 处理时间
+    handle_remark VARCHAR(500),                          -- 处理说明
+    checked_at TIMESTAMP NOT NULL DEFAULT NOW(),         -- 检查时间
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_wallet_balance_checks_wallet ON wallet_balance_checks(wallet_id, checked_at DESC);
+CREATE INDEX idx_wallet_balance_checks_status ON wallet_balance_checks(status);
+CREATE INDEX idx_wallet_balance_checks_agent ON wallet_balance_checks(agent_id);
+
+-- 回放查询所需索引
+CREATE INDEX IF NOT EXISTS idx_wallet_logs_wallet_created ON wallet_logs(wallet_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_profit_records_agent_channel ON profit_records(agent_id, channel_id, wallet_type);
+
+COMMENT ON TABLE wallet_balance_checks IS '钱包余额一致性检查记录表';
+COMMENT ON COLUMN wallet_balance_checks.baseline_at IS '回放起点，取该钱包上一条已关闭检查的检查时间';
+COMMENT ON COLUMN wallet_balance_checks.opening_balance IS '回放期初余额，取上一条已关闭检查时的钱包余额';
+COMMENT ON COLUMN wallet_balance_checks.difference IS '回放余额 - 钱包余额(分)';
+COMMENT ON COLUMN wallet_balance_checks.divergence_kind IS '分歧类型: missing_log缺少流水 balance_chain流水余额断链 amount_mismatch流水金额不符 final_balance余额不符';
+COMMENT ON COLUMN wallet_balance_checks.proposed_amount IS '建议修正调账金额(分)，正数充入，负数扣减';
+COMMENT ON COLUMN wallet_balance_checks.status IS '状态: 1一致 2待处理 3已采纳 4已忽略 5已失效';
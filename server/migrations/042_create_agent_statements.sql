@@ -0,0 +1,53 @@
+-- 042_create_agent_statements.sql
+-- 代理商月度对账单
+-- 按代理商+通道每月生成，汇总期初余额、各类收入、代扣、调账、提现、税费及期末余额
+-- 代理商可确认或提出异议，管理员答复异议后可重新生成
+
+CREATE TABLE IF NOT EXISTS agent_statements (
+    id BIGSERIAL PRIMARY KEY,
+    statement_no VARCHAR(50) NOT NULL UNIQUE,            -- 对账单号
+    agent_id BIGINT NOT NULL,                            -- 代理商ID
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 通道ID
+    period VARCHAR(7) NOT NULL,                          -- 账期 YYYY-MM
+    period_start TIMESTAMP NOT NULL,                     -- 账期开始（含）
+    period_end TIMESTAMP NOT NULL,                       -- 账期结束（不含）
+
+    opening_balance BIGINT NOT NULL DEFAULT 0,           -- 期初余额(分)
+    trade_profit BIGINT NOT NULL DEFAULT 0,              -- 交易分润(分)
+    high_rate_profit BIGINT NOT NULL DEFAULT 0,          -- 高调分润(分)
+    d0_profit BIGINT NOT NULL DEFAULT 0,                 -- P+0分润(分)
+    profit_revoked BIGINT NOT NULL DEFAULT 0,            -- 退款撤销分润(分，负数)
+    deposit_cashback BIGINT NOT NULL DEFAULT 0,          -- 押金返现(分)
+    sim_cashback BIGINT NOT NULL DEFAULT 0,              -- 流量费返现(分)
+    activation_reward BIGINT NOT NULL DEFAULT 0,         -- 激活奖励(分)
+    deduction_out BIGINT NOT NULL DEFAULT 0,             -- 代扣扣款(分，负数)
+    deduction_in BIGINT NOT NULL DEFAULT 0,              -- 代扣收款(分)
+    adjustment BIGINT NOT NULL DEFAULT 0,                -- 调账(分)
+    withdrawal BIGINT NOT NULL DEFAULT 0,                -- 提现(分，负数)
+    tax_fee BIGINT NOT NULL DEFAULT 0,                   -- 提现税费(分，含在提现中)
+    other_amount BIGINT NOT NULL DEFAULT 0,              -- 其他变动(分)：划转、沉淀款、充值钱包等
+    closing_balance BIGINT NOT NULL DEFAULT 0,           -- 期末余额(分)
+
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 状态: 1待确认 2已确认 3有异议
+    confirmed_at TIMESTAMP,                              -- 确认时间
+    dispute_reason VARCHAR(500),                         -- 异议内容
+    disputed_at TIMESTAMP,                               -- 提出异议时间
+    dispute_reply VARCHAR(500),                          -- 管理员答复
+    replied_by BIGINT,                                   -- 答复人ID
+    replied_by_name VARCHAR(50),                         -- 答复人名称
+    replied_at TIMESTAMP,                                -- 答复时间
+    generated_at TIMESTAMP NOT NULL DEFAULT NOW(),       -- 生成时间
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (agent_id, channel_id, period)
+);
+
+CREATE INDEX idx_agent_statements_period ON agent_statements(period, status);
+CREATE INDEX idx_agent_statements_agent ON agent_statements(agent_id, period DESC);
+
+COMMENT ON TABLE agent_statements IS '代理商月度对账单';
+COMMENT ON COLUMN agent_statements.period IS '账期 YYYY-MM';
+COMMENT ON COLUMN agent_statements.opening_balance IS '期初余额，取上期对账单期末余额，无上期时按当前余额倒推';
+COMMENT ON COLUMN agent_statements.tax_fee IS '提现税费，已包含在提现金额中，仅展示';
+COMMENT ON COLUMN agent_statements.other_amount IS '其他变动：划转、沉淀款、充值钱包等';
+COMMENT ON COLUMN agent_statements.status IS '状态: 1待确认 2已确认 3有异议';
@@ -0,0 +1,45 @@
+-- 043_create_deduction_plan_changes.sql
+-- 代扣计划提前还款与重组
+-- 提前还款：被扣款方从所选钱包一次性结清剩余金额，释放冻结金额并关闭计划
+-- 重组：任一方提议新的剩余期数或每期金额，对方接收后生效（与需确认的代扣计划相同的接收/拒绝流程）
+-- 变更前后的扣款计划均以快照形式保留，用于审计
+
+CREATE TABLE IF NOT EXISTS deduction_plan_changes (
+    id BIGSERIAL PRIMARY KEY,
+    change_no VARCHAR(64) NOT NULL UNIQUE,               -- 变更编号
+    plan_id BIGINT NOT NULL REFERENCES deduction_plans(id),
+    plan_no VARCHAR(64),                                 -- 计划编号
+    change_type SMALLINT NOT NULL,                       -- 1提前还款 2重组
+    status SMALLINT NOT NULL DEFAULT 0,                  -- 0待确认 1已生效 2已拒绝 3已撤回 4已失效
+    proposer_id BIGINT NOT NULL,                         -- 发起方代理商ID
+    counterparty_id BIGINT,                              -- 确认方代理商ID
+
+    old_total_periods INT,                               -- 原总期数
+    old_period_amount BIGINT,                            -- 原每期金额（分）
+    old_remaining_amount BIGINT,                         -- 变更时剩余金额（分）
+    old_schedule JSONB,                                  -- 原未执行扣款计划快照
+
+    new_periods INT,                                     -- 新剩余期数（重组）
+    new_period_amount BIGINT,                            -- 新每期金额（分，重组）
+    new_schedule JSONB,                                  -- 新扣款计划快照
+    prepay_amount BIGINT DEFAULT 0,                      -- 提前还款金额（分）
+    released_frozen BIGINT DEFAULT 0,                    -- 提前还款释放的冻结金额（分）
+    wallet_details JSONB,                                -- 提前还款钱包扣款明细
+    record_id BIGINT,                                    -- 提前还款生成的代扣记录ID
+
+    reason VARCHAR(255),                                 -- 变更原因
+    reject_reason VARCHAR(255),                          -- 拒绝原因
+    decided_at TIMESTAMP,                                -- 确认/拒绝时间
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_deduction_plan_changes_plan ON deduction_plan_changes(plan_id, created_at DESC);
+CREATE INDEX idx_deduction_plan_changes_counterparty ON deduction_plan_changes(counterparty_id, status);
+-- 同一计划同时只允许一条待确认的重组提议
+CREATE UNIQUE INDEX uk_deduction_plan_changes_pending ON deduction_plan_changes(plan_id) WHERE status = 0;
+
+COMMENT ON TABLE deduction_plan_changes IS '代扣计划变更记录（提前还款/重组），保留变更前后扣款计划快照';
+COMMENT ON COLUMN deduction_plan_changes.change_type IS '变更类型：1提前还款 2重组';
+COMMENT ON COLUMN deduction_plan_changes.status IS '状态：0待确认 1已生效 2已拒绝 3已撤回 4已失效';
+COMMENT ON COLUMN deduction_records.status IS '状态：0待扣款 1成功 2部分成功 3失败 4已结清 5已重组';
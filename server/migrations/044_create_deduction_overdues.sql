@@ -0,0 +1,61 @@
+-- 044_create_deduction_overdues.sql
+-- 代扣逾期处理
+-- 逾期：已到期的代扣记录部分成功或失败（应扣金额 > 实扣金额），且计划仍为进行中/已暂停
+-- 每日任务：按逾期天数计收滞纳金、按节点通知双方、逾期N天上报上级（可自动代偿并通过代扣链追偿）、冻结被扣款方出款
+
+CREATE TABLE IF NOT EXISTS deduction_overdue_configs (
+    id BIGSERIAL PRIMARY KEY,
+    grace_days INT NOT NULL DEFAULT 0,                   -- 宽限天数
+    late_fee_daily_rate INT NOT NULL DEFAULT 0,          -- 滞纳金日费率（万分之），0不收取
+    late_fee_cap_rate INT NOT NULL DEFAULT 0,            -- 滞纳金上限（占逾期本金万分比），0不设上限
+    notify_days VARCHAR(100) DEFAULT '1,3,7,15,30',      -- 通知节点（逾期天数）
+    escalate_days INT NOT NULL DEFAULT 0,                -- 逾期N天上报上级，0不上报
+    auto_draw_parent BOOLEAN NOT NULL DEFAULT FALSE,     -- 上报时自动从上级代偿
+    parent_recover_periods INT NOT NULL DEFAULT 1,       -- 上级代偿后追偿期数
+    block_withdraw_days INT NOT NULL DEFAULT 0,          -- 逾期N天冻结出款，0不冻结
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS deduction_overdues (
+    id BIGSERIAL PRIMARY KEY,
+    overdue_no VARCHAR(64) NOT NULL UNIQUE,              -- 逾期编号
+    plan_id BIGINT NOT NULL REFERENCES deduction_plans(id),
+    plan_no VARCHAR(64),                                 -- 计划编号
+    deductor_id BIGINT NOT NULL,                         -- 扣款方（债权人）
+    deductee_id BIGINT NOT NULL,                         -- 被扣款方（债务人）
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 1逾期中 2已结清 3已关闭
+
+    overdue_amount BIGINT NOT NULL DEFAULT 0,            -- 当前逾期本金（分）
+    first_due_at TIMESTAMP,                              -- 最早未清偿应扣时间
+    days_past_due INT NOT NULL DEFAULT 0,                -- 逾期天数
+    collected_amount BIGINT NOT NULL DEFAULT 0,          -- 逾期后补扣本金（分）
+    late_fee BIGINT NOT NULL DEFAULT 0,                  -- 累计滞纳金（分）
+    late_fee_paid BIGINT NOT NULL DEFAULT 0,             -- 已收滞纳金（分）
+    late_fee_accrued_to DATE,                            -- 滞纳金已计至日期
+    last_notified_day INT NOT NULL DEFAULT 0,            -- 最近通知节点
+
+    escalated_to BIGINT DEFAULT 0,                       -- 上报上级代理商ID
+    escalated_at TIMESTAMP,                              -- 上报时间
+    drawn_amount BIGINT NOT NULL DEFAULT 0,              -- 上级代偿金额（分）
+    drawn_chain_id BIGINT,                               -- 上级追偿代扣链ID
+
+    risk_hold_id BIGINT,                                 -- 出款冻结ID
+    cleared_at TIMESTAMP,                                -- 结清/关闭时间
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_deduction_overdues_deductor ON deduction_overdues(deductor_id, status);
+CREATE INDEX idx_deduction_overdues_deductee ON deduction_overdues(deductee_id, status);
+-- 同一计划同时只允许一条逾期中的记录
+CREATE UNIQUE INDEX uk_deduction_overdues_open ON deduction_overdues(plan_id) WHERE status = 1;
+
+INSERT INTO deduction_overdue_configs (grace_days, late_fee_daily_rate, late_fee_cap_rate, notify_days, escalate_days, auto_draw_parent, parent_recover_periods, block_withdraw_days)
+SELECT 0, 0, 0, '1,3,7,15,30', 0, FALSE, 1, 0
+WHERE NOT EXISTS (SELECT 1 FROM deduction_overdue_configs);
+
+COMMENT ON TABLE deduction_overdue_configs IS '代扣逾期处理配置（全局单行）';
+COMMENT ON TABLE deduction_overdues IS '代扣逾期记录，每个计划同时只有一条逾期中的记录';
+COMMENT ON COLUMN deduction_overdues.status IS '状态：1逾期中 2已结清 3已关闭';
@@ -0,0 +1,39 @@
+-- 045_goods_deduction_migration.sql
+-- 货款代扣（goods_deductions）并入统一代扣计划（deduction_plans）
+-- 迁移由后台工具逐笔执行：先试运行生成核对报告，再正式迁移，迁移后可随时对账
+-- 历史扣款明细逐条转换为成功的代扣记录（legacy_detail_id 指向原明细），剩余金额生成一期待扣记录
+-- 031_migrate_goods_deductions.sql 已插入的同编号计划在无扣款记录时直接接管，否则列为冲突人工处理
+
+ALTER TABLE goods_deductions ADD COLUMN IF NOT EXISTS migrated_plan_id BIGINT;   -- 迁移后的代扣计划ID
+ALTER TABLE goods_deductions ADD COLUMN IF NOT EXISTS migrated_at TIMESTAMP;     -- 迁移时间
+CREATE INDEX IF NOT EXISTS idx_goods_deductions_migrated_plan ON goods_deductions(migrated_plan_id);
+
+-- 由货款代扣明细转换的代扣记录，钱包流水仍为原 goods_deduction_detail，钱包一致性核对据此跳过
+ALTER TABLE deduction_records ADD COLUMN IF NOT EXISTS legacy_detail_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS uk_deduction_records_legacy_detail ON deduction_records(legacy_detail_id) WHERE legacy_detail_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS goods_deduction_migration_runs (
+    id BIGSERIAL PRIMARY KEY,
+    run_no VARCHAR(64) NOT NULL UNIQUE,                  -- 批次编号
+    dry_run BOOLEAN NOT NULL DEFAULT TRUE,               -- 是否试运行（不写入）
+    total_count INT DEFAULT 0,                           -- 待迁移货款代扣数
+    in_flight_count INT DEFAULT 0,                       -- 其中待接收/进行中数
+    migrated_count INT DEFAULT 0,                        -- 新建计划数
+    adopted_count INT DEFAULT 0,                         -- 接管031脚本计划数
+    mismatch_count INT DEFAULT 0,                        -- 数据不一致跳过数
+    conflict_count INT DEFAULT 0,                        -- 编号冲突跳过数
+    failed_count INT DEFAULT 0,                          -- 执行失败数
+    remaining_amount BIGINT DEFAULT 0,                   -- 迁移的剩余待扣总额（分）
+    frozen_amount BIGINT DEFAULT 0,                      -- 迁移后冻结的现有余额（分）
+    report JSONB,                                        -- 逐笔核对结果
+    operator_id BIGINT,
+    operator_name VARCHAR(64),
+    created_at TIMESTAMP DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_goods_deduction_migration_runs_created ON goods_deduction_migration_runs(created_at DESC);
+
+COMMENT ON TABLE goods_deduction_migration_runs IS '货款代扣迁移批次（含试运行），report保存逐笔核对结果';
+COMMENT ON COLUMN goods_deductions.migrated_plan_id IS '迁移后的统一代扣计划ID，非空即由代扣计划接管';
+COMMENT ON COLUMN deduction_records.legacy_detail_id IS '由货款代扣明细转换时的原明细ID';
@@ -0,0 +1,32 @@
+-- 046_create_terminal_status_history.sql
+-- 终端生命周期状态流转记录
+-- 终端状态只允许由状态机按事件流转：入库、下发确认、绑定回调、激活、解绑回调、回拨确认
+-- 每次流转记录操作人与来源单据，用于按SN查看终端时间线
+
+CREATE TABLE IF NOT EXISTS terminal_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    terminal_id BIGINT NOT NULL,
+    terminal_sn VARCHAR(50) NOT NULL,                    -- 终端SN
+    event VARCHAR(32) NOT NULL,                          -- 事件：import/distribute_confirm/bind/activate/unbind/recall_confirm
+    from_status SMALLINT NOT NULL DEFAULT 0,             -- 流转前状态（入库为0）
+    to_status SMALLINT NOT NULL,                         -- 流转后状态
+    from_owner_id BIGINT NOT NULL DEFAULT 0,             -- 流转前所属代理商
+    to_owner_id BIGINT NOT NULL DEFAULT 0,               -- 流转后所属代理商
+    merchant_no VARCHAR(64),                             -- 绑定/解绑的商户号
+
+    actor_type VARCHAR(20) NOT NULL,                     -- 操作方：user/channel/system
+    actor_id BIGINT NOT NULL DEFAULT 0,                  -- 操作人ID
+    actor_name VARCHAR(50),                              -- 操作人名称/通道编码
+
+    source_type VARCHAR(32),                             -- 来源单据：terminal_import/terminal_distribute/terminal_recall/callback
+    source_id BIGINT NOT NULL DEFAULT 0,                 -- 来源单据ID
+    source_no VARCHAR(64),                               -- 来源单据编号
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_terminal_status_history_sn ON terminal_status_history(terminal_sn, created_at);
+CREATE INDEX IF NOT EXISTS idx_terminal_status_history_terminal ON terminal_status_history(terminal_id);
+CREATE INDEX IF NOT EXISTS idx_terminal_status_history_source ON terminal_status_history(source_type, source_id);
+
+COMMENT ON TABLE terminal_status_history IS '终端生命周期状态流转记录';
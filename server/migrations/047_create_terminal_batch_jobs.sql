@@ -0,0 +1,44 @@
+-- 047_create_terminal_batch_jobs.sql
+-- 终端批量入库/下发/回拨任务（XLSX/CSV上传）
+-- 上传时解析文件并展开SN号段，逐行校验后执行；行数较多时转为后台任务，完成后可下载逐行错误报告
+
+CREATE TABLE IF NOT EXISTS terminal_batch_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    job_no VARCHAR(64) NOT NULL UNIQUE,                  -- 任务编号
+    job_type VARCHAR(20) NOT NULL,                       -- import/distribute/recall
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 1待处理 2处理中 3已完成 4失败
+    file_name VARCHAR(255),                              -- 上传文件名
+
+    agent_id BIGINT NOT NULL,                            -- 操作代理商
+    created_by BIGINT NOT NULL DEFAULT 0,                -- 操作人
+    source SMALLINT NOT NULL DEFAULT 2,                  -- 1:APP 2:PC
+
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 通道（入库必填，下发/回拨为筛选条件）
+    channel_code VARCHAR(32),
+    brand_code VARCHAR(32),
+    model_code VARCHAR(32),
+    to_agent_id BIGINT NOT NULL DEFAULT 0,               -- 下发/回拨接收方
+    goods_price BIGINT NOT NULL DEFAULT 0,               -- 下发货款（分）
+    deduction_type SMALLINT NOT NULL DEFAULT 0,          -- 下发代扣类型
+    deduction_periods INT NOT NULL DEFAULT 0,            -- 分期期数
+    deduction_source SMALLINT NOT NULL DEFAULT 0,        -- 货款代扣来源
+    remark VARCHAR(255),
+
+    rows JSONB,                                          -- 展开后的待处理行
+    total_count INT NOT NULL DEFAULT 0,                  -- 总行数（按SN计）
+    success_count INT NOT NULL DEFAULT 0,
+    failed_count INT NOT NULL DEFAULT 0,
+    errors JSONB,                                        -- 逐行错误
+    result_nos TEXT,                                     -- 生成的入库批次号（逗号分隔）
+    error_message VARCHAR(500),                          -- 任务级错误
+
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_terminal_batch_jobs_agent ON terminal_batch_jobs(agent_id, created_at);
+CREATE INDEX idx_terminal_batch_jobs_status ON terminal_batch_jobs(status);
+
+COMMENT ON TABLE terminal_batch_jobs IS '终端批量入库/下发/回拨任务';
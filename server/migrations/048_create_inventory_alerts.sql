@@ -0,0 +1,42 @@
+-- 048_create_inventory_alerts.sql
+-- 终端库存账龄与呆滞库存预警
+-- 库存账龄按终端到达当前持有代理商的时间计算（最近一次确认下发/回拨，无则取入库时间）
+-- 每日扫描各代理商呆滞库存（库龄超过N天的未绑定终端），超过阈值时通知代理商及其上级，并可推送总部告警
+
+CREATE TABLE IF NOT EXISTS inventory_alert_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,               -- 是否开启呆滞库存预警
+    stale_days INT NOT NULL DEFAULT 90,                  -- 库龄超过N天视为呆滞
+    stale_count_threshold INT NOT NULL DEFAULT 20,       -- 呆滞台数达到N台时预警
+    remind_interval_days INT NOT NULL DEFAULT 7,         -- 同一代理商重复预警间隔天数
+    notify_parent BOOLEAN NOT NULL DEFAULT TRUE,         -- 是否同时通知直属上级
+    alert_hq BOOLEAN NOT NULL DEFAULT FALSE,             -- 是否推送总部告警通道
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS inventory_alerts (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL,                            -- 预警代理商
+    alert_date DATE NOT NULL,                            -- 预警日期
+    stale_days INT NOT NULL,                             -- 预警时的呆滞天数
+    stale_count INT NOT NULL,                            -- 呆滞台数
+    stock_count INT NOT NULL DEFAULT 0,                  -- 库存总台数
+    threshold INT NOT NULL,                              -- 预警阈值
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (agent_id, alert_date)
+);
+
+CREATE INDEX idx_inventory_alerts_date ON inventory_alerts(alert_date);
+
+-- 库龄计算按SN+接收方查找最近确认的下发/回拨记录
+CREATE INDEX IF NOT EXISTS idx_terminal_distributes_sn_to ON terminal_distributes(terminal_sn, to_agent_id);
+CREATE INDEX IF NOT EXISTS idx_terminal_recalls_sn_to ON terminal_recalls(terminal_sn, to_agent_id);
+
+INSERT INTO inventory_alert_configs (enabled, stale_days, stale_count_threshold, remind_interval_days, notify_parent, alert_hq)
+SELECT TRUE, 90, 20, 7, TRUE, FALSE
+WHERE NOT EXISTS (SELECT 1 FROM inventory_alert_configs);
+
+COMMENT ON TABLE inventory_alert_configs IS '呆滞库存预警配置（全局单行）';
+COMMENT ON TABLE inventory_alerts IS '呆滞库存预警记录，用于预警去重与追溯';
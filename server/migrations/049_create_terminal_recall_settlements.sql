@@ -0,0 +1,53 @@
+-- 049_create_terminal_recall_settlements.sql
+-- 终端回拨结算：回拨确认后按规则冲减下发时产生的货款代扣计划、退还已付货款，并可追回已发放的激活奖励/押金返现
+-- 结算汇总写入回拨记录，逐项明细写入 terminal_recall_settlement_items
+
+CREATE TABLE IF NOT EXISTS terminal_recall_settlement_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,               -- 是否开启回拨结算
+    reduce_outstanding BOOLEAN NOT NULL DEFAULT TRUE,    -- 冲减未扣货款（代扣计划剩余金额）
+    refund_paid BOOLEAN NOT NULL DEFAULT TRUE,           -- 退还已扣货款给回拨方
+    refund_rate INT NOT NULL DEFAULT 100,                -- 已扣货款退还比例（%）
+    clawback_activation BOOLEAN NOT NULL DEFAULT FALSE,  -- 追回激活奖励
+    clawback_deposit BOOLEAN NOT NULL DEFAULT FALSE,     -- 追回押金返现
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO terminal_recall_settlement_configs (enabled, reduce_outstanding, refund_paid, refund_rate, clawback_activation, clawback_deposit)
+SELECT TRUE, TRUE, TRUE, 100, FALSE, FALSE
+WHERE NOT EXISTS (SELECT 1 FROM terminal_recall_settlement_configs);
+
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS settlement_status SMALLINT NOT NULL DEFAULT 0;       -- 0未结算 1已结算 2部分完成 3无需结算
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS plan_reduced_amount BIGINT NOT NULL DEFAULT 0;      -- 冲减未扣货款（分）
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS goods_refund_amount BIGINT NOT NULL DEFAULT 0;      -- 退还已扣货款（分）
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS reward_clawback_amount BIGINT NOT NULL DEFAULT 0;   -- 追回奖励/返现（分）
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS settlement_summary VARCHAR(500);                    -- 结算摘要
+ALTER TABLE terminal_recalls ADD COLUMN IF NOT EXISTS settled_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS terminal_recall_settlement_items (
+    id BIGSERIAL PRIMARY KEY,
+    recall_id BIGINT NOT NULL,                           -- 回拨记录
+    recall_no VARCHAR(64) NOT NULL,
+    terminal_sn VARCHAR(50) NOT NULL,
+    item_type VARCHAR(32) NOT NULL,                      -- plan_reduce/goods_refund/activation_clawback/deposit_clawback
+    ref_type VARCHAR(32),                                -- 关联对象：deduction_plan/activation_reward/deposit_cashback
+    ref_id BIGINT NOT NULL DEFAULT 0,
+    agent_id BIGINT NOT NULL DEFAULT 0,                  -- 出资方（退款付款方/被追回方/被扣款方）
+    counterparty_id BIGINT NOT NULL DEFAULT 0,           -- 收款方（退款收款方，追回为0）
+    amount BIGINT NOT NULL DEFAULT 0,                    -- 应处理金额（分）
+    settled_amount BIGINT NOT NULL DEFAULT 0,            -- 实际处理金额（分）
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 1已完成 2部分完成 3已跳过 4失败
+    refund_plan_id BIGINT,                               -- 余额不足时生成的退款代扣计划
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_terminal_recall_settlement_items_recall ON terminal_recall_settlement_items(recall_id);
+CREATE INDEX idx_terminal_recall_settlement_items_ref ON terminal_recall_settlement_items(ref_type, ref_id);
+
+COMMENT ON TABLE terminal_recall_settlement_configs IS '终端回拨结算规则（全局单行）';
+COMMENT ON TABLE terminal_recall_settlement_items IS '终端回拨结算明细';
+COMMENT ON COLUMN activation_reward_records.wallet_status IS '0待入账 1已入账 2已追回';
+COMMENT ON COLUMN deposit_cashback_records.wallet_status IS '0待入账 1已入账 2已追回';
@@ -0,0 +1,36 @@
+-- 050_create_reward_progress_rules.sql
+-- 终端奖励进度自动化：终端绑定/激活时按代理商、通道匹配奖励政策模版自动开启奖励进度，解绑/回拨时终止
+-- 模版匹配：终端所属代理商 → 逐级上级 → 平台默认（agent_id=0），同一代理商下指定通道优先于全部通道（channel_id=0），再按优先级
+
+CREATE TABLE IF NOT EXISTS reward_progress_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,                    -- 是否自动开启/终止奖励进度
+    start_event VARCHAR(20) NOT NULL DEFAULT 'bind',          -- 开启时机：bind绑定 activate激活
+    rebind_mode VARCHAR(20) NOT NULL DEFAULT 'restart',       -- 换绑规则：restart解绑终止、重新绑定重新开始 continue解绑不终止、换绑沿用原进度 once解绑终止、终端仅享受一次
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO reward_progress_configs (enabled, start_event, rebind_mode)
+SELECT TRUE, 'bind', 'restart'
+WHERE NOT EXISTS (SELECT 1 FROM reward_progress_configs);
+
+CREATE TABLE IF NOT EXISTS reward_template_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL DEFAULT 0,                       -- 代理商（含其下级），0为平台默认
+    channel_id BIGINT NOT NULL DEFAULT 0,                     -- 通道，0为全部通道
+    template_id BIGINT NOT NULL,                              -- 奖励政策模版
+    priority INT NOT NULL DEFAULT 0,                          -- 同一代理商、通道下多条时优先级高者生效
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    remark VARCHAR(255),
+    created_by BIGINT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_reward_template_assignments_agent ON reward_template_assignments(agent_id, channel_id);
+CREATE INDEX idx_reward_template_assignments_template ON reward_template_assignments(template_id);
+
+COMMENT ON TABLE reward_progress_configs IS '终端奖励进度自动化规则（全局单行）';
+COMMENT ON TABLE reward_template_assignments IS '奖励政策模版适用范围（代理商、通道）';
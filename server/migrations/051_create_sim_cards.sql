@@ -0,0 +1,71 @@
+-- 051_create_sim_cards.sql
+-- 流量卡（ICCID）管理：流量卡库存、与终端的装卡/换卡记录、续费到期预测及提醒
+-- 流量费按扣费时装在终端上的流量卡归属，返现档次按流量卡的缴费次数计算，换卡后不再沿用终端的缴费次数
+
+CREATE TABLE IF NOT EXISTS sim_cards (
+    id BIGSERIAL PRIMARY KEY,
+    iccid VARCHAR(22) NOT NULL UNIQUE,                   -- 流量卡ICCID
+    msisdn VARCHAR(20),                                  -- 卡号
+    carrier VARCHAR(10) NOT NULL DEFAULT 'other',        -- 运营商：cmcc移动 cucc联通 ctcc电信 other其他
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 所属通道
+    owner_agent_id BIGINT NOT NULL DEFAULT 0,            -- 库存持有代理商，0为平台
+    terminal_id BIGINT,                                  -- 当前装卡终端
+    terminal_sn VARCHAR(50) NOT NULL DEFAULT '',
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 1库存 2已装机 3已停用
+    activated_at TIMESTAMP,                              -- 开卡/激活日期
+    expire_at TIMESTAMP,                                 -- 卡有效期
+    renewal_cycle_days INT NOT NULL DEFAULT 0,           -- 续费周期（天），0使用全局配置
+    fee_count INT NOT NULL DEFAULT 0,                    -- 已缴流量费次数
+    last_fee_at TIMESTAMP,                               -- 最近缴费时间
+    next_renewal_at TIMESTAMP,                           -- 预计下次续费时间
+    reminded_renewal_at TIMESTAMP,                       -- 已提醒的续费时间（与next_renewal_at相同表示本期已提醒）
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_sim_cards_terminal ON sim_cards(terminal_sn);
+CREATE INDEX idx_sim_cards_owner ON sim_cards(owner_agent_id);
+CREATE INDEX idx_sim_cards_renewal ON sim_cards(status, next_renewal_at);
+
+CREATE TABLE IF NOT EXISTS sim_card_bindings (
+    id BIGSERIAL PRIMARY KEY,
+    sim_card_id BIGINT NOT NULL,
+    iccid VARCHAR(22) NOT NULL,
+    terminal_id BIGINT NOT NULL,
+    terminal_sn VARCHAR(50) NOT NULL,
+    bound_at TIMESTAMP NOT NULL,                         -- 装卡时间
+    unbound_at TIMESTAMP,                                -- 拆卡时间，NULL为当前装卡
+    source VARCHAR(20) NOT NULL,                         -- manual手动 swap换卡 callback流量费回调登记
+    operator_id BIGINT NOT NULL DEFAULT 0,
+    operator_name VARCHAR(50),
+    remark VARCHAR(255)
+);
+
+CREATE INDEX idx_sim_card_bindings_sim ON sim_card_bindings(sim_card_id, bound_at);
+CREATE INDEX idx_sim_card_bindings_terminal ON sim_card_bindings(terminal_sn, bound_at);
+
+CREATE TABLE IF NOT EXISTS sim_card_configs (
+    id BIGSERIAL PRIMARY KEY,
+    first_fee_days INT NOT NULL DEFAULT 180,             -- 首次扣费距激活天数
+    renewal_cycle_days INT NOT NULL DEFAULT 365,         -- 续费周期（天）
+    remind_enabled BOOLEAN NOT NULL DEFAULT TRUE,        -- 是否提醒代理商
+    remind_days_before INT NOT NULL DEFAULT 15,          -- 提前提醒天数
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO sim_card_configs (first_fee_days, renewal_cycle_days, remind_enabled, remind_days_before)
+SELECT 180, 365, TRUE, 15
+WHERE NOT EXISTS (SELECT 1 FROM sim_card_configs);
+
+ALTER TABLE device_fees ADD COLUMN IF NOT EXISTS iccid VARCHAR(22);                   -- 通道回传或按装卡记录归属的ICCID
+ALTER TABLE device_fees ADD COLUMN IF NOT EXISTS sim_card_id BIGINT;
+ALTER TABLE device_fees ADD COLUMN IF NOT EXISTS sim_fee_count INT NOT NULL DEFAULT 0; -- 该卡第几次缴费
+ALTER TABLE sim_cashback_records ADD COLUMN IF NOT EXISTS sim_card_id BIGINT;
+ALTER TABLE sim_cashback_records ADD COLUMN IF NOT EXISTS iccid VARCHAR(22);
+
+COMMENT ON TABLE sim_cards IS '流量卡';
+COMMENT ON TABLE sim_card_bindings IS '流量卡装卡/换卡记录';
+COMMENT ON TABLE sim_card_configs IS '流量卡续费预测及提醒配置（全局单行）';
@@ -0,0 +1,38 @@
+-- 052_create_merchant_class_rules.sql
+-- 商户分类规则：管理员按通道/一级代理商配置分类规则集，规则按顺序匹配（首条满足的规则生效），条件基于30/60/90天交易指标
+-- 未配置规则集的商户沿用内置默认规则（60天无交易流失、30天无交易预警、30天交易额≥5万优质、≥3万中等、其余普通）
+
+CREATE TABLE IF NOT EXISTS merchant_class_rule_sets (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    channel_id BIGINT NOT NULL DEFAULT 0,                -- 适用通道，0为全部通道
+    agent_id BIGINT NOT NULL DEFAULT 0,                  -- 适用一级代理商（含其下级的商户），0为全部代理商
+    rules JSONB NOT NULL DEFAULT '[]',                   -- 有序规则：[{code,label,conditions:[{metric,op,value}]}]
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    description VARCHAR(255),
+    created_by BIGINT NOT NULL DEFAULT 0,
+    updated_by BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 同一范围只允许一个启用的规则集
+CREATE UNIQUE INDEX uk_merchant_class_rule_sets_scope ON merchant_class_rule_sets(agent_id, channel_id) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS merchant_class_histories (
+    id BIGSERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    rule_set_id BIGINT NOT NULL DEFAULT 0,               -- 0为内置默认规则
+    old_type VARCHAR(20),
+    new_type VARCHAR(20) NOT NULL,
+    new_label VARCHAR(50),
+    metrics JSONB,                                       -- 分类时的交易指标快照
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_merchant_class_histories_merchant ON merchant_class_histories(merchant_id, created_at);
+
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS merchant_type_label VARCHAR(50); -- 分类名称（自定义规则的标签）
+
+COMMENT ON TABLE merchant_class_rule_sets IS '商户分类规则集';
+COMMENT ON TABLE merchant_class_histories IS '商户分类变更历史';
@@ -0,0 +1,62 @@
+-- 053_create_merchant_churn_watch.sql
+-- 商户流失预警：按周交易额环比下降、距最近交易天数识别流失趋势，给商户所属代理商生成跟进任务并发送消息
+-- 代理商记录跟进结果（已联系/已换机/已流失），交易恢复后任务自动标记为已挽回，按代理商统计挽回率
+
+CREATE TABLE IF NOT EXISTS churn_watch_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    drop_percent INT NOT NULL DEFAULT 50,                -- 近7天交易额较前7天下降百分比达到该值时预警
+    min_base_amount BIGINT NOT NULL DEFAULT 100000,      -- 前7天交易额低于该值（分）不做环比预警
+    inactive_days INT NOT NULL DEFAULT 15,               -- 距最近交易天数达到该值时预警
+    recover_percent INT NOT NULL DEFAULT 80,             -- 近7天交易额恢复到预警时基准的百分比视为已挽回
+    cooldown_days INT NOT NULL DEFAULT 30,               -- 任务结束后该天数内不再为同一商户生成任务
+    follow_up_days INT NOT NULL DEFAULT 7,               -- 跟进期限（天）
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO churn_watch_configs (enabled)
+SELECT TRUE
+WHERE NOT EXISTS (SELECT 1 FROM churn_watch_configs);
+
+CREATE TABLE IF NOT EXISTS merchant_follow_up_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    merchant_no VARCHAR(64),
+    merchant_name VARCHAR(100),
+    agent_id BIGINT NOT NULL,                            -- 跟进代理商（商户所属代理商）
+    trigger_type VARCHAR(20) NOT NULL,                   -- volume_drop交易额下降 inactive长时间无交易
+    last_week_amount BIGINT NOT NULL DEFAULT 0,          -- 预警时近7天交易额（分）
+    prev_week_amount BIGINT NOT NULL DEFAULT 0,          -- 预警时前7天交易额（分）
+    base_amount BIGINT NOT NULL DEFAULT 0,               -- 挽回判断基准（分）
+    last_trade_at TIMESTAMP,                             -- 预警时最近交易时间
+    inactive_days INT NOT NULL DEFAULT 0,                -- 预警时距最近交易天数
+    status SMALLINT NOT NULL DEFAULT 1,                  -- 1待跟进 2跟进中 3已挽回 4已流失
+    last_outcome VARCHAR(20),                            -- 最近跟进结果
+    follow_up_count INT NOT NULL DEFAULT 0,
+    due_at TIMESTAMP NOT NULL,                           -- 跟进期限
+    resolved_at TIMESTAMP,                               -- 挽回/流失时间
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_merchant_follow_up_tasks_merchant ON merchant_follow_up_tasks(merchant_id, created_at);
+CREATE INDEX idx_merchant_follow_up_tasks_agent ON merchant_follow_up_tasks(agent_id, status);
+CREATE INDEX idx_merchant_follow_up_tasks_status ON merchant_follow_up_tasks(status);
+
+CREATE TABLE IF NOT EXISTS merchant_follow_up_logs (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    outcome VARCHAR(20) NOT NULL,                        -- contacted已联系 replaced_terminal已换机 lost已流失 recovered交易恢复（系统）
+    remark VARCHAR(500),
+    operator_id BIGINT NOT NULL DEFAULT 0,               -- 0为系统
+    operator_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_merchant_follow_up_logs_task ON merchant_follow_up_logs(task_id);
+
+COMMENT ON TABLE churn_watch_configs IS '商户流失预警配置（全局单行）';
+COMMENT ON TABLE merchant_follow_up_tasks IS '商户流失跟进任务';
+COMMENT ON TABLE merchant_follow_up_logs IS '商户流失跟进记录';
@@ -0,0 +1,88 @@
+-- 054_create_tx_risk.sql
+-- 交易风控（套现识别）：分润计算后按规则对消费交易打分，达到阈值生成风控事件
+-- 规则阈值存储在配置表中，修改后无需重新部署；可选对高分事件关联的分润做风控冻结，审核排除后自动解除
+
+CREATE TABLE IF NOT EXISTS tx_risk_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    event_score INT NOT NULL DEFAULT 20,                   -- 命中规则总分达到该值时生成风控事件
+    hold_enabled BOOLEAN NOT NULL DEFAULT FALSE,           -- 是否冻结高分事件关联的分润
+    hold_score INT NOT NULL DEFAULT 60,                    -- 事件总分达到该值时冻结分润
+
+    -- 同卡频繁交易：同一商户同一脱敏卡号在窗口内交易笔数
+    same_card_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    same_card_hours INT NOT NULL DEFAULT 24,
+    same_card_count INT NOT NULL DEFAULT 5,
+    same_card_score INT NOT NULL DEFAULT 30,
+
+    -- 临界整数金额：金额为整数且略低于限额
+    near_limit_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    near_limit_amounts JSONB NOT NULL DEFAULT '[1000000, 2000000, 5000000]', -- 限额列表（分）
+    near_limit_margin BIGINT NOT NULL DEFAULT 50000,       -- 低于限额不超过该值（分）
+    near_limit_round_unit BIGINT NOT NULL DEFAULT 10000,   -- 金额须为该值整数倍（分），0不要求
+    near_limit_score INT NOT NULL DEFAULT 20,
+
+    -- 夜间集中交易：夜间时段内同一商户交易笔数
+    night_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    night_start_hour INT NOT NULL DEFAULT 0,
+    night_end_hour INT NOT NULL DEFAULT 5,
+    night_count INT NOT NULL DEFAULT 3,
+    night_score INT NOT NULL DEFAULT 20,
+
+    -- 金额突增：单笔金额超过商户历史笔均的倍数
+    spike_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    spike_history_days INT NOT NULL DEFAULT 30,
+    spike_min_history INT NOT NULL DEFAULT 10,             -- 历史交易笔数不足时不判断
+    spike_multiple INT NOT NULL DEFAULT 5,
+    spike_min_amount BIGINT NOT NULL DEFAULT 500000,       -- 单笔低于该值（分）不判断
+    spike_score INT NOT NULL DEFAULT 25,
+
+    -- 法人身份证共用：同一法人身份证名下正常商户数
+    shared_id_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    shared_id_count INT NOT NULL DEFAULT 3,
+    shared_id_score INT NOT NULL DEFAULT 30,
+
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO tx_risk_configs (enabled)
+SELECT TRUE
+WHERE NOT EXISTS (SELECT 1 FROM tx_risk_configs);
+
+CREATE TABLE IF NOT EXISTS tx_risk_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_no VARCHAR(50) NOT NULL UNIQUE,
+    transaction_id BIGINT NOT NULL UNIQUE,
+    order_no VARCHAR(64),
+    channel_id BIGINT NOT NULL DEFAULT 0,
+    merchant_id BIGINT NOT NULL,
+    merchant_no VARCHAR(64),
+    merchant_name VARCHAR(100),
+    agent_id BIGINT NOT NULL DEFAULT 0,
+    terminal_sn VARCHAR(50),
+    card_no VARCHAR(50),
+    amount BIGINT NOT NULL DEFAULT 0,                      -- 交易金额（分）
+    trade_time TIMESTAMP NOT NULL,
+    score INT NOT NULL DEFAULT 0,                          -- 命中规则总分
+    hits JSONB NOT NULL DEFAULT '[]',                      -- 命中规则明细
+    status SMALLINT NOT NULL DEFAULT 1,                    -- 1待审核 2确认风险 3已排除
+    held BOOLEAN NOT NULL DEFAULT FALSE,                   -- 是否已冻结关联分润
+    held_amount BIGINT NOT NULL DEFAULT 0,                 -- 冻结分润金额（分）
+    review_remark VARCHAR(500),
+    reviewed_by BIGINT,
+    reviewed_by_name VARCHAR(50),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tx_risk_events_status ON tx_risk_events(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_tx_risk_events_merchant ON tx_risk_events(merchant_id);
+CREATE INDEX IF NOT EXISTS idx_tx_risk_events_agent ON tx_risk_events(agent_id);
+
+-- 同卡查询
+CREATE INDEX IF NOT EXISTS idx_transactions_merchant_card ON transactions(merchant_id, card_no, trade_time);
+-- 法人身份证共用查询
+CREATE INDEX IF NOT EXISTS idx_merchants_legal_id_card ON merchants(legal_id_card);
@@ -0,0 +1,42 @@
+-- 055_create_merchant_rate_change_requests.sql
+-- 商户费率修改申请：代理商提交申请，按通道费率配置的底价/上限校验，低于自身政策费率时须上级代理商（一级代理商为平台）审批
+-- 审批通过后先同步到通道，通道成功后才更新本地费率；同步失败按退避策略自动重试，并可查询本地与通道费率不一致的商户
+
+CREATE TABLE IF NOT EXISTS merchant_rate_change_requests (
+    id BIGSERIAL PRIMARY KEY,
+    request_no VARCHAR(50) NOT NULL UNIQUE,
+    merchant_id BIGINT NOT NULL,
+    merchant_no VARCHAR(64),
+    merchant_name VARCHAR(100),
+    channel_id BIGINT NOT NULL DEFAULT 0,
+    agent_id BIGINT NOT NULL,                          -- 商户所属代理商
+    old_credit_rate DECIMAL(10,4),                     -- 费率为小数形式，如0.0060表示0.6%
+    old_debit_rate DECIMAL(10,4),
+    new_credit_rate DECIMAL(10,4) NOT NULL,
+    new_debit_rate DECIMAL(10,4) NOT NULL,
+    reason VARCHAR(500),
+    status SMALLINT NOT NULL DEFAULT 1,                -- 1待审批 2已驳回 3同步中 4已生效 5同步失败 6已取消
+    approval_reason VARCHAR(255),                      -- 需要审批的原因
+    approver_agent_id BIGINT NOT NULL DEFAULT 0,       -- 审批代理商，0表示平台审批
+    requested_by BIGINT,
+    requested_by_name VARCHAR(50),
+    reviewed_by BIGINT,
+    reviewed_by_name VARCHAR(50),
+    review_remark VARCHAR(500),
+    reviewed_at TIMESTAMP,
+    applied_at TIMESTAMP,
+    sync_log_id BIGINT NOT NULL DEFAULT 0,
+    sync_message VARCHAR(500),
+    synced_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_change_requests_merchant ON merchant_rate_change_requests(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_rate_change_requests_agent ON merchant_rate_change_requests(agent_id);
+CREATE INDEX IF NOT EXISTS idx_rate_change_requests_approver ON merchant_rate_change_requests(approver_agent_id, status);
+
+-- 同步日志关联费率修改申请，重试结果回写到申请
+ALTER TABLE rate_sync_logs ADD COLUMN IF NOT EXISTS request_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_rate_sync_logs_merchant ON rate_sync_logs(merchant_id, id);
+CREATE INDEX IF NOT EXISTS idx_rate_changes_merchant_no ON rate_changes(merchant_no, received_at);
@@ -0,0 +1,50 @@
+-- 056_create_merchant_rate_plans.sql
+-- 商户费率计划：新商户限时优惠费率到期自动恢复，或按多个阶梯在指定时间调整费率
+-- 到期步骤由定时任务通过费率修改申请执行（先同步通道，通道成功后本地生效，失败自动重试），调整前提醒代理商和商户
+-- 与通道级的费率阶梯政策（rate_stage_policies）相互独立
+
+CREATE TABLE IF NOT EXISTS merchant_rate_plans (
+    id BIGSERIAL PRIMARY KEY,
+    plan_no VARCHAR(50) NOT NULL UNIQUE,
+    plan_type VARCHAR(20) NOT NULL,                    -- promo限时优惠 steps阶梯调整
+    name VARCHAR(100),
+    merchant_id BIGINT NOT NULL,
+    merchant_no VARCHAR(64),
+    merchant_name VARCHAR(100),
+    channel_id BIGINT NOT NULL DEFAULT 0,
+    agent_id BIGINT NOT NULL,                          -- 商户所属代理商
+    remind_days INT NOT NULL DEFAULT 3,                -- 调整前提前提醒天数
+    status SMALLINT NOT NULL DEFAULT 1,                -- 1执行中 2已完成 3已取消
+    remark VARCHAR(500),
+    created_by BIGINT,
+    created_by_name VARCHAR(50),
+    cancelled_by BIGINT,
+    cancelled_by_name VARCHAR(50),
+    cancelled_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_rate_plans_merchant ON merchant_rate_plans(merchant_id, status);
+CREATE INDEX IF NOT EXISTS idx_merchant_rate_plans_agent ON merchant_rate_plans(agent_id);
+
+CREATE TABLE IF NOT EXISTS merchant_rate_plan_steps (
+    id BIGSERIAL PRIMARY KEY,
+    plan_id BIGINT NOT NULL,
+    seq INT NOT NULL,                                  -- 执行顺序，从1开始
+    effective_at TIMESTAMP NOT NULL,                   -- 生效时间
+    credit_rate DECIMAL(10,4) NOT NULL,                -- 费率为小数形式，如0.0060表示0.6%
+    debit_rate DECIMAL(10,4) NOT NULL,
+    is_revert BOOLEAN NOT NULL DEFAULT FALSE,          -- 是否为优惠到期恢复
+    status SMALLINT NOT NULL DEFAULT 1,                -- 1待执行 2执行中 3已生效 4执行失败 5已取消
+    change_request_id BIGINT NOT NULL DEFAULT 0,       -- 执行时生成的费率修改申请
+    message VARCHAR(500),
+    reminded_at TIMESTAMP,
+    executed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (plan_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_rate_plan_steps_due ON merchant_rate_plan_steps(status, effective_at);
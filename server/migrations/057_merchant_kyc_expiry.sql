@@ -0,0 +1,38 @@
+-- 057_merchant_kyc_expiry.sql
+-- 商户法人身份证有效期：商户入网回调中的有效期落库（新回调到达时更新），每日按提醒窗口（默认60/30/7天）提醒所属代理商
+-- 通道会冻结证件过期的商户，到期看板按剩余天数分档展示
+
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS id_card_start_date DATE;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS id_card_end_date DATE;
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS id_card_long_term BOOLEAN NOT NULL DEFAULT FALSE;  -- 长期有效
+ALTER TABLE merchants ADD COLUMN IF NOT EXISTS kyc_updated_at TIMESTAMP;                         -- 有效期最近更新时间
+
+CREATE INDEX IF NOT EXISTS idx_merchants_id_card_end_date ON merchants(id_card_end_date) WHERE id_card_end_date IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS kyc_expiry_configs (
+    id BIGSERIAL PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    window_days JSONB NOT NULL DEFAULT '[60,30,7]',    -- 到期前提醒窗口（天）
+    notify_expired BOOLEAN NOT NULL DEFAULT TRUE,      -- 已过期时再提醒一次
+    updated_by BIGINT,
+    updated_by_name VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO kyc_expiry_configs (enabled)
+SELECT TRUE
+WHERE NOT EXISTS (SELECT 1 FROM kyc_expiry_configs);
+
+-- 提醒记录：同一证件到期日每个窗口只提醒一次，证件更新后到期日变化重新计算
+CREATE TABLE IF NOT EXISTS kyc_expiry_reminders (
+    id BIGSERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    agent_id BIGINT NOT NULL,
+    end_date DATE NOT NULL,
+    window_days INT NOT NULL,                          -- 命中的提醒窗口，0表示已过期
+    days_left INT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (merchant_id, end_date, window_days)
+);
+
+CREATE INDEX IF NOT EXISTS idx_kyc_expiry_reminders_agent ON kyc_expiry_reminders(agent_id, created_at);
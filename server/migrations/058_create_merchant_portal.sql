@@ -0,0 +1,57 @@
+-- 058_create_merchant_portal.sql
+-- 商户自助服务：商户用商户号+登记手机号短信验证码登录，查询本商户交易、日结汇总、费率和终端，提交结算卡变更申请
+-- 商户令牌与代理商/管理员令牌分开签发和存储，只能访问本商户数据
+
+-- 短信验证码（只存哈希）
+CREATE TABLE IF NOT EXISTS merchant_sms_codes (
+    id BIGSERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    scene VARCHAR(20) NOT NULL DEFAULT 'login',
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,                   -- 校验失败次数
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    request_ip VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_sms_codes_merchant ON merchant_sms_codes(merchant_id, scene, created_at);
+
+-- 商户刷新令牌（只存哈希）
+CREATE TABLE IF NOT EXISTS merchant_refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    merchant_id BIGINT NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_refresh_tokens_merchant ON merchant_refresh_tokens(merchant_id);
+
+-- 结算卡变更申请：平台审核通过后在通道侧人工变更
+CREATE TABLE IF NOT EXISTS merchant_settle_card_requests (
+    id BIGSERIAL PRIMARY KEY,
+    request_no VARCHAR(50) NOT NULL UNIQUE,
+    merchant_id BIGINT NOT NULL,
+    merchant_no VARCHAR(64),
+    merchant_name VARCHAR(100),
+    agent_id BIGINT NOT NULL,
+    account_name VARCHAR(50) NOT NULL,                 -- 开户名
+    bank_name VARCHAR(100) NOT NULL,
+    branch_name VARCHAR(100),
+    card_no_encrypted VARCHAR(200) NOT NULL,           -- 卡号（加密）
+    card_no_masked VARCHAR(30) NOT NULL,
+    reason VARCHAR(500),
+    status SMALLINT NOT NULL DEFAULT 1,                -- 1待审核 2已通过 3已驳回 4已撤销
+    reviewed_by BIGINT,
+    reviewed_by_name VARCHAR(50),
+    review_remark VARCHAR(500),
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_settle_card_requests_merchant ON merchant_settle_card_requests(merchant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_merchant_settle_card_requests_agent ON merchant_settle_card_requests(agent_id, status);
+-- 同一商户同时只能有一个待审核申请
+CREATE UNIQUE INDEX IF NOT EXISTS uk_merchant_settle_card_requests_pending ON merchant_settle_card_requests(merchant_id) WHERE status = 1;
@@ -0,0 +1,60 @@
+-- 059_create_merchant_applications.sql
+-- 商户进件申请：代理商在本系统录入法人、结算卡、经营信息和资料图片，通过支持进件的通道适配器提交
+-- 审核结果由状态同步任务查询或merc_income回调对账，审核通过后创建本地商户
+
+CREATE TABLE IF NOT EXISTS merchant_applications (
+    id BIGSERIAL PRIMARY KEY,
+    apply_no VARCHAR(50) NOT NULL UNIQUE,
+    agent_id BIGINT NOT NULL,
+    channel_id BIGINT NOT NULL,
+    channel_code VARCHAR(32) NOT NULL,
+    terminal_sn VARCHAR(50) NOT NULL,
+    brand_code VARCHAR(32),
+
+    -- 法人信息（身份证号、手机号加密存储）
+    legal_name VARCHAR(50) NOT NULL,
+    legal_id_card VARCHAR(200) NOT NULL,
+    legal_phone VARCHAR(100) NOT NULL,
+    id_card_start_date VARCHAR(20),
+    id_card_end_date VARCHAR(20),                      -- yyyy-mm-dd或"长期"
+
+    -- 结算信息（卡号加密存储）
+    settle_account_name VARCHAR(50) NOT NULL,
+    settle_bank_name VARCHAR(100) NOT NULL,
+    settle_branch_name VARCHAR(100),
+    settle_card_encrypted VARCHAR(200) NOT NULL,
+    settle_card_masked VARCHAR(30) NOT NULL,
+
+    -- 经营信息
+    merchant_name VARCHAR(100) NOT NULL,
+    mcc VARCHAR(10),
+    district_code VARCHAR(20),
+    address VARCHAR(255),
+    credit_rate DECIMAL(10,4) NOT NULL,                -- 小数形式
+    debit_rate DECIMAL(10,4) NOT NULL,
+    documents JSONB NOT NULL DEFAULT '[]',             -- [{type, file_id}]
+
+    -- 审核跟踪
+    status SMALLINT NOT NULL DEFAULT 1,                -- 1待提交 2审核中 3已通过 4已驳回 5已撤销
+    channel_apply_no VARCHAR(64),
+    merchant_no VARCHAR(64),                           -- 审核通过后的通道商户号
+    merchant_id BIGINT,                                -- 审核通过后的本地商户
+    status_message VARCHAR(500),                       -- 提交失败/驳回原因
+    status_source VARCHAR(20),                         -- 最近一次状态来源：submit/query/callback
+    submit_attempts INT NOT NULL DEFAULT 0,
+    submitted_at TIMESTAMP,
+    last_queried_at TIMESTAMP,
+    finished_at TIMESTAMP,
+
+    created_by BIGINT,
+    created_by_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merchant_applications_agent ON merchant_applications(agent_id, status);
+CREATE INDEX IF NOT EXISTS idx_merchant_applications_status ON merchant_applications(status, last_queried_at);
+CREATE INDEX IF NOT EXISTS idx_merchant_applications_terminal ON merchant_applications(channel_code, terminal_sn);
+CREATE INDEX IF NOT EXISTS idx_merchant_applications_merchant_no ON merchant_applications(channel_code, merchant_no) WHERE merchant_no IS NOT NULL;
+-- 同一终端同时只能有一个未结束的申请
+CREATE UNIQUE INDEX IF NOT EXISTS uk_merchant_applications_open_terminal ON merchant_applications(channel_code, terminal_sn) WHERE status IN (1, 2);
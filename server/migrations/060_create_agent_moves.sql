@@ -0,0 +1,24 @@
+-- 060_create_agent_moves.sql
+-- 代理商迁移记录：平台将代理商（连同整棵下级树）挂到新的上级，迁移时事务内重写下级的path/level
+-- 记录迁移前后的路径、影响的代理商数及迁移时的提示项（进行中的代扣计划/代扣链），用于审计
+
+CREATE TABLE IF NOT EXISTS agent_moves (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL,
+    agent_name VARCHAR(100),
+    from_parent_id BIGINT NOT NULL DEFAULT 0,          -- 0表示原为顶级
+    to_parent_id BIGINT NOT NULL DEFAULT 0,            -- 0表示迁移为顶级
+    old_path VARCHAR(500) NOT NULL,
+    new_path VARCHAR(500) NOT NULL,
+    old_level INT NOT NULL,
+    new_level INT NOT NULL,
+    subtree_size INT NOT NULL,                         -- 受影响的代理商数（含本人）
+    warnings JSONB NOT NULL DEFAULT '[]',              -- 迁移时的提示项（不阻断迁移）
+    reason VARCHAR(255),
+    operator_id BIGINT,
+    operator_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_moves_agent ON agent_moves(agent_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_agent_moves_created ON agent_moves(created_at DESC);
@@ -0,0 +1,28 @@
+-- 061_create_agent_hierarchy.sql
+-- 代理商层级闭包表：每对（上级, 下级）一行，depth为相隔层数，每个代理商有一条depth=0的自身记录
+-- 下级树、上级链、限深查询和上下级判断都走该表（按ancestor_id/descendant_id索引），不再解析path或path LIKE扫描
+-- 创建代理商和迁移代理商时由应用维护；agents.path/level仍保留用于展示和兼容
+
+CREATE TABLE IF NOT EXISTS agent_hierarchy (
+    ancestor_id BIGINT NOT NULL,
+    descendant_id BIGINT NOT NULL,
+    depth INT NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_hierarchy_descendant ON agent_hierarchy(descendant_id, depth);
+CREATE INDEX IF NOT EXISTS idx_agent_hierarchy_ancestor_depth ON agent_hierarchy(ancestor_id, depth);
+
+-- 按物化路径回填：/1/5/12/ 生成 (1,12,2) (5,12,1) (12,12,0)
+INSERT INTO agent_hierarchy (ancestor_id, descendant_id, depth)
+SELECT p.ancestor_id::BIGINT, a.id, CARDINALITY(s.parts) - p.ord
+FROM agents a
+CROSS JOIN LATERAL (SELECT string_to_array(TRIM(BOTH '/' FROM a.path), '/') AS parts) s
+CROSS JOIN LATERAL UNNEST(s.parts) WITH ORDINALITY AS p(ancestor_id, ord)
+WHERE TRIM(BOTH '/' FROM COALESCE(a.path, '')) <> ''
+ON CONFLICT (ancestor_id, descendant_id) DO NOTHING;
+
+-- 路径为空的代理商补自身记录
+INSERT INTO agent_hierarchy (ancestor_id, descendant_id, depth)
+SELECT id, id, 0 FROM agents
+ON CONFLICT (ancestor_id, descendant_id) DO NOTHING;
@@ -0,0 +1,21 @@
+-- 062_rebalance_deduction_freeze_logs.sql
+-- 到期扣款从冻结金额扣款时此前未写冻结明细，导致按明细汇总的“计划在各钱包冻结余额”偏大，
+-- 释放冻结时可能动到钱包上其他业务（提现、转账）的冻结
+-- 按计划当前冻结金额，以钱包ID顺序保留冻结，超出部分补写扣减明细（trigger_type=deduct，金额为负数）
+
+INSERT INTO deduction_freeze_logs (plan_id, agent_id, wallet_id, wallet_type, channel_id,
+                                   freeze_amount, total_frozen, trigger_type, trigger_ref_id, created_at)
+SELECT w.plan_id, w.agent_id, w.wallet_id, w.wallet_type, w.channel_id,
+       LEAST(GREATEST(w.frozen_amount - (w.cumulative - w.amount), 0), w.amount) - w.amount,
+       w.frozen_amount, 'deduct', 0, NOW()
+FROM (
+    SELECT l.plan_id, p.deductee_id AS agent_id, l.wallet_id,
+           MAX(l.wallet_type) AS wallet_type, MAX(l.channel_id) AS channel_id,
+           SUM(l.freeze_amount) AS amount, p.frozen_amount,
+           SUM(SUM(l.freeze_amount)) OVER (PARTITION BY l.plan_id ORDER BY l.wallet_id) AS cumulative
+    FROM deduction_freeze_logs l
+    JOIN deduction_plans p ON p.id = l.plan_id
+    GROUP BY l.plan_id, p.deductee_id, l.wallet_id, p.frozen_amount
+) w
+WHERE w.amount > 0
+  AND LEAST(GREATEST(w.frozen_amount - (w.cumulative - w.amount), 0), w.amount) < w.amount;
@@ -0,0 +1,14 @@
+-- 063_reward_clawback_partial_status.sql
+-- 终端回拨追回奖励时钱包余额不足，奖励记录置为部分追回（wallet_status=3），由管理员继续追回，全部追回后置为已追回
+
+COMMENT ON COLUMN activation_reward_records.wallet_status IS '0待入账 1已入账 2已追回 3部分追回';
+COMMENT ON COLUMN deposit_cashback_records.wallet_status IS '0待入账 1已入账 2已追回 3部分追回';
+
+-- 此前部分追回的记录已被置为已追回，按结算明细恢复为部分追回
+UPDATE activation_reward_records r SET wallet_status = 3
+FROM terminal_recall_settlement_items i
+WHERE i.ref_type = 'activation_reward' AND i.ref_id = r.id AND i.status = 2 AND r.wallet_status = 2;
+
+UPDATE deposit_cashback_records r SET wallet_status = 3
+FROM terminal_recall_settlement_items i
+WHERE i.ref_type = 'deposit_cashback' AND i.ref_id = r.id AND i.status = 2 AND r.wallet_status = 2;
@@ -0,0 +1,11 @@
+-- 064_backfill_transaction_merchant.sql
+-- 交易回调入库时此前未写入商户ID和通道ID，商户端交易列表、日结汇总和交易风控均按商户ID查询
+-- 按终端SN回填历史交易的商户ID，通道ID取自商户，商户未入库时取自终端
+
+UPDATE transactions t SET merchant_id = m.id, channel_id = m.channel_id
+FROM merchants m
+WHERE m.terminal_sn = t.terminal_sn AND COALESCE(t.merchant_id, 0) = 0;
+
+UPDATE transactions t SET channel_id = tm.channel_id
+FROM terminals tm
+WHERE tm.terminal_sn = t.terminal_sn AND t.channel_id = 0;
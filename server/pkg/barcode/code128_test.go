@@ -0,0 +1,78 @@
+package barcode
+
+import "testing"
+
+func TestCode128Patterns(t *testing.T) {
+	seen := make(map[string]bool, len(code128Patterns))
+	for i, pattern := range code128Patterns {
+		want := 11
+		if i == code128Stop {
+			want = 13
+		}
+		sum := 0
+		for _, w := range pattern {
+			sum += int(w - '0')
+		}
+		if sum != want {
+			t.Errorf("符号%d 模块数 = %d, want %d", i, sum, want)
+		}
+		if seen[pattern] {
+			t.Errorf("符号%d 条空宽度重复: %s", i, pattern)
+		}
+		seen[pattern] = true
+	}
+}
+
+func TestCode128Symbols(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []int
+	}{
+		// B字符集：104 + 33×1 + 34×2 = 205，205 % 103 = 102
+		{"B字符集", "AB", []int{104, 33, 34, 102, 106}},
+		// C字符集：105 + 12×1 + 34×2 = 185，185 % 103 = 82
+		{"C字符集", "1234", []int{105, 12, 34, 82, 106}},
+		// 奇数位数字使用B字符集：104 + 17×1 + 18×2 + 19×3 = 214，214 % 103 = 8
+		{"奇数位数字", "123", []int{104, 17, 18, 19, 8, 106}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := code128Symbols(tt.content)
+			if err != nil {
+				t.Fatalf("code128Symbols(%q) error: %v", tt.content, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("code128Symbols(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("code128Symbols(%q) = %v, want %v", tt.content, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCode128Modules(t *testing.T) {
+	modules, err := Code128("SN00012345")
+	if err != nil {
+		t.Fatalf("Code128 error: %v", err)
+	}
+	// 起始符 + 10个数据符号 + 校验符各11模块，终止符13模块
+	if want := 11*12 + 13; len(modules) != want {
+		t.Errorf("模块数 = %d, want %d", len(modules), want)
+	}
+	if !modules[0] || !modules[len(modules)-1] {
+		t.Error("条码应以条开始并以条结束")
+	}
+
+	if _, err := Code128(""); err == nil {
+		t.Error("空内容应返回错误")
+	}
+	if _, err := Code128("终端"); err == nil {
+		t.Error("非ASCII内容应返回错误")
+	}
+}
@@ -0,0 +1,329 @@
+package label
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	"image/png"
+	"math"
+
+	"xiangshoufu/pkg/barcode"
+	"xiangshoufu/pkg/pdf"
+	"xiangshoufu/pkg/qrcode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// A4 纸张尺寸及最小页边距（毫米）
+const (
+	pageWidthMM  = 210.0
+	pageHeightMM = 297.0
+	pageMarginMM = 3.0
+
+	minModuleMM   = 0.15 // 条码最小模块宽度，低于此值扫码枪难以识别
+	quietModules  = 10   // 条码左右静区模块数
+	mmToPoint     = 72 / 25.4
+	textLineRatio = 1.3
+)
+
+// Layout 标签纸排版（单位：毫米），标签网格在A4页面上居中
+type Layout struct {
+	Width   float64 `json:"width"`   // 单张标签宽度
+	Height  float64 `json:"height"`  // 单张标签高度
+	Columns int     `json:"columns"` // 每行标签数
+	Rows    int     `json:"rows"`    // 每页行数
+	GapX    float64 `json:"gap_x"`   // 列间距
+	GapY    float64 `json:"gap_y"`   // 行间距
+	Border  bool    `json:"border"`  // 是否绘制裁切边框
+}
+
+// DefaultLayout 默认排版：60×40mm，每页3列6行
+func DefaultLayout() Layout {
+	return Layout{Width: 60, Height: 40, Columns: 3, Rows: 6, GapX: 2, GapY: 2, Border: true}
+}
+
+// PerPage 每页标签数
+func (l Layout) PerPage() int {
+	return l.Columns * l.Rows
+}
+
+// Validate 校验排版是否可在A4纸上排下
+func (l Layout) Validate() error {
+	if l.Width < 30 || l.Height < 20 {
+		return errors.New("标签尺寸不能小于30×20mm")
+	}
+	if l.Columns <= 0 || l.Rows <= 0 {
+		return errors.New("行数和列数必须大于0")
+	}
+	if l.GapX < 0 || l.GapY < 0 {
+		return errors.New("标签间距不能为负数")
+	}
+	if l.gridWidth() > pageWidthMM-2*pageMarginMM {
+		return fmt.Errorf("%d列标签总宽%.1fmm，超出A4纸可打印宽度", l.Columns, l.gridWidth())
+	}
+	if l.gridHeight() > pageHeightMM-2*pageMarginMM {
+		return fmt.Errorf("%d行标签总高%.1fmm，超出A4纸可打印高度", l.Rows, l.gridHeight())
+	}
+	return nil
+}
+
+func (l Layout) gridWidth() float64 {
+	return float64(l.Columns)*l.Width + float64(l.Columns-1)*l.GapX
+}
+
+func (l Layout) gridHeight() float64 {
+	return float64(l.Rows)*l.Height + float64(l.Rows-1)*l.GapY
+}
+
+// Label 单张标签内容
+type Label struct {
+	Code      string   // 条码内容（终端SN），同时作为条码下方的可读文字
+	QRContent string   // 二维码内容
+	Lines     []string // 左下角附加文字（通道、品牌、型号等）
+}
+
+// box 标签内的矩形区域（毫米，相对标签左上角）
+type box struct {
+	X, Y, W, H float64
+}
+
+// geometry 标签内各元素的位置，PDF与PNG共用，保证两种输出版式一致
+type geometry struct {
+	Barcode  box
+	CodeText box // 条码下方SN文字，H 为字号
+	QR       box
+	Lines    box // 附加文字区域，行高为 CodeText.H
+}
+
+func (l Layout) geometry() geometry {
+	pad := math.Min(2, l.Height*0.05)
+	textSize := math.Min(l.Height*0.08, 3)
+
+	var g geometry
+	g.Barcode = box{X: pad, Y: pad, W: l.Width - 2*pad, H: l.Height * 0.3}
+	g.CodeText = box{X: pad, Y: g.Barcode.Y + g.Barcode.H + 0.5, W: g.Barcode.W, H: textSize}
+
+	top := g.CodeText.Y + textSize*textLineRatio
+	qrSize := math.Min(l.Height-top-pad, l.Width*0.45)
+	g.QR = box{X: l.Width - pad - qrSize, Y: l.Height - pad - qrSize, W: qrSize, H: qrSize}
+	g.Lines = box{X: pad, Y: top, W: g.QR.X - 2*pad, H: l.Height - top - pad}
+	return g
+}
+
+// encode 生成条码模块与二维码点阵
+func encode(item Label) ([]bool, [][]bool, error) {
+	bars, err := barcode.Code128(item.Code)
+	if err != nil {
+		return nil, nil, fmt.Errorf("终端%s: %w", item.Code, err)
+	}
+	qr, err := qrcode.Bitmap(item.QRContent)
+	if err != nil {
+		return nil, nil, fmt.Errorf("终端%s: %w", item.Code, err)
+	}
+	return bars, qr, nil
+}
+
+// PDF 按排版生成A4标签页
+func PDF(items []Label, layout Layout) ([]byte, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("没有需要打印的标签")
+	}
+
+	g := layout.geometry()
+	originX := (pageWidthMM - layout.gridWidth()) / 2
+	originY := (pageHeightMM - layout.gridHeight()) / 2
+
+	doc := pdf.New()
+	for i, item := range items {
+		bars, qr, err := encode(item)
+		if err != nil {
+			return nil, err
+		}
+		moduleW := g.Barcode.W / float64(len(bars)+2*quietModules)
+		if moduleW < minModuleMM {
+			return nil, fmt.Errorf("标签宽度不足以打印终端%s的条码，请加大标签宽度", item.Code)
+		}
+
+		slot := i % layout.PerPage()
+		if slot == 0 {
+			doc.AddPage()
+		}
+		x := originX + float64(slot%layout.Columns)*(layout.Width+layout.GapX)
+		y := originY + float64(slot/layout.Columns)*(layout.Height+layout.GapY)
+
+		if layout.Border {
+			doc.Rect(x*mmToPoint, y*mmToPoint, layout.Width*mmToPoint, layout.Height*mmToPoint)
+		}
+
+		// 条码：连续的条合并为一个矩形，整体水平居中
+		barX := x + g.Barcode.X + (g.Barcode.W-moduleW*float64(len(bars)))/2
+		for start := 0; start < len(bars); {
+			if !bars[start] {
+				start++
+				continue
+			}
+			end := start
+			for end < len(bars) && bars[end] {
+				end++
+			}
+			doc.FillRect((barX+float64(start)*moduleW)*mmToPoint, (y+g.Barcode.Y)*mmToPoint,
+				float64(end-start)*moduleW*mmToPoint, g.Barcode.H*mmToPoint)
+			start = end
+		}
+
+		// SN 文字居中于条码下方
+		size := g.CodeText.H * mmToPoint
+		textW := pdf.TextWidth(item.Code, size)
+		doc.Text((x+g.CodeText.X)*mmToPoint+(g.CodeText.W*mmToPoint-textW)/2, (y+g.CodeText.Y)*mmToPoint+size, size, item.Code)
+
+		// 二维码：每行连续的黑色模块合并为一个矩形
+		qrModule := g.QR.W / float64(len(qr))
+		for row, cells := range qr {
+			for start := 0; start < len(cells); {
+				if !cells[start] {
+					start++
+					continue
+				}
+				end := start
+				for end < len(cells) && cells[end] {
+					end++
+				}
+				doc.FillRect((x+g.QR.X+float64(start)*qrModule)*mmToPoint, (y+g.QR.Y+float64(row)*qrModule)*mmToPoint,
+					float64(end-start)*qrModule*mmToPoint, qrModule*mmToPoint)
+				start = end
+			}
+		}
+
+		// 附加文字
+		lineY := y + g.Lines.Y
+		for _, line := range item.Lines {
+			lineY += g.CodeText.H * textLineRatio
+			if lineY > y+g.Lines.Y+g.Lines.H {
+				break
+			}
+			doc.Text((x+g.Lines.X)*mmToPoint, lineY*mmToPoint, size, truncatePDFText(line, size, g.Lines.W*mmToPoint))
+		}
+	}
+	return doc.Bytes(), nil
+}
+
+// truncatePDFText 截断超出宽度的文本
+func truncatePDFText(text string, size, width float64) string {
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.TextWidth(string(runes), size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
+
+// PNG 按排版尺寸生成单张标签图片
+// 条码和二维码按整数像素绘制以保证清晰；文字使用内置点阵字体，仅支持ASCII字符
+func PNG(item Label, layout Layout, dpi int) ([]byte, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	if dpi < 150 || dpi > 600 {
+		return nil, errors.New("分辨率必须在150-600dpi之间")
+	}
+	bars, qr, err := encode(item)
+	if err != nil {
+		return nil, err
+	}
+
+	g := layout.geometry()
+	scale := float64(dpi) / 25.4
+	px := func(mm float64) int { return int(math.Round(mm * scale)) }
+
+	img := image.NewRGBA(image.Rect(0, 0, px(layout.Width), px(layout.Height)))
+	stddraw.Draw(img, img.Bounds(), image.White, image.Point{}, stddraw.Src)
+	black := image.NewUniform(color.Black)
+
+	// 条码
+	moduleW := px(g.Barcode.W) / (len(bars) + 2*quietModules)
+	if moduleW < 1 || float64(moduleW)/scale < minModuleMM {
+		return nil, fmt.Errorf("标签宽度不足以打印终端%s的条码，请加大标签宽度或分辨率", item.Code)
+	}
+	barX := px(g.Barcode.X) + (px(g.Barcode.W)-moduleW*len(bars))/2
+	for i, bar := range bars {
+		if bar {
+			r := image.Rect(barX+i*moduleW, px(g.Barcode.Y), barX+(i+1)*moduleW, px(g.Barcode.Y+g.Barcode.H))
+			stddraw.Draw(img, r, black, image.Point{}, stddraw.Src)
+		}
+	}
+
+	// 二维码
+	qrModule := px(g.QR.W) / len(qr)
+	if qrModule < 1 {
+		return nil, fmt.Errorf("标签高度不足以打印终端%s的二维码", item.Code)
+	}
+	qrX := px(g.QR.X) + (px(g.QR.W)-qrModule*len(qr))/2
+	qrY := px(g.QR.Y) + (px(g.QR.H)-qrModule*len(qr))/2
+	for row, cells := range qr {
+		for col, cell := range cells {
+			if cell {
+				r := image.Rect(qrX+col*qrModule, qrY+row*qrModule, qrX+(col+1)*qrModule, qrY+(row+1)*qrModule)
+				stddraw.Draw(img, r, black, image.Point{}, stddraw.Src)
+			}
+		}
+	}
+
+	// 文字
+	textPx := px(g.CodeText.H)
+	drawText(img, item.Code, px(g.CodeText.X), px(g.CodeText.Y), px(g.CodeText.W), textPx, true)
+	lineY := g.Lines.Y
+	for _, line := range item.Lines {
+		if lineY+g.CodeText.H > g.Lines.Y+g.Lines.H {
+			break
+		}
+		lineY += g.CodeText.H * (textLineRatio - 1)
+		drawText(img, line, px(g.Lines.X), px(lineY), px(g.Lines.W), textPx, false)
+		lineY += g.CodeText.H
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("编码标签图片失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// drawText 使用7×13点阵字体绘制单行文本，按整数倍放大到接近目标字高，超出宽度的字符截断
+func drawText(dst *image.RGBA, text string, x, y, width, height int, center bool) {
+	face := basicfont.Face7x13
+	factor := max(height/face.Height, 1)
+	charW := face.Advance * factor
+	maxChars := width / charW
+	if maxChars <= 0 {
+		return
+	}
+	runes := []rune(text)
+	if len(runes) > maxChars {
+		runes = runes[:maxChars]
+	}
+	text = string(runes)
+
+	src := image.NewRGBA(image.Rect(0, 0, face.Advance*len(runes), face.Height))
+	stddraw.Draw(src, src.Bounds(), image.White, image.Point{}, stddraw.Src)
+	drawer := &font.Drawer{
+		Dst:  src,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	drawer.DrawString(text)
+
+	w := src.Bounds().Dx() * factor
+	if center {
+		x += (width - w) / 2
+	}
+	draw.NearestNeighbor.Scale(dst, image.Rect(x, y, x+w, y+face.Height*factor), src, src.Bounds(), draw.Src, nil)
+}
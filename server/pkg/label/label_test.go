@@ -0,0 +1,106 @@
+package label
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestLayoutValidate(t *testing.T) {
+	if err := DefaultLayout().Validate(); err != nil {
+		t.Fatalf("默认排版应有效: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(l *Layout)
+	}{
+		{"标签过小", func(l *Layout) { l.Width = 20 }},
+		{"列数为0", func(l *Layout) { l.Columns = 0 }},
+		{"超出纸宽", func(l *Layout) { l.Columns = 4 }},
+		{"超出纸高", func(l *Layout) { l.Rows = 8 }},
+		{"间距为负", func(l *Layout) { l.GapY = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := DefaultLayout()
+			tt.modify(&layout)
+			if err := layout.Validate(); err == nil {
+				t.Errorf("%s 应校验失败", tt.name)
+			}
+		})
+	}
+}
+
+func TestGeometryFitsLabel(t *testing.T) {
+	for _, layout := range []Layout{DefaultLayout(), {Width: 30, Height: 20, Columns: 1, Rows: 1}, {Width: 100, Height: 60, Columns: 2, Rows: 4}} {
+		g := layout.geometry()
+		for name, b := range map[string]box{"条码": g.Barcode, "二维码": g.QR, "文字": g.Lines} {
+			if b.X < 0 || b.Y < 0 || b.X+b.W > layout.Width+1e-9 || b.Y+b.H > layout.Height+1e-9 {
+				t.Errorf("%.0f×%.0f 标签的%s区域越界: %+v", layout.Width, layout.Height, name, b)
+			}
+		}
+		if g.QR.Y < g.CodeText.Y+g.CodeText.H {
+			t.Errorf("%.0f×%.0f 标签的二维码与SN文字重叠", layout.Width, layout.Height)
+		}
+	}
+}
+
+func testLabels(n int) []Label {
+	items := make([]Label, n)
+	for i := range items {
+		items[i] = Label{
+			Code:      "SN0000000" + string(rune('0'+i%10)),
+			QRContent: "https://m.xiangshoufu.com/terminal/verify?sn=SN00000001&ch=HXT&sig=abcdef",
+			Lines:     []string{"HXT", "BRAND MODEL"},
+		}
+	}
+	return items
+}
+
+func TestPDFPaging(t *testing.T) {
+	layout := DefaultLayout()
+	data, err := PDF(testLabels(layout.PerPage()+1), layout)
+	if err != nil {
+		t.Fatalf("PDF error: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) {
+		t.Fatal("missing PDF header")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Errorf("%d张标签应分为2页", layout.PerPage()+1)
+	}
+
+	if _, err := PDF(nil, layout); err == nil {
+		t.Error("没有标签时应返回错误")
+	}
+}
+
+func TestPDFBarcodeTooNarrow(t *testing.T) {
+	layout := Layout{Width: 30, Height: 20, Columns: 1, Rows: 1}
+	items := []Label{{Code: strings.Repeat("A", 20), QRContent: "x"}}
+	if _, err := PDF(items, layout); err == nil {
+		t.Error("条码模块过窄时应返回错误")
+	}
+}
+
+func TestPNGSize(t *testing.T) {
+	layout := DefaultLayout()
+	data, err := PNG(testLabels(1)[0], layout, 203)
+	if err != nil {
+		t.Fatalf("PNG error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	// 60mm × 203dpi / 25.4 ≈ 480px，40mm ≈ 320px
+	if b := img.Bounds(); b.Dx() != 480 || b.Dy() != 320 {
+		t.Errorf("图片尺寸 = %dx%d, want 480x320", b.Dx(), b.Dy())
+	}
+
+	if _, err := PNG(testLabels(1)[0], layout, 72); err == nil {
+		t.Error("分辨率过低时应返回错误")
+	}
+}
@@ -0,0 +1,145 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf16"
+)
+
+// A4 页面尺寸（单位：point）
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document 简易PDF文档
+// 使用阅读器内置的 STSong-Light 宋体（UniGB-UCS2-H 编码）输出中文，无需嵌入字体文件；
+// 仅支持文本、直线和矩形，满足对账单等表格类单据及标签导出
+type Document struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+}
+
+// New 创建空白文档
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage 新增一页，后续绘制内容写入该页
+func (d *Document) AddPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+}
+
+// PageCount 页数
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text 在指定位置输出文本
+// 坐标以页面左上角为原点，y 为文本基线位置
+func (d *Document) Text(x, y, size float64, text string) {
+	if d.current == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.current, "BT /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, PageHeight-y, encodeUCS2(text))
+}
+
+// Line 绘制直线，坐标以页面左上角为原点
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	if d.current == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.current, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// FillRect 绘制黑色实心矩形（用于条码、二维码），坐标以页面左上角为原点，(x, y) 为左上角
+func (d *Document) FillRect(x, y, w, h float64) {
+	if d.current == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.current, "%.3f %.3f %.3f %.3f re f\n", x, PageHeight-y-h, w, h)
+}
+
+// Rect 绘制矩形边框，坐标以页面左上角为原点，(x, y) 为左上角
+func (d *Document) Rect(x, y, w, h float64) {
+	if d.current == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(d.current, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, PageHeight-y-h, w, h)
+}
+
+// TextWidth 估算文本宽度：ASCII 按半角，其余按全角
+func TextWidth(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		if r < 0x80 {
+			width += size * 0.5
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// Bytes 输出PDF文件内容
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// 对象编号：1目录 2页面树 3字体 4CID字体 5字体描述，之后每页占用页面和内容两个对象
+	objects := []string{
+		"", // 目录，页面对象编号确定后填充
+		"", // 页面树
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> " +
+			"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+			"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	}
+
+	kids := &bytes.Buffer{}
+	for _, page := range d.pages {
+		pageNum := len(objects) + 1
+		contentNum := pageNum + 1
+		fmt.Fprintf(kids, "%d 0 R ", pageNum)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				PageWidth, PageHeight, contentNum),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()),
+		)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(d.pages))
+
+	out := &bytes.Buffer{}
+	out.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xrefOffset := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+
+	return out.Bytes()
+}
+
+// encodeUCS2 将文本编码为 UCS-2 大端十六进制串，超出基本平面的字符以问号替代
+func encodeUCS2(text string) string {
+	buf := &bytes.Buffer{}
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(buf, "%04X", r)
+	}
+	return buf.String()
+}
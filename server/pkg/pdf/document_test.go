@@ -0,0 +1,75 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEncodeUCS2(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"ASCII", "A1", "00410031"},
+		{"中文", "对账", "5BF98D26"},
+		{"超出基本平面", "😀", "003F"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeUCS2(tt.text); got != tt.want {
+				t.Errorf("encodeUCS2(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentBytes(t *testing.T) {
+	doc := New()
+	doc.AddPage()
+	doc.Text(40, 60, 16, "代理商月度对账单")
+	doc.Line(40, 70, 555, 70)
+	doc.AddPage()
+	doc.Text(40, 60, 10, "第2页")
+
+	data := doc.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) {
+		t.Fatalf("missing PDF header")
+	}
+	if !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing EOF marker")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Errorf("page count not written")
+	}
+
+	// 校验 xref 中记录的偏移指向对应对象
+	xrefStart := bytes.Index(data, []byte("\nxref\n")) + 1
+	lines := strings.Split(string(data[xrefStart:]), "\n")
+	for i := 1; i <= 9; i++ {
+		var offset int
+		fmt.Sscanf(lines[2+i], "%010d", &offset)
+		prefix := fmt.Sprintf("%d 0 obj", i)
+		if !bytes.HasPrefix(data[offset:], []byte(prefix)) {
+			t.Errorf("xref entry %d points to wrong offset %d", i, offset)
+		}
+	}
+}
+
+func TestFillRect(t *testing.T) {
+	doc := New()
+	doc.FillRect(10, 20, 5, 30)
+	doc.Rect(0, 0, 100, 50)
+
+	data := string(doc.Bytes())
+	// y 轴翻转：841.89 - 20 - 30 = 791.89
+	if !strings.Contains(data, "10.000 791.890 5.000 30.000 re f") {
+		t.Errorf("filled rect not written in page coordinates")
+	}
+	if !strings.Contains(data, "0.00 791.89 100.00 50.00 re S") {
+		t.Errorf("stroked rect not written in page coordinates")
+	}
+}
@@ -106,3 +106,13 @@ func (g *Generator) GenerateInviteQRCode(inviteCode string, agentID int64) (stri
 func (g *Generator) GenerateQRCodeBytes(content string) ([]byte, error) {
 	return qrcode.Encode(content, qrcode.Medium, g.config.Size)
 }
+
+// Bitmap 生成二维码点阵（含静区），bitmap[y][x] 为 true 表示黑色模块
+// 用于在PDF、标签等非位图场景中按模块绘制
+func Bitmap(content string) ([][]bool, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("创建二维码失败: %w", err)
+	}
+	return qr.Bitmap(), nil
+}
@@ -0,0 +1,119 @@
+// Package sms 短信发送
+// 通过Provider接口对接短信服务商，本地开发和测试须显式选择StubProvider（不实际发送）
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+)
+
+// 服务商名称
+const (
+	ProviderStub     = "stub"     // 本地桩，不实际发送，仅供开发联调
+	ProviderDisabled = "disabled" // 未配置服务商，发送一律失败
+)
+
+// ErrProviderNotConfigured 未配置短信服务商
+var ErrProviderNotConfigured = errors.New("短信服务商未配置")
+
+var phonePattern = regexp.MustCompile(`^1\d{10}$`)
+
+// Provider 短信服务商
+type Provider interface {
+	// Name 服务商名称
+	Name() string
+	// Send 发送短信，phone为11位手机号
+	Send(phone, content string) error
+}
+
+// NewProvider 按名称创建短信服务商
+// name为空时返回ErrProviderNotConfigured，本地桩须显式指定stub，避免生产环境误用
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case "":
+		return nil, ErrProviderNotConfigured
+	case ProviderStub:
+		return NewStubProvider(), nil
+	case ProviderDisabled:
+		return DisabledProvider{}, nil
+	}
+	return nil, fmt.Errorf("不支持的短信服务商: %s", name)
+}
+
+// ValidatePhone 校验手机号格式
+func ValidatePhone(phone string) error {
+	if !phonePattern.MatchString(phone) {
+		return errors.New("手机号格式不正确")
+	}
+	return nil
+}
+
+// Message 已发送的短信
+type Message struct {
+	Phone   string
+	Content string
+}
+
+// StubProvider 本地桩服务商，记录日志并保留最近发送的短信，便于联调时查看验证码
+type StubProvider struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// NewStubProvider 创建本地桩服务商
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+// Name 服务商名称
+func (p *StubProvider) Name() string {
+	return ProviderStub
+}
+
+// stubKeep 本地桩最多保留的短信条数
+const stubKeep = 100
+
+// Send 记录短信（不实际发送）
+func (p *StubProvider) Send(phone, content string) error {
+	if err := ValidatePhone(phone); err != nil {
+		return err
+	}
+	// 不打印短信内容，避免验证码出现在日志中
+	log.Printf("[SMS:stub] to=%s****%s length=%d", phone[:3], phone[7:], len([]rune(content)))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, Message{Phone: phone, Content: content})
+	if len(p.sent) > stubKeep {
+		p.sent = p.sent[len(p.sent)-stubKeep:]
+	}
+	return nil
+}
+
+// Last 最近发送给phone的短信
+func (p *StubProvider) Last(phone string) (Message, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.sent) - 1; i >= 0; i-- {
+		if p.sent[i].Phone == phone {
+			return p.sent[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// DisabledProvider 未配置服务商时使用，发送一律失败
+type DisabledProvider struct{}
+
+// Name 服务商名称
+func (DisabledProvider) Name() string {
+	return ProviderDisabled
+}
+
+// Send 不发送，返回ErrProviderNotConfigured
+func (DisabledProvider) Send(phone, content string) error {
+	return ErrProviderNotConfigured
+}
@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"未配置", "", true},
+		{"本地桩", "stub", false},
+		{"未知", "unknown", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProvider(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProvider(%q) err = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && p.Name() != ProviderStub {
+				t.Errorf("Name() = %s, want %s", p.Name(), ProviderStub)
+			}
+		})
+	}
+}
+
+func TestStubProviderSend(t *testing.T) {
+	p := NewStubProvider()
+
+	if err := p.Send("1380013800", "短号"); err == nil {
+		t.Errorf("Send with invalid phone should fail")
+	}
+	if err := p.Send("13800138000", "第一条"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := p.Send("13900139000", "其他号码"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := p.Send("13800138000", "第二条"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	msg, ok := p.Last("13800138000")
+	if !ok || msg.Content != "第二条" {
+		t.Errorf("Last = %+v, %v, want 第二条", msg, ok)
+	}
+	if _, ok := p.Last("13700137000"); ok {
+		t.Errorf("Last for unknown phone should be empty")
+	}
+}
+
+func TestDisabledProviderSend(t *testing.T) {
+	p, err := NewProvider(ProviderDisabled)
+	if err != nil {
+		t.Fatalf("NewProvider(disabled) err = %v", err)
+	}
+	if err := p.Send("13800138000", "验证码"); !errors.Is(err, ErrProviderNotConfigured) {
+		t.Errorf("Send err = %v, want ErrProviderNotConfigured", err)
+	}
+}
@@ -0,0 +1,223 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ReadFirstSheet 读取工作簿第一个工作表的全部行
+// 仅解析单元格文本值（共享字符串、内联字符串、数字、公式缓存值），不处理样式和日期格式；
+// 空行保留为空切片，行内缺失的单元格补空字符串
+func ReadFirstSheet(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("不是有效的XLSX文件: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("工作表不存在: %s", sheetPath)
+	}
+	return readSheet(f, shared)
+}
+
+type workbookXML struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationshipsXML struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// firstSheetPath 通过 workbook.xml 及其关系文件定位第一个工作表
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var wb workbookXML
+	if err := decodeZipXML(files["xl/workbook.xml"], &wb); err != nil || len(wb.Sheets) == 0 {
+		return fallback, nil
+	}
+
+	var rels relationshipsXML
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return fallback, nil
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+type richTextXML struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s richTextXML) text() string {
+	if len(s.R) == 0 {
+		return s.T
+	}
+	var b strings.Builder
+	for _, r := range s.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	var sst struct {
+		Items []richTextXML `xml:"si"`
+	}
+	if err := decodeZipXML(f, &sst); err != nil {
+		return nil, fmt.Errorf("解析共享字符串失败: %w", err)
+	}
+
+	shared := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		shared[i] = si.text()
+	}
+	return shared, nil
+}
+
+type cellXML struct {
+	Ref    string      `xml:"r,attr"`
+	Type   string      `xml:"t,attr"`
+	Value  string      `xml:"v"`
+	Inline richTextXML `xml:"is"`
+}
+
+type rowXML struct {
+	Num   int       `xml:"r,attr"`
+	Cells []cellXML `xml:"c"`
+}
+
+func readSheet(f *zip.File, shared []string) ([][]string, error) {
+	var sheet struct {
+		Rows []rowXML `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(f, &sheet); err != nil {
+		return nil, fmt.Errorf("解析工作表失败: %w", err)
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// 行号缺失时按顺序递增；跳过的空行补空切片，保证下标与表格行号一致
+		num := row.Num
+		if num <= 0 {
+			num = len(rows) + 1
+		}
+		for len(rows) < num-1 {
+			rows = append(rows, []string{})
+		}
+
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if idx, ok := columnIndex(c.Ref); ok {
+					col = idx
+				}
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			value, err := cellValue(c, shared)
+			if err != nil {
+				return nil, fmt.Errorf("单元格 %s: %w", c.Ref, err)
+			}
+			values[col] = value
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+func cellValue(c cellXML, shared []string) (string, error) {
+	switch c.Type {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || idx < 0 || idx >= len(shared) {
+			return "", fmt.Errorf("无效的共享字符串索引 %q", c.Value)
+		}
+		return shared[idx], nil
+	case "inlineStr":
+		return c.Inline.text(), nil
+	case "b":
+		if c.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	default:
+		return c.Value, nil
+	}
+}
+
+// columnIndex 将单元格引用（如 "AB12"）的列字母转换为从0开始的列下标
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	if f == nil {
+		return io.ErrUnexpectedEOF
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
@@ -0,0 +1,101 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func buildWorkbook(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadFirstSheet(t *testing.T) {
+	data := buildWorkbook(t, map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="入库" sheetId="1" r:id="rId3"/><sheet name="说明" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId3" Type="worksheet" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>终端SN</t></si><si><r><t>结束</t></r><r><t>SN</t></r></si><si><t>SN0001</t></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>错误的表</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2" t="inlineStr"><is><t>备注</t></is></c></row>
+<row r="4"><c r="B4"><v>123456789012</v></c></row>
+</sheetData></worksheet>`,
+	})
+
+	rows, err := ReadFirstSheet(data)
+	if err != nil {
+		t.Fatalf("ReadFirstSheet: %v", err)
+	}
+	want := [][]string{
+		{"终端SN", "结束SN"},
+		{"SN0001", "", "备注"},
+		{},
+		{"", "123456789012"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestReadFirstSheetFallback(t *testing.T) {
+	data := buildWorkbook(t, map[string]string{
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c><v>1</v></c><c t="b"><v>1</v></c></row></sheetData></worksheet>`,
+	})
+
+	rows, err := ReadFirstSheet(data)
+	if err != nil {
+		t.Fatalf("ReadFirstSheet: %v", err)
+	}
+	if !reflect.DeepEqual(rows, [][]string{{"1", "TRUE"}}) {
+		t.Errorf("rows = %q", rows)
+	}
+}
+
+func TestReadFirstSheetInvalid(t *testing.T) {
+	if _, err := ReadFirstSheet([]byte("SN0001,SN0002")); err == nil {
+		t.Error("非XLSX文件应报错")
+	}
+
+	data := buildWorkbook(t, map[string]string{
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>5</v></c></row></sheetData></worksheet>`,
+	})
+	if _, err := ReadFirstSheet(data); err == nil {
+		t.Error("共享字符串索引越界应报错")
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "ab3": 27}
+	for ref, want := range tests {
+		if got, ok := columnIndex(ref); !ok || got != want {
+			t.Errorf("columnIndex(%s) = %d, want %d", ref, got, want)
+		}
+	}
+	if _, ok := columnIndex("12"); ok {
+		t.Error("无列字母应返回false")
+	}
+}